@@ -2,11 +2,13 @@ package glimo
 
 import (
 	"image"
+	"io"
+	"io/fs"
 
 	"github.com/Krispeckt/glimo/instructions"
 	imageUtil "github.com/Krispeckt/glimo/internal/core/image"
-	"github.com/Krispeckt/glimo/internal/core/image/patterns"
 	"github.com/Krispeckt/glimo/internal/render"
+	"github.com/Krispeckt/glimo/patterns"
 )
 
 //
@@ -32,6 +34,63 @@ type (
 	Layer = instructions.Layer
 	// Frame is an alias for Layer, used semantically for frame-based rendering.
 	Frame = instructions.Layer
+	// Size is the width/height pair used by BoundedShape implementations.
+	Size = instructions.Size
+	// Matrix is the 2D affine transform accepted by shapes' SetTransform.
+	Matrix = instructions.Matrix
+	// DrawContext is the base/overlay buffer pair shapes draw against.
+	DrawContext = instructions.DrawContext
+	// MissingGlyphMode controls how runes absent from a font are rendered.
+	MissingGlyphMode = render.MissingGlyphMode
+	// FontRegistry resolves fonts by family, weight and style.
+	FontRegistry = render.FontRegistry
+	// FontAxis is a design axis of a variable font.
+	FontAxis = render.FontAxis
+	// Hyphenator finds hyphenation points from TeX patterns.
+	Hyphenator = render.Hyphenator
+	// HintingMode selects how glyphs are fitted to the pixel grid.
+	HintingMode = render.HintingMode
+	// Limits caps canvas size, effect radii and instruction count for untrusted renders.
+	Limits = instructions.Limits
+	// LimitError reports which Limits a render exceeded.
+	LimitError = instructions.LimitError
+	// DecodeLimits bound the work of decoding untrusted images.
+	DecodeLimits = instructions.DecodeLimits
+	// LayoutOverflowError reports an AutoLayout item leaving its container.
+	LayoutOverflowError = instructions.LayoutOverflowError
+	// ConfigError reports an invalid setter argument recorded on a shape.
+	ConfigError = instructions.ConfigError
+	// DocumentLayer is one named raster layer of a layered ORA export.
+	DocumentLayer = instructions.DocumentLayer
+	// Patch changes properties of the shape with a given ID.
+	Patch = instructions.Patch
+	// PatchError reports a patch that could not be applied.
+	PatchError = instructions.PatchError
+)
+
+// Missing glyph modes re-exported from the render subsystem.
+const (
+	MissingGlyphNotdef      = render.MissingGlyphNotdef
+	MissingGlyphSkip        = render.MissingGlyphSkip
+	MissingGlyphBox         = render.MissingGlyphBox
+	MissingGlyphPlaceholder = render.MissingGlyphPlaceholder
+)
+
+// Hinting modes re-exported from the render subsystem.
+const (
+	HintingNone     = render.HintingNone
+	HintingVertical = render.HintingVertical
+	HintingFull     = render.HintingFull
+)
+
+// Errors re-exported from the subsystems, for use with errors.Is.
+var (
+	ErrUnsupportedFormat  = imageUtil.ErrUnsupportedFormat
+	ErrImageTooLarge      = imageUtil.ErrImageTooLarge
+	ErrImageNotRegistered = imageUtil.ErrImageNotRegistered
+	ErrFontGlyphMissing   = render.ErrFontGlyphMissing
+	ErrFontNotRegistered  = render.ErrFontNotRegistered
+	ErrCanvasTooLarge     = instructions.ErrCanvasTooLarge
 )
 
 //
@@ -46,6 +105,51 @@ func NewLayer(width, height int) *instructions.Layer {
 	return instructions.NewLayer(width, height)
 }
 
+// NewLayerWithScale creates a layer for logical dimensions rendered at a device scale (e.g. 2 for @2x).
+func NewLayerWithScale(width, height int, scale float64) *instructions.Layer {
+	return instructions.NewLayerWithScale(width, height, scale)
+}
+
+// NewLayerWithLimits creates a scaled layer, or returns a *LimitError if the canvas exceeds lim.
+func NewLayerWithLimits(width, height int, scale float64, lim Limits) (*instructions.Layer, error) {
+	return instructions.NewLayerWithLimits(width, height, scale, lim)
+}
+
+// NewLayerAutoHeight creates a scaled layer as tall as its content, up to maxHeight, and draws the shapes on it.
+func NewLayerAutoHeight(width, maxHeight, padding int, scale float64, shapes ...instructions.Shape) *instructions.Layer {
+	return instructions.NewLayerAutoHeight(width, maxHeight, padding, scale, shapes...)
+}
+
+// NewLayerWithBackground creates a layer filled with a solid, gradient or surface pattern.
+func NewLayerWithBackground(width, height int, p patterns.Pattern) *instructions.Layer {
+	return instructions.NewLayerWithBackground(width, height, p)
+}
+
+// Render draws root on a new layer of the given size, or fails on an invalid size.
+func Render(width, height int, root instructions.BoundedShape) (*instructions.Layer, error) {
+	return instructions.Render(width, height, root)
+}
+
+// RenderToPNG renders root like Render and saves the result as a PNG file.
+func RenderToPNG(width, height int, root instructions.BoundedShape, path string) error {
+	return instructions.RenderToPNG(width, height, root, path)
+}
+
+// Validate returns the configuration errors recorded on shapes and their children.
+func Validate(shapes ...instructions.Shape) error {
+	return instructions.Validate(shapes...)
+}
+
+// SplitLayers renders each shape onto its own cropped layer for a layered export.
+func SplitLayers(width, height int, scale float64, shapes ...instructions.Shape) []DocumentLayer {
+	return instructions.SplitLayers(width, height, scale, shapes...)
+}
+
+// ExportORA writes layers, bottom first, as an OpenRaster document editable in GIMP or Krita.
+func ExportORA(path string, width, height int, layers ...DocumentLayer) error {
+	return instructions.ExportORA(path, width, height, layers...)
+}
+
 // NewLayerFromImage wraps an existing image.Image into a Layer.
 func NewLayerFromImage(img image.Image) *instructions.Layer {
 	return instructions.NewLayerFromImage(img)
@@ -56,11 +160,26 @@ func NewLayerFromRGBA(rgba *image.RGBA) *instructions.Layer {
 	return instructions.NewLayerFromRGBA(rgba)
 }
 
-// NewLayerFromImagePath loads a PNG or JPEG image from a given file path and returns it as a Layer.
+// NewLayerFromImagePath loads a PNG, JPEG or WebP image from a given file path and returns it as a Layer.
 func NewLayerFromImagePath(path string) (*instructions.Layer, error) {
 	return instructions.NewLayerFromImagePath(path)
 }
 
+// NewLayerFromReader safely decodes an untrusted PNG, JPEG or WebP image within lim into a Layer.
+func NewLayerFromReader(r io.Reader, lim DecodeLimits) (*instructions.Layer, error) {
+	return instructions.NewLayerFromReader(r, lim)
+}
+
+// DefaultLimits returns the render limits server.NewHandler starts with.
+func DefaultLimits() Limits {
+	return instructions.DefaultLimits()
+}
+
+// DefaultDecodeLimits returns the decode limits used for images loaded from paths.
+func DefaultDecodeLimits() DecodeLimits {
+	return instructions.DefaultDecodeLimits()
+}
+
 // MustLoadLayerFromImagePath loads a Layer from an image file and panics on failure.
 func MustLoadLayerFromImagePath(path string) *instructions.Layer {
 	return instructions.MustLoadLayerFromImagePath(path)
@@ -123,6 +242,53 @@ func MustLoadFontFromBytes(data []byte, sizePt float64) *render.Font {
 	return render.MustLoadFontFromBytes(data, sizePt)
 }
 
+// DefaultFont returns the bundled default font (Go Regular) at the given size.
+func DefaultFont(sizePt float64) *render.Font {
+	return render.DefaultFont(sizePt)
+}
+
+// RegisterFont parses a font and registers it for NamedFont under name.
+func RegisterFont(name string, data []byte) error {
+	return render.RegisterFont(name, data)
+}
+
+// RegisterFontsFS registers the fonts of fsys matching pattern, such as an
+// embed.FS, by base name without extension.
+func RegisterFontsFS(fsys fs.FS, pattern string) error {
+	return render.RegisterFontsFS(fsys, pattern)
+}
+
+// NamedFont returns a registered font at the given size.
+func NamedFont(name string, sizePt float64) (*render.Font, error) {
+	return render.NamedFont(name, sizePt)
+}
+
+// NewFontRegistry returns an empty family/weight/style font registry.
+func NewFontRegistry() *render.FontRegistry {
+	return render.NewFontRegistry()
+}
+
+// DefaultFontRegistry returns the process-wide font registry used by
+// Text.SetFontSpec.
+func DefaultFontRegistry() *render.FontRegistry {
+	return render.DefaultFontRegistry()
+}
+
+// NewHyphenator builds a Hyphenator from TeX patterns and hyphenated exceptions.
+func NewHyphenator(patterns, exceptions []string) *render.Hyphenator {
+	return render.NewHyphenator(patterns, exceptions)
+}
+
+// ParseHyphenator reads a TeX hyphenation pattern file.
+func ParseHyphenator(r io.Reader) (*render.Hyphenator, error) {
+	return render.ParseHyphenator(r)
+}
+
+// RegisterHyphenator registers h for Text.SetHyphenation under lang.
+func RegisterHyphenator(lang string, h *render.Hyphenator) {
+	render.RegisterHyphenator(lang, h)
+}
+
 // SetFontCacheCapacity sets the maximum number of cached faces for memory management.
 func SetFontCacheCapacity(limit int) {
 	render.SetFontCacheCapacity(limit)
@@ -133,6 +299,30 @@ func ClearFontCache() {
 	render.ClearFontCache()
 }
 
+// SetMaskCacheCapacity sets the maximum number of rasterized text masks kept
+// for reuse across draws. Zero disables the cache.
+func SetMaskCacheCapacity(limit int) {
+	render.SetMaskCacheCapacity(limit)
+}
+
+// SetWordMaskCacheCapacity enables a cache of up to limit rasterized words,
+// from which text masks missing from the mask cache are assembled, so
+// templated renders re-rasterize only the words that change. Zero, the
+// default, disables it.
+func SetWordMaskCacheCapacity(limit int) {
+	render.SetWordMaskCacheCapacity(limit)
+}
+
+// FormatMessage formats an ICU MessageFormat message, with plural and select arguments, for a locale.
+func FormatMessage(locale, msg string, args map[string]any) (string, error) {
+	return render.FormatMessage(locale, msg, args)
+}
+
+// ClearMaskCache removes all cached text masks.
+func ClearMaskCache() {
+	render.ClearMaskCache()
+}
+
 //
 // Image Utilities
 //
@@ -143,3 +333,24 @@ func ClearFontCache() {
 func LoadImage(path string) (image.Image, error) {
 	return imageUtil.LoadImage(path)
 }
+
+// RegisterImage decodes an image and registers it for NamedImage under name.
+func RegisterImage(name string, data []byte) error {
+	return imageUtil.RegisterImage(name, data)
+}
+
+// RegisterImagesFS registers the images of fsys matching pattern, such as an
+// embed.FS, by base name without extension.
+func RegisterImagesFS(fsys fs.FS, pattern string) error {
+	return imageUtil.RegisterImagesFS(fsys, pattern)
+}
+
+// NamedImage returns a registered image. It is shared and must not be modified.
+func NamedImage(name string) (*image.RGBA, error) {
+	return imageUtil.NamedImage(name)
+}
+
+// ApplyPatch applies patches to the shapes with their IDs in scene.
+func ApplyPatch(scene instructions.Shape, patches []Patch) error {
+	return instructions.ApplyPatch(scene, patches)
+}
@@ -4,14 +4,15 @@ import (
 	"image"
 	"strings"
 
-	"github.com/Krispeckt/glimo/internal/core/image/patterns"
+	"github.com/Krispeckt/glimo/patterns"
 )
 
 //
 // Package Overview
 //
 // The `colors` package provides a high-level interface for constructing and composing
-// color and pattern objects from the internal `patterns` package.
+// color and pattern objects from the `patterns` package, which also holds the
+// Pattern interfaces for custom implementations.
 //
 // It exposes the main gradient, surface, and blending abstractions directly under
 // the `colors` namespace, allowing concise and readable use in client code.
@@ -44,6 +45,14 @@ type (
 	Solid = patterns.Solid
 	// Surface represents an image-based pattern that can be repeated or clamped.
 	Surface = patterns.Surface
+	// MaskedPattern modulates a pattern's alpha by another pattern's luminance.
+	MaskedPattern = patterns.MaskedPattern
+	// BlendOverride composites a pattern with a fixed blend mode.
+	BlendOverride = patterns.BlendOverride
+	// FuncPattern is a procedural pattern backed by a per-pixel callback.
+	FuncPattern = patterns.FuncPattern
+	// ColorStop is one color stop of a gradient.
+	ColorStop = patterns.ColorStop
 )
 
 //
@@ -69,6 +78,12 @@ func NewLinearGradient(x0, y0, x1, y1 float64) *patterns.LinearGradient {
 	return patterns.NewLinearGradient(x0, y0, x1, y1)
 }
 
+// NewLinearGradientAngle creates a linear gradient from a CSS-style angle
+// (0° up, 90° left to right) sized to the w×h box centered at (cx, cy).
+func NewLinearGradientAngle(cx, cy, w, h, angleDeg float64) *patterns.LinearGradient {
+	return patterns.NewLinearGradientAngle(cx, cy, w, h, angleDeg)
+}
+
 // NewLinearGradientWithBlend creates a linear gradient with a specific blend mode and opacity.
 func NewLinearGradientWithBlend(x0, y0, x1, y1 float64, blend patterns.BlendMode, opacity float64) *patterns.LinearGradient {
 	return patterns.NewLinearGradientWithBlend(x0, y0, x1, y1, blend, opacity)
@@ -104,6 +119,36 @@ func NewSurfaceWithBlend(img image.Image, repeat patterns.RepeatOp, blend patter
 	return patterns.NewSurfaceWithBlend(img, repeat, blend, opacity)
 }
 
+// NewFuncPattern creates a procedural pattern returning fn(x, y) for each pixel.
+func NewFuncPattern(fn func(x, y int) patterns.Color) *patterns.FuncPattern {
+	return patterns.NewFuncPattern(fn)
+}
+
+// NewFuncPatternWithBlend creates a procedural pattern with a blend mode and opacity.
+func NewFuncPatternWithBlend(fn func(x, y int) patterns.Color, blend patterns.BlendMode, opacity float64) *patterns.FuncPattern {
+	return patterns.NewFuncPatternWithBlend(fn, blend, opacity)
+}
+
+// WithAlphaMask returns p with its alpha modulated by the luminance of mask.
+func WithAlphaMask(p, mask patterns.Pattern) patterns.Pattern {
+	return patterns.WithAlphaMask(p, mask)
+}
+
+// WithBlendMode returns p composited with mode instead of its own blend mode.
+func WithBlendMode(p patterns.Pattern, mode patterns.BlendMode) patterns.Pattern {
+	return patterns.WithBlendMode(p, mode)
+}
+
+// LerpGradient interpolates two gradients of the same kind by t, e.g. between animation keyframes.
+func LerpGradient(a, b patterns.GradientPattern, t float64) patterns.GradientPattern {
+	return patterns.LerpGradient(a, b, t)
+}
+
+// LerpColorStops interpolates the offsets and colors of two stop lists by t.
+func LerpColorStops(a, b []patterns.ColorStop, t float64) []patterns.ColorStop {
+	return patterns.LerpColorStops(a, b, t)
+}
+
 //
 // Color Constructors
 //
@@ -132,6 +177,42 @@ func HSL(h, s, l float64) patterns.Color {
 	return patterns.ColorFromHSL(h, s, l, 255)
 }
 
+// HSV constructs an opaque Color from hue, saturation, and value.
+func HSV(h, s, v float64) patterns.Color {
+	return patterns.ColorFromHSV(h, s, v, 255)
+}
+
+// HWB constructs an opaque Color from hue, whiteness, and blackness.
+func HWB(h, w, b float64) patterns.Color {
+	return patterns.ColorFromHWB(h, w, b, 255)
+}
+
+// OKLCH constructs an opaque Color from perceptual lightness, chroma, and hue,
+// reducing chroma to fit colors outside sRGB.
+func OKLCH(l, c, h float64) patterns.Color {
+	return patterns.ColorFromOKLCH(l, c, h, 255)
+}
+
+// ContrastRatio returns the WCAG contrast ratio between two colors, in the range [1–21].
+func ContrastRatio(a, b patterns.Color) float64 {
+	return a.ContrastWith(b)
+}
+
+// BestTextColorOn returns the candidate most readable on bg, or white or black without candidates.
+func BestTextColorOn(bg patterns.Color, candidates ...patterns.Color) patterns.Color {
+	return patterns.BestTextColorOn(bg, candidates...)
+}
+
+// ExtractPalette returns up to n representative colors of img, most common first.
+func ExtractPalette(img image.Image, n int) []patterns.Color {
+	return patterns.ExtractPalette(img, n)
+}
+
+// DominantColor returns the most common color of img, e.g. to tint a card to match an avatar.
+func DominantColor(img image.Image) patterns.Color {
+	return patterns.DominantColor(img)
+}
+
 //
 // Surface Repetition Modes
 //
@@ -151,6 +232,16 @@ const (
 	SurfaceRepeatNone SurfaceRepeatOp = patterns.RepeatNone
 )
 
+// SurfaceFilter selects how an image-based pattern interpolates between texels.
+type SurfaceFilter = patterns.SurfaceFilter
+
+const (
+	// SurfaceFilterNearest picks the nearest texel.
+	SurfaceFilterNearest SurfaceFilter = patterns.FilterNearest
+	// SurfaceFilterBilinear blends the four nearest texels.
+	SurfaceFilterBilinear SurfaceFilter = patterns.FilterBilinear
+)
+
 //
 // Blend Modes
 //
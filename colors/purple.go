@@ -1,7 +1,7 @@
 package colors
 
 import (
-	"github.com/Krispeckt/glimo/internal/core/image/patterns"
+	"github.com/Krispeckt/glimo/patterns"
 )
 
 var (
@@ -0,0 +1,232 @@
+package colors_test
+
+import (
+	"image"
+	"image/draw"
+	_ "image/png"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/instructions"
+	"github.com/Krispeckt/glimo/patterns"
+	"github.com/stretchr/testify/require"
+)
+
+func mustLoadImage(t *testing.T, p string) image.Image {
+	t.Helper()
+	f, err := os.Open(p)
+	require.NoError(t, err)
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	img, _, err := image.Decode(f)
+	require.NoError(t, err)
+	return img
+}
+
+func TestColorContrastHelpers(t *testing.T) {
+	require.InDelta(t, 21, colors.ContrastRatio(colors.White, colors.Black), 1e-9)
+	require.InDelta(t, 1, colors.ContrastRatio(colors.Red, colors.Red), 1e-9)
+
+	yellow, navy := colors.RGB(250, 220, 60), colors.RGB(20, 30, 90)
+	require.Equal(t, colors.Black, colors.BestTextColorOn(yellow))
+	require.Equal(t, colors.White, colors.BestTextColorOn(navy))
+	require.Equal(t, navy, colors.BestTextColorOn(yellow, colors.White, navy, colors.Pumpkin))
+
+	// Lighten and Darken move along HSL lightness and keep alpha.
+	_, _, l := colors.Red.ToHSL()
+	_, _, up := colors.Red.Lighten(0.2).ToHSL()
+	_, _, down := colors.Red.Darken(0.2).ToHSL()
+	require.InDelta(t, l+0.2, up, 0.01)
+	require.InDelta(t, l-0.2, down, 0.01)
+	require.Equal(t, colors.White, colors.Red.Lighten(1))
+	require.Equal(t, uint8(128), colors.RGBA(200, 40, 40, 128).Darken(0.1).A)
+
+	// EnsureContrast changes the color just enough.
+	gray := colors.RGB(150, 150, 150)
+	fixed := gray.EnsureContrast(colors.White, 4.5)
+	require.GreaterOrEqual(t, fixed.ContrastWith(colors.White), 4.5)
+	require.Less(t, fixed.ContrastWith(colors.White), 4.6)
+	require.Less(t, fixed.R, gray.R, "darkened on white")
+	dim := colors.RGB(90, 90, 90)
+	require.Greater(t, dim.EnsureContrast(navy, 4.5).R, dim.R, "lightened on navy")
+	require.Equal(t, colors.Black, colors.Black.EnsureContrast(colors.White, 4.5))
+	require.Equal(t, colors.Black, gray.EnsureContrast(colors.White, 30), "unreachable ratio")
+}
+
+func TestExtractPalette(t *testing.T) {
+	// Red covers 60% of the opaque pixels, blue 30% and green 10%; the
+	// transparent strip is ignored.
+	img := image.NewRGBA(image.Rect(0, 0, 100, 120))
+	fill := func(x0, x1, y1 int, c patterns.Color) {
+		draw.Draw(img, image.Rect(x0, 0, x1, y1), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	fill(0, 60, 100, colors.RGB(220, 30, 40))
+	fill(60, 90, 100, colors.RGB(30, 60, 200))
+	fill(90, 100, 100, colors.RGB(40, 180, 60))
+
+	p := colors.ExtractPalette(img, 3)
+	require.Equal(t, []patterns.Color{
+		colors.RGB(220, 30, 40), colors.RGB(30, 60, 200), colors.RGB(40, 180, 60),
+	}, p)
+	require.Equal(t, colors.RGB(220, 30, 40), colors.DominantColor(img))
+
+	// Asking for fewer colors merges the smaller groups; asking for more
+	// than exist returns the distinct ones.
+	require.Len(t, colors.ExtractPalette(img, 2), 2)
+	require.Equal(t, colors.RGB(220, 30, 40), colors.ExtractPalette(img, 2)[0])
+	require.Len(t, colors.ExtractPalette(img, 16), 3)
+
+	require.Empty(t, colors.ExtractPalette(image.NewRGBA(image.Rect(0, 0, 10, 10)), 4))
+	require.Zero(t, colors.DominantColor(image.NewRGBA(image.Rect(0, 0, 10, 10))).A)
+	require.Empty(t, colors.ExtractPalette(img, 0))
+
+	// A photo yields distinct colors.
+	photo := colors.ExtractPalette(mustLoadImage(t, "../../instructions/tests/testdata/image.png"), 5)
+	require.Len(t, photo, 5)
+	for i := 1; i < len(photo); i++ {
+		require.NotContains(t, photo[:i], photo[i])
+	}
+}
+
+func TestColorSpaces(t *testing.T) {
+	h, s, v := colors.RGB(255, 128, 0).ToHSV()
+	require.InDelta(t, 30, h, 0.5)
+	require.InDelta(t, 1, s, 1e-9)
+	require.InDelta(t, 1, v, 1e-9)
+	require.Equal(t, colors.RGB(255, 128, 0), colors.HSV(30, 1, 1))
+	require.Equal(t, colors.RGB(0, 0, 0), colors.HSV(120, 1, 0))
+
+	h, w, b := colors.RGB(204, 102, 102).ToHWB()
+	require.InDelta(t, 0, h, 1e-9)
+	require.InDelta(t, 0.4, w, 0.01)
+	require.InDelta(t, 0.2, b, 0.01)
+	require.Equal(t, colors.RGB(128, 128, 128), colors.HWB(200, 0.5, 0.5), "gray when w+b >= 1")
+
+	// OKLab reference values for pure red and white.
+	l, a, bb := colors.Red.ToOKLab()
+	require.InDelta(t, 0.6279, l, 1e-3)
+	require.InDelta(t, 0.2249, a, 1e-3)
+	require.InDelta(t, 0.1258, bb, 1e-3)
+	l, c, _ := colors.White.ToOKLCH()
+	require.InDelta(t, 1, l, 1e-3)
+	require.Zero(t, c)
+
+	// Every conversion round-trips.
+	for _, col := range []patterns.Color{colors.RGB(12, 200, 99), colors.RGB(250, 220, 60), colors.RGBA(20, 30, 90, 128)} {
+		h, s, v := col.ToHSV()
+		require.Equal(t, col, patterns.ColorFromHSV(h, s, v, col.A))
+		h, w, b := col.ToHWB()
+		require.Equal(t, col, patterns.ColorFromHWB(h, w, b, col.A))
+		l, a, b := col.ToOKLab()
+		require.Equal(t, col, patterns.ColorFromOKLab(l, a, b, col.A))
+		l, c, h := col.ToOKLCH()
+		require.Equal(t, col, patterns.ColorFromOKLCH(l, c, h, col.A))
+	}
+
+	// Out-of-gamut chroma is reduced, keeping the hue.
+	vivid := colors.OKLCH(0.7, 0.5, 150)
+	_, c, h = vivid.ToOKLCH()
+	require.Less(t, c, 0.5)
+	require.InDelta(t, 150, h, 2)
+
+	// Rotating the hue keeps perceived lightness, unlike HSL.
+	accent := colors.RGB(40, 120, 220)
+	l0, _, h0 := accent.ToOKLCH()
+	l1, _, h1 := accent.RotateHue(30).ToOKLCH()
+	require.InDelta(t, l0, l1, 0.01)
+	require.InDelta(t, math.Mod(h0+30, 360), h1, 1.5)
+	require.Equal(t, colors.White, colors.White.RotateHue(90))
+}
+
+func TestParseCSSColor(t *testing.T) {
+	for in, want := range map[string]patterns.Color{
+		"#aabbcc":                     colors.RGB(0xAA, 0xBB, 0xCC),
+		"#abc8":                       colors.RGBA(0xAA, 0xBB, 0xCC, 0x88),
+		"RebeccaPurple":               colors.RGB(0x66, 0x33, 0x99),
+		" navy ":                      colors.RGB(0, 0, 0x80),
+		"transparent":                 colors.Transparent,
+		"rgb(12,34,56)":               colors.RGB(12, 34, 56),
+		"rgba(12, 34, 56, 0.5)":       colors.RGBA(12, 34, 56, 128),
+		"rgb(12 34 56 / 50%)":         colors.RGBA(12, 34, 56, 128),
+		"rgb(100% 50% 0%)":            colors.RGB(255, 128, 0),
+		"rgb(300, -5, 0)":             colors.RGB(255, 0, 0),
+		"hsl(210 40% 50% / .5)":       colors.RGBA(77, 128, 179, 128),
+		"hsl(210, 40%, 50%)":          colors.RGB(77, 128, 179),
+		"hsla(0.5turn, 100%, 50%, 1)": colors.RGB(0, 255, 255),
+		"hsl(-120deg 100% 50%)":       colors.RGB(0, 0, 255),
+	} {
+		got, err := colors.Parse(in)
+		require.NoError(t, err, in)
+		require.Equal(t, want, got, in)
+	}
+	for _, in := range []string{"", "notacolor", "#12345", "rgb(1, 2)", "rgb(1 2 3 /)", "hsl(a, b, c)", "cmyk(1, 2, 3)", "rgb(1, 2, 3"} {
+		_, err := colors.Parse(in)
+		require.Error(t, err, in)
+	}
+	require.Equal(t, colors.RGB(0xFF, 0x7F, 0x50), colors.MustParse("coral"))
+	require.Panics(t, func() { colors.MustParse("nope") })
+}
+
+func TestBlendFastPaths(t *testing.T) {
+	// Opaque Normal over anything is the source itself, for every level.
+	for v := 0; v < 256; v++ {
+		c := colors.RGB(uint8(v), uint8(255-v), uint8(v/2))
+		require.Equal(t, c, c.BlendOver(colors.RGB(9, 99, 199), 1))
+	}
+
+	bg := colors.RGBA(200, 120, 40, 220)
+	for _, mode := range []patterns.BlendMode{patterns.BlendNormal, patterns.BlendMultiply, patterns.BlendScreen} {
+		for _, op := range []float64{1, 0.6, 0.2} {
+			src := colors.RGBA(30, 160, 250, 180).SetBlendMode(mode)
+			fast := src.BlendOver(bg, op)
+			exact := src.BlendOverAccurate(bg, op)
+			for i, ch := range [][2]uint8{{fast.R, exact.R}, {fast.G, exact.G}, {fast.B, exact.B}, {fast.A, exact.A}} {
+				require.InDelta(t, ch[1], ch[0], 1, "%s op=%v channel %d", mode, op, i)
+			}
+		}
+	}
+
+	// Screen never darkens and Multiply never brightens.
+	base, top := colors.RGB(100, 50, 200), colors.RGB(120, 120, 120)
+	s := top.SetBlendMode(patterns.BlendScreen).BlendOver(base, 1)
+	m := top.SetBlendMode(patterns.BlendMultiply).BlendOver(base, 1)
+	require.GreaterOrEqual(t, s.R, base.R)
+	require.GreaterOrEqual(t, s.B, base.B)
+	require.LessOrEqual(t, m.R, base.R)
+	require.LessOrEqual(t, m.B, base.B)
+
+	// Accuracy is chosen per Layer: find a Multiply the fast path rounds
+	// differently and check each Layer draws its own result.
+	under := colors.RGB(200, 120, 40)
+	var src patterns.Color
+	found := false
+	for v := 0; v < 256 && !found; v++ {
+		src = colors.RGBA(uint8(v), uint8(v), uint8(v), 128).SetBlendMode(patterns.BlendMultiply)
+		found = src.BlendOver(under, 1) != src.BlendOverAccurate(under, 1)
+	}
+	require.True(t, found)
+	draw := func(accurate bool, sh instructions.Shape) patterns.Color {
+		l := instructions.NewLayer(4, 4).SetAccurateBlending(accurate)
+		l.FillBackground(patterns.NewSolid(under))
+		l.LoadInstruction(sh)
+		c := l.Image().RGBAAt(2, 2)
+		return colors.RGB(c.R, c.G, c.B)
+	}
+	rect := instructions.NewRectangle(0, 0, 4, 4).SetLineWidth(0).
+		SetFillPattern(patterns.NewSolidWithBlend(src, patterns.BlendMultiply, 1))
+	custom := instructions.ContextFunc(func(ctx *instructions.DrawContext) {
+		ctx.Blend(2, 2, src, 1)
+	})
+	for _, sh := range []instructions.Shape{rect, custom} {
+		want := src.BlendOver(under, 1)
+		require.Equal(t, colors.RGB(want.R, want.G, want.B), draw(false, sh))
+		want = src.BlendOverAccurate(under, 1)
+		require.Equal(t, colors.RGB(want.R, want.G, want.B), draw(true, sh))
+	}
+	require.True(t, instructions.NewLayer(1, 1).SetAccurateBlending(true).Clone().AccurateBlending())
+}
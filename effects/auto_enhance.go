@@ -0,0 +1,192 @@
+// Package effects implements pre- and post-render effects for 2D drawing instructions.
+// This file defines an automatic photo correction similar to the “Auto” button
+// of photo editors, meant for user-uploaded pictures of unknown quality.
+//
+// Algorithm summary
+//
+//  1. Optionally estimate a white balance correction with the gray-world
+//     assumption: the average of a scene is neutral gray, so each channel is
+//     scaled to bring its mean to the mean of all three.
+//  2. Build one histogram of the (balanced) R, G and B values of visible
+//     pixels, weighted by alpha, and find the levels below and above which
+//     `clip` of the values lie.
+//  3. Stretch those levels to the full 0–255 range. The same levels are used
+//     for every channel, so the stretch adds contrast without shifting hues.
+//  4. Blend the result with the original by `strength`.
+//
+// The alpha channel remains unchanged. The effect is post-applied (IsPre() == false).
+//
+// Notes:
+//   - Deterministic; both passes are O(W×H) and the per-pixel work is a table lookup.
+//   - Flat images (all values within one level) are left untouched by the stretch.
+package effects
+
+import (
+	"image"
+	"math"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+)
+
+// AutoEnhanceEffect stretches the levels of a layer from its histogram and
+// optionally corrects its white balance.
+type AutoEnhanceEffect struct {
+	clip         float64 // fraction of values clipped at each end, [0, 0.5)
+	whiteBalance bool    // apply gray-world white balance first
+	strength     float64 // blend with the original, [0, 1]
+}
+
+// NewAutoEnhanceEffect creates an AutoEnhanceEffect that clips 0.5% of the
+// values at each end of the histogram, without white balance, at full strength.
+//
+// Example:
+//
+//	photo.AddEffect(effects.NewAutoEnhanceEffect().SetWhiteBalance(true))
+func NewAutoEnhanceEffect() *AutoEnhanceEffect {
+	return &AutoEnhanceEffect{clip: 0.005, strength: 1}
+}
+
+// SetClip sets the percentage of values, in [0, 49], treated as outliers at
+// each end of the histogram. Higher values give punchier contrast.
+// Returns the receiver for chaining.
+func (e *AutoEnhanceEffect) SetClip(percent float64) *AutoEnhanceEffect {
+	e.clip = geom.ClampF64(percent, 0, 49) / 100
+	return e
+}
+
+// SetWhiteBalance enables gray-world white balance correction.
+// Returns the receiver for chaining.
+func (e *AutoEnhanceEffect) SetWhiteBalance(enabled bool) *AutoEnhanceEffect {
+	e.whiteBalance = enabled
+	return e
+}
+
+// SetStrength sets how much of the correction is applied in [0, 1].
+// 0 disables the effect, 1 applies it fully.
+// Returns the receiver for chaining.
+func (e *AutoEnhanceEffect) SetStrength(v float64) *AutoEnhanceEffect {
+	e.strength = geom.ClampF64(v, 0, 1)
+	return e
+}
+
+// Name returns the effect identifier.
+// Implements the Effect interface.
+func (e *AutoEnhanceEffect) Name() string {
+	return "AutoEnhance"
+}
+
+// IsPre indicates whether the effect should be applied before drawing.
+// AutoEnhanceEffect is always post-applied, so this returns false.
+func (e *AutoEnhanceEffect) IsPre() bool {
+	return false
+}
+
+// Apply analyzes the visible pixels of dst and corrects them in place.
+// Fully transparent pixels are neither measured nor changed.
+func (e *AutoEnhanceEffect) Apply(dst *image.RGBA) {
+	if e.strength == 0 {
+		return
+	}
+	b := dst.Bounds()
+
+	// visit calls fn with the straight (un-premultiplied) color of every
+	// visible pixel, its alpha weight and its offset in Pix.
+	visit := func(fn func(c [3]float64, w float64, i int)) {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				i := dst.PixOffset(x, y)
+				a := dst.Pix[i+3]
+				if a == 0 {
+					continue
+				}
+				k := 255 / float64(a)
+				fn([3]float64{
+					float64(dst.Pix[i]) * k,
+					float64(dst.Pix[i+1]) * k,
+					float64(dst.Pix[i+2]) * k,
+				}, float64(a)/255, i)
+			}
+		}
+	}
+
+	gain := [3]float64{1, 1, 1}
+	if e.whiteBalance {
+		var sum [3]float64
+		var total float64
+		visit(func(c [3]float64, w float64, _ int) {
+			for ch := range c {
+				sum[ch] += c[ch] * w
+			}
+			total += w
+		})
+		if total == 0 {
+			return
+		}
+		gray := (sum[0] + sum[1] + sum[2]) / 3
+		for ch := range gain {
+			if sum[ch] > 0 {
+				// Limit the correction so a genuinely colorful scene is not neutralized.
+				gain[ch] = geom.ClampF64(gray/sum[ch], 0.5, 2)
+			}
+		}
+	}
+
+	var hist [256]float64
+	var total float64
+	visit(func(c [3]float64, w float64, _ int) {
+		for ch := range c {
+			hist[clampByte(c[ch]*gain[ch])] += w
+		}
+		total += 3 * w
+	})
+	if total == 0 {
+		return
+	}
+	lo, hi := histogramLevels(&hist, total, e.clip)
+
+	// One lookup table per channel: balance, stretch, then blend by strength.
+	var lut [3][256]float64
+	for ch := range lut {
+		for v := range lut[ch] {
+			out := float64(v) * gain[ch]
+			if hi > lo {
+				out = (out - lo) * 255 / (hi - lo)
+			}
+			lut[ch][v] = geom.Lerp(float64(v), geom.ClampF64(out, 0, 255), e.strength)
+		}
+	}
+
+	visit(func(c [3]float64, w float64, i int) {
+		for ch := range c {
+			v := lut[ch][clampByte(c[ch])]
+			dst.Pix[i+ch] = clampByte(v * w)
+		}
+	})
+}
+
+// histogramLevels returns the values below and above which frac of the
+// histogram's weight lies.
+func histogramLevels(hist *[256]float64, total, frac float64) (lo, hi float64) {
+	var acc float64
+	for v := 0; v < 256; v++ {
+		acc += hist[v]
+		if acc > total*frac {
+			lo = float64(v)
+			break
+		}
+	}
+	acc = 0
+	for v := 255; v >= 0; v-- {
+		acc += hist[v]
+		if acc > total*frac {
+			hi = float64(v)
+			break
+		}
+	}
+	return lo, hi
+}
+
+// clampByte rounds v to the nearest integer in [0, 255].
+func clampByte(v float64) uint8 {
+	return uint8(geom.ClampF64(math.Round(v), 0, 255))
+}
@@ -0,0 +1,117 @@
+package effects
+
+import "math"
+
+// CostEstimator is implemented by effects that can predict the resources
+// they need on a w×h layer, so renders can be sized up before allocating.
+type CostEstimator interface {
+	// EstimateCost returns the bytes of temporary buffers the effect holds at
+	// once and an approximate count of elementary per-pixel operations.
+	EstimateCost(w, h int) (tempBytes, ops int64)
+}
+
+// EstimateCost predicts the temporary bytes and operations e needs on a w×h
+// layer. Effects that do not implement CostEstimator are assumed to copy the
+// layer once and do a small constant amount of work per pixel.
+func EstimateCost(e Effect, w, h int) (tempBytes, ops int64) {
+	if e == nil || w <= 0 || h <= 0 {
+		return 0, 0
+	}
+	if c, ok := e.(CostEstimator); ok {
+		return c.EstimateCost(w, h)
+	}
+	px := int64(w) * int64(h)
+	return px * 4, px * 16
+}
+
+// boxBlurCost returns the scratch bytes and operations of boxBlur over px
+// pixels: three horizontal and vertical passes over 2·radius+1 taps.
+func boxBlurCost(px int64, radius float64) (tempBytes, ops int64) {
+	r := int64(math.Max(1, math.Round(radius)))
+	return px * 4, px * 6 * (2*r + 1)
+}
+
+// EstimateCost implements CostEstimator: a copy of the layer plus the blur.
+func (e *LayerBlurEffect) EstimateCost(w, h int) (tempBytes, ops int64) {
+	px := int64(w) * int64(h)
+	tmp, ops := boxBlurCost(px, math.Max(e.radiusStart, e.radiusEnd))
+	return px*4 + tmp, ops
+}
+
+// EstimateCost implements CostEstimator: alpha masks for the spread and
+// blur, the tinted shadow and a copy of the layer.
+func (e *DropShadowEffect) EstimateCost(w, h int) (tempBytes, ops int64) {
+	px := int64(w) * int64(h)
+	s := int64(math.Round(e.spread))
+	_, ops = boxBlurCost(px, e.blur)
+	return px*3 + px*8, ops + px*(2*s+1)*(2*s+1)
+}
+
+// EstimateCost implements CostEstimator: the inverted and shifted masks
+// plus the blur.
+func (e *InnerShadowEffect) EstimateCost(w, h int) (tempBytes, ops int64) {
+	px := int64(w) * int64(h)
+	tmp, ops := boxBlurCost(px, e.blur)
+	return px*8 + tmp, ops
+}
+
+// EstimateCost implements CostEstimator: the original and one blurred copy
+// per level, each with its own blur.
+func (e *FocusBlurEffect) EstimateCost(w, h int) (tempBytes, ops int64) {
+	px := int64(w) * int64(h)
+	tmp, _ := boxBlurCost(px, e.radius)
+	for i := 1; i <= focusLevels; i++ {
+		_, o := boxBlurCost(px, e.radius*float64(i)/focusLevels)
+		ops += o
+	}
+	return px*4*(focusLevels+1) + tmp, ops
+}
+
+// EstimateCost implements CostEstimator: a copy and a blurred copy.
+func (e *SharpenEffect) EstimateCost(w, h int) (tempBytes, ops int64) {
+	px := int64(w) * int64(h)
+	tmp, ops := boxBlurCost(px, e.radius)
+	return px*8 + tmp, ops
+}
+
+// EstimateCost implements CostEstimator: a copy and a blurred copy.
+func (e *ClarityEffect) EstimateCost(w, h int) (tempBytes, ops int64) {
+	px := int64(w) * int64(h)
+	tmp, ops := boxBlurCost(px, e.radius)
+	return px*8 + tmp, ops
+}
+
+// EstimateCost implements CostEstimator: a copy and the cell average.
+func (e *HalftoneEffect) EstimateCost(w, h int) (tempBytes, ops int64) {
+	px := int64(w) * int64(h)
+	tmp, ops := boxBlurCost(px, e.cell/2)
+	return px*8 + tmp, ops
+}
+
+// Radiused is implemented by effects whose work grows with a sampling
+// radius, such as blurs, so services can cap it (see instructions.Limits).
+type Radiused interface {
+	// Radius returns the largest distance, in pixels, the effect samples over.
+	Radius() float64
+}
+
+// Radius implements Radiused: the larger of the start and end radii.
+func (e *LayerBlurEffect) Radius() float64 { return math.Max(e.radiusStart, e.radiusEnd) }
+
+// Radius implements Radiused: the blur plus the spread.
+func (e *DropShadowEffect) Radius() float64 { return e.blur + e.spread }
+
+// Radius implements Radiused: the blur radius.
+func (e *InnerShadowEffect) Radius() float64 { return e.blur }
+
+// Radius implements Radiused: the blur radius at full strength.
+func (e *FocusBlurEffect) Radius() float64 { return e.radius }
+
+// Radius implements Radiused: the detail blur radius.
+func (e *SharpenEffect) Radius() float64 { return e.radius }
+
+// Radius implements Radiused: the neighbourhood radius.
+func (e *ClarityEffect) Radius() float64 { return e.radius }
+
+// Radius implements Radiused: half the screen period.
+func (e *HalftoneEffect) Radius() float64 { return e.cell / 2 }
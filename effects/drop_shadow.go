@@ -110,12 +110,12 @@ func (e *DropShadowEffect) Apply(dst *image.RGBA) {
 		mask = fastBlurAlpha(mask, int(math.Round(e.blur)))
 	}
 
-	const m = 1<<16 - 1
-	r16, g16, b16, a16 := e.color.RGBA()
-	cr := float64(r16) / m
-	cg := float64(g16) / m
-	cb := float64(b16) / m
-	ca := float64(a16) / m * e.opacity
+	// Use the straight color: its alpha is applied once, with the mask.
+	c := color.NRGBAModel.Convert(e.color).(color.NRGBA)
+	cr := float64(c.R) / 255
+	cg := float64(c.G) / 255
+	cb := float64(c.B) / 255
+	ca := float64(c.A) / 255 * e.opacity
 
 	// Build tinted shadow image with premultiplied RGB
 	shadow := image.NewRGBA(b)
@@ -0,0 +1,161 @@
+// Package effects implements pre- and post-render effects for 2D drawing instructions.
+// This file defines a halftone effect that redraws a layer as a print screen
+// of dots, squares or lines whose size follows the local luminance.
+//
+// Algorithm summary
+//
+//  1. Average the layer's luminance over each screen cell with a box blur.
+//  2. Rotate every pixel into screen space by `angle` and find the cell it
+//     belongs to; the cell's darkness sampled at its center sets the size of
+//     the cell's mark so that the inked area matches the darkness.
+//  3. Paint ink over paper by the pixel's coverage of the mark, anti-aliased
+//     over one pixel.
+//
+// The alpha of the layer is kept. The effect is post-applied (IsPre() == false).
+package effects
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/draw"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+	"github.com/Krispeckt/glimo/patterns"
+)
+
+// HalftoneShape selects the mark drawn in each screen cell.
+type HalftoneShape int
+
+const (
+	// HalftoneDot draws round dots, the classic newspaper look.
+	HalftoneDot HalftoneShape = iota
+	// HalftoneSquare draws squares aligned with the screen.
+	HalftoneSquare
+	// HalftoneLine draws lines along the screen angle whose thickness
+	// follows the darkness.
+	HalftoneLine
+)
+
+// HalftoneEffect redraws a layer as a halftone screen.
+type HalftoneEffect struct {
+	cell       float64 // screen period in pixels
+	angle      float64 // screen rotation in radians
+	shape      HalftoneShape
+	ink, paper patterns.Color
+}
+
+// NewHalftoneEffect creates a halftone with cells of cellSize pixels, round
+// black dots on white paper and the screen at 45°.
+//
+// Example:
+//
+//	photo.AddEffect(effects.NewHalftoneEffect(8).SetShape(effects.HalftoneLine).SetAngle(30))
+func NewHalftoneEffect(cellSize float64) *HalftoneEffect {
+	return &HalftoneEffect{
+		cell:  math.Max(cellSize, 2),
+		angle: math.Pi / 4,
+		shape: HalftoneDot,
+		ink:   patterns.Color{A: 255},
+		paper: patterns.Color{R: 255, G: 255, B: 255, A: 255},
+	}
+}
+
+// SetAngle rotates the screen clockwise by deg degrees.
+// Returns the receiver for chaining.
+func (e *HalftoneEffect) SetAngle(deg float64) *HalftoneEffect {
+	e.angle = geom.NormalizeAngle(deg) * math.Pi / 180
+	return e
+}
+
+// SetShape selects the mark drawn in each cell.
+// Returns the receiver for chaining.
+func (e *HalftoneEffect) SetShape(s HalftoneShape) *HalftoneEffect {
+	e.shape = s
+	return e
+}
+
+// SetColors sets the ink and paper colors. A transparent paper leaves only
+// the marks. Returns the receiver for chaining.
+func (e *HalftoneEffect) SetColors(ink, paper patterns.Color) *HalftoneEffect {
+	e.ink, e.paper = ink, paper
+	return e
+}
+
+// Name returns the effect identifier.
+// Implements the Effect interface.
+func (e *HalftoneEffect) Name() string {
+	return "Halftone"
+}
+
+// IsPre indicates whether the effect should be applied before drawing.
+// HalftoneEffect is always post-applied, so this returns false.
+func (e *HalftoneEffect) IsPre() bool {
+	return false
+}
+
+// Apply replaces dst with its halftone in place.
+func (e *HalftoneEffect) Apply(dst *image.RGBA) {
+	b := dst.Bounds()
+	if b.Empty() {
+		return
+	}
+	orig := image.NewRGBA(b)
+	draw.Copy(orig, b.Min, dst, b, draw.Src, nil)
+	avg := image.NewRGBA(b)
+	boxBlur(avg, orig, int(math.Max(1, math.Round(e.cell/2))))
+
+	// darkness returns 1 - luminance of the averaged layer near canvas (x, y).
+	darkness := func(x, y float64) float64 {
+		px := geom.ClampInt(int(math.Floor(x)), b.Min.X, b.Max.X-1)
+		py := geom.ClampInt(int(math.Floor(y)), b.Min.Y, b.Max.Y-1)
+		i := avg.PixOffset(px, py)
+		a := float64(avg.Pix[i+3])
+		if a == 0 {
+			return 0
+		}
+		l := (0.2126*float64(avg.Pix[i]) + 0.7152*float64(avg.Pix[i+1]) + 0.0722*float64(avg.Pix[i+2])) / a
+		return geom.ClampF64(1-l, 0, 1)
+	}
+
+	sin, cos := math.Sincos(e.angle)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			o := dst.PixOffset(x, y)
+			a := float64(orig.Pix[orig.PixOffset(x, y)+3]) / 255
+			if a == 0 {
+				continue
+			}
+
+			// Screen coordinates of the pixel center, and of its cell's center.
+			fx, fy := float64(x)+0.5, float64(y)+0.5
+			u, v := fx*cos+fy*sin, -fx*sin+fy*cos
+			cu := (math.Floor(u/e.cell) + 0.5) * e.cell
+			cv := (math.Floor(v/e.cell) + 0.5) * e.cell
+			d := darkness(cu*cos-cv*sin, cu*sin+cv*cos)
+
+			var cover float64
+			switch e.shape {
+			case HalftoneSquare:
+				half := e.cell * math.Sqrt(d) / 2
+				cover = half - math.Max(math.Abs(u-cu), math.Abs(v-cv)) + 0.5
+			case HalftoneLine:
+				cover = d*e.cell/2 - math.Abs(v-cv) + 0.5
+			default:
+				// Dots grow until they touch at 78.5% and merge past it.
+				r := e.cell * math.Sqrt(d/math.Pi)
+				cover = r - math.Hypot(u-cu, v-cv) + 0.5
+			}
+			if d == 0 {
+				cover = 0
+			}
+
+			c := e.ink.BlendOver(e.paper, geom.ClampF64(cover, 0, 1))
+			ca := float64(c.A) / 255 * a
+			dst.Pix[o+0] = uint8(math.Round(float64(c.R) * ca))
+			dst.Pix[o+1] = uint8(math.Round(float64(c.G) * ca))
+			dst.Pix[o+2] = uint8(math.Round(float64(c.B) * ca))
+			dst.Pix[o+3] = uint8(math.Round(ca * 255))
+		}
+	}
+}
@@ -54,7 +54,7 @@ import (
 	"math"
 
 	"github.com/Krispeckt/glimo/internal/core/geom"
-	"github.com/Krispeckt/glimo/internal/core/image/patterns"
+	"github.com/Krispeckt/glimo/patterns"
 	"golang.org/x/image/draw"
 )
 
@@ -152,14 +152,15 @@ func (e *InnerShadowEffect) Apply(dst *image.RGBA) {
 			i := dst.PixOffset(x, y)
 			sa := float64(shifted.Pix[i+3]) / 255.0 // shadow mask
 			aa := float64(dst.Pix[i+3]) / 255.0     // original alpha
-			a := sa * aa * op                       // final blend factor
-			if a <= 0 {
+			a := sa * op                            // final blend factor
+			if a <= 0 || aa <= 0 {
 				continue
 			}
-			// Blend toward shadow color without changing alpha.
-			dst.Pix[i+0] = uint8(geom.Lerp(float64(dst.Pix[i+0]), cr, a))
-			dst.Pix[i+1] = uint8(geom.Lerp(float64(dst.Pix[i+1]), cg, a))
-			dst.Pix[i+2] = uint8(geom.Lerp(float64(dst.Pix[i+2]), cb, a))
+			// Blend toward the shadow color premultiplied by the pixel alpha,
+			// so alpha is unchanged and translucent edges stay valid.
+			dst.Pix[i+0] = uint8(math.Round(geom.Lerp(float64(dst.Pix[i+0]), cr*aa, a)))
+			dst.Pix[i+1] = uint8(math.Round(geom.Lerp(float64(dst.Pix[i+1]), cg*aa, a)))
+			dst.Pix[i+2] = uint8(math.Round(geom.Lerp(float64(dst.Pix[i+2]), cb*aa, a)))
 		}
 	}
 }
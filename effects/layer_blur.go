@@ -235,10 +235,10 @@ func boxBlurLine(dst, src *image.RGBA, radius, lineY int) {
 	}
 }
 
-// applyOpacity scales all alpha channel values by the given factor.
+// applyOpacity fades every pixel by the given factor.
 //
 // This provides a simple way to fade the entire blurred layer without
-// re-rendering. Alpha is multiplied by factor ∈ [0,1].
+// re-rendering. All premultiplied channels are multiplied by factor ∈ [0,1].
 func applyOpacity(img *image.RGBA, alpha float64) {
 	if alpha >= 1.0 {
 		return
@@ -246,8 +246,11 @@ func applyOpacity(img *image.RGBA, alpha float64) {
 	b := img.Bounds()
 	for y := b.Min.Y; y < b.Max.Y; y++ {
 		for x := b.Min.X; x < b.Max.X; x++ {
+			// Pixels are premultiplied, so color fades with alpha.
 			i := img.PixOffset(x, y)
-			img.Pix[i+3] = uint8(float64(img.Pix[i+3]) * alpha)
+			for ch := 0; ch < 4; ch++ {
+				img.Pix[i+ch] = uint8(math.Round(float64(img.Pix[i+ch]) * alpha))
+			}
 		}
 	}
 }
@@ -0,0 +1,87 @@
+package effects
+
+import (
+	"image/color"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+	"github.com/Krispeckt/glimo/patterns"
+)
+
+// This file holds interpolation helpers for keyframed effects: each one
+// returns a new effect between a (t = 0) and b (t = 1), with t clamped to
+// [0, 1]. Numeric parameters move linearly, colors mix as patterns.Color.Mix
+// does, and switches change at t = 0.5. A nil shadow stands for the other
+// end fully transparent, so a shadow can fade in or out.
+
+// LerpDropShadow interpolates offset, blur, spread, color and opacity of
+// two drop shadows.
+func LerpDropShadow(a, b *DropShadowEffect, t float64) *DropShadowEffect {
+	switch {
+	case a == nil && b == nil:
+		return nil
+	case a == nil:
+		c := *b
+		c.opacity = 0
+		a = &c
+	case b == nil:
+		c := *a
+		c.opacity = 0
+		b = &c
+	}
+	t = geom.ClampF64(t, 0, 1)
+	return NewDropShadow(
+		geom.Lerp(a.x, b.x, t), geom.Lerp(a.y, b.y, t),
+		geom.Lerp(a.blur, b.blur, t), geom.Lerp(a.spread, b.spread, t),
+		lerpColor(a.color, b.color, t),
+		geom.Lerp(a.opacity, b.opacity, t),
+	)
+}
+
+// LerpInnerShadow interpolates offset, blur, color and opacity of two inner
+// shadows.
+func LerpInnerShadow(a, b *InnerShadowEffect, t float64) *InnerShadowEffect {
+	switch {
+	case a == nil && b == nil:
+		return nil
+	case a == nil:
+		c := *b
+		c.opacity = 0
+		a = &c
+	case b == nil:
+		c := *a
+		c.opacity = 0
+		b = &c
+	}
+	t = geom.ClampF64(t, 0, 1)
+	return NewInnerShadowEffect(
+		geom.Lerp(a.offsetX, b.offsetX, t), geom.Lerp(a.offsetY, b.offsetY, t),
+		geom.Lerp(a.blur, b.blur, t),
+		a.color.Mix(b.color, t),
+	).SetOpacity(geom.Lerp(a.opacity, b.opacity, t))
+}
+
+// LerpLayerBlur interpolates the radii and opacity of two layer blurs. The
+// result is progressive if either end is, with a constant blur taken as
+// equal start and end radii. A nil end stands for no blur.
+func LerpLayerBlur(a, b *LayerBlurEffect, t float64) *LayerBlurEffect {
+	switch {
+	case a == nil && b == nil:
+		return nil
+	case a == nil:
+		a = NewLayerBlurEffect(0)
+	case b == nil:
+		b = NewLayerBlurEffect(0)
+	}
+	t = geom.ClampF64(t, 0, 1)
+	e := NewLayerBlurEffect(geom.Lerp(a.radiusStart, b.radiusStart, t)).
+		SetOpacity(geom.Lerp(a.opacity, b.opacity, t))
+	if a.progressive || b.progressive {
+		e.SetProgressive(e.radiusStart, geom.Lerp(a.radiusEnd, b.radiusEnd, t))
+	}
+	return e
+}
+
+// lerpColor mixes two shadow colors given as any color.Color.
+func lerpColor(a, b color.Color, t float64) patterns.Color {
+	return patterns.NewColorFromStd(a).Mix(patterns.NewColorFromStd(b), t)
+}
@@ -30,7 +30,7 @@ import (
 	"math/rand"
 
 	"github.com/Krispeckt/glimo/internal/core/geom"
-	"github.com/Krispeckt/glimo/internal/core/image/patterns"
+	"github.com/Krispeckt/glimo/patterns"
 )
 
 // NoiseType defines the noise generation mode.
@@ -126,15 +126,18 @@ func (e *NoiseEffect) Apply(dst *image.RGBA) {
 				continue
 			}
 
+			// Noise colors are premultiplied by the pixel alpha, so
+			// transparent pixels stay transparent.
 			i := dst.PixOffset(x, y)
+			aa := float64(dst.Pix[i+3]) / 255
 			switch e.noiseType {
 
 			case NoiseMono:
 				// Grayscale noise
 				v := uint8(rand.Intn(256))
-				dst.Pix[i+0] = uint8(geom.Lerp(float64(dst.Pix[i+0]), float64(v), e.opacity))
-				dst.Pix[i+1] = uint8(geom.Lerp(float64(dst.Pix[i+1]), float64(v), e.opacity))
-				dst.Pix[i+2] = uint8(geom.Lerp(float64(dst.Pix[i+2]), float64(v), e.opacity))
+				dst.Pix[i+0] = uint8(geom.Lerp(float64(dst.Pix[i+0]), float64(v)*aa, e.opacity))
+				dst.Pix[i+1] = uint8(geom.Lerp(float64(dst.Pix[i+1]), float64(v)*aa, e.opacity))
+				dst.Pix[i+2] = uint8(geom.Lerp(float64(dst.Pix[i+2]), float64(v)*aa, e.opacity))
 
 			case NoiseDuo:
 				// Two-color noise
@@ -142,9 +145,9 @@ func (e *NoiseEffect) Apply(dst *image.RGBA) {
 				if rand.Intn(2) == 1 {
 					c = e.colorB
 				}
-				dst.Pix[i+0] = uint8(geom.Lerp(float64(dst.Pix[i+0]), float64(c.R), e.opacity))
-				dst.Pix[i+1] = uint8(geom.Lerp(float64(dst.Pix[i+1]), float64(c.G), e.opacity))
-				dst.Pix[i+2] = uint8(geom.Lerp(float64(dst.Pix[i+2]), float64(c.B), e.opacity))
+				dst.Pix[i+0] = uint8(geom.Lerp(float64(dst.Pix[i+0]), float64(c.R)*aa, e.opacity))
+				dst.Pix[i+1] = uint8(geom.Lerp(float64(dst.Pix[i+1]), float64(c.G)*aa, e.opacity))
+				dst.Pix[i+2] = uint8(geom.Lerp(float64(dst.Pix[i+2]), float64(c.B)*aa, e.opacity))
 
 			case NoiseMulti:
 				// Full RGB random noise
@@ -154,9 +157,9 @@ func (e *NoiseEffect) Apply(dst *image.RGBA) {
 					B: uint8(rand.Intn(256)),
 					A: 255,
 				}
-				dst.Pix[i+0] = uint8(geom.Lerp(float64(dst.Pix[i+0]), float64(c.R), e.opacity))
-				dst.Pix[i+1] = uint8(geom.Lerp(float64(dst.Pix[i+1]), float64(c.G), e.opacity))
-				dst.Pix[i+2] = uint8(geom.Lerp(float64(dst.Pix[i+2]), float64(c.B), e.opacity))
+				dst.Pix[i+0] = uint8(geom.Lerp(float64(dst.Pix[i+0]), float64(c.R)*aa, e.opacity))
+				dst.Pix[i+1] = uint8(geom.Lerp(float64(dst.Pix[i+1]), float64(c.G)*aa, e.opacity))
+				dst.Pix[i+2] = uint8(geom.Lerp(float64(dst.Pix[i+2]), float64(c.B)*aa, e.opacity))
 			}
 		}
 	}
@@ -0,0 +1,113 @@
+// Package effects implements pre- and post-render effects for 2D drawing instructions.
+// This file defines a palette effect that restricts a layer, or a region of
+// it, to a fixed set of colors, for pixel-art looks and brand-locked output.
+//
+// Algorithm summary
+//
+//  1. Optionally offset each pixel by an ordered (Bayer) threshold, or add
+//     the error diffused from already mapped neighbours (Floyd–Steinberg).
+//  2. Replace the pixel's color with the nearest palette color by RGB
+//     distance.
+//
+// The mapping is shared with Layer.ExportPNGPaletted, so an export with the
+// same palette and dithering reproduces the effect's colors exactly.
+// The alpha channel remains unchanged. The effect is post-applied (IsPre() == false).
+package effects
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	imageUtil "github.com/Krispeckt/glimo/internal/core/image"
+	"github.com/Krispeckt/glimo/patterns"
+)
+
+// Dither selects how palette mapping errors are spread; see the Dither*
+// constants.
+type Dither = imageUtil.Dither
+
+// Dithering modes for PaletteEffect and Layer.ExportPNGPaletted.
+const (
+	DitherNone           = imageUtil.DitherNone
+	DitherOrdered        = imageUtil.DitherOrdered
+	DitherFloydSteinberg = imageUtil.DitherFloydSteinberg
+)
+
+// PaletteEffect maps a layer to a fixed palette.
+type PaletteEffect struct {
+	palette []color.NRGBA
+	dither  Dither
+	region  image.Rectangle // empty means the whole layer
+}
+
+// NewPaletteEffect creates a palette effect mapping every pixel to the
+// nearest of the given colors, without dithering. Palette alpha is ignored.
+//
+// Example:
+//
+//	card.AddEffect(effects.NewPaletteEffect(colors.GrayscalePalette).SetDither(effects.DitherOrdered))
+func NewPaletteEffect(palette []patterns.Color) *PaletteEffect {
+	return &PaletteEffect{palette: opaquePalette(palette)}
+}
+
+// SetDither selects the dithering mode.
+// Returns the receiver for chaining.
+func (e *PaletteEffect) SetDither(d Dither) *PaletteEffect {
+	e.dither = d
+	return e
+}
+
+// SetRegion limits the effect to r, in layer coordinates. An empty
+// rectangle applies it to the whole layer. Returns the receiver for chaining.
+func (e *PaletteEffect) SetRegion(r image.Rectangle) *PaletteEffect {
+	e.region = r
+	return e
+}
+
+// Name returns the effect identifier.
+// Implements the Effect interface.
+func (e *PaletteEffect) Name() string {
+	return "Palette"
+}
+
+// IsPre indicates whether the effect should be applied before drawing.
+// PaletteEffect is always post-applied, so this returns false.
+func (e *PaletteEffect) IsPre() bool {
+	return false
+}
+
+// Apply maps dst to the palette in place.
+func (e *PaletteEffect) Apply(dst *image.RGBA) {
+	if len(e.palette) == 0 {
+		return
+	}
+	r := dst.Bounds()
+	if !e.region.Empty() {
+		r = r.Intersect(e.region)
+	}
+	indices := imageUtil.PaletteIndices(dst, r, e.palette, e.dither)
+	for y := 0; y < r.Dy(); y++ {
+		for x := 0; x < r.Dx(); x++ {
+			k := indices[y*r.Dx()+x]
+			if k < 0 {
+				continue
+			}
+			o := dst.PixOffset(r.Min.X+x, r.Min.Y+y)
+			a := float64(dst.Pix[o+3]) / 255
+			c := e.palette[k]
+			dst.Pix[o+0] = uint8(math.Round(float64(c.R) * a))
+			dst.Pix[o+1] = uint8(math.Round(float64(c.G) * a))
+			dst.Pix[o+2] = uint8(math.Round(float64(c.B) * a))
+		}
+	}
+}
+
+// opaquePalette converts colors to the opaque palette form used for mapping.
+func opaquePalette(colors []patterns.Color) []color.NRGBA {
+	out := make([]color.NRGBA, len(colors))
+	for i, c := range colors {
+		out[i] = color.NRGBA{R: c.R, G: c.G, B: c.B, A: 255}
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+// Package effects implements pre- and post-render effects for 2D drawing instructions.
+// This file defines a reflection effect: the classic "floor reflection" of
+// product shots and album art cards, where a mirrored copy of the content
+// fades out below it.
+//
+// Algorithm summary
+//
+//  1. Find the bounding box of the visible (non-transparent) pixels.
+//  2. Below it, after a gap, copy its rows bottom-up, so the copy is flipped
+//     vertically, scaled by an opacity that falls linearly from `opacity` to
+//     zero over `length` of the content height.
+//  3. Composite the copy behind whatever the buffer already holds there.
+//
+// The reflection is drawn inside the buffer, so it is clipped by the layer
+// bounds. The effect is post-applied (IsPre() == false).
+package effects
+
+import (
+	"image"
+	"math"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+)
+
+// ReflectionEffect mirrors the content below itself, fading it out
+// downwards.
+type ReflectionEffect struct {
+	gap     float64 // pixels between the content and its reflection
+	opacity float64 // opacity at the top of the reflection, 0..1
+	length  float64 // fraction of the content height shown before it fades out
+}
+
+// NewReflectionEffect creates a reflection gap pixels below the content,
+// starting at opacity in [0, 1] and fading out over half the content height.
+//
+// Example:
+//
+//	cover.AddEffect(effects.NewReflectionEffect(4, 0.35))
+func NewReflectionEffect(gap, opacity float64) *ReflectionEffect {
+	return &ReflectionEffect{
+		gap:     math.Max(gap, 0),
+		opacity: geom.ClampF64(opacity, 0, 1),
+		length:  0.5,
+	}
+}
+
+// SetLength sets the fraction of the content height, in (0, 1], the
+// reflection shows before it has faded out. Returns the receiver for
+// chaining.
+func (e *ReflectionEffect) SetLength(f float64) *ReflectionEffect {
+	e.length = geom.ClampF64(f, 0.01, 1)
+	return e
+}
+
+// Name returns the effect identifier.
+// Implements the Effect interface.
+func (e *ReflectionEffect) Name() string {
+	return "Reflection"
+}
+
+// IsPre indicates whether the effect should be applied before drawing.
+// ReflectionEffect is always post-applied, so this returns false.
+func (e *ReflectionEffect) IsPre() bool {
+	return false
+}
+
+// Apply draws the faded, flipped copy of the visible content below it.
+func (e *ReflectionEffect) Apply(dst *image.RGBA) {
+	if e.opacity <= 0 {
+		return
+	}
+	c := opaqueBounds(dst)
+	if c.Empty() {
+		return
+	}
+
+	n := int(math.Ceil(float64(c.Dy()) * e.length))
+	top := c.Max.Y + int(math.Round(e.gap))
+	for i := 0; i < n; i++ {
+		y := top + i
+		if y >= dst.Rect.Max.Y {
+			break
+		}
+		if y < dst.Rect.Min.Y {
+			continue
+		}
+		a := uint32(math.Round(255 * e.opacity * (1 - (float64(i)+0.5)/float64(n))))
+		src := dst.Pix[dst.PixOffset(c.Min.X, c.Max.Y-1-i):][:4*c.Dx()]
+		row := dst.Pix[dst.PixOffset(c.Min.X, y):][:4*c.Dx()]
+		for x := 0; x < len(row); x += 4 {
+			// The reflection goes behind what is already there.
+			back := 255 - uint32(row[x+3])
+			for k := 0; k < 4; k++ {
+				s := (uint32(src[x+k])*a + 127) / 255
+				row[x+k] += uint8((s*back + 127) / 255)
+			}
+		}
+	}
+}
+
+// opaqueBounds returns the smallest rectangle holding every pixel of img
+// with a non-zero alpha.
+func opaqueBounds(img *image.RGBA) image.Rectangle {
+	b := img.Bounds()
+	x0, y0, x1, y1 := b.Max.X, b.Max.Y, b.Min.X, b.Min.Y
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(b.Min.X, y):][:4*b.Dx()]
+		for x := 3; x < len(row); x += 4 {
+			if row[x] == 0 {
+				continue
+			}
+			px := b.Min.X + x/4
+			x0, x1 = min(x0, px), max(x1, px+1)
+			y0, y1 = min(y0, y), y+1
+		}
+	}
+	if x0 >= x1 {
+		return image.Rectangle{}
+	}
+	return image.Rect(x0, y0, x1, y1)
+}
@@ -0,0 +1,62 @@
+package effects_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/Krispeckt/glimo/effects"
+	"github.com/Krispeckt/glimo/instructions"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoEnhanceEffect(t *testing.T) {
+	// A dull, warm-tinted picture: two flat halves close together in value.
+	dull := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+		for y := 0; y < 20; y++ {
+			for x := 0; x < 40; x++ {
+				c := color.RGBA{R: 120, G: 100, B: 80, A: 255}
+				if x >= 20 {
+					c = color.RGBA{R: 160, G: 140, B: 120, A: 255}
+				}
+				img.SetRGBA(x, y, c)
+			}
+		}
+		return img
+	}
+
+	img := dull()
+	effects.NewAutoEnhanceEffect().Apply(img)
+	dark, light := img.RGBAAt(0, 0), img.RGBAAt(39, 0)
+	require.Equal(t, uint8(0), dark.B, "darkest level maps to black")
+	require.Equal(t, uint8(255), light.R, "brightest level maps to white")
+	require.Greater(t, dark.R, dark.B, "a stretch alone keeps the cast")
+	cast := int(dark.R) - int(dark.B)
+
+	img = dull()
+	effects.NewAutoEnhanceEffect().SetWhiteBalance(true).Apply(img)
+	dark, light = img.RGBAAt(0, 0), img.RGBAAt(39, 0)
+	require.Less(t, int(dark.R)-int(dark.B), cast/2, "gray world reduces the cast")
+	require.Less(t, dark.G, light.G)
+
+	img = dull()
+	effects.NewAutoEnhanceEffect().SetStrength(0).Apply(img)
+	require.Equal(t, dull().Pix, img.Pix)
+
+	// Transparent pixels are ignored.
+	img = dull()
+	img.SetRGBA(5, 5, color.RGBA{})
+	effects.NewAutoEnhanceEffect().Apply(img)
+	require.Equal(t, color.RGBA{}, img.RGBAAt(5, 5))
+
+	src := mustLoadImage(t, "../../instructions/tests/testdata/image.png")
+	canvas := newLayer(t, 600, 600)
+	canvas.LoadInstruction(
+		instructions.NewImage(src, 0, 0).
+			SetSize(600, 600).
+			SetFit(instructions.FitCover).
+			AddEffect(effects.NewAutoEnhanceEffect().SetClip(1).SetWhiteBalance(true)),
+	)
+	require.NoError(t, canvas.Export("./output/auto_enhance.png"))
+}
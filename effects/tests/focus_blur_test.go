@@ -0,0 +1,62 @@
+package effects_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/Krispeckt/glimo/effects"
+	"github.com/Krispeckt/glimo/instructions"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFocusBlurEffect(t *testing.T) {
+	// Alternating black and white columns, which any blur turns gray.
+	stripes := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+		for y := 0; y < 100; y++ {
+			for x := 0; x < 100; x++ {
+				v := uint8(0)
+				if x%2 == 0 {
+					v = 255
+				}
+				img.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+			}
+		}
+		return img
+	}
+	blurred := func(img *image.RGBA, x, y int) bool {
+		v := img.RGBAAt(x, y).R
+		return v > 40 && v < 215
+	}
+
+	img := stripes()
+	effects.NewTiltShiftEffect(50, 20, 10, 4).Apply(img)
+	require.Equal(t, stripes().Pix[img.PixOffset(0, 45):img.PixOffset(0, 56)], img.Pix[img.PixOffset(0, 45):img.PixOffset(0, 56)],
+		"the band stays sharp")
+	require.True(t, blurred(img, 50, 5))
+	require.True(t, blurred(img, 50, 95))
+
+	// Rotated a quarter turn the band runs vertically.
+	img = stripes()
+	effects.NewTiltShiftEffect(0, 20, 10, 4).SetAngle(50, 50, 90).Apply(img)
+	require.Equal(t, uint8(255), img.RGBAAt(50, 5).R)
+	require.True(t, blurred(img, 5, 50))
+
+	img = stripes()
+	effects.NewFocusEllipseEffect(50, 50, 20, 10, 15, 4).Apply(img)
+	require.Equal(t, uint8(255), img.RGBAAt(60, 50).R, "inside the ellipse")
+	require.Equal(t, uint8(255), img.RGBAAt(50, 58).R)
+	require.True(t, blurred(img, 50, 80), "beyond the falloff")
+	require.True(t, blurred(img, 2, 2))
+
+	src := mustLoadImage(t, "../../instructions/tests/testdata/image.png")
+	canvas := newLayer(t, 300, 300)
+	canvas.LoadInstruction(
+		instructions.NewImage(src, 0, 0).
+			SetSize(300, 300).
+			SetFit(instructions.FitCover).
+			AddEffect(effects.NewTiltShiftEffect(150, 60, 60, 10).SetAngle(150, 150, -15)),
+	)
+	require.NoError(t, canvas.Export("./output/tilt_shift.png"))
+}
@@ -0,0 +1,63 @@
+package effects_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/effects"
+	"github.com/Krispeckt/glimo/instructions"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHalftoneEffect(t *testing.T) {
+	// A horizontal ramp from black to white.
+	ramp := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 120, 40))
+		for y := 0; y < 40; y++ {
+			for x := 0; x < 120; x++ {
+				v := uint8(x * 255 / 119)
+				img.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+			}
+		}
+		return img
+	}
+	// inkIn returns the fraction of ink (darkness) over columns [x0, x1).
+	inkIn := func(img *image.RGBA, x0, x1 int) float64 {
+		var sum float64
+		for y := 0; y < 40; y++ {
+			for x := x0; x < x1; x++ {
+				sum += 1 - float64(img.RGBAAt(x, y).R)/255
+			}
+		}
+		return sum / float64(40*(x1-x0))
+	}
+
+	for _, shape := range []effects.HalftoneShape{effects.HalftoneDot, effects.HalftoneSquare, effects.HalftoneLine} {
+		img := ramp()
+		effects.NewHalftoneEffect(8).SetShape(shape).Apply(img)
+		dark, mid, light := inkIn(img, 10, 30), inkIn(img, 50, 70), inkIn(img, 90, 110)
+		require.Greater(t, dark, mid, "shape %d", shape)
+		require.Greater(t, mid, light, "shape %d", shape)
+		require.InDelta(t, 0.5, mid, 0.15, "ink coverage follows darkness, shape %d", shape)
+
+		// Every pixel is ink, paper or an anti-aliased edge between them.
+		c := img.RGBAAt(60, 20)
+		require.Equal(t, c.R, c.G)
+	}
+
+	img := ramp()
+	effects.NewHalftoneEffect(8).SetColors(colors.Navy, colors.Transparent).Apply(img)
+	require.Zero(t, img.RGBAAt(119, 20).A, "white becomes bare paper")
+
+	src := mustLoadImage(t, "../../instructions/tests/testdata/image.png")
+	canvas := newLayer(t, 300, 300)
+	canvas.LoadInstruction(
+		instructions.NewImage(src, 0, 0).
+			SetSize(300, 300).
+			SetFit(instructions.FitCover).
+			AddEffect(effects.NewHalftoneEffect(6).SetColors(colors.Black, colors.Cornsilk)),
+	)
+	require.NoError(t, canvas.Export("./output/halftone.png"))
+}
@@ -0,0 +1,36 @@
+package effects_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/effects"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLerpEffects(t *testing.T) {
+	// shadowAt draws a square with shadow e and returns a pixel of the shadow.
+	shadowAt := func(e *effects.DropShadowEffect) color.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 60, 60))
+		draw.Draw(img, image.Rect(20, 20, 40, 40), image.NewUniform(color.White), image.Point{}, draw.Src)
+		e.Apply(img)
+		return img.RGBAAt(44, 30)
+	}
+	a := effects.NewDropShadow(0, 0, 0, 0, colors.Black, 1)
+	b := effects.NewDropShadow(10, 0, 0, 0, colors.Red, 1)
+
+	require.Equal(t, shadowAt(a), shadowAt(effects.LerpDropShadow(a, b, 0)))
+	require.Equal(t, shadowAt(b), shadowAt(effects.LerpDropShadow(a, b, 1)))
+	require.Zero(t, shadowAt(a).A, "no shadow right of the square")
+	require.Equal(t, uint8(255), shadowAt(effects.LerpDropShadow(a, b, 0.6)).A, "offset moved 6px")
+	require.Zero(t, shadowAt(effects.LerpDropShadow(nil, b, 0)).A, "nil fades in from transparent")
+	require.Nil(t, effects.LerpDropShadow(nil, nil, 0.5))
+
+	blurA, blurB := effects.NewLayerBlurEffect(0), effects.NewLayerBlurEffect(8).SetProgressive(0, 8)
+	require.NotNil(t, effects.LerpLayerBlur(blurA, blurB, 0.5))
+	inner := effects.LerpInnerShadow(nil, effects.NewInnerShadowEffect(0, 4, 4, colors.Black), 0.5)
+	require.NotNil(t, inner)
+}
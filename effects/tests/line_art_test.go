@@ -0,0 +1,68 @@
+package effects_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/effects"
+	"github.com/Krispeckt/glimo/instructions"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineArtEffect(t *testing.T) {
+	// A light square on a dark background.
+	square := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 60, 60))
+		for y := 0; y < 60; y++ {
+			for x := 0; x < 60; x++ {
+				v := uint8(30)
+				if x >= 20 && x < 40 && y >= 20 && y < 40 {
+					v = 220
+				}
+				img.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+			}
+		}
+		return img
+	}
+	// inked returns the columns of row y drawn darker than mid gray.
+	inked := func(img *image.RGBA, y int) []int {
+		var xs []int
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			if img.RGBAAt(x, y).R < 128 {
+				xs = append(xs, x)
+			}
+		}
+		return xs
+	}
+
+	img := square()
+	effects.NewLineArtEffect(40).Apply(img)
+	require.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, img.RGBAAt(5, 5), "flat areas become paper")
+	require.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, img.RGBAAt(30, 30))
+	row := inked(img, 30)
+	require.NotEmpty(t, row)
+	require.Subset(t, []int{17, 18, 19, 20, 21, 22, 37, 38, 39, 40, 41, 42}, row, "lines follow the edges")
+
+	img = square()
+	effects.NewLineArtEffect(40).SetCanny(15).Apply(img)
+	canny := inked(img, 30)
+	require.Len(t, canny, 2, "canny thins each edge to one pixel")
+	require.Less(t, len(canny), len(row))
+
+	img = square()
+	effects.NewLineArtEffect(40).SetColors(colors.Crimson, colors.Transparent).Apply(img)
+	require.Zero(t, img.RGBAAt(5, 5).A, "a transparent background keeps only the lines")
+	require.NotZero(t, img.RGBAAt(row[0], 30).A)
+
+	src := mustLoadImage(t, "../../instructions/tests/testdata/image.png")
+	canvas := newLayer(t, 300, 300)
+	canvas.LoadInstruction(
+		instructions.NewImage(src, 0, 0).
+			SetSize(300, 300).
+			SetFit(instructions.FitCover).
+			AddEffect(effects.NewLineArtEffect(30).SetCanny(10)),
+	)
+	require.NoError(t, canvas.Export("./output/line_art.png"))
+}
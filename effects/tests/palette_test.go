@@ -0,0 +1,93 @@
+package effects_test
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"testing"
+
+	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/effects"
+	"github.com/Krispeckt/glimo/instructions"
+	"github.com/Krispeckt/glimo/patterns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaletteEffect(t *testing.T) {
+	// A horizontal ramp from black to white.
+	ramp := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 120, 40))
+		for y := 0; y < 40; y++ {
+			for x := 0; x < 120; x++ {
+				v := uint8(x * 255 / 119)
+				img.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+			}
+		}
+		return img
+	}
+	// brightness returns the mean red level over columns [x0, x1), in [0, 1].
+	brightness := func(img *image.RGBA, x0, x1 int) float64 {
+		var sum float64
+		for y := 0; y < 40; y++ {
+			for x := x0; x < x1; x++ {
+				sum += float64(img.RGBAAt(x, y).R) / 255
+			}
+		}
+		return sum / float64(40*(x1-x0))
+	}
+	bw := []patterns.Color{colors.Black, colors.White}
+
+	for _, d := range []effects.Dither{effects.DitherNone, effects.DitherOrdered, effects.DitherFloydSteinberg} {
+		img := ramp()
+		effects.NewPaletteEffect(bw).SetDither(d).Apply(img)
+		for x := 0; x < 120; x++ {
+			c := img.RGBAAt(x, 20)
+			require.True(t, c.R == 0 || c.R == 255, "dither %d: only palette colors remain", d)
+			require.Equal(t, uint8(255), c.A)
+		}
+		if d != effects.DitherNone {
+			// Dithering keeps the average tone of the ramp.
+			require.InDelta(t, 0.25, brightness(img, 20, 40), 0.1, "dither %d", d)
+			require.InDelta(t, 0.75, brightness(img, 80, 100), 0.1, "dither %d", d)
+		}
+	}
+	img := ramp()
+	effects.NewPaletteEffect(bw).Apply(img)
+	require.Zero(t, brightness(img, 0, 55), "no dithering leaves hard steps")
+
+	// A region leaves the rest of the layer untouched.
+	img = ramp()
+	effects.NewPaletteEffect(bw).SetRegion(image.Rect(0, 0, 60, 40)).Apply(img)
+	require.Equal(t, ramp().RGBAAt(90, 10), img.RGBAAt(90, 10))
+	require.Zero(t, img.RGBAAt(30, 10).R)
+
+	// The paletted PNG export maps colors the same way.
+	src := mustLoadImage(t, "../../instructions/tests/testdata/image.png")
+	canvas := newLayer(t, 300, 300)
+	canvas.LoadInstruction(
+		instructions.NewImage(src, 0, 0).
+			SetSize(300, 300).
+			SetFit(instructions.FitCover).
+			AddEffect(effects.NewPaletteEffect(colors.GrayscalePalette).SetDither(effects.DitherFloydSteinberg)),
+	)
+	require.NoError(t, canvas.Export("./output/palette.png"))
+
+	layer := newLayer(t, 120, 40)
+	copy(layer.Image().Pix, ramp().Pix)
+	require.NoError(t, layer.ExportPNGPaletted("./output/palette_indexed.png", bw, effects.DitherOrdered))
+	f, err := os.Open("./output/palette_indexed.png")
+	require.NoError(t, err)
+	defer f.Close()
+	decoded, err := png.Decode(f)
+	require.NoError(t, err)
+	paletted, ok := decoded.(*image.Paletted)
+	require.True(t, ok)
+	require.Len(t, paletted.Palette, 2)
+	want := ramp()
+	effects.NewPaletteEffect(bw).SetDither(effects.DitherOrdered).Apply(want)
+	for x := 0; x < 120; x++ {
+		r, _, _, _ := paletted.At(x, 7).RGBA()
+		require.Equal(t, want.RGBAAt(x, 7).R, uint8(r>>8))
+	}
+}
@@ -0,0 +1,58 @@
+package effects_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/Krispeckt/glimo/effects"
+	"github.com/Krispeckt/glimo/instructions"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharpenAndClarityEffects(t *testing.T) {
+	// A soft vertical edge between two grays.
+	edge := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 60, 10))
+		for y := 0; y < 10; y++ {
+			for x := 0; x < 60; x++ {
+				v := uint8(80)
+				if x >= 30 {
+					v = 170
+				}
+				img.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+			}
+		}
+		return img
+	}
+
+	img := edge()
+	effects.NewSharpenEffect(1, 2).Apply(img)
+	require.Less(t, img.RGBAAt(29, 5).R, uint8(80), "dark side of the edge gets darker")
+	require.Greater(t, img.RGBAAt(30, 5).R, uint8(170), "light side gets lighter")
+	require.Equal(t, uint8(80), img.RGBAAt(5, 5).R, "flat areas are untouched")
+
+	img = edge()
+	effects.NewSharpenEffect(1, 2).SetThreshold(255).Apply(img)
+	require.Equal(t, edge().Pix, img.Pix, "nothing reaches the threshold")
+
+	img = edge()
+	effects.NewClarityEffect(1).SetRadius(5).Apply(img)
+	require.Less(t, img.RGBAAt(29, 5).R, uint8(80))
+	require.Greater(t, img.RGBAAt(30, 5).R, uint8(170))
+
+	img = edge()
+	effects.NewClarityEffect(-1).SetRadius(5).Apply(img)
+	require.Greater(t, img.RGBAAt(29, 5).R, uint8(80), "negative clarity softens")
+	require.Less(t, img.RGBAAt(30, 5).R, uint8(170))
+
+	src := mustLoadImage(t, "../../instructions/tests/testdata/image.png")
+	canvas := newLayer(t, 300, 300)
+	canvas.LoadInstruction(
+		instructions.NewImage(src, 0, 0).
+			SetSize(300, 300).
+			SetFit(instructions.FitCover).
+			AddEffects(effects.NewClarityEffect(0.4), effects.NewSharpenEffect(0.8, 1).SetThreshold(3)),
+	)
+	require.NoError(t, canvas.Export("./output/sharpen_clarity.png"))
+}
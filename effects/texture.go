@@ -40,7 +40,7 @@ import (
 	"image"
 
 	"github.com/Krispeckt/glimo/internal/core/geom"
-	"github.com/Krispeckt/glimo/internal/core/image/patterns"
+	"github.com/Krispeckt/glimo/patterns"
 )
 
 // TextureEffect simulates a texture/roughness overlay similar to Figma's "Texture" fill.
@@ -168,11 +168,13 @@ func (e *TextureEffect) Apply(dst *image.RGBA) {
 			t := n * e.opacity
 
 			i := dst.PixOffset(b.Min.X+x, b.Min.Y+y)
+			aa := float64(dst.Pix[i+3]) / 255
 
-			// Lerp each RGB channel toward the texture color.
-			dst.Pix[i+0] = uint8(geom.Lerp(float64(dst.Pix[i+0]), float64(e.color.R), t))
-			dst.Pix[i+1] = uint8(geom.Lerp(float64(dst.Pix[i+1]), float64(e.color.G), t))
-			dst.Pix[i+2] = uint8(geom.Lerp(float64(dst.Pix[i+2]), float64(e.color.B), t))
+			// Lerp each RGB channel toward the texture color, premultiplied
+			// by the pixel alpha.
+			dst.Pix[i+0] = uint8(geom.Lerp(float64(dst.Pix[i+0]), float64(e.color.R)*aa, t))
+			dst.Pix[i+1] = uint8(geom.Lerp(float64(dst.Pix[i+1]), float64(e.color.G)*aa, t))
+			dst.Pix[i+2] = uint8(geom.Lerp(float64(dst.Pix[i+2]), float64(e.color.B)*aa, t))
 			// Alpha channel (i+3) remains unchanged by design.
 		}
 	}
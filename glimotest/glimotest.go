@@ -0,0 +1,232 @@
+// Package glimotest provides golden-image assertions for tests that render
+// with glimo.
+//
+// Goldens are compared with a structural similarity (SSIM) metric rather than
+// exact bytes, so harmless antialiasing or rounding drift passes while visible
+// regressions fail. Because mean SSIM forgives flat color shifts, a second
+// gate also bounds how many pixels may change by a visible amount. Run the tests with -glimotest.update, or with
+// GLIMOTEST_UPDATE=1 in the environment, to (re)write golden files:
+//
+//	go test ./... -glimotest.update
+package glimotest
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Krispeckt/glimo/instructions"
+	imageUtil "github.com/Krispeckt/glimo/internal/core/image"
+)
+
+// update is namespaced so it does not clash with the -update flag many test
+// binaries define for their own golden files.
+var update = flag.Bool("glimotest.update", false, "rewrite glimotest golden images instead of comparing against them")
+
+// Updating reports whether goldens are being rewritten, either with the
+// -glimotest.update flag or with GLIMOTEST_UPDATE set to a true value.
+func Updating() bool {
+	if *update {
+		return true
+	}
+	on, _ := strconv.ParseBool(os.Getenv("GLIMOTEST_UPDATE"))
+	return on
+}
+
+// ssimWindow is the side of the square windows SSIM statistics are taken
+// over; windows overlap by half.
+const ssimWindow = 8
+
+// pixelThreshold is the per-channel difference, on premultiplied 8-bit values,
+// above which a pixel counts as visibly changed.
+const pixelThreshold = 24
+
+// AssertImageMatches compares the layer's image against the golden PNG at
+// path. The test fails when the images differ in size, when their
+// dissimilarity (1 - SSIM) exceeds tolerance, or when more than tolerance of
+// all pixels changed by a visible amount (see DifferingPixels); 0 demands a
+// perfect match, 0.01 is a reasonable default for antialiased output.
+//
+// On failure the rendered image is written next to the golden with an
+// ".actual.png" suffix for inspection. While Updating the golden is written
+// instead and the assertion always passes.
+func AssertImageMatches(t testing.TB, layer *instructions.Layer, path string, tolerance float64) {
+	t.Helper()
+	got := layer.Image()
+
+	if Updating() {
+		if err := writePNG(path, got); err != nil {
+			t.Fatalf("glimotest: update %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := imageUtil.LoadImage(path)
+	if err != nil {
+		t.Fatalf("glimotest: load golden %s: %v (run with -glimotest.update to create it)", path, err)
+		return
+	}
+
+	if !want.Bounds().Size().Eq(got.Bounds().Size()) {
+		saveActual(t, path, got)
+		t.Fatalf("glimotest: %s: size %v, golden is %v", path, got.Bounds().Size(), want.Bounds().Size())
+		return
+	}
+
+	// Compare what the golden would have stored: PNG keeps straight alpha, so
+	// round-trip the render through NRGBA before scoring it.
+	stored := image.NewNRGBA(got.Bounds())
+	draw.Draw(stored, stored.Bounds(), got, got.Bounds().Min, draw.Src)
+	if d := 1 - SSIM(want, stored); d > tolerance {
+		saveActual(t, path, got)
+		t.Errorf("glimotest: %s: dissimilarity %.5f exceeds tolerance %.5f", path, d, tolerance)
+		return
+	}
+	size := want.Bounds().Size()
+	total := size.X * size.Y
+	if n := DifferingPixels(want, stored, pixelThreshold); float64(n) > tolerance*float64(total) {
+		saveActual(t, path, got)
+		t.Errorf("glimotest: %s: %d of %d pixels differ by more than %d, tolerance is %.5f", path, n, total, pixelThreshold, tolerance)
+	}
+}
+
+// DifferingPixels counts the pixels of a and b where any premultiplied
+// channel differs by more than threshold. Images of different sizes count
+// every pixel of the larger one.
+func DifferingPixels(a, b image.Image, threshold int) int {
+	sa, sb := a.Bounds().Size(), b.Bounds().Size()
+	if !sa.Eq(sb) {
+		return max(sa.X*sa.Y, sb.X*sb.Y)
+	}
+	ra, rb := imageUtil.ToRGBA(a), imageUtil.ToRGBA(b)
+	var n int
+	for y := 0; y < sa.Y; y++ {
+		rowA := ra.Pix[ra.PixOffset(ra.Bounds().Min.X, ra.Bounds().Min.Y+y):]
+		rowB := rb.Pix[rb.PixOffset(rb.Bounds().Min.X, rb.Bounds().Min.Y+y):]
+		for i := 0; i < sa.X*4; i += 4 {
+			for c := 0; c < 4; c++ {
+				if d := int(rowA[i+c]) - int(rowB[i+c]); d > threshold || -d > threshold {
+					n++
+					break
+				}
+			}
+		}
+	}
+	return n
+}
+
+// SSIM returns the mean structural similarity of a and b in [-1, 1], where 1
+// means identical. Luma and alpha are scored separately and the lower of the
+// two is returned, so changes in transparency are not hidden. Images of
+// different sizes score 0.
+func SSIM(a, b image.Image) float64 {
+	if !a.Bounds().Size().Eq(b.Bounds().Size()) {
+		return 0
+	}
+	ra, rb := imageUtil.ToRGBA(a), imageUtil.ToRGBA(b)
+	la, aa := channels(ra)
+	lb, ab := channels(rb)
+	w, h := ra.Bounds().Dx(), ra.Bounds().Dy()
+	return math.Min(ssimPlane(la, lb, w, h), ssimPlane(aa, ab, w, h))
+}
+
+// channels splits img into luma (of the premultiplied color) and alpha planes.
+func channels(img *image.RGBA) (luma, alpha []float64) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	luma = make([]float64, w*h)
+	alpha = make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		row := img.Pix[img.PixOffset(b.Min.X, b.Min.Y+y):]
+		for x := 0; x < w; x++ {
+			p := row[x*4 : x*4+4]
+			luma[y*w+x] = 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+			alpha[y*w+x] = float64(p[3])
+		}
+	}
+	return luma, alpha
+}
+
+// ssimPlane computes the mean SSIM of two 8-bit planes over half-overlapping
+// windows. Planes smaller than a window are scored as a single window.
+func ssimPlane(a, b []float64, w, h int) float64 {
+	const (
+		c1 = (0.01 * 255) * (0.01 * 255)
+		c2 = (0.03 * 255) * (0.03 * 255)
+	)
+	win, step := ssimWindow, ssimWindow/2
+	ww, wh := min(win, w), min(win, h)
+
+	var sum float64
+	var n int
+	for y0 := 0; y0+wh <= h; y0 += step {
+		for x0 := 0; x0+ww <= w; x0 += step {
+			var ma, mb float64
+			for y := y0; y < y0+wh; y++ {
+				for x := x0; x < x0+ww; x++ {
+					ma += a[y*w+x]
+					mb += b[y*w+x]
+				}
+			}
+			cnt := float64(ww * wh)
+			ma /= cnt
+			mb /= cnt
+
+			var va, vb, cov float64
+			for y := y0; y < y0+wh; y++ {
+				for x := x0; x < x0+ww; x++ {
+					da, db := a[y*w+x]-ma, b[y*w+x]-mb
+					va += da * da
+					vb += db * db
+					cov += da * db
+				}
+			}
+			va /= cnt
+			vb /= cnt
+			cov /= cnt
+
+			sum += ((2*ma*mb + c1) * (2*cov + c2)) / ((ma*ma + mb*mb + c1) * (va + vb + c2))
+			n++
+		}
+	}
+	if n == 0 {
+		return 1
+	}
+	return sum / float64(n)
+}
+
+// saveActual writes the rendered image beside the golden for inspection.
+func saveActual(t testing.TB, path string, img image.Image) {
+	t.Helper()
+	out := strings.TrimSuffix(path, filepath.Ext(path)) + ".actual.png"
+	if err := writePNG(out, img); err != nil {
+		t.Logf("glimotest: save %s: %v", out, err)
+		return
+	}
+	t.Logf("glimotest: rendered image saved to %s", out)
+}
+
+// writePNG encodes img to path, creating parent directories as needed.
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("encode: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,89 @@
+package glimotest_test
+
+import (
+	"flag"
+	"path/filepath"
+	"testing"
+
+	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/glimotest"
+	"github.com/Krispeckt/glimo/instructions"
+	"github.com/Krispeckt/glimo/patterns"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder captures assertion failures instead of failing the real test.
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper()               {}
+func (r *recorder) Logf(string, ...any)   {}
+func (r *recorder) Errorf(string, ...any) { r.failed = true }
+func (r *recorder) Fatalf(string, ...any) { r.failed = true }
+
+// Test binaries importing glimotest keep the -update flag for their own
+// goldens; defining it here panics if glimotest registers it too.
+var _ = flag.Bool("update", false, "rewrite this package's own goldens")
+
+func scene(dx float64) *instructions.Layer {
+	return tinted(dx, colors.Coral)
+}
+
+func tinted(dx float64, fill patterns.Color) *instructions.Layer {
+	l := instructions.NewLayer(120, 120)
+	l.LoadInstructions(
+		instructions.NewRectangle(10, 10, 100, 100).SetRadius(12).SetFillColor(colors.SkyBlue),
+		instructions.NewCircle(30+dx, 30, 30).SetFillColor(fill),
+	)
+	return l
+}
+
+func TestAssertImageMatchesGolden(t *testing.T) {
+	glimotest.AssertImageMatches(t, scene(0), "testdata/scene.png", 0.01)
+}
+
+func TestAssertImageMatchesCatchesRegression(t *testing.T) {
+	if glimotest.Updating() {
+		t.Skip("goldens are being rewritten")
+	}
+	r := &recorder{TB: t}
+	actual := filepath.Join(t.TempDir(), "scene.png")
+	require.NoError(t, scene(0).Export(actual))
+
+	glimotest.AssertImageMatches(r, scene(0.25), actual, 0.01)
+	require.False(t, r.failed, "sub-pixel drift should stay within tolerance")
+
+	glimotest.AssertImageMatches(r, scene(20), actual, 0.01)
+	require.True(t, r.failed, "moved circle should be reported")
+}
+
+func TestAssertImageMatchesCatchesColorShift(t *testing.T) {
+	if glimotest.Updating() {
+		t.Skip("goldens are being rewritten")
+	}
+	actual := filepath.Join(t.TempDir(), "scene.png")
+	require.NoError(t, scene(0).Export(actual))
+
+	shifted := tinted(0, patterns.Color{R: 225, G: 127, B: 110, A: 255})
+	require.Less(t, 1-glimotest.SSIM(scene(0).Image(), shifted.Image()), 0.01, "mean SSIM alone forgives a flat color shift")
+
+	r := &recorder{TB: t}
+	glimotest.AssertImageMatches(r, shifted, actual, 0.01)
+	require.True(t, r.failed, "recolored circle should be reported")
+}
+
+func TestDifferingPixels(t *testing.T) {
+	a := scene(0).Image()
+	require.Zero(t, glimotest.DifferingPixels(a, a, 0))
+	require.Greater(t, glimotest.DifferingPixels(a, scene(20).Image(), 24), 500)
+	require.Equal(t, 120*120, glimotest.DifferingPixels(a, instructions.NewLayer(10, 10).Image(), 24))
+}
+
+func TestSSIM(t *testing.T) {
+	a := scene(0).Image()
+	require.InDelta(t, 1, glimotest.SSIM(a, a), 1e-9)
+	require.Less(t, glimotest.SSIM(a, scene(20).Image()), 0.99)
+	require.Equal(t, float64(0), glimotest.SSIM(a, instructions.NewLayer(10, 10).Image()))
+}
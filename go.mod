@@ -3,10 +3,12 @@ module github.com/Krispeckt/glimo
 go 1.25.0
 
 require (
+	github.com/go-text/typesetting v0.2.1
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
 	github.com/rivo/uniseg v0.4.7
 	github.com/stretchr/testify v1.11.1
 	golang.org/x/image v0.33.0
+	golang.org/x/text v0.31.0
 )
 
 require (
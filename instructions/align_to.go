@@ -0,0 +1,172 @@
+package instructions
+
+import (
+	"image"
+	"math"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+)
+
+// Anchor names one of the nine reference points of a box.
+type Anchor int
+
+const (
+	AnchorTopLeft Anchor = iota
+	AnchorTop
+	AnchorTopRight
+	AnchorLeft
+	AnchorCenter
+	AnchorRight
+	AnchorBottomLeft
+	AnchorBottom
+	AnchorBottomRight
+)
+
+// point returns the anchor of a w×h box at (x, y).
+func (a Anchor) point(x, y, w, h float64) (float64, float64) {
+	fx := float64(int(a)%3) / 2
+	fy := float64(int(a)/3) / 2
+	return x + w*fx, y + h*fy
+}
+
+// AlignTarget is anything with a box to align to: every BoundedShape, and
+// Layers, whose box is their position and size.
+type AlignTarget interface {
+	Position() (int, int)
+	Size() *Size
+}
+
+// Aligned places a shape relative to another one, recomputing the position
+// from the target's current box every time it is measured or drawn, so the
+// placement survives the target moving or changing size. Create it with
+// AlignTo.
+type Aligned struct {
+	shape  BoundedShape
+	target AlignTarget
+	at     Anchor
+	self   Anchor
+	dx, dy int
+	// sx, sy is the shift applied by SetPosition, which containers such as
+	// Group use to move children temporarily.
+	sx, sy int
+}
+
+// AlignTo places shape so that its own anchor at lands on the anchor at of
+// target, moved by (offsetX, offsetY): AlignTo(caption, photo,
+// AnchorBottomLeft, 8, -8) keeps the caption inside the photo's bottom-left
+// corner. Use SetSelfAnchor to align a different point of shape, e.g. its
+// center to put a badge over a corner.
+//
+// Draw the target before the aligned shape when both are drawn. Inside a
+// Group, align to a sibling so both use the group's coordinates.
+func AlignTo(shape BoundedShape, target AlignTarget, at Anchor, offsetX, offsetY int) *Aligned {
+	return &Aligned{shape: shape, target: target, at: at, self: at, dx: offsetX, dy: offsetY}
+}
+
+// SetSelfAnchor sets the point of the shape placed on the target's anchor.
+// It defaults to the same anchor as the target's.
+func (a *Aligned) SetSelfAnchor(self Anchor) *Aligned { a.self = self; return a }
+
+// Shape returns the aligned shape.
+func (a *Aligned) Shape() BoundedShape { return a.shape }
+
+// resolve returns the top-left of the shape for the target's current box.
+func (a *Aligned) resolve() (int, int) {
+	if a.shape == nil {
+		return 0, 0
+	}
+	tx, ty, tw, th := 0.0, 0.0, 0.0, 0.0
+	if a.target != nil {
+		x, y := a.target.Position()
+		tx, ty = float64(x), float64(y)
+		if sz := a.target.Size(); sz != nil {
+			tw, th = sz.Width(), sz.Height()
+		}
+	}
+	px, py := a.at.point(tx, ty, tw, th)
+	sw, sh := 0.0, 0.0
+	if sz := a.shape.Size(); sz != nil {
+		sw, sh = sz.Width(), sz.Height()
+	}
+	ox, oy := a.self.point(0, 0, sw, sh)
+	return int(math.Round(px-ox)) + a.dx + a.sx, int(math.Round(py-oy)) + a.dy + a.sy
+}
+
+// Position returns the resolved top-left of the shape.
+func (a *Aligned) Position() (int, int) { return a.resolve() }
+
+// SetPosition shifts the shape so its resolved position becomes (x, y). The
+// shift stays until the next SetPosition.
+func (a *Aligned) SetPosition(x, y int) {
+	a.sx, a.sy = 0, 0
+	px, py := a.resolve()
+	a.sx, a.sy = x-px, y-py
+}
+
+// Size returns the size of the shape.
+func (a *Aligned) Size() *geom.Size {
+	if a.shape == nil {
+		return geom.NewSize(0, 0)
+	}
+	return a.shape.Size()
+}
+
+// drawBounds returns the region the shape draws once moved to its resolved
+// position, so shadows, outside strokes and text overhang are not clipped
+// to the box.
+func (a *Aligned) drawBounds() (image.Rectangle, bool) {
+	if a.shape == nil {
+		return image.Rectangle{}, false
+	}
+	r, ok := shapeBounds(a.shape)
+	if !ok {
+		return image.Rectangle{}, false
+	}
+	x, y := a.resolve()
+	px, py := a.shape.Position()
+	return r.Add(image.Pt(x-px, y-py)), true
+}
+
+// scaled returns a copy with the shape, the target box and the offsets
+// scaled by s.
+func (a *Aligned) scaled(d device) Shape {
+	s := d.scale
+	c := *a
+	if bs, ok := scaleShape(a.shape, d).(BoundedShape); ok {
+		c.shape = bs
+	}
+	if a.target != nil {
+		c.target = scaledTarget{a.target, s}
+	}
+	c.dx, c.dy = int(math.Round(float64(a.dx)*s)), int(math.Round(float64(a.dy)*s))
+	c.sx, c.sy = int(math.Round(float64(a.sx)*s)), int(math.Round(float64(a.sy)*s))
+	return &c
+}
+
+// Draw moves the shape to its resolved position and draws it.
+func (a *Aligned) Draw(base, overlay *image.RGBA) {
+	if a.shape == nil {
+		return
+	}
+	a.shape.SetPosition(a.resolve())
+	a.shape.Draw(base, overlay)
+}
+
+// scaledTarget reports the box of a target multiplied by a device scale.
+type scaledTarget struct {
+	target AlignTarget
+	s      float64
+}
+
+func (t scaledTarget) Position() (int, int) {
+	x, y := t.target.Position()
+	return int(math.Round(float64(x) * t.s)), int(math.Round(float64(y) * t.s))
+}
+
+func (t scaledTarget) Size() *Size {
+	sz := t.target.Size()
+	if sz == nil {
+		return geom.NewSize(0, 0)
+	}
+	return geom.NewSize(sz.Width()*t.s, sz.Height()*t.s)
+}
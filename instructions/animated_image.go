@@ -0,0 +1,165 @@
+package instructions
+
+import (
+	"image"
+	"io"
+	"time"
+
+	imageUtil "github.com/Krispeckt/glimo/internal/core/image"
+)
+
+// AnimatedImage is a sequence of Image instructions decoded from an animated
+// GIF or APNG source. Each frame is fully composited, so any frame can be drawn
+// on its own. Frames share a position and can be configured together with Each.
+type AnimatedImage struct {
+	frames []*Image
+	delays []time.Duration
+	loops  int
+}
+
+// NewAnimatedImageFromPath decodes an animated GIF or APNG file into frames placed at (x, y),
+// within DefaultDecodeLimits. Static PNG files produce a single-frame animation.
+func NewAnimatedImageFromPath(path string, x, y int) (*AnimatedImage, error) {
+	a, err := imageUtil.LoadAnimation(path)
+	if err != nil {
+		return nil, err
+	}
+	return newAnimatedImage(a, x, y), nil
+}
+
+// NewAnimatedImageFromReader decodes an animated GIF or APNG stream within lim
+// into frames placed at (x, y). The canvas size is checked against lim before
+// it is allocated, so it suits user uploads like NewImageFromReader.
+func NewAnimatedImageFromReader(r io.Reader, x, y int, lim DecodeLimits) (*AnimatedImage, error) {
+	a, err := imageUtil.DecodeAnimation(r, lim)
+	if err != nil {
+		return nil, err
+	}
+	return newAnimatedImage(a, x, y), nil
+}
+
+// newAnimatedImage wraps decoded frames as Image instructions.
+func newAnimatedImage(a *imageUtil.Animation, x, y int) *AnimatedImage {
+	out := &AnimatedImage{
+		frames: make([]*Image, len(a.Frames)),
+		delays: append([]time.Duration(nil), a.Delays...),
+		loops:  a.Loops,
+	}
+	for i, f := range a.Frames {
+		out.frames[i] = NewImage(f, x, y)
+	}
+	return out
+}
+
+// Len returns the number of frames.
+func (a *AnimatedImage) Len() int { return len(a.frames) }
+
+// Frame returns the Image instruction for frame i, or nil when out of range.
+func (a *AnimatedImage) Frame(i int) *Image {
+	if i < 0 || i >= len(a.frames) {
+		return nil
+	}
+	return a.frames[i]
+}
+
+// Frames returns all frame instructions in display order.
+func (a *AnimatedImage) Frames() []*Image { return a.frames }
+
+// Delay returns the display duration of frame i.
+func (a *AnimatedImage) Delay(i int) time.Duration {
+	if i < 0 || i >= len(a.delays) {
+		return 0
+	}
+	return a.delays[i]
+}
+
+// Delays returns the display duration of every frame.
+func (a *AnimatedImage) Delays() []time.Duration { return a.delays }
+
+// LoopCount returns the number of times the animation plays. Zero means forever.
+func (a *AnimatedImage) LoopCount() int { return a.loops }
+
+// Duration returns the total length of one play of the animation.
+func (a *AnimatedImage) Duration() time.Duration {
+	var total time.Duration
+	for _, d := range a.delays {
+		total += d
+	}
+	return total
+}
+
+// FrameIndexAt returns the index of the frame visible after the given elapsed
+// time, wrapping around the animation length. Useful to sync animations
+// with different frame timings onto a shared timeline.
+func (a *AnimatedImage) FrameIndexAt(elapsed time.Duration) int {
+	total := a.Duration()
+	if len(a.frames) == 0 || total <= 0 {
+		return 0
+	}
+	elapsed %= total
+	if elapsed < 0 {
+		elapsed += total
+	}
+	for i, d := range a.delays {
+		if elapsed < d {
+			return i
+		}
+		elapsed -= d
+	}
+	return len(a.frames) - 1
+}
+
+// FrameAt returns the frame visible after the given elapsed time.
+func (a *AnimatedImage) FrameAt(elapsed time.Duration) *Image {
+	return a.Frame(a.FrameIndexAt(elapsed))
+}
+
+// Each applies fn to every frame, e.g. to set size, fit, mask, or effects uniformly.
+func (a *AnimatedImage) Each(fn func(im *Image)) *AnimatedImage {
+	for _, f := range a.frames {
+		fn(f)
+	}
+	return a
+}
+
+// SetPosition moves every frame to (x, y).
+func (a *AnimatedImage) SetPosition(x, y int) {
+	for _, f := range a.frames {
+		f.SetPosition(x, y)
+	}
+}
+
+// RenderOver composites each frame on top of a copy of template and returns
+// one Layer per frame. Extra shapes are drawn above the frame in order.
+// The template itself is not modified.
+func (a *AnimatedImage) RenderOver(template *Layer, shapes ...Shape) []*Layer {
+	out := make([]*Layer, len(a.frames))
+	for i, f := range a.frames {
+		l := template.Clone()
+		l.LoadInstruction(f)
+		l.LoadInstructions(shapes...)
+		out[i] = l
+	}
+	return out
+}
+
+// RenderOverAnimated composites this animation onto an animated template.
+// The output follows the template's frame timing; for each template frame the
+// overlay frame visible at the same point in time is drawn on top. Output
+// layers match the size of the template's source frames.
+func (a *AnimatedImage) RenderOverAnimated(template *AnimatedImage, shapes ...Shape) []*Layer {
+	out := make([]*Layer, 0, template.Len())
+	var elapsed time.Duration
+	for i, tf := range template.frames {
+		b := tf.src.Bounds()
+		l := NewLayerFromRGBA(image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy())))
+		l.LoadInstruction(tf)
+		if f := a.FrameAt(elapsed); f != nil {
+			l.LoadInstruction(f)
+		}
+		l.LoadInstructions(shapes...)
+		out = append(out, l)
+		elapsed += template.Delay(i)
+	}
+	return out
+}
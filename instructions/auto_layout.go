@@ -2,6 +2,7 @@ package instructions
 
 import (
 	"image"
+	"math"
 	"sort"
 
 	"github.com/Krispeckt/glimo/internal/core/geom"
@@ -15,6 +16,14 @@ type AutoLayout struct {
 	children []*node
 	w, h     int
 	dirty    bool // marks layout as invalidated
+
+	overflow       *OverflowPolicy
+	overflowReport *OverflowReport
+	fitting        bool // guards fitOverflow against reentry
+
+	shapeTransform
+	shapeID
+	configErrors
 }
 
 // NewAutoLayout constructs a new flex container anchored at (x, y).
@@ -23,12 +32,26 @@ func NewAutoLayout(x, y int, style ContainerStyle) *AutoLayout {
 	if style.Display != DisplayFlex {
 		style.Display = DisplayFlex
 	}
-	return &AutoLayout{x: x, y: y, style: style, dirty: true}
+	al := &AutoLayout{x: x, y: y, style: style, dirty: true}
+	al.checkStyle("NewAutoLayout", style)
+	return al
+}
+
+// checkStyle records negative container dimensions, padding and gaps.
+func (al *AutoLayout) checkStyle(setter string, st ContainerStyle) {
+	ok := st.Width >= 0 && st.Height >= 0 && st.Gap.X >= 0 && st.Gap.Y >= 0 &&
+		min(st.Padding[0], st.Padding[1], st.Padding[2], st.Padding[3]) >= 0
+	al.check(ok, "AutoLayout", setter, "negative size, padding or gap")
 }
 
 // Add registers a child Shape with an optional ItemStyle.
 // If the shape implements BoundedShape, its size and position are queried/updated automatically.
+// A nil shape is ignored.
 func (al *AutoLayout) Add(s Shape, st ItemStyle) *AutoLayout {
+	al.check(s != nil, "AutoLayout", "Add", "nil shape ignored")
+	if s == nil {
+		return al
+	}
 	n := &node{shape: s, st: st}
 	if bs, ok := s.(BoundedShape); ok {
 		n.meas = bs
@@ -40,31 +63,63 @@ func (al *AutoLayout) Add(s Shape, st ItemStyle) *AutoLayout {
 	return al
 }
 
+// SetID names the layout for FindByID.
+func (al *AutoLayout) SetID(id string) *AutoLayout { al.id = id; return al }
+
+// InvalidateLayout marks the layout as stale, so it is computed again before
+// the next draw. Texts changed in place are noticed automatically; call it
+// after changing the size of another child in place.
+func (al *AutoLayout) InvalidateLayout() {
+	al.w, al.h = 0, 0
+	al.dirty = true
+}
+
 // SetStyle replaces the container style and invalidates the current layout.
 func (al *AutoLayout) SetStyle(style ContainerStyle) {
 	if style.Display != DisplayFlex {
 		style.Display = DisplayFlex
 	}
+	al.checkStyle("SetStyle", style)
 	al.style = style
 	al.w, al.h = 0, 0
 	al.dirty = true
 }
 
-// Size returns the outer dimensions of the container including padding.
-// Triggers layout if needed.
+// SetTransform applies m to the laid-out container about origin, a point
+// of its untransformed box. Children are laid out and drawn as usual into a
+// transparent buffer, which is resampled bilinearly, so they no longer blend
+// with what lies beneath the container. Size reports the transformed
+// bounding box. The zero Matrix removes the transform.
+func (al *AutoLayout) SetTransform(m Matrix, origin Anchor) *AutoLayout {
+	al.setTransform(m, origin)
+	return al
+}
+
+// Size returns the outer dimensions of the container including padding, or
+// of its transformed bounding box. Triggers layout if needed.
 func (al *AutoLayout) Size() *geom.Size {
+	al.fitOverflow()
 	al.ensureLayout()
-	return geom.NewSize(float64(al.w), float64(al.h))
+	_, _, w, h := al.transformBox(float64(al.x), float64(al.y), float64(al.w), float64(al.h))
+	return geom.NewSize(w, h)
 }
 
 // Draw performs layout, sorts children by ZIndex, and draws each one in order.
 // Shapes implementing Boundable receive SetBounds; else Position/Size are propagated if available.
 func (al *AutoLayout) Draw(base, overlay *image.RGBA) {
+	al.fitOverflow()
 	al.ensureLayout()
+	// Children may overflow the box, so the whole canvas is resampled.
+	if al.drawResampled(overlay, overlay.Bounds(), float64(al.x), float64(al.y), float64(al.w), float64(al.h), al.Draw) {
+		return
+	}
 	sort.SliceStable(al.children, func(i, j int) bool {
 		return al.children[i].st.ZIndex < al.children[j].st.ZIndex
 	})
 	for _, n := range al.children {
+		if n.dropped {
+			continue
+		}
 		// Propagate resolved bounds to the shape if supported.
 		if b, ok := n.shape.(Boundable); ok {
 			b.SetBounds(n.x, n.y, n.w, n.h)
@@ -80,10 +135,70 @@ func (al *AutoLayout) Draw(base, overlay *image.RGBA) {
 	}
 }
 
-// ensureLayout computes a fresh layout if it is marked dirty or empty.
+// scaled returns a copy with container and item styles scaled by s and
+// every child replaced by its scaled copy. Layout is recomputed on draw.
+func (al *AutoLayout) scaled(d device) Shape {
+	s := d.scale
+	// The policy fits the template in logical pixels; the copy keeps the result.
+	al.fitOverflow()
+	px := func(v int) int { return int(math.Round(float64(v) * s)) }
+	pxPtr := func(v *int) *int {
+		if v == nil {
+			return nil
+		}
+		r := px(*v)
+		return &r
+	}
+
+	st := al.style
+	for i := range st.Padding {
+		st.Padding[i] = px(st.Padding[i])
+	}
+	st.Gap.X, st.Gap.Y = st.Gap.X*s, st.Gap.Y*s
+	st.Width, st.Height = px(st.Width), px(st.Height)
+
+	c := NewAutoLayout(px(al.x), px(al.y), st)
+	for _, n := range al.children {
+		it := n.st
+		for i := range it.Margin {
+			it.Margin[i] = px(it.Margin[i])
+		}
+		it.Width, it.Height = px(it.Width), px(it.Height)
+		it.FlexBasis = px(it.FlexBasis)
+		it.Top, it.Right = pxPtr(it.Top), pxPtr(it.Right)
+		it.Bottom, it.Left = pxPtr(it.Bottom), pxPtr(it.Left)
+		c.Add(scaleShape(n.shape, d), it)
+		c.children[len(c.children)-1].dropped = n.dropped
+	}
+	c.shapeTransform = al.scaledBy(s)
+	return c
+}
+
+// ensureLayout computes a fresh layout if it is marked dirty or empty, or a
+// child was resized since.
 func (al *AutoLayout) ensureLayout() {
-	if al.dirty || (al.w == 0 && al.h == 0) {
+	if al.dirty || (al.w == 0 && al.h == 0) || al.resized() {
 		al.layoutFlex()
 		al.dirty = false
 	}
 }
+
+// resized reports whether a Text child measures differently than when the
+// layout was computed, e.g. after SetText. Texts cache their size, so the
+// check does not wrap them again. Other shapes are skipped: the layout
+// resizes some of them itself.
+func (al *AutoLayout) resized() bool {
+	for _, n := range al.children {
+		if n.dropped || n.meas == nil {
+			continue
+		}
+		if _, ok := n.shape.(*Text); !ok {
+			continue
+		}
+		sz := n.meas.Size()
+		if int(math.Round(sz.Width())) != n.mw || int(math.Round(sz.Height())) != n.mh {
+			return true
+		}
+	}
+	return false
+}
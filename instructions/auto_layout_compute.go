@@ -19,7 +19,7 @@ func (al *AutoLayout) computeInner(isRow bool) (innerW, innerH, pl, pt, gx, gy i
 	if cs.Width == 0 || cs.Height == 0 {
 		natMain, natCross, count := 0, 0, 0
 		for _, n := range al.children {
-			if n.st.Position == PosAbsolute {
+			if n.st.Position == PosAbsolute || n.dropped {
 				continue
 			}
 			baseMain, baseCross := baseMainCross(n, isRow)
@@ -69,7 +69,7 @@ func (al *AutoLayout) buildLines(isRow bool, mainLimit, gx, gy int) []line {
 	}
 
 	for _, n := range al.children {
-		if n.st.Position == PosAbsolute {
+		if n.st.Position == PosAbsolute || n.dropped {
 			continue
 		}
 		baseMain, baseCross := baseMainCross(n, isRow)
@@ -13,6 +13,11 @@ type node struct {
 	st    ItemStyle
 	x, y  int // computed top-left position
 	w, h  int // computed width and height
+
+	// mw, mh is the size the shape reported when the layout was computed.
+	mw, mh int
+
+	dropped bool // removed by OverflowHideLowPriority
 }
 
 // Resizable is an optional capability: layout passes resolved size to the shape.
@@ -35,6 +40,7 @@ func naturalSize(n *node) (w, h int) {
 		size := n.meas.Size()
 		w = int(math.Round(size.Width()))
 		h = int(math.Round(size.Height()))
+		n.mw, n.mh = w, h
 	}
 	if n.st.Width > 0 {
 		w = n.st.Width
@@ -0,0 +1,65 @@
+package instructions
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+)
+
+// LayoutOverflowError reports an AutoLayout item that does not fit the
+// content box of its container.
+type LayoutOverflowError struct {
+	// Path locates the item from the outermost container, one element per
+	// nesting level: the item's ItemStyle.Name, or its index as "#i".
+	Path []string
+	// Item is the laid-out box of the item and Container the content box
+	// (inside the padding) it overflows, in canvas pixels.
+	Item, Container image.Rectangle
+}
+
+// Error implements the error interface.
+func (e *LayoutOverflowError) Error() string {
+	return fmt.Sprintf("layout: %s at %v overflows its container %v",
+		strings.Join(e.Path, "/"), e.Item, e.Container)
+}
+
+// CheckOverflow lays out the container and returns a *LayoutOverflowError
+// for the first item, in drawing order and depth first through nested
+// AutoLayouts, whose box leaves its container's content box. It returns nil
+// when everything fits. Auto-sized containers grow with their content, so
+// only fixed Width and Height can overflow.
+func (al *AutoLayout) CheckOverflow() error {
+	return al.checkOverflow(nil)
+}
+
+// itemName names the i-th item in paths: its ItemStyle.Name, or "#i".
+func itemName(n *node, i int) string {
+	if n.st.Name != "" {
+		return n.st.Name
+	}
+	return "#" + strconv.Itoa(i)
+}
+
+// checkOverflow implements CheckOverflow with path naming al.
+func (al *AutoLayout) checkOverflow(path []string) error {
+	al.ensureLayout()
+	pt, pr, pb, pl := sum4(al.style.Padding)
+	content := image.Rect(al.x+pl, al.y+pt, al.x+al.w-pr, al.y+al.h-pb)
+	for i, n := range al.children {
+		if n.dropped {
+			continue
+		}
+		p := append(path[:len(path):len(path)], itemName(n, i))
+		item := image.Rect(n.x, n.y, n.x+n.w, n.y+n.h)
+		if !item.Empty() && !item.In(content) {
+			return &LayoutOverflowError{Path: p, Item: item, Container: content}
+		}
+		if child, ok := n.shape.(*AutoLayout); ok {
+			if err := child.checkOverflow(p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -345,7 +345,7 @@ func (al *AutoLayout) placeLines(lines []line, isRow bool, innerW, innerH, pl, p
 // relative to the container’s padding box. Margins are honored.
 func (al *AutoLayout) positionAbsolute(innerW, innerH, pl, pt int) {
 	for _, n := range al.children {
-		if n.st.Position != PosAbsolute {
+		if n.st.Position != PosAbsolute || n.dropped {
 			continue
 		}
 		w, h := naturalSize(n)
@@ -88,4 +88,12 @@ type ItemStyle struct {
 	// IgnoreGapBefore skips the container gap directly before this item.
 	// Affects line construction, wrapping, and final positioning.
 	IgnoreGapBefore bool
+
+	// Name identifies the item in LayoutOverflowError paths; unnamed items
+	// are identified by their index.
+	Name string
+
+	// Priority orders items for OverflowHideLowPriority: lower values are
+	// removed first.
+	Priority int
 }
@@ -0,0 +1,134 @@
+package instructions
+
+import (
+	"image"
+	"math"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+)
+
+// BoundedBox gives a shape without its own bounds, such as a Line or a
+// ContextFunc, a w×h box so it can be placed in Group and AutoLayout. The
+// wrapped shape draws in local coordinates, with (0, 0) at the top-left of
+// the box, and is clipped to the box.
+type BoundedBox struct {
+	shape Shape
+	x, y  int
+	w, h  int
+
+	shapeOpacity
+	shapeTransform
+	shapeID
+	configErrors
+}
+
+// Bounded wraps shape in a w×h box at (0, 0). Negative sizes are treated as
+// zero.
+func Bounded(shape Shape, w, h int) *BoundedBox {
+	b := &BoundedBox{shape: shape, w: max(w, 0), h: max(h, 0)}
+	b.check(shape != nil, "BoundedBox", "Bounded", "nil shape")
+	b.check(w >= 0 && h >= 0, "BoundedBox", "Bounded", "negative size treated as zero")
+	return b
+}
+
+// Shape returns the wrapped shape.
+func (b *BoundedBox) Shape() Shape { return b.shape }
+
+// Position returns the top-left of the box, or of its transformed bounding
+// box.
+func (b *BoundedBox) Position() (int, int) {
+	x, y, _, _ := b.layoutBox()
+	return int(math.Floor(x)), int(math.Floor(y))
+}
+
+// SetPosition moves the box, and the wrapped shape with it, so that its
+// bounding box starts at (x, y).
+func (b *BoundedBox) SetPosition(x, y int) {
+	px, py := b.Position()
+	b.x += x - px
+	b.y += y - py
+}
+
+// SetPositionChain sets the position and returns the box for chaining.
+func (b *BoundedBox) SetPositionChain(x, y int) *BoundedBox { b.SetPosition(x, y); return b }
+
+// SetTransform applies m to the box about origin, a point of the
+// untransformed box. The wrapped shape is drawn as usual into a transparent
+// buffer, which is resampled bilinearly, so it no longer blends with what
+// lies beneath. Position and Size report the transformed bounding box. The
+// zero Matrix removes the transform.
+func (b *BoundedBox) SetTransform(m Matrix, origin Anchor) *BoundedBox {
+	b.setTransform(m, origin)
+	return b
+}
+
+// SetID names the box for FindByID.
+func (b *BoundedBox) SetID(id string) *BoundedBox { b.id = id; return b }
+
+// SetVisible shows or hides the box without removing it from its
+// container. Hidden shapes keep their place in layouts.
+func (b *BoundedBox) SetVisible(v bool) *BoundedBox { b.setVisible(v); return b }
+
+// SetOpacity fades the wrapped shape to o in [0, 1]. Values are clamped.
+func (b *BoundedBox) SetOpacity(o float64) *BoundedBox {
+	b.check(o >= 0 && o <= 1, "BoundedBox", "SetOpacity", "opacity outside [0, 1] clamped")
+	b.setOpacity(o)
+	return b
+}
+
+// Size returns the box size, or the size of its transformed bounding box.
+func (b *BoundedBox) Size() *geom.Size {
+	_, _, w, h := b.layoutBox()
+	return geom.NewSize(w, h)
+}
+
+// layoutBox returns the box, transformed by SetTransform.
+func (b *BoundedBox) layoutBox() (x, y, w, h float64) {
+	return b.transformBox(float64(b.x), float64(b.y), float64(b.w), float64(b.h))
+}
+
+// scaled returns a copy with the box and the wrapped shape scaled by s.
+func (b *BoundedBox) scaled(d device) Shape {
+	s := d.scale
+	return &BoundedBox{
+		shape: scaleShape(b.shape, d),
+		x:     int(math.Round(float64(b.x) * s)),
+		y:     int(math.Round(float64(b.y) * s)),
+		w:     int(math.Round(float64(b.w) * s)),
+		h:     int(math.Round(float64(b.h) * s)),
+
+		shapeOpacity:   b.shapeOpacity,
+		shapeTransform: b.scaledBy(s),
+		shapeID:        b.shapeID,
+	}
+}
+
+// Draw draws the wrapped shape offset to the box position, through views of
+// base and overlay that share their pixels but are limited to the box and
+// shifted into local coordinates.
+func (b *BoundedBox) Draw(base, overlay *image.RGBA) {
+	if b.shape == nil || overlay == nil || b.faded(b, base, overlay, b.Draw) {
+		return
+	}
+	box := image.Rect(b.x, b.y, b.x+b.w, b.y+b.h)
+	if b.drawResampled(overlay, box, float64(b.x), float64(b.y), float64(b.w), float64(b.h), b.Draw) {
+		return
+	}
+	r := box.Intersect(overlay.Bounds())
+	if r.Empty() {
+		return
+	}
+	off := image.Pt(b.x, b.y)
+	var localBase *image.RGBA
+	if base != nil {
+		localBase = localView(base, r.Intersect(base.Bounds()), off)
+	}
+	b.shape.Draw(localBase, localView(overlay, r, off))
+}
+
+// localView returns the part of img inside r, sharing its pixels, with its
+// coordinates shifted by -off.
+func localView(img *image.RGBA, r image.Rectangle, off image.Point) *image.RGBA {
+	sub := img.SubImage(r).(*image.RGBA)
+	return &image.RGBA{Pix: sub.Pix, Stride: sub.Stride, Rect: sub.Rect.Sub(off)}
+}
@@ -3,24 +3,37 @@ package instructions
 import (
 	"image"
 	"math"
+	"slices"
 
 	"github.com/Krispeckt/glimo/colors"
 	"github.com/Krispeckt/glimo/effects"
 	"github.com/Krispeckt/glimo/internal/containers"
 	"github.com/Krispeckt/glimo/internal/core/geom"
-	"github.com/Krispeckt/glimo/internal/core/image/patterns"
+	"github.com/Krispeckt/glimo/patterns"
 )
 
 // Circle represents a drawable circle with fill, stroke, and optional effects.
 type Circle struct {
-	x, y      float64 // top-left corner
-	radius    float64
-	fill      patterns.Pattern
-	stroke    patterns.Pattern
-	lineWidth float64
-	strokePos StrokePosition
-	steps     int
-	effects   containers.Effects
+	x, y        float64 // top-left corner
+	radius      float64
+	fill        patterns.Pattern
+	stroke      patterns.Pattern
+	fillBlend   *patterns.BlendMode
+	strokeBlend *patterns.BlendMode
+	lineWidth   float64
+	strokePos   StrokePosition
+	dashes      []float64
+	dashOffset  float64
+	lineCap     LineCap
+	steps       int
+	aliased     bool
+	accurate    bool // see Layer.SetAccurateBlending; set by scaled
+	effects     containers.Effects
+
+	shapeTransform
+	shapeOpacity
+	shapeID
+	configErrors
 }
 
 // NewCircle creates a new circle with given top-left and radius.
@@ -40,12 +53,14 @@ func NewCircle(x, y, radius float64) *Circle {
 
 // SetRadius sets circle radius.
 func (c *Circle) SetRadius(r float64) *Circle {
+	c.check(r >= 0, "Circle", "SetRadius", "negative radius clamped to 0")
 	c.radius = geom.ClampF64(r, 0, math.MaxFloat64)
 	return c
 }
 
 // SetLineWidth sets stroke width.
 func (c *Circle) SetLineWidth(width float64) *Circle {
+	c.check(width >= 0, "Circle", "SetLineWidth", "negative width clamped to 0")
 	c.lineWidth = geom.ClampF64(width, 0, math.MaxFloat64)
 	return c
 }
@@ -58,6 +73,7 @@ func (c *Circle) SetStrokePosition(pos StrokePosition) *Circle {
 
 // SetSteps sets resolution for circle approximation.
 func (c *Circle) SetSteps(steps int) *Circle {
+	c.check(steps >= 3, "Circle", "SetSteps", "steps below 3 raised to 3")
 	if steps < 3 {
 		steps = 3
 	}
@@ -65,6 +81,12 @@ func (c *Circle) SetSteps(steps int) *Circle {
 	return c
 }
 
+// SetAntiAlias enables or disables anti-aliased edges (enabled by default).
+func (c *Circle) SetAntiAlias(aa bool) *Circle {
+	c.aliased = !aa
+	return c
+}
+
 // SetFillColor sets solid fill color.
 func (c *Circle) SetFillColor(col patterns.Color) *Circle {
 	c.fill = col.MakeSolidPattern()
@@ -73,6 +95,7 @@ func (c *Circle) SetFillColor(col patterns.Color) *Circle {
 
 // SetFillPattern sets custom fill pattern.
 func (c *Circle) SetFillPattern(p patterns.Pattern) *Circle {
+	c.check(p != nil, "Circle", "SetFillPattern", "nil pattern ignored")
 	if p != nil {
 		c.fill = p
 	}
@@ -87,12 +110,54 @@ func (c *Circle) SetStrokeColor(col patterns.Color) *Circle {
 
 // SetStrokePattern sets custom stroke pattern.
 func (c *Circle) SetStrokePattern(p patterns.Pattern) *Circle {
+	c.check(p != nil, "Circle", "SetStrokePattern", "nil pattern ignored")
 	if p != nil {
 		c.stroke = p
 	}
 	return c
 }
 
+// SetFillBlendMode composites the fill with mode, overriding the blend mode
+// of the fill pattern and its colors.
+func (c *Circle) SetFillBlendMode(mode patterns.BlendMode) *Circle {
+	c.fillBlend = &mode
+	return c
+}
+
+// SetStrokeBlendMode composites the stroke with mode, overriding the blend
+// mode of the stroke pattern and its colors.
+func (c *Circle) SetStrokeBlendMode(mode patterns.BlendMode) *Circle {
+	c.strokeBlend = &mode
+	return c
+}
+
+// SetDashes dashes the stroke with lengths alternating on and off, e.g.
+// {0.5, 10} with round caps for a dotted ring. The pattern is stretched or
+// squeezed slightly so a whole number of cycles fits around the circle, so
+// the dashes meet seamlessly where the stroke starts. Nil removes dashing.
+func (c *Circle) SetDashes(d []float64) *Circle {
+	neg := slices.ContainsFunc(d, func(v float64) bool { return v < 0 })
+	c.check(!neg, "Circle", "SetDashes", "negative dash lengths ignored")
+	if neg {
+		return c
+	}
+	c.dashes = append([]float64(nil), d...)
+	return c
+}
+
+// SetDashOffset shifts the dash pattern along the stroke, clockwise from
+// the rightmost point of the circle; animate it to spin the dashes.
+func (c *Circle) SetDashOffset(off float64) *Circle {
+	c.dashOffset = off
+	return c
+}
+
+// SetLineCap sets the cap style of dash ends, LineCapRound by default.
+func (c *Circle) SetLineCap(lc LineCap) *Circle {
+	c.lineCap = lc
+	return c
+}
+
 // AddEffect adds a visual effect (blur, shadow, etc.) to the circle.
 func (c *Circle) AddEffect(e effects.Effect) *Circle {
 	c.effects.Add(e)
@@ -105,25 +170,118 @@ func (c *Circle) AddEffects(es ...effects.Effect) *Circle {
 	return c
 }
 
+// SetRotation turns the circle by deg degrees clockwise about pivot, a
+// point of its bounding box. The path is rotated before it is rasterized, so
+// the outline stays crisp; this matters for dashes, whose start turns with
+// it, and for a pivot off the center, which moves the circle. Fill and
+// stroke patterns keep their canvas orientation.
+func (c *Circle) SetRotation(deg float64, pivot Anchor) *Circle {
+	c.setRotation(deg, pivot)
+	return c
+}
+
+// SetTransform applies m to the circle's path about origin, a point of its
+// bounding box, after any rotation; a shear or uneven scale turns it into an
+// ellipse. The zero Matrix removes the transform.
+func (c *Circle) SetTransform(m Matrix, origin Anchor) *Circle {
+	c.setTransform(m, origin)
+	return c
+}
+
 // SetPosition sets top-left position of circle bounding box.
 func (c *Circle) SetPosition(x, y int) {
-	c.x, c.y = float64(x), float64(y)
+	if !c.transformed() {
+		c.x, c.y = float64(x), float64(y)
+		return
+	}
+	px, py := c.Position()
+	c.x += float64(x - px)
+	c.y += float64(y - py)
 }
 
-// Size returns circle diameter as Size.
+// Size returns circle diameter as Size, or the extent of the transformed
+// ellipse. Rotation alone does not change it.
 func (c *Circle) Size() *geom.Size {
-	d := c.radius * 2
+	_, _, rx, ry := c.ellipse()
 	o := c.strokePos.Outset(c.lineWidth)
-	return geom.NewSize(d+o, d+o)
+	return geom.NewSize(2*rx+o, 2*ry+o)
 }
 
-// Position returns top-left of bounding box.
+// Position returns top-left of bounding box, which a transform, or a
+// rotation about a pivot other than the center, moves.
 func (c *Circle) Position() (int, int) {
-	return int(c.x), int(c.y)
+	if !c.transformed() {
+		return int(c.x), int(c.y)
+	}
+	cx, cy, rx, ry := c.ellipse()
+	return int(cx - rx), int(cy - ry)
+}
+
+// ellipse returns the center of the transformed circle and its horizontal
+// and vertical half extents.
+func (c *Circle) ellipse() (cx, cy, rx, ry float64) {
+	if !c.transformed() {
+		return c.x + c.radius, c.y + c.radius, c.radius, c.radius
+	}
+	d := 2 * c.radius
+	m := c.transformMatrix(c.x, c.y, d, d)
+	cx, cy = m.TransformPoint(c.x+c.radius, c.y+c.radius)
+	return cx, cy, c.radius * math.Hypot(m.XX, m.XY), c.radius * math.Hypot(m.YX, m.YY)
+}
+
+// drawBounds returns the box grown by the stroke width to cover outside
+// strokes. Effects may draw anywhere, so they disable bounds.
+func (c *Circle) drawBounds() (image.Rectangle, bool) {
+	if c.effects.Count() > 0 {
+		return image.Rectangle{}, false
+	}
+	return boxBounds(c, 2*c.lineWidth), true
+}
+
+// scaled returns a copy with geometry, stroke width and dashes multiplied by s.
+func (c *Circle) scaled(d device) Shape {
+	s := d.scale
+	cc := *c
+	cc.x, cc.y = c.x*s, c.y*s
+	cc.radius = c.radius * s
+	cc.lineWidth = c.lineWidth * s
+	if c.dashes != nil {
+		cc.dashes = make([]float64, len(c.dashes))
+		for i, d := range c.dashes {
+			cc.dashes[i] = d * s
+		}
+	}
+	cc.dashOffset = c.dashOffset * s
+	cc.accurate = d.accurate
+	cc.fill = d.pattern(c.fill)
+	cc.stroke = d.pattern(c.stroke)
+	cc.shapeTransform = c.scaledBy(s)
+	return &cc
+}
+
+// SetID names the circle for FindByID.
+func (c *Circle) SetID(id string) *Circle {
+	c.id = id
+	return c
+}
+
+// SetVisible shows or hides the circle without removing it from its
+// container. Hidden shapes keep their place in layouts.
+func (c *Circle) SetVisible(v bool) *Circle { c.setVisible(v); return c }
+
+// SetOpacity fades the whole circle, fill, stroke and effects together, to o
+// in [0, 1]. Values are clamped.
+func (c *Circle) SetOpacity(o float64) *Circle {
+	c.check(o >= 0 && o <= 1, "Circle", "SetOpacity", "opacity outside [0, 1] clamped")
+	c.setOpacity(o)
+	return c
 }
 
 // Draw renders the circle to the overlay.
 func (c *Circle) Draw(base, overlay *image.RGBA) {
+	if c.faded(c, base, overlay, c.Draw) {
+		return
+	}
 	if c.radius <= 0 {
 		return
 	}
@@ -146,9 +304,18 @@ func (c *Circle) Draw(base, overlay *image.RGBA) {
 	cy := c.y + c.radius
 
 	line := NewLine().
+		blendAccurately(c.accurate).
+		SetAntiAlias(!c.aliased).
 		SetLineWidth(c.lineWidth).
-		SetStrokePattern(c.stroke).
-		SetFillPattern(c.fill)
+		SetLineCap(c.lineCap).
+		SetDashes(fitDashes(c.dashes, 2*float64(c.steps)*r*math.Sin(math.Pi/float64(c.steps)))).
+		SetDashOffset(c.dashOffset).
+		SetStrokePattern(blendedWith(c.stroke, c.strokeBlend)).
+		SetFillPattern(blendedWith(c.fill, c.fillBlend))
+	if c.transformed() {
+		d := 2 * c.radius
+		line.WithMatrix(c.transformMatrix(c.x, c.y, d, d))
+	}
 
 	addCirclePath(line, cx, cy, r, c.steps)
 
@@ -157,10 +324,32 @@ func (c *Circle) Draw(base, overlay *image.RGBA) {
 		line.StrokePreserve()
 	}
 	line.Draw(base, overlay)
+	line.release()
 
 	c.effects.PostApplyAll(overlay)
 }
 
+// fitDashes scales a dash pattern so a whole number of its cycles, at
+// least one, spans length, letting the pattern close up on a closed path.
+func fitDashes(dashes []float64, length float64) []float64 {
+	cycle := 0.0
+	for _, d := range dashes {
+		cycle += d
+	}
+	if len(dashes) == 1 {
+		cycle *= 2 // a single length is used for both on and off
+	}
+	if cycle <= 0 || length <= 0 {
+		return nil
+	}
+	k := length / (max(math.Round(length/cycle), 1) * cycle)
+	out := make([]float64, len(dashes))
+	for i, d := range dashes {
+		out[i] = d * k
+	}
+	return out
+}
+
 // addCirclePath approximates circle using polygonal segments.
 func addCirclePath(line *Line, cx, cy, r float64, steps int) {
 	if r <= 0 || steps < 3 {
@@ -0,0 +1,137 @@
+package instructions
+
+import (
+	"image"
+	"math"
+
+	"github.com/Krispeckt/glimo/internal/containers"
+)
+
+// Cost is a prediction of the resources a render needs.
+type Cost struct {
+	Pixels    int64 // canvas pixels after device scale
+	PeakBytes int64 // most memory held by pixel buffers at once, canvas included
+	Ops       int64 // approximate count of elementary pixel operations
+}
+
+// effectCarrier is implemented by shapes that run effects on their overlay.
+type effectCarrier interface {
+	effectList() *containers.Effects
+}
+
+func (c *Circle) effectList() *containers.Effects       { return &c.effects }
+func (r *Rectangle) effectList() *containers.Effects    { return &r.effects }
+func (t *Text) effectList() *containers.Effects         { return &t.effects }
+func (i *Image) effectList() *containers.Effects        { return i.effects }
+func (p *Polyline) effectList() *containers.Effects     { return &p.effects }
+func (r *RegionEffect) effectList() *containers.Effects { return &r.effects }
+
+// EstimateCost predicts the memory and work of drawing shapes in order on a
+// width×height canvas at the given device scale, without allocating the
+// canvas, so services can reject or downscale oversized requests first.
+//
+// Each shape is charged for the buffers LoadInstruction allocates for it
+// (over its bounds when known, the whole canvas otherwise), for its
+// effects (see effects.EstimateCost) and, for text, for its glyph masks,
+// which grow with the font size. Shapes draw one at a time, so the peak
+// is the canvas plus the most expensive shape. Containers are charged for
+// their own buffers plus their most expensive child, walked the same way.
+func EstimateCost(width, height int, scale float64, shapes ...Shape) Cost {
+	if scale <= 0 {
+		scale = 1
+	}
+	w := max(int(math.Ceil(float64(width)*scale)), 0)
+	h := max(int(math.Ceil(float64(height)*scale)), 0)
+	canvas := image.Rect(0, 0, w, h)
+	c := Cost{Pixels: int64(w) * int64(h)}
+
+	var shapePeak int64
+	for _, s := range shapes {
+		if s == nil {
+			continue
+		}
+		scaled := scaleShape(s, device{scale: scale})
+		r, ok := dirtyRect(scaled, canvas)
+		bytes, ops := shapeCost(scaled, r, ok && r != canvas)
+		shapePeak = max(shapePeak, bytes)
+		c.Ops += ops
+	}
+	c.PeakBytes = c.Pixels*4 + shapePeak
+	return c
+}
+
+// shapeCost returns the peak bytes and the ops of drawing the device-scaled
+// s over r, with a copy of the base under it when bounded. Children draw one
+// at a time inside their container, so the most expensive one adds to its
+// peak and all of them add to its ops.
+func shapeCost(s Shape, r image.Rectangle, bounded bool) (bytes, ops int64) {
+	px := int64(r.Dx()) * int64(r.Dy())
+
+	// The overlay, plus a copy of the base under bounded shapes.
+	bytes = px * 4
+	if bounded {
+		bytes += px * 4
+	}
+	ops = px * 8
+	if e, ok := s.(effectCarrier); ok {
+		t, o := e.effectList().EstimateCost(r.Dx(), r.Dy())
+		bytes += t
+		ops += o
+	}
+	if t, ok := s.(*Text); ok {
+		b, o := textMaskCost(t)
+		bytes += b
+		ops += o
+	}
+
+	var childPeak int64
+	for _, child := range childShapes(s) {
+		if child == nil {
+			continue
+		}
+		// Children are positioned inside their container, so only their
+		// size counts, clipped to the container's region.
+		cr, ok := shapeBounds(child)
+		if ok {
+			cr = image.Rect(0, 0, min(max(cr.Dx(), 0), r.Dx()), min(max(cr.Dy(), 0), r.Dy()))
+		} else {
+			cr = image.Rect(0, 0, r.Dx(), r.Dy())
+		}
+		b, o := shapeCost(child, cr, ok)
+		childPeak = max(childPeak, b)
+		ops += o
+	}
+	return bytes + childPeak, ops
+}
+
+// textMaskCost returns the bytes of the glyph caches of the faces t draws
+// with plus its largest line mask, both supersampled as ssScale selects, and
+// the ops of rasterizing all of its lines. Both grow with the square of the
+// font size, and masks cover whole lines whatever the canvas clips, so a
+// large font costs more than the text's bounds suggest.
+func textMaskCost(t *Text) (bytes, ops int64) {
+	if t.font == nil || t.text == "" {
+		return 0, 0
+	}
+	var faces, mask int64
+	lines, _ := t.wrapTextScaled()
+	for i, line := range lines {
+		if t.maxLines > 0 && i >= t.maxLines {
+			break
+		}
+		f := t.fontForLine(i)
+		k := float64(ssScale(f))
+		ss := *f
+		ss.SetFontSizePt(f.HeightPt() * k)
+		faces = max(faces, ss.FaceBytes())
+
+		w, _ := f.MeasureString(line)
+		// One font height of room for overhang, as in drawBounds.
+		mw := math.Ceil((w + f.HeightPx()) * k)
+		mh := math.Ceil((f.LineHeightPx() + f.DescentPx()) * k)
+		px := int64(mw) * int64(mh)
+		mask = max(mask, px*4)
+		ops += px * 4
+	}
+	return faces + mask, ops
+}
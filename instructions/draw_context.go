@@ -0,0 +1,127 @@
+package instructions
+
+import (
+	"image"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+	"github.com/Krispeckt/glimo/patterns"
+)
+
+// DrawContext is the two-buffer contract every Shape draws against.
+//
+//   - Base holds the canvas as it was before the shape. It is read-only:
+//     blend modes, opacity and auto-contrast sample it, but never write it.
+//   - Overlay starts transparent and receives the shape's output. After Draw
+//     returns, the Layer composites Overlay onto the canvas with source-over.
+//
+// Shapes may write Overlay in one of two ways. Plain source pixels (as Image
+// and Layer do) composite correctly for normal blending. Pixels already
+// blended against Base (as the pattern painters and Text do) are required
+// for other blend modes, since those depend on what lies underneath; the
+// Blend and FillMask helpers produce them.
+//
+// Both buffers share geometry and are addressed in canvas coordinates, but
+// they may cover only part of the canvas: Bounds().Min need not be zero.
+//
+// Base is the already-composited result of everything drawn before the
+// shape, including earlier children of the same Group, so shapes that depend
+// on their backdrop (blurs, frosted glass, magnifiers) read it there. Only
+// the area of Bounds is guaranteed: a BoundedShape gets buffers covering its
+// box plus a small margin, so shapes sampling further around themselves
+// should not report bounds. Base is owned by the caller and may change once
+// Draw returns; copy what must outlive the call, or what is read while
+// Overlay is written over the same pixels, with SnapshotBase. Base may be
+// nil when the shape is drawn without a backdrop; Blend and FillMask then
+// treat it as transparent.
+type DrawContext struct {
+	Base    *image.RGBA
+	Overlay *image.RGBA
+
+	// accurate is set for ContextFuncs loaded into a Layer with
+	// SetAccurateBlending.
+	accurate bool
+}
+
+// NewDrawContext wraps the buffers passed to Shape.Draw.
+func NewDrawContext(base, overlay *image.RGBA) *DrawContext {
+	return &DrawContext{Base: base, Overlay: overlay}
+}
+
+// Bounds returns the canvas region that may be drawn in.
+func (c *DrawContext) Bounds() image.Rectangle {
+	if c.Base == nil {
+		return c.Overlay.Bounds()
+	}
+	return c.Overlay.Bounds().Intersect(c.Base.Bounds())
+}
+
+// Blend composites src over the base pixel at (x, y) with the given coverage
+// in [0, 1], honoring src's blend mode, and stores the result in Overlay.
+// Points outside Bounds are ignored.
+func (c *DrawContext) Blend(x, y int, src patterns.Color, coverage float64) {
+	if coverage <= 0 || !image.Pt(x, y).In(c.Bounds()) {
+		return
+	}
+	var bg patterns.Color
+	if c.Base != nil {
+		i := c.Base.PixOffset(x, y)
+		bg.A = c.Base.Pix[i+3]
+		bg.R, bg.G, bg.B = geom.UnpremultiplyRGB(c.Base.Pix[i], c.Base.Pix[i+1], c.Base.Pix[i+2], bg.A)
+	}
+	blend := patterns.Color.BlendOver
+	if c.accurate {
+		blend = patterns.Color.BlendOverAccurate
+	}
+	out := blend(src, bg, coverage)
+
+	o := c.Overlay.PixOffset(x, y)
+	c.Overlay.Pix[o+0], c.Overlay.Pix[o+1], c.Overlay.Pix[o+2] = geom.PremultiplyRGB(out.R, out.G, out.B, out.A)
+	c.Overlay.Pix[o+3] = out.A
+}
+
+// FillMask fills pattern p through the alpha channel of mask placed with its
+// top-left corner at (x, y), blending against Base with p's blend mode and
+// opacity. This is the primitive Text uses for glyphs and strokes.
+func (c *DrawContext) FillMask(mask *image.RGBA, x, y int, p patterns.Pattern) {
+	base := c.Base
+	if base == nil && mask != nil {
+		base = image.NewRGBA(mask.Bounds().Sub(mask.Bounds().Min).Add(image.Pt(x, y)).Intersect(c.Overlay.Bounds()))
+	}
+	compositePatternWithMask(base, c.Overlay, mask, x, y, image.Rectangle{}, p, c.accurate)
+}
+
+// SnapshotBase returns a copy of the base pixels inside r, in canvas
+// coordinates. Pixels of r outside the base, or all of them when base is nil,
+// are transparent. See DrawContext for what the base holds.
+func SnapshotBase(base *image.RGBA, r image.Rectangle) *image.RGBA {
+	return cloneBaseTo(r, base)
+}
+
+// SnapshotBase returns a copy of the base pixels inside r; see SnapshotBase.
+func (c *DrawContext) SnapshotBase(r image.Rectangle) *image.RGBA {
+	return SnapshotBase(c.Base, r)
+}
+
+// ContextFunc adapts a function drawing against a DrawContext into a Shape.
+//
+//	star := instructions.ContextFunc(func(ctx *instructions.DrawContext) {
+//		ctx.Blend(10, 10, colors.Red, 1)
+//	})
+//	layer.LoadInstruction(star)
+type ContextFunc func(ctx *DrawContext)
+
+// Draw calls f with a DrawContext over base and overlay.
+func (f ContextFunc) Draw(base, overlay *image.RGBA) {
+	f(NewDrawContext(base, overlay))
+}
+
+// accurateContextFunc is a ContextFunc whose DrawContext blends accurately.
+// scaleShape wraps ContextFuncs in it for Layers with SetAccurateBlending.
+type accurateContextFunc ContextFunc
+
+// Draw calls f with an accurately blending DrawContext over base and overlay.
+func (f accurateContextFunc) Draw(base, overlay *image.RGBA) {
+	c := NewDrawContext(base, overlay)
+	c.accurate = true
+	f(c)
+}
@@ -0,0 +1,245 @@
+package instructions
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/draw"
+
+	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/effects"
+	"github.com/Krispeckt/glimo/internal/core/geom"
+	"github.com/Krispeckt/glimo/patterns"
+)
+
+// GlassRect is a frosted-glass card: a rounded rectangle showing a blurred
+// copy of what lies beneath it, covered by a translucent tint and outlined
+// by a thin highlight border. The blur samples the base image, i.e. what the
+// Layer (or the enclosing Group) held before the card was drawn, so draw the
+// background first.
+type GlassRect struct {
+	x, y          float64
+	width, height float64
+
+	radiusTL, radiusTR float64
+	radiusBR, radiusBL float64
+
+	blur        float64
+	tint        patterns.Pattern
+	border      patterns.Pattern
+	borderWidth float64
+	accurate    bool // see Layer.SetAccurateBlending; set by scaled
+
+	shapeOpacity
+	shapeTransform
+	shapeID
+	configErrors
+}
+
+// NewGlassRect creates a glass card at (x, y) with a 16px blur, a white tint
+// at 20% and a 1px white border at 40%.
+func NewGlassRect(x, y, width, height float64) *GlassRect {
+	return &GlassRect{
+		x:           x,
+		y:           y,
+		width:       width,
+		height:      height,
+		blur:        16,
+		tint:        patterns.NewSolidWithBlend(colors.White, patterns.BlendNormal, 0.2),
+		border:      patterns.NewSolidWithBlend(colors.White, patterns.BlendNormal, 0.4),
+		borderWidth: 1,
+	}
+}
+
+// SetSize sets width and height.
+func (g *GlassRect) SetSize(width, height float64) *GlassRect {
+	g.check(width >= 0 && height >= 0, "GlassRect", "SetSize", "negative size")
+	g.width, g.height = width, height
+	return g
+}
+
+// SetRadius sets a uniform corner radius.
+func (g *GlassRect) SetRadius(radius float64) *GlassRect {
+	return g.SetCornerRadii(radius, radius, radius, radius)
+}
+
+// SetCornerRadii sets per-corner radii: top-left, top-right, bottom-right, bottom-left.
+func (g *GlassRect) SetCornerRadii(tl, tr, br, bl float64) *GlassRect {
+	g.check(min(tl, tr, br, bl) >= 0, "GlassRect", "SetCornerRadii", "negative radius clamped to 0")
+	g.radiusTL = math.Max(tl, 0)
+	g.radiusTR = math.Max(tr, 0)
+	g.radiusBR = math.Max(br, 0)
+	g.radiusBL = math.Max(bl, 0)
+	return g
+}
+
+// SetBlur sets the blur radius of the background in pixels; 0 shows it sharp.
+func (g *GlassRect) SetBlur(radius float64) *GlassRect {
+	g.check(radius >= 0, "GlassRect", "SetBlur", "negative radius clamped to 0")
+	g.blur = math.Max(radius, 0)
+	return g
+}
+
+// SetTint sets a solid tint laid over the blurred background at the given
+// opacity, which controls how frosted the glass looks.
+func (g *GlassRect) SetTint(c patterns.Color, opacity float64) *GlassRect {
+	g.tint = patterns.NewSolidWithBlend(c, patterns.BlendNormal, opacity)
+	return g
+}
+
+// SetTintPattern sets the tint pattern, e.g. a gradient.
+func (g *GlassRect) SetTintPattern(p patterns.Pattern) *GlassRect {
+	g.check(p != nil, "GlassRect", "SetTintPattern", "nil pattern ignored")
+	if p != nil {
+		g.tint = p
+	}
+	return g
+}
+
+// SetBorder sets a solid border drawn inside the edge at the given opacity;
+// a zero width removes it.
+func (g *GlassRect) SetBorder(c patterns.Color, opacity, width float64) *GlassRect {
+	return g.SetBorderPattern(patterns.NewSolidWithBlend(c, patterns.BlendNormal, opacity), width)
+}
+
+// SetBorderPattern sets the border pattern and width, e.g. a gradient fading
+// from a bright top-left edge.
+func (g *GlassRect) SetBorderPattern(p patterns.Pattern, width float64) *GlassRect {
+	g.check(p != nil, "GlassRect", "SetBorderPattern", "nil pattern ignored")
+	g.check(width >= 0, "GlassRect", "SetBorderPattern", "negative width clamped to 0")
+	if p != nil {
+		g.border = p
+	}
+	g.borderWidth = math.Max(width, 0)
+	return g
+}
+
+// SetID names the card for FindByID.
+func (g *GlassRect) SetID(id string) *GlassRect { g.id = id; return g }
+
+// SetVisible shows or hides the card without removing it from its
+// container. Hidden shapes keep their place in layouts.
+func (g *GlassRect) SetVisible(v bool) *GlassRect { g.setVisible(v); return g }
+
+// SetOpacity fades the whole card to o in [0, 1]. Values are clamped.
+func (g *GlassRect) SetOpacity(o float64) *GlassRect {
+	g.check(o >= 0 && o <= 1, "GlassRect", "SetOpacity", "opacity outside [0, 1] clamped")
+	g.setOpacity(o)
+	return g
+}
+
+// SetTransform applies m to the card's path about origin, a point of its
+// untransformed box, as Rectangle.SetTransform does; the blurred backdrop is
+// clipped to the transformed card. Position and Size then report the
+// transformed bounding box. The zero Matrix removes the transform.
+func (g *GlassRect) SetTransform(m Matrix, origin Anchor) *GlassRect {
+	g.setTransform(m, origin)
+	return g
+}
+
+// SetPosition sets the top-left corner, or moves a transformed card so its
+// bounding box starts at (x, y).
+func (g *GlassRect) SetPosition(x, y int) {
+	if !g.transformed() {
+		g.x, g.y = float64(x), float64(y)
+		return
+	}
+	px, py := g.Position()
+	g.x += float64(x - px)
+	g.y += float64(y - py)
+}
+
+// Position returns the top-left corner, or that of the transformed bounding
+// box.
+func (g *GlassRect) Position() (int, int) {
+	x, y, _, _ := g.transformBox(g.x, g.y, g.width, g.height)
+	return int(x), int(y)
+}
+
+// Size returns the card size, or the size of the transformed bounding box.
+func (g *GlassRect) Size() *geom.Size {
+	_, _, w, h := g.transformBox(g.x, g.y, g.width, g.height)
+	return geom.NewSize(w, h)
+}
+
+// scaled returns a copy with geometry, blur and border width multiplied by s.
+func (g *GlassRect) scaled(d device) Shape {
+	s := d.scale
+	c := *g
+	c.x, c.y = g.x*s, g.y*s
+	c.width, c.height = g.width*s, g.height*s
+	c.radiusTL, c.radiusTR = g.radiusTL*s, g.radiusTR*s
+	c.radiusBR, c.radiusBL = g.radiusBR*s, g.radiusBL*s
+	c.blur = g.blur * s
+	c.borderWidth = g.borderWidth * s
+	c.accurate = d.accurate
+	c.tint = d.pattern(g.tint)
+	c.border = d.pattern(g.border)
+	c.shapeTransform = g.scaledBy(s)
+	return &c
+}
+
+// blurReach is how far the three-pass box blur samples around the card.
+func (g *GlassRect) blurReach() int { return 3 * int(math.Ceil(g.blur)) }
+
+// drawBounds returns the card grown by the blur reach, so a Layer hands it
+// enough of the base to blur the edges like the middle.
+func (g *GlassRect) drawBounds() (image.Rectangle, bool) {
+	return boxBounds(g, float64(g.blurReach())), true
+}
+
+// rect returns a Rectangle with the card geometry and transform and the
+// given fill.
+func (g *GlassRect) rect(fill patterns.Pattern) *Rectangle {
+	r := NewRectangle(g.x, g.y, g.width, g.height).
+		SetCornerRadii(g.radiusTL, g.radiusTR, g.radiusBR, g.radiusBL).
+		SetFillPattern(fill).
+		SetLineWidth(0)
+	r.shapeTransform = g.shapeTransform
+	r.accurate = g.accurate
+	return r
+}
+
+// Draw blurs the base under the card, clips it to the rounded rectangle,
+// then draws the tint and the border over it.
+func (g *GlassRect) Draw(base, overlay *image.RGBA) {
+	if overlay == nil || g.faded(g, base, overlay, g.Draw) {
+		return
+	}
+	if g.width <= 0 || g.height <= 0 {
+		return
+	}
+	box := image.Rect(
+		int(math.Floor(g.x)), int(math.Floor(g.y)),
+		int(math.Ceil(g.x+g.width)), int(math.Ceil(g.y+g.height)),
+	)
+	box = g.boundsAfter(box, g.x, g.y, g.width, g.height).Intersect(overlay.Bounds())
+	if box.Empty() {
+		return
+	}
+
+	// Shapes paint over their base, so the blurred backdrop also goes into a
+	// copy of the base that the tint and border are then painted over.
+	under := SnapshotBase(base, overlay.Bounds())
+	if base != nil {
+		mask := image.NewRGBA(box)
+		g.rect(colors.White.MakeSolidPattern()).Draw(mask, mask)
+
+		// The blur expects a buffer starting at (0, 0).
+		src := box.Inset(-g.blurReach()).Intersect(base.Bounds())
+		backdrop := image.NewRGBA(image.Rect(0, 0, src.Dx(), src.Dy()))
+		draw.Copy(backdrop, image.Point{}, base, src, draw.Src, nil)
+		if g.blur > 0 && !src.Empty() {
+			effects.NewLayerBlurEffect(g.blur).Apply(backdrop)
+		}
+		sp := box.Min.Sub(src.Min)
+		draw.DrawMask(overlay, box, backdrop, sp, mask, box.Min, draw.Over)
+		draw.DrawMask(under, box, backdrop, sp, mask, box.Min, draw.Over)
+	}
+
+	card := g.rect(g.tint)
+	if g.borderWidth > 0 {
+		card.SetStrokePattern(g.border).SetLineWidth(g.borderWidth)
+	}
+	card.Draw(under, overlay)
+}
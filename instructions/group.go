@@ -16,26 +16,70 @@ type Group struct {
 	w, h   int  // Frame size; if 0, computed from content bounds
 	clip   bool // Clip to frame rect
 	shapes []BoundedShape
+
+	shapeOpacity
+	shapeTransform
+	shapeID
+	configErrors
 }
 
 // NewGroup creates a new Group with frame semantics by default.
 func NewGroup() *Group { return &Group{} }
 
-// Position returns the current frame position.
-func (g *Group) Position() (int, int) { return g.x, g.y }
+// Position returns the current frame position, or the top-left of the
+// transformed bounding box.
+func (g *Group) Position() (int, int) {
+	x, y, _, _ := g.layoutBox()
+	return int(math.Floor(x)), int(math.Floor(y))
+}
 
-// SetPosition sets frame position.
-func (g *Group) SetPosition(x, y int) { g.x, g.y = x, y }
+// SetPosition sets frame position, or moves a transformed group so its
+// bounding box starts at (x, y).
+func (g *Group) SetPosition(x, y int) {
+	px, py := g.Position()
+	g.x += x - px
+	g.y += y - py
+}
 
 // SetPositionChain sets position and returns the group for chaining.
-func (g *Group) SetPositionChain(x, y int) *Group { g.x, g.y = x, y; return g }
+func (g *Group) SetPositionChain(x, y int) *Group { g.SetPosition(x, y); return g }
 
-// SetFrameSize sets explicit frame size. Zero means auto from content.
-func (g *Group) SetFrameSize(w, h int) *Group { g.w, g.h = w, h; return g }
+// SetID names the group for FindByID.
+func (g *Group) SetID(id string) *Group { g.id = id; return g }
+
+// SetVisible shows or hides the group without removing it from its
+// container. Hidden shapes keep their place in layouts.
+func (g *Group) SetVisible(v bool) *Group { g.setVisible(v); return g }
+
+// SetOpacity fades the whole group to o in [0, 1], as one composite, so
+// overlapping children do not show through each other. Values are clamped.
+func (g *Group) SetOpacity(o float64) *Group {
+	g.check(o >= 0 && o <= 1, "Group", "SetOpacity", "opacity outside [0, 1] clamped")
+	g.setOpacity(o)
+	return g
+}
+
+// SetFrameSize sets explicit frame size. Zero means auto from content;
+// negative sizes are treated as zero.
+func (g *Group) SetFrameSize(w, h int) *Group {
+	g.check(w >= 0 && h >= 0, "Group", "SetFrameSize", "negative size treated as auto")
+	g.w, g.h = max(w, 0), max(h, 0)
+	return g
+}
 
 // SetClip enables or disables clipping to the frame rect.
 func (g *Group) SetClip(clip bool) *Group { g.clip = clip; return g }
 
+// SetTransform applies m to the composited group about origin, a point of
+// its untransformed frame: the children are drawn as usual into a
+// transparent buffer, which is resampled bilinearly, so they no longer blend
+// with what lies beneath the group. Position and Size report the
+// transformed bounding box. The zero Matrix removes the transform.
+func (g *Group) SetTransform(m Matrix, origin Anchor) *Group {
+	g.setTransform(m, origin)
+	return g
+}
+
 // AddInstruction adds a single shape.
 func (g *Group) AddInstruction(s BoundedShape) {
 	if g != nil && s != nil {
@@ -90,9 +134,24 @@ func (g *Group) bounds() (r image.Rectangle, ok bool) {
 	return
 }
 
-// Size returns composite size.
+// Size returns composite size, or the size of the transformed bounding box.
 // - Explicit frame size if set, otherwise content bounds size.
 func (g *Group) Size() *geom.Size {
+	if g == nil {
+		return geom.NewSize(0, 0)
+	}
+	_, _, w, h := g.layoutBox()
+	return geom.NewSize(w, h)
+}
+
+// layoutBox returns the frame box, transformed by SetTransform.
+func (g *Group) layoutBox() (x, y, w, h float64) {
+	sz := g.frameSize()
+	return g.transformBox(float64(g.x), float64(g.y), sz.Width(), sz.Height())
+}
+
+// frameSize returns the untransformed composite size.
+func (g *Group) frameSize() *geom.Size {
 	if g == nil {
 		return geom.NewSize(0, 0)
 	}
@@ -106,6 +165,44 @@ func (g *Group) Size() *geom.Size {
 	return geom.NewSize(float64(r.Dx()), float64(r.Dy()))
 }
 
+// drawBounds returns the frame for clipped groups, and otherwise the union of
+// the children's bounds, transformed with the group. Any child without known
+// bounds disables them.
+func (g *Group) drawBounds() (image.Rectangle, bool) {
+	r, ok := g.localDrawBounds()
+	if !ok || g == nil {
+		return r, ok
+	}
+	sz := g.frameSize()
+	return g.boundsAfter(r, float64(g.x), float64(g.y), sz.Width(), sz.Height()), true
+}
+
+// localDrawBounds returns the untransformed drawBounds.
+func (g *Group) localDrawBounds() (image.Rectangle, bool) {
+	if g == nil || len(g.shapes) == 0 {
+		return image.Rectangle{}, true
+	}
+	if g.clip {
+		if g.w > 0 && g.h > 0 {
+			return image.Rect(g.x, g.y, g.x+g.w, g.y+g.h), true
+		}
+		local, ok := g.bounds()
+		return local.Add(image.Pt(g.x, g.y)), ok
+	}
+	var r image.Rectangle
+	for _, s := range g.shapes {
+		if s == nil {
+			continue
+		}
+		sr, ok := shapeBounds(s)
+		if !ok {
+			return image.Rectangle{}, false
+		}
+		r = r.Union(sr.Add(image.Pt(g.x, g.y)))
+	}
+	return r, true
+}
+
 // cloneBaseTo allocates an RGBA with given bounds and copies overlapping pixels from src.
 func cloneBaseTo(bounds image.Rectangle, src *image.RGBA) *image.RGBA {
 	acc := image.NewRGBA(bounds)
@@ -119,8 +216,42 @@ func cloneBaseTo(bounds image.Rectangle, src *image.RGBA) *image.RGBA {
 	return acc
 }
 
+// scaled returns a copy with the frame and every child scaled by s.
+// Children that cannot be scaled are kept as-is.
+func (g *Group) scaled(d device) Shape {
+	s := d.scale
+	c := &Group{
+		x:    int(math.Round(float64(g.x) * s)),
+		y:    int(math.Round(float64(g.y) * s)),
+		w:    int(math.Round(float64(g.w) * s)),
+		h:    int(math.Round(float64(g.h) * s)),
+		clip: g.clip,
+
+		shapeOpacity:   g.shapeOpacity,
+		shapeTransform: g.scaledBy(s),
+		shapeID:        g.shapeID,
+	}
+	c.shapes = make([]BoundedShape, len(g.shapes))
+	for i, sh := range g.shapes {
+		if bs, ok := scaleShape(sh, d).(BoundedShape); ok {
+			c.shapes[i] = bs
+		} else {
+			c.shapes[i] = sh
+		}
+	}
+	return c
+}
+
 func (g *Group) Draw(base, overlay *image.RGBA) {
-	if g == nil || overlay == nil || len(g.shapes) == 0 {
+	if g == nil || overlay == nil || len(g.shapes) == 0 || g.faded(g, base, overlay, g.Draw) {
+		return
+	}
+	src, ok := g.localDrawBounds()
+	if !ok {
+		src = overlay.Bounds()
+	}
+	sz := g.frameSize()
+	if g.drawResampled(overlay, src, float64(g.x), float64(g.y), sz.Width(), sz.Height(), g.Draw) {
 		return
 	}
 
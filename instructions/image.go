@@ -6,6 +6,7 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"io"
 	"math"
 
 	"github.com/Krispeckt/glimo/colors"
@@ -13,7 +14,8 @@ import (
 	"github.com/Krispeckt/glimo/internal/containers"
 	"github.com/Krispeckt/glimo/internal/core/geom"
 	imageUtil "github.com/Krispeckt/glimo/internal/core/image"
-	"github.com/Krispeckt/glimo/internal/core/image/patterns"
+	"github.com/Krispeckt/glimo/patterns"
+	xdraw "golang.org/x/image/draw"
 )
 
 // FitMode defines how the source image is resized to the target width/height.
@@ -43,6 +45,12 @@ type Image struct {
 	// mask is an optional per-pixel alpha mask in destination space.
 	mask *image.RGBA
 
+	// maskMode, maskInvert and maskFeather are applied to mask when
+	// compositing.
+	maskMode    MaskMode
+	maskInvert  bool
+	maskFeather float64
+
 	// x,y are the destination top-left where the prepared layer is placed.
 	x, y int
 
@@ -70,6 +78,13 @@ type Image struct {
 
 	// effects is an external pipeline that can run pre/post.
 	effects *containers.Effects
+
+	// linear resamples in linear light instead of sRGB when resizing.
+	linear bool
+
+	shapeTransform
+	shapeID
+	configErrors
 }
 
 // NewImage creates a new Image at (x, y) with safe defaults:
@@ -89,8 +104,33 @@ func NewImage(src image.Image, x, y int) *Image {
 	}
 }
 
+// DecodeLimits bound the work of decoding an untrusted image; see
+// NewImageFromReader and NewLayerFromReader.
+type DecodeLimits = imageUtil.DecodeLimits
+
+// DefaultDecodeLimits returns the limits used by NewLayerFromImagePath:
+// 64 MiB of input, 50 megapixels, a 1<<16 expansion ratio and a 10 second
+// timeout.
+func DefaultDecodeLimits() DecodeLimits { return imageUtil.DefaultDecodeLimits() }
+
+// NewImageFromReader decodes a PNG, JPEG or WebP image from r within lim and
+// places it at (x, y) like NewImage. The declared size is checked against
+// the pixel and expansion limits before decoding, and malformed input is
+// reported as an error instead of a panic.
+func NewImageFromReader(r io.Reader, x, y int, lim DecodeLimits) (*Image, error) {
+	img, _, err := imageUtil.SafeDecode(r, lim)
+	if err != nil {
+		return nil, err
+	}
+	return NewImage(img, x, y), nil
+}
+
 // SetSize sets target width/height. Zero keeps that axis from the source.
-func (im *Image) SetSize(w, h int) *Image { im.w, im.h = w, h; return im }
+func (im *Image) SetSize(w, h int) *Image {
+	im.check(w >= 0 && h >= 0, "Image", "SetSize", "negative size")
+	im.w, im.h = w, h
+	return im
+}
 
 // SetFit selects Stretch/Contain/Cover.
 func (im *Image) SetFit(f FitMode) *Image { im.fit = f; return im }
@@ -107,16 +147,40 @@ func (im *Image) Rotate(deg float64) *Image { im.angleDeg = deg; return im }
 // SetExpand controls whether rotation expands the canvas to avoid cropping.
 func (im *Image) SetExpand(b bool) *Image { im.expand = b; return im }
 
+// SetTransform applies m to the placed image about origin, a point of its
+// untransformed box, e.g. ShearMatrix(-0.2, 0) with AnchorCenter slants it
+// in place. Unlike Rotate, which turns the pixels inside the box, it moves
+// the box itself: the prepared image is resampled bilinearly, and Position
+// and Size report the transformed bounding box. The zero Matrix removes the
+// transform.
+func (im *Image) SetTransform(m Matrix, origin Anchor) *Image {
+	im.setTransform(m, origin)
+	return im
+}
+
+// SetID names the image for FindByID.
+func (im *Image) SetID(id string) *Image {
+	im.id = id
+	return im
+}
+
 // SetOpacity sets global alpha in [0..1]. Values are clamped.
 func (im *Image) SetOpacity(o float64) *Image {
+	im.check(o >= 0 && o <= 1, "Image", "SetOpacity", "opacity outside [0, 1] clamped")
 	im.opacity = geom.ClampF64(o, 0, 1)
 	return im
 }
 
+// SetLinearResample selects linear-light filtering when the image is resized.
+// Downscaling in sRGB darkens fine bright detail such as hairlines and small
+// text in screenshots; linear light keeps its weight at some extra cost.
+func (im *Image) SetLinearResample(b bool) *Image { im.linear = b; return im }
+
 // SetBackground sets the color sampled outside source bounds during rotation.
 func (im *Image) SetBackground(c patterns.Color) *Image { im.bg = c; return im }
 
-// SetMaskImage assigns a mask image in destination space.
+// SetMaskImage assigns a mask image in destination space, read according to
+// SetMaskMode.
 func (im *Image) SetMaskImage(m *image.RGBA) *Image {
 	im.mask = m
 	return im
@@ -142,6 +206,40 @@ func (im *Image) SetMaskFromShape(s Shape) *Image {
 	return im
 }
 
+// MaskMode selects which channels of a mask image give its coverage.
+type MaskMode int
+
+const (
+	// MaskAlpha uses the alpha channel: opaque pixels show the image, so a
+	// mask drawn with any fill color works.
+	MaskAlpha MaskMode = iota
+	// MaskLuminance uses the brightness, as design tools do for grayscale
+	// masks: white shows the image and black hides it. The mask alpha
+	// scales the result further.
+	MaskLuminance
+	// MaskInverseLuminance is MaskLuminance with black showing the image.
+	MaskInverseLuminance
+)
+
+// SetMaskMode selects how the mask image is read, MaskAlpha by default.
+// Grayscale mask PNGs without transparency need MaskLuminance.
+func (im *Image) SetMaskMode(m MaskMode) *Image {
+	im.maskMode = m
+	return im
+}
+
+// SetMaskOptions controls how the mask is applied: invert swaps the kept and
+// hidden areas for knockouts, and feather softens its edges with a blur of
+// that radius in pixels for soft crops. Both are applied when drawing, so
+// the mask image itself is not modified. Pixels outside the mask bounds stay
+// hidden either way.
+func (im *Image) SetMaskOptions(invert bool, feather float64) *Image {
+	im.check(feather >= 0, "Image", "SetMaskOptions", "negative feather clamped to 0")
+	im.maskInvert = invert
+	im.maskFeather = math.Max(feather, 0)
+	return im
+}
+
 // ClearMask removes the current mask.
 func (im *Image) ClearMask() *Image { im.mask = nil; return im }
 
@@ -157,14 +255,42 @@ func (im *Image) AddEffects(es ...effects.Effect) *Image {
 	return im
 }
 
-// SetPosition moves the layer to (x, y).
-func (im *Image) SetPosition(x, y int) { im.x, im.y = x, y }
+// SetPosition moves the layer to (x, y), or moves a transformed image so
+// its bounding box starts there.
+func (im *Image) SetPosition(x, y int) {
+	px, py := im.Position()
+	im.x += x - px
+	im.y += y - py
+}
 
-// Position returns the destination top-left coordinate.
-func (im *Image) Position() (int, int) { return im.x, im.y }
+// Position returns the destination top-left coordinate, or the top-left of
+// the transformed bounding box.
+func (im *Image) Position() (int, int) {
+	x, y, _, _ := im.layoutBox()
+	return int(math.Floor(x)), int(math.Floor(y))
+}
 
-// Size returns the target size. Zero values mean "use source" for that axis.
+// Size returns the target size, or the size of the transformed bounding
+// box. Zero values mean "use source" for that axis.
 func (im *Image) Size() *geom.Size {
+	_, _, w, h := im.layoutBox()
+	return geom.NewSize(w, h)
+}
+
+// layoutBox returns the placed box, transformed by SetTransform.
+func (im *Image) layoutBox() (x, y, w, h float64) {
+	sz := im.boxSize()
+	return im.transformBox(float64(im.x), float64(im.y), sz.Width(), sz.Height())
+}
+
+// box returns the untransformed placed box.
+func (im *Image) box() image.Rectangle {
+	sz := im.boxSize()
+	return image.Rect(im.x, im.y, im.x+int(sz.Width()), im.y+int(sz.Height()))
+}
+
+// boxSize returns the untransformed target size.
+func (im *Image) boxSize() *geom.Size {
 	if im.src == nil {
 		return geom.NewSize(0, 0)
 	}
@@ -189,11 +315,58 @@ func (im *Image) Size() *geom.Size {
 	return geom.NewSize(float64(w), float64(h))
 }
 
+// drawBounds returns the placed image box. Effects may draw anywhere,
+// so they disable bounds.
+func (im *Image) drawBounds() (image.Rectangle, bool) {
+	if im.src == nil {
+		return image.Rectangle{}, true
+	}
+	if im.effects.Count() > 0 {
+		return image.Rectangle{}, false
+	}
+	b := im.box()
+	return im.boundsAfter(b, float64(b.Min.X), float64(b.Min.Y), float64(b.Dx()), float64(b.Dy())), true
+}
+
+// scaled returns a copy placed and sized for a device scale of s.
+// Zero target dimensions are resolved from the source before scaling,
+// and the mask is resampled to match.
+func (im *Image) scaled(d device) Shape {
+	s := d.scale
+	c := *im
+	c.x = int(math.Round(float64(im.x) * s))
+	c.y = int(math.Round(float64(im.y) * s))
+	if im.src != nil {
+		w, h := im.targetSize()
+		c.w = int(math.Round(float64(w) * s))
+		c.h = int(math.Round(float64(h) * s))
+	}
+	if im.mask != nil {
+		mb := im.mask.Bounds()
+		c.mask = imageUtil.ResizeRGBA(im.mask,
+			geom.MaxInt(int(math.Round(float64(mb.Dx())*s)), 1),
+			geom.MaxInt(int(math.Round(float64(mb.Dy())*s)), 1),
+		)
+	}
+	c.maskFeather = im.maskFeather * s
+	c.shapeTransform = im.scaledBy(s)
+	return &c
+}
+
 // Draw runs the pipeline and composites onto overlay.
 func (im *Image) Draw(_, overlay *image.RGBA) {
 	if im.src == nil || im.opacity <= 0 {
 		return
 	}
+	// Effects may draw outside the box, so they are resampled from the
+	// whole canvas.
+	b, src := im.box(), overlay.Bounds()
+	if im.effects.Count() == 0 {
+		src = b
+	}
+	if im.drawResampled(overlay, src, float64(b.Min.X), float64(b.Min.Y), float64(b.Dx()), float64(b.Dy()), im.Draw) {
+		return
+	}
 
 	im.effects.PreApplyAll(overlay)
 
@@ -201,7 +374,7 @@ func (im *Image) Draw(_, overlay *image.RGBA) {
 	img := im.src
 	W, H := im.targetSize()
 	if W > 0 && H > 0 {
-		img = resizeWithFit(img, W, H, im.fit)
+		img = resizeWithFit(img, W, H, im.fit, im.linear)
 	}
 	imgLayer := imageUtil.ToRGBA(img)
 
@@ -229,8 +402,9 @@ func (im *Image) Draw(_, overlay *image.RGBA) {
 	srcPt := imgLayer.Bounds().Min.Add(place.Min.Sub(dstRect.Min))
 
 	// 5) Align mask to follow the image. Mask origin is tied to dstRect.Min.
+	mask := im.compositeMask()
 	maskOffset := image.Pt(0, 0)
-	if im.mask != nil {
+	if mask != nil {
 		// mp in DrawMask corresponds to place.Min. To move mask with the image,
 		// set mp = place.Min - dstRect.Min.
 		maskOffset = place.Min.Sub(dstRect.Min)
@@ -238,19 +412,53 @@ func (im *Image) Draw(_, overlay *image.RGBA) {
 
 	// 6) Composite with or without mask and global opacity.
 	switch {
-	case im.mask == nil && im.opacity >= 1:
+	case mask == nil && im.opacity >= 1:
 		draw.Draw(overlay, place, imgLayer, srcPt, draw.Over)
 
-	case im.mask != nil && im.opacity >= 1:
-		draw.DrawMask(overlay, place, imgLayer, srcPt, im.mask, maskOffset, draw.Over)
+	case mask != nil && im.opacity >= 1:
+		draw.DrawMask(overlay, place, imgLayer, srcPt, mask, maskOffset, draw.Over)
 
 	default:
 		alpha := uint8(geom.ClampF64(im.opacity*255, 0, 255))
-		loc := multiplyMaskAlphaClippedOffset(im.mask, place, maskOffset, alpha)
+		loc := multiplyMaskAlphaClippedOffset(mask, place, maskOffset, alpha)
 		draw.DrawMask(overlay, place, imgLayer, srcPt, loc, image.Point{}, draw.Over)
 	}
 }
 
+// compositeMask returns the mask with the SetMaskOptions applied, or the
+// mask itself without options.
+func (im *Image) compositeMask() *image.RGBA {
+	if im.mask == nil || (im.maskMode == MaskAlpha && !im.maskInvert && im.maskFeather == 0) {
+		return im.mask
+	}
+	// The blur expects a buffer starting at (0, 0).
+	mb := im.mask.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, mb.Dx(), mb.Dy()))
+	draw.Draw(out, out.Bounds(), im.mask, mb.Min, draw.Src)
+	if im.maskMode != MaskAlpha {
+		for i := 0; i < len(out.Pix); i += 4 {
+			// Luma of premultiplied channels is already scaled by alpha.
+			p := out.Pix[i : i+4 : i+4]
+			a := uint8((2126*uint32(p[0]) + 7152*uint32(p[1]) + 722*uint32(p[2]) + 5000) / 10000)
+			if im.maskMode == MaskInverseLuminance {
+				a = p[3] - min(a, p[3])
+			}
+			p[0], p[1], p[2], p[3] = a, a, a, a
+		}
+	}
+	if im.maskFeather > 0 {
+		effects.NewLayerBlurEffect(im.maskFeather).Apply(out)
+	}
+	if im.maskInvert {
+		// Gray at the new alpha keeps the pixels valid premultiplied colors.
+		for i := 0; i < len(out.Pix); i += 4 {
+			a := 255 - out.Pix[i+3]
+			out.Pix[i], out.Pix[i+1], out.Pix[i+2], out.Pix[i+3] = a, a, a, a
+		}
+	}
+	return out
+}
+
 // multiplyMaskAlphaClippedOffset creates a local Alpha mask sized to `place`.
 // Each pixel is: mask.A at (mp + local) multiplied by globalAlpha / 255.
 func multiplyMaskAlphaClippedOffset(mask *image.RGBA, place image.Rectangle, maskOffset image.Point, globalAlpha uint8) *image.Alpha {
@@ -295,18 +503,26 @@ func (im *Image) targetSize() (int, int) {
 
 // resizeWithFit applies the selected FitMode.
 // Stretch: direct resize. Contain: aspect-fit. Cover: aspect-fill + center crop.
-func resizeWithFit(src image.Image, W, H int, mode FitMode) image.Image {
+// When linear is set, resampling happens in linear light.
+func resizeWithFit(src image.Image, W, H int, mode FitMode, linear bool) image.Image {
+	resize := imageUtil.ResizeRGBA
+	if linear {
+		resize = func(src image.Image, W, H int) *image.RGBA {
+			return imageUtil.ResizeRGBALinear(src, W, H, xdraw.CatmullRom)
+		}
+	}
+
 	switch mode {
 	case FitStretch:
-		return imageUtil.ResizeRGBA(src, W, H)
+		return resize(src, W, H)
 
 	case FitContain:
 		sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
 		if sw == 0 || sh == 0 {
-			return imageUtil.ResizeRGBA(src, W, H)
+			return resize(src, W, H)
 		}
 		r := math.Min(float64(W)/float64(sw), float64(H)/float64(sh))
-		return imageUtil.ResizeRGBA(src,
+		return resize(src,
 			int(math.Round(float64(sw)*r)),
 			int(math.Round(float64(sh)*r)),
 		)
@@ -314,19 +530,19 @@ func resizeWithFit(src image.Image, W, H int, mode FitMode) image.Image {
 	case FitCover:
 		sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
 		if sw == 0 || sh == 0 {
-			return imageUtil.ResizeRGBA(src, W, H)
+			return resize(src, W, H)
 		}
 		r := math.Max(float64(W)/float64(sw), float64(H)/float64(sh))
 		tw := int(math.Ceil(float64(sw) * r))
 		th := int(math.Ceil(float64(sh) * r))
 
-		scaled := imageUtil.ResizeRGBA(src, tw, th)
+		scaled := resize(src, tw, th)
 		cx := (tw - W) / 2
 		cy := (th - H) / 2
 		return imageUtil.CropRGBA(scaled, image.Rect(cx, cy, cx+W, cy+H))
 
 	default:
-		return imageUtil.ResizeRGBA(src, W, H)
+		return resize(src, W, H)
 	}
 }
 
@@ -5,12 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"image/color"
 	_ "image/jpeg"
 	"image/png"
+	"io"
+	"math"
 	"os"
 
+	"github.com/Krispeckt/glimo/effects"
 	"github.com/Krispeckt/glimo/internal/core/geom"
 	imageUtil "github.com/Krispeckt/glimo/internal/core/image"
+	"github.com/Krispeckt/glimo/internal/render"
+	"github.com/Krispeckt/glimo/patterns"
 	"golang.org/x/image/draw"
 )
 
@@ -20,6 +26,32 @@ type Layer struct {
 	x, y  int
 	image *image.RGBA
 	size  *geom.Size
+	scale float64
+
+	// batching and scratch back the BeginBatch/EndBatch mode.
+	batching bool
+	scratch  *image.RGBA
+
+	// scene and sceneBase back the retained-scene API (Retain/Rerender);
+	// repainted is the region the last Rerender restored.
+	scene     []*retained
+	sceneBase *image.RGBA
+	repainted image.Rectangle
+
+	// limits and loaded back LoadInstructionsChecked.
+	limits Limits
+	loaded int
+
+	// antialias is the supersampling factor set with SetAntialias.
+	antialias Antialias
+
+	// strict is set by SetStrict; refused holds the errors of the shapes
+	// it kept from being drawn.
+	strict  bool
+	refused []error
+
+	// accurate is set by SetAccurateBlending.
+	accurate bool
 }
 
 // NewLayer creates a new empty Layer with the specified width and height.
@@ -29,6 +61,24 @@ func NewLayer(width, height int) *Layer {
 	return NewLayerFromRGBA(rgba)
 }
 
+// NewLayerWithScale creates a Layer for a logical width and height rendered at
+// the given device scale, e.g. 2 for @2x exports. The pixel buffer measures
+// ceil(width*scale) by ceil(height*scale), and every loaded instruction has its
+// coordinates, sizes and font DPI multiplied by scale at draw time, so the same
+// template can be exported at several densities. Effect parameters such as
+// shadow offsets and blur radii stay in device pixels. Non-positive scales
+// are treated as 1.
+func NewLayerWithScale(width, height int, scale float64) *Layer {
+	if scale <= 0 {
+		scale = 1
+	}
+	w := int(math.Ceil(float64(width) * scale))
+	h := int(math.Ceil(float64(height) * scale))
+	l := NewLayer(w, h)
+	l.scale = scale
+	return l
+}
+
 // NewLayerFromImage creates a new Layer from any image.Image instance.
 // The input image is converted to RGBA format if necessary.
 func NewLayerFromImage(src image.Image) *Layer {
@@ -41,11 +91,13 @@ func NewLayerFromRGBA(rgba *image.RGBA) *Layer {
 	return &Layer{
 		image: rgba,
 		size:  geom.NewSizeFromImage(rgba),
+		scale: 1,
 	}
 }
 
 // NewLayerFromImagePath loads an image from the given file path.
-// Only PNG and JPEG formats are allowed.
+// Only PNG, JPEG and WebP formats are allowed, and the image is decoded
+// within DefaultDecodeLimits (see NewLayerFromReader).
 func NewLayerFromImagePath(path string) (*Layer, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -54,15 +106,17 @@ func NewLayerFromImagePath(path string) (*Layer, error) {
 	defer func() {
 		_ = f.Close()
 	}()
+	return NewLayerFromReader(f, DefaultDecodeLimits())
+}
 
-	img, format, err := image.Decode(f)
+// NewLayerFromReader decodes a PNG, JPEG or WebP image from r within lim
+// into a new Layer. Oversized, forged and malformed inputs fail with an
+// error before the pixel buffer is allocated, so it suits user uploads.
+func NewLayerFromReader(r io.Reader, lim DecodeLimits) (*Layer, error) {
+	img, _, err := imageUtil.SafeDecode(r, lim)
 	if err != nil {
 		return nil, err
 	}
-	if format != "png" && format != "jpeg" {
-		return nil, errors.New("unsupported image format: only PNG and JPEG are allowed")
-	}
-
 	return NewLayerFromRGBA(imageUtil.ToRGBA(img)), nil
 }
 
@@ -78,11 +132,51 @@ func MustLoadLayerFromImagePath(path string) *Layer {
 	return l
 }
 
+// Clone returns a deep copy of the Layer with its own pixel buffer.
+// Position and bounds are preserved.
+func (l *Layer) Clone() *Layer {
+	rgba := image.NewRGBA(l.image.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), l.image, l.image.Bounds().Min, draw.Src)
+	c := NewLayerFromRGBA(rgba)
+	c.x, c.y = l.x, l.y
+	c.scale = l.scale
+	c.antialias = l.antialias
+	c.limits, c.loaded = l.limits, l.loaded
+	c.strict, c.refused = l.strict, append([]error(nil), l.refused...)
+	c.accurate = l.accurate
+	return c
+}
+
 // Size returns the dimensions of the current Layer as a *geom.Size object.
 func (l *Layer) Size() *geom.Size {
 	return l.size
 }
 
+// Scale returns the device scale factor applied to loaded instructions.
+func (l *Layer) Scale() float64 {
+	return l.scale
+}
+
+// SetAccurateBlending makes instructions loaded afterwards composite
+// Multiply and Screen in floating point instead of on 8-bit channels. The
+// fast path is the default; the results differ by at most one level per
+// channel, which matters only when comparing against other renderers bit for
+// bit. The setting belongs to the Layer, so concurrent renders can choose it
+// independently. Returns the receiver for chaining.
+func (l *Layer) SetAccurateBlending(on bool) *Layer {
+	l.accurate = on
+	return l
+}
+
+// AccurateBlending reports whether accurate blending is on (see
+// SetAccurateBlending).
+func (l *Layer) AccurateBlending() bool { return l.accurate }
+
+// device returns the rendering parameters shapes are prepared for.
+func (l *Layer) device() device {
+	return device{scale: l.scale, accurate: l.accurate}
+}
+
 // Image returns the underlying *image.RGBA buffer of the Layer.
 func (l *Layer) Image() *image.RGBA {
 	return l.image
@@ -107,6 +201,7 @@ func (l *Layer) SetPositionChain(x, y int) *Layer {
 // SetSize adjusts the Layer's visible bounds to the requested width and height
 // without resampling or reallocating pixels. The bounds are clamped to the
 // current backing buffer capacity to avoid expansion. Content is not modified.
+// Use Resize, Crop, or ExtendCanvas to produce new pixel data.
 func (l *Layer) SetSize(w, h int) {
 	if l == nil || l.image == nil {
 		return
@@ -178,12 +273,45 @@ func (l *Layer) Draw(_, overlay *image.RGBA) {
 	draw.Draw(overlay, r, l.image, l.image.Bounds().Min, draw.Over)
 }
 
+// scaled returns a copy positioned for a device scale of s whose pixels are
+// resampled from the Layer's own scale to s.
+func (l *Layer) scaled(d device) Shape {
+	s := d.scale
+	c := &Layer{
+		x:     int(math.Round(float64(l.x) * s)),
+		y:     int(math.Round(float64(l.y) * s)),
+		image: l.image,
+		size:  l.size,
+		scale: s,
+	}
+	if f := s / l.scale; f != 1 {
+		b := l.image.Bounds()
+		c.setImage(imageUtil.ResizeRGBA(l.image,
+			geom.MaxInt(int(math.Round(float64(b.Dx())*f)), 1),
+			geom.MaxInt(int(math.Round(float64(b.Dy())*f)), 1),
+		))
+	}
+	return c
+}
+
 // ExportPNG saves the Layer as a PNG image to the specified file path.
 // The compression level controls the output file size and encoding speed.
 func (l *Layer) ExportPNG(path string, level png.CompressionLevel) error {
 	return imageUtil.ExportPNG(l.image, path, level)
 }
 
+// ExportPNGPaletted saves the Layer as an indexed-color PNG limited to the
+// given colors (at most 256), mapped with the same dithering as
+// effects.PaletteEffect. Transparent pixels use the first fully transparent
+// color of the palette, or one added for them.
+func (l *Layer) ExportPNGPaletted(path string, palette []patterns.Color, dither effects.Dither) error {
+	pal := make([]color.NRGBA, len(palette))
+	for i, c := range palette {
+		pal[i] = color.NRGBA{R: c.R, G: c.G, B: c.B, A: c.A}
+	}
+	return imageUtil.ExportPNGPaletted(l.image, path, pal, dither)
+}
+
 // ExportJPEG saves the Layer as a JPEG image to the specified file path.
 // The quality value must be between 0 and 100.
 func (l *Layer) ExportJPEG(path string, quality int) error {
@@ -212,11 +340,60 @@ func (l *Layer) ExportBytes(level png.CompressionLevel) ([]byte, error) {
 
 // LoadInstruction executes a single drawing instruction on the Layer.
 // The instruction defines its own drawing behavior through the Shape interface.
+// On a scaled Layer, built-in instructions are drawn from a scaled copy;
+// the instruction passed in is not modified. Between BeginBatch and EndBatch
+// a shared scratch overlay is used instead of a fresh one.
+//
+// Shapes with known bounds (BoundedShape) are drawn into an overlay covering
+// only those bounds, and only that region is composited; other shapes get a
+// full-canvas overlay.
+//
+// A strict Layer (see SetStrict) skips shapes with configuration errors and
+// reports them through Err.
 func (l *Layer) LoadInstruction(shape Shape) {
-	overlay := image.NewRGBA(l.image.Bounds())
+	if l.refuse(shape) {
+		return
+	}
+	l.loaded += countShapes(shape)
+	if l.batching && l.Antialias() <= AntialiasDefault {
+		l.loadBatched(scaleShape(shape, l.device()))
+		return
+	}
+	l.drawShape(shape, l.image.Bounds())
+}
+
+// drawShape draws shape onto the canvas, supersampled when SetAntialias asks
+// for it, and composites only the pixels inside clip. LoadInstruction passes
+// the whole canvas and Rerender its repaint region, so both produce the same
+// pixels.
+func (l *Layer) drawShape(shape Shape, clip image.Rectangle) {
+	if k := int(l.Antialias()); k > 1 && l.loadSupersampled(shape, k, clip) {
+		return
+	}
+	shape = scaleShape(shape, l.device())
+	canvas := l.image.Bounds()
+	if r, ok := dirtyRect(shape, canvas); ok && r != canvas {
+		if r = r.Intersect(clip); !r.Empty() {
+			l.loadBounded(shape, r)
+		}
+		return
+	}
+	overlay := render.GetRGBA(canvas)
 	shape.Draw(l.image, overlay)
 
-	draw.Draw(l.image, overlay.Bounds(), overlay, image.Point{}, draw.Over)
+	draw.Draw(l.image, clip, overlay, clip.Min, draw.Over)
+	render.PutRGBA(overlay)
+}
+
+// drawRegion returns the canvas region drawShape may change for shape, and
+// whether its bounds are known.
+func (l *Layer) drawRegion(shape Shape) (image.Rectangle, bool) {
+	if k := int(l.Antialias()); k > 1 {
+		if hiShape, ok := l.supersampled(shape, k); ok {
+			return l.supersampledRegion(hiShape, k)
+		}
+	}
+	return dirtyRect(scaleShape(shape, l.device()), l.image.Bounds())
 }
 
 // LoadInstructions executes a sequence of drawing instructions in order.
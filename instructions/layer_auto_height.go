@@ -0,0 +1,41 @@
+package instructions
+
+import "math"
+
+// ContentHeight returns the logical height shapes need: the lowest bottom
+// edge of the BoundedShape boxes among them plus padding. Shapes without
+// bounds, such as a ContextFunc, are not measured. AutoLayouts are laid out
+// to measure them, so their widths should already be set.
+func ContentHeight(padding int, shapes ...Shape) int {
+	bottom := 0
+	for _, s := range shapes {
+		bs, ok := s.(BoundedShape)
+		if !ok || bs == nil {
+			continue
+		}
+		sz := bs.Size()
+		if sz == nil {
+			continue
+		}
+		_, y := bs.Position()
+		bottom = max(bottom, y+int(math.Ceil(sz.Height())))
+	}
+	return bottom + padding
+}
+
+// NewLayerAutoHeight creates a Layer as tall as its content, for
+// variable-length images such as quotes and receipts: it measures shapes
+// with ContentHeight, allocates a width×height canvas at the given device
+// scale with the height clamped to [1, maxHeight], and loads the shapes in
+// order. Content below maxHeight is clipped; a non-positive maxHeight means
+// no limit. Shapes without bounds are drawn on the final canvas, so a
+// ContextFunc can paint a background of whatever height results.
+func NewLayerAutoHeight(width, maxHeight, padding int, scale float64, shapes ...Shape) *Layer {
+	h := max(ContentHeight(padding, shapes...), 1)
+	if maxHeight > 0 {
+		h = min(h, maxHeight)
+	}
+	l := NewLayerWithScale(width, h, scale)
+	l.LoadInstructions(shapes...)
+	return l
+}
@@ -0,0 +1,53 @@
+package instructions
+
+import (
+	"image"
+
+	"github.com/golang/freetype/raster"
+	"golang.org/x/image/draw"
+
+	"github.com/Krispeckt/glimo/internal/render"
+	"github.com/Krispeckt/glimo/patterns"
+)
+
+// NewLayerWithBackground creates a width×height Layer filled with p, e.g. a
+// solid color, gradient or surface, ready for the scene to be drawn over.
+func NewLayerWithBackground(width, height int, p patterns.Pattern) *Layer {
+	return NewLayer(width, height).FillBackground(p)
+}
+
+// FillBackground paints p over the whole Layer with the pattern's blend mode
+// and opacity, like a canvas-sized Rectangle drawn first but without
+// rasterizing a path or allocating an overlay. Opaque solid colors are
+// copied straight into the buffer. On a scaled Layer the pattern is scaled
+// like the fills of instructions. A nil pattern is ignored. Returns the
+// Layer for chaining.
+func (l *Layer) FillBackground(p patterns.Pattern) *Layer {
+	if l == nil || l.image == nil || p == nil {
+		return l
+	}
+	b := l.image.Bounds()
+	if b.Empty() {
+		return l
+	}
+	if s, ok := p.(*patterns.Solid); ok && s.Opacity() >= 1 && normalBlend(s.BlendMode()) {
+		if c, ok := s.ColorAt(0, 0).(patterns.Color); ok && c.A == 255 && normalBlend(c.BlendMode()) {
+			draw.Draw(l.image, b, image.NewUniform(c), image.Point{}, draw.Src)
+			return l
+		}
+	}
+
+	// The painter reads each pixel before writing it, so the buffer can be
+	// both base and overlay.
+	spans := make([]raster.Span, b.Dy())
+	for i := range spans {
+		spans[i] = raster.Span{Y: b.Min.Y + i, X0: b.Min.X, X1: b.Max.X, Alpha: 1<<16 - 1}
+	}
+	render.NewPatternPainter(l.image, l.image, nil, l.device().pattern(p)).SetAccurateBlending(l.accurate).Paint(spans, true)
+	return l
+}
+
+// normalBlend reports whether m composites like plain source-over.
+func normalBlend(m patterns.BlendMode) bool {
+	return m == patterns.BlendNormal || m == patterns.BlendPassThrough
+}
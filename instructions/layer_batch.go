@@ -0,0 +1,141 @@
+package instructions
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// BeginBatch switches the Layer into batched mode. Until EndBatch is called,
+// LoadInstruction reuses a single scratch overlay instead of allocating a
+// buffer per instruction, and composites only the rectangle each instruction
+// may touch. Drawing order and results are unchanged.
+//
+// Batched mode pays off when many small shapes are loaded onto a large canvas.
+func (l *Layer) BeginBatch() *Layer {
+	if l == nil || l.image == nil {
+		return l
+	}
+	l.ensureScratch()
+	l.batching = true
+	return l
+}
+
+// EndBatch leaves batched mode and releases the scratch overlay.
+func (l *Layer) EndBatch() *Layer {
+	if l == nil {
+		return l
+	}
+	l.batching = false
+	l.scratch = nil
+	return l
+}
+
+// LoadInstructionsFast executes shapes in order using batched mode.
+// If the Layer is already batching, the surrounding batch is kept open.
+func (l *Layer) LoadInstructionsFast(shapes ...Shape) {
+	if len(shapes) == 0 {
+		return
+	}
+	if l.batching {
+		l.LoadInstructions(shapes...)
+		return
+	}
+	l.BeginBatch()
+	l.LoadInstructions(shapes...)
+	l.EndBatch()
+}
+
+// ensureScratch (re)allocates the scratch overlay to match the Layer bounds.
+func (l *Layer) ensureScratch() {
+	if l.scratch == nil || l.scratch.Bounds() != l.image.Bounds() {
+		l.scratch = image.NewRGBA(l.image.Bounds())
+	}
+}
+
+// loadBatched draws shape into the scratch overlay, composites the touched
+// region onto the Layer, and clears that region for the next instruction.
+// Shapes with known bounds only draw, composite and clear inside their dirty
+// rectangle; the overlay is scanned for the touched region only when the
+// bounds are unknown.
+func (l *Layer) loadBatched(shape Shape) {
+	l.ensureScratch()
+
+	dirty, ok := dirtyRect(shape, l.image.Bounds())
+	if ok {
+		if dirty.Empty() {
+			return
+		}
+		shape.Draw(l.image.SubImage(dirty).(*image.RGBA), l.scratch.SubImage(dirty).(*image.RGBA))
+	} else {
+		shape.Draw(l.image, l.scratch)
+		dirty = dirtyBounds(l.scratch)
+		if dirty.Empty() {
+			return
+		}
+	}
+	draw.Draw(l.image, dirty, l.scratch, dirty.Min, draw.Over)
+	clearRect(l.scratch, dirty)
+}
+
+// dirtyBounds returns the smallest rectangle containing every pixel of img
+// that has any non-zero channel. Rows are scanned from both ends so mostly
+// empty buffers are rejected quickly.
+func dirtyBounds(img *image.RGBA) image.Rectangle {
+	b := img.Bounds()
+	rowLen := b.Dx() * 4
+	minX, maxX := b.Max.X, b.Min.X
+	minY, maxY := b.Max.Y, b.Min.Y
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		o := img.PixOffset(b.Min.X, y)
+		row := img.Pix[o : o+rowLen]
+
+		first := -1
+		for i, v := range row {
+			if v != 0 {
+				first = i
+				break
+			}
+		}
+		if first < 0 {
+			continue
+		}
+		last := first
+		for i := len(row) - 1; i > first; i-- {
+			if row[i] != 0 {
+				last = i
+				break
+			}
+		}
+
+		if x := b.Min.X + first/4; x < minX {
+			minX = x
+		}
+		if x := b.Min.X + last/4 + 1; x > maxX {
+			maxX = x
+		}
+		if y < minY {
+			minY = y
+		}
+		maxY = y + 1
+	}
+
+	if minX >= maxX || minY >= maxY {
+		return image.Rectangle{}
+	}
+	return image.Rect(minX, minY, maxX, maxY)
+}
+
+// clearRect zeroes the pixels of img inside r.
+func clearRect(img *image.RGBA, r image.Rectangle) {
+	r = r.Intersect(img.Bounds())
+	if r.Empty() {
+		return
+	}
+	n := r.Dx() * 4
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		o := img.PixOffset(r.Min.X, y)
+		clear(img.Pix[o : o+n])
+	}
+}
@@ -0,0 +1,76 @@
+package instructions
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/draw"
+
+	"github.com/Krispeckt/glimo/internal/render"
+)
+
+// dirtyPad is the margin added around declared shape bounds to cover
+// antialiased edges that fall just outside them.
+const dirtyPad = 2
+
+// drawBounder is implemented by built-in shapes whose drawn area differs from
+// their Position/Size box, e.g. because of outside strokes or text alignment.
+// ok is false when the shape may touch pixels anywhere on the canvas, such as
+// when effects like drop shadows or blurs are attached.
+type drawBounder interface {
+	drawBounds() (r image.Rectangle, ok bool)
+}
+
+// dirtyRect returns the canvas region a shape may modify, clipped to canvas.
+// ok is false when the region is unknown and the whole canvas must be used.
+func dirtyRect(sh Shape, canvas image.Rectangle) (image.Rectangle, bool) {
+	r, ok := shapeBounds(sh)
+	if !ok {
+		return canvas, false
+	}
+	if r.Empty() {
+		return r, true
+	}
+	return r.Inset(-dirtyPad).Intersect(canvas), true
+}
+
+// shapeBounds returns the unclipped region a shape may modify.
+func shapeBounds(sh Shape) (image.Rectangle, bool) {
+	switch s := sh.(type) {
+	case drawBounder:
+		return s.drawBounds()
+	case BoundedShape:
+		sz := s.Size()
+		if sz == nil || sz.Width() <= 0 || sz.Height() <= 0 {
+			return image.Rectangle{}, false
+		}
+		return boxBounds(s, 0), true
+	default:
+		return image.Rectangle{}, false
+	}
+}
+
+// loadBounded draws shape into an overlay covering only its dirty rectangle
+// and composites that region back. The shape receives a base cropped to the
+// same rectangle, mirroring how Group hands sub-regions to its children.
+func (l *Layer) loadBounded(shape Shape, r image.Rectangle) {
+	if r.Empty() {
+		return
+	}
+	overlay := render.GetRGBA(r)
+	shape.Draw(cloneBaseTo(r, l.image), overlay)
+
+	draw.Draw(l.image, r, overlay, r.Min, draw.Over)
+	render.PutRGBA(overlay)
+}
+
+// boxBounds returns the Position/Size box of s grown by pad on every side.
+func boxBounds(s BoundedShape, pad float64) image.Rectangle {
+	x, y := s.Position()
+	sz := s.Size()
+	p := int(math.Ceil(pad))
+	return image.Rect(x-p, y-p,
+		x+int(math.Ceil(sz.Width()))+p,
+		y+int(math.Ceil(sz.Height()))+p,
+	)
+}
@@ -0,0 +1,75 @@
+package instructions
+
+import (
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"io"
+	"math"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// FrameDiff is one frame of a sequence rendered by Layer.RenderFrames: the
+// pixels inside Rect, which is the whole canvas for the first frame and the
+// repainted region for the others. Pixels outside Rect are unchanged from the
+// previous frame; an empty Rect repeats it.
+type FrameDiff struct {
+	Rect  image.Rectangle
+	Image *image.RGBA // bounds equal Rect, nil when Rect is empty
+}
+
+// RenderFrames renders n frames of the retained scene (see Retain). Before
+// frame i, update(i) changes the scene, for example with Text.SetText on a
+// volatile text, and the frame is drawn with Rerender, so only the changed
+// region is repainted and copied. A countdown that changes a few digits per
+// frame costs a few digits of rendering per frame.
+func (l *Layer) RenderFrames(n int, update func(i int)) []FrameDiff {
+	if l == nil || l.image == nil || n <= 0 {
+		return nil
+	}
+	frames := make([]FrameDiff, 0, n)
+	for i := range n {
+		if update != nil {
+			update(i)
+		}
+		l.Rerender()
+		r := l.Repainted()
+		if i == 0 {
+			r = l.image.Bounds()
+		}
+		f := FrameDiff{Rect: r}
+		if !r.Empty() {
+			f.Image = image.NewRGBA(r)
+			draw.Draw(f.Image, r, l.image, r.Min, draw.Src)
+		}
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+// EncodeGIF writes frames as an animated GIF looping forever, each frame shown
+// for delay. Frames after the first store only their changed rectangle over
+// the previous frame, and frames without changes extend the previous one.
+// GIF delays are in hundredths of a second, so 60 fps rounds to 50 fps.
+// Colors are reduced to the Plan 9 palette with Floyd–Steinberg dithering,
+// without transparency.
+func EncodeGIF(w io.Writer, frames []FrameDiff, delay time.Duration) error {
+	cs := max(int(math.Round(delay.Seconds()*100)), 1)
+	anim := &gif.GIF{}
+	for _, f := range frames {
+		if f.Rect.Empty() || f.Image == nil {
+			if len(anim.Delay) > 0 {
+				anim.Delay[len(anim.Delay)-1] += cs
+			}
+			continue
+		}
+		pm := image.NewPaletted(f.Rect, palette.Plan9)
+		draw.FloydSteinberg.Draw(pm, f.Rect, f.Image, f.Rect.Min)
+		anim.Image = append(anim.Image, pm)
+		anim.Delay = append(anim.Delay, cs)
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+	}
+	return gif.EncodeAll(w, anim)
+}
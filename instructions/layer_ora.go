@@ -0,0 +1,194 @@
+package instructions
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"reflect"
+
+	"golang.org/x/image/draw"
+)
+
+// DocumentLayer is one named raster layer of a layered export.
+type DocumentLayer struct {
+	Name string
+	// Image holds the layer's pixels, placed on the canvas at
+	// Image.Bounds().Min.
+	Image *image.RGBA
+	// Opacity in (0, 1]; zero is treated as fully opaque, use Hidden to hide
+	// a layer.
+	Opacity float64
+	Hidden  bool
+}
+
+// SplitLayers draws each shape alone on a transparent width×height canvas at
+// the given device scale and returns one DocumentLayer per shape, bottom
+// first, cropped to the area the shape covers. Groups and layouts become one
+// layer each. Names are the shape type and index, e.g. "Text 3". Layers are
+// in device pixels, so export them at the scaled canvas size.
+//
+// Shapes drawn in isolation cannot blend with what lies beneath, so blend
+// modes other than normal and effects that read the backdrop look different
+// until the layers are composited by the editor.
+func SplitLayers(width, height int, scale float64, shapes ...Shape) []DocumentLayer {
+	var out []DocumentLayer
+	for i, s := range shapes {
+		if s == nil {
+			continue
+		}
+		l := NewLayerWithScale(width, height, scale)
+		r, _ := dirtyRect(scaleShape(s, l.device()), l.image.Bounds())
+		l.LoadInstruction(s)
+		if r.Empty() {
+			continue
+		}
+		img := image.NewRGBA(r)
+		draw.Draw(img, r, l.image, r.Min, draw.Src)
+		out = append(out, DocumentLayer{
+			Name:    fmt.Sprintf("%s %d", shapeTypeName(s), i+1),
+			Image:   img,
+			Opacity: 1,
+		})
+	}
+	return out
+}
+
+// shapeTypeName returns the bare type name of a shape, e.g. "Rectangle".
+func shapeTypeName(s Shape) string {
+	t := reflect.TypeOf(s)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// ExportORA writes layers, bottom first, as an OpenRaster (.ora) document of
+// width×height pixels, which GIMP, Krita and MyPaint open as separate
+// layers. See EncodeORA.
+func ExportORA(path string, width, height int, layers ...DocumentLayer) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := EncodeORA(f, width, height, layers...); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// EncodeORA writes layers, bottom first, as an OpenRaster document of
+// width×height pixels to w. The document also holds the flattened image and
+// a thumbnail, for viewers without layer support.
+func EncodeORA(w io.Writer, width, height int, layers ...DocumentLayer) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("ora: invalid size %dx%d", width, height)
+	}
+	zw := zip.NewWriter(w)
+
+	// The mimetype must come first and uncompressed.
+	mt, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mt, "image/openraster"); err != nil {
+		return err
+	}
+
+	doc := oraImage{Version: "0.0.3", W: width, H: height}
+	merged := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i, ly := range layers {
+		if ly.Image == nil {
+			continue
+		}
+		src := fmt.Sprintf("data/layer%d.png", i)
+		if err := writeZipPNG(zw, src, ly.Image); err != nil {
+			return err
+		}
+		opacity := ly.Opacity
+		if opacity <= 0 || opacity > 1 {
+			opacity = 1
+		}
+		visibility := "visible"
+		if ly.Hidden {
+			visibility = "hidden"
+		} else {
+			draw.DrawMask(merged, ly.Image.Bounds(), ly.Image, ly.Image.Bounds().Min,
+				image.NewUniform(color.Alpha{A: uint8(opacity*255 + 0.5)}), image.Point{}, draw.Over)
+		}
+		b := ly.Image.Bounds()
+		// The stack lists the topmost layer first.
+		doc.Stack.Layers = append([]oraLayer{{
+			Name:       ly.Name,
+			Src:        src,
+			X:          b.Min.X,
+			Y:          b.Min.Y,
+			Opacity:    fmt.Sprintf("%.3f", opacity),
+			Visibility: visibility,
+		}}, doc.Stack.Layers...)
+	}
+
+	sw, err := zw.Create("stack.xml")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(sw, xml.Header); err != nil {
+		return err
+	}
+	if err := xml.NewEncoder(sw).Encode(doc); err != nil {
+		return err
+	}
+	if err := writeZipPNG(zw, "mergedimage.png", merged); err != nil {
+		return err
+	}
+	if err := writeZipPNG(zw, "Thumbnails/thumbnail.png", oraThumbnail(merged)); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// oraImage is the root of stack.xml.
+type oraImage struct {
+	XMLName xml.Name `xml:"image"`
+	Version string   `xml:"version,attr"`
+	W       int      `xml:"w,attr"`
+	H       int      `xml:"h,attr"`
+	Stack   struct {
+		Layers []oraLayer `xml:"layer"`
+	} `xml:"stack"`
+}
+
+// oraLayer is a <layer> element of stack.xml.
+type oraLayer struct {
+	Name       string `xml:"name,attr"`
+	Src        string `xml:"src,attr"`
+	X          int    `xml:"x,attr"`
+	Y          int    `xml:"y,attr"`
+	Opacity    string `xml:"opacity,attr"`
+	Visibility string `xml:"visibility,attr"`
+}
+
+// writeZipPNG adds img to zw as a PNG named name.
+func writeZipPNG(zw *zip.Writer, name string, img image.Image) error {
+	// PNG data is already compressed.
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	return png.Encode(f, img)
+}
+
+// oraThumbnail scales img to fit the 256×256 OpenRaster thumbnail limit.
+func oraThumbnail(img *image.RGBA) *image.RGBA {
+	b := img.Bounds()
+	k := min(256/float64(b.Dx()), 256/float64(b.Dy()), 1)
+	w, h := max(int(float64(b.Dx())*k), 1), max(int(float64(b.Dy())*k), 1)
+	thumb := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(thumb, thumb.Bounds(), img, b, draw.Src, nil)
+	return thumb
+}
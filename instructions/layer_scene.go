@@ -0,0 +1,127 @@
+package instructions
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// retained is a Layer scene entry: a shape plus the region it covered when
+// it was last drawn.
+type retained struct {
+	shape   Shape
+	bounds  image.Rectangle
+	bounded bool
+	dirty   bool
+}
+
+// Retain draws shapes like LoadInstructions and keeps them in the Layer's
+// retained scene, so they can later be mutated, invalidated and redrawn with
+// Rerender. The first call snapshots the current pixels as the scene
+// background. A strict Layer neither draws nor retains shapes with
+// configuration errors.
+//
+// Line instructions consume their pending operations when drawn and cannot be
+// redrawn; draw them with LoadInstruction before retaining other shapes instead.
+func (l *Layer) Retain(shapes ...Shape) *Layer {
+	if l == nil || l.image == nil {
+		return l
+	}
+	if l.sceneBase == nil {
+		l.sceneBase = image.NewRGBA(l.image.Bounds())
+		draw.Draw(l.sceneBase, l.sceneBase.Bounds(), l.image, l.image.Bounds().Min, draw.Src)
+	}
+	for _, s := range shapes {
+		if s == nil || l.refuse(s) {
+			continue
+		}
+		n := &retained{shape: s}
+		n.bounds, n.bounded = l.drawRegion(s)
+		l.scene = append(l.scene, n)
+		l.LoadInstruction(s)
+	}
+	return l
+}
+
+// Invalidate marks a retained shape as changed. The next Rerender repaints
+// the area it covered last time together with the area it covers now.
+// Shapes that are not part of the scene are ignored.
+func (l *Layer) Invalidate(shape Shape) *Layer {
+	if l == nil {
+		return l
+	}
+	for _, n := range l.scene {
+		if n.shape == shape {
+			n.dirty = true
+		}
+	}
+	return l
+}
+
+// volatileShape is implemented by shapes that can be marked as changing on
+// every frame, like Text.SetVolatile.
+type volatileShape interface {
+	Volatile() bool
+}
+
+// Rerender repaints the regions of invalidated and volatile shapes. The scene
+// background is restored inside the union of their old and new bounds, and
+// every retained shape touching that union is drawn again in order, clipped
+// to it. Shapes without known bounds widen the repaint to the whole canvas.
+func (l *Layer) Rerender() *Layer {
+	if l == nil || l.image == nil || l.sceneBase == nil {
+		return l
+	}
+	canvas := l.image.Bounds()
+	l.repainted = image.Rectangle{}
+
+	var region image.Rectangle
+	for _, n := range l.scene {
+		if v, ok := n.shape.(volatileShape); ok && v.Volatile() {
+			n.dirty = true
+		}
+		if !n.dirty {
+			continue
+		}
+		r, ok := l.drawRegion(n.shape)
+		if !ok || !n.bounded {
+			region = canvas
+			break
+		}
+		region = region.Union(n.bounds).Union(r)
+	}
+	region = region.Intersect(canvas)
+	if region.Empty() {
+		return l
+	}
+	l.repainted = region
+
+	draw.Draw(l.image, region, l.sceneBase, region.Min, draw.Src)
+	for _, n := range l.scene {
+		n.bounds, n.bounded = l.drawRegion(n.shape)
+		n.dirty = false
+		l.drawShape(n.shape, region)
+	}
+	return l
+}
+
+// Repainted returns the region the last Rerender changed, empty when it had
+// nothing to repaint. Frame encoders can store only this part of the image.
+func (l *Layer) Repainted() image.Rectangle {
+	if l == nil {
+		return image.Rectangle{}
+	}
+	return l.repainted
+}
+
+// ResetScene drops the retained scene and its background snapshot.
+// Pixels already drawn are kept.
+func (l *Layer) ResetScene() *Layer {
+	if l == nil {
+		return l
+	}
+	l.scene = nil
+	l.sceneBase = nil
+	l.repainted = image.Rectangle{}
+	return l
+}
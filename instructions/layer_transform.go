@@ -0,0 +1,91 @@
+package instructions
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+	imageUtil "github.com/Krispeckt/glimo/internal/core/image"
+	"github.com/Krispeckt/glimo/patterns"
+	xdraw "golang.org/x/image/draw"
+)
+
+// ResizeFilter selects the resampling kernel used by Layer.Resize.
+type ResizeFilter int
+
+const (
+	// ResizeNearest picks the nearest source pixel. Fastest, blocky results.
+	ResizeNearest ResizeFilter = iota
+	// ResizeApproxBiLinear is a fast approximation of bilinear filtering.
+	ResizeApproxBiLinear
+	// ResizeBiLinear interpolates linearly between the four nearest pixels.
+	ResizeBiLinear
+	// ResizeCatmullRom uses the Catmull-Rom cubic kernel. Sharpest, slowest.
+	ResizeCatmullRom
+)
+
+// interpolator maps the filter to its x/image/draw implementation.
+// Unknown values fall back to Catmull-Rom.
+func (f ResizeFilter) interpolator() xdraw.Interpolator {
+	switch f {
+	case ResizeNearest:
+		return xdraw.NearestNeighbor
+	case ResizeApproxBiLinear:
+		return xdraw.ApproxBiLinear
+	case ResizeBiLinear:
+		return xdraw.BiLinear
+	default:
+		return xdraw.CatmullRom
+	}
+}
+
+// Resize resamples the Layer's pixels to w×h with the given filter and
+// replaces the backing buffer. Unlike SetSize, content is scaled rather than
+// clipped. Non-positive dimensions leave the Layer unchanged.
+func (l *Layer) Resize(w, h int, filter ResizeFilter) *Layer {
+	if l == nil || l.image == nil || w <= 0 || h <= 0 {
+		return l
+	}
+	l.setImage(imageUtil.ResizeRGBAWith(l.image, w, h, filter.interpolator()))
+	return l
+}
+
+// Crop keeps only the pixels inside r, given in the Layer's image coordinates,
+// and replaces the backing buffer with a copy whose origin is (0, 0).
+// The rectangle is clamped to the current bounds.
+func (l *Layer) Crop(r image.Rectangle) *Layer {
+	if l == nil || l.image == nil {
+		return l
+	}
+	l.setImage(imageUtil.CropRGBA(l.image, r))
+	return l
+}
+
+// ExtendCanvas grows the Layer by the given margins in pixels, placing the
+// current content at (left, top) and painting the new area with fill.
+// Negative margins are treated as zero; use Crop to shrink.
+func (l *Layer) ExtendCanvas(top, right, bottom, left int, fill patterns.Color) *Layer {
+	if l == nil || l.image == nil {
+		return l
+	}
+	top, right = geom.MaxInt(top, 0), geom.MaxInt(right, 0)
+	bottom, left = geom.MaxInt(bottom, 0), geom.MaxInt(left, 0)
+
+	src := l.image
+	sb := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, sb.Dx()+left+right, sb.Dy()+top+bottom))
+
+	bg := color.NRGBA{R: fill.R, G: fill.G, B: fill.B, A: fill.A}
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	draw.Draw(dst, sb.Sub(sb.Min).Add(image.Pt(left, top)), src, sb.Min, draw.Src)
+
+	l.setImage(dst)
+	return l
+}
+
+// setImage swaps the backing buffer and refreshes the cached size.
+func (l *Layer) setImage(rgba *image.RGBA) {
+	l.image = rgba
+	l.size = geom.NewSizeFromImage(rgba)
+}
@@ -0,0 +1,195 @@
+package instructions
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrCanvasTooLarge is matched by errors.Is for a *LimitError on the canvas
+// width, height or pixel count.
+var ErrCanvasTooLarge = errors.New("canvas too large")
+
+// Limits are hard caps on the resources a render may use, for services that
+// draw untrusted requests. Zero fields are unlimited.
+type Limits struct {
+	MaxWidth        int     // canvas width in device pixels
+	MaxHeight       int     // canvas height in device pixels
+	MaxPixels       int64   // canvas width × height in device pixels
+	MaxEffectRadius float64 // largest effect radius in pixels (see effects.Radiused)
+	MaxInstructions int     // instructions loaded into one Layer
+	MaxFontSize     float64 // text size in points, per-line scale steps included
+}
+
+// DefaultLimits returns limits suited to rendering untrusted requests: 50
+// megapixels like DefaultDecodeLimits, 10000 instructions, effect radii up
+// to 256 pixels and text up to 256 points. Canvas width and height are
+// only capped through the pixel count.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxPixels:       50_000_000,
+		MaxEffectRadius: 256,
+		MaxInstructions: 10_000,
+		MaxFontSize:     256,
+	}
+}
+
+// LimitError reports the limit a request exceeded.
+type LimitError struct {
+	Limit string  // name of the exceeded limit, e.g. "canvas width"
+	Value float64 // requested value
+	Max   float64 // configured limit
+}
+
+// Error implements the error interface.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s %g exceeds the limit of %g", e.Limit, e.Value, e.Max)
+}
+
+// Is reports whether target is ErrCanvasTooLarge and e is a canvas limit.
+func (e *LimitError) Is(target error) bool {
+	if target != ErrCanvasTooLarge {
+		return false
+	}
+	switch e.Limit {
+	case "canvas width", "canvas height", "canvas pixels":
+		return true
+	}
+	return false
+}
+
+// CheckCanvas reports whether a width×height canvas at the given device scale
+// fits the dimension and pixel limits. Non-positive scales are treated as 1.
+func (lim Limits) CheckCanvas(width, height int, scale float64) error {
+	if scale <= 0 {
+		scale = 1
+	}
+	w := int64(math.Ceil(float64(width) * scale))
+	h := int64(math.Ceil(float64(height) * scale))
+	switch {
+	case lim.MaxWidth > 0 && w > int64(lim.MaxWidth):
+		return &LimitError{Limit: "canvas width", Value: float64(w), Max: float64(lim.MaxWidth)}
+	case lim.MaxHeight > 0 && h > int64(lim.MaxHeight):
+		return &LimitError{Limit: "canvas height", Value: float64(h), Max: float64(lim.MaxHeight)}
+	case lim.MaxPixels > 0 && w*h > lim.MaxPixels:
+		return &LimitError{Limit: "canvas pixels", Value: float64(w * h), Max: float64(lim.MaxPixels)}
+	}
+	return nil
+}
+
+// CheckShapes reports whether loading shapes on top of loaded earlier
+// instructions fits the instruction, effect radius and font size limits.
+// Shapes nested
+// in Groups, AutoLayouts, BoundedBoxes and Aligned shapes count as
+// instructions too and have their effects inspected.
+func (lim Limits) CheckShapes(loaded int, shapes ...Shape) error {
+	if n := loaded + countShapes(shapes...); lim.MaxInstructions > 0 && n > lim.MaxInstructions {
+		return &LimitError{Limit: "instruction count", Value: float64(n), Max: float64(lim.MaxInstructions)}
+	}
+	for i, s := range shapes {
+		if lim.MaxEffectRadius <= 0 {
+			break
+		}
+		if r := maxEffectRadius(s); r > lim.MaxEffectRadius {
+			return &LimitError{Limit: fmt.Sprintf("instruction %d effect radius", loaded+i), Value: r, Max: lim.MaxEffectRadius}
+		}
+	}
+	for i, s := range shapes {
+		if lim.MaxFontSize <= 0 {
+			break
+		}
+		if pt := maxFontSize(s); pt > lim.MaxFontSize {
+			return &LimitError{Limit: fmt.Sprintf("instruction %d font size", loaded+i), Value: pt, Max: lim.MaxFontSize}
+		}
+	}
+	return nil
+}
+
+// countShapes returns the number of shapes, their nested children included.
+func countShapes(shapes ...Shape) int {
+	n := 0
+	for _, s := range shapes {
+		if s == nil {
+			continue
+		}
+		n += 1 + countShapes(childShapes(s)...)
+	}
+	return n
+}
+
+// maxEffectRadius returns the largest effect radius on s or its nested
+// children.
+func maxEffectRadius(s Shape) float64 {
+	if s == nil {
+		return 0
+	}
+	var r float64
+	if e, ok := s.(effectCarrier); ok {
+		r = e.effectList().MaxRadius()
+	}
+	for _, child := range childShapes(s) {
+		r = math.Max(r, maxEffectRadius(child))
+	}
+	return r
+}
+
+// maxFontSize returns the largest point size texts in s or its nested
+// children draw at.
+func maxFontSize(s Shape) float64 {
+	if s == nil {
+		return 0
+	}
+	var pt float64
+	if t, ok := s.(*Text); ok && t.font != nil {
+		pt = t.font.HeightPt()
+		if t.scaleStep > 0 && t.text != "" {
+			lines, _ := t.wrapTextScaled()
+			pt += t.scaleStep * float64(max(len(lines)-1, 0))
+		}
+	}
+	for _, child := range childShapes(s) {
+		pt = math.Max(pt, maxFontSize(child))
+	}
+	return pt
+}
+
+// NewLayerWithLimits is NewLayerWithScale for untrusted input: it returns a
+// *LimitError instead of allocating a canvas over lim, and the Layer keeps
+// lim for LoadInstructionsChecked.
+func NewLayerWithLimits(width, height int, scale float64, lim Limits) (*Layer, error) {
+	if err := lim.CheckCanvas(width, height, scale); err != nil {
+		return nil, err
+	}
+	return NewLayerWithScale(width, height, scale).SetLimits(lim), nil
+}
+
+// SetLimits sets the limits LoadInstructionsChecked enforces. They do not
+// apply to the existing canvas; see NewLayerWithLimits.
+// Returns the receiver for chaining.
+func (l *Layer) SetLimits(lim Limits) *Layer {
+	l.limits = lim
+	return l
+}
+
+// Limits returns the limits set with SetLimits or NewLayerWithLimits.
+func (l *Layer) Limits() Limits {
+	return l.limits
+}
+
+// LoadInstructionsChecked is LoadInstructions under the Layer's limits. All
+// shapes are checked before any is drawn, so on a *LimitError, or on a
+// strict Layer on configuration errors (see SetStrict and Validate), the
+// Layer is left unchanged. Instructions loaded earlier by any method count
+// towards MaxInstructions.
+func (l *Layer) LoadInstructionsChecked(shapes ...Shape) error {
+	if err := l.limits.CheckShapes(l.loaded, shapes...); err != nil {
+		return err
+	}
+	if l.strict {
+		if err := Validate(shapes...); err != nil {
+			return err
+		}
+	}
+	l.LoadInstructions(shapes...)
+	return nil
+}
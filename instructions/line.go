@@ -4,11 +4,12 @@ import (
 	"image"
 	"image/draw"
 	"math"
+	"slices"
 
 	"github.com/Krispeckt/glimo/colors"
 	"github.com/Krispeckt/glimo/internal/core/geom"
-	"github.com/Krispeckt/glimo/internal/core/image/patterns"
 	"github.com/Krispeckt/glimo/internal/render"
+	"github.com/Krispeckt/glimo/patterns"
 	"github.com/golang/freetype/raster"
 	"golang.org/x/image/math/fixed"
 )
@@ -46,7 +47,11 @@ const (
 )
 
 // Line is the public facade that exposes a vector drawing API.
-type Line struct{ eng *engine }
+type Line struct {
+	eng *engine
+	shapeID
+	configErrors
+}
 
 // NewLine creates a new Line with default styles and identity transform.
 func NewLine() *Line {
@@ -56,19 +61,48 @@ func NewLine() *Line {
 			lineJoin:      LineJoinRound,
 			fillRule:      FillRuleWinding,
 			lineWidth:     1,
+			strokePos:     StrokeCenter,
 			matrix:        geom.Identity(),
 			fillPattern:   patterns.NewSolid(colors.Transparent),
 			strokePattern: patterns.NewSolid(colors.Black),
+			strokePath:    render.GetPath(),
+			fillPath:      render.GetPath(),
 		},
 	}
 }
 
+// release returns the path buffers to the pool. Shapes drawing through a
+// temporary Line call it once the Line has been drawn and is dropped.
+func (l *Line) release() {
+	e := l.eng
+	render.PutPath(e.strokePath)
+	render.PutPath(e.fillPath)
+	e.strokePath, e.fillPath = nil, nil
+}
+
+// blendAccurately makes the line blend its patterns accurately, as a Line
+// loaded into a Layer with SetAccurateBlending does. Shapes drawing through
+// a temporary Line use it to pass their own flag on.
+func (l *Line) blendAccurately(on bool) *Line { l.eng.dev.accurate = on; return l }
+
 // WithMatrix sets the current transform matrix.
 func (l *Line) WithMatrix(m geom.Matrix) *Line { l.eng.matrix = m; return l }
 
 // ResetMatrix resets the transform matrix to identity.
 func (l *Line) ResetMatrix() *Line { l.eng.matrix = geom.Identity(); return l }
 
+// SetTransform composes m after the current matrix (see WithMatrix), so
+// paths added afterwards are drawn transformed like other shapes'
+// SetTransform. A Line has no box to anchor m to: m applies in canvas
+// coordinates, so chain Translate to pick its origin.
+func (l *Line) SetTransform(m Matrix) *Line {
+	l.eng.matrix = l.eng.matrix.Multiply(m)
+	return l
+}
+
+// SetID names the line for FindByID.
+func (l *Line) SetID(id string) *Line { l.id = id; return l }
+
 // SetLineCap sets the cap style for strokes.
 func (l *Line) SetLineCap(c LineCap) *Line { l.eng.lineCap = c; return l }
 
@@ -78,20 +112,57 @@ func (l *Line) SetLineJoin(j LineJoin) *Line { l.eng.lineJoin = j; return l }
 // SetFillRule sets the fill rule used to determine interior.
 func (l *Line) SetFillRule(r FillRule) *Line { l.eng.fillRule = r; return l }
 
-// SetLineWidth sets the stroke width in pixels.
-func (l *Line) SetLineWidth(w float64) *Line { l.eng.lineWidth = w; return l }
+// SetLineWidth sets the stroke width in pixels. Negative widths are clamped
+// to 0.
+func (l *Line) SetLineWidth(w float64) *Line {
+	l.check(w >= 0, "Line", "SetLineWidth", "negative width clamped to 0")
+	l.eng.lineWidth = math.Max(w, 0)
+	return l
+}
 
-// SetDashes sets dash lengths alternating on/off along the stroke.
-func (l *Line) SetDashes(d []float64) *Line { l.eng.dashes = d; return l }
+// SetStrokePosition aligns subsequent strokes to the path like Figma's
+// stroke alignment: StrokeCenter (the default) straddles the path, while
+// StrokeInside and StrokeOutside keep the whole width inside or outside the
+// region the path would fill under the current fill rule. Inside and outside
+// are meant for closed paths; open subpaths are closed by their fill region.
+func (l *Line) SetStrokePosition(p StrokePosition) *Line { l.eng.strokePos = p; return l }
+
+// SetDashes sets dash lengths alternating on/off along the stroke. Patterns
+// with negative lengths are ignored.
+func (l *Line) SetDashes(d []float64) *Line {
+	neg := slices.ContainsFunc(d, func(v float64) bool { return v < 0 })
+	l.check(!neg, "Line", "SetDashes", "negative dash lengths ignored")
+	if !neg {
+		l.eng.dashes = d
+	}
+	return l
+}
 
 // SetDashOffset sets the initial dash offset along the path.
 func (l *Line) SetDashOffset(off float64) *Line { l.eng.dashOffset = off; return l }
 
-// SetStrokePattern sets the pattern used to paint strokes.
-func (l *Line) SetStrokePattern(p patterns.Pattern) *Line { l.eng.strokePattern = p; return l }
+// SetStrokePattern sets the pattern used to paint strokes. Nil is ignored.
+func (l *Line) SetStrokePattern(p patterns.Pattern) *Line {
+	l.check(p != nil, "Line", "SetStrokePattern", "nil pattern ignored")
+	if p != nil {
+		l.eng.strokePattern = p
+	}
+	return l
+}
+
+// SetFillPattern sets the pattern used to paint fills. Nil is ignored.
+func (l *Line) SetFillPattern(p patterns.Pattern) *Line {
+	l.check(p != nil, "Line", "SetFillPattern", "nil pattern ignored")
+	if p != nil {
+		l.eng.fillPattern = p
+	}
+	return l
+}
 
-// SetFillPattern sets the pattern used to paint fills.
-func (l *Line) SetFillPattern(p patterns.Pattern) *Line { l.eng.fillPattern = p; return l }
+// SetAntiAlias enables or disables anti-aliasing for subsequent strokes,
+// fills and clips. With anti-aliasing off, pixels are either fully covered
+// or untouched, which suits pixel art and exact-coverage masks.
+func (l *Line) SetAntiAlias(aa bool) *Line { l.eng.aliased = !aa; return l }
 
 // ResetMask clears any active clip mask.
 func (l *Line) ResetMask() *Line { l.eng.mask = nil; return l }
@@ -258,36 +329,65 @@ func (l *Line) StrokePreserve() *Line {
 	capper := e.capper()
 	joiner := e.joiner()
 	strokePat := e.strokePattern
+	aliased := e.aliased
+	pos := e.strokePos
+	var region fillSnapshot
+	if pos != StrokeCenter {
+		region = e.fillSnapshot()
+	}
 
 	l.eng.pendingOps = append(l.eng.pendingOps, func(e2 *engine) {
 		var painter raster.Painter
+		sc := e2.drawScale()
+		strokePat := e2.dev.pattern(strokePat)
+
+		// An aligned stroke is a centered stroke twice as wide, clipped to
+		// the inside or outside of the fill region.
+		mask := e2.mask
+		width := lineWidth
+		if pos != StrokeCenter {
+			mask = e2.strokeRegion(region, pos == StrokeOutside)
+			width *= 2
+		}
 
 		useFast := false
-		if solid, ok := strokePat.(*patterns.Solid); ok && e2.mask == nil {
+		if solid, ok := strokePat.(*patterns.Solid); ok {
 			if bp, ok := strokePat.(patterns.BlendedPattern); ok {
 				useFast = (bp.BlendMode() == patterns.BlendPassThrough) && (bp.Opacity() == 1)
 			} else {
 				useFast = true
 			}
-			if useFast {
+			if useFast && mask != nil {
+				painter = render.NewMaskedSolidPainter(e2.overlay, mask, solid.ColorAt(0, 0))
+			} else if useFast {
 				p := raster.NewRGBAPainter(e2.overlay)
 				p.SetColor(solid.ColorAt(0, 0))
 				painter = p
 			}
 		}
 		if painter == nil {
-			painter = render.NewPatternPainter(e2.overlay, e2.base, e2.mask, strokePat)
+			painter = render.NewPatternPainter(e2.overlay, e2.base, mask, strokePat).SetAccurateBlending(e2.dev.accurate)
+		}
+		if aliased {
+			painter = render.NewAliasedPainter(painter)
 		}
 
-		path := spath
+		path := scaleRasterPath(spath, sc)
 		if len(dashes) > 0 {
-			path = rasterPath(dashPath(spoly, dashes, dashOffset))
+			scaledDashes := make([]float64, len(dashes))
+			for i, d := range dashes {
+				scaledDashes[i] = d * sc
+			}
+			path = rasterPath(dashPath(scalePolylines(spoly, sc), scaledDashes, dashOffset*sc))
 		}
 		r := e2.rasterizer
 		r.UseNonZeroWinding = true
 		r.Clear()
-		r.AddStroke(path, geom.Fix(lineWidth), capper, joiner)
+		r.AddStroke(path, geom.Fix(width*sc), capper, joiner)
 		r.Rasterize(painter)
+		if pos != StrokeCenter {
+			render.PutAlpha(mask)
+		}
 	})
 	return l
 }
@@ -310,31 +410,38 @@ func (l *Line) FillPreserve() *Line {
 	}
 	fillPat := e.fillPattern
 	fillRule := e.fillRule
+	aliased := e.aliased
 
 	l.eng.pendingOps = append(l.eng.pendingOps, func(e2 *engine) {
 		var painter raster.Painter
+		sc := e2.drawScale()
+		fillPat := e2.dev.pattern(fillPat)
 
 		useFast := false
-		if solid, ok := fillPat.(*patterns.Solid); ok && e2.mask == nil {
+		if solid, ok := fillPat.(*patterns.Solid); ok {
 			if bp, ok := fillPat.(patterns.BlendedPattern); ok {
 				useFast = (bp.BlendMode() == patterns.BlendPassThrough) && (bp.Opacity() == 1)
 			} else {
 				useFast = true
 			}
-			if useFast {
+			if useFast && e2.mask != nil {
+				painter = render.NewMaskedSolidPainter(e2.overlay, e2.mask, solid.ColorAt(0, 0))
+			} else if useFast {
 				p := raster.NewRGBAPainter(e2.overlay)
 				p.SetColor(solid.ColorAt(0, 0))
 				painter = p
 			}
 		}
 		if painter == nil {
-			painter = render.NewPatternPainter(e2.overlay, e2.base, e2.mask, fillPat)
+			painter = render.NewPatternPainter(e2.overlay, e2.base, e2.mask, fillPat).SetAccurateBlending(e2.dev.accurate)
+		}
+		if aliased {
+			painter = render.NewAliasedPainter(painter)
 		}
 
-		path := make(raster.Path, len(fpath))
-		copy(path, fpath)
+		path := scaleRasterPath(fpath, sc)
 		if hasCurrent {
-			path.Add1(start)
+			path.Add1(scaleFixedPoint(start, sc))
 		}
 		r := e2.rasterizer
 		r.UseNonZeroWinding = fillRule == FillRuleWinding
@@ -352,29 +459,11 @@ func (l *Line) Fill() *Line {
 
 // ClipPreserve updates the clip mask by rasterizing the current fill path.
 func (l *Line) ClipPreserve() *Line {
-	e := l.eng
 	// Snapshot geometry.
-	fpath := make(raster.Path, len(e.fillPath))
-	copy(fpath, e.fillPath)
-	hasCurrent := e.hasCurrent
-	var start fixed.Point26_6
-	if e.start != nil {
-		start = e.start.Fixed()
-	}
-	fillRule := e.fillRule
+	snap := l.eng.fillSnapshot()
 
 	l.eng.pendingOps = append(l.eng.pendingOps, func(e2 *engine) {
-		clip := image.NewAlpha(image.Rect(0, 0, e2.width, e2.height))
-		path := make(raster.Path, len(fpath))
-		copy(path, fpath)
-		if hasCurrent {
-			path.Add1(start)
-		}
-		r := e2.rasterizer
-		r.UseNonZeroWinding = fillRule == FillRuleWinding
-		r.Clear()
-		r.AddPath(path)
-		r.Rasterize(raster.NewAlphaOverPainter(clip))
+		clip := e2.fillAlpha(snap)
 
 		if e2.mask != nil && e2.mask.Bounds() != clip.Bounds() {
 			e2.mask = nil
@@ -390,15 +479,108 @@ func (l *Line) ClipPreserve() *Line {
 	return l
 }
 
+// fillSnapshot is the fill region of the path when an operation was scheduled.
+type fillSnapshot struct {
+	path       raster.Path
+	hasCurrent bool
+	start      fixed.Point26_6
+	rule       FillRule
+	aliased    bool
+}
+
+// fillSnapshot copies the current fill path and rule.
+func (e *engine) fillSnapshot() fillSnapshot {
+	s := fillSnapshot{
+		path:       append(raster.Path(nil), e.fillPath...),
+		hasCurrent: e.hasCurrent,
+		rule:       e.fillRule,
+		aliased:    e.aliased,
+	}
+	if e.start != nil {
+		s.start = e.start.Fixed()
+	}
+	return s
+}
+
+// fillAlpha rasterizes the coverage of a snapshotted fill region at the
+// draw-time scale into a canvas-sized mask.
+func (e *engine) fillAlpha(s fillSnapshot) *image.Alpha {
+	out := render.GetAlpha(image.Rect(0, 0, e.width, e.height))
+	sc := e.drawScale()
+	path := scaleRasterPath(s.path, sc)
+	if s.hasCurrent {
+		path.Add1(scaleFixedPoint(s.start, sc))
+	}
+	r := e.rasterizer
+	r.UseNonZeroWinding = s.rule == FillRuleWinding
+	r.Clear()
+	r.AddPath(path)
+	var painter raster.Painter = raster.NewAlphaOverPainter(out)
+	if s.aliased {
+		painter = render.NewAliasedPainter(painter)
+	}
+	r.Rasterize(painter)
+	return out
+}
+
+// strokeRegion returns the mask an aligned stroke is painted through: the
+// fill region, or its complement when outside, within the current clip.
+func (e *engine) strokeRegion(s fillSnapshot, outside bool) *image.Alpha {
+	region := e.fillAlpha(s)
+	clip := e.mask
+	if clip != nil && clip.Bounds() != region.Bounds() {
+		clip = nil
+	}
+	for i, a := range region.Pix {
+		if outside {
+			a = 255 - a
+		}
+		if clip != nil {
+			a = uint8((uint32(a)*uint32(clip.Pix[i]) + 127) / 255)
+		}
+		region.Pix[i] = a
+	}
+	return region
+}
+
+// scaled returns a view of the line that rasterizes its recorded paths,
+// stroke width and dashes multiplied by the device scale, with patterns
+// prepared for d. Pending operations are consumed exactly as with Draw.
+func (l *Line) scaled(d device) Shape { return &scaledLine{line: l, dev: d} }
+
+// scaledLine draws a Line with a temporary draw-time device.
+type scaledLine struct {
+	line *Line
+	dev  device
+}
+
+// Draw executes the line's pending operations on the wrapped device.
+func (sl *scaledLine) Draw(base, overlay *image.RGBA) {
+	e := sl.line.eng
+	prev := e.dev
+	e.dev = sl.dev
+	sl.line.Draw(base, overlay)
+	e.dev = prev
+}
+
 // Draw executes all pending raster operations onto the provided RGBA image.
+// The operations are consumed: drawing the Line again renders nothing new
+// until more are scheduled. Use Replay to repeat them on another target, or
+// Snapshot for content drawn onto several.
 func (l *Line) Draw(base, overlay *image.RGBA) {
 	e := l.eng
 	e.overlay = overlay
 	e.base = base
 	e.ensureRasterizer()
+	defer e.endDraw()
+	if len(e.pendingOps) == 0 {
+		return
+	}
+	e.drawnMask = e.mask
 	for _, op := range e.pendingOps {
 		op(e)
 	}
+	e.drawnOps = append(e.drawnOps[:0], e.pendingOps...)
 	e.pendingOps = e.pendingOps[:0]
 }
 
@@ -462,6 +644,9 @@ func CubicBezier(x0, y0, x1, y1, x2, y2, x3, y3 float64) []*Point {
 }
 
 // dashPath applies dash pattern to polylines and returns visible segments.
+// On a closed polyline, one whose last point is its first, a dash running
+// across the start point is kept whole rather than split into two pieces
+// meeting at a seam.
 func dashPath(paths [][]*Point, dashes []float64, offset float64) [][]*Point {
 	var result [][]*Point
 	if len(dashes) == 0 {
@@ -476,28 +661,9 @@ func dashPath(paths [][]*Point, dashes []float64, offset float64) [][]*Point {
 		}
 		previous := path[0]
 		pathIndex := 1
-		dashIndex := 0
-		segmentLength := 0.0
-
-		// Normalize and apply initial offset into the dash cycle.
-		if offset != 0 {
-			var totalLength float64
-			for _, dashLength := range dashes {
-				totalLength += dashLength
-			}
-			offset = math.Mod(offset, totalLength)
-			if offset < 0 {
-				offset += totalLength
-			}
-			for i, dashLength := range dashes {
-				offset -= dashLength
-				if offset < 0 {
-					dashIndex = i
-					segmentLength = dashLength + offset
-					break
-				}
-			}
-		}
+		dashIndex, segmentLength := dashPhase(dashes, offset)
+		startsOn := dashIndex%2 == 0
+		first := len(result)
 
 		segment := []*Point{previous}
 		for pathIndex < len(path) {
@@ -524,12 +690,44 @@ func dashPath(paths [][]*Point, dashes []float64, offset float64) [][]*Point {
 			}
 		}
 		if dashIndex%2 == 0 && len(segment) > 1 {
-			result = append(result, segment)
+			closed := path[0].Distance(path[len(path)-1]) < 1e-9
+			if closed && startsOn && len(result) > first {
+				// Continue the last dash into the first one.
+				result[first] = append(segment, result[first][1:]...)
+			} else {
+				result = append(result, segment)
+			}
 		}
 	}
 	return result
 }
 
+// dashPhase returns the dash index and the length already used of it at
+// offset along the dash cycle.
+func dashPhase(dashes []float64, offset float64) (int, float64) {
+	if offset == 0 {
+		return 0, 0
+	}
+	var totalLength float64
+	for _, dashLength := range dashes {
+		totalLength += dashLength
+	}
+	if totalLength <= 0 {
+		return 0, 0
+	}
+	offset = math.Mod(offset, totalLength)
+	if offset < 0 {
+		offset += totalLength
+	}
+	for i, dashLength := range dashes {
+		offset -= dashLength
+		if offset < 0 {
+			return i, dashLength + offset
+		}
+	}
+	return 0, 0
+}
+
 // rasterPath converts polylines to a raster.Path while dropping near-duplicate points.
 func rasterPath(paths [][]*Point) raster.Path {
 	var result raster.Path
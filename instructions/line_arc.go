@@ -0,0 +1,120 @@
+package instructions
+
+import (
+	"math"
+)
+
+// arcTolerance is the largest distance, in canvas pixels, between an arc and
+// the chords approximating it.
+const arcTolerance = 0.05
+
+// ArcTo adds a circular arc of radius r centered at (cx, cy), from
+// startAngle to endAngle in radians. Angles grow clockwise from the positive
+// x axis, as the y axis points down; the arc runs clockwise when endAngle is
+// greater than startAngle and anticlockwise otherwise. A straight segment
+// joins the current point to the start of the arc, or a subpath starts there
+// when there is none, so MoveTo(cx, cy) followed by ArcTo and ClosePath draws
+// a pie slice.
+func (l *Line) ArcTo(cx, cy, r, startAngle, endAngle float64) *Line {
+	return l.arc(cx, cy, r, r, 0, startAngle, endAngle-startAngle)
+}
+
+// EllipticalArcTo adds an elliptical arc from the current point to (x, y),
+// with SVG path "A" semantics: the ellipse has radii rx and ry and is rotated
+// by xAxisRotation degrees, and largeArc and sweep select which of the up to
+// four candidate arcs is drawn, sweep meaning clockwise on screen. Radii too
+// small to reach (x, y) are scaled up, a zero radius draws a straight line
+// and an end point equal to the current point adds nothing.
+func (l *Line) EllipticalArcTo(rx, ry, xAxisRotation float64, largeArc, sweep bool, x, y float64) *Line {
+	e := l.eng
+	if !e.hasCurrent {
+		return l.MoveTo(x, y)
+	}
+	// The current point is stored transformed; arcs are solved in user space.
+	inv := e.matrix.Invert()
+	x0, y0 := inv.TransformPoint(e.current.X, e.current.Y)
+	if x0 == x && y0 == y {
+		return l
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		return l.LineTo(x, y)
+	}
+
+	// Endpoint to center parameterization (SVG 1.1, appendix F.6.5).
+	phi := xAxisRotation * math.Pi / 180
+	sin, cos := math.Sincos(phi)
+	dx, dy := (x0-x)/2, (y0-y)/2
+	x1 := cos*dx + sin*dy
+	y1 := -sin*dx + cos*dy
+
+	// Scale radii up when no ellipse of this size reaches (x, y) (F.6.6).
+	if lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry); lambda > 1 {
+		s := math.Sqrt(lambda)
+		rx *= s
+		ry *= s
+	}
+
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	k := math.Sqrt(math.Max(num, 0) / den)
+	if largeArc == sweep {
+		k = -k
+	}
+	cx1 := k * rx * y1 / ry
+	cy1 := -k * ry * x1 / rx
+	cx := cos*cx1 - sin*cy1 + (x0+x)/2
+	cy := sin*cx1 + cos*cy1 + (y0+y)/2
+
+	theta := vectorAngle(1, 0, (x1-cx1)/rx, (y1-cy1)/ry)
+	delta := vectorAngle((x1-cx1)/rx, (y1-cy1)/ry, (-x1-cx1)/rx, (-y1-cy1)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+
+	return l.arc(cx, cy, rx, ry, phi, theta, delta)
+}
+
+// vectorAngle returns the signed angle from (ux, uy) to (vx, vy).
+func vectorAngle(ux, uy, vx, vy float64) float64 {
+	return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+}
+
+// arc adds the arc of the ellipse centered at (cx, cy) with radii rx, ry,
+// rotated by phi, from angle theta through delta, in user space. It joins
+// the current point to the arc start, or starts a subpath there, and feeds
+// the chords to the raster paths and the dash polylines alike.
+func (l *Line) arc(cx, cy, rx, ry, phi, theta, delta float64) *Line {
+	sin, cos := math.Sincos(phi)
+	at := func(a float64) (float64, float64) {
+		ex, ey := rx*math.Cos(a), ry*math.Sin(a)
+		return cx + cos*ex - sin*ey, cy + sin*ex + cos*ey
+	}
+
+	sx, sy := at(theta)
+	if l.eng.hasCurrent {
+		l.LineTo(sx, sy)
+	} else {
+		l.MoveTo(sx, sy)
+	}
+	if delta == 0 {
+		return l
+	}
+
+	// Chord count from the radius on the canvas, so transformed arcs stay
+	// smooth.
+	m := l.eng.matrix
+	scale := math.Sqrt(math.Abs(m.XX*m.YY - m.XY*m.YX))
+	r := math.Max(rx, ry) * scale
+	step := math.Pi / 2
+	if r > arcTolerance {
+		step = math.Min(step, 2*math.Acos(1-arcTolerance/r))
+	}
+	n := max(int(math.Ceil(math.Abs(delta)/step)), 1)
+	for i := 1; i <= n; i++ {
+		l.LineTo(at(theta + delta*float64(i)/float64(n)))
+	}
+	return l
+}
@@ -2,10 +2,13 @@ package instructions
 
 import (
 	"image"
+	"math"
 
 	"github.com/Krispeckt/glimo/internal/core/geom"
-	"github.com/Krispeckt/glimo/internal/core/image/patterns"
+	"github.com/Krispeckt/glimo/internal/render"
+	"github.com/Krispeckt/glimo/patterns"
 	"github.com/golang/freetype/raster"
+	"golang.org/x/image/math/fixed"
 )
 
 // engine is the rasterization backend that holds drawing state and buffers.
@@ -23,6 +26,7 @@ type engine struct {
 	dashes        []float64
 	dashOffset    float64
 	lineWidth     float64
+	strokePos     StrokePosition
 	mask          *image.Alpha
 	fillPattern   patterns.Pattern
 	strokePattern patterns.Pattern
@@ -31,25 +35,111 @@ type engine struct {
 
 	matrix geom.Matrix
 
+	// dev is the draw-time device: its scale multiplies recorded geometry
+	// (zero means 1) and its patterns are prepared for it.
+	dev device
+
+	// aliased disables anti-aliasing for subsequently scheduled operations.
+	aliased bool
+
 	base, overlay *image.RGBA
 	width, height int
 
 	pendingOps []func(e *engine)
+
+	// drawnOps and drawnMask hold the operations run by the last Draw and
+	// the clip it started with, for Replay.
+	drawnOps  []func(e *engine)
+	drawnMask *image.Alpha
 }
 
-// ensureRasterizer initializes or resizes the rasterizer to match the target image.
+// ensureRasterizer takes a rasterizer matching the target image from the
+// pool, unless the engine holds one already.
 func (e *engine) ensureRasterizer() {
 	if e.overlay == nil {
 		return
 	}
-	w, h := e.overlay.Bounds().Dx(), e.overlay.Bounds().Dy()
-	if e.rasterizer == nil || w != e.width || h != e.height {
+	// Paths are in canvas coordinates, so the rasterizer spans from the canvas
+	// origin to the overlay's far corner even when the overlay is a sub-region.
+	w, h := e.overlay.Bounds().Max.X, e.overlay.Bounds().Max.Y
+	if w != e.width || h != e.height {
+		e.releaseRasterizer()
 		e.width, e.height = w, h
-		e.rasterizer = raster.NewRasterizer(w, h)
-		if e.mask != nil && e.mask.Bounds() != e.overlay.Bounds() {
+		if e.mask != nil && e.mask.Bounds() != image.Rect(0, 0, w, h) {
 			e.mask = nil
 		}
 	}
+	if e.rasterizer == nil {
+		e.rasterizer = render.GetRasterizer(w, h)
+	}
+}
+
+// releaseRasterizer returns the rasterizer to the pool.
+func (e *engine) releaseRasterizer() {
+	if e.rasterizer != nil {
+		render.PutRasterizer(e.rasterizer, e.width, e.height)
+		e.rasterizer = nil
+	}
+}
+
+// endDraw releases the rasterizer and drops the draw targets, which may be
+// pooled buffers too. Called at the end of each Draw.
+func (e *engine) endDraw() {
+	e.releaseRasterizer()
+	e.base, e.overlay = nil, nil
+}
+
+// drawScale returns the active draw-time scale factor.
+func (e *engine) drawScale() float64 {
+	if e.dev.scale <= 0 {
+		return 1
+	}
+	return e.dev.scale
+}
+
+// scaleRasterPath returns a copy of p with every coordinate multiplied by s.
+// The path layout is a sequence of segments [op, coords..., op], where op is
+// the number of points in the segment (0 for a start, which holds one point).
+func scaleRasterPath(p raster.Path, s float64) raster.Path {
+	out := make(raster.Path, len(p))
+	copy(out, p)
+	if s == 1 {
+		return out
+	}
+	for i := 0; i < len(out); {
+		n := int(out[i])
+		if n == 0 {
+			n = 1
+		}
+		for k := i + 1; k <= i+2*n; k++ {
+			out[k] = fixed.Int26_6(math.Round(float64(out[k]) * s))
+		}
+		i += 2*n + 2
+	}
+	return out
+}
+
+// scaleFixedPoint multiplies a fixed-point coordinate by s.
+func scaleFixedPoint(p fixed.Point26_6, s float64) fixed.Point26_6 {
+	return fixed.Point26_6{
+		X: fixed.Int26_6(math.Round(float64(p.X) * s)),
+		Y: fixed.Int26_6(math.Round(float64(p.Y) * s)),
+	}
+}
+
+// scalePolylines returns a copy of polylines with points multiplied by s.
+func scalePolylines(in [][]*Point, s float64) [][]*Point {
+	if s == 1 {
+		return in
+	}
+	out := make([][]*Point, len(in))
+	for i, pl := range in {
+		out[i] = make([]*Point, len(pl))
+		for j, p := range pl {
+			out[i][j] = &Point{X: p.X * s, Y: p.Y * s, color: p.color}
+		}
+	}
+	return out
 }
 
 // capper returns the raster.Capper implementation for the current line cap.
@@ -0,0 +1,63 @@
+package instructions
+
+import (
+	"image"
+	"slices"
+)
+
+// LineSnapshot is the prepared content of a Line frozen as a reusable Shape.
+// Unlike the Line, drawing it does not consume anything, so the same
+// snapshot can be loaded into any number of Layers, each draw starting with
+// no clip. A snapshot is not safe for concurrent draws.
+type LineSnapshot struct {
+	eng *engine
+}
+
+// Snapshot returns the operations scheduled so far (strokes, fills and
+// clips) as a LineSnapshot. The Line is unchanged: its operations stay
+// pending and later ones do not affect the snapshot.
+func (l *Line) Snapshot() *LineSnapshot {
+	return &LineSnapshot{eng: &engine{pendingOps: slices.Clone(l.eng.pendingOps)}}
+}
+
+// Replay schedules again the operations run by the most recent Draw that
+// ran any, ahead of any scheduled since, and restores the clip that Draw
+// started with, so the next Draw repeats the same content on another
+// target. Returns the Line for chaining.
+func (l *Line) Replay() *Line {
+	e := l.eng
+	e.pendingOps = append(slices.Clone(e.drawnOps), e.pendingOps...)
+	e.mask = e.drawnMask
+	return l
+}
+
+// Draw runs the snapshotted operations onto the provided RGBA image.
+func (s *LineSnapshot) Draw(base, overlay *image.RGBA) {
+	e := s.eng
+	e.overlay = overlay
+	e.base = base
+	e.mask = nil
+	e.ensureRasterizer()
+	defer e.endDraw()
+	for _, op := range e.pendingOps {
+		op(e)
+	}
+}
+
+// scaled returns a view of the snapshot drawn on device d.
+func (s *LineSnapshot) scaled(d device) Shape { return &scaledSnapshot{snap: s, dev: d} }
+
+// scaledSnapshot draws a LineSnapshot with a temporary draw-time device.
+type scaledSnapshot struct {
+	snap *LineSnapshot
+	dev  device
+}
+
+// Draw runs the snapshot on the wrapped device.
+func (ss *scaledSnapshot) Draw(base, overlay *image.RGBA) {
+	e := ss.snap.eng
+	prev := e.dev
+	e.dev = ss.dev
+	ss.snap.Draw(base, overlay)
+	e.dev = prev
+}
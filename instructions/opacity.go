@@ -0,0 +1,78 @@
+package instructions
+
+import (
+	"image"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+)
+
+// shapeOpacity holds the opacity and visibility set by a shape's SetOpacity
+// and SetVisible. Shapes embed it and start their Draw with faded, which draws them once more at full
+// opacity into a scratch buffer and fades the result into the overlay, so
+// fill, stroke and effects are faded together rather than one by one.
+type shapeOpacity struct {
+	// fade is 1 - opacity, so the zero value is fully opaque.
+	fade float64
+	// hidden is set by SetVisible(false).
+	hidden bool
+}
+
+// setOpacity stores o clamped to [0, 1].
+func (s *shapeOpacity) setOpacity(o float64) {
+	s.fade = 1 - geom.ClampF64(o, 0, 1)
+}
+
+// Opacity returns the opacity applied to the whole shape, 1 by default.
+func (s *shapeOpacity) Opacity() float64 { return 1 - s.fade }
+
+// setVisible shows or hides the shape.
+func (s *shapeOpacity) setVisible(v bool) { s.hidden = !v }
+
+// Visible reports whether the shape is drawn, true by default.
+func (s *shapeOpacity) Visible() bool { return !s.hidden }
+
+// faded handles a shape below full opacity and reports whether it did, in
+// which case Draw must return. It calls draw, the shape's own Draw, at full
+// opacity on a copy of the overlay limited to the shape's bounds, then mixes
+// the copy into overlay at the opacity. Hidden and fully transparent shapes
+// draw nothing.
+func (s *shapeOpacity) faded(shape Shape, base, overlay *image.RGBA, draw func(base, overlay *image.RGBA)) bool {
+	if s.hidden {
+		return true
+	}
+	if s.fade <= 0 || overlay == nil {
+		return false
+	}
+	if s.fade >= 1 {
+		return true
+	}
+	r := overlay.Bounds()
+	if b, ok := shapeBounds(shape); ok {
+		r = r.Intersect(b.Inset(-dirtyPad))
+	}
+	if r.Empty() {
+		return true
+	}
+
+	scratch := cloneBaseTo(r, overlay)
+	fade := s.fade
+	s.fade = 0
+	draw(base, scratch)
+	s.fade = fade
+	mixInto(overlay, scratch, 1-fade)
+	return true
+}
+
+// mixInto moves every pixel of dst inside src's bounds toward src by t, in
+// premultiplied space.
+func mixInto(dst, src *image.RGBA, t float64) {
+	k := uint32(t*255 + 0.5)
+	r := src.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		d := dst.Pix[dst.PixOffset(r.Min.X, y):dst.PixOffset(r.Max.X, y)]
+		s := src.Pix[src.PixOffset(r.Min.X, y):src.PixOffset(r.Max.X, y)]
+		for i := range d {
+			d[i] = uint8((uint32(d[i])*(255-k) + uint32(s[i])*k + 127) / 255)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package instructions
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// OverflowStrategy is one remedy an OverflowPolicy applies to content that
+// does not fit.
+type OverflowStrategy int
+
+const (
+	// OverflowShrinkText lowers the font size of texts step by step, down to
+	// the policy's MinFontSize.
+	OverflowShrinkText OverflowStrategy = iota
+	// OverflowTruncate limits texts to the policy's MaxLines, ending the last
+	// line with an ellipsis. Texts without a maximum width in an AutoLayout
+	// get the width of its content box.
+	OverflowTruncate
+	// OverflowHideLowPriority removes AutoLayout items from the layout, the
+	// lowest ItemStyle.Priority first and later items first among equals.
+	// The last remaining item is never removed.
+	OverflowHideLowPriority
+	// OverflowGrow enlarges the fixed Width and Height of an AutoLayout to
+	// fit its content. It does nothing for texts.
+	OverflowGrow
+)
+
+// String returns the strategy name.
+func (s OverflowStrategy) String() string {
+	switch s {
+	case OverflowShrinkText:
+		return "shrink"
+	case OverflowTruncate:
+		return "truncate"
+	case OverflowHideLowPriority:
+		return "hide"
+	case OverflowGrow:
+		return "grow"
+	}
+	return fmt.Sprintf("OverflowStrategy(%d)", int(s))
+}
+
+// OverflowPolicy decides what to do when content does not fit, so templates
+// filled with user data of unpredictable length still render cleanly.
+// Attach it with AutoLayout.SetOverflowPolicy, where content fits when no
+// item overflows (see CheckOverflow) and no text was squeezed below its own
+// size by flex shrinking, or with Text.SetOverflowPolicy, where
+// it fits when the text is at most MaxHeight pixels tall. The policy runs
+// when the shape is measured or drawn, applying Strategies in order until the
+// content fits, and records what it did in an OverflowReport.
+type OverflowPolicy struct {
+	Strategies []OverflowStrategy
+
+	MinFontSize float64 // smallest size in points for OverflowShrinkText; 8 when 0
+	ShrinkStep  float64 // points removed per step; 1 when 0
+	MaxLines    int     // line limit for OverflowTruncate; 1 when 0
+	MaxHeight   float64 // height budget in pixels of a Text; ignored by AutoLayout
+}
+
+// OverflowAction is one change made by an OverflowPolicy.
+type OverflowAction struct {
+	Strategy OverflowStrategy
+	// Path locates the changed item like LayoutOverflowError.Path; empty
+	// for the shape the policy is attached to.
+	Path   []string
+	Detail string // e.g. "24pt -> 18pt"
+}
+
+// String formats the action for logs.
+func (a OverflowAction) String() string {
+	if len(a.Path) == 0 {
+		return fmt.Sprintf("%s: %s", a.Strategy, a.Detail)
+	}
+	return fmt.Sprintf("%s %s: %s", a.Strategy, strings.Join(a.Path, "/"), a.Detail)
+}
+
+// OverflowReport tells what an OverflowPolicy did.
+type OverflowReport struct {
+	Actions []OverflowAction
+	// Fits reports whether the content fits after the actions.
+	Fits bool
+}
+
+// defaults returns the policy with zero fields replaced by their defaults.
+func (p OverflowPolicy) defaults() OverflowPolicy {
+	if p.MinFontSize <= 0 {
+		p.MinFontSize = 8
+	}
+	if p.ShrinkStep <= 0 {
+		p.ShrinkStep = 1
+	}
+	if p.MaxLines <= 0 {
+		p.MaxLines = 1
+	}
+	return p
+}
+
+// fitText is a Text in the scope of a policy, with its item path.
+type fitText struct {
+	text *Text
+	path []string
+}
+
+// shrinkTexts lowers every text above the minimum by one step and reports
+// whether any changed.
+func (p OverflowPolicy) shrinkTexts(texts []fitText, from map[*Text]float64) bool {
+	changed := false
+	for _, ft := range texts {
+		t := ft.text
+		if t.font == nil || t.font.HeightPt() <= p.MinFontSize {
+			continue
+		}
+		if _, ok := from[t]; !ok {
+			from[t] = t.font.HeightPt()
+		}
+		f := *t.font
+		t.font = f.SetFontSizePt(math.Max(t.font.HeightPt()-p.ShrinkStep, p.MinFontSize))
+		t.InvalidateLayout()
+		changed = true
+	}
+	return changed
+}
+
+// shrinkActions reports the size changes made by shrinkTexts.
+func shrinkActions(texts []fitText, from map[*Text]float64) []OverflowAction {
+	var out []OverflowAction
+	for _, ft := range texts {
+		if pt, ok := from[ft.text]; ok {
+			out = append(out, OverflowAction{
+				Strategy: OverflowShrinkText,
+				Path:     ft.path,
+				Detail:   fmt.Sprintf("%gpt -> %gpt", pt, ft.text.font.HeightPt()),
+			})
+		}
+	}
+	return out
+}
+
+// truncate limits t to the policy's line count, giving it maxWidth when it
+// has none, and reports the change or false when there was nothing to do.
+func (p OverflowPolicy) truncate(t *Text, path []string, maxWidth float64) (OverflowAction, bool) {
+	if t.maxLines > 0 && t.maxLines <= p.MaxLines && (t.maxWidth > 0 || maxWidth <= 0) {
+		return OverflowAction{}, false
+	}
+	detail := fmt.Sprintf("max %d lines", p.MaxLines)
+	if t.maxWidth <= 0 && maxWidth > 0 {
+		t.SetMaxWidth(maxWidth)
+		detail += fmt.Sprintf(", %gpx wide", maxWidth)
+	}
+	t.SetMaxLines(p.MaxLines)
+	return OverflowAction{Strategy: OverflowTruncate, Path: path, Detail: detail}, true
+}
+
+// SetOverflowPolicy attaches an overflow policy to the text; nil removes it.
+// The policy only acts on a positive MaxHeight and never restores the text.
+func (t *Text) SetOverflowPolicy(p *OverflowPolicy) *Text {
+	t.overflow = p
+	t.overflowReport = nil
+	return t
+}
+
+// OverflowReport returns what the overflow policy did so far, or nil without
+// a policy.
+func (t *Text) OverflowReport() *OverflowReport { return t.overflowReport }
+
+// fitOverflow applies the text's overflow policy.
+func (t *Text) fitOverflow() {
+	if t.overflow == nil || t.fitting || t.overflow.MaxHeight <= 0 {
+		return
+	}
+	t.fitting = true
+	defer func() { t.fitting = false }()
+
+	if t.overflowReport == nil {
+		t.overflowReport = &OverflowReport{}
+	}
+	r := t.overflowReport
+	p := t.overflow.defaults()
+	fits := func() bool { return t.blockSize().Height() <= p.MaxHeight }
+	if r.Fits = fits(); r.Fits {
+		return
+	}
+	self := []fitText{{text: t}}
+	for _, s := range p.Strategies {
+		switch s {
+		case OverflowShrinkText:
+			from := map[*Text]float64{}
+			for !fits() && p.shrinkTexts(self, from) {
+			}
+			r.Actions = append(r.Actions, shrinkActions(self, from)...)
+		case OverflowTruncate:
+			// Drop lines from the policy's limit until the text fits.
+			var last OverflowAction
+			for lines := p.MaxLines; lines >= 1; lines-- {
+				tp := p
+				tp.MaxLines = lines
+				if a, ok := tp.truncate(t, nil, 0); ok {
+					last = a
+				}
+				if fits() {
+					break
+				}
+			}
+			if last.Detail != "" {
+				r.Actions = append(r.Actions, last)
+			}
+		}
+		if r.Fits = fits(); r.Fits {
+			return
+		}
+	}
+}
+
+// SetOverflowPolicy attaches an overflow policy to the layout; nil removes
+// it. Texts of nested AutoLayouts are shrunk and truncated too, but only
+// direct items are hidden and only this layout grows. Changes are not
+// undone when the content later fits.
+func (al *AutoLayout) SetOverflowPolicy(p *OverflowPolicy) *AutoLayout {
+	al.overflow = p
+	al.overflowReport = nil
+	return al
+}
+
+// OverflowReport returns what the overflow policy did so far, or nil without
+// a policy.
+func (al *AutoLayout) OverflowReport() *OverflowReport { return al.overflowReport }
+
+// fitOverflow applies the layout's overflow policy.
+func (al *AutoLayout) fitOverflow() {
+	if al.overflow == nil || al.fitting {
+		return
+	}
+	al.fitting = true
+	defer func() { al.fitting = false }()
+
+	if al.overflowReport == nil {
+		al.overflowReport = &OverflowReport{}
+	}
+	r := al.overflowReport
+	p := al.overflow.defaults()
+	fits := func() bool {
+		al.InvalidateLayout()
+		return al.CheckOverflow() == nil && !al.squeezed()
+	}
+	if r.Fits = fits(); r.Fits {
+		return
+	}
+	for _, s := range p.Strategies {
+		switch s {
+		case OverflowShrinkText:
+			texts := al.texts(nil)
+			from := map[*Text]float64{}
+			for !fits() && p.shrinkTexts(texts, from) {
+			}
+			r.Actions = append(r.Actions, shrinkActions(texts, from)...)
+
+		case OverflowTruncate:
+			_, pr, _, pl := sum4(al.style.Padding)
+			inner := 0.0
+			if al.style.Width > 0 {
+				inner = float64(al.style.Width - pl - pr)
+			}
+			for _, ft := range al.texts(nil) {
+				if a, ok := p.truncate(ft.text, ft.path, inner); ok {
+					r.Actions = append(r.Actions, a)
+				}
+			}
+
+		case OverflowHideLowPriority:
+			order := make([]int, 0, len(al.children))
+			for i, n := range al.children {
+				if !n.dropped {
+					order = append(order, i)
+				}
+			}
+			sort.SliceStable(order, func(a, b int) bool {
+				pa, pb := al.children[order[a]].st.Priority, al.children[order[b]].st.Priority
+				if pa != pb {
+					return pa < pb
+				}
+				return order[a] > order[b]
+			})
+			for k := 0; k < len(order)-1 && !fits(); k++ {
+				n := al.children[order[k]]
+				n.dropped = true
+				r.Actions = append(r.Actions, OverflowAction{
+					Strategy: OverflowHideLowPriority,
+					Path:     []string{itemName(n, order[k])},
+					Detail:   fmt.Sprintf("hidden (priority %d)", n.st.Priority),
+				})
+			}
+
+		case OverflowGrow:
+			w, h := al.style.Width, al.style.Height
+			if w == 0 && h == 0 {
+				break
+			}
+			al.style.Width, al.style.Height = 0, 0
+			al.InvalidateLayout()
+			al.ensureLayout()
+			nw, nh := w, h
+			if w > 0 {
+				nw = max(w, al.w)
+			}
+			if h > 0 {
+				nh = max(h, al.h)
+			}
+			al.style.Width, al.style.Height = nw, nh
+			if nw != w || nh != h {
+				r.Actions = append(r.Actions, OverflowAction{
+					Strategy: OverflowGrow,
+					Detail:   fmt.Sprintf("%dx%d -> %dx%d", w, h, nw, nh),
+				})
+			}
+		}
+		if r.Fits = fits(); r.Fits {
+			return
+		}
+	}
+}
+
+// squeezed reports whether flex shrinking gave an item that cannot be
+// resized, such as a Text, a smaller box than its own size, so it would spill
+// out of its box although CheckOverflow finds nothing.
+func (al *AutoLayout) squeezed() bool {
+	for _, n := range al.children {
+		if n.dropped || n.meas == nil {
+			continue
+		}
+		if child, ok := n.shape.(*AutoLayout); ok && child.squeezed() {
+			return true
+		}
+		_, resizable := n.shape.(Resizable)
+		_, boundable := n.shape.(Boundable)
+		if resizable || boundable {
+			continue
+		}
+		sz := n.meas.Size()
+		if n.w < int(math.Round(sz.Width())) || n.h < int(math.Round(sz.Height())) {
+			return true
+		}
+	}
+	return false
+}
+
+// texts returns the Text items of the layout and its nested layouts, depth
+// first, skipping removed items.
+func (al *AutoLayout) texts(path []string) []fitText {
+	var out []fitText
+	for i, n := range al.children {
+		if n.dropped {
+			continue
+		}
+		p := append(path[:len(path):len(path)], itemName(n, i))
+		switch s := n.shape.(type) {
+		case *Text:
+			out = append(out, fitText{text: s, path: p})
+		case *AutoLayout:
+			out = append(out, s.texts(p)...)
+		}
+	}
+	return out
+}
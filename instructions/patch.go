@@ -0,0 +1,156 @@
+package instructions
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Krispeckt/glimo/patterns"
+)
+
+// Patch changes properties of the shape with the given ID (see SetID). Nil
+// fields are left unchanged, so a patch names only what differs from the
+// template.
+type Patch struct {
+	ID string
+
+	Text    *string          // content of a Text
+	Fill    patterns.Pattern // fill of a Rectangle, Circle or Polyline; color of a Text; tint of a GlassRect
+	Stroke  patterns.Pattern // stroke of a Rectangle, Circle, Polyline or Text; border of a GlassRect
+	Opacity *float64         // see SetOpacity
+	Visible *bool            // see SetVisible
+}
+
+// PatchError reports a patch that could not be applied.
+type PatchError struct {
+	ID     string
+	Field  string // empty when no shape has the ID
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *PatchError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("patch %q: %s", e.ID, e.Reason)
+	}
+	return fmt.Sprintf("patch %q: %s: %s", e.ID, e.Field, e.Reason)
+}
+
+// ApplyPatch applies patches in order to the shapes with their IDs in scene,
+// searched like FindByID, so a template parsed once can be specialized per
+// request without rebuilding it. Patches are applied as far as possible; the
+// ones naming unknown IDs or properties the shape lacks are reported as
+// *PatchError values joined with errors.Join.
+//
+// AutoLayouts holding patched shapes are laid out again on their next draw.
+// For a retained scene, use Layer.ApplyPatch, which also invalidates it.
+func ApplyPatch(scene Shape, patches []Patch) error {
+	var errs []error
+	for _, p := range patches {
+		path := pathByID(scene, p.ID)
+		if path == nil {
+			errs = append(errs, &PatchError{ID: p.ID, Reason: "no shape with this ID"})
+			continue
+		}
+		errs = append(errs, p.apply(path[len(path)-1])...)
+		for _, s := range path {
+			if al, ok := s.(*AutoLayout); ok {
+				al.InvalidateLayout()
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ApplyPatch applies patches to the retained scene (see Retain and the
+// package-level ApplyPatch) and invalidates the retained shapes containing
+// the patched ones, so the next Rerender repaints only what changed.
+func (l *Layer) ApplyPatch(patches []Patch) error {
+	if l == nil {
+		return nil
+	}
+	var errs []error
+	for _, p := range patches {
+		found := false
+		for _, n := range l.scene {
+			if pathByID(n.shape, p.ID) == nil {
+				continue
+			}
+			found = true
+			if err := ApplyPatch(n.shape, []Patch{p}); err != nil {
+				errs = append(errs, err)
+			}
+			n.dirty = true
+			break
+		}
+		if !found {
+			errs = append(errs, &PatchError{ID: p.ID, Reason: "no shape with this ID"})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// apply sets the patch fields on s and returns the ones it lacks.
+func (p Patch) apply(s Shape) []error {
+	var errs []error
+	unsupported := func(field string) {
+		errs = append(errs, &PatchError{ID: p.ID, Field: field, Reason: fmt.Sprintf("not supported by %s", shapeTypeName(s))})
+	}
+
+	if p.Text != nil {
+		if t, ok := s.(*Text); ok {
+			t.SetText(*p.Text)
+		} else {
+			unsupported("Text")
+		}
+	}
+	if p.Fill != nil {
+		switch v := s.(type) {
+		case *Rectangle:
+			v.SetFillPattern(p.Fill)
+		case *Circle:
+			v.SetFillPattern(p.Fill)
+		case *Polyline:
+			v.SetFillPattern(p.Fill)
+		case *Text:
+			v.SetColorPattern(p.Fill)
+		case *GlassRect:
+			v.SetTintPattern(p.Fill)
+		default:
+			unsupported("Fill")
+		}
+	}
+	if p.Stroke != nil {
+		switch v := s.(type) {
+		case *Rectangle:
+			v.SetStrokePattern(p.Stroke)
+		case *Circle:
+			v.SetStrokePattern(p.Stroke)
+		case *Polyline:
+			v.SetStrokePattern(p.Stroke)
+		case *Text:
+			v.SetStrokeWithPattern(p.Stroke, v.strokeWidth)
+		case *GlassRect:
+			v.SetBorderPattern(p.Stroke, v.borderWidth)
+		default:
+			unsupported("Stroke")
+		}
+	}
+	if p.Opacity != nil {
+		switch v := s.(type) {
+		case *Image:
+			v.SetOpacity(*p.Opacity)
+		case interface{ setOpacity(float64) }:
+			v.setOpacity(*p.Opacity)
+		default:
+			unsupported("Opacity")
+		}
+	}
+	if p.Visible != nil {
+		if v, ok := s.(interface{ setVisible(bool) }); ok {
+			v.setVisible(*p.Visible)
+		} else {
+			unsupported("Visible")
+		}
+	}
+	return errs
+}
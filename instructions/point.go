@@ -5,7 +5,7 @@ import (
 	"math"
 
 	"github.com/Krispeckt/glimo/colors"
-	"github.com/Krispeckt/glimo/internal/core/image/patterns"
+	"github.com/Krispeckt/glimo/patterns"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -52,6 +52,12 @@ func (p *Point) Fixed() fixed.Point26_6 {
 	}
 }
 
+// scaled returns a copy with coordinates multiplied by s.
+func (p *Point) scaled(d device) Shape {
+	s := d.scale
+	return &Point{X: p.X * s, Y: p.Y * s, color: p.color}
+}
+
 // Draw renders a single pixel representing the Point’s location
 // on the given overlay image, if it lies within bounds.
 // The base image parameter is ignored for consistency with other Draw methods.
@@ -0,0 +1,353 @@
+package instructions
+
+import (
+	"image"
+	"math"
+	"slices"
+
+	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/effects"
+	"github.com/Krispeckt/glimo/internal/containers"
+	"github.com/Krispeckt/glimo/internal/core/geom"
+	"github.com/Krispeckt/glimo/patterns"
+)
+
+// Polyline is a path through a list of points that, unlike Line, owns its
+// geometry and implements BoundedShape, so it can be placed in Group and
+// AutoLayout. Its box spans the points plus half the stroke width on every
+// side; SetPosition moves the whole path.
+type Polyline struct {
+	points []*Point
+	dx, dy float64 // translation applied by SetPosition
+	closed bool
+
+	lineWidth    float64
+	lineCap      LineCap
+	lineJoin     LineJoin
+	dashes       []float64
+	dashOffset   float64
+	stroke       patterns.Pattern
+	fill         patterns.Pattern
+	vertexColors bool
+	aliased      bool
+	accurate     bool // see Layer.SetAccurateBlending; set by scaled
+
+	effects containers.Effects
+	shapeTransform
+	shapeOpacity
+	shapeID
+	configErrors
+}
+
+// NewPolyline creates a polyline through points with a 1px black stroke,
+// butt caps, round joins and no fill.
+func NewPolyline(points ...*Point) *Polyline {
+	return &Polyline{
+		points:    points,
+		lineWidth: 1,
+		lineCap:   LineCapButt,
+		lineJoin:  LineJoinRound,
+		stroke:    patterns.NewSolid(colors.Black),
+	}
+}
+
+// AddPoint appends a point at (x, y), in the coordinates of the points
+// passed to NewPolyline.
+func (p *Polyline) AddPoint(x, y float64) *Polyline {
+	p.points = append(p.points, NewPoint(x, y))
+	return p
+}
+
+// AddPoints appends points.
+func (p *Polyline) AddPoints(points ...*Point) *Polyline {
+	p.points = append(p.points, points...)
+	return p
+}
+
+// Points returns the points of the polyline, before SetPosition moves them.
+func (p *Polyline) Points() []*Point { return p.points }
+
+// SetClosed joins the last point back to the first.
+func (p *Polyline) SetClosed(closed bool) *Polyline { p.closed = closed; return p }
+
+// SetLineWidth sets the stroke width; zero disables the stroke.
+func (p *Polyline) SetLineWidth(w float64) *Polyline {
+	p.check(w >= 0, "Polyline", "SetLineWidth", "negative width clamped to 0")
+	p.lineWidth = math.Max(w, 0)
+	return p
+}
+
+// SetLineCap sets how open ends are drawn.
+func (p *Polyline) SetLineCap(c LineCap) *Polyline { p.lineCap = c; return p }
+
+// SetLineJoin sets how segments meet.
+func (p *Polyline) SetLineJoin(j LineJoin) *Polyline { p.lineJoin = j; return p }
+
+// SetDashes sets the dash pattern, alternating dash and gap lengths in
+// pixels. Nil draws a solid stroke; patterns with negative lengths are
+// ignored.
+func (p *Polyline) SetDashes(d []float64) *Polyline {
+	neg := slices.ContainsFunc(d, func(v float64) bool { return v < 0 })
+	p.check(!neg, "Polyline", "SetDashes", "negative dash lengths ignored")
+	if !neg {
+		p.dashes = d
+	}
+	return p
+}
+
+// SetDashOffset shifts the dash pattern along the path.
+func (p *Polyline) SetDashOffset(off float64) *Polyline { p.dashOffset = off; return p }
+
+// SetStrokeColor sets a solid stroke color.
+func (p *Polyline) SetStrokeColor(c patterns.Color) *Polyline {
+	p.stroke = c.MakeSolidPattern()
+	return p
+}
+
+// SetStrokePattern sets the stroke pattern, such as a gradient, in canvas
+// coordinates. Nil disables the stroke.
+func (p *Polyline) SetStrokePattern(pat patterns.Pattern) *Polyline { p.stroke = pat; return p }
+
+// SetFillColor fills the area enclosed by the points with a solid color.
+func (p *Polyline) SetFillColor(c patterns.Color) *Polyline {
+	p.fill = c.MakeSolidPattern()
+	return p
+}
+
+// SetFillPattern fills the area enclosed by the points; nil disables the fill.
+func (p *Polyline) SetFillPattern(pat patterns.Pattern) *Polyline { p.fill = pat; return p }
+
+// SetVertexColors strokes the path with a gradient through the colors of its
+// points (see Point.SetColor) instead of the stroke pattern: each pixel takes
+// the color interpolated along its nearest segment. The cost grows with the
+// number of segments.
+func (p *Polyline) SetVertexColors(on bool) *Polyline { p.vertexColors = on; return p }
+
+// SetAntiAlias enables or disables anti-aliased edges (enabled by default).
+func (p *Polyline) SetAntiAlias(aa bool) *Polyline { p.aliased = !aa; return p }
+
+// SetID names the polyline for FindByID.
+func (p *Polyline) SetID(id string) *Polyline { p.id = id; return p }
+
+// SetVisible shows or hides the polyline without removing it from its
+// container. Hidden shapes keep their place in layouts.
+func (p *Polyline) SetVisible(v bool) *Polyline { p.setVisible(v); return p }
+
+// SetOpacity fades the whole polyline, fill, stroke and effects together, to o
+// in [0, 1]. Values are clamped.
+func (p *Polyline) SetOpacity(o float64) *Polyline {
+	p.check(o >= 0 && o <= 1, "Polyline", "SetOpacity", "opacity outside [0, 1] clamped")
+	p.setOpacity(o)
+	return p
+}
+
+// SetRotation turns the path by deg degrees clockwise about pivot, a point
+// of its untransformed box. Returns the receiver for chaining.
+func (p *Polyline) SetRotation(deg float64, pivot Anchor) *Polyline {
+	p.setRotation(deg, pivot)
+	return p
+}
+
+// SetTransform applies m to the path about origin, a point of its
+// untransformed box, after any rotation. The stroke keeps its width; vertex
+// colors follow the transformed points. The zero Matrix removes the
+// transform. Returns the receiver for chaining.
+func (p *Polyline) SetTransform(m Matrix, origin Anchor) *Polyline {
+	p.setTransform(m, origin)
+	return p
+}
+
+// AddEffect attaches a visual effect to the polyline.
+func (p *Polyline) AddEffect(e effects.Effect) *Polyline {
+	p.effects.Add(e)
+	return p
+}
+
+// AddEffects attaches multiple visual effects.
+func (p *Polyline) AddEffects(es ...effects.Effect) *Polyline {
+	p.effects.AddList(es)
+	return p
+}
+
+// extent returns the bounding box of the points before translation.
+func (p *Polyline) extent() (minX, minY, maxX, maxY float64, ok bool) {
+	if len(p.points) == 0 {
+		return 0, 0, 0, 0, false
+	}
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+	for _, pt := range p.points {
+		minX, maxX = math.Min(minX, pt.X), math.Max(maxX, pt.X)
+		minY, maxY = math.Min(minY, pt.Y), math.Max(maxY, pt.Y)
+	}
+	return minX, minY, maxX, maxY, true
+}
+
+// origin returns the top-left of the box before rounding.
+func (p *Polyline) origin() (float64, float64) {
+	minX, minY, _, _, _ := p.extent()
+	hw := p.lineWidth / 2
+	return minX - hw + p.dx, minY - hw + p.dy
+}
+
+// Position returns the top-left of the box, or of its transformed bounds.
+func (p *Polyline) Position() (int, int) {
+	x, y := p.origin()
+	if p.transformed() {
+		sz := p.untransformedSize()
+		x, y, _, _ = transformedBox(p.matrix(), x, y, sz.Width(), sz.Height())
+	}
+	return int(math.Floor(x)), int(math.Floor(y))
+}
+
+// SetPosition moves the path so its box starts at (x, y).
+func (p *Polyline) SetPosition(x, y int) {
+	if p.transformed() {
+		px, py := p.Position()
+		p.dx += float64(x - px)
+		p.dy += float64(y - py)
+		return
+	}
+	ox, oy := p.origin()
+	p.dx += float64(x) - ox
+	p.dy += float64(y) - oy
+}
+
+// Size returns the box size: the extent of the points plus the stroke width,
+// or the size of that box once transformed.
+func (p *Polyline) Size() *geom.Size {
+	sz := p.untransformedSize()
+	if !p.transformed() {
+		return sz
+	}
+	x, y := p.origin()
+	_, _, w, h := transformedBox(p.matrix(), x, y, sz.Width(), sz.Height())
+	return geom.NewSize(w, h)
+}
+
+// untransformedSize returns the extent of the points plus the stroke width.
+func (p *Polyline) untransformedSize() *geom.Size {
+	minX, minY, maxX, maxY, ok := p.extent()
+	if !ok {
+		return geom.NewSize(0, 0)
+	}
+	return geom.NewSize(maxX-minX+p.lineWidth, maxY-minY+p.lineWidth)
+}
+
+// matrix returns the rotation and transform of the untransformed box.
+func (p *Polyline) matrix() geom.Matrix {
+	x, y := p.origin()
+	sz := p.untransformedSize()
+	return p.transformMatrix(x, y, sz.Width(), sz.Height())
+}
+
+// drawBounds returns the box grown to cover square caps and miter joins.
+// Effects may draw anywhere, so they disable bounds.
+func (p *Polyline) drawBounds() (image.Rectangle, bool) {
+	if p.effects.Count() > 0 || len(p.points) == 0 {
+		return image.Rectangle{}, false
+	}
+	return boxBounds(p, 2*p.lineWidth), true
+}
+
+// scaled returns a copy with geometry, stroke width and dashes multiplied
+// by s.
+func (p *Polyline) scaled(d device) Shape {
+	s := d.scale
+	c := *p
+	c.points = make([]*Point, len(p.points))
+	for i, pt := range p.points {
+		c.points[i] = &Point{X: pt.X * s, Y: pt.Y * s, color: pt.color}
+	}
+	c.dx, c.dy = p.dx*s, p.dy*s
+	c.lineWidth = p.lineWidth * s
+	if p.dashes != nil {
+		c.dashes = make([]float64, len(p.dashes))
+		for i, d := range p.dashes {
+			c.dashes[i] = d * s
+		}
+	}
+	c.dashOffset = p.dashOffset * s
+	c.accurate = d.accurate
+	c.stroke = d.pattern(p.stroke)
+	c.fill = d.pattern(p.fill)
+	c.shapeTransform = p.scaledBy(s)
+	return &c
+}
+
+// Draw strokes and fills the path through a Line.
+func (p *Polyline) Draw(base, overlay *image.RGBA) {
+	if len(p.points) == 0 || p.faded(p, base, overlay, p.Draw) {
+		return
+	}
+	p.effects.PreApplyAll(overlay)
+
+	line := NewLine().
+		blendAccurately(p.accurate).
+		SetAntiAlias(!p.aliased).
+		SetLineWidth(p.lineWidth).
+		SetLineCap(p.lineCap).
+		SetLineJoin(p.lineJoin).
+		SetDashes(p.dashes).
+		SetDashOffset(p.dashOffset)
+	if p.transformed() {
+		line.WithMatrix(p.matrix())
+	}
+	for i, pt := range p.points {
+		if i == 0 {
+			line.MoveTo(pt.X+p.dx, pt.Y+p.dy)
+		} else {
+			line.LineTo(pt.X+p.dx, pt.Y+p.dy)
+		}
+	}
+	if p.closed {
+		line.ClosePath()
+	}
+	if p.fill != nil {
+		line.SetFillPattern(p.fill).FillPreserve()
+	}
+	stroke := p.stroke
+	if p.vertexColors && len(p.points) > 1 {
+		stroke = p.vertexPattern()
+	}
+	if p.lineWidth > 0 && stroke != nil {
+		line.SetStrokePattern(stroke).StrokePreserve()
+	}
+	line.Draw(base, overlay)
+	line.release()
+
+	p.effects.PostApplyAll(overlay)
+}
+
+// vertexPattern colors each pixel by its nearest segment, interpolating the
+// colors of the segment's end points.
+func (p *Polyline) vertexPattern() patterns.Pattern {
+	m := geom.Identity()
+	if p.transformed() {
+		m = p.matrix()
+	}
+	pts := make([]*Point, 0, len(p.points)+1)
+	for _, pt := range p.points {
+		x, y := m.TransformPoint(pt.X+p.dx, pt.Y+p.dy)
+		pts = append(pts, &Point{X: x, Y: y, color: pt.color})
+	}
+	if p.closed {
+		pts = append(pts, pts[0])
+	}
+	return patterns.NewFuncPattern(func(x, y int) patterns.Color {
+		px, py := float64(x)+0.5, float64(y)+0.5
+		best, bestT, bestD := 0, 0.0, math.Inf(1)
+		for i := 0; i+1 < len(pts); i++ {
+			a, b := pts[i], pts[i+1]
+			vx, vy := b.X-a.X, b.Y-a.Y
+			t := 0.0
+			if l2 := vx*vx + vy*vy; l2 > 0 {
+				t = geom.ClampF64(((px-a.X)*vx+(py-a.Y)*vy)/l2, 0, 1)
+			}
+			if d := math.Hypot(a.X+t*vx-px, a.Y+t*vy-py); d < bestD {
+				best, bestT, bestD = i, t, d
+			}
+		}
+		return pts[best].color.Mix(pts[best+1].color, bestT)
+	})
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/Krispeckt/glimo/effects"
 	"github.com/Krispeckt/glimo/internal/containers"
 	"github.com/Krispeckt/glimo/internal/core/geom"
-	"github.com/Krispeckt/glimo/internal/core/image/patterns"
+	"github.com/Krispeckt/glimo/patterns"
 )
 
 // StrokePosition defines stroke alignment relative to the rectangle border.
@@ -48,11 +48,20 @@ type Rectangle struct {
 
 	fillPattern   patterns.Pattern
 	strokePattern patterns.Pattern
+	fillBlend     *patterns.BlendMode
+	strokeBlend   *patterns.BlendMode
 	lineWidth     float64
 	strokePos     StrokePosition
 	roundSteps    int
+	aliased       bool
+	accurate      bool // see Layer.SetAccurateBlending; set by scaled
 
 	effects containers.Effects
+
+	shapeTransform
+	shapeOpacity
+	shapeID
+	configErrors
 }
 
 // NewRectangle creates a new rectangle with the given position and size.
@@ -73,12 +82,14 @@ func NewRectangle(x, y, width, height float64) *Rectangle {
 
 // SetSize sets width and height.
 func (r *Rectangle) SetSize(width, height float64) *Rectangle {
+	r.check(width >= 0 && height >= 0, "Rectangle", "SetSize", "negative size")
 	r.width, r.height = width, height
 	return r
 }
 
 // SetRadius sets uniform corner radius for all corners.
 func (r *Rectangle) SetRadius(radius float64) *Rectangle {
+	r.check(radius >= 0, "Rectangle", "SetRadius", "negative radius clamped to 0")
 	r.radiusTL = geom.ClampF64(radius, 0, math.MaxFloat64)
 	r.radiusTR = geom.ClampF64(radius, 0, math.MaxFloat64)
 	r.radiusBR = geom.ClampF64(radius, 0, math.MaxFloat64)
@@ -88,6 +99,7 @@ func (r *Rectangle) SetRadius(radius float64) *Rectangle {
 
 // SetCornerRadii sets per-corner radii: top-left, top-right, bottom-right, bottom-left.
 func (r *Rectangle) SetCornerRadii(tl, tr, br, bl float64) *Rectangle {
+	r.check(min(tl, tr, br, bl) >= 0, "Rectangle", "SetCornerRadii", "negative radius clamped to 0")
 	r.radiusTL = geom.ClampF64(tl, 0, math.MaxFloat64)
 	r.radiusTR = geom.ClampF64(tr, 0, math.MaxFloat64)
 	r.radiusBR = geom.ClampF64(br, 0, math.MaxFloat64)
@@ -97,6 +109,7 @@ func (r *Rectangle) SetCornerRadii(tl, tr, br, bl float64) *Rectangle {
 
 // SetLineWidth sets stroke width.
 func (r *Rectangle) SetLineWidth(width float64) *Rectangle {
+	r.check(width >= 0, "Rectangle", "SetLineWidth", "negative width clamped to 0")
 	r.lineWidth = geom.ClampF64(width, 0, math.MaxFloat64)
 	return r
 }
@@ -109,6 +122,7 @@ func (r *Rectangle) SetStrokePosition(pos StrokePosition) *Rectangle {
 
 // SetRoundedSteps sets resolution of rounded arcs.
 func (r *Rectangle) SetRoundedSteps(steps int) *Rectangle {
+	r.check(steps >= 1, "Rectangle", "SetRoundedSteps", "steps below 1 raised to 1")
 	if steps < 1 {
 		steps = 1
 	}
@@ -116,6 +130,12 @@ func (r *Rectangle) SetRoundedSteps(steps int) *Rectangle {
 	return r
 }
 
+// SetAntiAlias enables or disables anti-aliased edges (enabled by default).
+func (r *Rectangle) SetAntiAlias(aa bool) *Rectangle {
+	r.aliased = !aa
+	return r
+}
+
 // SetFillColor sets solid fill colorPattern.
 func (r *Rectangle) SetFillColor(c patterns.Color) *Rectangle {
 	r.fillPattern = c.MakeSolidPattern()
@@ -124,6 +144,7 @@ func (r *Rectangle) SetFillColor(c patterns.Color) *Rectangle {
 
 // SetFillPattern sets fill pattern.
 func (r *Rectangle) SetFillPattern(p patterns.Pattern) *Rectangle {
+	r.check(p != nil, "Rectangle", "SetFillPattern", "nil pattern ignored")
 	if p != nil {
 		r.fillPattern = p
 	}
@@ -138,12 +159,27 @@ func (r *Rectangle) SetStrokeColor(c patterns.Color) *Rectangle {
 
 // SetStrokePattern sets stroke pattern.
 func (r *Rectangle) SetStrokePattern(p patterns.Pattern) *Rectangle {
+	r.check(p != nil, "Rectangle", "SetStrokePattern", "nil pattern ignored")
 	if p != nil {
 		r.strokePattern = p
 	}
 	return r
 }
 
+// SetFillBlendMode composites the fill with mode, overriding the blend mode
+// of the fill pattern and its colors.
+func (r *Rectangle) SetFillBlendMode(mode patterns.BlendMode) *Rectangle {
+	r.fillBlend = &mode
+	return r
+}
+
+// SetStrokeBlendMode composites the stroke with mode, overriding the blend
+// mode of the stroke pattern and its colors.
+func (r *Rectangle) SetStrokeBlendMode(mode patterns.BlendMode) *Rectangle {
+	r.strokeBlend = &mode
+	return r
+}
+
 // AddEffect attaches a visual effect to the rectangle rendering pipeline.
 //
 // The added effect will be stored inside the internal effect container `t.effects`
@@ -168,22 +204,108 @@ func (r *Rectangle) AddEffects(es ...effects.Effect) *Rectangle {
 	return r
 }
 
-// SetPosition sets the rectangle’s top-left corner.
+// SetRotation turns the rectangle by deg degrees clockwise about pivot, a
+// point of its unrotated box. The path is rotated before it is rasterized,
+// so edges stay as crisp as at 0°; fill and stroke patterns keep their
+// canvas orientation. Position and Size then report the rotated bounding
+// box, which is what containers lay out.
+func (r *Rectangle) SetRotation(deg float64, pivot Anchor) *Rectangle {
+	r.setRotation(deg, pivot)
+	return r
+}
+
+// SetTransform applies m to the rectangle's path about origin, a point of
+// its unrotated box, after any rotation: e.g. ShearMatrix(-0.2, 0) with
+// AnchorCenter slants it in place. The zero Matrix removes the transform.
+// Like SetRotation, it changes the reported box, not the stroke width.
+func (r *Rectangle) SetTransform(m Matrix, origin Anchor) *Rectangle {
+	r.setTransform(m, origin)
+	return r
+}
+
+// SetPosition sets the rectangle’s top-left corner, or moves a transformed
+// rectangle so its bounding box starts at (x, y).
 func (r *Rectangle) SetPosition(x, y int) {
-	r.x, r.y = float64(x), float64(y)
+	if !r.transformed() {
+		r.x, r.y = float64(x), float64(y)
+		return
+	}
+	px, py := r.Position()
+	r.x += float64(x - px)
+	r.y += float64(y - py)
 }
 
-// Size returns rectangle size.
+// Size returns rectangle size, or the size of its transformed bounding box.
 func (r *Rectangle) Size() *geom.Size {
-	o := r.strokePos.Outset(r.lineWidth)
-	return geom.NewSize(r.width+o, r.height+o)
+	_, _, w, h := r.layoutBox()
+	return geom.NewSize(w, h)
 }
 
 // Position returns the top-left coordinate where the layer is drawn.
-func (r *Rectangle) Position() (int, int) { return int(r.x), int(r.y) }
+func (r *Rectangle) Position() (int, int) {
+	x, y, _, _ := r.layoutBox()
+	return int(x), int(y)
+}
+
+// layoutBox returns the box reported by Position and Size: the rectangle
+// grown by the stroke outset, or that box's transformed bounds.
+func (r *Rectangle) layoutBox() (x, y, w, h float64) {
+	o := r.strokePos.Outset(r.lineWidth)
+	x, y, w, h = r.x, r.y, r.width+o, r.height+o
+	if r.transformed() {
+		return transformedBox(r.transformMatrix(r.x, r.y, r.width, r.height), x, y, w, h)
+	}
+	return x, y, w, h
+}
+
+// scaled returns a copy with geometry and stroke width multiplied by s.
+func (r *Rectangle) scaled(d device) Shape {
+	s := d.scale
+	c := *r
+	c.x, c.y = r.x*s, r.y*s
+	c.width, c.height = r.width*s, r.height*s
+	c.radiusTL, c.radiusTR = r.radiusTL*s, r.radiusTR*s
+	c.radiusBR, c.radiusBL = r.radiusBR*s, r.radiusBL*s
+	c.lineWidth = r.lineWidth * s
+	c.accurate = d.accurate
+	c.fillPattern = d.pattern(r.fillPattern)
+	c.strokePattern = d.pattern(r.strokePattern)
+	c.shapeTransform = r.scaledBy(s)
+	return &c
+}
+
+// drawBounds returns the box grown by the stroke width to cover outside strokes
+// and miter joins. Effects may draw anywhere, so they disable bounds.
+func (r *Rectangle) drawBounds() (image.Rectangle, bool) {
+	if r.effects.Count() > 0 {
+		return image.Rectangle{}, false
+	}
+	return boxBounds(r, 2*r.lineWidth), true
+}
+
+// SetID names the rectangle for FindByID.
+func (r *Rectangle) SetID(id string) *Rectangle {
+	r.id = id
+	return r
+}
+
+// SetVisible shows or hides the rectangle without removing it from its
+// container. Hidden shapes keep their place in layouts.
+func (r *Rectangle) SetVisible(v bool) *Rectangle { r.setVisible(v); return r }
+
+// SetOpacity fades the whole rectangle, fill, stroke and effects together, to o
+// in [0, 1]. Values are clamped.
+func (r *Rectangle) SetOpacity(o float64) *Rectangle {
+	r.check(o >= 0 && o <= 1, "Rectangle", "SetOpacity", "opacity outside [0, 1] clamped")
+	r.setOpacity(o)
+	return r
+}
 
 // Draw renders the rectangle with stroke alignment (inside, center, outside).
 func (r *Rectangle) Draw(base, overlay *image.RGBA) {
+	if r.faded(r, base, overlay, r.Draw) {
+		return
+	}
 	if r.width <= 0 || r.height <= 0 {
 		return
 	}
@@ -201,9 +323,14 @@ func (r *Rectangle) Draw(base, overlay *image.RGBA) {
 	r.effects.PreApplyAll(overlay)
 
 	line := NewLine().
+		blendAccurately(r.accurate).
+		SetAntiAlias(!r.aliased).
 		SetLineWidth(r.lineWidth).
-		SetStrokePattern(r.strokePattern).
-		SetFillPattern(r.fillPattern)
+		SetStrokePattern(blendedWith(r.strokePattern, r.strokeBlend)).
+		SetFillPattern(blendedWith(r.fillPattern, r.fillBlend))
+	if r.transformed() {
+		line.WithMatrix(r.transformMatrix(r.x, r.y, r.width, r.height))
+	}
 
 	addRoundedRectCorners(
 		line,
@@ -223,10 +350,19 @@ func (r *Rectangle) Draw(base, overlay *image.RGBA) {
 		line.StrokePreserve()
 	}
 	line.Draw(base, overlay)
+	line.release()
 
 	r.effects.PostApplyAll(overlay)
 }
 
+// blendedWith returns p composited with mode, or p itself when mode is nil.
+func blendedWith(p patterns.Pattern, mode *patterns.BlendMode) patterns.Pattern {
+	if mode == nil {
+		return p
+	}
+	return patterns.WithBlendMode(p, *mode)
+}
+
 // addRoundedRectCorners draws rectangle with per-corner radii.
 func addRoundedRectCorners(line *Line, x, y, w, h, rtl, rtr, rbr, rbl float64, steps int) {
 	clamp := func(v float64) float64 { return geom.ClampF64(v, 0, math.MaxFloat64) }
@@ -0,0 +1,96 @@
+package instructions
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/draw"
+
+	"github.com/Krispeckt/glimo/effects"
+	"github.com/Krispeckt/glimo/internal/containers"
+)
+
+// RegionEffect applies an effect to what lies beneath a shape, limited to
+// the shape's alpha: e.g. pixelating or blurring only the area behind a
+// "spoiler" rectangle. The shape itself is not drawn; its coverage is the
+// mask, so soft edges and opacity feather the effect. Like GlassRect, the
+// effect sees the base image, i.e. what the Layer (or the enclosing Group)
+// held before, so draw the content first.
+type RegionEffect struct {
+	shape   Shape
+	effects containers.Effects
+
+	shapeOpacity
+	shapeID
+}
+
+// NewRegionEffect limits e to the region covered by shape, which needs an
+// opaque fill; its colors do not matter. Pre- and post-render effects alike
+// are applied to the base.
+//
+// Example:
+//
+//	layer.LoadInstruction(instructions.NewRegionEffect(
+//		instructions.NewRectangle(40, 60, 200, 24).SetRadius(6).SetFillColor(colors.Black),
+//		effects.NewLayerBlurEffect(8),
+//	))
+func NewRegionEffect(shape Shape, e effects.Effect) *RegionEffect {
+	r := &RegionEffect{shape: shape}
+	r.effects.Add(e)
+	return r
+}
+
+// SetID names the region for FindByID.
+func (r *RegionEffect) SetID(id string) *RegionEffect { r.id = id; return r }
+
+// SetVisible shows or hides the region without removing it from its
+// container.
+func (r *RegionEffect) SetVisible(v bool) *RegionEffect { r.setVisible(v); return r }
+
+// SetOpacity blends the affected region with the original at o in [0, 1].
+// Values are clamped.
+func (r *RegionEffect) SetOpacity(o float64) *RegionEffect { r.setOpacity(o); return r }
+
+// scaled returns a copy with the shape prepared for d. Effect parameters
+// stay in device pixels, as for shapes.
+func (r *RegionEffect) scaled(d device) Shape {
+	c := *r
+	c.shape = scaleShape(r.shape, d)
+	return &c
+}
+
+// reach is how far around the region the effect may sample; blurs read a
+// few radii out.
+func (r *RegionEffect) reach() int { return 3 * int(math.Ceil(r.effects.MaxRadius())) }
+
+// drawBounds returns the shape's bounds grown by the effect reach, so a
+// Layer hands it enough of the base to treat the edges like the middle.
+func (r *RegionEffect) drawBounds() (image.Rectangle, bool) {
+	b, ok := shapeBounds(r.shape)
+	if !ok {
+		return image.Rectangle{}, false
+	}
+	return b.Inset(-r.reach()), true
+}
+
+// Draw rasterizes the shape into a mask, applies the effect to a copy of the
+// base around it and paints the result through the mask.
+func (r *RegionEffect) Draw(base, overlay *image.RGBA) {
+	if overlay == nil || base == nil || r.shape == nil || r.faded(r, base, overlay, r.Draw) {
+		return
+	}
+	mask := image.NewRGBA(overlay.Bounds())
+	r.shape.Draw(mask, mask)
+	box := dirtyBounds(mask)
+	if box.Empty() {
+		return
+	}
+
+	// Effects expect a buffer starting at (0, 0).
+	src := box.Inset(-r.reach()).Intersect(base.Bounds())
+	buf := image.NewRGBA(image.Rect(0, 0, src.Dx(), src.Dy()))
+	draw.Copy(buf, image.Point{}, base, src, draw.Src, nil)
+	r.effects.PreApplyAll(buf)
+	r.effects.PostApplyAll(buf)
+	draw.DrawMask(overlay, box, buf, box.Min.Sub(src.Min), mask, box.Min, draw.Over)
+}
@@ -0,0 +1,33 @@
+package instructions
+
+import (
+	"errors"
+	"fmt"
+	"image/png"
+)
+
+// Render draws root on a new width×height Layer and returns it, for simple
+// programs that draw a single tree of shapes. It fails on a non-positive
+// size or a nil root, in which case nothing is drawn. Like a new Layer it is
+// lenient: to refuse configuration errors, load root into a Layer set with
+// SetStrict.
+func Render(width, height int, root BoundedShape) (*Layer, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("render: invalid size %dx%d", width, height)
+	}
+	if root == nil {
+		return nil, errors.New("render: nil root")
+	}
+	l := NewLayer(width, height)
+	l.LoadInstruction(root)
+	return l, nil
+}
+
+// RenderToPNG renders root like Render and saves the result as a PNG file.
+func RenderToPNG(width, height int, root BoundedShape, path string) error {
+	l, err := Render(width, height, root)
+	if err != nil {
+		return err
+	}
+	return l.ExportPNG(path, png.DefaultCompression)
+}
@@ -4,6 +4,7 @@ import (
 	"image"
 
 	"github.com/Krispeckt/glimo/internal/core/geom"
+	"github.com/Krispeckt/glimo/patterns"
 )
 
 // Shape defines the minimal contract for any drawable visual entity or
@@ -20,8 +21,13 @@ import (
 //
 // The drawing function should confine its modifications to the intended
 // area of the shape and must not alter unrelated pixels outside its bounds.
-// Each Shape implementation defines its own compositing, blending, and
-// color behavior.
+// Both buffers use canvas coordinates but may cover only a sub-region of the
+// canvas (their Bounds().Min need not be zero), e.g. when a Layer restricts
+// drawing to a BoundedShape's area. Each Shape implementation defines its
+// own compositing, blending, and color behavior.
+//
+// DrawContext documents the contract in full and provides blend-aware
+// helpers; ContextFunc turns a function over a DrawContext into a Shape.
 type Shape interface {
 	// Draw renders the shape’s visual representation into the given
 	// base and overlay image buffers. Implementations decide how to
@@ -39,10 +45,10 @@ type Shape interface {
 type BoundedShape interface {
 	Shape
 
-	// Size returns the intended width and height of the shape as a *geom.Size.
+	// Size returns the intended width and height of the shape as a *Size.
 	// A zero value for either axis typically indicates that the shape should
 	// determine its size automatically from its content or intrinsic geometry.
-	Size() *geom.Size
+	Size() *Size
 
 	// Position returns the current top-left coordinate of the shape in
 	// integer pixel units. This defines the anchor point used for rendering
@@ -54,3 +60,59 @@ type BoundedShape interface {
 	// position is applied the next time Draw() is called.
 	SetPosition(x, y int)
 }
+
+// Size is the width and height pair returned by BoundedShape.Size. It aliases
+// the internal geometry type, so shapes defined outside this module can
+// implement BoundedShape and take part in Group, AutoLayout and dirty-rect
+// drawing like the built-in instructions.
+type Size = geom.Size
+
+// NewSize creates a Size with the given width and height.
+func NewSize(width, height float64) *Size {
+	return geom.NewSize(width, height)
+}
+
+// device describes how a Layer renders: its scale factor and whether
+// patterns blend accurately (see Layer.SetAccurateBlending).
+type device struct {
+	scale    float64
+	accurate bool
+}
+
+// pattern returns p prepared for the device scale. Accurate blending is
+// not a property of the pattern: shapes keep the flag from scaled and hand
+// it to whatever composites their pixels.
+func (d device) pattern(p patterns.Pattern) patterns.Pattern {
+	return patterns.Scaled(p, d.scale)
+}
+
+// scalable is implemented by built-in instructions that can produce a copy
+// of themselves with every pixel-space parameter multiplied by the device
+// scale and every pattern prepared for the device. Layers with a scale
+// factor use it to render the same template at @2x/@3x.
+type scalable interface {
+	scaled(d device) Shape
+}
+
+// scaleShape returns a copy of sh prepared for d, or sh itself when d is
+// the default device (scale 1, fast blending) or the shape does not support
+// scaling. A scale of 0 or less is treated as 1.
+func scaleShape(sh Shape, d device) Shape {
+	if d.scale <= 0 {
+		d.scale = 1
+	}
+	if d.scale == 1 && !d.accurate {
+		return sh
+	}
+	if f, ok := sh.(ContextFunc); ok {
+		// Custom drawing stays in canvas pixels; only the blending carries over.
+		if d.accurate {
+			return accurateContextFunc(f)
+		}
+		return sh
+	}
+	if sc, ok := sh.(scalable); ok {
+		return sc.scaled(d)
+	}
+	return sh
+}
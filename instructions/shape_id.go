@@ -0,0 +1,114 @@
+package instructions
+
+// shapeID holds the ID set by a shape's SetID. Shapes embed it to gain the
+// ID method.
+type shapeID struct {
+	id string
+}
+
+// ID returns the identifier set with SetID, empty by default.
+func (s *shapeID) ID() string { return s.id }
+
+// identified is implemented by shapes carrying an ID.
+type identified interface {
+	ID() string
+}
+
+// FindByID returns the first shape whose ID is id, searching shapes in order
+// and depth first through the children of Groups, AutoLayouts,
+// BoundedBoxes and Aligned shapes, or nil when there is none. Empty IDs never
+// match.
+//
+// Changing the size of a shape found inside an AutoLayout requires
+// InvalidateLayout on that layout before it is drawn again.
+func FindByID(id string, shapes ...Shape) Shape {
+	if id == "" {
+		return nil
+	}
+	for _, s := range shapes {
+		if path := pathByID(s, id); path != nil {
+			return path[len(path)-1]
+		}
+	}
+	return nil
+}
+
+// pathByID returns the chain of shapes from s down to the first one whose ID
+// is id, depth first as in FindByID, or nil.
+func pathByID(s Shape, id string) []Shape {
+	if s == nil || id == "" {
+		return nil
+	}
+	if v, ok := s.(identified); ok && v.ID() == id {
+		return []Shape{s}
+	}
+	children := childShapes(s)
+	for _, child := range children {
+		if path := pathByID(child, id); path != nil {
+			return append([]Shape{s}, path...)
+		}
+	}
+	return nil
+}
+
+// childShapes returns the direct children of container shapes: Groups,
+// AutoLayouts, BoundedBoxes and Aligned shapes. Other shapes have none.
+func childShapes(s Shape) []Shape {
+	var children []Shape
+	switch c := s.(type) {
+	case *Group:
+		for _, child := range c.shapes {
+			children = append(children, child)
+		}
+	case *AutoLayout:
+		for _, n := range c.children {
+			children = append(children, n.shape)
+		}
+	case *BoundedBox:
+		children = append(children, c.shape)
+	case *Aligned:
+		children = append(children, c.shape)
+	}
+	return children
+}
+
+// FindByID returns the first child, depth first, whose ID is id (see
+// FindByID), or nil.
+func (g *Group) FindByID(id string) Shape {
+	if g == nil {
+		return nil
+	}
+	shapes := make([]Shape, len(g.shapes))
+	for i, s := range g.shapes {
+		shapes[i] = s
+	}
+	return FindByID(id, shapes...)
+}
+
+// FindByID returns the first child, depth first, whose ID is id (see
+// FindByID), or nil.
+func (al *AutoLayout) FindByID(id string) Shape {
+	if al == nil {
+		return nil
+	}
+	shapes := make([]Shape, len(al.children))
+	for i, n := range al.children {
+		shapes[i] = n.shape
+	}
+	return FindByID(id, shapes...)
+}
+
+// FindByID returns the first shape of the retained scene (see Retain),
+// depth first, whose ID is id, or nil. Shapes drawn with LoadInstruction are
+// not kept and cannot be found. Invalidate the returned shape, or the
+// retained container holding it, after changing it.
+func (l *Layer) FindByID(id string) Shape {
+	if l == nil {
+		return nil
+	}
+	shapes := make([]Shape, len(l.scene))
+	for i, n := range l.scene {
+		shapes[i] = n.shape
+	}
+	return FindByID(id, shapes...)
+}
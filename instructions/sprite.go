@@ -0,0 +1,140 @@
+package instructions
+
+import (
+	"image"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+)
+
+// subImager is implemented by image types that can share pixels with a
+// rectangle of themselves, such as *image.RGBA.
+type subImager interface {
+	SubImage(r image.Rectangle) image.Image
+}
+
+// Sprite draws one frame of a sprite sheet: a grid of equally sized frames,
+// such as pre-rendered Lottie or emoji animation frames, numbered left to
+// right and top to bottom. It is drawn like an Image, so configure fit,
+// flips and effects through Image.
+type Sprite struct {
+	sheet          image.Image
+	frameW, frameH int
+	count          int
+	frame          int
+	im             *Image
+
+	shapeID
+	configErrors
+}
+
+// NewSprite creates a Sprite showing frame 0 of a sheet cut into
+// frameW×frameH frames, placed at (x, y). Partial frames at the right and
+// bottom edges are ignored.
+func NewSprite(sheet image.Image, frameW, frameH, x, y int) *Sprite {
+	s := &Sprite{sheet: sheet, frameW: frameW, frameH: frameH, im: NewImage(nil, x, y)}
+	s.check(sheet != nil, "Sprite", "NewSprite", "nil sheet")
+	s.check(frameW > 0 && frameH > 0, "Sprite", "NewSprite", "non-positive frame size")
+	s.count = s.gridSize()
+	return s
+}
+
+// gridSize returns the number of whole frames in the sheet.
+func (s *Sprite) gridSize() int {
+	if s.sheet == nil || s.frameW <= 0 || s.frameH <= 0 {
+		return 0
+	}
+	b := s.sheet.Bounds()
+	return (b.Dx() / s.frameW) * (b.Dy() / s.frameH)
+}
+
+// SetFrame selects the frame to draw. Indices wrap around the frame count,
+// so a frame counter of a longer sequence can be passed as is.
+func (s *Sprite) SetFrame(i int) *Sprite {
+	if s.count > 0 {
+		i %= s.count
+		if i < 0 {
+			i += s.count
+		}
+	}
+	s.frame = i
+	return s
+}
+
+// Frame returns the selected frame index.
+func (s *Sprite) Frame() int { return s.frame }
+
+// SetFrameCount limits the sprite to the first n frames, for sheets whose
+// last row is partly empty. It cannot exceed the frames the sheet holds.
+func (s *Sprite) SetFrameCount(n int) *Sprite {
+	s.check(n > 0, "Sprite", "SetFrameCount", "non-positive count ignored")
+	if n > 0 {
+		s.count = min(n, s.gridSize())
+		s.SetFrame(s.frame)
+	}
+	return s
+}
+
+// Len returns the number of frames.
+func (s *Sprite) Len() int { return s.count }
+
+// Image returns the Image instruction that draws the frames, to set its
+// size, fit, flips, opacity, mask or effects.
+func (s *Sprite) Image() *Image { return s.im }
+
+// SetID names the sprite for FindByID.
+func (s *Sprite) SetID(id string) *Sprite { s.id = id; return s }
+
+// current points the image at the selected frame and returns it.
+func (s *Sprite) current() *Image {
+	s.im.src = s.frameImage()
+	return s.im
+}
+
+// frameImage returns the pixels of the selected frame, sharing them with the
+// sheet where its type allows, or nil without frames.
+func (s *Sprite) frameImage() image.Image {
+	if s.count == 0 {
+		return nil
+	}
+	b := s.sheet.Bounds()
+	cols := b.Dx() / s.frameW
+	at := b.Min.Add(image.Pt(s.frame%cols*s.frameW, s.frame/cols*s.frameH))
+	r := image.Rectangle{Min: at, Max: at.Add(image.Pt(s.frameW, s.frameH))}
+	if si, ok := s.sheet.(subImager); ok {
+		return si.SubImage(r)
+	}
+	return subImage{s.sheet, r}
+}
+
+// SetTransform applies m to the drawn frame about origin, as
+// Image.SetTransform does. Returns the receiver for chaining.
+func (s *Sprite) SetTransform(m Matrix, origin Anchor) *Sprite {
+	s.im.SetTransform(m, origin)
+	return s
+}
+
+// SetPosition moves the sprite to (x, y).
+func (s *Sprite) SetPosition(x, y int) { s.im.SetPosition(x, y) }
+
+// Position returns the top-left corner.
+func (s *Sprite) Position() (int, int) { return s.im.Position() }
+
+// Size returns the drawn size, the frame size unless Image sets another.
+func (s *Sprite) Size() *geom.Size { return s.current().Size() }
+
+// drawBounds returns the bounds of the frame image.
+func (s *Sprite) drawBounds() (image.Rectangle, bool) { return s.current().drawBounds() }
+
+// scaled returns the frame image prepared for d.
+func (s *Sprite) scaled(d device) Shape { return s.current().scaled(d) }
+
+// Draw draws the selected frame.
+func (s *Sprite) Draw(base, overlay *image.RGBA) { s.current().Draw(base, overlay) }
+
+// subImage is a rectangle of an image type without SubImage.
+type subImage struct {
+	image.Image
+	r image.Rectangle
+}
+
+func (s subImage) Bounds() image.Rectangle { return s.r }
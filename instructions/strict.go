@@ -0,0 +1,106 @@
+package instructions
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxConfigErrors caps the errors one shape keeps, so a setter called with
+// the same bad value every frame does not grow without bound.
+const maxConfigErrors = 32
+
+// SetStrict turns strict mode on or off for this Layer. Setters normally
+// clamp or ignore invalid values, such as a nil pattern or a negative size,
+// so a template mistake renders something plausible instead of failing.
+// They always record a *ConfigError on the shape, reported by its Err method
+// and by Validate. A lenient Layer draws such shapes clamped; a strict one
+// refuses them on every load path. LoadInstructionsChecked returns the
+// errors and draws none of the batch; LoadInstruction, LoadInstructions,
+// LoadInstructionsFast and Retain skip each refused shape and record its
+// errors for Layer.Err.
+//
+// Strictness belongs to the Layer, so concurrent renders can choose it
+// independently. Returns the receiver for chaining.
+func (l *Layer) SetStrict(on bool) *Layer {
+	l.strict = on
+	return l
+}
+
+// Strict reports whether the Layer is in strict mode (see SetStrict).
+func (l *Layer) Strict() bool { return l.strict }
+
+// Err returns the configuration errors of the shapes a strict Layer refused
+// to draw, joined with errors.Join, or nil if it refused none. Only the
+// first 32 are kept.
+func (l *Layer) Err() error {
+	return errors.Join(l.refused...)
+}
+
+// refuse reports whether a strict Layer must skip shape, recording its
+// configuration errors.
+func (l *Layer) refuse(shape Shape) bool {
+	if !l.strict {
+		return false
+	}
+	err := Validate(shape)
+	if err != nil && len(l.refused) < maxConfigErrors {
+		l.refused = append(l.refused, err)
+	}
+	return err != nil
+}
+
+// ConfigError reports an invalid value passed to a shape setter.
+type ConfigError struct {
+	Shape  string // shape type, e.g. "Rectangle"
+	Setter string // setter name, e.g. "SetFillPattern"
+	Reason string // what was wrong and what was done instead
+}
+
+// Error implements the error interface.
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s.%s: %s", e.Shape, e.Setter, e.Reason)
+}
+
+// configErrors collects the ConfigErrors of one shape. Shapes embed it to
+// gain the Err method.
+type configErrors struct {
+	errs []error
+}
+
+// check records a ConfigError when ok is false.
+func (c *configErrors) check(ok bool, shape, setter, reason string) {
+	if !ok && len(c.errs) < maxConfigErrors {
+		c.errs = append(c.errs, &ConfigError{Shape: shape, Setter: setter, Reason: reason})
+	}
+}
+
+// Err returns the invalid configuration recorded by the shape's setters,
+// joined with errors.Join, or nil if there is none.
+func (c *configErrors) Err() error {
+	return errors.Join(c.errs...)
+}
+
+// Validate returns the configuration errors recorded on shapes and, depth
+// first, on the children of Groups, AutoLayouts, BoundedBoxes and Aligned
+// shapes, joined with errors.Join, or nil.
+func Validate(shapes ...Shape) error {
+	var errs []error
+	var walk func(s Shape)
+	walk = func(s Shape) {
+		if s == nil {
+			return
+		}
+		if v, ok := s.(interface{ Err() error }); ok {
+			if err := v.Err(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		for _, child := range childShapes(s) {
+			walk(child)
+		}
+	}
+	for _, s := range shapes {
+		walk(s)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,130 @@
+package glimo_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/instructions"
+	"github.com/stretchr/testify/require"
+)
+
+func solidFrame(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i+0], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+func writeChunk(buf *bytes.Buffer, typ string, data []byte) {
+	_ = binary.Write(buf, binary.BigEndian, uint32(len(data)))
+	buf.WriteString(typ)
+	buf.Write(data)
+	_ = binary.Write(buf, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(typ), data...)))
+}
+
+// buildAPNG assembles a minimal APNG from full-canvas frames with 1/10s delays.
+func buildAPNG(t testing.TB, frames []*image.RGBA) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	out.WriteString("\x89PNG\r\n\x1a\n")
+	seq := uint32(0)
+	for i, f := range frames {
+		var enc bytes.Buffer
+		require.NoError(t, png.Encode(&enc, f))
+		data := enc.Bytes()[8:]
+		var idat [][]byte
+		for p := 0; p+8 <= len(data); {
+			n := int(binary.BigEndian.Uint32(data[p:]))
+			typ := string(data[p+4 : p+8])
+			body := data[p+8 : p+8+n]
+			if i == 0 && typ == "IHDR" {
+				writeChunk(&out, "IHDR", body)
+				actl := make([]byte, 8)
+				binary.BigEndian.PutUint32(actl[0:], uint32(len(frames)))
+				writeChunk(&out, "acTL", actl)
+			}
+			if typ == "IDAT" {
+				idat = append(idat, body)
+			}
+			p += 12 + n
+		}
+
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], seq)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(f.Bounds().Dx()))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(f.Bounds().Dy()))
+		binary.BigEndian.PutUint16(fctl[20:], 1)
+		binary.BigEndian.PutUint16(fctl[22:], 10)
+		writeChunk(&out, "fcTL", fctl)
+		seq++
+		for _, d := range idat {
+			if i == 0 {
+				writeChunk(&out, "IDAT", d)
+				continue
+			}
+			fd := make([]byte, 4, 4+len(d))
+			binary.BigEndian.PutUint32(fd, seq)
+			writeChunk(&out, "fdAT", append(fd, d...))
+			seq++
+		}
+	}
+	writeChunk(&out, "IEND", nil)
+	return out.Bytes()
+}
+
+func TestAnimatedImage(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+
+	t.Run("gif", func(t *testing.T) {
+		pal := color.Palette{color.Transparent, red, blue}
+		g := &gif.GIF{LoopCount: 0}
+		for _, idx := range []uint8{1, 2, 1} {
+			p := image.NewPaletted(image.Rect(0, 0, 40, 40), pal)
+			for i := range p.Pix {
+				p.Pix[i] = idx
+			}
+			g.Image = append(g.Image, p)
+			g.Delay = append(g.Delay, 5)
+		}
+		var buf bytes.Buffer
+		require.NoError(t, gif.EncodeAll(&buf, g))
+
+		anim, err := instructions.NewAnimatedImageFromReader(&buf, 10, 10, instructions.DefaultDecodeLimits())
+		require.NoError(t, err)
+		require.Equal(t, 3, anim.Len())
+		require.Equal(t, 50*time.Millisecond, anim.Delay(0))
+		require.Equal(t, 150*time.Millisecond, anim.Duration())
+		require.Equal(t, 1, anim.FrameIndexAt(60*time.Millisecond))
+		require.Equal(t, 0, anim.FrameIndexAt(160*time.Millisecond))
+
+		template := newLayer(t, 80, 80)
+		template.LoadInstruction(instructions.NewRectangle(0, 0, 80, 80).SetFillColor(colors.White))
+		layers := anim.RenderOver(template)
+		require.Len(t, layers, 3)
+		require.Equal(t, blue, layers[1].Image().RGBAAt(20, 20))
+		require.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, template.Image().RGBAAt(20, 20))
+	})
+
+	t.Run("apng", func(t *testing.T) {
+		data := buildAPNG(t, []*image.RGBA{solidFrame(30, 20, red), solidFrame(30, 20, blue)})
+
+		anim, err := instructions.NewAnimatedImageFromReader(bytes.NewReader(data), 0, 0, instructions.DefaultDecodeLimits())
+		require.NoError(t, err)
+		require.Equal(t, 2, anim.Len())
+		require.Equal(t, 100*time.Millisecond, anim.Delay(1))
+
+		layers := anim.RenderOver(newLayer(t, 30, 20))
+		require.Equal(t, red, layers[0].Image().RGBAAt(5, 5))
+		require.Equal(t, blue, layers[1].Image().RGBAAt(5, 5))
+	})
+}
@@ -2,11 +2,13 @@ package glimo_test
 
 import (
 	"image"
+	"math"
 	"testing"
 
 	"github.com/Krispeckt/glimo/colors"
 	"github.com/Krispeckt/glimo/instructions"
 	"github.com/Krispeckt/glimo/internal/core/geom"
+	"github.com/Krispeckt/glimo/internal/render"
 	"github.com/stretchr/testify/require"
 )
 
@@ -404,3 +406,106 @@ func TestAutoLayout_RenderSmoke(t *testing.T) {
 	require.Equal(t, 500.0, sz.Width())
 	require.Equal(t, 1000.0, sz.Height())
 }
+
+func TestAutoLayout_CheckOverflow(t *testing.T) {
+	row := func(h int) *instructions.AutoLayout {
+		return instructions.NewAutoLayout(10, 10, instructions.ContainerStyle{
+			Direction: instructions.Row, Width: 300, Height: h, Padding: [4]int{5, 5, 5, 5},
+		})
+	}
+
+	fits := row(60).
+		Add(newMock("a", 100, 40), instructions.ItemStyle{}).
+		Add(newMock("b", 100, 40), instructions.ItemStyle{})
+	require.NoError(t, fits.CheckOverflow())
+
+	// A fixed item height does not fit a shorter row.
+	inner := row(30).
+		Add(newMock("a", 100, 20), instructions.ItemStyle{}).
+		Add(newMock("b", 100, 20), instructions.ItemStyle{Height: 40, Name: "price"})
+	outer := instructions.NewAutoLayout(0, 0, instructions.ContainerStyle{Width: 400, Height: 200}).
+		Add(newMock("title", 100, 20), instructions.ItemStyle{}).
+		Add(inner, instructions.ItemStyle{})
+
+	err := outer.CheckOverflow()
+	var overflow *instructions.LayoutOverflowError
+	require.ErrorAs(t, err, &overflow)
+	require.Equal(t, []string{"#1", "price"}, overflow.Path)
+	require.Equal(t, image.Rect(15, 15, 305, 35), overflow.Container)
+	require.Greater(t, overflow.Item.Max.Y, overflow.Container.Max.Y)
+	require.Contains(t, err.Error(), "layout: #1/price")
+}
+
+func TestAutoLayout_ChildChangedInPlace(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 20)
+	title := instructions.NewText("Hi", 0, 0, font)
+	al := instructions.NewAutoLayout(0, 0, instructions.ContainerStyle{Direction: instructions.Row})
+	al.Add(title, instructions.ItemStyle{}).Add(newMock("icon", 20, 20), instructions.ItemStyle{})
+	w := al.Size().Width()
+
+	// Texts edited after layout resize their container without InvalidateLayout.
+	title.SetText("Hello, world")
+	require.Equal(t, math.Round(title.Size().Width())+20, al.Size().Width())
+	require.Greater(t, al.Size().Width(), w)
+	title.SetText("Hi")
+	require.Equal(t, w, al.Size().Width())
+}
+
+func TestAutoLayout_OverflowPolicy(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 30)
+	card := func(policy *instructions.OverflowPolicy) (*instructions.AutoLayout, *instructions.Text) {
+		title := instructions.NewText("A much longer headline", 0, 0, font).SetSolidColor(colors.Black)
+		al := instructions.NewAutoLayout(0, 0, instructions.ContainerStyle{
+			Direction: instructions.Row, Width: 240, Height: 60, Gap: instructions.Vector2{X: 8},
+		})
+		al.Add(title, instructions.ItemStyle{Name: "title", Priority: 1})
+		al.Add(newMock("badge", 40, 40), instructions.ItemStyle{Name: "badge"})
+		al.SetOverflowPolicy(policy)
+		return al, title
+	}
+
+	// Shrinking alone reaches the floor and reports that it still overflows.
+	al, title := card(&instructions.OverflowPolicy{
+		Strategies:  []instructions.OverflowStrategy{instructions.OverflowShrinkText},
+		MinFontSize: 20,
+	})
+	al.Size()
+	r := al.OverflowReport()
+	require.False(t, r.Fits)
+	require.Equal(t, 20.0, title.Font().HeightPt())
+	require.Equal(t, "shrink title: 30pt -> 20pt", r.Actions[0].String())
+
+	// Hiding the badge then makes room.
+	al, _ = card(&instructions.OverflowPolicy{
+		Strategies:  []instructions.OverflowStrategy{instructions.OverflowShrinkText, instructions.OverflowHideLowPriority},
+		MinFontSize: 16,
+	})
+	al.Draw(newCanvases())
+	r = al.OverflowReport()
+	require.True(t, r.Fits)
+	require.Equal(t, instructions.OverflowHideLowPriority, r.Actions[len(r.Actions)-1].Strategy)
+	require.Equal(t, []string{"badge"}, r.Actions[len(r.Actions)-1].Path)
+
+	// Growing keeps the text as is and widens the layout.
+	al, title = card(&instructions.OverflowPolicy{Strategies: []instructions.OverflowStrategy{instructions.OverflowGrow}})
+	require.Greater(t, al.Size().Width(), 240.0)
+	require.True(t, al.OverflowReport().Fits)
+	require.Equal(t, 30.0, title.Font().HeightPt())
+
+	// Truncation wraps the title to the content width on one line.
+	al, title = card(&instructions.OverflowPolicy{Strategies: []instructions.OverflowStrategy{instructions.OverflowTruncate}})
+	al.Size()
+	require.Len(t, al.OverflowReport().Actions, 1)
+	require.LessOrEqual(t, title.Size().Width(), 240.0)
+
+	// A policy on a Text fits it into a height budget.
+	body := instructions.NewText("Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor.", 0, 0, font).
+		SetMaxWidth(200).
+		SetOverflowPolicy(&instructions.OverflowPolicy{
+			Strategies:  []instructions.OverflowStrategy{instructions.OverflowShrinkText, instructions.OverflowTruncate},
+			MinFontSize: 24, MaxLines: 10, MaxHeight: 90,
+		})
+	require.LessOrEqual(t, body.Size().Height(), 90.0)
+	require.True(t, body.OverflowReport().Fits)
+	require.Len(t, body.OverflowReport().Actions, 2)
+}
@@ -1,10 +1,13 @@
 package glimo_test
 
 import (
+	"math"
+	"slices"
 	"testing"
 
 	"github.com/Krispeckt/glimo/colors"
 	"github.com/Krispeckt/glimo/effects"
+	"github.com/Krispeckt/glimo/glimotest"
 	"github.com/Krispeckt/glimo/instructions"
 	"github.com/stretchr/testify/require"
 )
@@ -165,6 +168,63 @@ func TestInstructionCircle(t *testing.T) {
 
 			err := canvas.Export("./output/circle_" + cse.name + ".png")
 			require.NoError(t, err, "export failed for %s", cse.name)
+
+			glimotest.AssertImageMatches(t, canvas, "./testdata/golden/circle_"+cse.name+".png", 0.01)
 		})
 	}
 }
+
+func TestCircleDashes(t *testing.T) {
+	// runs returns the lengths, in half-degree steps, of the stroked runs
+	// around the ring of radius r centered at (50, 50), joined across 0°.
+	runs := func(l *instructions.Layer, r float64) []int {
+		on := make([]bool, 720)
+		for i := range on {
+			a := float64(i) / 2 * math.Pi / 180
+			on[i] = l.Image().RGBAAt(int(50+r*math.Cos(a)), int(50+r*math.Sin(a))).A > 128
+		}
+		start := slices.Index(on, false)
+		require.GreaterOrEqual(t, start, 0, "ring is dashed")
+		var out []int
+		n := 0
+		for k := 0; k < len(on); k++ {
+			if on[(start+k)%len(on)] {
+				n++
+			} else if n > 0 {
+				out = append(out, n)
+				n = 0
+			}
+		}
+		if n > 0 {
+			out = append(out, n)
+		}
+		return out
+	}
+
+	for _, off := range []float64{0, 5, 13} {
+		l := instructions.NewLayer(100, 100)
+		l.LoadInstruction(instructions.NewCircle(10, 10, 40).
+			SetLineWidth(4).
+			SetStrokeColor(colors.Black).
+			SetLineCap(instructions.LineCapButt).
+			SetDashes([]float64{10, 10}).
+			SetDashOffset(off))
+
+		// A circumference of about 239px fits 12 cycles of 20px, all of
+		// the same length, with none cut short at the start point.
+		got := runs(l, 38)
+		require.Len(t, got, 12, "offset %v", off)
+		require.LessOrEqual(t, slices.Max(got)-slices.Min(got), 4, "offset %v: %v", off, got)
+		if off == 5 {
+			require.NoError(t, l.Export("./output/circle_dashes.png"))
+		}
+	}
+
+	// Round caps on tiny dashes draw a dotted ring.
+	l := instructions.NewLayer(100, 100)
+	l.LoadInstruction(instructions.NewCircle(10, 10, 40).
+		SetLineWidth(4).
+		SetStrokeColor(colors.Black).
+		SetDashes([]float64{0.5, 12}))
+	require.Len(t, runs(l, 38), 19)
+}
@@ -0,0 +1,161 @@
+package glimo_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+
+	"github.com/Krispeckt/glimo/instructions"
+	"github.com/stretchr/testify/require"
+)
+
+// encodePNG returns a w×h opaque PNG.
+func encodePNG(t testing.TB, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// forgePNGSize rewrites the size in the IHDR chunk of data, fixing its CRC.
+func forgePNGSize(data []byte, w, h uint32) []byte {
+	out := append([]byte(nil), data...)
+	binary.BigEndian.PutUint32(out[16:], w)
+	binary.BigEndian.PutUint32(out[20:], h)
+	binary.BigEndian.PutUint32(out[29:], crc32.ChecksumIEEE(out[12:29]))
+	return out
+}
+
+func TestSafeDecode(t *testing.T) {
+	lim := instructions.DefaultDecodeLimits()
+	data := encodePNG(t, 40, 30)
+
+	im, err := instructions.NewImageFromReader(bytes.NewReader(data), 5, 5, lim)
+	require.NoError(t, err)
+	require.Equal(t, 40.0, im.Size().Width())
+	l, err := instructions.NewLayerFromReader(bytes.NewReader(data), lim)
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 40, 30), l.Image().Bounds())
+
+	// Sizes are checked from the header, before decoding.
+	small := lim
+	small.MaxPixels = 1000
+	_, err = instructions.NewLayerFromReader(bytes.NewReader(data), small)
+	require.ErrorContains(t, err, "exceeds 1000 pixels")
+
+	// A tiny file declaring a huge canvas is a bomb even under the pixel limit.
+	bomb := forgePNGSize(data, 6000, 6000)
+	_, err = instructions.NewLayerFromReader(bytes.NewReader(bomb), lim)
+	require.ErrorContains(t, err, "decompression bomb")
+
+	capped := lim
+	capped.MaxBytes = 64
+	_, err = instructions.NewLayerFromReader(bytes.NewReader(data), capped)
+	require.ErrorContains(t, err, "exceeds 64 bytes")
+
+	// Malformed and unsupported input fails cleanly.
+	_, err = instructions.NewLayerFromReader(bytes.NewReader(data[:len(data)/2]), lim)
+	require.Error(t, err)
+	_, err = instructions.NewLayerFromReader(bytes.NewReader([]byte("GIF89a\x01\x00\x01\x00")), lim)
+	require.Error(t, err)
+	_, err = instructions.NewLayerFromReader(bytes.NewReader(forgePNGSize(data, 0, 30)), lim)
+	require.Error(t, err)
+}
+
+// forgeChunk applies edit to the body of the first typ chunk in a PNG
+// stream, fixing its CRC.
+func forgeChunk(data []byte, typ string, edit func(body []byte)) []byte {
+	out := append([]byte(nil), data...)
+	at := bytes.Index(out, []byte(typ))
+	n := int(binary.BigEndian.Uint32(out[at-4:]))
+	edit(out[at+4 : at+4+n])
+	binary.BigEndian.PutUint32(out[at+4+n:], crc32.ChecksumIEEE(out[at:at+4+n]))
+	return out
+}
+
+func TestSafeDecodeAnimation(t *testing.T) {
+	lim := instructions.DefaultDecodeLimits()
+	red := color.RGBA{R: 255, A: 255}
+	data := buildAPNG(t, []*image.RGBA{solidFrame(30, 20, red), solidFrame(30, 20, red)})
+	decode := func(in []byte, lim instructions.DecodeLimits) error {
+		_, err := instructions.NewAnimatedImageFromReader(bytes.NewReader(in), 0, 0, lim)
+		return err
+	}
+	require.NoError(t, decode(data, lim))
+
+	// A forged IHDR fails before the canvas is allocated.
+	require.ErrorContains(t, decode(forgePNGSize(data, 1<<30, 1<<30), lim), "image too large")
+	require.ErrorContains(t, decode(forgePNGSize(data, 6000, 6000), lim), "decompression bomb")
+	require.ErrorContains(t, decode(forgePNGSize(data, 0xFFFFFFFF, 0xFFFFFFFF), lim), "invalid image size")
+	require.ErrorContains(t, decode(forgePNGSize(encodePNG(t, 8, 8), 1<<20, 1<<20), lim), "image too large",
+		"static PNGs are checked too")
+
+	// Frames must lie inside the canvas, and many frames count together.
+	outside := forgeChunk(data, "fcTL", func(b []byte) { binary.BigEndian.PutUint32(b[4:], 1<<30) })
+	require.ErrorContains(t, decode(outside, lim), "outside the 30x20 canvas")
+	frames := lim
+	frames.MaxExpansion = float64(30*20*4*3/2) / float64(len(data))
+	require.ErrorContains(t, decode(data, frames), "2 frames of 30x20")
+
+	capped := lim
+	capped.MaxBytes = 64
+	require.ErrorContains(t, decode(data, capped), "exceeds 64 bytes")
+
+	// The GIF logical screen is checked the same way.
+	g := &gif.GIF{Image: []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{red})}, Delay: []int{0}}
+	var buf bytes.Buffer
+	require.NoError(t, gif.EncodeAll(&buf, g))
+	require.NoError(t, decode(buf.Bytes(), lim))
+	huge := append([]byte(nil), buf.Bytes()...)
+	binary.LittleEndian.PutUint16(huge[6:], 0xFFFF)
+	binary.LittleEndian.PutUint16(huge[8:], 0xFFFF)
+	require.ErrorContains(t, decode(huge, lim), "image too large")
+	require.Error(t, decode(buf.Bytes()[:len(buf.Bytes())/2], lim))
+}
+
+func FuzzNewLayerFromReader(f *testing.F) {
+	data := encodePNG(f, 8, 8)
+	f.Add(data)
+	f.Add(data[:40])
+	f.Add(forgePNGSize(data, 1<<20, 1<<20))
+	f.Add([]byte("RIFF\x00\x00\x00\x00WEBPVP8L"))
+	f.Add([]byte{0xff, 0xd8, 0xff, 0xdb})
+
+	lim := instructions.DefaultDecodeLimits()
+	lim.MaxPixels = 1 << 20
+	f.Fuzz(func(t *testing.T, in []byte) {
+		l, err := instructions.NewLayerFromReader(bytes.NewReader(in), lim)
+		if err != nil {
+			return
+		}
+		b := l.Image().Bounds()
+		require.LessOrEqual(t, int64(b.Dx())*int64(b.Dy()), lim.MaxPixels)
+	})
+}
+
+func FuzzNewAnimatedImageFromReader(f *testing.F) {
+	red := color.RGBA{R: 255, A: 255}
+	data := buildAPNG(f, []*image.RGBA{solidFrame(4, 4, red), solidFrame(4, 4, red)})
+	f.Add(data)
+	f.Add(forgePNGSize(data, 1<<20, 1<<20))
+	f.Add([]byte("GIF89a\xff\xff\xff\xff\x00\x00\x00;"))
+
+	lim := instructions.DefaultDecodeLimits()
+	lim.MaxPixels = 1 << 20
+	f.Fuzz(func(t *testing.T, in []byte) {
+		anim, err := instructions.NewAnimatedImageFromReader(bytes.NewReader(in), 0, 0, lim)
+		if err != nil {
+			return
+		}
+		require.Positive(t, anim.Len())
+	})
+}
@@ -0,0 +1,313 @@
+package glimo_test
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// Synthetic OpenType fonts for shaping tests. Every glyph is a filled
+// rectangle standing on the baseline, so a test can read back which glyphs
+// were drawn, and in which order, from the heights of the ink columns.
+
+// testGlyph is one glyph of a synthetic font, in units of a 1000-unit em.
+type testGlyph struct {
+	r      rune   // mapped code point, 0 for glyphs reached only by GSUB
+	adv    uint16 // advance width
+	x0, x1 int16  // horizontal extent of the rectangle; empty if equal
+	y0, y1 int16  // vertical extent of the rectangle
+	class  uint16 // GDEF glyph class
+}
+
+// testFont assembles a TrueType font from glyphs, optional GSUB and GPOS
+// tables built with layoutTable and optional fvar and gvar tables built with
+// fvarTable and gvarTable.
+type testFont struct {
+	glyphs     []testGlyph // glyph 0 is .notdef
+	gsub, gpos []byte
+	fvar, gvar []byte
+}
+
+// bytes encodes the font.
+func (tf *testFont) bytes() []byte {
+	n := uint16(len(tf.glyphs))
+	tables := map[string][]byte{}
+
+	tables["head"] = cat(
+		u32s(0x00010000, 0x00010000, 0, 0x5F0F3CF5),
+		u16s(3, 1000), make([]byte, 16),
+		u16s(0xFF38, 0xFF38, 1000, 1000, 0, 8, 2, 1, 0),
+	)
+	tables["hhea"] = cat(u32s(0x00010000), u16s(1000, 0xFF38, 0, 1000, 0, 0, 1000, 1, 0, 0, 0, 0, 0, 0, 0, n))
+	tables["maxp"] = cat(u32s(0x00010000), u16s(n, 4, 1, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0))
+	tables["post"] = cat(u32s(0x00030000, 0), u16s(0xFF9C, 50), u32s(0, 0, 0, 0, 0))
+
+	var hmtx, glyf, loca, classes []byte
+	type group struct{ r, g uint32 }
+	var groups []group
+	for i, g := range tf.glyphs {
+		hmtx = append(hmtx, u16s(g.adv, uint16(g.x0))...)
+		loca = append(loca, u32s(uint32(len(glyf)))...)
+		if g.x0 != g.x1 {
+			glyf = append(glyf, cat(
+				u16s(1, uint16(g.x0), uint16(g.y0), uint16(g.x1), uint16(g.y1), 3, 0),
+				[]byte{1, 1, 1, 1},
+				u16s(uint16(g.x0), 0, uint16(g.x1-g.x0), 0),
+				u16s(uint16(g.y0), uint16(g.y1-g.y0), 0, uint16(g.y0-g.y1)),
+			)...)
+			for len(glyf)%4 != 0 {
+				glyf = append(glyf, 0)
+			}
+		}
+		classes = append(classes, u16s(g.class)...)
+		if g.r != 0 {
+			groups = append(groups, group{uint32(g.r), uint32(i)})
+		}
+	}
+	tables["hmtx"] = hmtx
+	tables["glyf"] = glyf
+	tables["loca"] = append(loca, u32s(uint32(len(glyf)))...)
+	tables["GDEF"] = cat(u32s(0x00010000), u16s(12, 0, 0, 0), u16s(1, 0, n), classes)
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].r < groups[j].r })
+	cmap := cat(u16s(12, 0), u32s(uint32(16+12*len(groups)), 0, uint32(len(groups))))
+	for _, g := range groups {
+		cmap = append(cmap, u32s(g.r, g.r, g.g)...)
+	}
+	tables["cmap"] = cat(u16s(0, 1, 3, 10), u32s(12), cmap)
+
+	if tf.gsub != nil {
+		tables["GSUB"] = tf.gsub
+	}
+	if tf.gpos != nil {
+		tables["GPOS"] = tf.gpos
+	}
+	if tf.fvar != nil {
+		tables["fvar"], tables["gvar"] = tf.fvar, tf.gvar
+	}
+
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	out := cat(u32s(0x00010000), u16s(uint16(len(tags)), 0, 0, 0))
+	off := len(out) + 16*len(tags)
+	var data []byte
+	for _, tag := range tags {
+		t := tables[tag]
+		out = append(out, tag...)
+		out = append(out, u32s(0, uint32(off+len(data)), uint32(len(t)))...)
+		data = append(data, t...)
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+	}
+	return append(out, data...)
+}
+
+// otFeature enables lookups, by index, under a feature tag.
+type otFeature struct {
+	tag     string
+	lookups []uint16
+}
+
+// layoutTable builds a GSUB or GPOS table whose scripts all enable every
+// feature by default.
+func layoutTable(scripts []string, features []otFeature, lookups ...[]byte) []byte {
+	indices := make([]uint16, len(features))
+	for i := range indices {
+		indices[i] = uint16(i)
+	}
+	langSys := cat(u16s(0, 0xFFFF, uint16(len(features))), u16s(indices...))
+	scriptList := u16s(uint16(len(scripts)))
+	var scriptData []byte
+	for _, tag := range scripts {
+		scriptList = append(scriptList, tag...)
+		scriptList = append(scriptList, u16s(uint16(2+6*len(scripts)+len(scriptData)))...)
+		scriptData = append(scriptData, cat(u16s(4, 0), langSys)...)
+	}
+	scriptList = append(scriptList, scriptData...)
+
+	var featureTables [][]byte
+	for _, f := range features {
+		featureTables = append(featureTables, cat(u16s(0, uint16(len(f.lookups))), u16s(f.lookups...)))
+	}
+	tags := make([]string, len(features))
+	for i, f := range features {
+		tags[i] = f.tag
+	}
+	featureList := offsetList(featureTables, tags)
+	lookupList := offsetList(lookups, nil)
+	return cat(u32s(0x00010000),
+		u16s(10, uint16(10+len(scriptList)), uint16(10+len(scriptList)+len(featureList))),
+		scriptList, featureList, lookupList)
+}
+
+// offsetList encodes a count, one record per item (a tag, if given, and a
+// 16-bit offset from the list start) and the items.
+func offsetList(items [][]byte, tags []string) []byte {
+	rec := 2
+	if tags != nil {
+		rec = 6
+	}
+	out := u16s(uint16(len(items)))
+	off := 2 + rec*len(items)
+	for i, item := range items {
+		if tags != nil {
+			out = append(out, tags[i]...)
+		}
+		out = append(out, u16s(uint16(off))...)
+		off += len(item)
+	}
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+// lookup builds a lookup table of the given type and flags.
+func lookup(kind, flag uint16, subtables ...[]byte) []byte {
+	head := u16s(kind, flag, uint16(len(subtables)))
+	off := len(head) + 2*len(subtables)
+	var body []byte
+	for _, st := range subtables {
+		head = append(head, u16s(uint16(off+len(body)))...)
+		body = append(body, st...)
+	}
+	return append(head, body...)
+}
+
+// coverage builds a format 1 coverage table of sorted glyphs.
+func coverage(glyphs ...uint16) []byte {
+	return cat(u16s(1, uint16(len(glyphs))), u16s(glyphs...))
+}
+
+// singleSubst builds a format 2 single substitution of one glyph.
+func singleSubst(from, to uint16) []byte {
+	return cat(u16s(2, 8, 1, to), coverage(from))
+}
+
+// ligatureSubst builds a ligature substitution of one component sequence.
+func ligatureSubst(lig uint16, comps ...uint16) []byte {
+	ligature := cat(u16s(lig, uint16(len(comps))), u16s(comps[1:]...))
+	set := cat(u16s(1, 4), ligature)
+	return cat(u16s(1, uint16(8+len(set)), 1, 8), set, coverage(comps[0]))
+}
+
+// markToBase builds a mark-to-base attachment of one mark class: the mark
+// glyph's anchor meets the anchor at (x, y) on every base glyph.
+func markToBase(mark uint16, mx, my int16, bases []uint16, x, y int16) []byte {
+	anchor := func(x, y int16) []byte { return u16s(1, uint16(x), uint16(y)) }
+	markArray := cat(u16s(1, 0, 6), anchor(mx, my))
+	baseArray := u16s(uint16(len(bases)))
+	for i := range bases {
+		baseArray = append(baseArray, u16s(uint16(2+2*len(bases)+6*i))...)
+	}
+	for range bases {
+		baseArray = append(baseArray, anchor(x, y)...)
+	}
+	markCov, baseCov := coverage(mark), coverage(bases...)
+	head := 12
+	return cat(
+		u16s(1, uint16(head+len(markArray)+len(baseArray)), uint16(head+len(markArray)+len(baseArray)+len(markCov)),
+			1, uint16(head), uint16(head+len(markArray))),
+		markArray, baseArray, markCov, baseCov,
+	)
+}
+
+// testAxis is a design axis of a synthetic variable font.
+type testAxis struct {
+	tag           string
+	min, def, max float64
+}
+
+// fvarTable builds an fvar table with the given axes and no named instances.
+func fvarTable(axes ...testAxis) []byte {
+	fixed := func(v float64) uint32 { return uint32(int32(v * 65536)) }
+	out := cat(u32s(0x00010000), u16s(16, 2, uint16(len(axes)), 20, 0, uint16(4+4*len(axes))))
+	for _, a := range axes {
+		out = append(out, a.tag...)
+		out = append(out, cat(u32s(fixed(a.min), fixed(a.def), fixed(a.max)), u16s(0, 256))...)
+	}
+	return out
+}
+
+// testTuple moves points of a glyph by (dx, dy) at a peak position given in
+// normalized axis coordinates. With points nil it moves every point, the
+// four phantom points after the outline included.
+type testTuple struct {
+	peak   []float64
+	points []uint16
+	dx, dy []int16
+}
+
+// gvarTable builds a gvar table for a font of numGlyphs glyphs and the given
+// number of axes, with the tuples of each varied glyph.
+func gvarTable(axes, numGlyphs int, tuples map[int][]testTuple) []byte {
+	f2dot14 := func(v float64) uint16 { return uint16(int16(v * 16384)) }
+	deltas := func(d []int16) []byte {
+		words := make([]uint16, len(d))
+		for i, v := range d {
+			words[i] = uint16(v)
+		}
+		return cat([]byte{0x40 | byte(len(d)-1)}, u16s(words...))
+	}
+
+	var data []byte
+	offsets := u32s(0)
+	for g := 0; g < numGlyphs; g++ {
+		if ts := tuples[g]; len(ts) > 0 {
+			var headers, serial []byte
+			for _, t := range ts {
+				var body []byte
+				if t.points == nil {
+					body = []byte{0}
+				} else {
+					body = []byte{byte(len(t.points)), byte(len(t.points) - 1)}
+					prev := uint16(0)
+					for _, p := range t.points {
+						body = append(body, byte(p-prev))
+						prev = p
+					}
+				}
+				body = cat(body, deltas(t.dx), deltas(t.dy))
+				headers = append(headers, u16s(uint16(len(body)), 0xA000)...)
+				for _, p := range t.peak {
+					headers = append(headers, u16s(f2dot14(p))...)
+				}
+				serial = append(serial, body...)
+			}
+			data = append(data, cat(u16s(uint16(len(ts)), uint16(4+len(headers))), headers, serial)...)
+			for len(data)%2 != 0 {
+				data = append(data, 0)
+			}
+		}
+		offsets = append(offsets, u32s(uint32(len(data)))...)
+	}
+	start := uint32(20 + len(offsets))
+	return cat(u32s(0x00010000), u16s(uint16(axes), 0), u32s(start), u16s(uint16(numGlyphs), 1), u32s(start), offsets, data)
+}
+
+func u16s(v ...uint16) []byte {
+	var b []byte
+	for _, x := range v {
+		b = binary.BigEndian.AppendUint16(b, x)
+	}
+	return b
+}
+
+func u32s(v ...uint32) []byte {
+	var b []byte
+	for _, x := range v {
+		b = binary.BigEndian.AppendUint32(b, x)
+	}
+	return b
+}
+
+func cat(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
@@ -2,10 +2,14 @@ package glimo_test
 
 import (
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
 	"path/filepath"
 	"testing"
 
 	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/effects"
 	"github.com/Krispeckt/glimo/instructions"
 	"github.com/Krispeckt/glimo/internal/render"
 	"github.com/stretchr/testify/require"
@@ -170,3 +174,216 @@ func TestGroup_DrawAndExport(t *testing.T) {
 		})
 	}
 }
+
+// swatch is a BoundedShape defined outside the instructions package.
+type swatch struct {
+	x, y, w, h int
+	c          color.RGBA
+}
+
+func (s *swatch) Draw(_, overlay *image.RGBA) {
+	r := image.Rect(s.x, s.y, s.x+s.w, s.y+s.h).Intersect(overlay.Bounds())
+	draw.Draw(overlay, r, image.NewUniform(s.c), image.Point{}, draw.Src)
+}
+
+func (s *swatch) Size() *instructions.Size {
+	return instructions.NewSize(float64(s.w), float64(s.h))
+}
+
+func (s *swatch) Position() (int, int) { return s.x, s.y }
+func (s *swatch) SetPosition(x, y int) { s.x, s.y = x, y }
+
+func TestGroup_ExternalBoundedShape(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	sw := &swatch{x: 10, y: 10, w: 20, h: 20, c: red}
+
+	g := instructions.NewGroup().SetPositionChain(40, 40)
+	g.AddInstruction(sw)
+	require.Equal(t, float64(20), g.Size().Width())
+
+	l := instructions.NewLayer(100, 100)
+	l.LoadInstruction(g)
+	require.Equal(t, red, l.Image().RGBAAt(55, 55))
+	require.Equal(t, uint8(0), l.Image().RGBAAt(45, 45).A)
+
+	// Drawn directly, the layer restricts the overlay to the shape's box.
+	l = instructions.NewLayer(100, 100)
+	l.LoadInstruction(&swatch{x: 60, y: 5, w: 10, h: 10, c: red})
+	require.Equal(t, red, l.Image().RGBAAt(65, 10))
+	require.Equal(t, uint8(0), l.Image().RGBAAt(50, 10).A)
+}
+
+func TestBoundedLineInGroup(t *testing.T) {
+	cross := func() *instructions.Line {
+		return instructions.NewLine().
+			SetLineWidth(4).
+			SetStrokePattern(colors.NewSolid(colors.Black)).
+			MoveTo(0, 0).LineTo(40, 40).
+			MoveTo(40, 0).LineTo(0, 40).
+			Stroke()
+	}
+
+	b := instructions.Bounded(cross(), 40, 40)
+	x, y := b.Position()
+	require.Equal(t, 0, x)
+	require.Equal(t, 0, y)
+	require.Equal(t, 40.0, b.Size().Width())
+
+	g := instructions.NewGroup().SetPositionChain(30, 20)
+	g.AddInstruction(b.SetPositionChain(10, 10))
+	l := instructions.NewLayer(120, 100)
+	l.LoadInstruction(g)
+	img := l.Image()
+	require.Greater(t, img.RGBAAt(60, 50).A, uint8(128), "center of the cross")
+	require.Zero(t, img.RGBAAt(20, 20).A, "line origin before the offset")
+
+	// Drawing is clipped to the box.
+	clipped := instructions.Bounded(instructions.NewLine().
+		SetLineWidth(4).
+		SetStrokePattern(colors.NewSolid(colors.Black)).
+		MoveTo(0, 10).LineTo(100, 10).
+		Stroke(), 30, 20).SetPositionChain(10, 0)
+	l = instructions.NewLayer(120, 20)
+	l.LoadInstruction(clipped)
+	require.Greater(t, l.Image().RGBAAt(20, 10).A, uint8(128))
+	require.Zero(t, l.Image().RGBAAt(60, 10).A)
+
+	al := instructions.NewAutoLayout(10, 10, instructions.ContainerStyle{
+		Display:   instructions.DisplayFlex,
+		Direction: instructions.Row,
+		Gap:       instructions.Vector2{X: 10},
+	})
+	al.Add(instructions.Bounded(cross(), 40, 40), instructions.ItemStyle{})
+	al.Add(instructions.Bounded(cross(), 40, 40), instructions.ItemStyle{})
+	l = instructions.NewLayerWithScale(110, 60, 2)
+	l.LoadInstruction(al)
+	require.Greater(t, l.Image().RGBAAt(2*80, 2*30).A, uint8(128), "center of the second cross")
+	require.NoError(t, l.Export("./output/bounded_line.png"))
+}
+
+func TestFindByID(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 20)
+	title := instructions.NewText("Title", 0, 0, font).SetID("title")
+	badge := instructions.NewCircle(0, 0, 10).SetFillColor(colors.Red).SetID("badge")
+	g := instructions.NewGroup().SetID("card")
+	g.AddInstructions(instructions.NewRectangle(0, 0, 100, 40).SetFillColor(colors.White), title)
+
+	al := instructions.NewAutoLayout(0, 0, instructions.ContainerStyle{Direction: instructions.Row, Gap: instructions.Vector2{X: 4}})
+	al.Add(g, instructions.ItemStyle{})
+	al.Add(instructions.Bounded(badge, 20, 20), instructions.ItemStyle{})
+
+	require.Same(t, title, al.FindByID("title"))
+	require.Same(t, badge, al.FindByID("badge"))
+	require.Same(t, g, al.FindByID("card"))
+	require.Same(t, title, g.FindByID("title"))
+	require.Nil(t, g.FindByID("badge"))
+	require.Nil(t, al.FindByID(""), "empty IDs never match")
+	require.Nil(t, instructions.FindByID("missing", al))
+
+	// Mutate a node found in a retained scene and redraw.
+	l := instructions.NewLayer(200, 60)
+	l.Retain(al)
+	before := append([]uint8(nil), l.Image().Pix...)
+	l.FindByID("title").(*instructions.Text).SetText("Renamed")
+	al.InvalidateLayout()
+	l.Invalidate(al).Rerender()
+	require.Equal(t, "Renamed", title.Text())
+	require.NotEqual(t, before, l.Image().Pix)
+}
+
+func TestApplyPatch(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 20)
+	build := func() *instructions.AutoLayout {
+		al := instructions.NewAutoLayout(10, 10, instructions.ContainerStyle{Direction: instructions.Row, Gap: instructions.Vector2{X: 8}})
+		al.Add(instructions.NewRectangle(0, 0, 40, 40).SetLineWidth(0).SetFillColor(colors.Red).SetID("swatch"), instructions.ItemStyle{})
+		al.Add(instructions.NewText("Hello", 0, 0, font).SetSolidColor(colors.Black).SetID("greeting"), instructions.ItemStyle{})
+		al.Add(instructions.NewCircle(0, 0, 12).SetFillColor(colors.Blue).SetID("dot"), instructions.ItemStyle{})
+		return al
+	}
+	name, hidden := "Goodbye, world", false
+
+	tpl := build()
+	l := instructions.NewLayer(260, 60)
+	l.Retain(tpl)
+	require.NoError(t, l.ApplyPatch([]instructions.Patch{
+		{ID: "greeting", Text: &name},
+		{ID: "swatch", Fill: colors.NewSolid(colors.Green)},
+		{ID: "dot", Visible: &hidden},
+	}))
+	l.Rerender()
+
+	// Same result as building the patched scene from scratch.
+	want := build()
+	want.FindByID("greeting").(*instructions.Text).SetText(name)
+	want.FindByID("swatch").(*instructions.Rectangle).SetFillColor(colors.Green)
+	want.FindByID("dot").(*instructions.Circle).SetVisible(false)
+	fresh := instructions.NewLayer(260, 60)
+	fresh.LoadInstruction(want)
+	require.Equal(t, fresh.Image().Pix, l.Image().Pix)
+	require.Zero(t, l.Image().RGBAAt(15, 15).R)
+
+	err := instructions.ApplyPatch(tpl, []instructions.Patch{
+		{ID: "missing", Visible: &hidden},
+		{ID: "swatch", Text: &name},
+	})
+	var pe *instructions.PatchError
+	require.ErrorAs(t, err, &pe)
+	require.ErrorContains(t, err, `patch "missing": no shape with this ID`)
+	require.ErrorContains(t, err, `patch "swatch": Text: not supported by Rectangle`)
+}
+
+func TestAlignTo(t *testing.T) {
+	avatar := instructions.NewRectangle(20, 20, 60, 60).SetLineWidth(0).SetFillColor(colors.RoyalBlue)
+	badge := instructions.NewCircle(0, 0, 10).SetLineWidth(0).SetFillColor(colors.Tomato)
+	aligned := instructions.AlignTo(badge, avatar, instructions.AnchorBottomRight, 0, 0).
+		SetSelfAnchor(instructions.AnchorCenter)
+
+	x, y := aligned.Position()
+	require.Equal(t, 70, x)
+	require.Equal(t, 70, y)
+
+	// The badge follows the avatar when it grows.
+	avatar.SetSize(80, 80)
+	x, y = aligned.Position()
+	require.Equal(t, 90, x)
+	require.Equal(t, 90, y)
+
+	// Same anchor inside, with an offset.
+	label := instructions.NewRectangle(0, 0, 20, 10)
+	x, y = instructions.AlignTo(label, avatar, instructions.AnchorTop, 0, 4).Position()
+	require.Equal(t, 50, x)
+	require.Equal(t, 24, y)
+
+	// Inside a group both follow the group offset, also at a device scale.
+	g := instructions.NewGroup().SetPositionChain(10, 10)
+	g.AddInstructions(avatar, aligned)
+	l := instructions.NewLayerWithScale(140, 140, 2)
+	l.LoadInstruction(g)
+	c := l.Image().RGBAAt(2*110, 2*110)
+	require.Equal(t, uint8(255), c.R, "badge centered on the corner at (110, 110)")
+	require.NoError(t, l.Export("./output/align_to.png"))
+}
+
+func TestAlignToDrawBounds(t *testing.T) {
+	card := func(x, y float64, shadow bool) *instructions.Rectangle {
+		r := instructions.NewRectangle(x, y, 40, 30).
+			SetFillColor(colors.RoyalBlue).
+			SetStrokeColor(colors.Tomato).SetLineWidth(6).SetStrokePosition(instructions.StrokeOutside)
+		if shadow {
+			r.AddEffect(effects.NewDropShadow(8, 8, 4, 0, colors.Black, 0.8))
+		}
+		return r
+	}
+	target := instructions.NewRectangle(20, 20, 60, 60).SetLineWidth(0).SetFillColor(colors.White)
+
+	// The aligned card must render exactly like the same card placed by hand
+	// at the resolved position, outside stroke and shadow included.
+	for _, shadow := range []bool{false, true} {
+		byHand := newLayer(t, 160, 160)
+		byHand.LoadInstructions(target, card(80, 80, shadow))
+		aligned := newLayer(t, 160, 160)
+		aligned.LoadInstructions(target, instructions.AlignTo(card(0, 0, shadow), target, instructions.AnchorBottomRight, 0, 0).
+			SetSelfAnchor(instructions.AnchorTopLeft))
+		require.Equal(t, byHand.Image().Pix, aligned.Image().Pix, "shadow=%v", shadow)
+	}
+}
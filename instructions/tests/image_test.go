@@ -1,7 +1,9 @@
 package glimo_test
 
 import (
-	_ "image/png"
+	"image"
+	"image/color"
+	"image/draw"
 	"testing"
 
 	"github.com/Krispeckt/glimo/colors"
@@ -110,3 +112,137 @@ func TestInstructionImage(t *testing.T) {
 		})
 	}
 }
+
+func TestImageLinearResample(t *testing.T) {
+	// One-pixel black and white stripes average to 50% linear light,
+	// which encodes to ~188 in sRGB rather than the naive 128.
+	stripes := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			v := uint8(0)
+			if x%2 == 0 {
+				v = 255
+			}
+			stripes.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+
+	draw := func(linear bool) color.RGBA {
+		l := instructions.NewLayer(16, 16)
+		l.LoadInstruction(
+			instructions.NewImage(stripes, 0, 0).
+				SetSize(16, 16).
+				SetFit(instructions.FitStretch).
+				SetLinearResample(linear),
+		)
+		return l.Image().RGBAAt(8, 8)
+	}
+
+	require.InDelta(t, 128, draw(false).R, 6)
+	lin := draw(true)
+	require.InDelta(t, 188, lin.R, 6)
+	require.Equal(t, uint8(255), lin.A)
+}
+
+func TestImageMaskOptions(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 100, 40))
+	for i := range src.Pix {
+		src.Pix[i] = 255
+	}
+	alpha := func(cfg func(*instructions.Image)) func(x, y int) uint8 {
+		im := instructions.NewImage(src, 0, 0).
+			SetMaskFromShape(instructions.NewRectangle(20, 0, 60, 40).SetLineWidth(0).SetFillColor(colors.Black))
+		cfg(im)
+		l := instructions.NewLayer(100, 40)
+		l.LoadInstruction(im)
+		return func(x, y int) uint8 { return l.Image().RGBAAt(x, y).A }
+	}
+
+	plain := alpha(func(*instructions.Image) {})
+	require.Equal(t, uint8(255), plain(50, 20))
+	require.Zero(t, plain(10, 20))
+
+	// Inverting knocks the masked area out instead.
+	inverted := alpha(func(im *instructions.Image) { im.SetMaskOptions(true, 0) })
+	require.Zero(t, inverted(50, 20))
+	require.Equal(t, uint8(255), inverted(10, 20))
+
+	// Feathering softens the edges and keeps the middle.
+	soft := alpha(func(im *instructions.Image) { im.SetMaskOptions(false, 6) })
+	require.Equal(t, uint8(255), soft(50, 20))
+	require.InDelta(t, 127, float64(soft(20, 20)), 20)
+	require.Greater(t, soft(15, 20), uint8(0))
+	require.Less(t, soft(24, 20), uint8(255))
+
+	l := newLayer(t, 400, 300)
+	l.LoadInstructions(
+		instructions.NewRectangle(0, 0, 400, 300).SetLineWidth(0).SetFillColor(colors.Amethyst),
+		instructions.NewImage(mustLoadImage(t, "./testdata/image.png"), 0, 0).SetSize(400, 300).
+			SetMaskFromShape(instructions.NewCircle(50, 0, 150).SetLineWidth(0).SetFillColor(colors.Black)).
+			SetMaskOptions(true, 12),
+	)
+	require.NoError(t, l.Export("./output/image_mask_options.png"))
+}
+
+func TestImageMaskModes(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 30, 10))
+	for i := range src.Pix {
+		src.Pix[i] = 255
+	}
+	// An opaque grayscale mask: white, mid gray and black thirds.
+	mask := image.NewRGBA(image.Rect(0, 0, 30, 10))
+	for x, v := range []uint8{255, 128, 0} {
+		draw.Draw(mask, image.Rect(x*10, 0, x*10+10, 10), image.NewUniform(color.Gray{Y: v}), image.Point{}, draw.Src)
+	}
+	alphas := func(m instructions.MaskMode) [3]uint8 {
+		l := instructions.NewLayer(30, 10)
+		l.LoadInstruction(instructions.NewImage(src, 0, 0).SetMaskImage(mask).SetMaskMode(m))
+		return [3]uint8{l.Image().RGBAAt(5, 5).A, l.Image().RGBAAt(15, 5).A, l.Image().RGBAAt(25, 5).A}
+	}
+
+	require.Equal(t, [3]uint8{255, 255, 255}, alphas(instructions.MaskAlpha), "alpha ignores brightness")
+	lum := alphas(instructions.MaskLuminance)
+	require.Equal(t, uint8(255), lum[0])
+	require.InDelta(t, 128, float64(lum[1]), 1)
+	require.Zero(t, lum[2])
+	inv := alphas(instructions.MaskInverseLuminance)
+	require.Zero(t, inv[0])
+	require.InDelta(t, 127, float64(inv[1]), 1)
+	require.Equal(t, uint8(255), inv[2])
+}
+
+func TestSprite(t *testing.T) {
+	// A 4×2 sheet of 10×10 frames, frame i filled with red 10*(i+1).
+	sheet := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for i := 0; i < 8; i++ {
+		r := image.Rect(i%4*10, i/4*10, i%4*10+10, i/4*10+10)
+		draw.Draw(sheet, r, image.NewUniform(color.RGBA{R: uint8(10 * (i + 1)), A: 255}), image.Point{}, draw.Src)
+	}
+	red := func(s *instructions.Sprite) uint8 {
+		l := instructions.NewLayer(40, 40)
+		l.LoadInstruction(s)
+		return l.Image().RGBAAt(5, 5).R
+	}
+
+	s := instructions.NewSprite(sheet, 10, 10, 0, 0)
+	require.Equal(t, 8, s.Len())
+	require.Equal(t, uint8(10), red(s))
+	require.Equal(t, uint8(60), red(s.SetFrame(5)))
+	require.Equal(t, 10.0, s.Size().Width())
+
+	// Indices wrap, and the count can skip empty frames at the end.
+	require.Equal(t, 5, s.SetFrame(13).Frame())
+	require.Equal(t, 3, s.SetFrameCount(6).SetFrame(-3).Frame())
+	require.Equal(t, uint8(40), red(s))
+
+	// Frames are drawn like Images, with their size and fit.
+	s.Image().SetSize(20, 20).SetFit(instructions.FitStretch)
+	require.Equal(t, 20.0, s.Size().Width())
+	require.Equal(t, uint8(40), red(s))
+
+	l := newLayer(t, 400, 50)
+	for i := 0; i < 8; i++ {
+		l.LoadInstruction(instructions.NewSprite(sheet, 10, 10, i*50, 0).SetFrame(i))
+	}
+	require.Equal(t, uint8(80), l.Image().RGBAAt(355, 5).R)
+}
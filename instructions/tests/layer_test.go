@@ -0,0 +1,763 @@
+package glimo_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/effects"
+	"github.com/Krispeckt/glimo/instructions"
+	imageUtil "github.com/Krispeckt/glimo/internal/core/image"
+	"github.com/Krispeckt/glimo/internal/render"
+	"github.com/Krispeckt/glimo/patterns"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/draw"
+)
+
+func TestLayerTransforms(t *testing.T) {
+	src := mustLoadImage(t, "./testdata/image.png")
+	b := src.Bounds()
+
+	t.Run("resize", func(t *testing.T) {
+		l := instructions.NewLayerFromImage(src).
+			Resize(b.Dx()/2, b.Dy()/2, instructions.ResizeCatmullRom)
+		require.Equal(t, float64(b.Dx()/2), l.Size().Width())
+		require.Equal(t, float64(b.Dy()/2), l.Size().Height())
+		require.NoError(t, l.Export("./output/layer_resize.png"))
+	})
+
+	t.Run("crop", func(t *testing.T) {
+		l := instructions.NewLayerFromImage(src).Crop(image.Rect(10, 20, 110, 70))
+		require.Equal(t, image.Rect(0, 0, 100, 50), l.Image().Bounds())
+		require.NoError(t, l.Export("./output/layer_crop.png"))
+	})
+
+	t.Run("extend_canvas", func(t *testing.T) {
+		l := instructions.NewLayer(100, 50).ExtendCanvas(10, 20, 30, 40, colors.Red)
+		require.Equal(t, image.Rect(0, 0, 160, 90), l.Image().Bounds())
+		require.Equal(t, uint8(255), l.Image().RGBAAt(0, 0).R)
+		require.Equal(t, uint8(0), l.Image().RGBAAt(40, 10).A)
+		require.NoError(t, l.Export("./output/layer_extend_canvas.png"))
+	})
+}
+
+func TestLayerScale(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 32)
+
+	template := func() []instructions.Shape {
+		return []instructions.Shape{
+			instructions.NewRectangle(10, 10, 80, 40).SetFillColor(colors.Red).SetRadius(8),
+			instructions.NewCircle(120, 10, 20).SetFillColor(colors.Blue),
+			instructions.NewLine().SetLineWidth(2).MoveTo(10, 70).LineTo(190, 70).Stroke(),
+			instructions.NewText("glimo @2x", 10, 80, font).SetSolidColor(colors.Black),
+		}
+	}
+
+	for _, scale := range []float64{1, 2, 3} {
+		l := instructions.NewLayerWithScale(200, 130, scale)
+		l.LoadInstructions(template()...)
+
+		require.Equal(t, scale, l.Scale())
+		require.Equal(t, int(200*scale), l.Image().Bounds().Dx())
+		require.Equal(t, int(130*scale), l.Image().Bounds().Dy())
+
+		// The rectangle centre lands on the same logical spot at every scale.
+		c := l.Image().RGBAAt(int(50*scale), int(30*scale))
+		require.Equal(t, uint8(255), c.R)
+		require.Equal(t, uint8(0), c.B)
+
+		require.NoError(t, l.Export(fmt.Sprintf("./output/layer_scale_%gx.png", scale)))
+	}
+}
+
+func TestLayerBatchMatchesUnbatched(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 24)
+
+	shapes := func() []instructions.Shape {
+		out := []instructions.Shape{
+			instructions.NewRectangle(0, 0, 400, 300).SetFillColor(colors.MintCream),
+		}
+		for i := 0; i < 20; i++ {
+			x, y := float64(10+i*18), float64(10+(i%5)*50)
+			out = append(out,
+				instructions.NewCircle(x, y, 12).SetFillColor(colors.IndianRed),
+				instructions.NewRectangle(x+4, y+30, 10, 10).
+					SetFillPattern(colors.NewSolidWithBlend(colors.Aquamarine, colors.BlendMultiply, 0.7)),
+			)
+		}
+		out = append(out,
+			instructions.NewText("batched", 20, 250, font).SetSolidColor(colors.MediumPurple),
+			instructions.NewLine().SetLineWidth(3).MoveTo(0, 290).LineTo(400, 200).Stroke(),
+		)
+		return out
+	}
+
+	plain := instructions.NewLayer(400, 300)
+	plain.LoadInstructions(shapes()...)
+
+	batched := instructions.NewLayer(400, 300)
+	batched.LoadInstructionsFast(shapes()...)
+
+	require.Equal(t, plain.Image().Pix, batched.Image().Pix)
+	require.NoError(t, batched.Export("./output/layer_batch.png"))
+}
+
+func TestLayerDirtyRectMatchesFullCanvas(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 24)
+
+	shapes := func() []instructions.Shape {
+		group := instructions.NewGroup().SetPositionChain(200, 150).SetFrameSize(60, 40).SetClip(true)
+		group.AddInstructions(
+			instructions.NewRectangle(-10, -10, 60, 40).SetFillColor(colors.Pumpkin),
+			instructions.NewCircle(20, 10, 25).SetFillColor(colors.MidnightBlue),
+		)
+		return []instructions.Shape{
+			instructions.NewRectangle(0, 0, 300, 220).SetFillColor(colors.MintCream),
+			instructions.NewRectangle(30, 30, 80, 50).
+				SetFillPattern(colors.NewLinearGradient(0, 0, 300, 220).
+					AddColorStop(0, colors.Red).AddColorStop(1, colors.Blue)).
+				SetStrokeColor(colors.Black).SetLineWidth(6).
+				SetStrokePosition(instructions.StrokeOutside),
+			instructions.NewCircle(150, 20, 30).SetFillColor(colors.IndianRed).
+				SetStrokeColor(colors.Black).SetLineWidth(4).
+				SetStrokePosition(instructions.StrokeOutside),
+			instructions.NewText("dirty rect", 20, 150, font).
+				SetMaxWidth(260).SetAlign(instructions.AlignTextCenter).
+				SetSolidColor(colors.MediumPurple),
+			group,
+		}
+	}
+
+	bounded := instructions.NewLayer(300, 220)
+	bounded.LoadInstructions(shapes()...)
+
+	// Reference: every shape drawn into a full-canvas overlay.
+	full := instructions.NewLayer(300, 220)
+	for _, s := range shapes() {
+		overlay := image.NewRGBA(full.Image().Bounds())
+		s.Draw(full.Image(), overlay)
+		draw.Draw(full.Image(), overlay.Bounds(), overlay, image.Point{}, draw.Over)
+	}
+
+	require.Equal(t, full.Image().Pix, bounded.Image().Pix)
+	require.NoError(t, bounded.Export("./output/layer_dirty_rect.png"))
+}
+
+func TestLayerRerender(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 28)
+
+	type card struct {
+		bg     *instructions.Rectangle
+		title  *instructions.Text
+		count  *instructions.Text
+		marker *instructions.Circle
+	}
+	newCard := func(count string, markerX float64) card {
+		return card{
+			bg: instructions.NewRectangle(10, 10, 280, 180).SetRadius(16).
+				SetFillPattern(colors.NewLinearGradient(0, 0, 300, 200).
+					AddColorStop(0, colors.MintCream).AddColorStop(1, colors.Aquamarine)),
+			title:  instructions.NewText("Messages", 30, 30, font).SetSolidColor(colors.MidnightBlue),
+			count:  instructions.NewText(count, 30, 100, font).SetSolidColor(colors.IndianRed),
+			marker: instructions.NewCircle(markerX, 150, 10).SetFillColor(colors.Pumpkin),
+		}
+	}
+	shapes := func(c card) []instructions.Shape {
+		return []instructions.Shape{c.bg, c.title, c.count, c.marker}
+	}
+
+	live := newCard("0", 30)
+	l := instructions.NewLayer(300, 200)
+	l.Retain(shapes(live)...)
+
+	for i, count := range []string{"1", "42", "1000", "7"} {
+		markerX := float64(30 + i*50)
+		live.count.SetText(count)
+		live.marker.SetPosition(int(markerX), 150)
+		l.Invalidate(live.count).Invalidate(live.marker).Rerender()
+
+		fresh := instructions.NewLayer(300, 200)
+		fresh.LoadInstructions(shapes(newCard(count, markerX))...)
+		require.Equal(t, fresh.Image().Pix, l.Image().Pix, "count %q", count)
+	}
+	require.NoError(t, l.Export("./output/layer_rerender.png"))
+}
+
+func TestLayerRerenderAntialiased(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 20)
+	shapes := func(label string, markerX int) (*instructions.Text, *instructions.Circle, []instructions.Shape) {
+		text := instructions.NewText(label, 20, 20, font).SetSolidColor(colors.MidnightBlue)
+		marker := instructions.NewCircle(float64(markerX), 60, 9).SetFillColor(colors.Pumpkin)
+		bg := instructions.NewRectangle(5, 5, 190, 90).SetRadius(12).SetFillColor(colors.MintCream)
+		return text, marker, []instructions.Shape{bg, text, marker}
+	}
+
+	text, marker, retained := shapes("0", 20)
+	l := instructions.NewLayer(200, 100).SetAntialias(instructions.Antialias4x)
+	l.Retain(retained...)
+
+	for i, label := range []string{"1", "42", "7"} {
+		markerX := 20 + (i+1)*40
+		text.SetText(label)
+		marker.SetPosition(markerX, 60)
+		l.Invalidate(text).Invalidate(marker).Rerender()
+
+		fresh := instructions.NewLayer(200, 100).SetAntialias(instructions.Antialias4x)
+		_, _, want := shapes(label, markerX)
+		fresh.LoadInstructions(want...)
+		require.Equal(t, fresh.Image().Pix, l.Image().Pix, "label %q", label)
+	}
+}
+
+func TestDrawContextBlendsAgainstBase(t *testing.T) {
+	l := instructions.NewLayer(4, 4)
+	l.LoadInstruction(instructions.NewRectangle(0, 0, 4, 4).SetFillColor(colors.Red).SetLineWidth(0))
+
+	// Multiply white over red keeps red; a plain overlay write would not.
+	white := colors.White.SetBlendMode(colors.BlendMultiply)
+	l.LoadInstruction(instructions.ContextFunc(func(ctx *instructions.DrawContext) {
+		require.Equal(t, image.Rect(0, 0, 4, 4), ctx.Bounds())
+		ctx.Blend(1, 1, white, 1)
+		ctx.Blend(10, 10, white, 1) // outside, ignored
+	}))
+
+	c := l.Image().RGBAAt(1, 1)
+	require.Equal(t, uint8(255), c.R)
+	require.Zero(t, c.G)
+	require.Zero(t, c.B)
+}
+
+func TestDrawContextWithoutBase(t *testing.T) {
+	// BoundedBox passes no base on when drawn without one; the context
+	// treats it as transparent instead of panicking.
+	dot := instructions.ContextFunc(func(ctx *instructions.DrawContext) {
+		require.Nil(t, ctx.Base)
+		ctx.Blend(1, 1, colors.Red, 1)
+		mask := image.NewRGBA(image.Rect(0, 0, 1, 1))
+		mask.Pix[3] = 255
+		ctx.FillMask(mask, 2, 2, colors.Blue.MakeSolidPattern())
+	})
+	overlay := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	instructions.Bounded(dot, 4, 4).SetPositionChain(3, 3).Draw(nil, overlay)
+	require.Equal(t, color.RGBA{R: 255, A: 255}, overlay.RGBAAt(4, 4))
+	require.Equal(t, color.RGBA{B: 255, A: 255}, overlay.RGBAAt(5, 5))
+}
+
+func TestSnapshotBase(t *testing.T) {
+	// A custom shape inverting its backdrop sees the earlier sibling in its
+	// group through the base.
+	invert := instructions.ContextFunc(func(ctx *instructions.DrawContext) {
+		snap := ctx.SnapshotBase(ctx.Bounds())
+		b := snap.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := snap.RGBAAt(x, y)
+				ctx.Blend(x, y, colors.RGBA(255-c.R, 255-c.G, 255-c.B, 255), 1)
+			}
+		}
+	})
+	g := instructions.NewGroup()
+	g.AddInstructions(
+		instructions.NewRectangle(0, 0, 20, 20).SetFillColor(colors.Red).SetLineWidth(0),
+		instructions.Bounded(invert, 10, 10).SetPositionChain(5, 5),
+	)
+	l := instructions.NewLayer(20, 20)
+	l.LoadInstruction(g)
+	require.Equal(t, color.RGBA{G: 255, B: 255, A: 255}, l.Image().RGBAAt(8, 8))
+	require.Equal(t, color.RGBA{R: 255, A: 255}, l.Image().RGBAAt(2, 2))
+
+	// The snapshot is a copy, transparent outside the base.
+	base := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	base.Pix[3] = 255
+	snap := instructions.SnapshotBase(base, image.Rect(-2, -2, 2, 2))
+	require.Equal(t, image.Rect(-2, -2, 2, 2), snap.Bounds())
+	require.Equal(t, uint8(255), snap.RGBAAt(0, 0).A)
+	require.Zero(t, snap.RGBAAt(-1, -1).A)
+	snap.Pix[snap.PixOffset(0, 0)+3] = 0
+	require.Equal(t, uint8(255), base.Pix[3])
+	require.Zero(t, instructions.SnapshotBase(nil, image.Rect(0, 0, 1, 1)).RGBAAt(0, 0).A)
+}
+
+func TestEstimateCost(t *testing.T) {
+	rect := func() *instructions.Rectangle {
+		return instructions.NewRectangle(10, 10, 50, 50).SetFillColor(colors.Red)
+	}
+	empty := instructions.EstimateCost(400, 300, 1)
+	require.Equal(t, int64(400*300), empty.Pixels)
+	require.Equal(t, int64(400*300*4), empty.PeakBytes)
+
+	// Bounded shapes cost their bounds, not the canvas.
+	one := instructions.EstimateCost(400, 300, 1, rect())
+	require.Greater(t, one.PeakBytes, empty.PeakBytes)
+	require.Less(t, one.PeakBytes, 2*empty.PeakBytes)
+
+	// Shapes draw one at a time: the peak follows the largest, work adds up.
+	many := instructions.EstimateCost(400, 300, 1, rect(), rect(), rect())
+	require.Equal(t, one.PeakBytes, many.PeakBytes)
+	require.Equal(t, 3*one.Ops, many.Ops)
+
+	// Effects add their temporaries, and blur work grows with the radius.
+	blurred := instructions.EstimateCost(400, 300, 1, rect().AddEffect(effects.NewLayerBlurEffect(4)))
+	wider := instructions.EstimateCost(400, 300, 1, rect().AddEffect(effects.NewLayerBlurEffect(40)))
+	require.Greater(t, blurred.PeakBytes, one.PeakBytes)
+	require.Greater(t, wider.Ops, blurred.Ops)
+
+	// Device scale multiplies the canvas.
+	retina := instructions.EstimateCost(400, 300, 2)
+	require.Equal(t, 4*empty.Pixels, retina.Pixels)
+
+	// Fractional scales round up like NewLayerWithScale.
+	b := instructions.NewLayerWithScale(101, 101, 1.5).Image().Bounds()
+	require.Equal(t, int64(b.Dx()*b.Dy()), instructions.EstimateCost(101, 101, 1.5).Pixels)
+
+	// The estimate needs no canvas, even for absurd sizes.
+	huge := instructions.EstimateCost(20000, 20000, 1, rect().AddEffect(effects.NewLayerBlurEffect(20)))
+	require.Greater(t, huge.PeakBytes, int64(4<<30))
+
+	// Nested shapes are charged inside their container.
+	g := instructions.NewGroup()
+	g.AddInstruction(rect())
+	plain := instructions.EstimateCost(400, 300, 1, g)
+	g = instructions.NewGroup()
+	g.AddInstruction(rect().AddEffect(effects.NewLayerBlurEffect(40)))
+	nested := instructions.EstimateCost(400, 300, 1, g)
+	require.Greater(t, nested.PeakBytes, plain.PeakBytes)
+	require.Greater(t, nested.Ops, plain.Ops)
+
+	// Text is charged for its glyph masks, which grow with the font size.
+	text := func(pt float64) instructions.Shape {
+		return instructions.NewText("Hi", 10, 10, render.MustLoadFont("testdata/montserrat.ttf", pt))
+	}
+	small := instructions.EstimateCost(400, 300, 1, text(12))
+	large := instructions.EstimateCost(400, 300, 1, text(150))
+	require.Greater(t, large.PeakBytes, small.PeakBytes+16<<20)
+	require.Greater(t, large.Ops, small.Ops)
+}
+
+func TestLayerLimits(t *testing.T) {
+	lim := instructions.Limits{MaxWidth: 1000, MaxHeight: 800, MaxPixels: 500_000, MaxEffectRadius: 16, MaxInstructions: 3}
+
+	var limitErr *instructions.LimitError
+	_, err := instructions.NewLayerWithLimits(1200, 100, 1, lim)
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, "canvas width", limitErr.Limit)
+
+	// Dimensions are checked in device pixels.
+	_, err = instructions.NewLayerWithLimits(600, 500, 2, lim)
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, "canvas width", limitErr.Limit)
+
+	_, err = instructions.NewLayerWithLimits(900, 700, 1, lim)
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, "canvas pixels", limitErr.Limit)
+	require.Contains(t, err.Error(), "630000 exceeds the limit of 500000")
+
+	l, err := instructions.NewLayerWithLimits(400, 300, 1, lim)
+	require.NoError(t, err)
+	rect := func() *instructions.Rectangle {
+		return instructions.NewRectangle(10, 10, 50, 50).SetFillColor(colors.Red)
+	}
+
+	// A rejected batch leaves the Layer untouched.
+	err = l.LoadInstructionsChecked(rect(), rect().AddEffect(effects.NewLayerBlurEffect(40)))
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, "instruction 1 effect radius", limitErr.Limit)
+	require.Zero(t, l.Image().RGBAAt(20, 20).A)
+
+	require.NoError(t, l.LoadInstructionsChecked(rect(), rect().AddEffect(effects.NewLayerBlurEffect(8))))
+	require.NotZero(t, l.Image().RGBAAt(20, 20).A)
+
+	// Unchecked loads count towards the instruction limit.
+	l.LoadInstruction(rect())
+	err = l.LoadInstructionsChecked(rect())
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, "instruction count", limitErr.Limit)
+
+	// Zero limits cap nothing.
+	require.NoError(t, instructions.Limits{}.CheckCanvas(100000, 100000, 1))
+}
+
+func TestLimitsCheckNestedShapes(t *testing.T) {
+	lim := instructions.Limits{MaxEffectRadius: 16, MaxInstructions: 3}
+	rect := func() *instructions.Rectangle {
+		return instructions.NewRectangle(10, 10, 50, 50).SetFillColor(colors.Red)
+	}
+	var limitErr *instructions.LimitError
+
+	g := instructions.NewGroup()
+	g.AddInstructions(rect(), rect().AddEffect(effects.NewLayerBlurEffect(40)))
+	err := lim.CheckShapes(0, g)
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, "instruction 0 effect radius", limitErr.Limit)
+
+	// The group and each of its children count as instructions.
+	crowded := instructions.NewGroup()
+	crowded.AddInstructions(rect(), rect(), rect())
+	err = lim.CheckShapes(0, crowded)
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, "instruction count", limitErr.Limit)
+	require.Equal(t, float64(4), limitErr.Value)
+
+	text := instructions.NewText("Hi", 0, 0, render.MustLoadFont("testdata/montserrat.ttf", 2000))
+	g = instructions.NewGroup()
+	g.AddInstruction(text)
+	err = instructions.Limits{MaxFontSize: 500}.CheckShapes(0, g)
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, "instruction 0 font size", limitErr.Limit)
+	require.NoError(t, instructions.Limits{MaxFontSize: 2000}.CheckShapes(0, g))
+}
+
+func TestStructuredErrors(t *testing.T) {
+	_, err := instructions.NewLayerWithLimits(5000, 10, 1, instructions.Limits{MaxWidth: 4096})
+	require.ErrorIs(t, err, instructions.ErrCanvasTooLarge)
+	err = instructions.Limits{MaxInstructions: 1}.CheckShapes(1, instructions.NewRectangle(0, 0, 1, 1))
+	require.Error(t, err)
+	require.NotErrorIs(t, err, instructions.ErrCanvasTooLarge)
+
+	_, err = instructions.NewLayerFromReader(bytes.NewReader([]byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;")), instructions.DefaultDecodeLimits())
+	require.ErrorIs(t, err, imageUtil.ErrUnsupportedFormat)
+	_, err = instructions.NewLayerFromReader(bytes.NewReader([]byte("not an image")), instructions.DefaultDecodeLimits())
+	require.ErrorIs(t, err, imageUtil.ErrUnsupportedFormat)
+	require.ErrorIs(t, imageUtil.ExportAuto(image.NewRGBA(image.Rect(0, 0, 1, 1)), "out.bmp"), imageUtil.ErrUnsupportedFormat)
+	_, err = imageUtil.NamedImage("no such image")
+	require.ErrorIs(t, err, imageUtil.ErrImageNotRegistered)
+
+	font := render.MustLoadFont("testdata/montserrat.ttf", 16)
+	require.NoError(t, font.CheckGlyphs("Hello\n"))
+	err = font.CheckGlyphs("Hi 日本日")
+	require.ErrorIs(t, err, render.ErrFontGlyphMissing)
+	require.Contains(t, err.Error(), `"日本"`)
+	require.Empty(t, font.MissingRunes(), "checking does not report")
+	_, err = render.NamedFont("no such font", 12)
+	require.ErrorIs(t, err, render.ErrFontNotRegistered)
+}
+
+func TestExportORA(t *testing.T) {
+	shapes := []instructions.Shape{
+		instructions.NewRectangle(0, 0, 120, 80).SetLineWidth(0).SetFillColor(colors.White),
+		instructions.NewCircle(20, 10, 25).SetLineWidth(0).SetFillColor(colors.Red),
+		instructions.NewRectangle(60, 30, 40, 30).SetRadius(6).SetLineWidth(0).SetFillColor(colors.Blue),
+	}
+	layers := instructions.SplitLayers(120, 80, 1, shapes...)
+	require.Len(t, layers, 3)
+	require.Equal(t, "Circle 2", layers[1].Name)
+	require.True(t, layers[1].Image.Bounds().In(image.Rect(0, 0, 120, 80)))
+	require.Less(t, layers[1].Image.Bounds().Dx(), 120, "layers are cropped to their shape")
+	layers[2].Opacity = 0.5
+
+	var buf bytes.Buffer
+	require.NoError(t, instructions.EncodeORA(&buf, 120, 80, layers...))
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Equal(t, "mimetype", zr.File[0].Name)
+	require.Equal(t, zip.Store, zr.File[0].Method)
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	read := func(name string) []byte {
+		require.Contains(t, files, name)
+		rc, err := files[name].Open()
+		require.NoError(t, err)
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		return data
+	}
+
+	var stack struct {
+		W      int `xml:"w,attr"`
+		Layers []struct {
+			Name    string  `xml:"name,attr"`
+			Src     string  `xml:"src,attr"`
+			X       int     `xml:"x,attr"`
+			Opacity float64 `xml:"opacity,attr"`
+		} `xml:"stack>layer"`
+	}
+	require.NoError(t, xml.Unmarshal(read("stack.xml"), &stack))
+	require.Equal(t, 120, stack.W)
+	require.Len(t, stack.Layers, 3)
+	require.Equal(t, "Rectangle 3", stack.Layers[0].Name, "topmost layer first")
+	require.Equal(t, 0.5, stack.Layers[0].Opacity)
+	require.Equal(t, layers[2].Image.Bounds().Min.X, stack.Layers[0].X)
+
+	layer, err := png.Decode(bytes.NewReader(read(stack.Layers[1].Src)))
+	require.NoError(t, err)
+	require.Equal(t, layers[1].Image.Bounds().Size(), layer.Bounds().Size())
+
+	merged, err := png.Decode(bytes.NewReader(read("mergedimage.png")))
+	require.NoError(t, err)
+	r, g, b, _ := merged.At(80, 45).RGBA()
+	require.InDelta(t, 0xffff/2, r, 0x200)
+	require.InDelta(t, 0xffff/2, g, 0x200)
+	require.Equal(t, uint32(0xffff), b)
+	_, err = png.Decode(bytes.NewReader(read("Thumbnails/thumbnail.png")))
+	require.NoError(t, err)
+}
+
+func TestLayerVolatileTextFrames(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 28)
+	bg := instructions.NewRectangle(0, 0, 200, 80).SetLineWidth(0).
+		SetFillPattern(colors.NewLinearGradient(0, 0, 200, 80).
+			AddColorStop(0, colors.MidnightBlue).AddColorStop(1, colors.IndianRed))
+	label := instructions.NewText("T-", 20, 25, font).SetSolidColor(colors.White)
+	clock := instructions.NewText("10", 110, 25, font).SetSolidColor(colors.White).SetVolatile(true)
+	require.True(t, clock.Volatile())
+
+	l := instructions.NewLayer(200, 80)
+	l.Retain(bg, label, clock)
+	frames := l.RenderFrames(11, func(i int) { clock.SetText(fmt.Sprint(10 - i)) })
+	require.Len(t, frames, 11)
+	require.Equal(t, l.Image().Bounds(), frames[0].Rect)
+	for _, f := range frames[1:] {
+		require.False(t, f.Rect.Empty())
+		require.Less(t, f.Rect.Dx()*f.Rect.Dy(), 200*80/2, "only the clock is repainted")
+		require.Greater(t, f.Rect.Min.X, 60, "label untouched")
+	}
+
+	fresh := instructions.NewLayer(200, 80)
+	fresh.LoadInstructions(bg, label, instructions.NewText("0", 110, 25, font).SetSolidColor(colors.White))
+	// Gradient spans clipped to the repaint region may round differently.
+	for i, v := range fresh.Image().Pix {
+		require.InDelta(t, v, l.Image().Pix[i], 1, "byte %d", i)
+	}
+
+	// Without changes nothing is repainted.
+	clock.SetVolatile(false)
+	l.Rerender()
+	require.True(t, l.Repainted().Empty())
+
+	var buf bytes.Buffer
+	require.NoError(t, instructions.EncodeGIF(&buf, frames, time.Second/60))
+	anim, err := gif.DecodeAll(&buf)
+	require.NoError(t, err)
+	require.Len(t, anim.Image, 11)
+	require.Equal(t, 200, anim.Config.Width)
+	require.Equal(t, 2, anim.Delay[0])
+}
+
+func TestRender(t *testing.T) {
+	card := instructions.NewRectangle(10, 10, 80, 40).SetLineWidth(0).SetFillColor(colors.Red)
+	l, err := instructions.Render(100, 60, card)
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 100, 60), l.Image().Bounds())
+	require.Equal(t, uint8(255), l.Image().RGBAAt(50, 30).R)
+	require.Zero(t, l.Image().RGBAAt(5, 5).A)
+
+	_, err = instructions.Render(0, 60, card)
+	require.ErrorContains(t, err, "invalid size 0x60")
+	_, err = instructions.Render(100, 60, nil)
+	require.Error(t, err)
+
+	// Render is lenient: clamped values still draw.
+	_, err = instructions.Render(100, 60, instructions.NewCircle(0, 0, 5).SetSteps(2))
+	require.NoError(t, err)
+
+	require.NoError(t, instructions.RenderToPNG(100, 60, card, "./output/render.png"))
+	im := mustLoadImage(t, "./output/render.png")
+	require.Equal(t, image.Rect(0, 0, 100, 60), im.Bounds())
+}
+
+func TestLayerWithBackground(t *testing.T) {
+	l := instructions.NewLayerWithBackground(40, 20, colors.NewSolid(colors.Amethyst))
+	require.Equal(t, colors.Amethyst.ToColor(), l.Image().RGBAAt(0, 0))
+	require.Equal(t, colors.Amethyst.ToColor(), l.Image().RGBAAt(39, 19))
+
+	// Gradients match a canvas-sized Rectangle.
+	grad := func() patterns.Pattern {
+		return colors.NewLinearGradient(0, 0, 40, 0).AddColorStop(0, colors.Red).AddColorStop(1, colors.Blue)
+	}
+	want := instructions.NewLayer(40, 20)
+	want.LoadInstruction(instructions.NewRectangle(0, 0, 40, 20).SetLineWidth(0).SetFillPattern(grad()))
+	got := instructions.NewLayerWithBackground(40, 20, grad())
+	require.Equal(t, want.Image().Pix, got.Image().Pix)
+
+	// Translucent patterns composite over what the layer holds.
+	l.FillBackground(colors.NewSolidWithBlend(colors.White, patterns.BlendNormal, 0.5))
+	c := l.Image().RGBAAt(10, 10)
+	require.Equal(t, uint8(255), c.A)
+	require.Greater(t, c.R, colors.Amethyst.R)
+	require.Less(t, c.R, uint8(255))
+
+	// Scaled layers scale the pattern with the canvas.
+	hi := instructions.NewLayerWithScale(40, 20, 2).FillBackground(grad())
+	require.InDelta(t, got.Image().RGBAAt(20, 10).R, hi.Image().RGBAAt(40, 20).R, 1)
+	require.InDelta(t, got.Image().RGBAAt(20, 10).B, hi.Image().RGBAAt(40, 20).B, 1)
+	// The gradient is evaluated per device pixel, not upsampled in blocks.
+	require.NotEqual(t, hi.Image().RGBAAt(40, 20), hi.Image().RGBAAt(41, 20))
+	require.NoError(t, hi.ExportPNG("./output/layer_background.png", png.DefaultCompression))
+}
+
+func TestPremultipliedAlpha(t *testing.T) {
+	// valid reports whether every pixel is a valid premultiplied color.
+	valid := func(img *image.RGBA) bool {
+		for i := 0; i < len(img.Pix); i += 4 {
+			if max(img.Pix[i], img.Pix[i+1], img.Pix[i+2]) > img.Pix[i+3] {
+				return false
+			}
+		}
+		return true
+	}
+	straight := func(img *image.RGBA, x, y int) color.NRGBA {
+		return color.NRGBAModel.Convert(img.RGBAAt(x, y)).(color.NRGBA)
+	}
+
+	// Color is straight; RGBA premultiplies it.
+	half := colors.RGBA(255, 0, 0, 128)
+	r, _, _, a := half.RGBA()
+	require.Equal(t, uint32(0x8080), r)
+	require.Equal(t, uint32(0x8080), a)
+	require.Equal(t, half, patterns.NewColorFromStd(half.ToColor()))
+
+	// Translucent fills and their overlaps stay premultiplied.
+	l := instructions.NewLayer(60, 40)
+	l.LoadInstructions(
+		instructions.NewRectangle(0, 0, 40, 40).SetLineWidth(0).SetFillColor(half),
+		instructions.NewRectangle(20, 0, 40, 40).SetLineWidth(0).SetFillPattern(colors.NewSolid(colors.RGBA(0, 0, 255, 128))),
+		instructions.NewCircle(30, 20, 15).SetFillColor(colors.RGBA(0, 255, 0, 100)),
+	)
+	require.True(t, valid(l.Image()))
+	require.Equal(t, color.NRGBA{R: 255, A: 128}, straight(l.Image(), 5, 5))
+	c := straight(l.Image(), 30, 2)
+	require.InDelta(t, 192, int(c.A), 1, "two halves cover three quarters")
+	require.Greater(t, c.B, c.R, "blue on top")
+
+	// A gradient fading to transparent black does not darken on the way.
+	g := instructions.NewLayer(100, 10).FillBackground(
+		colors.NewLinearGradient(0, 0, 100, 0).AddColorStop(0, colors.Red).AddColorStop(1, colors.Transparent),
+	)
+	require.True(t, valid(g.Image()))
+	require.InDelta(t, 255, int(straight(g.Image(), 50, 5).R), 2)
+	require.InDelta(t, 128, int(g.Image().RGBAAt(50, 5).A), 3)
+
+	// Effects keep translucent pixels valid.
+	for _, e := range []effects.Effect{
+		effects.NewLayerBlurEffect(2).SetOpacity(0.5),
+		effects.NewInnerShadowEffect(2, 2, 2, colors.White),
+		effects.NewNoiseEffect(effects.NoiseMono, 1),
+		effects.NewTextureEffect(4, 1, colors.White),
+		effects.NewDropShadow(0, 0, 4, 0, colors.RGBA(0, 0, 255, 128), 1),
+	} {
+		img := image.NewRGBA(image.Rect(0, 0, 30, 30))
+		draw.Draw(img, image.Rect(8, 8, 22, 22), image.NewUniform(half.ToColor()), image.Point{}, draw.Src)
+		e.Apply(img)
+		require.True(t, valid(img), e.Name())
+	}
+
+	// A translucent shadow color is applied once, not darkened by its alpha.
+	img := image.NewRGBA(image.Rect(0, 0, 30, 30))
+	draw.Draw(img, image.Rect(8, 8, 22, 22), image.NewUniform(color.White), image.Point{}, draw.Src)
+	effects.NewDropShadow(10, 0, 0, 0, colors.RGBA(0, 0, 255, 128), 1).Apply(img)
+	require.Equal(t, color.NRGBA{B: 255, A: 128}, straight(img, 25, 15))
+}
+
+func TestLayerAutoHeight(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 20)
+	background := instructions.ContextFunc(func(ctx *instructions.DrawContext) {
+		b := ctx.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				ctx.Blend(x, y, colors.White, 1)
+			}
+		}
+	})
+	quote := func(s string) *instructions.Text {
+		return instructions.NewText(s, 20, 20, font).SetMaxWidth(260).SetSolidColor(colors.Black)
+	}
+
+	short := quote("Less is more.")
+	long := quote("Simplicity is the ultimate sophistication, and the details are not the details: they make the design.")
+	hShort := instructions.ContentHeight(20, short)
+	hLong := instructions.ContentHeight(20, long)
+	require.Equal(t, 20+int(math.Ceil(short.Size().Height()))+20, hShort)
+	require.Greater(t, hLong, hShort)
+
+	l := instructions.NewLayerAutoHeight(300, 1000, 20, 2, background, long)
+	require.Equal(t, image.Rect(0, 0, 600, 2*hLong), l.Image().Bounds())
+	require.Equal(t, uint8(255), l.Image().RGBAAt(599, 2*hLong-1).A, "background covers the whole canvas")
+	require.NoError(t, l.Export("./output/layer_auto_height.png"))
+
+	// The height is capped.
+	l = instructions.NewLayerAutoHeight(300, 50, 20, 1, long)
+	require.Equal(t, 50, l.Image().Bounds().Dy())
+}
+
+func TestPooledBuffersStartClean(t *testing.T) {
+	scene := func() *image.RGBA {
+		l := instructions.NewLayer(160, 120)
+		for i := 0; i < 20; i++ {
+			f := float64(i)
+			l.LoadInstructions(
+				instructions.NewRectangle(4*f, 3*f, 60, 40).SetRadius(8).
+					SetFillColor(colors.RGBA(uint8(12*i), 90, 200, 160)).
+					SetStrokeColor(colors.Black).SetLineWidth(3).SetStrokePosition(instructions.StrokeOutside),
+				instructions.NewCircle(100-3*f, 2*f, 12).SetFillColor(colors.Pumpkin),
+			)
+		}
+		return l.Image()
+	}
+
+	// Drawing reuses rasterizers, paths and scratch images; a repeated scene
+	// must not pick up anything left in them.
+	first := scene()
+	require.Equal(t, first.Pix, scene().Pix)
+
+	l := instructions.NewLayer(160, 120)
+	l.LoadInstruction(instructions.NewCircle(10, 10, 40))
+	require.Equal(t, make([]uint8, len(l.Image().Pix)), l.Image().Pix, "an invisible circle leaves the layer empty")
+}
+
+func TestLayerAntialias(t *testing.T) {
+	draw := func(a instructions.Antialias, shapes ...instructions.Shape) *image.RGBA {
+		l := instructions.NewLayer(120, 120).SetAntialias(a)
+		l.LoadInstructions(shapes...)
+		return l.Image()
+	}
+	ring := func() instructions.Shape {
+		return instructions.NewCircle(10, 10, 50).SetLineWidth(1).SetStrokeColor(colors.Black)
+	}
+	coverage := func(img *image.RGBA) (sum float64) {
+		for i := 3; i < len(img.Pix); i += 4 {
+			sum += float64(img.Pix[i])
+		}
+		return sum
+	}
+
+	// Pixel-aligned edges come out the same; curved ones keep their ink.
+	square := instructions.NewRectangle(20, 20, 40, 30).SetLineWidth(0).SetFillColor(colors.Pumpkin)
+	require.Equal(t, draw(instructions.AntialiasDefault, square).Pix, draw(instructions.Antialias4x, square).Pix)
+	plain, smooth := draw(instructions.AntialiasDefault, ring()), draw(instructions.Antialias4x, ring())
+	require.InEpsilon(t, coverage(plain), coverage(smooth), 0.1)
+	require.NotEqual(t, plain.Pix, smooth.Pix)
+
+	// Blend modes see the background at the higher resolution.
+	bg := instructions.NewRectangle(0, 0, 120, 120).SetLineWidth(0).SetFillColor(colors.RGB(200, 100, 50))
+	tint := instructions.NewRectangle(30, 30, 40, 40).SetLineWidth(0).
+		SetFillColor(colors.Gray).SetFillBlendMode(patterns.BlendMultiply)
+	want := draw(instructions.AntialiasDefault, bg, tint).RGBAAt(50, 50)
+	got := draw(instructions.Antialias2x, bg, tint).RGBAAt(50, 50)
+	require.Equal(t, want, got)
+
+	l := instructions.NewLayer(10, 10).SetAntialias(16)
+	require.Equal(t, instructions.Antialias4x, l.Antialias())
+	require.Equal(t, instructions.Antialias4x, l.Clone().Antialias())
+	require.Equal(t, instructions.AntialiasDefault, instructions.NewLayer(10, 10).Antialias())
+
+	out := instructions.NewLayer(240, 120).SetAntialias(instructions.Antialias4x)
+	out.LoadInstructions(
+		instructions.NewCircle(10, 10, 50).SetLineWidth(1).SetStrokeColor(colors.Black),
+		instructions.NewRectangle(130, 20, 100, 80).SetRadius(24).SetLineWidth(1).SetStrokeColor(colors.Black),
+	)
+	require.NoError(t, out.Export("./output/layer_antialias_4x.png"))
+}
@@ -1,10 +1,14 @@
 package glimo_test
 
 import (
+	"image"
+	"math"
 	"testing"
 
 	"github.com/Krispeckt/glimo/colors"
 	"github.com/Krispeckt/glimo/instructions"
+	"github.com/Krispeckt/glimo/internal/render"
+	"github.com/Krispeckt/glimo/patterns"
 	"github.com/stretchr/testify/require"
 )
 
@@ -173,3 +177,308 @@ func TestInstructionLine(t *testing.T) {
 		})
 	}
 }
+
+func TestLineMaskedSolidMatchesPatternPainter(t *testing.T) {
+	draw := func(fill patterns.Pattern) *image.RGBA {
+		ctx := instructions.NewLayer(128, 128)
+		ctx.LoadInstruction(
+			instructions.NewLine().
+				MoveTo(64, 8).LineTo(120, 64).LineTo(64, 120).LineTo(8, 64).ClosePath().
+				ClipPreserve().ClearPath().
+				SetFillPattern(fill).
+				MoveTo(20, 20).LineTo(108, 30).LineTo(90, 110).ClosePath().
+				Fill(),
+		)
+		return ctx.Image()
+	}
+
+	// A single-color gradient renders through PatternPainter; the solid fill
+	// takes the masked fast path. Both must agree up to rounding.
+	solid := draw(colors.NewSolid(colors.Pumpkin))
+	generic := draw(colors.NewLinearGradient(0, 0, 128, 0).
+		AddColorStop(0, colors.Pumpkin).AddColorStop(1, colors.Pumpkin))
+
+	// PatternPainter keeps straight color at edges, so color channels are only
+	// compared where coverage is full.
+	for i := 0; i < len(solid.Pix); i += 4 {
+		require.InDelta(t, generic.Pix[i+3], solid.Pix[i+3], 2, "alpha at byte %d", i)
+		if solid.Pix[i+3] == 255 {
+			for c := 0; c < 3; c++ {
+				require.InDelta(t, generic.Pix[i+c], solid.Pix[i+c], 2, "byte %d", i+c)
+			}
+		}
+	}
+	require.Zero(t, solid.RGBAAt(20, 20).A, "pixel outside the clip must stay empty")
+	require.NotZero(t, solid.RGBAAt(64, 64).A)
+}
+
+func TestAntiAliasToggle(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 24)
+
+	draw := func(aa bool) *image.RGBA {
+		ctx := instructions.NewLayer(200, 120)
+		ctx.LoadInstructions(
+			instructions.NewRectangle(10.3, 10.6, 50.5, 30.2).SetRadius(8).
+				SetFillColor(colors.IndianRed).SetAntiAlias(aa),
+			instructions.NewCircle(80.4, 8.7, 18.3).SetFillColor(colors.MidnightBlue).SetAntiAlias(aa),
+			instructions.NewLine().SetAntiAlias(aa).SetLineWidth(2.5).
+				SetStrokePattern(colors.NewSolid(colors.Pumpkin)).
+				MoveTo(10, 60).LineTo(190, 75).Stroke(),
+			instructions.NewText("pixel", 10, 80, font).SetSolidColor(colors.Black).SetAntiAlias(aa),
+		)
+		return ctx.Image()
+	}
+
+	partial := func(img *image.RGBA) int {
+		n := 0
+		for i := 3; i < len(img.Pix); i += 4 {
+			if a := img.Pix[i]; a != 0 && a != 255 {
+				n++
+			}
+		}
+		return n
+	}
+
+	require.NotZero(t, partial(draw(true)))
+	hard := draw(false)
+	require.Zero(t, partial(hard), "aliased rendering must not produce partial coverage")
+	require.NoError(t, instructions.NewLayerFromRGBA(hard).Export("./output/line_anti_alias_off.png"))
+}
+
+func TestLineStrokePosition(t *testing.T) {
+	square := func(pos instructions.StrokePosition) *image.RGBA {
+		l := instructions.NewLayer(60, 60)
+		l.LoadInstruction(instructions.NewLine().
+			SetStrokePosition(pos).
+			SetLineWidth(8).
+			SetLineJoin(instructions.LineJoinRound).
+			SetStrokePattern(colors.NewSolid(colors.Black)).
+			MoveTo(20, 20).LineTo(40, 20).LineTo(40, 40).LineTo(20, 40).ClosePath().
+			Stroke())
+		return l.Image()
+	}
+
+	inside, center, outside := square(instructions.StrokeInside), square(instructions.StrokeCenter), square(instructions.StrokeOutside)
+	for _, c := range []struct {
+		x, y          int
+		in, ctr, outs uint8
+	}{
+		{22, 30, 255, 255, 0}, // just inside the edge
+		{18, 30, 0, 255, 255}, // just outside the edge
+		{26, 30, 255, 0, 0},   // 6px inside
+		{14, 30, 0, 0, 255},   // 6px outside
+		{30, 30, 0, 0, 0},     // center of the square
+	} {
+		require.Equal(t, c.in, inside.RGBAAt(c.x, c.y).A, "inside (%d, %d)", c.x, c.y)
+		require.Equal(t, c.ctr, center.RGBAAt(c.x, c.y).A, "center (%d, %d)", c.x, c.y)
+		require.Equal(t, c.outs, outside.RGBAAt(c.x, c.y).A, "outside (%d, %d)", c.x, c.y)
+	}
+
+	// A rounded outside join keeps the corner round.
+	require.Zero(t, outside.RGBAAt(46, 46).A)
+	require.Equal(t, uint8(255), outside.RGBAAt(44, 44).A)
+
+	canvas := instructions.NewLayer(300, 110)
+	star := func(line *instructions.Line, cx float64) *instructions.Line {
+		for i := 0; i < 10; i++ {
+			r := 40.0
+			if i%2 == 1 {
+				r = 18
+			}
+			a := float64(i)*math.Pi/5 - math.Pi/2
+			x, y := cx+r*math.Cos(a), 55+r*math.Sin(a)
+			if i == 0 {
+				line.MoveTo(x, y)
+			} else {
+				line.LineTo(x, y)
+			}
+		}
+		return line.ClosePath()
+	}
+	for i, pos := range []instructions.StrokePosition{instructions.StrokeInside, instructions.StrokeCenter, instructions.StrokeOutside} {
+		cx := 55 + float64(i)*95
+		canvas.LoadInstruction(star(instructions.NewLine(), cx).
+			SetFillPattern(colors.NewSolid(colors.Gold)).FillPreserve().
+			SetStrokePosition(pos).SetLineWidth(6).SetLineJoin(instructions.LineJoinRound).
+			SetStrokePattern(colors.NewSolid(colors.RGBA(20, 20, 120, 200))).Stroke())
+	}
+	require.NoError(t, canvas.Export("./output/line_stroke_position.png"))
+}
+
+func TestLineArcs(t *testing.T) {
+	fill := func(build func(*instructions.Line)) *image.RGBA {
+		l := instructions.NewLayer(100, 100)
+		line := instructions.NewLine().SetFillPattern(colors.NewSolid(colors.Black))
+		build(line)
+		l.LoadInstruction(line.Fill())
+		return l.Image()
+	}
+	filled := func(img *image.RGBA, x, y int) bool { return img.RGBAAt(x, y).A > 128 }
+
+	// A quarter pie slice from the positive x axis clockwise to the positive
+	// y axis, which points down.
+	pie := fill(func(l *instructions.Line) {
+		l.MoveTo(50, 50).ArcTo(50, 50, 40, 0, math.Pi/2).ClosePath()
+	})
+	require.True(t, filled(pie, 70, 70))
+	require.False(t, filled(pie, 30, 70))
+	require.False(t, filled(pie, 70, 30))
+	require.False(t, filled(pie, 80, 80), "outside the radius")
+
+	// Anticlockwise when the end angle is smaller.
+	ccw := fill(func(l *instructions.Line) {
+		l.MoveTo(50, 50).ArcTo(50, 50, 40, 0, -math.Pi/2).ClosePath()
+	})
+	require.True(t, filled(ccw, 70, 30))
+	require.False(t, filled(ccw, 70, 70))
+
+	// SVG "A": sweep from the left point to the right one passes over the top.
+	top := fill(func(l *instructions.Line) {
+		l.MoveTo(10, 50).EllipticalArcTo(40, 40, 0, false, true, 90, 50).ClosePath()
+	})
+	require.True(t, filled(top, 50, 15))
+	require.False(t, filled(top, 50, 85))
+	bottom := fill(func(l *instructions.Line) {
+		l.MoveTo(10, 50).EllipticalArcTo(40, 40, 0, false, false, 90, 50).ClosePath()
+	})
+	require.True(t, filled(bottom, 50, 85))
+
+	// Radii too small are scaled up to reach the end point.
+	scaled := fill(func(l *instructions.Line) {
+		l.MoveTo(10, 50).EllipticalArcTo(1, 1, 0, false, true, 90, 50).ClosePath()
+	})
+	require.Equal(t, top.Pix, scaled.Pix)
+
+	// The large anticlockwise arc from the top to the right point of a circle
+	// runs through its left and bottom.
+	large := fill(func(l *instructions.Line) {
+		l.MoveTo(50, 10).EllipticalArcTo(40, 40, 0, true, false, 90, 50).LineTo(50, 50).ClosePath()
+	})
+	require.True(t, filled(large, 30, 50))
+	require.True(t, filled(large, 50, 80))
+	require.False(t, filled(large, 75, 25))
+
+	// A rotated ellipse: rx 40 along the diagonal.
+	tilted := fill(func(l *instructions.Line) {
+		l.MoveTo(50-28.28, 50-28.28).
+			EllipticalArcTo(40, 15, 45, false, true, 50+28.28, 50+28.28).
+			EllipticalArcTo(40, 15, 45, false, true, 50-28.28, 50-28.28).
+			ClosePath()
+	})
+	require.True(t, filled(tilted, 25, 25))
+	require.True(t, filled(tilted, 75, 75))
+	require.False(t, filled(tilted, 75, 25))
+
+	canvas := instructions.NewLayer(240, 100)
+	canvas.LoadInstructions(
+		instructions.NewLine().SetFillPattern(colors.NewSolid(colors.Tomato)).
+			MoveTo(50, 50).ArcTo(50, 50, 40, -math.Pi/2, math.Pi).ClosePath().Fill(),
+		instructions.NewLine().SetLineWidth(4).SetDashes([]float64{10, 6}).
+			SetStrokePattern(colors.NewSolid(colors.RoyalBlue)).
+			MoveTo(110, 80).EllipticalArcTo(50, 30, -20, true, true, 220, 60).Stroke(),
+	)
+	require.NoError(t, canvas.Export("./output/line_arcs.png"))
+}
+
+func TestPolyline(t *testing.T) {
+	zig := func() *instructions.Polyline {
+		return instructions.NewPolyline(
+			instructions.NewPoint(20, 30),
+			instructions.NewPoint(40, 10),
+			instructions.NewPoint(60, 30),
+		).SetLineWidth(4)
+	}
+
+	// The box spans the points plus half the stroke width.
+	p := zig()
+	x, y := p.Position()
+	require.Equal(t, 18, x)
+	require.Equal(t, 8, y)
+	require.Equal(t, 44.0, p.Size().Width())
+	require.Equal(t, 24.0, p.Size().Height())
+
+	// SetPosition moves the whole path.
+	p.SetPosition(100, 50)
+	x, y = p.Position()
+	require.Equal(t, 100, x)
+	require.Equal(t, 50, y)
+	l := instructions.NewLayer(160, 90)
+	l.LoadInstruction(p)
+	require.Greater(t, l.Image().RGBAAt(122, 52).A, uint8(128), "moved apex")
+	require.Zero(t, l.Image().RGBAAt(40, 10).A, "original apex")
+
+	// Inside a group, positions are relative to the group origin.
+	g := instructions.NewGroup().SetPositionChain(10, 10)
+	g.AddInstruction(zig())
+	l = instructions.NewLayer(160, 90)
+	l.LoadInstruction(g)
+	require.Greater(t, l.Image().RGBAAt(50, 22).A, uint8(128))
+
+	// Vertex colors blend from one end of the path to the other.
+	v := instructions.NewPolyline(
+		instructions.NewPoint(10, 20).SetColor(colors.Red),
+		instructions.NewPoint(90, 20).SetColor(colors.Blue),
+	).SetLineWidth(8).SetVertexColors(true)
+	l = instructions.NewLayer(100, 40)
+	l.LoadInstruction(v)
+	left, right := l.Image().RGBAAt(12, 20), l.Image().RGBAAt(88, 20)
+	require.Greater(t, left.R, left.B)
+	require.Greater(t, right.B, right.R)
+
+	// Dashes leave gaps along the path.
+	d := instructions.NewPolyline(instructions.NewPoint(0, 10), instructions.NewPoint(100, 10)).
+		SetLineWidth(4).SetDashes([]float64{10, 10})
+	l = instructions.NewLayer(100, 20)
+	l.LoadInstruction(d)
+	require.Greater(t, l.Image().RGBAAt(5, 10).A, uint8(128))
+	require.Zero(t, l.Image().RGBAAt(15, 10).A)
+
+	al := instructions.NewAutoLayout(10, 10, instructions.ContainerStyle{
+		Display:    instructions.DisplayFlex,
+		Direction:  instructions.Row,
+		Gap:        instructions.Vector2{X: 12},
+		AlignItems: instructions.AlignItemsCenter,
+	})
+	al.Add(zig().SetStrokeColor(colors.Tomato), instructions.ItemStyle{})
+	al.Add(zig().SetClosed(true).SetFillColor(colors.Gold).SetStrokeColor(colors.SeaGreen), instructions.ItemStyle{})
+	al.Add(instructions.NewPolyline(
+		instructions.NewPoint(0, 0).SetColor(colors.Tomato),
+		instructions.NewPoint(30, 30).SetColor(colors.Gold),
+		instructions.NewPoint(60, 0).SetColor(colors.RoyalBlue),
+	).SetLineWidth(6).SetLineCap(instructions.LineCapRound).SetVertexColors(true), instructions.ItemStyle{})
+	canvas := instructions.NewLayer(200, 60)
+	canvas.LoadInstruction(al)
+	require.NoError(t, canvas.Export("./output/polyline.png"))
+}
+
+func TestLineSnapshotAndReplay(t *testing.T) {
+	prepare := func() *instructions.Line {
+		return instructions.NewLine().
+			MoveTo(40, 4).LineTo(76, 40).LineTo(40, 76).LineTo(4, 40).ClosePath().
+			ClipPreserve().ClearPath().
+			SetFillPattern(colors.NewSolid(colors.Pumpkin)).
+			MoveTo(10, 10).LineTo(70, 20).LineTo(50, 70).ClosePath().
+			FillPreserve().
+			SetLineWidth(3).SetStrokePattern(colors.NewSolid(colors.RoyalBlue)).
+			Stroke()
+	}
+	paint := func(scale float64, sh instructions.Shape) *image.RGBA {
+		l := instructions.NewLayerWithScale(80, 80, scale)
+		l.LoadInstruction(sh)
+		return l.Image()
+	}
+	want := paint(1, prepare())
+
+	// Draw consumes the operations; Replay queues them again.
+	line := prepare()
+	require.Equal(t, want.Pix, paint(1, line).Pix)
+	require.Equal(t, instructions.NewLayer(80, 80).Image().Pix, paint(1, line).Pix)
+	require.Equal(t, want.Pix, paint(1, line.Replay()).Pix)
+	require.Equal(t, want.Pix, paint(1, line.Replay()).Pix, "clip is not narrowed twice")
+
+	// A snapshot draws the same content any number of times, at any scale.
+	snap := prepare().Snapshot()
+	require.Equal(t, want.Pix, paint(1, snap).Pix)
+	require.Equal(t, want.Pix, paint(1, snap).Pix)
+	require.Equal(t, paint(2, prepare()).Pix, paint(2, snap).Pix)
+}
@@ -0,0 +1,568 @@
+package glimo_test
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/instructions"
+	"github.com/Krispeckt/glimo/patterns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatternSpansMatchColorAt(t *testing.T) {
+	tex := image.NewRGBA(image.Rect(0, 0, 7, 5))
+	for i := range tex.Pix {
+		tex.Pix[i] = uint8(i * 37)
+	}
+	// Keep the pixels valid premultiplied colors.
+	for i := 0; i < len(tex.Pix); i += 4 {
+		for ch := 0; ch < 3; ch++ {
+			tex.Pix[i+ch] = min(tex.Pix[i+ch], tex.Pix[i+3])
+		}
+	}
+
+	cases := map[string]patterns.Pattern{
+		"solid":             colors.NewSolid(colors.Red),
+		"linear_horizontal": colors.NewLinearGradient(0, 0, 200, 0).AddColorStop(0, colors.Red).AddColorStop(1, colors.Blue),
+		"linear_vertical":   colors.NewLinearGradient(0, 0, 0, 200).AddColorStop(0, colors.Red).AddColorStop(1, colors.Blue),
+		"linear_diagonal": colors.NewLinearGradient(10, 20, 180, 150).
+			AddColorStop(0, colors.Red).AddColorStop(0.5, colors.Green).AddColorStop(1, colors.Blue),
+		"radial": colors.NewRadialGradient(100, 100, 10, 120, 90, 90).AddColorStop(0, colors.Amethyst).AddColorStop(1, colors.Pumpkin),
+		"linear_grain": colors.NewLinearGradient(0, 0, 200, 0).WithGrain(0.05, 7).
+			AddColorStop(0, colors.Red).AddColorStop(1, colors.Blue),
+		"linear_vertical_grain": colors.NewLinearGradient(0, 0, 0, 200).WithGrain(0.05, 7).
+			AddColorStop(0, colors.Red).AddColorStop(1, colors.Blue),
+		"radial_grain": colors.NewRadialGradient(100, 100, 10, 120, 90, 90).WithGrain(0.05, 7).
+			AddColorStop(0, colors.Amethyst).AddColorStop(1, colors.Pumpkin),
+		"surface_repeat": colors.NewSurface(tex, patterns.RepeatBoth),
+		"surface_none":   colors.NewSurface(tex, patterns.RepeatNone),
+		"masked": colors.NewSolid(colors.Red).WithAlphaMask(
+			colors.NewRadialGradient(100, 100, 0, 100, 100, 90).AddColorStop(0, colors.White).AddColorStop(1, colors.Black)),
+	}
+
+	for name, p := range cases {
+		t.Run(name, func(t *testing.T) {
+			dst := make([]patterns.Color, 200)
+			for _, y := range []int{0, 3, 57, 199} {
+				patterns.FillSpan(p, y, 0, len(dst), dst)
+				for x := range dst {
+					want := patterns.NewColorFromStd(p.ColorAt(x, y))
+					got := dst[x]
+					require.InDelta(t, want.R, got.R, 1, "x=%d y=%d", x, y)
+					require.InDelta(t, want.G, got.G, 1, "x=%d y=%d", x, y)
+					require.InDelta(t, want.B, got.B, 1, "x=%d y=%d", x, y)
+					require.InDelta(t, want.A, got.A, 1, "x=%d y=%d", x, y)
+				}
+			}
+		})
+	}
+}
+
+func TestGradientGrain(t *testing.T) {
+	row := func(p patterns.Pattern) []patterns.Color {
+		dst := make([]patterns.Color, 200)
+		patterns.FillSpan(p, 10, 0, len(dst), dst)
+		return dst
+	}
+	linear := func(amount float64, seed uint64) patterns.Pattern {
+		return colors.NewLinearGradient(0, 0, 200, 0).WithGrain(amount, seed).
+			AddColorStop(0, colors.Black).AddColorStop(1, colors.White)
+	}
+
+	plain := row(colors.NewLinearGradient(0, 0, 200, 0).AddColorStop(0, colors.Black).AddColorStop(1, colors.White))
+	require.Equal(t, plain, row(linear(0, 1)))
+	require.Equal(t, row(linear(0.05, 1)), row(linear(0.05, 1)))
+	require.NotEqual(t, row(linear(0.05, 1)), row(linear(0.05, 2)))
+
+	// The jitter stays within the requested amount of the gradient.
+	grainy := row(linear(0.05, 1))
+	for x := range grainy {
+		require.InDelta(t, plain[x].R, grainy[x].R, 0.05*255+1, "x=%d", x)
+	}
+
+	canvas := instructions.NewLayer(400, 200)
+	canvas.LoadInstruction(instructions.NewRectangle(0, 0, 400, 200).SetLineWidth(0).
+		SetFillPattern(colors.NewLinearGradient(0, 0, 400, 200).WithGrain(0.04, 1).
+			AddColorStop(0, colors.Amethyst).AddColorStop(1, colors.Pumpkin)))
+	require.NoError(t, canvas.Export("./output/pattern_gradient_grain.png"))
+}
+
+func TestLinearGradientAngle(t *testing.T) {
+	gray := func(p patterns.Pattern, x, y int) float64 {
+		return float64(patterns.NewColorFromStd(p.ColorAt(x, y)).R)
+	}
+	grad := func(w, h, deg float64) patterns.Pattern {
+		return colors.NewLinearGradientAngle(w/2, h/2, w, h, deg).
+			AddColorStop(0, colors.Black).AddColorStop(1, colors.White)
+	}
+
+	// 90° runs left to right, 180° top to bottom, 0° bottom to top.
+	require.InDelta(t, 0, gray(grad(200, 100, 90), 0, 50), 3)
+	require.InDelta(t, 255, gray(grad(200, 100, 90), 199, 50), 3)
+	require.InDelta(t, 0, gray(grad(200, 100, 180), 100, 0), 3)
+	require.InDelta(t, 255, gray(grad(200, 100, 180), 100, 99), 3)
+	require.InDelta(t, 255, gray(grad(200, 100, 0), 100, 0), 3)
+
+	// Corners reach the end stops: at 45° the bottom-left corner is the
+	// start and the top-right corner the end, even in a non-square box.
+	g := grad(300, 100, 45)
+	require.InDelta(t, 0, gray(g, 0, 99), 3)
+	require.InDelta(t, 255, gray(g, 299, 0), 3)
+	require.InDelta(t, 127, gray(g, 150, 50), 3)
+
+	canvas := instructions.NewLayer(300, 100)
+	canvas.LoadInstruction(instructions.NewRectangle(0, 0, 300, 100).SetLineWidth(0).
+		SetFillPattern(colors.NewLinearGradientAngle(150, 50, 300, 100, 45).
+			AddColorStop(0, colors.Amethyst).AddColorStop(1, colors.Pumpkin)))
+	require.NoError(t, canvas.Export("./output/pattern_gradient_angle.png"))
+}
+
+func TestGradientColorHint(t *testing.T) {
+	gray := func(p patterns.Pattern, x int) float64 {
+		return float64(patterns.NewColorFromStd(p.ColorAt(x, 0)).R)
+	}
+	plain := colors.NewLinearGradient(0, 0, 100, 0).AddColorStop(0, colors.Black).AddColorStop(1, colors.White)
+	hinted := colors.NewLinearGradient(0, 0, 100, 0).AddColorStopWithHint(1, colors.White, 0.25).AddColorStop(0, colors.Black)
+
+	// The halfway color moves from the middle to the hint.
+	require.InDelta(t, 127, gray(plain, 50), 1)
+	require.InDelta(t, 127, gray(hinted, 25), 1)
+	require.Greater(t, gray(hinted, 50), gray(plain, 50))
+	require.InDelta(t, 0, gray(hinted, 0), 1)
+	require.InDelta(t, 255, gray(hinted, 100), 1)
+
+	// Hints are positions on the gradient like offsets.
+	radial := colors.NewRadialGradient(0, 0, 0, 0, 0, 100).
+		AddColorStopWithHint(0.5, colors.White, 0.1).AddColorStop(0, colors.Black).AddColorStop(1, colors.Black)
+	require.InDelta(t, 127, gray(radial, 10), 4)
+
+	canvas := instructions.NewLayer(300, 60)
+	canvas.LoadInstructions(
+		instructions.NewRectangle(0, 0, 300, 30).SetLineWidth(0).SetFillPattern(
+			colors.NewLinearGradient(0, 0, 300, 0).AddColorStop(0, colors.Amethyst).AddColorStop(1, colors.Pumpkin)),
+		instructions.NewRectangle(0, 30, 300, 30).SetLineWidth(0).SetFillPattern(
+			colors.NewLinearGradient(0, 0, 300, 0).AddColorStopWithHint(1, colors.Pumpkin, 0.2).AddColorStop(0, colors.Amethyst)),
+	)
+	require.NoError(t, canvas.Export("./output/pattern_gradient_hint.png"))
+}
+
+func TestLerpGradient(t *testing.T) {
+	a := colors.NewLinearGradient(0, 0, 100, 0).
+		AddColorStop(0, colors.Red).
+		AddColorStop(1, colors.Blue)
+	b := colors.NewLinearGradient(0, 0, 200, 0).
+		AddColorStop(0, colors.Red).
+		AddColorStop(0.5, colors.White).
+		AddColorStop(1, colors.Blue)
+
+	// The ends reproduce the keyframes, even with different stop counts, up
+	// to the rounding of the stops added to match them.
+	for _, tc := range []struct {
+		g    patterns.GradientPattern
+		frac float64
+	}{{a, 0}, {b, 1}} {
+		got := colors.LerpGradient(a, b, tc.frac)
+		for _, x := range []int{0, 30, 60, 99, 150} {
+			want, have := patterns.NewColorFromStd(tc.g.ColorAt(x, 0)), patterns.NewColorFromStd(got.ColorAt(x, 0))
+			for i, ch := range [][2]uint8{{want.R, have.R}, {want.G, have.G}, {want.B, have.B}, {want.A, have.A}} {
+				require.InDelta(t, ch[0], ch[1], 1, "t=%v x=%d channel %d", tc.frac, x, i)
+			}
+		}
+	}
+
+	// Halfway, the end point and the middle stop have moved halfway.
+	mid := colors.LerpGradient(a, b, 0.5).(*patterns.LinearGradient)
+	stops := mid.ColorStops()
+	require.Len(t, stops, 3)
+	require.InDelta(t, 0.5, stops[1].Offset, 1e-9)
+	require.Equal(t, colors.Red, patterns.NewColorFromStd(mid.ColorAt(0, 0)))
+	require.Equal(t, colors.Blue, patterns.NewColorFromStd(mid.ColorAt(150, 0)))
+	require.Greater(t, patterns.NewColorFromStd(mid.ColorAt(75, 0)).G, uint8(0), "partly white in the middle")
+
+	// Conic rotation takes the shorter way across 0°.
+	c0 := colors.NewConicGradient(50, 50, 350).AddColorStop(0, colors.Red).AddColorStop(1, colors.Blue)
+	c1 := colors.NewConicGradient(50, 50, 10).AddColorStop(0, colors.Red).AddColorStop(1, colors.Blue)
+	require.InDelta(t, 0, colors.LerpGradient(c0, c1, 0.5).(*patterns.ConicGradient).Rotation(), 1e-9)
+
+	// Gradients of different kinds switch halfway.
+	r := colors.NewRadialGradient(0, 0, 0, 0, 0, 50).AddColorStop(0, colors.Red)
+	require.Same(t, a, colors.LerpGradient(a, r, 0.4))
+	require.Same(t, r, colors.LerpGradient(a, r, 0.6))
+
+	// Stop lists pair up or merge.
+	ls := colors.LerpColorStops(
+		[]colors.ColorStop{{Offset: 0, Color: colors.Black}, {Offset: 1, Color: colors.Black}},
+		[]colors.ColorStop{{Offset: 0.2, Color: colors.White}, {Offset: 0.8, Color: colors.White}},
+		1,
+	)
+	require.Equal(t, []colors.ColorStop{{Offset: 0.2, Color: colors.White}, {Offset: 0.8, Color: colors.White}}, ls)
+	require.Len(t, colors.LerpColorStops(a.(*patterns.LinearGradient).ColorStops(), b.(*patterns.LinearGradient).ColorStops(), 0.3), 3)
+}
+
+func TestConicGradientSeamAndGap(t *testing.T) {
+	rgba := func(p patterns.Pattern, x, y int) patterns.Color {
+		return patterns.NewColorFromStd(p.ColorAt(x, y))
+	}
+	conic := func() *patterns.ConicGradient {
+		g := colors.NewConicGradient(50, 50, 0)
+		g.AddColorStop(0, colors.Black).AddColorStop(1, colors.White)
+		return g
+	}
+
+	// The seam runs left from the center; pixels on it blend both ends
+	// instead of snapping to one of them.
+	g := conic()
+	require.InDelta(t, 127, rgba(g, 20, 50).R, 2)
+	require.Less(t, rgba(g, 20, 47).R, uint8(10))
+	require.Greater(t, rgba(g, 20, 53).R, uint8(245))
+
+	// A 90° gap leaves the quadrant before the seam empty and squeezes the
+	// stops into the remaining 270°.
+	gap := conic().WithGap(90)
+	require.Equal(t, 90.0, gap.Gap())
+	require.Zero(t, rgba(gap, 30, 70).A)
+	require.Equal(t, uint8(255), rgba(gap, 70, 30).A)
+	require.InDelta(t, 127, rgba(gap, 70, 30).R, 2)
+	require.InDelta(t, 127, rgba(gap, 20, 50).A, 2, "arc start is anti-aliased")
+	require.InDelta(t, 127, rgba(gap, 50, 80).A, 2, "arc end is anti-aliased")
+
+	canvas := instructions.NewLayer(120, 120)
+	canvas.LoadInstruction(instructions.NewCircle(10, 10, 50).SetLineWidth(12).
+		SetStrokePattern(colors.NewConicGradient(60, 60, 0).WithGap(60).
+			AddColorStop(0, colors.Amethyst).AddColorStop(1, colors.Pumpkin)))
+	require.NoError(t, canvas.Export("./output/pattern_conic_gap.png"))
+}
+
+func TestConicGradientSmoothSeam(t *testing.T) {
+	gray := func(p patterns.Pattern, x, y int) float64 {
+		return float64(patterns.NewColorFromStd(p.ColorAt(x, y)).R)
+	}
+
+	// A closed seam returns to the first color, as if it were repeated at 1.
+	closed := colors.NewConicGradient(50, 50, 0).WithClosedSeam(true)
+	closed.AddColorStop(0, colors.Black).AddColorStop(0.5, colors.White)
+	require.InDelta(t, 255, gray(closed, 80, 50), 2)
+	require.InDelta(t, 127, gray(closed, 50, 80), 2)
+	require.Less(t, gray(closed, 20, 53), 20.0)
+	require.Less(t, gray(closed, 20, 47), 20.0)
+
+	// A seam blend cross-fades both sides of the seam instead.
+	blend := colors.NewConicGradient(50, 50, 0).WithSeamBlend(90)
+	blend.AddColorStop(0, colors.Black).AddColorStop(1, colors.White)
+	require.InDelta(t, 127, gray(blend, 20, 50), 2)
+	require.InDelta(t, 127, gray(blend, 20, 47), 20)
+	require.InDelta(t, 127, gray(blend, 20, 53), 20)
+	require.InDelta(t, 127, gray(blend, 80, 50), 2, "the rest of the sweep is unchanged")
+
+	canvas := instructions.NewLayer(240, 120)
+	spinner := func(x float64, g *patterns.ConicGradient) *instructions.Circle {
+		g.AddColorStop(0, colors.Amethyst).AddColorStop(0.5, colors.Pumpkin)
+		return instructions.NewCircle(x, 10, 50).SetLineWidth(12).SetStrokePattern(g)
+	}
+	canvas.LoadInstructions(
+		spinner(10, colors.NewConicGradient(60, 60, 0).WithClosedSeam(true)),
+		spinner(130, colors.NewConicGradient(180, 60, 0).WithSeamBlend(60)),
+	)
+	require.NoError(t, canvas.Export("./output/pattern_conic_seam.png"))
+}
+
+func TestConicGradientDegreeStops(t *testing.T) {
+	rgba := func(p patterns.Pattern, x, y int) patterns.Color {
+		return patterns.NewColorFromStd(p.ColorAt(x, y))
+	}
+	pie := func() *patterns.ConicGradient {
+		return colors.NewConicGradient(50, 50, 0).
+			AddColorStopDeg(0, colors.Red).AddColorStopDeg(120, colors.Red).
+			AddColorStopDeg(120, colors.Blue).AddColorStopDeg(240, colors.Blue).
+			AddColorStopDeg(240, colors.Green).AddColorStopDeg(360, colors.Green)
+	}
+
+	// The sweep starts at the seam on the left and runs clockwise.
+	g := pie()
+	require.Equal(t, colors.Red, rgba(g, 50, 20), "north is at 90°")
+	require.Equal(t, colors.Blue, rgba(g, 80, 50), "east is at 180°")
+	require.Equal(t, colors.Green, rgba(g, 50, 80), "south is at 270°")
+
+	// Hard stops are anti-aliased over about one pixel.
+	var blended int
+	for x := 60; x <= 70; x++ {
+		if c := rgba(g, x, 24); c.R > 20 && c.B > 20 {
+			blended++
+		}
+	}
+	require.Equal(t, 1, blended)
+
+	ccw := pie().WithAnticlockwise(true)
+	require.True(t, ccw.Anticlockwise())
+	require.Equal(t, colors.Red, rgba(ccw, 50, 80))
+	require.Equal(t, colors.Blue, rgba(ccw, 80, 50))
+	require.Equal(t, colors.Green, rgba(ccw, 50, 20))
+
+	canvas := instructions.NewLayer(120, 120)
+	canvas.LoadInstruction(instructions.NewCircle(10, 10, 50).SetLineWidth(0).
+		SetFillPattern(colors.NewConicGradient(60, 60, 90).
+			AddColorStopDeg(0, colors.Amethyst).AddColorStopDeg(200, colors.Amethyst).
+			AddColorStopDeg(200, colors.Pumpkin).AddColorStopDeg(290, colors.Pumpkin).
+			AddColorStopDeg(290, colors.SeaGreen).AddColorStopDeg(360, colors.SeaGreen)))
+	require.NoError(t, canvas.Export("./output/pattern_conic_pie.png"))
+}
+
+func TestPatternAlphaMask(t *testing.T) {
+	mask := colors.NewLinearGradient(0, 0, 200, 0).AddColorStop(0, colors.Black).AddColorStop(1, colors.White)
+	masked := colors.NewSolid(colors.Red).WithAlphaMask(mask)
+	at := func(p patterns.Pattern, x int) patterns.Color { return patterns.NewColorFromStd(p.ColorAt(x, 0)) }
+
+	require.Zero(t, at(masked, 0).A, "black hides")
+	require.InDelta(t, 255, at(masked, 200).A, 1, "white keeps")
+	require.InDelta(t, 127, at(masked, 100).A, 2)
+	require.Equal(t, uint8(255), at(masked, 100).R, "color is untouched")
+
+	// The mask's alpha counts too, and masks stack multiplicatively.
+	half := colors.NewSolid(colors.RGBA(255, 255, 255, 128))
+	require.InDelta(t, 128, at(colors.NewSolid(colors.Red).WithAlphaMask(half), 50).A, 1)
+	require.InDelta(t, 64, at(colors.WithAlphaMask(colors.NewSolid(colors.Red).WithAlphaMask(half), half), 50).A, 1)
+
+	// Blend mode and opacity of the masked pattern are preserved.
+	bp := colors.NewSolidWithBlend(colors.Red, colors.BlendMultiply, 0.5).WithAlphaMask(mask).(patterns.BlendedPattern)
+	require.Equal(t, colors.BlendMultiply, bp.BlendMode())
+	require.Equal(t, 0.5, bp.Opacity())
+
+	canvas := instructions.NewLayer(300, 120)
+	canvas.LoadInstruction(instructions.NewRectangle(0, 0, 300, 120).SetLineWidth(0).
+		SetFillPattern(colors.WithAlphaMask(
+			colors.NewLinearGradient(0, 0, 300, 0).AddColorStop(0, colors.Amethyst).AddColorStop(1, colors.Pumpkin),
+			colors.NewRadialGradient(150, 60, 0, 150, 60, 150).AddColorStop(0, colors.White).AddColorStop(1, colors.Black))))
+	require.NoError(t, canvas.Export("./output/pattern_alpha_mask.png"))
+}
+
+func TestFuncPattern(t *testing.T) {
+	checker := colors.NewFuncPattern(func(x, y int) patterns.Color {
+		if (x/8+y/8)%2 == 0 {
+			return colors.White
+		}
+		return colors.Black
+	})
+	require.Equal(t, colors.White, patterns.NewColorFromStd(checker.ColorAt(3, 3)))
+	require.Equal(t, colors.Black, patterns.NewColorFromStd(checker.ColorAt(11, 3)))
+
+	span := make([]patterns.Color, 20)
+	patterns.FillSpan(checker, 5, 0, 20, span)
+	for x, c := range span {
+		require.Equal(t, patterns.NewColorFromStd(checker.ColorAt(x, 5)), c)
+	}
+
+	// Procedural fills follow the device scale like other patterns.
+	draw := func(scale float64) *image.RGBA {
+		l := instructions.NewLayerWithScale(32, 32, scale)
+		l.LoadInstruction(instructions.NewRectangle(0, 0, 32, 32).SetLineWidth(0).SetFillPattern(checker))
+		return l.Image()
+	}
+	one, two := draw(1), draw(2)
+	require.Equal(t, one.RGBAAt(10, 3), two.RGBAAt(20, 6))
+
+	// Opacity applies as for built-in patterns, and a nil callback draws
+	// nothing.
+	faded := instructions.NewLayer(16, 16)
+	faded.LoadInstruction(instructions.NewRectangle(0, 0, 16, 16).SetLineWidth(0).
+		SetFillPattern(colors.NewFuncPatternWithBlend(func(x, y int) patterns.Color { return colors.Red }, patterns.BlendNormal, 0.5)))
+	require.InDelta(t, 127, faded.Image().RGBAAt(8, 8).A, 2)
+	empty := instructions.NewLayer(16, 16)
+	empty.LoadInstruction(instructions.NewRectangle(0, 0, 16, 16).SetLineWidth(0).SetFillPattern(colors.NewFuncPattern(nil)))
+	require.Zero(t, empty.Image().RGBAAt(8, 8).A)
+
+	canvas := instructions.NewLayer(120, 120)
+	canvas.LoadInstruction(instructions.NewCircle(10, 10, 50).SetLineWidth(0).
+		SetFillPattern(colors.NewFuncPattern(func(x, y int) patterns.Color {
+			d := math.Hypot(float64(x-60), float64(y-60))
+			v := uint8(127 + 127*math.Sin(d/4))
+			return patterns.Color{R: v, G: 80, B: 255 - v, A: 255}
+		})))
+	require.NoError(t, canvas.Export("./output/pattern_func.png"))
+}
+
+// checker is a third-party pattern: alternating 4px cells of two colors,
+// multiplied over what lies beneath.
+type checker struct{ a, b patterns.Color }
+
+func (c checker) ColorAt(x, y int) color.Color {
+	if (x/4+y/4)%2 == 0 {
+		return c.a
+	}
+	return c.b
+}
+
+func (checker) BlendMode() patterns.BlendMode { return patterns.BlendMultiply }
+func (checker) Opacity() float64              { return 1 }
+
+func TestCustomPattern(t *testing.T) {
+	var p patterns.BlendedPattern = checker{colors.Red, colors.Blue}
+
+	l := newLayer(t, 16, 16)
+	l.LoadInstruction(instructions.NewRectangle(0, 0, 16, 16).SetFillColor(colors.White))
+	l.LoadInstruction(instructions.NewRectangle(0, 0, 16, 16).SetFillPattern(p))
+	require.Equal(t, color.RGBA{255, 0, 0, 255}, l.Image().RGBAAt(1, 1))
+	require.Equal(t, color.RGBA{0, 0, 255, 255}, l.Image().RGBAAt(5, 1))
+}
+
+func TestSurfacePlacement(t *testing.T) {
+	tex := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	tex.Set(0, 0, colors.Red)
+	tex.Set(1, 0, colors.Green)
+	tex.Set(0, 1, colors.Blue)
+	tex.Set(1, 1, colors.White)
+	at := func(p patterns.Pattern, x, y int) patterns.Color {
+		return patterns.NewColorFromStd(p.ColorAt(x, y))
+	}
+
+	s := colors.NewSurface(tex, patterns.RepeatBoth)
+	require.Equal(t, colors.Red.R, at(s, 2, 0).R)
+	require.Equal(t, colors.Green.G, at(s, -1, 0).G, "negative coordinates wrap")
+
+	s.SetOffset(10, 10)
+	require.Equal(t, colors.Red.R, at(s, 10, 10).R)
+	require.Equal(t, colors.Green.G, at(s, 11, 10).G)
+	s.SetScale(3, 3)
+	require.Equal(t, colors.Red.R, at(s, 12, 12).R)
+	require.Equal(t, colors.Blue.B, at(s, 12, 13).B)
+
+	// Tiles of 8×4 aligned to a shape corner at (5, 5).
+	tiled := colors.NewSurface(tex, patterns.RepeatBoth).WithOffset(5, 5).WithScale(4, 2)
+	require.Equal(t, colors.Red.R, at(tiled, 8, 6).R)
+	require.Equal(t, colors.Green.G, at(tiled, 9, 6).G)
+	require.Equal(t, colors.Blue.B, at(tiled, 5, 7).B)
+	require.Equal(t, colors.Red.R, at(tiled, 13, 9).R, "next tile")
+
+	clamped := colors.NewSurface(tex, patterns.RepeatNone).SetOffset(10, 10)
+	require.Zero(t, at(clamped, 9, 10).A)
+	require.Zero(t, at(clamped, 12, 10).A)
+
+	// Cover a 10×4 box: scaled 5× and centered, the texture's rows are
+	// cropped and its columns span the box.
+	cover := colors.NewSurface(tex, patterns.RepeatNone).SetCover(0, 0, 10, 4)
+	require.Equal(t, colors.Red.R, at(cover, 0, 0).R)
+	require.Equal(t, colors.Green.G, at(cover, 9, 0).G)
+	require.Equal(t, colors.Blue.B, at(cover, 0, 3).B)
+	contain := colors.NewSurface(tex, patterns.RepeatNone).SetContain(0, 0, 10, 4)
+	require.Zero(t, at(contain, 0, 0).A)
+	require.Equal(t, colors.Red.R, at(contain, 3, 0).R)
+
+	// Spans match ColorAt under any placement.
+	for _, p := range []*patterns.Surface{s, clamped, cover, colors.NewSurface(tex, patterns.RepeatX).SetOffset(-3.5, 1).SetAnchor(0.5, 0)} {
+		for y := -2; y < 16; y++ {
+			span := make([]patterns.Color, 30)
+			patterns.FillSpan(p, y, -5, 25, span)
+			for i, c := range span {
+				require.Equal(t, at(p, i-5, y), c, "(%d, %d)", i-5, y)
+			}
+		}
+	}
+
+	photo := mustLoadImage(t, "./testdata/image.png")
+	canvas := instructions.NewLayer(320, 160)
+	canvas.LoadInstruction(instructions.NewRectangle(10, 10, 140, 140).SetRadius(16).SetLineWidth(0).
+		SetFillPattern(colors.NewSurface(photo, patterns.RepeatNone).SetCover(10, 10, 140, 140)))
+	canvas.LoadInstruction(instructions.NewRectangle(170, 10, 140, 140).SetLineWidth(0).
+		SetFillPattern(colors.NewSurface(photo, patterns.RepeatBoth).SetOffset(170, 10).SetScale(0.25, 0.25)))
+	require.NoError(t, canvas.Export("./output/pattern_surface_placement.png"))
+}
+
+func TestSurfaceFiltering(t *testing.T) {
+	at := func(p patterns.Pattern, x, y int) patterns.Color {
+		return patterns.NewColorFromStd(p.ColorAt(x, y))
+	}
+
+	// Magnified 4×, bilinear sampling blends neighbouring texels.
+	pair := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	pair.Set(0, 0, colors.Red)
+	pair.Set(1, 0, colors.Blue)
+	nearest := colors.NewSurface(pair, patterns.RepeatNone).SetScale(4, 4)
+	smooth := colors.NewSurface(pair, patterns.RepeatNone).SetScale(4, 4).SetFilter(patterns.FilterBilinear)
+	require.Zero(t, at(nearest, 3, 1).B)
+	c := at(smooth, 3, 1)
+	require.Greater(t, c.R, c.B)
+	require.NotZero(t, c.B)
+	require.Equal(t, colors.Red.R, at(smooth, 1, 1).R, "texel centers keep their color")
+
+	// Minified 4×, a one-texel checkerboard aliases with nearest sampling and
+	// averages to gray with mipmaps.
+	checker := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			if (x+y)%2 == 0 {
+				checker.Set(x, y, colors.White)
+			} else {
+				checker.Set(x, y, colors.Black)
+			}
+		}
+	}
+	aliased := colors.NewSurface(checker, patterns.RepeatBoth).SetScale(0.25, 0.25)
+	mip := colors.NewSurface(checker, patterns.RepeatBoth).SetScale(0.25, 0.25).
+		SetFilter(patterns.FilterBilinear).SetMipmaps(true)
+	for x := 0; x < 8; x++ {
+		g := at(aliased, x, 3).R
+		require.True(t, g == 0 || g == 255)
+		require.InDelta(t, 128, int(at(mip, x, 3).R), 2)
+	}
+
+	// Spans match ColorAt for filtered surfaces too.
+	for _, p := range []*patterns.Surface{smooth, mip} {
+		span := make([]patterns.Color, 20)
+		patterns.FillSpan(p, 2, -4, 16, span)
+		for i, c := range span {
+			require.Equal(t, at(p, i-4, 2), c)
+		}
+	}
+
+	photo := mustLoadImage(t, "./testdata/image.png")
+	canvas := instructions.NewLayerWithScale(320, 160, 2)
+	canvas.LoadInstruction(instructions.NewRectangle(10, 10, 140, 140).SetLineWidth(0).
+		SetFillPattern(colors.NewSurface(photo, patterns.RepeatBoth).SetOffset(10, 10).SetScale(0.1, 0.1)))
+	canvas.LoadInstruction(instructions.NewRectangle(170, 10, 140, 140).SetLineWidth(0).
+		SetFillPattern(colors.NewSurface(photo, patterns.RepeatBoth).SetOffset(170, 10).SetScale(0.1, 0.1).
+			SetFilter(patterns.FilterBilinear).SetMipmaps(true)))
+	require.NoError(t, canvas.Export("./output/pattern_surface_filtering.png"))
+}
+
+func TestSampledSpansTolerance(t *testing.T) {
+	// Radial and conic spans interpolate between sparse samples; seams, gap
+	// ends and hard stops must still come out as ColorAt draws them.
+	cases := map[string]patterns.Pattern{
+		"conic": colors.NewConicGradient(100, 90, 30).
+			AddColorStop(0, colors.Red).AddColorStop(0.4, colors.Gold).AddColorStop(1, colors.Blue),
+		"conic_gap_hard": colors.NewConicGradient(100, 100, 0).WithGap(40).
+			AddColorStop(0, colors.Amethyst).AddColorStop(0.5, colors.Amethyst).
+			AddColorStop(0.5, colors.Pumpkin).AddColorStop(1, colors.RGBA(20, 90, 200, 128)),
+		"conic_seam": colors.NewConicGradient(60, 140, 90).WithSeamBlend(20).
+			AddColorStop(0, colors.Red).AddColorStop(1, colors.Green),
+		"radial_hard": colors.NewRadialGradient(90, 110, 0, 90, 110, 80).
+			AddColorStop(0, colors.White).AddColorStop(0.6, colors.Red).
+			AddColorStop(0.6, colors.Blue).AddColorStop(1, colors.Black),
+	}
+
+	for name, p := range cases {
+		t.Run(name, func(t *testing.T) {
+			dst := make([]patterns.Color, 200)
+			var off, total int
+			for y := 0; y < 200; y += 3 {
+				patterns.FillSpan(p, y, 0, len(dst), dst)
+				for x := range dst {
+					want := patterns.NewColorFromStd(p.ColorAt(x, y))
+					got := dst[x]
+					d := 0
+					for _, ch := range [][2]uint8{{want.R, got.R}, {want.G, got.G}, {want.B, got.B}, {want.A, got.A}} {
+						d = max(d, int(math.Abs(float64(ch[0])-float64(ch[1]))))
+					}
+					require.LessOrEqual(t, d, 2, "x=%d y=%d", x, y)
+					if d > 1 {
+						off++
+					}
+					total++
+				}
+			}
+			require.Less(t, off, total/100, "pixels off by more than one level")
+		})
+	}
+}
@@ -1,10 +1,17 @@
 package glimo_test
 
 import (
+	"image"
+	"image/color"
+	"math"
 	"testing"
 
 	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/effects"
+	"github.com/Krispeckt/glimo/glimotest"
 	"github.com/Krispeckt/glimo/instructions"
+	"github.com/Krispeckt/glimo/internal/render"
+	"github.com/Krispeckt/glimo/patterns"
 	"github.com/stretchr/testify/require"
 )
 
@@ -156,6 +163,427 @@ func TestInstructionRectangle(t *testing.T) {
 
 			err := canvas.Export("./output/rect_" + cse.name + ".png")
 			require.NoError(t, err, "export failed for %s", cse.name)
+
+			glimotest.AssertImageMatches(t, canvas, "./testdata/golden/rect_"+cse.name+".png", 0.01)
 		})
 	}
 }
+
+func TestStrictMode(t *testing.T) {
+	rect := instructions.NewRectangle(0, 0, 10, 10).
+		SetFillPattern(nil).
+		SetRadius(-4).
+		SetFillColor(colors.Red)
+	var cfgErr *instructions.ConfigError
+	require.ErrorAs(t, rect.Err(), &cfgErr)
+	require.Equal(t, "SetFillPattern", cfgErr.Setter)
+	require.Contains(t, rect.Err().Error(), "Rectangle.SetRadius: negative radius")
+
+	circle := instructions.NewCircle(0, 0, 5).SetSteps(2)
+	valid := instructions.NewCircle(0, 0, 5).SetSteps(16)
+	require.NoError(t, valid.Err())
+	require.Error(t, instructions.Validate(valid, circle))
+
+	// Validate finds errors on nested shapes, including lines and containers.
+	line := instructions.NewLine().SetLineWidth(-1)
+	require.ErrorContains(t, line.Err(), "Line.SetLineWidth")
+	group := instructions.NewGroup().SetFrameSize(-5, 10)
+	group.AddInstruction(instructions.Bounded(line, 10, 10))
+	layout := instructions.NewAutoLayout(0, 0, instructions.ContainerStyle{}).Add(group, instructions.ItemStyle{})
+	err := instructions.Validate(layout)
+	require.ErrorContains(t, err, "Group.SetFrameSize")
+	require.ErrorContains(t, err, "Line.SetLineWidth")
+	require.NoError(t, instructions.Validate(instructions.NewGroup()))
+
+	// Lenient layers draw clamped values even through a checked load.
+	lenient := newLayer(t, 20, 20)
+	require.False(t, lenient.Strict())
+	require.NoError(t, lenient.LoadInstructionsChecked(rect))
+	require.Equal(t, uint8(255), lenient.Image().RGBAAt(5, 5).R)
+
+	// A strict layer refuses the whole batch; strictness is per layer.
+	l := newLayer(t, 20, 20).SetStrict(true)
+	require.True(t, l.Strict())
+	err = l.LoadInstructionsChecked(valid, circle)
+	require.ErrorContains(t, err, "Circle.SetSteps")
+	require.Zero(t, l.Image().RGBAAt(5, 5).A)
+	require.ErrorContains(t, l.LoadInstructionsChecked(layout), "Group.SetFrameSize")
+	require.NoError(t, l.Err(), "checked loads return their errors instead")
+
+	// The other load paths skip refused shapes, draw the rest and keep the
+	// errors on the layer.
+	l.LoadInstruction(rect)
+	require.Zero(t, l.Image().RGBAAt(5, 5).A)
+	l.LoadInstructionsFast(circle, instructions.NewRectangle(0, 0, 4, 4).SetFillColor(colors.Blue))
+	require.Equal(t, uint8(255), l.Image().RGBAAt(2, 2).B)
+	l.Retain(layout)
+	err = l.Err()
+	require.ErrorContains(t, err, "Rectangle.SetFillPattern")
+	require.ErrorContains(t, err, "Circle.SetSteps")
+	require.ErrorContains(t, err, "Group.SetFrameSize")
+	require.Zero(t, l.Image().RGBAAt(5, 5).A)
+	require.ErrorContains(t, l.Clone().Err(), "Circle.SetSteps")
+}
+
+func TestShapeOpacity(t *testing.T) {
+	white := func() *instructions.Layer {
+		l := instructions.NewLayer(120, 60)
+		l.LoadInstruction(instructions.NewRectangle(0, 0, 120, 60).SetLineWidth(0).SetFillColor(colors.White))
+		return l
+	}
+
+	// A half-opaque red rectangle over white.
+	l := white()
+	rect := instructions.NewRectangle(10, 10, 40, 40).SetLineWidth(0).SetFillColor(colors.Red).SetOpacity(0.5)
+	require.Equal(t, 0.5, rect.Opacity())
+	l.LoadInstruction(rect)
+	c := l.Image().RGBAAt(30, 30)
+	require.Equal(t, uint8(255), c.R)
+	require.InDelta(t, 128, int(c.G), 1)
+
+	// Fill and stroke fade as one: the fill does not show under the stroke.
+	stroked := func(o float64) *instructions.Layer {
+		l := white()
+		l.LoadInstruction(instructions.NewRectangle(10, 10, 40, 40).
+			SetFillColor(colors.Blue).SetStrokeColor(colors.Red).SetLineWidth(6).SetOpacity(o))
+		return l
+	}
+	c = stroked(0.5).Image().RGBAAt(12, 30)
+	require.Equal(t, uint8(255), c.R, "red stroke over white")
+	require.InDelta(t, 128, int(c.B), 1, "no blue fill beneath the stroke")
+
+	// Overlapping children of a faded group do not show through each other.
+	g := instructions.NewGroup().SetOpacity(0.5)
+	g.AddInstructions(
+		instructions.NewRectangle(60, 10, 30, 30).SetLineWidth(0).SetFillColor(colors.Red),
+		instructions.NewRectangle(75, 20, 30, 30).SetLineWidth(0).SetFillColor(colors.Red),
+	)
+	l = white()
+	l.LoadInstruction(g)
+	require.Equal(t, l.Image().RGBAAt(65, 15), l.Image().RGBAAt(80, 30))
+	require.InDelta(t, 128, int(l.Image().RGBAAt(80, 30).G), 1)
+
+	// Zero opacity draws nothing.
+	l = white()
+	l.LoadInstructions(
+		instructions.NewCircle(10, 10, 20).SetFillColor(colors.Black).SetOpacity(0),
+		instructions.NewCircle(70, 10, 20).SetFillColor(colors.Black).SetOpacity(0.25),
+	)
+	require.Equal(t, uint8(255), l.Image().RGBAAt(30, 30).G)
+	require.InDelta(t, 191, int(l.Image().RGBAAt(90, 30).G), 1)
+}
+
+func TestGlassRect(t *testing.T) {
+	// Black and white stripes 4px wide behind the card.
+	l := instructions.NewLayer(200, 120)
+	l.LoadInstruction(instructions.NewRectangle(0, 0, 200, 120).SetLineWidth(0).SetFillColor(colors.White))
+	for x := 0; x < 200; x += 8 {
+		l.LoadInstruction(instructions.NewRectangle(float64(x), 0, 4, 120).SetLineWidth(0).SetFillColor(colors.Black))
+	}
+
+	glass := instructions.NewGlassRect(40, 20, 120, 80).
+		SetRadius(24).
+		SetBlur(6).
+		SetTint(colors.White, 0).
+		SetBorder(colors.White, 1, 0)
+	l.LoadInstruction(glass)
+	require.NoError(t, glass.Err())
+
+	img := l.Image()
+	c := img.RGBAAt(100, 60)
+	require.InDelta(t, 128, int(c.R), 24, "stripes are blurred to gray inside the card")
+	require.Equal(t, uint8(0), img.RGBAAt(41, 21).R, "corner outside the radius is untouched")
+	require.Equal(t, uint8(255), img.RGBAAt(20, 60).R, "stripes outside the card stay sharp")
+
+	// The tint and the border draw over the blur.
+	l.LoadInstruction(instructions.NewGlassRect(40, 20, 120, 80).
+		SetRadius(24).
+		SetTint(colors.Red, 0.5).
+		SetBorder(colors.White, 1, 2))
+	c = img.RGBAAt(100, 60)
+	require.Greater(t, int(c.R), int(c.G)+60, "red tint")
+	require.Equal(t, uint8(255), img.RGBAAt(100, 20).G, "white border")
+	require.NoError(t, l.Export("./output/glass_rect.png"))
+}
+
+func TestRegionEffect(t *testing.T) {
+	stripes := func() *instructions.Layer {
+		l := instructions.NewLayer(200, 120)
+		l.LoadInstruction(instructions.NewRectangle(0, 0, 200, 120).SetLineWidth(0).SetFillColor(colors.White))
+		for x := 0; x < 200; x += 8 {
+			l.LoadInstruction(instructions.NewRectangle(float64(x), 0, 4, 120).SetLineWidth(0).SetFillColor(colors.Red))
+		}
+		return l
+	}
+
+	// Blur only behind a spoiler bar; the bar itself is not painted.
+	l := stripes()
+	l.LoadInstruction(instructions.NewRegionEffect(
+		instructions.NewRectangle(40, 40, 120, 40).SetLineWidth(0).SetFillColor(colors.Black),
+		effects.NewLayerBlurEffect(6),
+	))
+	img := l.Image()
+	c := img.RGBAAt(100, 60)
+	require.Equal(t, uint8(255), c.R, "no black from the mask shape")
+	require.InDelta(t, 128, int(c.G), 24, "stripes are blurred inside the region")
+	require.Equal(t, uint8(0), img.RGBAAt(98, 20).G, "stripes outside stay sharp")
+	require.Equal(t, uint8(255), img.RGBAAt(4, 60).G)
+
+	// Any effect works, limited to the shape's coverage, and opacity
+	// blends it with the original.
+	l = stripes()
+	l.LoadInstruction(instructions.NewRegionEffect(
+		instructions.NewCircle(70, 30, 30).SetFillColor(colors.White),
+		effects.NewPaletteEffect([]patterns.Color{colors.Black, colors.White}),
+	).SetOpacity(0.5))
+	img = l.Image()
+	require.Equal(t, img.RGBAAt(98, 60).G, img.RGBAAt(98, 60).B)
+	require.Less(t, img.RGBAAt(98, 60).R, uint8(255), "red is pulled toward the palette")
+	require.Equal(t, uint8(255), img.RGBAAt(20, 60).R, "outside the circle is untouched")
+	require.NoError(t, l.Export("./output/region_effect.png"))
+
+	// The effect counts toward the effect radius limit.
+	lim := instructions.Limits{MaxEffectRadius: 4}
+	require.Error(t, lim.CheckShapes(0, instructions.NewRegionEffect(instructions.NewCircle(0, 0, 5), effects.NewLayerBlurEffect(6))))
+}
+
+func TestFillAndStrokeBlendModes(t *testing.T) {
+	l := instructions.NewLayer(160, 60)
+	l.LoadInstruction(instructions.NewRectangle(0, 0, 160, 60).SetLineWidth(0).SetFillColor(colors.Red))
+
+	// One gradient shared by shapes compositing differently.
+	blue := colors.Blue.MakeLinearGradient(0, 0, 160, 0, colors.Blue)
+	l.LoadInstructions(
+		instructions.NewRectangle(0, 0, 40, 40).SetLineWidth(0).SetFillPattern(blue),
+		instructions.NewRectangle(40, 0, 40, 40).SetLineWidth(0).SetFillPattern(blue).
+			SetFillBlendMode(colors.BlendMultiply),
+		instructions.NewRectangle(80, 0, 40, 40).SetLineWidth(0).SetFillPattern(blue).
+			SetFillBlendMode(colors.BlendLighten),
+		instructions.NewCircle(120, 0, 20).SetLineWidth(6).SetStrokePattern(blue).
+			SetStrokeBlendMode(colors.BlendLighten),
+	)
+	img := l.Image()
+	require.Equal(t, color.RGBA{B: 255, A: 255}, img.RGBAAt(20, 20))
+	require.Equal(t, color.RGBA{A: 255}, img.RGBAAt(60, 20))
+	require.Equal(t, color.RGBA{R: 255, B: 255, A: 255}, img.RGBAAt(100, 20))
+	require.Equal(t, color.RGBA{R: 255, B: 255, A: 255}, img.RGBAAt(122, 20), "lightened stroke")
+	require.Equal(t, color.RGBA{R: 255, A: 255}, img.RGBAAt(140, 20), "transparent fill")
+	require.Equal(t, colors.BlendPassThrough, blue.BlendMode(), "the shared pattern is unchanged")
+}
+
+func TestShapeRotation(t *testing.T) {
+	l := instructions.NewLayer(240, 120)
+
+	// A square turned 45° about its center becomes a diamond: its corners
+	// are gone and its tips reach past the unrotated box.
+	sq := instructions.NewRectangle(20, 20, 80, 80).SetLineWidth(0).SetFillColor(colors.Red).
+		SetRotation(45, instructions.AnchorCenter)
+	x, y := sq.Position()
+	require.Equal(t, 3, x)
+	require.Equal(t, 3, y)
+	require.InDelta(t, 80*math.Sqrt2, sq.Size().Width(), 1e-9)
+	l.LoadInstruction(sq)
+	img := l.Image()
+	require.Equal(t, uint8(255), img.RGBAAt(60, 60).R)
+	require.Equal(t, uint8(0), img.RGBAAt(22, 22).A, "corner of the unrotated box is empty")
+	require.Equal(t, uint8(255), img.RGBAAt(60, 6).R, "the top tip reaches above it")
+
+	// Layouts move the rotated bounding box.
+	sq.SetPosition(10, 10)
+	x, y = sq.Position()
+	require.Equal(t, 10, x)
+	require.Equal(t, 10, y)
+
+	// A circle turned about its top-left corner swings around it.
+	c := instructions.NewCircle(140, 20, 20).SetFillColor(colors.Blue).SetRotation(90, instructions.AnchorTopLeft)
+	x, y = c.Position()
+	require.Equal(t, 100, x)
+	require.Equal(t, 20, y)
+	require.Equal(t, 40.0, c.Size().Width())
+
+	// Rotated text is drawn from outlines along its turned baseline.
+	font := render.MustLoadFont("testdata/montserrat.ttf", 20)
+	txt := instructions.NewText("Rotated", 180, 10, font).SetSolidColor(colors.Black).
+		SetStrokeWithColor(colors.Red, 2).SetRotation(90, instructions.AnchorTopLeft)
+	plain := instructions.NewText("Rotated", 180, 10, font).Size()
+	require.InDelta(t, plain.Height(), txt.Size().Width(), 1e-9)
+	require.InDelta(t, plain.Width(), txt.Size().Height(), 1e-9)
+	l.LoadInstructions(c, txt)
+	ink := image.Rectangle{}
+	img = l.Image()
+	for py := 0; py < 120; py++ {
+		for px := 150; px < 240; px++ {
+			if img.RGBAAt(px, py).A > 0 {
+				ink = ink.Union(image.Rect(px, py, px+1, py+1))
+			}
+		}
+	}
+	require.Greater(t, ink.Dy(), 2*ink.Dx(), "glyphs run downwards")
+	require.LessOrEqual(t, ink.Max.X, 181)
+	require.NoError(t, l.Export("./output/shape_rotation.png"))
+
+	// Synthetic bold turns with the outlines too.
+	bold := render.MustLoadFont("testdata/montserrat.ttf", 20).SetSyntheticBold(0.05)
+	bl := instructions.NewLayer(60, 160)
+	bl.LoadInstruction(instructions.NewText("Bold", 40, 10, bold).SetSolidColor(colors.Black).
+		SetRotation(90, instructions.AnchorTopLeft))
+	ink = image.Rectangle{}
+	for py := 0; py < 160; py++ {
+		for px := 0; px < 60; px++ {
+			if bl.Image().RGBAAt(px, py).A > 0 {
+				ink = ink.Union(image.Rect(px, py, px+1, py+1))
+			}
+		}
+	}
+	require.Greater(t, ink.Dy(), ink.Dx(), "bold glyphs run downwards")
+	require.LessOrEqual(t, ink.Max.X, 41)
+
+	// Unshaped fonts have no outlines: the text stays unrotated, reports
+	// the unrotated box and records why.
+	unshaped := render.MustLoadFont("testdata/montserrat.ttf", 20).SetShaping(false)
+	flat := instructions.NewText("Rotated", 180, 10, unshaped).SetRotation(90, instructions.AnchorTopLeft)
+	require.Equal(t, instructions.NewText("Rotated", 180, 10, unshaped).Size(), flat.Size())
+	var ce *instructions.ConfigError
+	require.ErrorAs(t, flat.Err(), &ce)
+	require.Equal(t, "SetRotation", ce.Setter)
+}
+
+func TestReflectionEffect(t *testing.T) {
+	l := instructions.NewLayer(160, 180)
+	l.LoadInstruction(instructions.NewRectangle(40, 10, 80, 80).SetLineWidth(0).
+		SetFillPattern(patterns.NewLinearGradient(0, 10, 0, 90).
+			AddColorStop(0, colors.Red).AddColorStop(1, colors.Blue)).
+		AddEffect(effects.NewReflectionEffect(6, 0.5)))
+	img := l.Image()
+
+	// The bottom of the card reflects first, at about the set opacity,
+	// after the gap.
+	require.Equal(t, uint8(0), img.RGBAAt(80, 93).A, "gap stays clear")
+	top := img.RGBAAt(80, 96)
+	require.InDelta(t, 127, int(top.A), 4)
+	require.Greater(t, top.B, top.R, "flipped: blue comes first")
+
+	// It fades out over half the height and leaves the sides alone.
+	require.Less(t, img.RGBAAt(80, 130).A, top.A)
+	require.Equal(t, uint8(0), img.RGBAAt(80, 140).A)
+	require.Equal(t, uint8(0), img.RGBAAt(30, 100).A)
+	require.NoError(t, l.Export("./output/reflection_effect.png"))
+}
+
+func TestShapeTransform(t *testing.T) {
+	l := instructions.NewLayer(320, 120)
+
+	// A shear about the center slants the card in place: its bottom moves
+	// left, its top right, and the box grows by the slant.
+	card := instructions.NewRectangle(20, 20, 100, 80).SetLineWidth(0).SetFillColor(colors.Red).
+		SetTransform(instructions.ShearMatrix(-0.25, 0), instructions.AnchorCenter)
+	x, y := card.Position()
+	require.Equal(t, 10, x)
+	require.Equal(t, 20, y)
+	require.InDelta(t, 120, card.Size().Width(), 1e-9)
+	require.InDelta(t, 80, card.Size().Height(), 1e-9)
+	m, origin := card.Transform()
+	require.Equal(t, instructions.ShearMatrix(-0.25, 0), m)
+	require.Equal(t, instructions.AnchorCenter, origin)
+
+	// A circle scaled unevenly becomes an ellipse with matching bounds.
+	oval := instructions.NewCircle(150, 20, 40).SetFillColor(colors.Blue).
+		SetTransform(instructions.IdentityMatrix().Scale(1, 0.5), instructions.AnchorCenter)
+	x, y = oval.Position()
+	require.Equal(t, 150, x)
+	require.Equal(t, 40, y)
+	require.InDelta(t, 40, oval.Size().Height(), 1e-9)
+
+	font := render.MustLoadFont("testdata/montserrat.ttf", 28)
+	fast := instructions.NewText("FAST", 240, 40, font).SetSolidColor(colors.Black).
+		SetTransform(instructions.ShearMatrix(-0.3, 0), instructions.AnchorBottomLeft)
+	plain := instructions.NewText("FAST", 240, 40, font)
+	require.Greater(t, fast.Size().Width(), plain.Size().Width())
+	require.Equal(t, plain.Size().Height(), fast.Size().Height())
+
+	l.LoadInstructions(card, oval, fast)
+	img := l.Image()
+	require.Equal(t, uint8(255), img.RGBAAt(125, 24).R, "top slants right")
+	require.Equal(t, uint8(0), img.RGBAAt(125, 96).A)
+	require.Equal(t, uint8(255), img.RGBAAt(14, 96).R, "bottom slants left")
+	require.Equal(t, uint8(255), img.RGBAAt(190, 60).B)
+	require.Equal(t, uint8(0), img.RGBAAt(190, 30).A, "squashed vertically")
+
+	// The zero Matrix removes the transform.
+	card.SetTransform(instructions.Matrix{}, instructions.AnchorCenter)
+	x, _ = card.Position()
+	require.Equal(t, 20, x)
+
+	// Device scaling keeps the slant and scales the translation.
+	moved := instructions.NewRectangle(0, 0, 10, 10).SetLineWidth(0).SetFillColor(colors.Red).
+		SetTransform(instructions.IdentityMatrix().Translate(5, 0), instructions.AnchorTopLeft)
+	hi := instructions.NewLayerWithScale(30, 10, 2)
+	hi.LoadInstruction(moved)
+	require.Equal(t, uint8(0), hi.Image().RGBAAt(8, 4).A)
+	require.Equal(t, uint8(255), hi.Image().RGBAAt(12, 4).R)
+	require.NoError(t, l.Export("./output/shape_transform.png"))
+}
+
+func TestContainerTransform(t *testing.T) {
+	l := instructions.NewLayer(320, 120)
+	solid := func(c color.Color, w, h int) *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				img.Set(x, y, c)
+			}
+		}
+		return img
+	}
+	double := instructions.IdentityMatrix().Scale(2, 1)
+	tall := instructions.IdentityMatrix().Scale(1, 2)
+
+	// Images and containers are resampled and report the transformed box.
+	im := instructions.NewImage(solid(color.RGBA{R: 255, A: 255}, 40, 20), 10, 10).
+		SetTransform(double, instructions.AnchorTopLeft)
+	x, y := im.Position()
+	require.Equal(t, 10, x)
+	require.Equal(t, 10, y)
+	require.InDelta(t, 80, im.Size().Width(), 1e-9)
+
+	g := instructions.NewGroup().SetPositionChain(120, 10)
+	g.AddInstruction(instructions.NewRectangle(0, 0, 20, 20).SetLineWidth(0).SetFillColor(colors.Blue))
+	g.SetTransform(tall, instructions.AnchorTopLeft)
+	require.InDelta(t, 40, g.Size().Height(), 1e-9)
+
+	box := instructions.Bounded(instructions.NewLine().
+		MoveTo(0, 0).LineTo(20, 0).LineTo(20, 20).LineTo(0, 20).ClosePath().
+		SetFillPattern(colors.Green.MakeSolidPattern()).Fill(), 20, 20).
+		SetPositionChain(160, 10).
+		SetTransform(double, instructions.AnchorTopLeft)
+	require.InDelta(t, 40, box.Size().Width(), 1e-9)
+
+	al := instructions.NewAutoLayout(220, 10, instructions.ContainerStyle{Width: 20, Height: 20}).
+		Add(instructions.NewRectangle(0, 0, 20, 20).SetLineWidth(0).SetFillColor(colors.Red), instructions.ItemStyle{}).
+		SetTransform(tall, instructions.AnchorTopLeft)
+	require.InDelta(t, 40, al.Size().Height(), 1e-9)
+
+	// Lines compose the transform into their matrix.
+	line := instructions.NewLine().SetTransform(instructions.IdentityMatrix().Translate(260, 60)).
+		MoveTo(0, 0).LineTo(20, 0).LineTo(20, 20).LineTo(0, 20).ClosePath().
+		SetFillPattern(colors.Blue.MakeSolidPattern()).Fill()
+
+	l.LoadInstructions(im, g, box, al, line)
+	img := l.Image()
+	require.Equal(t, uint8(255), img.RGBAAt(80, 20).R, "image stretched right")
+	require.Equal(t, uint8(0), img.RGBAAt(95, 20).A)
+	require.Equal(t, uint8(255), img.RGBAAt(130, 45).B, "group stretched down")
+	require.Equal(t, uint8(0), img.RGBAAt(130, 55).A)
+	require.Equal(t, uint8(255), img.RGBAAt(195, 20).G, "box stretched right")
+	require.Equal(t, uint8(255), img.RGBAAt(230, 45).R, "layout stretched down")
+	require.Equal(t, uint8(255), img.RGBAAt(270, 70).B, "line moved")
+	require.Equal(t, uint8(0), img.RGBAAt(10, 70).A)
+
+	// Glass cards transform their path, sprites their frame.
+	glass := instructions.NewGlassRect(10, 60, 40, 40).SetTransform(double, instructions.AnchorTopLeft)
+	require.InDelta(t, 80, glass.Size().Width(), 1e-9)
+	sprite := instructions.NewSprite(solid(color.RGBA{G: 255, A: 255}, 20, 10), 10, 10, 0, 0).
+		SetTransform(tall, instructions.AnchorTopLeft)
+	require.InDelta(t, 20, sprite.Size().Height(), 1e-9)
+	require.NoError(t, l.Export("./output/container_transform.png"))
+}
@@ -1,13 +1,24 @@
 package glimo_test
 
 import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/Krispeckt/glimo/colors"
 	"github.com/Krispeckt/glimo/effects"
+	"github.com/Krispeckt/glimo/glimotest"
 	"github.com/Krispeckt/glimo/instructions"
 	"github.com/Krispeckt/glimo/internal/render"
 	"github.com/stretchr/testify/require"
+	xfont "golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/math/fixed"
 )
 
 func TestInstructionText(t *testing.T) {
@@ -83,6 +94,18 @@ func TestInstructionText(t *testing.T) {
 				)
 			},
 		},
+		{
+			name: "paragraph_align",
+			setup: func(t *testing.T, c *instructions.Layer) {
+				c.LoadInstructions(
+					instructions.NewText("Centered Title\nLeft body text\nRight footer", 0, 50, font).
+						SetColorPattern(colors.NewSolid(colors.MediumPurple)).
+						SetMaxWidth(1000).
+						SetParagraphAlign(0, instructions.AlignTextCenter).
+						SetParagraphAlign(2, instructions.AlignTextRight),
+				)
+			},
+		},
 		{
 			name: "stroke",
 			setup: func(t *testing.T, c *instructions.Layer) {
@@ -108,6 +131,1190 @@ func TestInstructionText(t *testing.T) {
 			outPath := "./output/text_" + cse.name + ".png"
 			err := canvas.Export(outPath)
 			require.NoError(t, err, "export failed for %s", cse.name)
+
+			glimotest.AssertImageMatches(t, canvas, "./testdata/golden/text_"+cse.name+".png", 0.01)
 		})
 	}
 }
+
+func TestTextMissingGlyphs(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 48)
+
+	plain, _ := font.MeasureString("AB")
+	require.Empty(t, font.MissingRunes())
+
+	font.SetMissingGlyphMode(render.MissingGlyphSkip)
+	skipped, _ := font.MeasureString("A中B")
+	require.Equal(t, plain, skipped)
+	require.Equal(t, []rune{'中'}, font.MissingRunes())
+
+	font.SetMissingGlyphMode(render.MissingGlyphBox)
+	boxed, _ := font.MeasureString("A中B")
+	require.Greater(t, boxed, plain)
+
+	canvas := newLayer(t, 400, 120)
+	canvas.LoadInstruction(
+		instructions.NewText("A中B", 10, 10, font).SetSolidColor(colors.Black),
+	)
+	require.NoError(t, canvas.Export("./output/text_missing_glyph_box.png"))
+
+	font.ResetMissingRunes()
+	require.Empty(t, font.MissingRunes())
+}
+
+func TestTextSoftBreaks(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 48)
+
+	plainW, _ := font.MeasureString("Donaudampfschiff")
+	unwrapped := instructions.NewText("Donau\u00ADdampf\u200Bschiff", 0, 0, font)
+	require.InDelta(t, plainW, unwrapped.Size().Width(), 0.5)
+
+	single := instructions.NewText("Donaudampfschiff", 0, 0, font).Size().Height()
+	wrapped := instructions.NewText("Donau\u00ADdampf\u00ADschiff", 10, 10, font).
+		SetSolidColor(colors.Black).
+		SetMaxWidth(plainW * 0.6)
+	require.Greater(t, wrapped.Size().Height(), single)
+
+	canvas := newLayer(t, 500, 300)
+	canvas.LoadInstruction(wrapped)
+	require.NoError(t, canvas.Export("./output/text_soft_hyphen.png"))
+}
+
+func TestTextAutoContrast(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 48)
+
+	canvas := newLayer(t, 600, 200)
+	canvas.LoadInstructions(
+		instructions.NewRectangle(0, 0, 300, 200).SetFillColor(colors.Black),
+		instructions.NewRectangle(300, 0, 300, 200).SetFillColor(colors.White),
+	)
+
+	// Over a flat dark area the light fill wins without a scrim.
+	canvas.LoadInstruction(
+		instructions.NewText("Dark", 20, 20, font).
+			SetAutoContrast(instructions.NewAutoContrast(colors.White, colors.Black)),
+	)
+	bright := 0
+	for y := 20; y < 80; y++ {
+		for x := 20; x < 200; x++ {
+			if canvas.Image().RGBAAt(x, y).R > 200 {
+				bright++
+			}
+		}
+	}
+	require.Positive(t, bright)
+
+	// Straddling both halves, neither fill is legible, so a scrim is inserted.
+	canvas.LoadInstruction(
+		instructions.NewText("Busy background", 150, 120, font).
+			SetAutoContrast(instructions.NewAutoContrast(colors.White, colors.Black).SetScrim(0.7, 8)),
+	)
+	require.NotEqual(t, uint8(255), canvas.Image().RGBAAt(450, 125).R)
+
+	require.NoError(t, canvas.Export("./output/text_auto_contrast.png"))
+}
+
+func TestTextShaping(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 48)
+	require.True(t, font.Shaping())
+
+	// Kerning pulls the pair together compared to the separate glyphs.
+	a, _ := font.MeasureString("A")
+	v, _ := font.MeasureString("V")
+	av, _ := font.MeasureString("AV")
+	require.Less(t, av, a+v)
+
+	// The pen position after drawing matches the measured width.
+	dst := image.NewRGBA(image.Rect(0, 0, 400, 80))
+	dot := font.DrawString(dst, colors.Black, "To AV office", 10, 60)
+	w, _ := font.MeasureString("To AV office")
+	require.InDelta(t, 10+w, float64(dot.X)/64, 1.0/64)
+
+	// The unshaped path advances as measured, with or without tracking.
+	legacy := render.MustLoadFont("testdata/montserrat.ttf", 48).SetShaping(false)
+	for _, track := range []float64{0, 10} {
+		legacy.SetLetterSpacingPercent(track)
+		w, _ = legacy.MeasureString("To AV office")
+		dot = legacy.DrawString(dst, colors.Black, "To AV office", 10, 60)
+		require.InDelta(t, 10+w, float64(dot.X)/64, 1)
+	}
+
+	// Without spacing the whole line goes to the face in one run, drawing
+	// exactly what its own drawer does.
+	legacy.SetLetterSpacingPercent(0)
+	got := image.NewRGBA(dst.Rect)
+	legacy.DrawString(got, colors.Black, "To AV office", 10, 60)
+	want := image.NewRGBA(dst.Rect)
+	(&xfont.Drawer{Dst: want, Src: image.NewUniform(colors.Black), Face: legacy.Face(), Dot: fixed.P(10, 60)}).DrawString("To AV office")
+	require.Equal(t, want.Pix, got.Pix)
+
+	canvas := newLayer(t, 520, 180)
+	canvas.LoadInstructions(
+		instructions.NewText("To AV office", 10, 10, font).SetSolidColor(colors.Black),
+		instructions.NewText("To AV office", 10, 90, render.MustLoadFont("testdata/montserrat.ttf", 48).SetShaping(false)).
+			SetSolidColor(colors.Black),
+	)
+	require.NoError(t, canvas.Export("./output/text_shaping.png"))
+}
+
+func TestTextBidi(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 32)
+	draw := func(s string, dir instructions.TextDirection, align instructions.AlignText) *instructions.Layer {
+		canvas := newLayer(t, 400, 60)
+		canvas.LoadInstruction(
+			instructions.NewText(s, 10, 10, font).
+				SetMaxWidth(380).
+				SetDirection(dir).
+				SetAlign(align).
+				SetSolidColor(colors.Black),
+		)
+		return canvas
+	}
+
+	// In a right-to-left paragraph, left-to-right runs and numbers keep their
+	// own order but are laid out from the right; enclosing brackets follow
+	// the embedding direction and are mirrored.
+	rtl := draw("12 abc", instructions.DirectionRTL, instructions.AlignTextLeft)
+	require.Equal(t, draw("abc 12", instructions.DirectionLTR, instructions.AlignTextLeft).Image().Pix, rtl.Image().Pix)
+
+	rtl = draw("(abc) 12", instructions.DirectionRTL, instructions.AlignTextLeft)
+	require.Equal(t, draw("12 (abc)", instructions.DirectionLTR, instructions.AlignTextLeft).Image().Pix, rtl.Image().Pix)
+
+	// Start and end alignment follow the paragraph direction.
+	require.Equal(t,
+		draw("abc", instructions.DirectionRTL, instructions.AlignTextRight).Image().Pix,
+		draw("abc", instructions.DirectionRTL, instructions.AlignTextStart).Image().Pix)
+	require.Equal(t,
+		draw("abc", instructions.DirectionLTR, instructions.AlignTextLeft).Image().Pix,
+		draw("abc", instructions.DirectionAuto, instructions.AlignTextStart).Image().Pix)
+	require.Equal(t,
+		draw("abc", instructions.DirectionRTL, instructions.AlignTextLeft).Image().Pix,
+		draw("abc", instructions.DirectionRTL, instructions.AlignTextEnd).Image().Pix)
+
+	require.NoError(t, draw("(abc) 12, def", instructions.DirectionRTL, instructions.AlignTextStart).
+		Export("./output/text_bidi_rtl.png"))
+}
+
+func TestTextComplexShaping(t *testing.T) {
+	// Glyph heights identify them: Arabic BEH forms are 10–40px tall, lam 50,
+	// lam-alef 60, alef 70; Devanagari KA 10, RA 20, SSA 30, virama 5,
+	// I matra 40, half KA 15, reph 50.
+	rect := func(r rune, adv uint16, h int16) testGlyph {
+		return testGlyph{r: r, adv: adv, x0: 20, x1: int16(adv) - 20, y1: h, class: 1}
+	}
+	glyphs := []testGlyph{
+		{adv: 500}, {r: ' ', adv: 250},
+		rect(0x0628, 300, 100), rect(0, 300, 200), rect(0, 300, 300), rect(0, 300, 400),
+		rect(0x0644, 300, 500), rect(0x0627, 200, 700),
+		{adv: 500, x0: 20, x1: 480, y1: 600, class: 2},
+		{r: 0x064E, x0: 0, x1: 100, y0: 850, y1: 950, class: 3},
+		rect(0x0915, 300, 100), rect(0x0930, 300, 200), rect(0x0937, 300, 300),
+		rect(0x094D, 200, 50), rect(0x093F, 200, 400), rect(0, 200, 150), rect(0, 200, 500),
+	}
+	tf := &testFont{
+		glyphs: glyphs,
+		gsub: layoutTable([]string{"arab", "dev2"}, []otFeature{
+			{"init", []uint16{0}}, {"medi", []uint16{1}}, {"fina", []uint16{2}},
+			{"rlig", []uint16{3}}, {"rphf", []uint16{4}}, {"half", []uint16{5}},
+		},
+			lookup(1, 0, singleSubst(2, 3)), lookup(1, 0, singleSubst(2, 4)), lookup(1, 0, singleSubst(2, 5)),
+			lookup(4, 0x8, ligatureSubst(8, 6, 7)), lookup(4, 0, ligatureSubst(16, 11, 13)),
+			lookup(4, 0, ligatureSubst(15, 10, 13)),
+		),
+		gpos: layoutTable([]string{"arab"}, []otFeature{{"mark", []uint16{0}}},
+			lookup(4, 0, markToBase(9, 50, 850, []uint16{2, 3, 4, 5}, 150, 800)),
+		),
+	}
+	font := render.MustLoadFontFromBytes(tf.bytes(), 100)
+
+	draw := func(font *render.Font, s string) *image.RGBA {
+		canvas := newLayer(t, 400, 200)
+		canvas.LoadInstruction(instructions.NewText(s, 10, 10, font).SetSolidColor(colors.Black))
+		return canvas.Image()
+	}
+	// blocks returns the height of each run of inked columns, left to
+	// right, counting ink up from the baseline, and where each run starts.
+	blocks := func(img *image.RGBA) (heights, starts []int) {
+		b := img.Bounds()
+		base := -1
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if img.RGBAAt(x, y).A > 127 {
+					base = y
+				}
+			}
+		}
+		require.GreaterOrEqual(t, base, 0)
+		for x := b.Min.X; x < b.Max.X; x++ {
+			h := 0
+			for y := base; y >= b.Min.Y && img.RGBAAt(x, y).A > 127; y-- {
+				h++
+			}
+			switch {
+			case h > 0 && (x == b.Min.X || len(starts) == 0 || starts[len(starts)-1] < 0):
+				heights, starts = append(heights, h), append(starts, x)
+			case h > 0:
+				heights[len(heights)-1] = max(heights[len(heights)-1], h)
+			case len(starts) > 0 && starts[len(starts)-1] >= 0:
+				starts = append(starts, -1) // gap
+			}
+		}
+		var s []int
+		for _, x := range starts {
+			if x >= 0 {
+				s = append(s, x)
+			}
+		}
+		return heights, s
+	}
+	heights := func(font *render.Font, s string) []int {
+		h, _ := blocks(draw(font, s))
+		return h
+	}
+
+	// Arabic letters join: the first BEH of a word is initial, the next
+	// medial, the last final, and a lone one isolated; the right-to-left
+	// line is drawn from the last letter.
+	require.Equal(t, []int{40, 30, 20}, heights(font, "ببب"))
+	require.Equal(t, []int{10, 40, 20}, heights(font, "بب ب"))
+	require.Equal(t, []int{60}, heights(font, "لا"))
+
+	// ZWNJ is no break opportunity but stays in the text, breaking the join.
+	require.Equal(t, []int{10, 10}, heights(font, "ب\u200Cب"))
+
+	// The fatha is transparent to joining, takes no advance and sits on the
+	// anchor of its BEH: 10px right of the glyph origin, 80px up.
+	withMark, _ := font.MeasureString("بَب")
+	without, _ := font.MeasureString("بب")
+	require.Equal(t, without, withMark)
+	img := draw(font, "بَب")
+	h, starts := blocks(img)
+	require.Equal(t, []int{40, 20}, h)
+	base := 0
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		if img.RGBAAt(starts[0], y).A > 127 {
+			base = y
+		}
+	}
+	origin := starts[1] - 2
+	require.Greater(t, img.RGBAAt(origin+15, base-85).A, uint8(127))
+	require.Zero(t, img.RGBAAt(origin+25, base-85).A)
+
+	// Devanagari: the I matra is drawn before its consonant cluster, KA
+	// takes its half form before another consonant, and RA with virama
+	// becomes a reph drawn after the cluster. A consonant without a half
+	// form keeps its virama, and the matra follows it.
+	require.Equal(t, []int{40, 10}, heights(font, "कि"))
+	require.Equal(t, []int{40, 15, 30}, heights(font, "क्षि"))
+	require.Equal(t, []int{10, 50}, heights(font, "र्क"))
+	require.Equal(t, []int{40, 10, 50}, heights(font, "र्कि"))
+	require.Equal(t, []int{30, 5, 40, 10}, heights(font, "ष्कि"))
+
+	// Without positional GSUB features the joining forms come from the
+	// font's Arabic Presentation Forms-B glyphs; lam-alef is ligated from
+	// the initial lam and final alef forms.
+	legacy := append([]testGlyph(nil), glyphs...)
+	legacy[3].r, legacy[4].r, legacy[5].r, legacy[8].r = 0xFE91, 0xFE92, 0xFE90, 0xFEFB
+	legacy = append(legacy, rect(0xFEDF, 300, 500), rect(0xFE8E, 200, 700))
+	fallback := render.MustLoadFontFromBytes((&testFont{glyphs: legacy}).bytes(), 100)
+	require.Equal(t, []int{40, 30, 20}, heights(fallback, "ببب"))
+	require.Equal(t, []int{60}, heights(fallback, "لا"))
+
+	// With shaping disabled, glyphs are drawn as mapped, in display order.
+	require.Equal(t, []int{10, 10, 10}, heights(render.MustLoadFontFromBytes(tf.bytes(), 100).SetShaping(false), "ببب"))
+}
+
+func TestTextSyntheticStyles(t *testing.T) {
+	load := func() *render.Font { return render.MustLoadFont("testdata/montserrat.ttf", 48) }
+	require.Equal(t, 700, load().FaceWeight())
+	require.False(t, load().FaceItalic())
+
+	regular, _ := load().MeasureString("abc")
+	lighter, _ := load().SetWeight(400).MeasureString("abc")
+	require.Equal(t, regular, lighter, "lighter weights cannot be synthesized")
+
+	heavier, _ := load().SetWeight(900).MeasureString("abc")
+	require.InDelta(t, regular+3*0.02*48, heavier, 0.1)
+
+	// inkCenter returns the mean x of the ink in the given rows.
+	inkCenter := func(l *instructions.Layer, y0, y1 int) float64 {
+		var sum, n float64
+		img := l.Image()
+		for y := y0; y < y1; y++ {
+			for x := 0; x < img.Bounds().Dx(); x++ {
+				if a := img.RGBAAt(x, y).A; a > 0 {
+					sum += float64(x) * float64(a)
+					n += float64(a)
+				}
+			}
+		}
+		return sum / n
+	}
+	draw := func(f *render.Font) *instructions.Layer {
+		canvas := newLayer(t, 120, 80)
+		canvas.LoadInstruction(instructions.NewText("I", 40, 10, f).SetSolidColor(colors.Black))
+		return canvas
+	}
+	upright, italic := draw(load()), draw(load().SetItalic(true))
+	require.Greater(t, inkCenter(italic, 10, 30), inkCenter(upright, 10, 30)+2, "top of the stem leans right")
+	require.Less(t, inkCenter(italic, 45, 60), inkCenter(upright, 10, 30)+2)
+
+	canvas := newLayer(t, 620, 200)
+	canvas.LoadInstructions(
+		instructions.NewText("Regular weight", 10, 10, load()).SetSolidColor(colors.Black),
+		instructions.NewText("Faux bold italic", 10, 100, load().SetWeight(900).SetItalic(true)).
+			SetSolidColor(colors.Black),
+	)
+	require.NoError(t, canvas.Export("./output/text_synthetic_styles.png"))
+}
+
+func TestTextVariableFont(t *testing.T) {
+	// One glyph on a weight axis: toward 900 its top-left corner rises 300
+	// units (the other corners follow by interpolation, the bottom stays) and
+	// its advance grows by 200; toward 100 the whole outline and advance
+	// lose 200 units of height and 100 of width.
+	tf := &testFont{
+		glyphs: []testGlyph{{adv: 500}, {r: 'a', adv: 500, x0: 50, x1: 450, y1: 500, class: 1}},
+		fvar:   fvarTable(testAxis{"wght", 100, 400, 900}),
+		gvar: gvarTable(1, 2, map[int][]testTuple{1: {
+			{peak: []float64{1}, points: []uint16{0, 1, 5}, dx: []int16{0, 0, 200}, dy: []int16{0, 300, 0}},
+			{peak: []float64{-1}, dx: []int16{0, 0, -100, -100, 0, -100, 0, 0}, dy: []int16{0, -200, -200, 0, 0, 0, 0, 0}},
+		}}),
+	}
+	load := func() *render.Font { return render.MustLoadFontFromBytes(tf.bytes(), 100) }
+	require.Equal(t, []render.FontAxis{{Tag: "wght", Min: 100, Default: 400, Max: 900}}, load().Axes())
+
+	// ink returns the height of the rightmost inked column, whose top
+	// corner is only moved by interpolation, and the number of inked columns.
+	ink := func(f *render.Font) (h, w int) {
+		canvas := newLayer(t, 200, 200)
+		canvas.LoadInstruction(instructions.NewText("a", 10, 10, f).SetSolidColor(colors.Black))
+		img := canvas.Image()
+		for x := 0; x < 200; x++ {
+			n := 0
+			for y := 0; y < 200; y++ {
+				if img.RGBAAt(x, y).A > 127 {
+					n++
+				}
+			}
+			if n > 0 {
+				h, w = n, w+1
+			}
+		}
+		return h, w
+	}
+	for _, tc := range []struct {
+		weight        float64
+		height, width int
+		advance       float64
+	}{
+		{400, 50, 40, 50},
+		{900, 80, 40, 70},
+		{650, 65, 40, 60},
+		{100, 30, 30, 40},
+	} {
+		f := load().SetVariation("wght", tc.weight)
+		h, w := ink(f)
+		require.Equal(t, tc.height, h, "height at %v", tc.weight)
+		require.Equal(t, tc.width, w, "width at %v", tc.weight)
+		adv, _ := f.MeasureString("a")
+		require.InDelta(t, tc.advance, adv, 0.01, "advance at %v", tc.weight)
+	}
+
+	f := load().SetVariation("wght", 2000).SetVariation("wdth", 50)
+	v, ok := f.Variation("wght")
+	require.True(t, ok)
+	require.Equal(t, 900.0, v, "clamped to the axis")
+	_, ok = f.Variation("wdth")
+	require.False(t, ok)
+
+	// SetWeight moves along the axis and only synthesizes what lies beyond.
+	adv, _ := load().SetWeight(900).MeasureString("a")
+	require.InDelta(t, 70, adv, 0.01)
+	adv, _ = load().SetWeight(1000).MeasureString("a")
+	require.InDelta(t, 70+0.01*100, adv, 0.01)
+
+	// Truncated or corrupt variation tables never panic: the font loads and
+	// draws, at worst as its default instance.
+	for _, bad := range []*testFont{
+		{glyphs: tf.glyphs, fvar: tf.fvar[:10], gvar: tf.gvar},
+		{glyphs: tf.glyphs, fvar: tf.fvar, gvar: tf.gvar[:len(tf.gvar)/2]},
+		{glyphs: tf.glyphs, fvar: tf.fvar, gvar: bytes.Repeat([]byte{0xFF}, len(tf.gvar))},
+	} {
+		f, err := render.LoadFontFromBytes(bad.bytes(), 100)
+		require.NoError(t, err)
+		h, _ := ink(f.SetVariation("wght", 900))
+		require.Positive(t, h)
+	}
+
+	reg := render.NewFontRegistry()
+	reg.AddAs("Var", 400, false, load())
+	matched, err := reg.Match("Var", 700, false, 100)
+	require.NoError(t, err)
+	v, _ = matched.Variation("wght")
+	require.Equal(t, 700.0, v)
+	adv, _ = matched.MeasureString("a")
+	require.InDelta(t, 62, adv, 0.01)
+}
+
+func TestTextSlantAxis(t *testing.T) {
+	glyphs := []testGlyph{{adv: 500}, {r: 'I', adv: 300, x0: 100, x1: 200, y1: 700, class: 1}}
+	font := func(axis testAxis) *render.Font {
+		return render.MustLoadFontFromBytes((&testFont{glyphs: glyphs, fvar: fvarTable(axis)}).bytes(), 100)
+	}
+	slnt := func() *render.Font { return font(testAxis{"slnt", -10, 0, 0}) }
+	// sheared reports the synthetic lean in degrees, from the overhang it
+	// adds above the baseline.
+	sheared := func(f *render.Font) float64 {
+		_, right := f.OverhangPx()
+		return math.Atan(right/f.AscentPx()) * 180 / math.Pi
+	}
+
+	// Within the axis range the real slant is drawn without a shear.
+	f := slnt().SetSlant(8)
+	v, _ := f.Variation("slnt")
+	require.Equal(t, -8.0, v)
+	require.Zero(t, sheared(f))
+
+	// Beyond it the shear makes up the difference.
+	f = slnt().SetSlant(15)
+	v, _ = f.Variation("slnt")
+	require.Equal(t, -10.0, v)
+	require.InDelta(t, 5, sheared(f), 1e-6)
+
+	// SetItalic prefers the axes and shears only faces without one.
+	f = slnt().SetItalic(true)
+	v, _ = f.Variation("slnt")
+	require.Equal(t, -10.0, v)
+	require.Zero(t, sheared(f))
+	f = font(testAxis{"ital", 0, 0, 1}).SetItalic(true)
+	v, _ = f.Variation("ital")
+	require.Equal(t, 1.0, v)
+	require.Zero(t, sheared(f))
+	require.InDelta(t, 12, sheared(font(testAxis{"wght", 100, 400, 900}).SetItalic(true)), 1e-6)
+
+	// Static faces are sheared by the full angle.
+	static := render.MustLoadFont("testdata/montserrat.ttf", 48)
+	require.InDelta(t, 12, sheared(static.SetSlant(12)), 1e-6)
+	require.Zero(t, sheared(static.SetSlant(0)))
+}
+
+func FuzzVariableFontTables(f *testing.F) {
+	glyphs := []testGlyph{{adv: 500}, {r: 'a', adv: 500, x0: 50, x1: 450, y1: 500, class: 1}}
+	fvar := fvarTable(testAxis{"wght", 100, 400, 900})
+	gvar := gvarTable(1, 2, map[int][]testTuple{1: {
+		{peak: []float64{1}, points: []uint16{0, 1, 5}, dx: []int16{0, 0, 200}, dy: []int16{0, 300, 0}},
+	}})
+	f.Add(fvar, gvar)
+	f.Add(fvar[:12], gvar)
+	f.Add(fvar, gvar[:len(gvar)-3])
+
+	f.Fuzz(func(t *testing.T, fvar, gvar []byte) {
+		font, err := render.LoadFontFromBytes((&testFont{glyphs: glyphs, fvar: fvar, gvar: gvar}).bytes(), 40)
+		if err != nil {
+			return
+		}
+		for _, a := range font.Axes() {
+			font.SetVariation(a.Tag, a.Max)
+		}
+		font.MeasureString("aa")
+		font.DrawString(image.NewRGBA(image.Rect(0, 0, 100, 60)), colors.Black, "aa", 0, 40)
+	})
+}
+
+func TestTextHighlights(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 32)
+	const s = "one two  three four"
+	draw := func(marks func(*instructions.Text)) *instructions.Layer {
+		canvas := newLayer(t, 260, 110)
+		txt := instructions.NewText(s, 10, 10, font).
+			SetMaxWidth(180).
+			SetSolidColor(colors.Black)
+		marks(txt)
+		canvas.LoadInstruction(txt)
+		return canvas
+	}
+	// blueRows reports which of the two text lines contain an opaque blue
+	// pixel.
+	blueRows := func(l *instructions.Layer) (first, second bool) {
+		img := l.Image()
+		b := img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if img.RGBAAt(x, y) == (color.RGBA{B: 255, A: 255}) {
+					if y < 50 {
+						first = true
+					} else {
+						second = true
+					}
+				}
+			}
+		}
+		return first, second
+	}
+
+	plain := draw(func(*instructions.Text) {})
+	empty := draw(func(txt *instructions.Text) { txt.AddHighlight(0, len(s), instructions.NewHighlight()) })
+	require.Equal(t, plain.Image().Pix, empty.Image().Pix, "an empty highlight changes nothing")
+
+	// "two  three" wraps after "two"; the mark follows it onto the next line.
+	bg := draw(func(txt *instructions.Text) {
+		txt.AddHighlightRegexp(regexp.MustCompile(`two\s+three`), instructions.NewHighlight().SetBackgroundColor(colors.Blue))
+	})
+	first, second := blueRows(bg)
+	require.True(t, first)
+	require.True(t, second)
+
+	fill := draw(func(txt *instructions.Text) {
+		txt.AddHighlight(0, 3, instructions.NewHighlight().SetSolidColor(colors.Blue))
+	})
+	first, second = blueRows(fill)
+	require.True(t, first)
+	require.False(t, second)
+
+	require.NoError(t, draw(func(txt *instructions.Text) {
+		txt.AddHighlightRegexp(regexp.MustCompile(`two\s+three`), instructions.NewHighlight().SetBackgroundColor(colors.LightBlue)).
+			AddHighlightRegexp(regexp.MustCompile(`four`), instructions.NewHighlight().
+				SetSolidColor(colors.Blue).
+				SetUnderlineWithColor(colors.Blue, 3))
+	}).Export("./output/text_highlights.png"))
+}
+
+func TestTextDefaultAndNamedFonts(t *testing.T) {
+	def := render.DefaultFont(24)
+	require.Equal(t, 24.0, def.HeightPt())
+	require.Equal(t, 12.0, render.DefaultFont(12).HeightPt(), "copies are independent")
+	w, _ := def.MeasureString("glimo")
+	require.Greater(t, w, 0.0)
+
+	named, err := render.NamedFont(render.DefaultFontName, 24)
+	require.NoError(t, err)
+	nw, _ := named.MeasureString("glimo")
+	require.Equal(t, w, nw)
+
+	_, err = render.NamedFont("montserrat", 24)
+	require.Error(t, err)
+	require.NoError(t, render.RegisterFontsFS(os.DirFS("testdata"), "*.ttf"))
+	mont, err := render.NamedFont("montserrat", 24)
+	require.NoError(t, err)
+	mw, _ := mont.MeasureString("glimo")
+	lw, _ := render.MustLoadFont("testdata/montserrat.ttf", 24).MeasureString("glimo")
+	require.Equal(t, lw, mw)
+
+	canvas := newLayer(t, 200, 50)
+	canvas.LoadInstruction(instructions.NewText("glimo", 10, 10, def).SetSolidColor(colors.Black))
+	require.NoError(t, canvas.Export("./output/text_default_font.png"))
+}
+
+func TestFontRegistry(t *testing.T) {
+	family, weight, italic, err := render.ParseFontSpec("Open Sans semibold italic")
+	require.NoError(t, err)
+	require.Equal(t, "Open Sans", family)
+	require.Equal(t, 600, weight)
+	require.True(t, italic)
+	family, weight, italic, err = render.ParseFontSpec("Gotham Pro")
+	require.NoError(t, err)
+	require.Equal(t, "Gotham Pro", family)
+	require.Equal(t, 400, weight)
+	require.False(t, italic)
+	_, _, _, err = render.ParseFontSpec("Montserrat 2000")
+	require.Error(t, err)
+
+	mont := render.MustLoadFont("testdata/montserrat.ttf", 12)
+	reg := render.NewFontRegistry().
+		AddAs("Montserrat", 400, false, mont).
+		AddAs("Montserrat", 700, false, mont).
+		AddAs("Montserrat", 400, true, mont)
+	require.Equal(t, []string{"Montserrat"}, reg.Families())
+
+	for spec, want := range map[string]int{
+		"montserrat 500": 400, // nothing heavier up to 500, so lighter
+		"Montserrat 600": 700, // above 500 prefers heavier
+		"Montserrat 300": 400, // nothing lighter, so heavier
+		"Montserrat 900": 700,
+	} {
+		f, err := reg.Lookup(spec, 24)
+		require.NoError(t, err, spec)
+		require.Equal(t, want, f.FaceWeight(), spec)
+		require.Equal(t, 24.0, f.HeightPt(), spec)
+	}
+
+	// Only an upright-400 and italic-400 face exist for italic bold, so the
+	// italic face is picked and bold is synthesized.
+	plain := reg.MustLookup("Montserrat italic", 24)
+	bold := reg.MustLookup("Montserrat bold italic", 24)
+	require.True(t, bold.FaceItalic())
+	require.Equal(t, 400, bold.FaceWeight())
+	pw, _ := plain.MeasureString("abc")
+	bw, _ := bold.MeasureString("abc")
+	require.Greater(t, bw, pw)
+
+	_, err = reg.Lookup("Inter 400", 24)
+	require.Error(t, err)
+
+	require.NoError(t, render.DefaultFontRegistry().RegisterFS(os.DirFS("testdata"), "gothampro_bold.ttf"))
+	txt := instructions.NewText("spec", 0, 0, nil).SetFontSpec("Gotham Pro bold", 20)
+	require.NotNil(t, txt.Font())
+	require.Equal(t, "Gotham Pro", txt.Font().Family())
+	require.Nil(t, instructions.NewText("spec", 0, 0, nil).SetFontSpec("Missing", 20).Font())
+}
+
+func TestTextTabsAndIndent(t *testing.T) {
+	mono := render.MustLoadFontFromBytes(gomono.TTF, 20)
+	width := func(txt *instructions.Text) float64 { return txt.Size().Width() }
+
+	// In a monospaced font a tab stop every 4 cells puts "c" in the fifth
+	// cell after "ab", both with and without wrapping.
+	cells, _ := mono.MeasureString("xxxxx")
+	require.InDelta(t, cells, width(instructions.NewText("ab\tc", 0, 0, mono).SetTabWidth(4)), 0.01)
+	tabbed := instructions.NewText("ab\tc", 0, 0, mono).SetTabWidth(4).SetMaxWidth(400)
+	require.InDelta(t, mono.LineHeightPx(), tabbed.Size().Height(), 0.01)
+
+	draw := func(txt *instructions.Text) []uint8 {
+		canvas := newLayer(t, 200, 40)
+		canvas.LoadInstruction(txt.SetSolidColor(colors.Black))
+		return canvas.Image().Pix
+	}
+	wrapped := func(s string) *instructions.Text {
+		return instructions.NewText(s, 0, 0, mono).SetMaxWidth(200)
+	}
+
+	// Without tab stops a tab is a plain word separator.
+	require.Equal(t, draw(wrapped("ab c")), draw(wrapped("ab\t\tc")))
+	require.NotEqual(t, draw(wrapped("ab c")), draw(wrapped("ab\t\tc").SetTabWidth(4)))
+	require.Equal(t, draw(wrapped("x")), draw(wrapped("    x")), "indentation collapses by default")
+	require.NotEqual(t, draw(wrapped("x")), draw(wrapped("    x").SetPreserveIndent(true)))
+	require.Equal(t,
+		draw(wrapped("    x").SetPreserveIndent(true)),
+		draw(wrapped("\tx").SetPreserveIndent(true)), "leading tabs default to 4 spaces")
+
+	canvas := newLayer(t, 360, 120)
+	canvas.LoadInstruction(instructions.NewText("func main() {\n\tfmt.Println(\"hi\")\n\tx :=\t1\n}", 10, 10, mono).
+		SetMaxWidth(340).SetTabWidth(4).SetPreserveIndent(true).SetSolidColor(colors.Black))
+	require.NoError(t, canvas.Export("./output/text_tabs_indent.png"))
+}
+
+func TestTextDecorations(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 32)
+	uo, ut := font.UnderlinePx()
+	require.Greater(t, uo, 0.0, "underline sits below the baseline")
+	require.Greater(t, ut, 0.0)
+	so, st := font.StrikethroughPx()
+	require.Less(t, so, 0.0, "strikethrough sits above the baseline")
+	require.Greater(t, st, 0.0)
+
+	draw := func(s string, style func(*instructions.Text)) *instructions.Layer {
+		canvas := newLayer(t, 240, 110)
+		txt := instructions.NewText(s, 10, 10, font).SetMaxWidth(200).SetSolidColor(colors.Black)
+		style(txt)
+		canvas.LoadInstruction(txt)
+		return canvas
+	}
+	// blueRows returns the rows containing an opaque blue pixel.
+	blueRows := func(l *instructions.Layer) []int {
+		img := l.Image()
+		var rows []int
+		for y := 0; y < img.Bounds().Dy(); y++ {
+			for x := 0; x < img.Bounds().Dx(); x++ {
+				if img.RGBAAt(x, y) == (color.RGBA{B: 255, A: 255}) {
+					rows = append(rows, y)
+					break
+				}
+			}
+		}
+		return rows
+	}
+	blue := colors.Blue.MakeSolidPattern()
+
+	require.Equal(t,
+		draw("plain text", func(*instructions.Text) {}).Image().Pix,
+		draw("plain text", func(txt *instructions.Text) { txt.SetDecoration(false, false) }).Image().Pix)
+
+	// One underline per wrapped line, each just below its baseline.
+	under := blueRows(draw("underline wraps", func(txt *instructions.Text) {
+		txt.SetDecoration(true, false).SetDecorationStyle(2, 0, blue)
+	}))
+	require.Len(t, under, 4, "two 2px underlines")
+	require.Greater(t, under[2]-under[0], 30)
+
+	// Strikethroughs lie above the underlines' rows, overlines above both.
+	strike := blueRows(draw("x", func(txt *instructions.Text) {
+		txt.SetDecoration(false, true).SetDecorationStyle(2, 0, blue)
+	}))
+	over := blueRows(draw("x", func(txt *instructions.Text) {
+		txt.SetOverline(true).SetDecorationStyle(2, 0, blue)
+	}))
+	require.Len(t, strike, 2)
+	require.Len(t, over, 2)
+	require.Less(t, over[0], strike[0])
+	require.Less(t, strike[0], under[0])
+
+	// The offset moves underlines away from the text.
+	moved := blueRows(draw("underline wraps", func(txt *instructions.Text) {
+		txt.SetDecoration(true, false).SetDecorationStyle(2, 3, blue)
+	}))
+	require.Equal(t, under[0]+3, moved[0])
+
+	require.NoError(t, draw("underline and strike through", func(txt *instructions.Text) {
+		txt.SetDecoration(true, true).SetOverline(true)
+	}).Export("./output/text_decorations.png"))
+}
+
+func TestTextLineHighlight(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 28)
+	canvas := newLayer(t, 320, 130)
+	txt := instructions.NewText("Short\nA much longer line", 20, 20, font).
+		SetSolidColor(colors.White).
+		SetHighlight(colors.NewSolidWithBlend(colors.Black, colors.BlendNormal, 0.5), 10, 6, 8)
+	canvas.LoadInstruction(txt)
+	img := canvas.Image()
+
+	// rowExtent returns the first and last column with a visible pixel on row y.
+	rowExtent := func(y int) (int, int) {
+		first, last := -1, -1
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			if img.RGBAAt(x, y).A != 0 {
+				if first < 0 {
+					first = x
+				}
+				last = x
+			}
+		}
+		return first, last
+	}
+
+	lh := font.LineHeightPx()
+	w1, _ := font.MeasureString("Short")
+	w2, _ := font.MeasureString("A much longer line")
+	y1, y2 := 20+int(lh/2), 20+int(lh*1.5)
+	x0, x1 := rowExtent(y1)
+	require.Equal(t, 10, x0, "box starts one padding left of the text")
+	require.InDelta(t, 20+w1+10, float64(x1+1), 1, "box hugs the first line")
+	_, x1 = rowExtent(y2)
+	require.InDelta(t, 20+w2+10, float64(x1+1), 1, "box hugs the second line")
+
+	// Rounded corners leave the box's outer corner empty.
+	require.Zero(t, img.RGBAAt(10, 14).A)
+
+	// The padded boxes overlap between the lines but are filled only once.
+	gap := 20 + int(lh)
+	require.Equal(t, img.RGBAAt(12, gap), img.RGBAAt(12, gap-int(lh/2)+4))
+
+	require.NoError(t, canvas.Export("./output/text_line_highlight.png"))
+}
+
+func TestTextLayout(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 32)
+	require.Empty(t, instructions.NewText("", 0, 0, font).Layout().Lines)
+
+	txt := instructions.NewText("Layout reports\nwhere glyphs go", 300, 10, font).
+		SetAlign(instructions.AlignTextCenter).
+		SetSolidColor(colors.Black)
+	l := txt.Layout()
+	require.Len(t, l.Lines, 2)
+	require.Equal(t, txt.Size().Width(), l.Width)
+	require.Equal(t, txt.Size().Height(), l.Height)
+
+	first, second := l.Lines[0], l.Lines[1]
+	w, _ := font.MeasureString("Layout reports")
+	require.Equal(t, "Layout reports", first.Text)
+	require.InDelta(t, 300-w/2, first.X, 1e-9, "centered on the anchor")
+	require.InDelta(t, w, first.Width, 1e-9)
+	require.Greater(t, second.Y, first.Y)
+	require.Greater(t, first.Baseline, first.Y)
+	require.Less(t, first.Baseline, first.Y+first.Height)
+
+	// Glyphs tile the line and point back into the source text.
+	glyphs := second.Glyphs
+	require.Len(t, glyphs, len("where glyphs go"))
+	require.Equal(t, second.X, glyphs[0].X)
+	require.InDelta(t, second.End(), glyphs[len(glyphs)-1].X+glyphs[len(glyphs)-1].Width, 1e-9)
+	require.Equal(t, "g", glyphs[6].Text)
+	require.Equal(t, len("Layout reports\n")+6, glyphs[6].Offset)
+
+	// A badge placed after the last word lands right of the drawn glyphs.
+	canvas := newLayer(t, 600, 120)
+	canvas.LoadInstructions(
+		txt,
+		instructions.NewRectangle(second.End()+6, second.Y+8, 24, second.Height-16).
+			SetFillColor(colors.IndianRed).SetRadius(6),
+	)
+	img := canvas.Image()
+	lastInk := 0
+	for y := int(second.Y); y < int(second.Y+second.Height); y++ {
+		for x := 0; x < int(second.End()+4); x++ {
+			if img.RGBAAt(x, y).A != 0 && x > lastInk {
+				lastInk = x
+			}
+		}
+	}
+	require.InDelta(t, second.End(), float64(lastInk), 4)
+	require.NoError(t, canvas.Export("./output/text_layout.png"))
+}
+
+func TestTextInlineObjects(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 32)
+	coin := image.NewRGBA(image.Rect(0, 0, 24, 24))
+	for i := range coin.Pix {
+		coin.Pix[i] = []uint8{255, 200, 0, 255}[i%4]
+	}
+	gold := color.RGBA{R: 255, G: 200, A: 255}
+
+	plain := instructions.NewText(" 1,250 coins", 0, 0, font).Size().Width()
+	txt := instructions.NewText("\uE000 1,250 coins", 10, 10, font).
+		SetSolidColor(colors.Black).
+		AddInline('\uE000', instructions.NewInlineImage(coin, 24, 24))
+	require.InDelta(t, plain+24, txt.Size().Width(), 1e-9, "the object advances like a glyph")
+
+	l := txt.Layout()
+	require.Len(t, l.Lines, 1)
+	slot := l.Lines[0].Glyphs[0]
+	require.InDelta(t, 24, slot.Width, 1e-9)
+
+	canvas := newLayer(t, 320, 140)
+	canvas.LoadInstruction(txt)
+	img := canvas.Image()
+	base := int(l.Lines[0].Baseline)
+	require.Equal(t, gold, img.RGBAAt(int(slot.X)+1, base-1), "the object rests on the baseline")
+	require.Equal(t, gold, img.RGBAAt(int(slot.X)+22, base-23))
+	require.Zero(t, img.RGBAAt(int(slot.X)+12, base+1).A)
+	require.Empty(t, font.MissingRunes())
+
+	// Objects wrap with the words around them and can be shifted.
+	wrapped := instructions.NewText("Earn 20 \uE000 daily", 10, 60, font).
+		SetMaxWidth(140).
+		SetSolidColor(colors.Black).
+		AddInline('\uE000', instructions.NewInlineImage(coin, 24, 24).SetBaselineShift(4))
+	wl := wrapped.Layout()
+	require.Len(t, wl.Lines, 2)
+	require.Equal(t, "\uE000 daily", wl.Lines[1].Text)
+	canvas.LoadInstruction(wrapped)
+	require.Equal(t, gold, img.RGBAAt(11, int(wl.Lines[1].Baseline)+3))
+
+	require.NoError(t, canvas.Export("./output/text_inline_objects.png"))
+}
+
+func TestTextHyphenation(t *testing.T) {
+	// The patterns Liang's thesis uses to hyphenate its own subject.
+	h := render.NewHyphenator(
+		[]string{"hy3ph", "he2n", "hena4", "hen5at", "1na", "n2at", "1tio", "2io", "o2n"},
+		[]string{"ta-ble"},
+	)
+	require.Equal(t, []int{2, 6}, h.Points("hyphenation"))
+	require.Equal(t, "hy-phen-ation", h.Hyphenate("hyphenation", "-"))
+	require.Equal(t, "Hy-phen-ation, ta-ble!", h.Hyphenate("Hyphenation, table!", "-"))
+	require.Equal(t, []int{2}, h.Points("Table"), "exceptions override patterns")
+	strict := render.NewHyphenator([]string{"hy3ph", "he2n", "hena4", "hen5at", "1na", "n2at", "1tio", "2io", "o2n"}, nil)
+	require.Equal(t, "hyphenation", strict.SetMinima(3, 6).Hyphenate("hyphenation", "-"))
+
+	parsed, err := render.ParseHyphenator(strings.NewReader(
+		"% sample\n\\patterns{\nhy3ph he2n hena4 hen5at\n1na n2at 1tio 2io o2n\n}\n\\hyphenation{ta-ble}\n"))
+	require.NoError(t, err)
+	require.Equal(t, "hy-phen-ation", parsed.Hyphenate("hyphenation", "-"))
+	_, err = render.ParseHyphenator(strings.NewReader("% nothing here\n"))
+	require.Error(t, err)
+
+	font := render.MustLoadFont("testdata/montserrat.ttf", 32)
+	width, _ := font.MeasureString("hyphena-")
+	lines := func(txt *instructions.Text) []string {
+		var out []string
+		for _, l := range txt.Layout().Lines {
+			out = append(out, l.Text)
+		}
+		return out
+	}
+	newText := func() *instructions.Text {
+		return instructions.NewText("hyphenation", 10, 10, font).SetMaxWidth(width + 1).SetSolidColor(colors.Black)
+	}
+	require.Equal(t, []string{"hyphena-", "tion"}, lines(newText()), "grapheme fallback")
+
+	hyphenated := newText().SetHyphenator(h)
+	require.Equal(t, []string{"hyphen-", "ation"}, lines(hyphenated))
+
+	render.RegisterHyphenator("en", h)
+	require.Equal(t, []string{"hyphen-", "ation"}, lines(newText().SetHyphenation("en-GB")))
+	require.Equal(t, []string{"hyphena-", "tion"}, lines(newText().SetHyphenation("de")))
+
+	canvas := newLayer(t, 220, 110)
+	canvas.LoadInstruction(hyphenated)
+	require.NoError(t, canvas.Export("./output/text_hyphenation.png"))
+}
+
+func TestTextLetterAndWordSpacing(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 32)
+	width := func(txt *instructions.Text) float64 { return txt.Size().Width() }
+
+	plain := width(instructions.NewText("two words", 0, 0, font))
+	tracked := instructions.NewText("two words", 0, 0, font).SetLetterSpacing(10)
+	spaced := instructions.NewText("two words", 0, 0, font).SetWordSpacing(50)
+
+	// 9 runes give 8 gaps of 10% of 32px; one space gets 50% of 32px.
+	require.InDelta(t, plain+8*3.2, width(tracked), 0.1)
+	require.InDelta(t, plain+16, width(spaced), 0.1)
+	require.Zero(t, font.LetterSpacingPercent(), "the shared font is untouched")
+	require.Zero(t, font.WordSpacingPercent())
+	require.Equal(t, plain, width(instructions.NewText("two words", 0, 0, font)))
+
+	// Overrides win over the font's own spacing, including a zero override.
+	loose := render.MustLoadFont("testdata/montserrat.ttf", 32).SetLetterSpacingPercent(10)
+	require.InDelta(t, plain, width(instructions.NewText("two words", 0, 0, loose).SetLetterSpacing(0)), 0.01)
+
+	// Drawing advances exactly as measured.
+	dst := image.NewRGBA(image.Rect(0, 0, 400, 60))
+	f := render.MustLoadFont("testdata/montserrat.ttf", 32).SetWordSpacingPercent(50)
+	dot := f.DrawString(dst, colors.Black, "two words", 0, 40)
+	w, _ := f.MeasureString("two words")
+	require.InDelta(t, w, float64(dot.X)/64, 1.0/64)
+	f.SetShaping(false)
+	w, _ = f.MeasureString("two words")
+	dot = f.DrawString(dst, colors.Black, "two words", 0, 40)
+	require.InDelta(t, w, float64(dot.X)/64, 1)
+
+	canvas := newLayer(t, 420, 160)
+	canvas.LoadInstructions(
+		instructions.NewText("SHARED FONT", 10, 10, font).SetSolidColor(colors.Black),
+		instructions.NewText("SHARED FONT", 10, 60, font).SetLetterSpacing(25).SetSolidColor(colors.Black),
+		instructions.NewText("SHARED FONT", 10, 110, font).SetWordSpacing(100).SetSolidColor(colors.Black),
+	)
+	require.NoError(t, canvas.Export("./output/text_letter_word_spacing.png"))
+}
+
+func TestTextHintingAndSubpixel(t *testing.T) {
+	// partial counts pixels with fractional coverage: blur at glyph edges.
+	partial := func(f *render.Font, s string) int {
+		dst := image.NewRGBA(image.Rect(0, 0, 200, 30))
+		f.DrawString(dst, colors.Black, s, 2, 20)
+		n := 0
+		for i := 3; i < len(dst.Pix); i += 4 {
+			if a := dst.Pix[i]; a > 0 && a < 255 {
+				n++
+			}
+		}
+		return n
+	}
+	plain := render.MustLoadFont("testdata/montserrat.ttf", 11)
+	vertical := render.MustLoadFont("testdata/montserrat.ttf", 11).SetHinting(render.HintingVertical)
+	full := render.MustLoadFont("testdata/montserrat.ttf", 11).SetHinting(render.HintingFull)
+	require.Equal(t, render.HintingNone, plain.Hinting())
+	require.Less(t, partial(vertical, "EFHTLZ"), partial(plain, "EFHTLZ"))
+	require.Less(t, partial(full, "lmnhi"), partial(plain, "lmnhi"))
+
+	// Full hinting rounds every advance to whole pixels.
+	w, _ := full.MeasureString("table 1.25")
+	require.Equal(t, math.Round(w), w)
+
+	// Without subpixel positioning the line snaps to the pixel grid.
+	dst := image.NewRGBA(image.Rect(0, 0, 200, 30))
+	w, _ = plain.MeasureString("table")
+	require.Equal(t, 11+w, float64(plain.DrawString(dst, colors.Black, "table", 10.5, 20).X)/64)
+	plain.SetSubpixelPositioning(true)
+	require.True(t, plain.SubpixelPositioning())
+	require.InDelta(t, 10.5+w, float64(plain.DrawString(dst, colors.Black, "table", 10.5, 20).X)/64, 1.0/64)
+
+	// Text at a fractional x moves by that fraction instead of a whole pixel.
+	inkX := func(f *render.Font, x float64) float64 {
+		l := newLayer(t, 80, 30)
+		l.LoadInstructions(instructions.NewText("l", x, 5, f).SetSolidColor(colors.Black))
+		img := l.Image()
+		var sum, weight float64
+		for y := 0; y < 30; y++ {
+			for px := 0; px < 80; px++ {
+				a := float64(img.RGBAAt(px, y).A)
+				sum += a * float64(px)
+				weight += a
+			}
+		}
+		return sum / weight
+	}
+	require.InDelta(t, 0.5, inkX(plain, 10.5)-inkX(plain, 10), 0.1)
+
+	canvas := newLayer(t, 320, 80)
+	canvas.LoadInstructions(
+		instructions.NewText("Qty 12 · 4.50 · Total 54.00", 6, 6, render.MustLoadFont("testdata/montserrat.ttf", 11)).SetSolidColor(colors.Black),
+		instructions.NewText("Qty 12 · 4.50 · Total 54.00", 6, 30, vertical).SetSolidColor(colors.Black),
+		instructions.NewText("Qty 12 · 4.50 · Total 54.00", 6, 54, full).SetSolidColor(colors.Black),
+	)
+	require.NoError(t, canvas.Export("./output/text_hinting.png"))
+}
+
+func TestTextLayoutCache(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 20)
+	txt := instructions.NewText("the quick brown fox jumps over the lazy dog", 0, 0, font).SetMaxWidth(200)
+	size := txt.Size()
+	require.Equal(t, size, instructions.MeasureText(txt.Text(), font, 200))
+	require.Equal(t, size, txt.Size(), "repeated measurement is stable")
+	lines := len(txt.Layout().Lines)
+
+	// Setters invalidate the cached wrap.
+	txt.SetMaxWidth(120)
+	require.Greater(t, len(txt.Layout().Lines), lines)
+	txt.SetText("short")
+	require.Len(t, txt.Layout().Lines, 1)
+
+	// Resizing the shared font in place is noticed without invalidation.
+	shared := render.MustLoadFont("testdata/montserrat.ttf", 20)
+	txt = instructions.NewText("the quick brown fox", 0, 0, shared).SetMaxWidth(240)
+	require.Len(t, txt.Layout().Lines, 1)
+	shared.SetFontSizePt(40)
+	require.Greater(t, len(txt.Layout().Lines), 1)
+
+	// Other in-place font changes need an explicit InvalidateLayout.
+	shared.SetFontSizePt(20)
+	require.Len(t, txt.Layout().Lines, 1)
+	shared.SetLetterSpacingPercent(40)
+	require.Len(t, txt.Layout().Lines, 1)
+	require.Greater(t, len(txt.InvalidateLayout().Layout().Lines), 1)
+
+	// The cached size follows every setter that changes it.
+	txt = instructions.NewText("one\ntwo", 0, 0, font)
+	h := txt.Size().Height()
+	require.Greater(t, txt.SetLineSpacing(200).Size().Height(), h)
+	require.Less(t, txt.SetText("one").Size().Height(), h)
+}
+
+func TestTextMaskCache(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 24)
+	a := font.StringMask("#1 player", 2, 20, 140, 30)
+	require.Same(t, a, font.StringMask("#1 player", 2, 20, 140, 30), "repeated strings reuse the mask")
+	require.NotSame(t, a, font.StringMask("#2 player", 2, 20, 140, 30))
+	require.NotSame(t, a, font.StringMask("#1 player", 2.5, 20, 140, 30))
+
+	// Any setting that changes the drawing changes the mask.
+	bold := render.MustLoadFont("testdata/montserrat.ttf", 24).SetSyntheticBold(0.03)
+	require.NotEqual(t, a.Pix, bold.StringMask("#1 player", 2, 20, 140, 30).Pix)
+	same := render.MustLoadFont("testdata/montserrat.ttf", 24)
+	require.NotSame(t, a, same.StringMask("#1 player", 2, 20, 140, 30), "faces are told apart")
+
+	// The cached mask matches a fresh rasterization.
+	fresh := image.NewRGBA(image.Rect(0, 0, 140, 30))
+	font.DrawString(fresh, colors.Black, "#1 player", 2, 20)
+	require.Equal(t, fresh.Pix, a.Pix)
+
+	// Repeated draws of a text render identically, and thresholding an
+	// aliased draw leaves the shared mask intact.
+	draw := func(aa bool) []byte {
+		l := newLayer(t, 200, 40)
+		l.LoadInstructions(instructions.NewText("#1 player", 4, 4, font).SetSolidColor(colors.Black).SetAntiAlias(aa))
+		return l.Image().Pix
+	}
+	first := draw(true)
+	require.NotEqual(t, first, draw(false))
+	require.Equal(t, first, draw(true))
+
+	render.SetMaskCacheCapacity(0)
+	defer render.SetMaskCacheCapacity(256)
+	require.NotSame(t, a, font.StringMask("#1 player", 2, 20, 140, 30))
+	require.Equal(t, first, draw(true))
+}
+
+func TestTextWordMaskCache(t *testing.T) {
+	render.SetWordMaskCacheCapacity(64)
+	defer render.SetWordMaskCacheCapacity(0)
+	defer render.ClearMaskCache()
+
+	// Strings assembled from cached words match a fresh rasterization,
+	// kerning across the spaces and fractional pen positions included.
+	fonts := []*render.Font{
+		render.MustLoadFont("testdata/montserrat.ttf", 24),
+		render.MustLoadFont("testdata/montserrat.ttf", 17).SetLetterSpacingPercent(7).SetSubpixelPositioning(true),
+		render.MustLoadFont("testdata/montserrat.ttf", 20).SetSyntheticItalic(12),
+	}
+	for _, f := range fonts {
+		for _, s := range []string{"Score: 1024 pts AV", "Score: 2048 pts AV", "To  AV office"} {
+			got := f.StringMask(s, 3.25, 24, 320, 34)
+			want := image.NewRGBA(image.Rect(0, 0, 320, 34))
+			f.DrawString(want, colors.Black, s, 3.25, 24)
+			require.Equal(t, want.Pix, got.Pix, s)
+		}
+	}
+
+	// Text renders identically with and without word caching.
+	font := render.MustLoadFont("testdata/montserrat.ttf", 11)
+	paint := func() []byte {
+		l := newLayer(t, 220, 40)
+		l.LoadInstructions(instructions.NewText("Total: 1 234 items", 4, 4, font).
+			SetSolidColor(colors.Black).SetStrokeWithColor(colors.Red, 1))
+		return l.Image().Pix
+	}
+	cached := paint()
+	render.SetWordMaskCacheCapacity(0)
+	render.ClearMaskCache()
+	require.Equal(t, paint(), cached)
+}
+
+func TestTextOutlineStroke(t *testing.T) {
+	font := render.MustLoadFont("testdata/montserrat.ttf", 96)
+	draw := func(q instructions.StrokeQuality, f *render.Font) *image.RGBA {
+		l := newLayer(t, 360, 160)
+		l.LoadInstructions(instructions.NewText("Og", 30, 20, f).
+			SetSolidColor(colors.White).
+			SetStrokeWithColor(colors.Red, 12).
+			SetStrokeQuality(q))
+		return l.Image()
+	}
+	stroked := func(img *image.RGBA) (n int) {
+		for i := 0; i < len(img.Pix); i += 4 {
+			if img.Pix[i] > 128 && img.Pix[i+1] < 128 {
+				n++
+			}
+		}
+		return n
+	}
+
+	dilated := draw(instructions.StrokeQualityDilate, font)
+	outlined := draw(instructions.StrokeQualityOutline, font)
+	require.Equal(t, outlined.Pix, draw(instructions.StrokeQualityAuto, font).Pix, "wide strokes use outlines by default")
+
+	// Both build the same band around the glyphs.
+	d, o := stroked(dilated), stroked(outlined)
+	require.Greater(t, d, 1000)
+	require.InEpsilon(t, d, o, 0.05)
+	require.Equal(t, dilated.RGBAAt(0, 0), outlined.RGBAAt(0, 0))
+
+	// Synthetic bold is outlined too, with the same band as dilation.
+	bold := func() *render.Font { return render.MustLoadFont("testdata/montserrat.ttf", 96).SetSyntheticBold(0.03) }
+	require.InEpsilon(t, stroked(draw(instructions.StrokeQualityDilate, bold())), stroked(draw(instructions.StrokeQualityOutline, bold())), 0.05)
+
+	// Unshaped fonts have no outlines and fall back to dilation.
+	unshaped := func() *render.Font { return render.MustLoadFont("testdata/montserrat.ttf", 96).SetShaping(false) }
+	require.Equal(t, draw(instructions.StrokeQualityDilate, unshaped()).Pix, draw(instructions.StrokeQualityOutline, unshaped()).Pix)
+
+	require.NoError(t, instructions.NewLayerFromRGBA(outlined).Export("./output/text_outline_stroke.png"))
+}
+
+func TestFormatMessage(t *testing.T) {
+	members := "{n, plural, =0 {No members} one {# member} other {# members}}"
+	cases := []struct {
+		locale, msg string
+		args        map[string]any
+		want        string
+	}{
+		{"en", members, map[string]any{"n": 0}, "No members"},
+		{"en", members, map[string]any{"n": 1}, "1 member"},
+		{"en", members, map[string]any{"n": 3.0}, "3 members"},
+		{"en", members, map[string]any{"n": "1.5"}, "1.5 members"},
+		{"ru", "{n, plural, one {# участник} few {# участника} many {# участников} other {# участника}}",
+			map[string]any{"n": 22}, "22 участника"},
+		{"ru", "{n, plural, one {# участник} few {# участника} many {# участников} other {# участника}}",
+			map[string]any{"n": 11}, "11 участников"},
+		{"en", "{place, selectordinal, one {#st} two {#nd} few {#rd} other {#th}} place",
+			map[string]any{"place": 23}, "23rd place"},
+		{"en", "{who} liked {gender, select, female {her} male {his} other {their}} post",
+			map[string]any{"who": "Ana", "gender": "female"}, "Ana liked her post"},
+		{"en", "{who}{n, plural, offset:1 =0 {} =1 {} one { and # other} other { and # others}}",
+			map[string]any{"who": "Ana", "n": 3}, "Ana and 2 others"},
+		{"en", "'{literal}' isn''t # {n, number}", map[string]any{"n": 2}, "{literal} isn't # 2"},
+		// Unchosen branches may reference missing arguments.
+		{"", "{n, plural, one {{missing}} other {#}}", map[string]any{"n": 7}, "7"},
+	}
+	for _, c := range cases {
+		got, err := render.FormatMessage(c.locale, c.msg, c.args)
+		require.NoError(t, err, c.msg)
+		require.Equal(t, c.want, got)
+	}
+
+	for _, msg := range []string{"{n", "n}", "{n, plural, one {#}}", "{n, date}", "{missing}"} {
+		_, err := render.FormatMessage("en", msg, map[string]any{"n": 1})
+		require.Error(t, err, msg)
+	}
+}
@@ -4,12 +4,12 @@ import (
 	"image"
 	"math"
 
-	"github.com/Krispeckt/glimo/colors"
 	"github.com/Krispeckt/glimo/effects"
 	"github.com/Krispeckt/glimo/internal/containers"
 	"github.com/Krispeckt/glimo/internal/core/geom"
-	"github.com/Krispeckt/glimo/internal/core/image/patterns"
+	imageUtil "github.com/Krispeckt/glimo/internal/core/image"
 	"github.com/Krispeckt/glimo/internal/render"
+	"github.com/Krispeckt/glimo/patterns"
 	xdraw "golang.org/x/image/draw"
 )
 
@@ -33,6 +33,11 @@ const (
 	AlignTextCenter
 	// AlignTextRight aligns text to the right edge.
 	AlignTextRight
+	// AlignTextStart aligns text to the edge its paragraph starts from: left
+	// for left-to-right paragraphs, right for right-to-left ones.
+	AlignTextStart
+	// AlignTextEnd aligns text to the edge opposite AlignTextStart.
+	AlignTextEnd
 	// todo: AlignTextJustify — not implemented yet.
 )
 
@@ -40,30 +45,68 @@ const (
 // scaling, and applying stroke/fill effects.
 //
 // Features include:
-//   - Word or symbol wrapping with optional hyphenation.
-//   - Left/center/right alignment within fixed width or anchor-based layout.
+//   - Word or symbol wrapping with optional hyphenation, by pattern
+//     dictionary in word mode.
+//   - Tab stops and preserved leading indentation.
+//   - Underline, strikethrough and overline placed from font metrics.
+//   - Rounded per-line background boxes for caption-style highlights.
+//   - Inline images and shapes that wrap and align like glyphs.
+//   - Layout inspection of line boxes, baselines and glyph positions.
+//   - Left/center/right alignment within fixed width or anchor-based layout,
+//     optionally overridden per paragraph.
+//   - Bidirectional (UAX #9) ordering of mixed right-to-left and
+//     left-to-right text, with direction-aware start/end alignment.
 //   - Progressive per-line scaling for dynamic typography.
 //   - Automatic or manual line spacing.
 //   - Pattern or gradient fill based on canvas coordinates.
-//   - Morphological stroke expansion using alpha dilation.
+//   - Optional light/dark fill selection and scrim over busy backgrounds.
+//   - Stroke expansion by alpha dilation, or by stroking glyph outlines.
 //   - Pre- and post-processing effects via a flexible effect container.
 type Text struct {
-	text         string
-	x, y         float64
-	font         *render.Font
-	colorPattern patterns.Pattern
-	maxWidth     float64
-	lineSpacing  float64
-	align        AlignText
-	wrapMode     WrapMode
-	wrapSymbol   string
-	maxLines     int
-	scaleStep    float64
+	text           string
+	x, y           float64
+	font           *render.Font
+	colorPattern   patterns.Pattern
+	maxWidth       float64
+	lineSpacing    float64
+	align          AlignText
+	paraAlign      map[int]AlignText
+	direction      TextDirection
+	wrapMode       WrapMode
+	wrapSymbol     string
+	tabWidth       int
+	hyphenator     *render.Hyphenator
+	hyphenLang     string
+	preserveIndent bool
+	maxLines       int
+	scaleStep      float64
+	letterSpacing  *float64
+	wordSpacing    *float64
 
 	strokePatternColor patterns.Pattern
 	strokeWidth        float64
-
-	effects containers.Effects
+	strokeQuality      StrokeQuality
+
+	autoContrast *AutoContrast
+	aliased      bool
+	accurate     bool // see Layer.SetAccurateBlending; set by scaled
+	marks        []textMark
+	decoration   textDecoration
+	background   lineBackground
+	inline       map[rune]*InlineObject
+
+	wrap     wrapCache
+	effects  containers.Effects
+	volatile bool
+
+	overflow       *OverflowPolicy
+	overflowReport *OverflowReport
+	fitting        bool // guards fitOverflow against reentry
+
+	shapeTransform
+	shapeOpacity
+	shapeID
+	configErrors
 }
 
 // NewText constructs a Text instance with default configuration.
@@ -92,15 +135,90 @@ func NewText(text string, x, y float64, font *render.Font) *Text {
 	}
 }
 
+// SetText replaces the text content.
+func (t *Text) SetText(text string) *Text {
+	t.text = text
+	t.InvalidateLayout()
+	return t
+}
+
+// Text returns the current text content.
+func (t *Text) Text() string { return t.text }
+
+// SetVolatile marks text that changes between frames, such as a clock or a
+// counter. Layers retaining it (see Layer.Retain) repaint it on every
+// Rerender without an explicit Invalidate, touching only its old and new
+// area.
+func (t *Text) SetVolatile(on bool) *Text {
+	t.volatile = on
+	return t
+}
+
+// Volatile reports whether the text is marked volatile.
+func (t *Text) Volatile() bool { return t.volatile }
+
+// SetFont replaces the font.
+func (t *Text) SetFont(f *render.Font) *Text {
+	t.check(f != nil, "Text", "SetFont", "nil font")
+	t.font = f
+	t.InvalidateLayout()
+	t.checkOutlined("SetFont")
+	return t
+}
+
+// Font returns the current font.
+func (t *Text) Font() *render.Font { return t.font }
+
+// SetFontSpec selects a font from the default font registry by description,
+// such as "Montserrat 600 italic", at sizePt (see render.FontRegistry.Lookup).
+// When nothing matches, the font is left unchanged and the error is recorded
+// on the Text (see Err); use the registry directly to handle it instead.
+func (t *Text) SetFontSpec(spec string, sizePt float64) *Text {
+	f, err := render.DefaultFontRegistry().Lookup(spec, sizePt)
+	if err != nil {
+		t.check(false, "Text", "SetFontSpec", err.Error()+"; font unchanged")
+		return t
+	}
+	t.font = f
+	t.InvalidateLayout()
+	t.checkOutlined("SetFontSpec")
+	return t
+}
+
 // SetAlign configures horizontal line alignment.
 func (t *Text) SetAlign(a AlignText) *Text {
 	t.align = a
 	return t
 }
 
+// SetParagraphAlign overrides alignment for a single paragraph of the text.
+// Paragraphs are the zero-based segments separated by '\n' in the source text;
+// wrapped lines inherit the alignment of the paragraph they belong to.
+// Paragraphs without an override use the block alignment set by SetAlign.
+func (t *Text) SetParagraphAlign(index int, a AlignText) *Text {
+	if index < 0 {
+		return t
+	}
+	if t.paraAlign == nil {
+		t.paraAlign = make(map[int]AlignText)
+	}
+	t.paraAlign[index] = a
+	return t
+}
+
+// SetDirection sets the base direction of the text's paragraphs. Lines are
+// always wrapped in logical order and reordered for display; the direction
+// decides how mixed runs are arranged and which edge AlignTextStart and
+// AlignTextEnd refer to. DirectionAuto (the default) detects it per paragraph.
+func (t *Text) SetDirection(d TextDirection) *Text {
+	t.direction = d
+	return t
+}
+
 // SetLineSpacing defines custom spacing as a percentage of line height.
 func (t *Text) SetLineSpacing(percent float64) *Text {
 	t.lineSpacing = percent / 100.0
+	t.InvalidateLayout()
 	return t
 }
 
@@ -114,6 +232,7 @@ func (t *Text) SetWrap(mode WrapMode, symbol string) *Text {
 // SetWrapMode changes wrapping behavior without modifying the hyphenation symbol.
 func (t *Text) SetWrapMode(mode WrapMode) *Text {
 	t.wrapMode = mode
+	t.InvalidateLayout()
 	return t
 }
 
@@ -125,19 +244,44 @@ func (t *Text) SetWrapSymbol(sym string) *Text {
 	} else {
 		t.wrapSymbol = sym
 	}
+	t.InvalidateLayout()
+	return t
+}
+
+// SetTabWidth sets tab stops every n space widths, so tabs align columns
+// instead of acting as word separators. Stops are measured from the start of
+// each paragraph. Zero (the default) keeps tabs as plain separators.
+func (t *Text) SetTabWidth(n int) *Text {
+	t.check(n >= 0, "Text", "SetTabWidth", "negative width clamped to 0")
+	t.tabWidth = max(n, 0)
+	t.InvalidateLayout()
+	return t
+}
+
+// SetPreserveIndent keeps the leading spaces and tabs of each paragraph
+// instead of collapsing them, so indented code and verse render as authored.
+// Leading tabs use the tab width, or 4 spaces when none is set. Only the
+// first line of a wrapped paragraph is indented.
+func (t *Text) SetPreserveIndent(preserve bool) *Text {
+	t.preserveIndent = preserve
+	t.InvalidateLayout()
 	return t
 }
 
 // SetMaxWidth limits the maximum text box width in pixels.
 // A value of 0 disables wrapping and aligns relative to anchor coordinates.
 func (t *Text) SetMaxWidth(w float64) *Text {
+	t.check(w >= 0, "Text", "SetMaxWidth", "negative width clamped to 0")
 	t.maxWidth = math.Max(w, 0)
+	t.InvalidateLayout()
 	return t
 }
 
 // SetMaxLines limits the number of rendered lines. Zero means no limit.
 func (t *Text) SetMaxLines(n int) *Text {
+	t.check(n >= 0, "Text", "SetMaxLines", "negative line count")
 	t.maxLines = n
+	t.InvalidateLayout()
 	return t
 }
 
@@ -145,11 +289,31 @@ func (t *Text) SetMaxLines(n int) *Text {
 // Positive values enlarge text progressively; negative values shrink it.
 func (t *Text) SetScaleStep(pt float64) *Text {
 	t.scaleStep = pt
+	t.InvalidateLayout()
+	return t
+}
+
+// SetLetterSpacing sets tracking for this text as a percentage of font
+// size, overriding the font's SetLetterSpacingPercent without changing the
+// font, so texts sharing a Font can track differently.
+func (t *Text) SetLetterSpacing(percent float64) *Text {
+	t.letterSpacing = &percent
+	t.InvalidateLayout()
+	return t
+}
+
+// SetWordSpacing adds extra advance after every space for this text as a
+// percentage of font size, overriding the font's SetWordSpacingPercent.
+func (t *Text) SetWordSpacing(percent float64) *Text {
+	t.wordSpacing = &percent
+	t.InvalidateLayout()
 	return t
 }
 
 // SetStrokeWithPattern defines a stroke using a color or gradient pattern.
 func (t *Text) SetStrokeWithPattern(p patterns.Pattern, width float64) *Text {
+	t.check(p != nil, "Text", "SetStrokeWithPattern", "nil pattern")
+	t.check(width >= 0, "Text", "SetStrokeWithPattern", "negative width clamped to 0")
 	t.strokePatternColor = p
 	t.strokeWidth = math.Max(width, 0)
 	return t
@@ -157,6 +321,7 @@ func (t *Text) SetStrokeWithPattern(p patterns.Pattern, width float64) *Text {
 
 // SetStrokeWithColor defines a stroke using a solid color pattern.
 func (t *Text) SetStrokeWithColor(c patterns.Color, width float64) *Text {
+	t.check(width >= 0, "Text", "SetStrokeWithColor", "negative width clamped to 0")
 	t.strokePatternColor = c.MakeSolidPattern()
 	t.strokeWidth = math.Max(width, 0)
 	return t
@@ -164,6 +329,7 @@ func (t *Text) SetStrokeWithColor(c patterns.Color, width float64) *Text {
 
 // SetColorPattern applies a pattern or gradient fill for text glyphs.
 func (t *Text) SetColorPattern(p patterns.Pattern) *Text {
+	t.check(p != nil, "Text", "SetColorPattern", "nil pattern")
 	t.colorPattern = p
 	return t
 }
@@ -174,6 +340,21 @@ func (t *Text) SetSolidColor(c patterns.Color) *Text {
 	return t
 }
 
+// SetAntiAlias enables or disables anti-aliased glyph edges (enabled by
+// default). With anti-aliasing off, glyph coverage is thresholded at 50%.
+func (t *Text) SetAntiAlias(aa bool) *Text {
+	t.aliased = !aa
+	return t
+}
+
+// SetAutoContrast enables automatic fill selection against the pixels under the
+// text block at draw time. The chosen fill replaces the color pattern for that
+// draw only. Passing nil disables it.
+func (t *Text) SetAutoContrast(a *AutoContrast) *Text {
+	t.autoContrast = a
+	return t
+}
+
 // AddEffect adds a single post-processing effect to the text rendering pipeline.
 func (t *Text) AddEffect(e effects.Effect) *Text {
 	t.effects.Add(e)
@@ -186,25 +367,54 @@ func (t *Text) AddEffects(es ...effects.Effect) *Text {
 	return t
 }
 
-// SetPosition updates the anchor coordinates of the text block.
+// SetPosition updates the anchor coordinates of the text block, or moves
+// transformed text so its bounding box starts at (x, y).
 func (t *Text) SetPosition(x, y int) {
-	t.x, t.y = float64(x), float64(y)
+	if !t.drawsTransformed() {
+		t.x, t.y = float64(x), float64(y)
+		return
+	}
+	px, py := t.Position()
+	t.x += float64(x - px)
+	t.y += float64(y - py)
 }
 
-// Position returns the integer coordinates where the text block originates.
-func (t *Text) Position() (int, int) { return int(t.x), int(t.y) }
+// Position returns the integer coordinates where the text block originates,
+// or the top-left of its transformed bounding box.
+func (t *Text) Position() (int, int) {
+	if !t.drawsTransformed() {
+		return int(t.x), int(t.y)
+	}
+	x, y, _, _ := t.transformedBlock()
+	return int(x), int(y)
+}
 
-// Size computes the bounding box of the rendered text.
-// Returns zero if text or font is undefined.
+// Size computes the bounding box of the rendered text, transformed by
+// SetRotation and SetTransform. Returns zero if text or font is undefined.
+// The result is cached with the line wrap (see InvalidateLayout), so
+// repeated calls are cheap.
 func (t *Text) Size() *geom.Size {
+	if !t.drawsTransformed() {
+		return t.blockSize()
+	}
+	_, _, w, h := t.transformedBlock()
+	return geom.NewSize(w, h)
+}
+
+// blockSize returns the size of the unrotated text block.
+func (t *Text) blockSize() *geom.Size {
+	t.fitOverflow()
 	if t.font == nil || t.text == "" {
 		return geom.NewSize(0, 0)
 	}
 
-	lines := t.wrapTextScaled()
+	lines, _ := t.wrapTextScaled()
 	if len(lines) == 0 {
 		return geom.NewSize(0, 0)
 	}
+	if sz := t.wrap.size; sz != nil {
+		return geom.NewSize(sz.Width(), sz.Height())
+	}
 
 	spacing := t.lineSpacing
 	if spacing <= 0 {
@@ -228,17 +438,116 @@ func (t *Text) Size() *geom.Size {
 		width = maxLineWidth
 	}
 
+	t.wrap.size = geom.NewSize(width, totalHeight)
 	return geom.NewSize(width, totalHeight)
 }
 
+// MeasureText returns the size of text drawn with font and wrapped to
+// maxWidth (0 disables wrapping), as Text.Size reports it. It is a shorthand
+// for a throwaway Text; build one to measure with other layout options.
+func MeasureText(text string, font *render.Font, maxWidth float64) *geom.Size {
+	return NewText(text, 0, 0, font).SetMaxWidth(maxWidth).Size()
+}
+
+// drawBounds returns the laid-out line boxes grown by the stroke width, the
+// scrim and line background padding and one font height for glyph overhang. Effects may draw
+// anywhere, so they disable bounds.
+func (t *Text) drawBounds() (image.Rectangle, bool) {
+	if t.effects.Count() > 0 {
+		return image.Rectangle{}, false
+	}
+	if t.font == nil || t.text == "" {
+		return image.Rectangle{}, true
+	}
+	lines, paraOf := t.wrapTextScaled()
+	spacing := t.lineSpacing
+	if spacing <= 0 {
+		spacing = t.autoSpacing(lines)
+	}
+	pad := t.strokeWidth + t.font.HeightPx()
+	if t.autoContrast != nil {
+		pad += float64(t.autoContrast.scrimPadding)
+	}
+	pad += math.Max(t.background.padX, t.background.padY)
+	r := t.textBounds(lines, paraOf, spacing)
+	if t.drawsTransformed() {
+		r = t.transformedBounds(r)
+	}
+	return r.Inset(-int(math.Ceil(pad))), true
+}
+
+// scaled returns a copy laid out for a device scale of s. Coordinates, wrap
+// width and stroke width are multiplied by s; the font keeps its point size
+// and has its DPI multiplied instead, so per-line scale steps stay in points.
+func (t *Text) scaled(d device) Shape {
+	s := d.scale
+	// The policy fits the template in logical pixels; the copy keeps the result.
+	t.fitOverflow()
+	c := *t
+	c.overflow = nil
+	if t.font != nil {
+		f := *t.font
+		f.SetDPI(t.font.DPI() * s)
+		c.font = &f
+	}
+	c.x, c.y = t.x*s, t.y*s
+	c.shapeTransform = t.scaledBy(s)
+	c.maxWidth = t.maxWidth * s
+	c.wrap = wrapCache{}
+	c.strokeWidth = t.strokeWidth * s
+	c.accurate = d.accurate
+	c.colorPattern = d.pattern(t.colorPattern)
+	c.strokePatternColor = d.pattern(t.strokePatternColor)
+	c.decoration.thickness = t.decoration.thickness * s
+	c.decoration.offset = t.decoration.offset * s
+	c.decoration.pattern = d.pattern(t.decoration.pattern)
+	c.background = t.background.scaled(d)
+	if t.inline != nil {
+		c.inline = make(map[rune]*InlineObject, len(t.inline))
+		for r, o := range t.inline {
+			c.inline[r] = o.scaled(d)
+		}
+	}
+	if t.marks != nil {
+		c.marks = make([]textMark, len(t.marks))
+		for i, m := range t.marks {
+			m.h = m.h.scaled(d)
+			c.marks[i] = m
+		}
+	}
+	return &c
+}
+
+// SetID names the text for FindByID.
+func (t *Text) SetID(id string) *Text {
+	t.id = id
+	return t
+}
+
+// SetVisible shows or hides the text without removing it from its
+// container. Hidden shapes keep their place in layouts.
+func (t *Text) SetVisible(v bool) *Text { t.setVisible(v); return t }
+
+// SetOpacity fades the whole text, fill, stroke and effects together, to o
+// in [0, 1]. Values are clamped.
+func (t *Text) SetOpacity(o float64) *Text {
+	t.check(o >= 0 && o <= 1, "Text", "SetOpacity", "opacity outside [0, 1] clamped")
+	t.setOpacity(o)
+	return t
+}
+
 // Draw renders the text block into the given base and overlay images.
 // The method performs optional stroke, fill, and post-processing effects.
 func (t *Text) Draw(base, overlay *image.RGBA) {
+	if t.faded(t, base, overlay, t.Draw) {
+		return
+	}
+	t.fitOverflow()
 	if t.font == nil || t.text == "" {
 		return
 	}
 
-	lines := t.wrapTextScaled()
+	lines, paraOf := t.wrapTextScaled()
 	spacing := t.lineSpacing
 	if spacing <= 0 {
 		spacing = t.autoSpacing(lines)
@@ -246,6 +555,30 @@ func (t *Text) Draw(base, overlay *image.RGBA) {
 
 	t.effects.PreApplyAll(overlay)
 
+	if t.drawsTransformed() && t.drawTransformed(base, overlay, lines, paraOf, spacing) {
+		t.effects.PostApplyAll(overlay)
+		return
+	}
+
+	fill := t.colorPattern
+	if t.autoContrast != nil {
+		base, fill = t.applyAutoContrast(base, overlay, t.textBounds(lines, paraOf, spacing))
+	}
+
+	base = t.drawLineBackgrounds(base, overlay, lines, paraOf, spacing)
+	base, spans := t.layoutHighlights(base, overlay, lines, paraOf, spacing)
+
+	decoFill := t.decoration.pattern
+	if decoFill == nil {
+		decoFill = fill
+	}
+	var strikes []image.Rectangle
+	if t.decoration.any() {
+		var under []image.Rectangle
+		under, strikes = t.decorationRects(lines, paraOf, spacing)
+		base = drawDecorations(base, overlay, under, decoFill, false, t.accurate)
+	}
+
 	yTop := t.y
 	for i, line := range lines {
 		if t.maxLines > 0 && i >= t.maxLines {
@@ -253,22 +586,37 @@ func (t *Text) Draw(base, overlay *image.RGBA) {
 		}
 		lineFont := t.fontForLine(i)
 		w, _ := lineFont.MeasureString(line)
-		x := t.alignX(t.x, w, t.align)
+		x := t.alignX(t.x, w, t.alignForParagraph(paraOf[i]))
+		line = visualLine(line, t.paragraphRTL(paraOf[i]))
 
 		if t.strokePatternColor != nil && t.strokeWidth > 0 {
 			t.drawStroke(base, overlay, lineFont, line, x, yTop)
 		}
-		t.drawProcess(base, overlay, lineFont, line, x, yTop, t.colorPattern)
+		lf := fill
+		if spans != nil {
+			lf = lineFill(fill, spans[i])
+		}
+		t.drawProcess(base, overlay, lineFont, line, x, yTop, lf)
+		if t.inline != nil {
+			t.drawInline(base, overlay, lineFont, lines[i], t.paragraphRTL(paraOf[i]), x, yTop)
+		}
 
 		yTop += lineFont.LineHeightPx() * spacing
 	}
+	drawDecorations(base, overlay, strikes, decoFill, true, t.accurate)
 
 	t.effects.PostApplyAll(overlay)
 }
 
 // ssScale determines supersampling factor based on font pixel height.
-// Small fonts get higher supersampling to minimize aliasing.
-func ssScale(px float64) int {
+// Small fonts get higher supersampling to minimize aliasing. Hinted fonts are
+// never supersampled, as grid fitting at a larger size is lost when the mask
+// is scaled down.
+func ssScale(fnt *render.Font) int {
+	if fnt.Hinting() != render.HintingNone {
+		return 1
+	}
+	px := fnt.HeightPx()
 	if px < 12 {
 		return 3
 	}
@@ -280,16 +628,21 @@ func ssScale(px float64) int {
 
 // drawStroke rasterizes text glyphs, applies morphological dilation to create
 // an outline, and composites it onto the destination using the configured stroke pattern.
+// Wide strokes and StrokeQualityOutline stroke the glyph outlines instead
+// (see drawOutlineStroke).
 //
 // The stroke is computed in supersampled space for accuracy when required.
 func (t *Text) drawStroke(base, overlay *image.RGBA, fnt *render.Font, s string, x, topY float64) {
 	if s == "" || t.strokePatternColor == nil || t.strokeWidth <= 0 {
 		return
 	}
+	if t.useOutlineStroke() && t.drawOutlineStroke(base, overlay, fnt, s, x, topY) {
+		return
+	}
 
 	yq := geom.Quant64(topY)
 	xq := geom.Quant64(x)
-	scale := ssScale(fnt.HeightPx())
+	scale := ssScale(fnt)
 
 	// Prepare font at working scale.
 	ff := *fnt
@@ -298,7 +651,7 @@ func (t *Text) drawStroke(base, overlay *image.RGBA, fnt *render.Font, s string,
 	}
 
 	// Rasterize glyph masks.
-	maskBig, maskSmall, bw, bh, dw, dh := rasterizeGlyphMasks(&ff, s, scale)
+	maskBig, maskSmall, pad, bw, bh, dw, dh := rasterizeGlyphMasks(&ff, s, scale, subpixelX(fnt, xq))
 	if bw <= 0 || bh <= 0 {
 		return
 	}
@@ -315,21 +668,28 @@ func (t *Text) drawStroke(base, overlay *image.RGBA, fnt *render.Font, s string,
 		strokeMask := image.NewRGBA(image.Rect(0, 0, dw+2*r, dh+2*r))
 		xdraw.BiLinear.Scale(strokeMask, strokeMask.Bounds(), strokeHR, strokeHR.Bounds(), xdraw.Over, nil)
 
-		xi := int(math.Floor(xq)) - r
+		if t.aliased {
+			thresholdMask(strokeMask)
+		}
+
+		xi := int(math.Floor(xq)) - r - pad
 		yi := int(math.Floor(yq)) - r
 		dstRect := image.Rect(xi, yi, xi+strokeMask.Bounds().Dx(), yi+strokeMask.Bounds().Dy())
-		compositePatternWithMask(base, overlay, strokeMask, xi, yi, dstRect, t.strokePatternColor)
+		compositePatternWithMask(base, overlay, strokeMask, xi, yi, dstRect, t.strokePatternColor, t.accurate)
 		return
 	}
 
 	// No supersampling: dilate in destination space.
 	strokeMask := dilateAlphaDisk(maskSmall, r)
 	subtractInnerMask(strokeMask, maskSmall, r)
+	if t.aliased {
+		thresholdMask(strokeMask)
+	}
 
-	xi := int(math.Floor(xq)) - r
+	xi := int(math.Floor(xq)) - r - pad
 	yi := int(math.Floor(yq)) - r
 	dstRect := image.Rect(xi, yi, xi+strokeMask.Bounds().Dx(), yi+strokeMask.Bounds().Dy())
-	compositePatternWithMask(base, overlay, strokeMask, xi, yi, dstRect, t.strokePatternColor)
+	compositePatternWithMask(base, overlay, strokeMask, xi, yi, dstRect, t.strokePatternColor, t.accurate)
 }
 
 // drawProcess rasterizes glyphs and composites the fill pattern using alpha coverage.
@@ -338,9 +698,24 @@ func (t *Text) drawProcess(base, overlay *image.RGBA, fnt *render.Font, s string
 		return
 	}
 
-	yq := geom.Quant64(topY)
-	xq := geom.Quant64(x)
-	scale := ssScale(fnt.HeightPx())
+	mask, xi, yi := fillMask(fnt, s, geom.Quant64(x), geom.Quant64(topY))
+	if mask == nil {
+		return
+	}
+	if t.aliased {
+		mask = imageUtil.CropRGBA(mask, mask.Bounds())
+		thresholdMask(mask)
+	}
+	dstRect := image.Rect(xi, yi, xi+mask.Bounds().Dx(), yi+mask.Bounds().Dy())
+	compositePatternWithMask(base, overlay, mask, xi, yi, dstRect, p, t.accurate)
+}
+
+// fillMask rasterizes the glyph coverage of s at the quantized line origin
+// (xq, yq), supersampled as ssScale selects, and returns it with its canvas
+// position. The mask may be shared with the string mask cache and must not
+// be modified. It is nil when s has no extent.
+func fillMask(fnt *render.Font, s string, xq, yq float64) (mask *image.RGBA, xi, yi int) {
+	scale := ssScale(fnt)
 
 	// Prepare font at working scale.
 	ff := *fnt
@@ -349,15 +724,11 @@ func (t *Text) drawProcess(base, overlay *image.RGBA, fnt *render.Font, s string
 	}
 
 	// Rasterize glyph masks.
-	_, maskSmall, bw, bh, dw, dh := rasterizeGlyphMasks(&ff, s, scale)
+	_, maskSmall, pad, bw, bh, _, _ := rasterizeGlyphMasks(&ff, s, scale, subpixelX(fnt, xq))
 	if bw <= 0 || bh <= 0 {
-		return
+		return nil, 0, 0
 	}
-
-	xi := int(math.Floor(xq))
-	yi := int(math.Floor(yq))
-	dstRect := image.Rect(xi, yi, xi+dw, yi+dh)
-	compositePatternWithMask(base, overlay, maskSmall, xi, yi, dstRect, p)
+	return maskSmall, int(math.Floor(xq)) - pad, int(math.Floor(yq))
 }
 
 // alignX computes the horizontal anchor for a line based on alignment and width constraints.
@@ -383,18 +754,44 @@ func (t *Text) alignX(anchorX, lineWidth float64, align AlignText) float64 {
 	}
 }
 
+// alignForParagraph returns the physical alignment for paragraph idx,
+// falling back to the block alignment.
+func (t *Text) alignForParagraph(idx int) AlignText {
+	a, ok := t.paraAlign[idx]
+	if !ok {
+		a = t.align
+	}
+	switch a {
+	case AlignTextStart, AlignTextEnd:
+		if (a == AlignTextStart) == t.paragraphRTL(idx) {
+			return AlignTextRight
+		}
+		return AlignTextLeft
+	}
+	return a
+}
+
 // fontForLine returns a new font instance scaled per line index
-// according to the configured scaleStep.
+// according to the configured scaleStep, with the text's spacing overrides
+// and inline object spacers applied.
 func (t *Text) fontForLine(lineIdx int) *render.Font {
 	fc := *t.font
-	if lineIdx == 0 || t.scaleStep == 0 {
-		return &fc
+	if lineIdx > 0 && t.scaleStep != 0 {
+		newPt := t.font.HeightPt() + t.scaleStep*float64(lineIdx)
+		if newPt < 1 {
+			newPt = 1
+		}
+		fc.SetFontSizePt(newPt)
+	}
+	if t.letterSpacing != nil {
+		fc.SetLetterSpacingPercent(*t.letterSpacing)
 	}
-	newPt := t.font.HeightPt() + t.scaleStep*float64(lineIdx)
-	if newPt < 1 {
-		newPt = 1
+	if t.wordSpacing != nil {
+		fc.SetWordSpacingPercent(*t.wordSpacing)
+	}
+	if t.inline != nil {
+		t.withSpacers(&fc)
 	}
-	fc.SetFontSizePt(newPt)
 	return &fc
 }
 
@@ -405,20 +802,26 @@ func (t *Text) safeRadius() int {
 
 // rasterizeGlyphMasks draws glyphs into an alpha mask at optional supersampled resolution.
 // It returns both high- and low-resolution masks for stroke and fill processing.
-func rasterizeGlyphMasks(ff *render.Font, s string, scale int) (maskBig *image.RGBA, maskSmall *image.RGBA, bw, bh, dw, dh int) {
+// The high-resolution mask comes from the shared string mask cache and must
+// not be modified; at scale 1 it is also the low-resolution one.
+// The text starts pad+fracX destination pixels from the mask's left edge,
+// leaving room for ink that synthetic styles push past the advance.
+func rasterizeGlyphMasks(ff *render.Font, s string, scale int, fracX float64) (maskBig *image.RGBA, maskSmall *image.RGBA, pad, bw, bh, dw, dh int) {
 	w, h := ff.MeasureString(s)
 	descent := ff.DescentPx()
-	bw, bh = int(math.Ceil(w)), int(math.Ceil(h+descent))
+	left, right := ff.OverhangPx()
+	pad = int(math.Ceil(left / float64(scale)))
+	padR := int(math.Ceil(right/float64(scale) + fracX))
+	bw, bh = int(math.Ceil(w))+(pad+padR)*scale, int(math.Ceil(h+descent))
 	if bw <= 0 || bh <= 0 {
-		return nil, nil, bw, bh, 0, 0
+		return nil, nil, pad, bw, bh, 0, 0
 	}
 
-	maskBig = image.NewRGBA(image.Rect(0, 0, bw, bh))
 	baselineY := math.Round(ff.BaselineForTopY(0))
-	_ = ff.DrawString(maskBig, colors.Black, s, 0, baselineY)
+	maskBig = ff.StringMask(s, (float64(pad)+fracX)*float64(scale), baselineY, bw, bh)
 
 	if scale == 1 {
-		return maskBig, maskBig, bw, bh, bw, bh
+		return maskBig, maskBig, pad, bw, bh, bw, bh
 	}
 
 	dw = int(math.Max(math.Round(float64(bw)/float64(scale)), 1))
@@ -426,7 +829,17 @@ func rasterizeGlyphMasks(ff *render.Font, s string, scale int) (maskBig *image.R
 	maskSmall = image.NewRGBA(image.Rect(0, 0, dw, dh))
 	// BiLinear is smoother for alpha masks than CatmullRom here.
 	xdraw.BiLinear.Scale(maskSmall, maskSmall.Bounds(), maskBig, maskBig.Bounds(), xdraw.Over, nil)
-	return maskBig, maskSmall, bw, bh, dw, dh
+	return maskBig, maskSmall, pad, bw, bh, dw, dh
+}
+
+// subpixelX returns the fraction of a pixel by which a line starting at x is
+// drawn right of its whole-pixel mask position; zero unless fnt positions
+// glyphs at subpixel precision.
+func subpixelX(fnt *render.Font, x float64) float64 {
+	if !fnt.SubpixelPositioning() {
+		return 0
+	}
+	return x - math.Floor(x)
 }
 
 // dilateAlphaDisk performs alpha-based morphological expansion by a circular kernel.
@@ -493,18 +906,26 @@ func dilateAlphaDisk(src *image.RGBA, r int) *image.RGBA {
 // subtractInnerMask removes the original glyph area from a dilated mask,
 // leaving only the outer rim for stroke rendering.
 func subtractInnerMask(expanded *image.RGBA, src *image.RGBA, r int) {
-	sb := src.Bounds()
-	W := expanded.Bounds().Dx()
+	subtractMaskAt(expanded, src, r, r)
+}
 
-	for y := 0; y < sb.Dy(); y++ {
-		srow := src.Pix[y*src.Stride : y*src.Stride+sb.Dx()*4]
-		drow := expanded.Pix[(y+r)*expanded.Stride : (y+r)*expanded.Stride+W*4]
-		for x2 := 0; x2 < sb.Dx(); x2++ {
+// subtractMaskAt lowers the alpha of dst by the alpha of src placed with its
+// top-left corner at (ox, oy) in dst, clipped to dst.
+func subtractMaskAt(dst, src *image.RGBA, ox, oy int) {
+	sb := src.Bounds()
+	db := dst.Bounds()
+	x0, x1 := max(0, -ox), min(sb.Dx(), db.Dx()-ox)
+	y0, y1 := max(0, -oy), min(sb.Dy(), db.Dy()-oy)
+
+	for y := y0; y < y1; y++ {
+		srow := src.Pix[y*src.Stride:]
+		drow := dst.Pix[(y+oy)*dst.Stride:]
+		for x2 := x0; x2 < x1; x2++ {
 			sa := srow[x2*4+3]
 			if sa == 0 {
 				continue
 			}
-			ai := &drow[(x2+r)*4+3]
+			ai := &drow[(x2+ox)*4+3]
 			if int(*ai) <= int(sa) {
 				*ai = 0
 			} else {
@@ -513,3 +934,15 @@ func subtractInnerMask(expanded *image.RGBA, src *image.RGBA, r int) {
 		}
 	}
 }
+
+// thresholdMask snaps mask coverage to fully opaque or transparent at 50%,
+// used when anti-aliasing is disabled.
+func thresholdMask(m *image.RGBA) {
+	for i := 0; i < len(m.Pix); i += 4 {
+		v := uint8(0)
+		if m.Pix[i+3] >= 128 {
+			v = 255
+		}
+		m.Pix[i+0], m.Pix[i+1], m.Pix[i+2], m.Pix[i+3] = v, v, v, v
+	}
+}
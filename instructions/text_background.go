@@ -0,0 +1,106 @@
+package instructions
+
+import (
+	"image"
+	"math"
+
+	"github.com/Krispeckt/glimo/internal/render"
+	"github.com/Krispeckt/glimo/patterns"
+)
+
+// lineBackground holds the per-line background box of a Text.
+type lineBackground struct {
+	pattern    patterns.Pattern
+	padX, padY float64
+	radius     float64
+}
+
+// SetHighlight paints a rounded box filled with p behind every drawn line,
+// as tight as the line's measured width and height and grown by paddingX and
+// paddingY pixels, like highlighted captions. Boxes of neighbouring lines
+// that overlap merge into one shape instead of darkening each other. The
+// radius is clamped to half the box size. A nil pattern removes the boxes.
+func (t *Text) SetHighlight(p patterns.Pattern, paddingX, paddingY, radius float64) *Text {
+	t.background = lineBackground{
+		pattern: p,
+		padX:    math.Max(paddingX, 0),
+		padY:    math.Max(paddingY, 0),
+		radius:  math.Max(radius, 0),
+	}
+	return t
+}
+
+// scaled returns a copy of b for a device scale of s.
+func (b lineBackground) scaled(d device) lineBackground {
+	s := d.scale
+	b.pattern = d.pattern(b.pattern)
+	b.padX, b.padY, b.radius = b.padX*s, b.padY*s, b.radius*s
+	return b
+}
+
+// drawLineBackgrounds paints the line boxes set by SetHighlight into overlay
+// and returns the base that later layers of the text should blend against.
+// Without a pattern it returns base.
+func (t *Text) drawLineBackgrounds(base, overlay *image.RGBA, lines []string, paraOf []int, spacing float64) *image.RGBA {
+	bg := t.background
+	if bg.pattern == nil {
+		return base
+	}
+
+	type box struct{ x, y, w, h float64 }
+	var boxes []box
+	var area image.Rectangle
+	yTop := t.y
+	for i, line := range lines {
+		if t.maxLines > 0 && i >= t.maxLines {
+			break
+		}
+		lineFont := t.fontForLine(i)
+		w, _ := lineFont.MeasureString(line)
+		h := lineFont.LineHeightPx()
+		if w > 0 {
+			x := t.alignX(t.x, w, t.alignForParagraph(paraOf[i]))
+			b := box{x - bg.padX, yTop - bg.padY, w + 2*bg.padX, h + 2*bg.padY}
+			boxes = append(boxes, b)
+			area = area.Union(image.Rect(
+				int(math.Floor(b.x)), int(math.Floor(b.y)),
+				int(math.Ceil(b.x+b.w)), int(math.Ceil(b.y+b.h)),
+			))
+		}
+		yTop += h * spacing
+	}
+	area = area.Intersect(base.Bounds()).Intersect(overlay.Bounds())
+	if area.Empty() {
+		return base
+	}
+
+	// Rasterize all boxes as one path so overlaps are covered once.
+	mask := render.GetRGBA(image.Rect(0, 0, area.Dx(), area.Dy()))
+	defer render.PutRGBA(mask)
+	path := NewLine().
+		SetAntiAlias(!t.aliased).
+		SetFillPattern(patterns.Color{R: 255, G: 255, B: 255, A: 255}.MakeSolidPattern())
+	ox, oy := float64(area.Min.X), float64(area.Min.Y)
+	for _, b := range boxes {
+		r := bg.radius
+		addRoundedRectCorners(path, b.x-ox, b.y-oy, b.w, b.h, r, r, r, r, 8)
+	}
+	path.Fill().Draw(mask, mask)
+	path.release()
+
+	compositePatternWithMask(base, overlay, mask, area.Min.X, area.Min.Y, area, bg.pattern, t.accurate)
+
+	// Glyphs blend against base; give them one that includes the boxes.
+	out := image.NewRGBA(base.Bounds())
+	copy(out.Pix, base.Pix)
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			if mask.Pix[mask.PixOffset(x-area.Min.X, y-area.Min.Y)+3] == 0 {
+				continue
+			}
+			o, b := overlay.PixOffset(x, y), out.PixOffset(x, y)
+			copy(out.Pix[b:b+4], overlay.Pix[o:o+4])
+		}
+	}
+	return out
+}
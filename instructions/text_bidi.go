@@ -0,0 +1,390 @@
+package instructions
+
+import (
+	"strings"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// TextDirection sets the base (paragraph) direction used to order mixed
+// left-to-right and right-to-left text.
+type TextDirection int
+
+const (
+	// DirectionAuto takes each paragraph's direction from its first strong
+	// character (UAX #9 rules P2–P3), falling back to left-to-right.
+	DirectionAuto TextDirection = iota
+	// DirectionLTR forces left-to-right paragraphs.
+	DirectionLTR
+	// DirectionRTL forces right-to-left paragraphs.
+	DirectionRTL
+)
+
+// paragraphRTL reports whether paragraph idx of the text runs right-to-left.
+func (t *Text) paragraphRTL(idx int) bool {
+	switch t.direction {
+	case DirectionLTR:
+		return false
+	case DirectionRTL:
+		return true
+	}
+	paras := strings.Split(normalizeNewlines(t.text), "\n")
+	if idx < 0 || idx >= len(paras) {
+		return false
+	}
+	for _, r := range paras[idx] {
+		p, _ := bidi.LookupRune(r)
+		switch p.Class() {
+		case bidi.L:
+			return false
+		case bidi.R, bidi.AL:
+			return true
+		}
+	}
+	return false
+}
+
+// visualLine reorders one wrapped line from logical to display order with
+// the Unicode Bidirectional Algorithm, treating the line as a paragraph of
+// the given base direction. Reordering works on grapheme clusters so
+// combining marks stay attached, and mirrored characters such as brackets
+// are swapped inside right-to-left runs.
+//
+// Explicit embeddings, overrides and isolates are ignored (treated as
+// boundary neutrals); all other rules, including bracket pairs, apply.
+// Lines without right-to-left characters are returned unchanged when the
+// base direction is left-to-right.
+func visualLine(s string, rtl bool) string {
+	if !rtl && !hasRTL(s) {
+		return s
+	}
+	clusters, _ := visualClusters(s, rtl)
+	if len(clusters) == 0 {
+		return s
+	}
+	return strings.Join(clusters, "")
+}
+
+// visualClusters returns the grapheme clusters of s in display order, as
+// visualLine joins them, along with the byte offset of each cluster in s.
+func visualClusters(s string, rtl bool) (clusters []string, offsets []int) {
+	var types []bidi.Class
+	g := uniseg.NewGraphemes(s)
+	for g.Next() {
+		c := g.Str()
+		start, _ := g.Positions()
+		p, _ := bidi.LookupString(c)
+		clusters = append(clusters, c)
+		offsets = append(offsets, start)
+		types = append(types, p.Class())
+	}
+	if len(clusters) == 0 || (!rtl && !hasRTL(s)) {
+		return clusters, offsets
+	}
+
+	levels := resolveLevels(clusters, types, rtl)
+	reorderClusters(clusters, offsets, levels)
+	return clusters, offsets
+}
+
+// hasRTL reports whether s contains right-to-left letters or Arabic digits.
+func hasRTL(s string) bool {
+	for _, r := range s {
+		p, _ := bidi.LookupRune(r)
+		switch p.Class() {
+		case bidi.R, bidi.AL, bidi.AN:
+			return true
+		}
+	}
+	return false
+}
+
+// resolveLevels applies the weak (W1–W7), bracket (N0), neutral (N1–N2) and
+// implicit (I1–I2) rules plus trailing-whitespace reset (L1) to a single
+// level run and returns the embedding level of each cluster. types is
+// rewritten in place.
+func resolveLevels(clusters []string, types []bidi.Class, rtl bool) []int {
+	base, e := 0, bidi.L
+	if rtl {
+		base, e = 1, bidi.R
+	}
+	orig := append([]bidi.Class(nil), types...)
+	n := len(types)
+
+	for i, c := range types {
+		if c >= bidi.Control {
+			types[i] = bidi.BN
+		}
+	}
+
+	// W1: marks take the type of the previous character.
+	for i, c := range types {
+		if c == bidi.NSM {
+			if i == 0 {
+				types[i] = e
+			} else {
+				types[i] = types[i-1]
+			}
+		}
+	}
+	// W2, W3: European numbers after Arabic letters become Arabic numbers.
+	last := e
+	for i, c := range types {
+		switch c {
+		case bidi.L, bidi.R:
+			last = c
+		case bidi.AL:
+			last = c
+			types[i] = bidi.R
+		case bidi.EN:
+			if last == bidi.AL {
+				types[i] = bidi.AN
+			}
+		}
+	}
+	// W4: single separators between numbers of the same kind.
+	for i := 1; i < n-1; i++ {
+		prev, next := types[i-1], types[i+1]
+		switch types[i] {
+		case bidi.ES:
+			if prev == bidi.EN && next == bidi.EN {
+				types[i] = bidi.EN
+			}
+		case bidi.CS:
+			if prev == next && (prev == bidi.EN || prev == bidi.AN) {
+				types[i] = prev
+			}
+		}
+	}
+	// W5: terminators adjacent to European numbers.
+	for i := 0; i < n; {
+		if types[i] != bidi.ET {
+			i++
+			continue
+		}
+		j := i
+		for j < n && types[j] == bidi.ET {
+			j++
+		}
+		if (i > 0 && types[i-1] == bidi.EN) || (j < n && types[j] == bidi.EN) {
+			for k := i; k < j; k++ {
+				types[k] = bidi.EN
+			}
+		}
+		i = j
+	}
+	// W6, W7.
+	last = e
+	for i, c := range types {
+		switch c {
+		case bidi.ES, bidi.ET, bidi.CS:
+			types[i] = bidi.ON
+		case bidi.L, bidi.R:
+			last = c
+		case bidi.EN:
+			if last == bidi.L {
+				types[i] = bidi.L
+			}
+		}
+	}
+
+	resolveBrackets(clusters, types, e)
+
+	// N1, N2: neutral runs take the surrounding direction, or the embedding
+	// direction when the sides disagree. Numbers count as right-to-left.
+	strong := func(c bidi.Class) (bidi.Class, bool) {
+		switch c {
+		case bidi.L:
+			return bidi.L, true
+		case bidi.R, bidi.EN, bidi.AN:
+			return bidi.R, true
+		}
+		return 0, false
+	}
+	for i := 0; i < n; {
+		if _, ok := strong(types[i]); ok {
+			i++
+			continue
+		}
+		j := i
+		for j < n {
+			if _, ok := strong(types[j]); ok {
+				break
+			}
+			j++
+		}
+		before, after := e, e
+		if i > 0 {
+			before, _ = strong(types[i-1])
+		}
+		if j < n {
+			after, _ = strong(types[j])
+		}
+		dir := e
+		if before == after {
+			dir = before
+		}
+		for k := i; k < j; k++ {
+			types[k] = dir
+		}
+		i = j
+	}
+
+	// I1, I2.
+	levels := make([]int, n)
+	for i, c := range types {
+		switch {
+		case base == 0 && c == bidi.R:
+			levels[i] = 1
+		case base == 0 && (c == bidi.AN || c == bidi.EN):
+			levels[i] = 2
+		case base == 1 && (c == bidi.L || c == bidi.EN || c == bidi.AN):
+			levels[i] = 2
+		default:
+			levels[i] = base
+		}
+	}
+
+	// L1: trailing whitespace returns to the paragraph level.
+	for i := n - 1; i >= 0; i-- {
+		if c := orig[i]; c != bidi.WS && c != bidi.S && c != bidi.BN && c < bidi.Control {
+			break
+		}
+		levels[i] = base
+	}
+	return levels
+}
+
+// resolveBrackets applies rule N0: paired brackets take the embedding
+// direction when they enclose a strong character of that direction, or the
+// opposite direction when they enclose only opposite characters and are
+// preceded by one.
+func resolveBrackets(clusters []string, types []bidi.Class, e bidi.Class) {
+	type pair struct{ open, close int }
+	var pairs []pair
+	var stack []int
+	for i, c := range clusters {
+		if types[i] != bidi.ON {
+			continue
+		}
+		p, _ := bidi.LookupString(c)
+		if !p.IsBracket() {
+			continue
+		}
+		if p.IsOpeningBracket() {
+			if len(stack) == 63 {
+				break
+			}
+			stack = append(stack, i)
+			continue
+		}
+		opener := bidi.ReverseString(c)
+		for k := len(stack) - 1; k >= 0; k-- {
+			if clusters[stack[k]] == opener {
+				pairs = append(pairs, pair{stack[k], i})
+				stack = stack[:k]
+				break
+			}
+		}
+	}
+	// Pairs were found in closing order; N0 processes them by opener.
+	for i := 1; i < len(pairs); i++ {
+		for j := i; j > 0 && pairs[j].open < pairs[j-1].open; j-- {
+			pairs[j], pairs[j-1] = pairs[j-1], pairs[j]
+		}
+	}
+
+	dirOf := func(c bidi.Class) bidi.Class {
+		switch c {
+		case bidi.L:
+			return bidi.L
+		case bidi.R, bidi.EN, bidi.AN:
+			return bidi.R
+		}
+		return bidi.ON
+	}
+	for _, p := range pairs {
+		found := bidi.ON
+		for k := p.open + 1; k < p.close; k++ {
+			d := dirOf(types[k])
+			if d == e {
+				found = e
+				break
+			}
+			if d != bidi.ON {
+				found = d
+			}
+		}
+		if found == bidi.ON {
+			continue
+		}
+		if found != e {
+			prev := e
+			for k := p.open - 1; k >= 0; k-- {
+				if d := dirOf(types[k]); d != bidi.ON {
+					prev = d
+					break
+				}
+			}
+			if prev != found {
+				found = e
+			}
+		}
+		types[p.open], types[p.close] = found, found
+	}
+}
+
+// reorderClusters applies rules L2 and L4: runs are reversed from the
+// highest level down to the lowest odd level, and mirrored characters at odd
+// levels are replaced by their counterparts. offsets is permuted alongside
+// clusters.
+func reorderClusters(clusters []string, offsets []int, levels []int) {
+	high, low := 0, 1<<30
+	for i, l := range levels {
+		if l > high {
+			high = l
+		}
+		if l%2 == 1 && l < low {
+			low = l
+		}
+		if l%2 == 1 {
+			clusters[i] = mirror(clusters[i])
+		}
+	}
+	for lvl := high; lvl >= low; lvl-- {
+		for i := 0; i < len(levels); {
+			if levels[i] < lvl {
+				i++
+				continue
+			}
+			j := i
+			for j < len(levels) && levels[j] >= lvl {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				clusters[a], clusters[b] = clusters[b], clusters[a]
+				offsets[a], offsets[b] = offsets[b], offsets[a]
+				levels[a], levels[b] = levels[b], levels[a]
+			}
+			i = j
+		}
+	}
+}
+
+// mirrors lists Bidi_Mirrored characters that are not paired brackets.
+var mirrors = map[string]string{
+	"<": ">", ">": "<", "«": "»", "»": "«", "‹": "›", "›": "‹",
+	"≤": "≥", "≥": "≤",
+}
+
+// mirror returns the mirrored form of a single-rune cluster, or c itself.
+func mirror(c string) string {
+	if m, ok := mirrors[c]; ok {
+		return m
+	}
+	if p, _ := bidi.LookupString(c); p.IsBracket() {
+		return bidi.ReverseString(c)
+	}
+	return c
+}
@@ -4,7 +4,7 @@ import (
 	"image"
 
 	"github.com/Krispeckt/glimo/internal/core/geom"
-	"github.com/Krispeckt/glimo/internal/core/image/patterns"
+	"github.com/Krispeckt/glimo/patterns"
 )
 
 // compositePatternWithMask composites a pattern into the overlay image
@@ -19,6 +19,7 @@ import (
 //   - dstX, dstY: top-left target coordinates where the mask is placed.
 //   - clip: optional clipping rectangle; if non-empty, restricts compositing area.
 //   - p: the pattern providing colors (solid, gradient, or blended).
+//   - accurate: blend with exact rounding (see Layer.SetAccurateBlending).
 //
 // Behavior:
 //  1. Skips processing if any input is nil or if the intersection area is empty.
@@ -32,7 +33,7 @@ import (
 //
 // This function performs per-pixel alpha blending and is a core primitive
 // for stroking and filling text or vector shapes with pattern-based colors.
-func compositePatternWithMask(base, overlay, mask *image.RGBA, dstX, dstY int, clip image.Rectangle, p patterns.Pattern) {
+func compositePatternWithMask(base, overlay, mask *image.RGBA, dstX, dstY int, clip image.Rectangle, p patterns.Pattern, accurate bool) {
 	if base == nil || overlay == nil || mask == nil || p == nil {
 		return
 	}
@@ -58,6 +59,10 @@ func compositePatternWithMask(base, overlay, mask *image.RGBA, dstX, dstY int, c
 	if opacity > 1 {
 		opacity = 1
 	}
+	blend := patterns.Color.BlendOver
+	if accurate {
+		blend = patterns.Color.BlendOverAccurate
+	}
 	t := uint8(opacity*255 + 0.5)
 	omt := 255 - t
 
@@ -85,19 +90,14 @@ func compositePatternWithMask(base, overlay, mask *image.RGBA, dstX, dstY int, c
 					src = src.SetBlendMode(mode)
 				}
 
-				bg := patterns.Color{
-					R: base.Pix[bRow+0],
-					G: base.Pix[bRow+1],
-					B: base.Pix[bRow+2],
-					A: base.Pix[bRow+3],
-				}
+				// The base is premultiplied; blending works on straight colors.
+				brp, bgp, bbp := base.Pix[bRow+0], base.Pix[bRow+1], base.Pix[bRow+2]
+				bg := patterns.Color{A: base.Pix[bRow+3]}
+				bg.R, bg.G, bg.B = geom.UnpremultiplyRGB(brp, bgp, bbp, bg.A)
 
-				out := src.BlendOver(bg, float64(ma)/255.0)
+				out := blend(src, bg, float64(ma)/255.0)
 
-				// premultiplied components
-				prp := geom.Mul255(out.R, out.A)
-				pgp := geom.Mul255(out.G, out.A)
-				pbp := geom.Mul255(out.B, out.A)
+				prp, pgp, pbp := geom.PremultiplyRGB(out.R, out.G, out.B, out.A)
 
 				if t == 255 {
 					overlay.Pix[oRow+0] = prp
@@ -105,10 +105,6 @@ func compositePatternWithMask(base, overlay, mask *image.RGBA, dstX, dstY int, c
 					overlay.Pix[oRow+2] = pbp
 					overlay.Pix[oRow+3] = out.A
 				} else {
-					brp := geom.Mul255(bg.R, bg.A)
-					bgp := geom.Mul255(bg.G, bg.A)
-					bbp := geom.Mul255(bg.B, bg.A)
-
 					overlay.Pix[oRow+0] = uint8((uint32(brp)*uint32(omt) + uint32(prp)*uint32(t) + 127) / 255)
 					overlay.Pix[oRow+1] = uint8((uint32(bgp)*uint32(omt) + uint32(pgp)*uint32(t) + 127) / 255)
 					overlay.Pix[oRow+2] = uint8((uint32(bbp)*uint32(omt) + uint32(pbp)*uint32(t) + 127) / 255)
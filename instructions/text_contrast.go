@@ -0,0 +1,209 @@
+package instructions
+
+import (
+	"image"
+	"math"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+	"github.com/Krispeckt/glimo/patterns"
+)
+
+// AutoContrast chooses a legible fill for a Text block from the pixels
+// already present under it. It samples the base image inside the text bounds,
+// picks whichever of the light or dark color contrasts better with the sampled
+// luminance, and optionally lays a translucent scrim behind the text when the
+// background is too busy to reach the contrast target on its own.
+//
+// Busy backgrounds are handled by judging each candidate against the luminance
+// spread (10th to 90th percentile) rather than the average alone, so a noisy
+// photo needs a scrim sooner than a flat color of the same average brightness.
+type AutoContrast struct {
+	light, dark  patterns.Color
+	minContrast  float64
+	scrimOpacity float64
+	scrimPadding int
+}
+
+// NewAutoContrast creates an AutoContrast that switches between light and dark.
+// Defaults:
+//   - minContrast = 4.5 (WCAG AA for body text)
+//   - scrim disabled
+func NewAutoContrast(light, dark patterns.Color) *AutoContrast {
+	return &AutoContrast{
+		light:        light,
+		dark:         dark,
+		minContrast:  4.5,
+		scrimPadding: 8,
+	}
+}
+
+// SetMinContrast sets the WCAG contrast ratio the fill must reach before a scrim
+// is inserted. Values are clamped to [1, 21].
+func (a *AutoContrast) SetMinContrast(ratio float64) *AutoContrast {
+	a.minContrast = geom.ClampF64(ratio, 1, 21)
+	return a
+}
+
+// SetScrim enables a scrim with the given opacity in [0, 1] and padding in pixels
+// around the text bounds. The scrim uses the color opposite to the chosen fill.
+// Zero opacity disables the scrim.
+func (a *AutoContrast) SetScrim(opacity float64, padding int) *AutoContrast {
+	a.scrimOpacity = geom.ClampF64(opacity, 0, 1)
+	a.scrimPadding = geom.MaxInt(padding, 0)
+	return a
+}
+
+// choose returns the fill color, the scrim color, and whether a scrim is needed
+// for a background whose luminance spans [lo, hi].
+func (a *AutoContrast) choose(lo, hi float64) (fill, scrim patterns.Color, needScrim bool) {
+	// Worst-case contrast of a candidate across the sampled luminance range.
+	worst := func(c patterns.Color) float64 {
+		l := c.Luminance()
+		return math.Min(patterns.LuminanceContrast(l, lo), patterns.LuminanceContrast(l, hi))
+	}
+
+	lightC, darkC := worst(a.light), worst(a.dark)
+	fill, scrim = a.light, a.dark
+	best := lightC
+	if darkC > lightC {
+		fill, scrim = a.dark, a.light
+		best = darkC
+	}
+	return fill, scrim, best < a.minContrast && a.scrimOpacity > 0
+}
+
+// applyAutoContrast samples base under the text bounds and returns the fill
+// pattern to use. Without visible pixels to sample, the configured pattern is
+// kept. When a scrim is required it is composited into overlay over the
+// padded bounds, and the returned base is a copy that includes the scrim so
+// glyph edges blend against it. Otherwise base is returned unchanged.
+func (t *Text) applyAutoContrast(base, overlay *image.RGBA, bounds image.Rectangle) (*image.RGBA, patterns.Pattern) {
+	a := t.autoContrast
+	lo, hi, ok := sampleLuminance(base, bounds)
+	if !ok {
+		return base, t.colorPattern
+	}
+
+	fill, scrimColor, needScrim := a.choose(lo, hi)
+	if !needScrim {
+		return base, fill.MakeSolidPattern()
+	}
+
+	rect := bounds.Inset(-a.scrimPadding).Intersect(base.Bounds()).Intersect(overlay.Bounds())
+	if rect.Empty() {
+		return base, fill.MakeSolidPattern()
+	}
+
+	mask := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	for i := 3; i < len(mask.Pix); i += 4 {
+		mask.Pix[i] = 255
+	}
+	scrim := patterns.NewSolidWithBlend(scrimColor, patterns.BlendNormal, a.scrimOpacity)
+	compositePatternWithMask(base, overlay, mask, rect.Min.X, rect.Min.Y, rect, scrim, t.accurate)
+
+	// Text glyphs blend against base; give them a base that already has the scrim.
+	return withOverlay(base, overlay, rect), fill.MakeSolidPattern()
+}
+
+// withOverlay returns a copy of base with the rectangles rects, which must
+// lie within both images, taken from overlay.
+func withOverlay(base, overlay *image.RGBA, rects ...image.Rectangle) *image.RGBA {
+	out := image.NewRGBA(base.Bounds())
+	copy(out.Pix, base.Pix)
+	for _, r := range rects {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			o := overlay.PixOffset(r.Min.X, y)
+			b := out.PixOffset(r.Min.X, y)
+			copy(out.Pix[b:b+r.Dx()*4], overlay.Pix[o:o+r.Dx()*4])
+		}
+	}
+	return out
+}
+
+// textBounds returns the union of line boxes as laid out by Draw.
+func (t *Text) textBounds(lines []string, paraOf []int, spacing float64) image.Rectangle {
+	var r image.Rectangle
+	yTop := t.y
+	for i, line := range lines {
+		if t.maxLines > 0 && i >= t.maxLines {
+			break
+		}
+		lineFont := t.fontForLine(i)
+		w, _ := lineFont.MeasureString(line)
+		x := t.alignX(t.x, w, t.alignForParagraph(paraOf[i]))
+		h := lineFont.LineHeightPx()
+
+		lr := image.Rect(
+			int(math.Floor(x)), int(math.Floor(yTop)),
+			int(math.Ceil(x+w)), int(math.Ceil(yTop+h)),
+		)
+		r = r.Union(lr)
+
+		yTop += h * spacing
+	}
+	return r
+}
+
+// sampleLuminance returns the 10th and 90th percentiles of relative luminance
+// inside r, weighted by alpha. Fully transparent pixels are ignored; ok is
+// false when r holds no visible pixels. Large areas are sampled on a grid.
+func sampleLuminance(img *image.RGBA, r image.Rectangle) (lo, hi float64, ok bool) {
+	r = r.Intersect(img.Bounds())
+	if r.Empty() {
+		return 0, 0, false
+	}
+
+	const (
+		bins       = 256
+		maxSamples = 1 << 16
+		tail       = 0.1
+	)
+	step := 1
+	for (r.Dx()/step)*(r.Dy()/step) > maxSamples {
+		step++
+	}
+
+	var hist [bins]float64
+	var total float64
+	for y := r.Min.Y; y < r.Max.Y; y += step {
+		for x := r.Min.X; x < r.Max.X; x += step {
+			i := img.PixOffset(x, y)
+			a := img.Pix[i+3]
+			if a == 0 {
+				continue
+			}
+			c := patterns.Color{R: img.Pix[i], G: img.Pix[i+1], B: img.Pix[i+2]}
+			if a < 255 {
+				// Un-premultiply before converting to linear light.
+				c.R = uint8(uint32(c.R) * 255 / uint32(a))
+				c.G = uint8(uint32(c.G) * 255 / uint32(a))
+				c.B = uint8(uint32(c.B) * 255 / uint32(a))
+			}
+			w := float64(a) / 255
+			hist[int(c.Luminance()*(bins-1)+0.5)] += w
+			total += w
+		}
+	}
+	if total == 0 {
+		return 0, 0, false
+	}
+
+	// Walk the histogram from both ends until each tail's weight is consumed.
+	var acc float64
+	for b := 0; b < bins; b++ {
+		acc += hist[b]
+		if acc >= total*tail {
+			lo = float64(b) / (bins - 1)
+			break
+		}
+	}
+	acc = 0
+	for b := bins - 1; b >= 0; b-- {
+		acc += hist[b]
+		if acc >= total*tail {
+			hi = float64(b) / (bins - 1)
+			break
+		}
+	}
+	return lo, hi, true
+}
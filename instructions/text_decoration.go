@@ -0,0 +1,135 @@
+package instructions
+
+import (
+	"image"
+	"math"
+
+	"github.com/Krispeckt/glimo/patterns"
+)
+
+// textDecoration holds the line decorations of a Text.
+type textDecoration struct {
+	underline, strikethrough, overline bool
+
+	thickness float64          // stroke thickness in pixels; 0 uses font metrics
+	offset    float64          // extra distance of under/overlines from the text
+	pattern   patterns.Pattern // nil uses the text fill
+}
+
+// any reports whether a decoration is enabled.
+func (d textDecoration) any() bool { return d.underline || d.strikethrough || d.overline }
+
+// SetDecoration turns the underline and strikethrough on or off. They are
+// drawn under every laid-out line, spanning its measured width, at the
+// positions the font suggests, so they follow the text when wrapping changes.
+func (t *Text) SetDecoration(underline, strikethrough bool) *Text {
+	t.decoration.underline = underline
+	t.decoration.strikethrough = strikethrough
+	return t
+}
+
+// SetOverline turns the overline, drawn along the ascent line, on or off.
+func (t *Text) SetOverline(overline bool) *Text {
+	t.decoration.overline = overline
+	return t
+}
+
+// SetDecorationStyle overrides how decorations are drawn: thickness in
+// pixels (0 keeps the font's suggestion), offset in pixels moving underlines
+// down and overlines up, away from the text, and the pattern to fill them
+// with (nil uses the text fill).
+func (t *Text) SetDecorationStyle(thickness, offset float64, p patterns.Pattern) *Text {
+	t.decoration.thickness = math.Max(thickness, 0)
+	t.decoration.offset = offset
+	t.decoration.pattern = p
+	return t
+}
+
+// decorationRects returns the canvas rectangles of the enabled decorations
+// of every drawn line: those drawn beneath the glyphs (underlines and
+// overlines) and those drawn over them (strikethroughs).
+func (t *Text) decorationRects(lines []string, paraOf []int, spacing float64) (under, over []image.Rectangle) {
+	d := t.decoration
+	yTop := t.y
+	for i, line := range lines {
+		if t.maxLines > 0 && i >= t.maxLines {
+			break
+		}
+		lineFont := t.fontForLine(i)
+		w, _ := lineFont.MeasureString(line)
+		x := t.alignX(t.x, w, t.alignForParagraph(paraOf[i]))
+		baseline := math.Floor(yTop) + math.Round(lineFont.BaselineForTopY(0))
+		rect := func(center, thickness float64) image.Rectangle {
+			if d.thickness > 0 {
+				thickness = d.thickness
+			}
+			th := math.Max(math.Round(thickness), 1)
+			y0 := math.Round(center - th/2)
+			return image.Rect(int(math.Round(x)), int(y0), int(math.Round(x+w)), int(y0+th))
+		}
+
+		if w > 0 {
+			uo, ut := lineFont.UnderlinePx()
+			if d.underline {
+				under = append(under, rect(baseline+uo+d.offset, ut))
+			}
+			if d.overline {
+				under = append(under, rect(baseline-lineFont.AscentPx()-d.offset, ut))
+			}
+			if d.strikethrough {
+				so, st := lineFont.StrikethroughPx()
+				over = append(over, rect(baseline+so, st))
+			}
+		}
+		yTop += lineFont.LineHeightPx() * spacing
+	}
+	return under, over
+}
+
+// drawDecorations fills rects with p. Unless onTop is set, the rectangles are
+// meant to lie beneath glyphs and the returned base includes them; otherwise
+// they blend over what is already in overlay and base is returned as is.
+// accurate selects exact blending (see Layer.SetAccurateBlending).
+func drawDecorations(base, overlay *image.RGBA, rects []image.Rectangle, p patterns.Pattern, onTop, accurate bool) *image.RGBA {
+	if p == nil || len(rects) == 0 {
+		return base
+	}
+	var drawn []image.Rectangle
+	for _, r := range rects {
+		r = r.Intersect(base.Bounds()).Intersect(overlay.Bounds())
+		if r.Empty() {
+			continue
+		}
+		mask := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+		for i := 3; i < len(mask.Pix); i += 4 {
+			mask.Pix[i] = 255
+		}
+		under := base
+		if onTop {
+			under = flatten(base, overlay, r)
+		}
+		compositePatternWithMask(under, overlay, mask, r.Min.X, r.Min.Y, r, p, accurate)
+		drawn = append(drawn, r)
+	}
+	if onTop || len(drawn) == 0 {
+		return base
+	}
+	return withOverlay(base, overlay, drawn...)
+}
+
+// flatten returns the pixels of r as they will appear once overlay is
+// composited onto base: overlay where it has been drawn, base elsewhere.
+func flatten(base, overlay *image.RGBA, r image.Rectangle) *image.RGBA {
+	out := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			src := base
+			if overlay.Pix[overlay.PixOffset(x, y)+3] != 0 {
+				src = overlay
+			}
+			i, o := src.PixOffset(x, y), out.PixOffset(x, y)
+			copy(out.Pix[o:o+4], src.Pix[i:i+4])
+		}
+	}
+	return out
+}
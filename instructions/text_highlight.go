@@ -0,0 +1,350 @@
+package instructions
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/Krispeckt/glimo/internal/render"
+	"github.com/Krispeckt/glimo/patterns"
+)
+
+// Highlight styles the parts of a Text marked with AddHighlight or
+// AddHighlightRegexp. Any combination of a replacement fill, a background
+// box and an underline may be set; unset parts leave the text unchanged.
+type Highlight struct {
+	colorPattern      patterns.Pattern
+	backgroundPattern patterns.Pattern
+	underlinePattern  patterns.Pattern
+	underlineWidth    float64
+}
+
+// NewHighlight returns a Highlight that changes nothing until configured.
+func NewHighlight() *Highlight {
+	return &Highlight{}
+}
+
+// SetColorPattern replaces the glyph fill of marked text with p.
+func (h *Highlight) SetColorPattern(p patterns.Pattern) *Highlight {
+	h.colorPattern = p
+	return h
+}
+
+// SetSolidColor replaces the glyph fill of marked text with a solid color.
+func (h *Highlight) SetSolidColor(c patterns.Color) *Highlight {
+	h.colorPattern = c.MakeSolidPattern()
+	return h
+}
+
+// SetBackgroundPattern fills the line box behind marked text with p.
+func (h *Highlight) SetBackgroundPattern(p patterns.Pattern) *Highlight {
+	h.backgroundPattern = p
+	return h
+}
+
+// SetBackgroundColor fills the line box behind marked text with a solid color.
+func (h *Highlight) SetBackgroundColor(c patterns.Color) *Highlight {
+	h.backgroundPattern = c.MakeSolidPattern()
+	return h
+}
+
+// SetUnderlineWithPattern underlines marked text with a bar of the given
+// width in pixels, placed where the font suggests. A width of zero uses the
+// font's underline thickness.
+func (h *Highlight) SetUnderlineWithPattern(p patterns.Pattern, width float64) *Highlight {
+	h.underlinePattern = p
+	h.underlineWidth = math.Max(width, 0)
+	return h
+}
+
+// SetUnderlineWithColor underlines marked text with a solid color bar.
+func (h *Highlight) SetUnderlineWithColor(c patterns.Color, width float64) *Highlight {
+	return h.SetUnderlineWithPattern(c.MakeSolidPattern(), width)
+}
+
+// scaled returns a copy of h for a device scale of s.
+func (h *Highlight) scaled(d device) *Highlight {
+	s := d.scale
+	c := *h
+	c.colorPattern = d.pattern(h.colorPattern)
+	c.backgroundPattern = d.pattern(h.backgroundPattern)
+	c.underlinePattern = d.pattern(h.underlinePattern)
+	c.underlineWidth = h.underlineWidth * s
+	return &c
+}
+
+// textMark applies a Highlight to a byte range of the text, or to every
+// match of re when it is set.
+type textMark struct {
+	start, end int
+	re         *regexp.Regexp
+	h          *Highlight
+}
+
+// AddHighlight marks the bytes [start, end) of the text, as passed to
+// NewText or SetText, with h. Marks are resolved after wrapping, so a marked
+// phrase keeps its style when it breaks across lines. Whitespace collapsed
+// by wrapping and inserted wrap symbols or ellipses are never marked. Later
+// marks draw over earlier ones.
+func (t *Text) AddHighlight(start, end int, h *Highlight) *Text {
+	if h != nil && end > start {
+		t.marks = append(t.marks, textMark{start: start, end: end, h: h})
+	}
+	return t
+}
+
+// AddHighlightRegexp marks every match of re in the text with h. Matches are
+// found when the text is drawn, so marks follow later SetText calls, which
+// suits templates that fill in the text per render.
+func (t *Text) AddHighlightRegexp(re *regexp.Regexp, h *Highlight) *Text {
+	if h != nil && re != nil {
+		t.marks = append(t.marks, textMark{re: re, h: h})
+	}
+	return t
+}
+
+// ClearHighlights removes all marks.
+func (t *Text) ClearHighlights() *Text {
+	t.marks = nil
+	return t
+}
+
+// ranges returns the byte ranges of text covered by m.
+func (m textMark) ranges(text string) [][]int {
+	if m.re != nil {
+		return m.re.FindAllStringIndex(text, -1)
+	}
+	start, end := max(m.start, 0), min(m.end, len(text))
+	if start >= end {
+		return nil
+	}
+	return [][]int{{start, end}}
+}
+
+// highlightSpan is a horizontal run of marked text on one line, in canvas
+// pixels.
+type highlightSpan struct {
+	x0, x1 float64
+	h      *Highlight
+}
+
+// lineSources maps every byte of each wrapped line to the offset of the
+// byte in src it was taken from, or -1 for text inserted by wrapping. Lines
+// are matched against src in order; separators that wrapping drops or
+// collapses (spaces, tabs, newlines, NBSP and soft breaks) are skipped.
+func lineSources(src string, lines []string) [][]int {
+	out := make([][]int, len(lines))
+	pos := 0
+	for i, line := range lines {
+		offs := make([]int, len(line))
+		for b := 0; b < len(line); {
+			r, n := utf8.DecodeRuneInString(line[b:])
+			at := -1
+			for j := pos; j < len(src); {
+				sr, sn := utf8.DecodeRuneInString(src[j:])
+				if sr == r || (isBlank(r) && isBlank(sr)) {
+					at, pos = j, j+sn
+					break
+				}
+				if !droppedByWrap(sr) {
+					break
+				}
+				j += sn
+			}
+			for k := 0; k < n; k++ {
+				if at < 0 {
+					offs[b+k] = -1
+				} else {
+					offs[b+k] = at + k
+				}
+			}
+			b += n
+		}
+		out[i] = offs
+	}
+	return out
+}
+
+// isBlank reports whether r is a space, tab or NBSP, which wrapping may
+// substitute for one another.
+func isBlank(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\u00A0'
+}
+
+// droppedByWrap reports whether wrapping may remove r from the text.
+func droppedByWrap(r rune) bool {
+	switch r {
+	case ' ', '\t', '\r', '\n', '\u00A0':
+		return true
+	}
+	return isSoftBreak(r)
+}
+
+// layoutHighlights resolves the marks against the wrapped lines, draws
+// their backgrounds and underlines, and returns the base that glyphs should
+// blend against along with each line's spans. Without marks it returns base
+// and nil.
+func (t *Text) layoutHighlights(base, overlay *image.RGBA, lines []string, paraOf []int, spacing float64) (*image.RGBA, [][]highlightSpan) {
+	if len(t.marks) == 0 {
+		return base, nil
+	}
+	marks := make([][][]int, len(t.marks))
+	for i, m := range t.marks {
+		marks[i] = m.ranges(t.text)
+	}
+	sources := lineSources(t.text, lines)
+
+	out := make([][]highlightSpan, len(lines))
+	var rects []image.Rectangle
+	yTop := t.y
+	for i, line := range lines {
+		if t.maxLines > 0 && i >= t.maxLines {
+			break
+		}
+		lineFont := t.fontForLine(i)
+		w, _ := lineFont.MeasureString(line)
+		x := t.alignX(t.x, w, t.alignForParagraph(paraOf[i]))
+		out[i] = t.highlightSpans(lineFont, line, sources[i], t.paragraphRTL(paraOf[i]), x, marks)
+		rects = append(rects, drawHighlightBoxes(base, overlay, lineFont, out[i], yTop, t.accurate)...)
+		yTop += lineFont.LineHeightPx() * spacing
+	}
+	if len(rects) == 0 {
+		return base, out
+	}
+	return withOverlay(base, overlay, rects...), out
+}
+
+// highlightSpans returns the marked runs of one line drawn at x, in mark
+// order. sources maps the line's bytes to the text as from lineSources.
+func (t *Text) highlightSpans(fnt *render.Font, line string, sources []int, rtl bool, x float64, marks [][][]int) []highlightSpan {
+	clusters, offsets := visualClusters(line, rtl)
+	if len(clusters) == 0 {
+		return nil
+	}
+
+	edges := clusterEdges(fnt, clusters)
+
+	var spans []highlightSpan
+	for mi, ranges := range marks {
+		open := -1
+		for i := 0; i <= len(clusters); i++ {
+			in := i < len(clusters) && inRanges(sources[offsets[i]], ranges)
+			if in && open < 0 {
+				open = i
+			}
+			if !in && open >= 0 {
+				spans = append(spans, highlightSpan{x0: x + edges[open], x1: x + edges[i], h: t.marks[mi].h})
+				open = -1
+			}
+		}
+	}
+	return spans
+}
+
+// clusterEdges returns the offsets from the start of the line of the left
+// edge of each cluster followed by the right edge of the last, measured on
+// growing prefixes so kerning and ligatures match the drawn line.
+func clusterEdges(fnt *render.Font, clusters []string) []float64 {
+	edges := make([]float64, len(clusters)+1)
+	var prefix string
+	for i, c := range clusters {
+		prefix += c
+		w, _ := fnt.MeasureString(prefix)
+		edges[i+1] = w
+	}
+	return edges
+}
+
+// inRanges reports whether off lies in one of the byte ranges.
+func inRanges(off int, ranges [][]int) bool {
+	if off < 0 {
+		return false
+	}
+	for _, r := range ranges {
+		if off >= r[0] && off < r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// drawHighlightBoxes paints the backgrounds and underlines of one line's
+// spans into overlay and returns the covered rectangles, which Draw copies
+// into the base the glyphs blend against. accurate selects exact blending.
+func drawHighlightBoxes(base, overlay *image.RGBA, fnt *render.Font, spans []highlightSpan, topY float64, accurate bool) []image.Rectangle {
+	var rects []image.Rectangle
+	fill := func(r image.Rectangle, p patterns.Pattern) {
+		r = r.Intersect(base.Bounds()).Intersect(overlay.Bounds())
+		if r.Empty() {
+			return
+		}
+		mask := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+		for i := 3; i < len(mask.Pix); i += 4 {
+			mask.Pix[i] = 255
+		}
+		compositePatternWithMask(base, overlay, mask, r.Min.X, r.Min.Y, r, p, accurate)
+		rects = append(rects, r)
+	}
+
+	yi := math.Floor(topY)
+	for _, s := range spans {
+		if s.h.backgroundPattern == nil {
+			continue
+		}
+		fill(image.Rect(
+			int(math.Round(s.x0)), int(yi),
+			int(math.Round(s.x1)), int(math.Ceil(topY+fnt.LineHeightPx())),
+		), s.h.backgroundPattern)
+	}
+
+	baseline := yi + math.Round(fnt.BaselineForTopY(0))
+	for _, s := range spans {
+		if s.h.underlinePattern == nil {
+			continue
+		}
+		offset, w := fnt.UnderlinePx()
+		if s.h.underlineWidth > 0 {
+			w = s.h.underlineWidth
+		}
+		y0 := math.Round(baseline + offset - w/2)
+		fill(image.Rect(
+			int(math.Round(s.x0)), int(y0),
+			int(math.Round(s.x1)), int(y0+math.Max(math.Round(w), 1)),
+		), s.h.underlinePattern)
+	}
+	return rects
+}
+
+// spanFill uses the fill of the last span whose columns contain a pixel and
+// the line's own fill elsewhere.
+type spanFill struct {
+	base  patterns.Pattern
+	spans []highlightSpan
+}
+
+// ColorAt implements patterns.Pattern.
+func (f spanFill) ColorAt(x, y int) color.Color {
+	for i := len(f.spans) - 1; i >= 0; i-- {
+		s := f.spans[i]
+		if s.h.colorPattern != nil && float64(x) >= math.Round(s.x0) && float64(x) < math.Round(s.x1) {
+			return s.h.colorPattern.ColorAt(x, y)
+		}
+	}
+	if f.base == nil {
+		return color.Transparent
+	}
+	return f.base.ColorAt(x, y)
+}
+
+// lineFill returns the fill for a line with the given spans: fill itself when
+// no span recolors glyphs.
+func lineFill(fill patterns.Pattern, spans []highlightSpan) patterns.Pattern {
+	for _, s := range spans {
+		if s.h.colorPattern != nil {
+			return spanFill{base: fill, spans: spans}
+		}
+	}
+	return fill
+}
@@ -0,0 +1,63 @@
+package instructions
+
+import (
+	"strings"
+
+	"github.com/Krispeckt/glimo/internal/render"
+)
+
+// SetHyphenation enables automatic hyphenation in WrapByWord mode with the
+// patterns registered for lang (see render.RegisterHyphenator). The language
+// is resolved when the text is laid out, so patterns may be registered
+// later (call InvalidateLayout if the text was already laid out); while none
+// are registered, words are not hyphenated. An empty lang disables
+// hyphenation.
+func (t *Text) SetHyphenation(lang string) *Text {
+	t.hyphenLang, t.hyphenator = lang, nil
+	t.InvalidateLayout()
+	return t
+}
+
+// SetHyphenator enables automatic hyphenation in WrapByWord mode with h,
+// taking precedence over SetHyphenation. A nil h disables it.
+//
+// Words are broken only at the points h allows, with the wrap symbol
+// appended, just as at soft hyphens; words that already contain soft break
+// marks keep only those. A word with no legal point that fits still falls
+// back to grapheme splitting.
+func (t *Text) SetHyphenator(h *render.Hyphenator) *Text {
+	t.hyphenator, t.hyphenLang = h, ""
+	t.InvalidateLayout()
+	return t
+}
+
+// hyphenate inserts soft hyphens at the legal break points of the words of
+// paragraph p, or returns p unchanged when hyphenation is off.
+func (t *Text) hyphenate(p string) string {
+	h := t.hyphenator
+	if h == nil && t.hyphenLang != "" {
+		h, _ = render.LookupHyphenator(t.hyphenLang)
+	}
+	if h == nil {
+		return p
+	}
+
+	var b strings.Builder
+	word := func(w string) {
+		if strings.ContainsAny(w, softBreakChars) {
+			b.WriteString(w)
+			return
+		}
+		b.WriteString(h.Hyphenate(w, string(softHyphen)))
+	}
+	start := 0
+	for i, r := range p {
+		if r == ' ' || r == '\t' {
+			word(p[start:i])
+			b.WriteRune(r)
+			start = i + 1
+		}
+	}
+	word(p[start:])
+	return b.String()
+}
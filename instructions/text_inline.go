@@ -0,0 +1,119 @@
+package instructions
+
+import (
+	"image"
+	"math"
+	"unicode/utf8"
+
+	"github.com/Krispeckt/glimo/internal/render"
+)
+
+// InlineObject is an image or shape laid out inside a Text as if it were a
+// glyph: it takes part in measuring, wrapping and alignment, and sits on the
+// baseline of its line.
+type InlineObject struct {
+	shape         BoundedShape
+	width, height float64
+	shift         float64
+}
+
+// NewInlineImage returns an InlineObject drawing img fitted into
+// width×height pixels.
+func NewInlineImage(img image.Image, width, height float64) *InlineObject {
+	im := NewImage(img, 0, 0).
+		SetSize(int(math.Round(width)), int(math.Round(height))).
+		SetFit(FitContain)
+	return NewInlineShape(im, width, height)
+}
+
+// NewInlineShape returns an InlineObject that reserves width×height pixels
+// and draws s there. The shape is moved with SetPosition to the top-left
+// corner of its slot before every draw; it should cover the slot itself.
+func NewInlineShape(s BoundedShape, width, height float64) *InlineObject {
+	return &InlineObject{
+		shape:  s,
+		width:  math.Max(width, 0),
+		height: math.Max(height, 0),
+	}
+}
+
+// SetBaselineShift moves the object down by px pixels from resting on the
+// baseline, or up for negative values; a shift of a third of the height
+// roughly centers an icon on lowercase letters.
+func (o *InlineObject) SetBaselineShift(px float64) *InlineObject {
+	o.shift = px
+	return o
+}
+
+// scaled returns a copy of o for a device scale of s.
+func (o *InlineObject) scaled(d device) *InlineObject {
+	s := d.scale
+	c := *o
+	c.width, c.height, c.shift = o.width*s, o.height*s, o.shift*s
+	if sc, ok := o.shape.(scalable); ok {
+		if b, ok := sc.scaled(d).(BoundedShape); ok {
+			c.shape = b
+		}
+	}
+	return &c
+}
+
+// AddInline makes every occurrence of r in the text stand for o, so a string
+// like "\uE000 1,250 coins" can show a coin image in front of the amount. The
+// rune should be one the text never uses otherwise, such as a code point
+// from the Private Use Area. Objects keep their pixel size on lines resized
+// by SetScaleStep. A nil object removes the mapping.
+func (t *Text) AddInline(r rune, o *InlineObject) *Text {
+	t.InvalidateLayout()
+	if o == nil {
+		delete(t.inline, r)
+		return t
+	}
+	if t.inline == nil {
+		t.inline = make(map[rune]*InlineObject)
+	}
+	t.inline[r] = o
+	return t
+}
+
+// withSpacers reserves room in f for the inline objects.
+func (t *Text) withSpacers(f *render.Font) {
+	px := f.HeightPx()
+	for r, o := range t.inline {
+		f.SetSpacer(r, o.width/px)
+	}
+}
+
+// drawInline draws the inline objects of one laid-out line, whose logical
+// text is line, starting at x with its top at topY.
+func (t *Text) drawInline(base, overlay *image.RGBA, fnt *render.Font, line string, rtl bool, x, topY float64) {
+	clusters, _ := visualClusters(line, rtl)
+	var edges []float64
+	baseline := math.Floor(topY) + math.Round(fnt.BaselineForTopY(0))
+	for i, c := range clusters {
+		o := t.inlineFor(c)
+		if o == nil || o.shape == nil {
+			continue
+		}
+		if edges == nil {
+			edges = clusterEdges(fnt, clusters)
+		}
+		o.shape.SetPosition(
+			int(math.Round(x+edges[i])),
+			int(math.Round(baseline-o.height+o.shift)),
+		)
+		o.shape.Draw(base, overlay)
+	}
+}
+
+// inlineFor returns the object a grapheme cluster stands for, if any.
+func (t *Text) inlineFor(cluster string) *InlineObject {
+	if len(t.inline) == 0 {
+		return nil
+	}
+	r, n := utf8.DecodeRuneInString(cluster)
+	if n != len(cluster) {
+		return nil
+	}
+	return t.inline[r]
+}
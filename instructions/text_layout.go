@@ -0,0 +1,97 @@
+package instructions
+
+import "math"
+
+// TextLayout describes where a Text block places its lines and glyphs, in
+// canvas pixels, as Draw would render them.
+type TextLayout struct {
+	Lines []LineLayout
+
+	// Width and Height are the block size, as reported by Size for
+	// unrotated text.
+	Width, Height float64
+}
+
+// LineLayout describes one drawn line.
+type LineLayout struct {
+	Text string // line content in display order
+
+	// X, Y, Width and Height give the line box: the measured advance of the
+	// line by the font's line height, after alignment.
+	X, Y, Width, Height float64
+
+	Baseline float64 // y of the baseline the glyphs sit on
+	Glyphs   []GlyphLayout
+}
+
+// GlyphLayout describes one grapheme cluster of a line.
+type GlyphLayout struct {
+	Text string // the cluster's characters
+
+	// Offset is the byte offset of the cluster in the text, or -1 for text
+	// inserted by wrapping such as a hyphen or ellipsis.
+	Offset int
+
+	// X and Width give the cluster's horizontal extent along its pen
+	// advance, including kerning and tracking towards the next cluster.
+	X, Width float64
+}
+
+// End returns the x coordinate right after the last glyph of the line, which
+// is where trailing content such as a badge or cursor belongs.
+func (l LineLayout) End() float64 { return l.X + l.Width }
+
+// Layout computes the layout Draw would produce without drawing anything,
+// so dependent elements such as badges after the last word, cursors or
+// inline icons can be positioned precisely. It returns an empty layout when
+// the text or font is unset.
+func (t *Text) Layout() *TextLayout {
+	out := &TextLayout{}
+	if t.font == nil || t.text == "" {
+		return out
+	}
+	size := t.blockSize()
+	out.Width, out.Height = size.Width(), size.Height()
+
+	lines, paraOf := t.wrapTextScaled()
+	spacing := t.lineSpacing
+	if spacing <= 0 {
+		spacing = t.autoSpacing(lines)
+	}
+	sources := lineSources(t.text, lines)
+
+	yTop := t.y
+	for i, line := range lines {
+		if t.maxLines > 0 && i >= t.maxLines {
+			break
+		}
+		lineFont := t.fontForLine(i)
+		w, _ := lineFont.MeasureString(line)
+		h := lineFont.LineHeightPx()
+		rtl := t.paragraphRTL(paraOf[i])
+		x := t.alignX(t.x, w, t.alignForParagraph(paraOf[i]))
+
+		ll := LineLayout{
+			Text:     visualLine(line, rtl),
+			X:        x,
+			Y:        yTop,
+			Width:    w,
+			Height:   h,
+			Baseline: math.Floor(yTop) + math.Round(lineFont.BaselineForTopY(0)),
+		}
+		clusters, offsets := visualClusters(line, rtl)
+		edges := clusterEdges(lineFont, clusters)
+		for j, c := range clusters {
+			ll.Glyphs = append(ll.Glyphs, GlyphLayout{
+				Text:   c,
+				Offset: sources[i][offsets[j]],
+				X:      x + edges[j],
+				Width:  edges[j+1] - edges[j],
+			})
+		}
+		out.Lines = append(out.Lines, ll)
+
+		yTop += h * spacing
+	}
+	return out
+}
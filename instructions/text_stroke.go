@@ -0,0 +1,129 @@
+package instructions
+
+import (
+	"image"
+	"math"
+
+	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/internal/core/geom"
+	"github.com/Krispeckt/glimo/internal/render"
+	"golang.org/x/image/font/sfnt"
+)
+
+// StrokeQuality selects how text strokes are built.
+type StrokeQuality int
+
+const (
+	// StrokeQualityAuto dilates the glyph mask for strokes up to
+	// outlineStrokeMin pixels wide and strokes the glyph outlines above that.
+	StrokeQualityAuto StrokeQuality = iota
+	// StrokeQualityDilate grows the rasterized glyphs by a disk of the stroke
+	// width. Its cost grows with the square of the width.
+	StrokeQualityDilate
+	// StrokeQualityOutline strokes the glyph contours as vector paths with
+	// round joins, through the Line engine. Its cost grows only with the
+	// length of the outline, and curves stay smooth at any width.
+	StrokeQualityOutline
+)
+
+// outlineStrokeMin is the widest stroke StrokeQualityAuto still dilates.
+const outlineStrokeMin = 6
+
+// SetStrokeQuality selects how the stroke is built; see StrokeQuality.
+// Fonts without vector outlines (see render.Font.Outlined) are always
+// dilated. Returns the receiver for chaining.
+func (t *Text) SetStrokeQuality(q StrokeQuality) *Text {
+	t.strokeQuality = q
+	return t
+}
+
+// StrokeQuality returns the stroke quality set with SetStrokeQuality.
+func (t *Text) StrokeQuality() StrokeQuality { return t.strokeQuality }
+
+// useOutlineStroke reports whether the stroke is built from glyph outlines.
+func (t *Text) useOutlineStroke() bool {
+	switch t.strokeQuality {
+	case StrokeQualityOutline:
+		return true
+	case StrokeQualityDilate:
+		return false
+	}
+	return t.strokeWidth > outlineStrokeMin
+}
+
+// drawOutlineStroke strokes the glyph contours of s with a line twice the
+// stroke width, removes the fill coverage so only the outer band remains,
+// like the dilated stroke, and composites it with the stroke pattern. It
+// reports false, drawing nothing, when fnt has no usable outlines.
+func (t *Text) drawOutlineStroke(base, overlay *image.RGBA, fnt *render.Font, s string, x, topY float64) bool {
+	yq := geom.Quant64(topY)
+	xq := geom.Quant64(x)
+	baselineY := math.Floor(yq) + math.Round(fnt.BaselineForTopY(0))
+	segs, ok := fnt.StringOutline(s, math.Floor(xq)+subpixelX(fnt, xq), baselineY)
+	if !ok {
+		return false
+	}
+	if len(segs) == 0 {
+		return true
+	}
+
+	b := segs.Bounds()
+	pad := t.strokeWidth + 1
+	r := image.Rect(
+		int(math.Floor(geom.Unfix(b.Min.X)-pad)), int(math.Floor(geom.Unfix(b.Min.Y)-pad)),
+		int(math.Ceil(geom.Unfix(b.Max.X)+pad)), int(math.Ceil(geom.Unfix(b.Max.Y)+pad)),
+	)
+	visible := r.Intersect(base.Bounds()).Intersect(overlay.Bounds())
+	if visible.Empty() {
+		return true
+	}
+
+	// Trace in mask coordinates; the mask spans r.
+	line := NewLine().
+		SetLineWidth(2 * t.strokeWidth).
+		SetLineCap(LineCapRound).
+		SetLineJoin(LineJoinRound).
+		SetStrokePattern(colors.Black.MakeSolidPattern()).
+		SetAntiAlias(!t.aliased)
+	addOutline(line, segs, float64(r.Min.X), float64(r.Min.Y))
+	mask := render.GetRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	defer render.PutRGBA(mask)
+	line.Stroke().Draw(mask, mask)
+	line.release()
+
+	// Keep only the band outside the glyphs, as drawProcess fills them.
+	if fill, fx, fy := fillMask(fnt, s, xq, yq); fill != nil {
+		subtractMaskAt(mask, fill, fx-r.Min.X, fy-r.Min.Y)
+	}
+	if t.aliased {
+		thresholdMask(mask)
+	}
+	compositePatternWithMask(base, overlay, mask, r.Min.X, r.Min.Y, r, t.strokePatternColor, t.accurate)
+	return true
+}
+
+// addOutline adds the contours segs to line, moved by (-ox, -oy), as closed
+// subpaths.
+func addOutline(line *Line, segs sfnt.Segments, ox, oy float64) {
+	pt := func(i int, seg sfnt.Segment) (float64, float64) {
+		return geom.Unfix(seg.Args[i].X) - ox, geom.Unfix(seg.Args[i].Y) - oy
+	}
+	for _, seg := range segs {
+		switch seg.Op {
+		case sfnt.SegmentOpMoveTo:
+			line.ClosePath().NewSubPath().MoveTo(pt(0, seg))
+		case sfnt.SegmentOpLineTo:
+			line.LineTo(pt(0, seg))
+		case sfnt.SegmentOpQuadTo:
+			x1, y1 := pt(0, seg)
+			x2, y2 := pt(1, seg)
+			line.QuadraticTo(x1, y1, x2, y2)
+		case sfnt.SegmentOpCubeTo:
+			x1, y1 := pt(0, seg)
+			x2, y2 := pt(1, seg)
+			x3, y3 := pt(2, seg)
+			line.CubicTo(x1, y1, x2, y2, x3, y3)
+		}
+	}
+	line.ClosePath()
+}
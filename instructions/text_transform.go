@@ -0,0 +1,145 @@
+package instructions
+
+import (
+	"image"
+	"math"
+
+	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/internal/core/geom"
+	"github.com/Krispeckt/glimo/internal/render"
+	"golang.org/x/image/font/sfnt"
+)
+
+// SetRotation turns the text by deg degrees clockwise about pivot, a point
+// of the unrotated block. Glyphs are filled and stroked from their vector
+// outlines placed along the rotated baselines, so they stay crisp at any
+// angle. Rotated text draws its fill and stroke only: line backgrounds,
+// highlights, decorations, inline objects and auto contrast are skipped.
+// Position and Size then report the rotated bounding box, which is what
+// containers lay out; Layout still describes the unrotated lines.
+//
+// Fonts drawn without shaping have no outlines (see render.Font.Outlined):
+// such text is drawn unrotated, Position and Size report the unrotated box,
+// and a *ConfigError is recorded (see Err).
+func (t *Text) SetRotation(deg float64, pivot Anchor) *Text {
+	t.setRotation(deg, pivot)
+	t.checkOutlined("SetRotation")
+	return t
+}
+
+// SetTransform applies m to the glyph outlines about origin, a point of the
+// unrotated block, after any rotation; ShearMatrix(-0.2, 0) gives a slanted
+// "speed" look to fonts without an italic. The zero Matrix removes the
+// transform. Transformed text is drawn with the same limits as rotated text.
+func (t *Text) SetTransform(m Matrix, origin Anchor) *Text {
+	t.setTransform(m, origin)
+	t.checkOutlined("SetTransform")
+	return t
+}
+
+// drawsTransformed reports whether the text is drawn through its transform,
+// which needs a font with outlines.
+func (t *Text) drawsTransformed() bool {
+	return t.transformed() && t.font != nil && t.font.Outlined()
+}
+
+// checkOutlined records a ConfigError when the text has a transform its
+// font cannot apply.
+func (t *Text) checkOutlined(setter string) {
+	t.check(!t.transformed() || t.font == nil || t.font.Outlined(), "Text", setter,
+		"font has no outlines (shaping disabled); drawn untransformed")
+}
+
+// blockMatrix returns the transform of the unrotated text block.
+func (t *Text) blockMatrix() geom.Matrix {
+	sz := t.blockSize()
+	return t.transformMatrix(t.x, t.y, sz.Width(), sz.Height())
+}
+
+// transformedBlock returns the bounds of the transformed text block.
+func (t *Text) transformedBlock() (x, y, w, h float64) {
+	sz := t.blockSize()
+	return transformedBox(t.blockMatrix(), t.x, t.y, sz.Width(), sz.Height())
+}
+
+// transformedBounds returns the pixel bounds of r transformed with the text
+// block.
+func (t *Text) transformedBounds(r image.Rectangle) image.Rectangle {
+	return transformRect(t.blockMatrix(), r)
+}
+
+// drawTransformed fills and strokes the glyph outlines of every line through
+// the block transform. It reports false, drawing nothing, when a line's font has
+// no usable outlines.
+func (t *Text) drawTransformed(base, overlay *image.RGBA, lines []string, paraOf []int, spacing float64) bool {
+	var segs sfnt.Segments
+	yTop := t.y
+	for i, line := range lines {
+		if t.maxLines > 0 && i >= t.maxLines {
+			break
+		}
+		lineFont := t.fontForLine(i)
+		w, _ := lineFont.MeasureString(line)
+		x := t.alignX(t.x, w, t.alignForParagraph(paraOf[i]))
+		ls, ok := lineFont.StringOutline(visualLine(line, t.paragraphRTL(paraOf[i])), x, lineFont.BaselineForTopY(yTop))
+		if !ok {
+			return false
+		}
+		segs = append(segs, ls...)
+		yTop += lineFont.LineHeightPx() * spacing
+	}
+	if len(segs) == 0 {
+		return true
+	}
+
+	stroked := t.strokePatternColor != nil && t.strokeWidth > 0
+	b := segs.Bounds()
+	r := t.transformedBounds(image.Rect(
+		int(math.Floor(geom.Unfix(b.Min.X))), int(math.Floor(geom.Unfix(b.Min.Y))),
+		int(math.Ceil(geom.Unfix(b.Max.X))), int(math.Ceil(geom.Unfix(b.Max.Y))),
+	)).Inset(-int(math.Ceil(t.strokeWidth + 1)))
+	r = r.Intersect(base.Bounds()).Intersect(overlay.Bounds())
+	if r.Empty() {
+		return true
+	}
+
+	// Trace in mask coordinates; the masks span r.
+	m := t.blockMatrix().Multiply(geom.Translate(-float64(r.Min.X), -float64(r.Min.Y)))
+	trace := func() *Line {
+		line := NewLine().WithMatrix(m).SetAntiAlias(!t.aliased)
+		addOutline(line, segs, 0, 0)
+		return line
+	}
+
+	fill := render.GetRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	defer render.PutRGBA(fill)
+	line := trace().SetFillPattern(colors.Black.MakeSolidPattern())
+	line.Fill().Draw(fill, fill)
+	line.release()
+
+	if stroked {
+		stroke := render.GetRGBA(fill.Bounds())
+		defer render.PutRGBA(stroke)
+		line := trace().
+			SetLineWidth(2 * t.strokeWidth).
+			SetLineCap(LineCapRound).
+			SetLineJoin(LineJoinRound).
+			SetStrokePattern(colors.Black.MakeSolidPattern())
+		line.Stroke().Draw(stroke, stroke)
+		line.release()
+
+		// Keep only the band outside the glyphs, like drawOutlineStroke.
+		subtractMaskAt(stroke, fill, 0, 0)
+		if t.aliased {
+			thresholdMask(stroke)
+		}
+		compositePatternWithMask(base, overlay, stroke, r.Min.X, r.Min.Y, r, t.strokePatternColor, t.accurate)
+	}
+	if t.colorPattern != nil {
+		if t.aliased {
+			thresholdMask(fill)
+		}
+		compositePatternWithMask(base, overlay, fill, r.Min.X, r.Min.Y, r, t.colorPattern, t.accurate)
+	}
+	return true
+}
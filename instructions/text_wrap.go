@@ -12,37 +12,103 @@ import (
 	"github.com/Krispeckt/glimo/internal/render"
 )
 
-// wrapTextScaled splits text by logical paragraphs, wraps per line using the current WrapMode,
+// wrapKey holds the wrap inputs that can change without going through a Text
+// setter, such as the size of a shared Font, so a cached wrap is still
+// recomputed when they differ.
+type wrapKey struct {
+	text        string
+	maxWidth    float64
+	font        *render.Font
+	sizePt, dpi float64
+	wrapMode    WrapMode
+	scaleStep   float64
+}
+
+// wrapCache holds the last result of wrapTextScaled and the Size measured
+// from it.
+type wrapCache struct {
+	valid  bool
+	key    wrapKey
+	lines  []string
+	paraOf []int
+	size   *geom.Size // nil until measured
+}
+
+// InvalidateLayout discards the cached line wrap and size, so the next Size,
+// Draw or Layout wraps the text again. Setters of Text invalidate it themselves; call
+// this after changing a Font the text uses in place, other than its size.
+func (t *Text) InvalidateLayout() *Text {
+	t.wrap = wrapCache{}
+	return t
+}
+
+// wrapTextScaled returns the wrapped lines and their paragraph indices (see
+// wrapLines), reusing the previous result while its inputs are unchanged.
+// Callers must not modify the returned slices.
+func (t *Text) wrapTextScaled() ([]string, []int) {
+	key := wrapKey{
+		text:      t.text,
+		maxWidth:  t.maxWidth,
+		font:      t.font,
+		wrapMode:  t.wrapMode,
+		scaleStep: t.scaleStep,
+	}
+	if t.font != nil {
+		key.sizePt, key.dpi = t.font.HeightPt(), t.font.DPI()
+	}
+	if t.wrap.valid && t.wrap.key == key {
+		return t.wrap.lines, t.wrap.paraOf
+	}
+	lines, paraOf := t.wrapLines()
+	t.wrap = wrapCache{valid: true, key: key, lines: lines, paraOf: paraOf}
+	return lines, paraOf
+}
+
+// wrapLines splits text by logical paragraphs, wraps per line using the current WrapMode,
 // applies per-line scaling via t.fontForLine, and enforces maxLines with an ellipsis
-// when there is undisplayed content.
+// when there is undisplayed content. The second result holds, for each output line,
+// the index of the source paragraph it came from.
 //
 // Notes:
 // - Line endings are normalized to '\n'.
 // - Unicode grapheme clusters are respected for all symbol-level operations.
 // - NBSP (U+00A0) is treated as non-breaking in word mode (it stays inside tokens).
 // - Hyphen/wrapSymbol is appended only when breaking inside a "word" boundary.
+// - Soft hyphens (U+00AD) and ZWSP (U+200B) are invisible break points in word mode.
+// - ZWNJ (U+200C) is not a break point and stays in the text for shaping.
+// - Tab stops and preserved indentation are expanded to NBSP before wrapping (see expandWhitespace).
+// - A soft hyphen renders wrapSymbol only when a line actually breaks at it.
+// - Automatic hyphenation inserts soft hyphens at legal points before word wrapping.
 // - Measurement caching is per Font pointer; pointer stability is assumed.
 //
 // Complexity:
 // - Word mode uses prefix sums per line to avoid string joins during fit checks.
 // - Symbol mode uses binary search over grapheme clusters.
-func (t *Text) wrapTextScaled() []string {
+func (t *Text) wrapLines() ([]string, []int) {
 	// Fast path: no wrapping requested.
 	if t.maxWidth <= 0 {
-		return strings.Split(normalizeNewlines(t.text), "\n")
+		out := strings.Split(stripSoftBreaks(normalizeNewlines(t.text)), "\n")
+		paraOf := make([]int, len(out))
+		for i := range paraOf {
+			paraOf[i] = i
+			out[i] = t.expandWhitespace(out[i], t.fontForLine(i))
+		}
+		return out, paraOf
 	}
 
 	var out []string
+	var paraOf []int
 	truncated := false
 	lineIdx := 0
 
 	// Helper: append a line and, if maxLines is reached while more content exists,
 	// append an ellipsis and mark as truncated.
-	appendAndMaybeTruncate := func(s string, hasMore bool) {
+	appendAndMaybeTruncate := func(s string, para int, hasMore bool) {
 		if truncated {
 			return
 		}
 		out = append(out, s)
+		paraOf = append(paraOf, para)
 		if t.maxLines > 0 && len(out) == t.maxLines && hasMore {
 			lastFont := t.fontForLine(t.maxLines - 1)
 			out = appendEllipsisGraphemes(out, lastFont, t.maxWidth)
@@ -60,16 +126,17 @@ func (t *Text) wrapTextScaled() []string {
 
 		// Preserve empty line as paragraph break.
 		if p == "" {
-			appendAndMaybeTruncate("", pi < len(paras)-1)
+			appendAndMaybeTruncate("", pi, pi < len(paras)-1)
 			lineIdx++
 			continue
 		}
 
+		p = t.expandWhitespace(p, t.fontForLine(lineIdx))
 		var sub []string
 		if t.wrapMode == WrapBySymbol {
-			sub = t.wrapParaBySymbolsScaled(p, &lineIdx)
+			sub = t.wrapParaBySymbolsScaled(stripSoftBreaks(p), &lineIdx)
 		} else {
-			sub = t.wrapParaByWordsScaled(p, &lineIdx)
+			sub = t.wrapParaByWordsScaled(t.hyphenate(p), &lineIdx)
 		}
 
 		for si, s := range sub {
@@ -77,17 +144,17 @@ func (t *Text) wrapTextScaled() []string {
 				break
 			}
 			hasMore := si < len(sub)-1 || pi < len(paras)-1
-			appendAndMaybeTruncate(s, hasMore)
+			appendAndMaybeTruncate(s, pi, hasMore)
 		}
 
 		// Preserve blank line following a paragraph if it exists.
 		if !truncated && pi < len(paras)-1 && paras[pi+1] == "" {
-			appendAndMaybeTruncate("", pi+1 < len(paras)-1)
+			appendAndMaybeTruncate("", pi+1, pi+1 < len(paras)-1)
 			lineIdx++
 		}
 	}
 
-	return out
+	return out, paraOf
 }
 
 // wrapParaByWordsScaled wraps a paragraph at word boundaries.
@@ -98,6 +165,7 @@ func (t *Text) wrapTextScaled() []string {
 // - Split only on ASCII space ' ' and TAB '\t'.
 // - NBSP (U+00A0) remains inside tokens and will not break lines by itself.
 // - Runs of separators collapse to a single gap in output by design.
+// - Soft break marks inside a token are preferred break points over grapheme splitting.
 func (t *Text) wrapParaByWordsScaled(p string, lineIdxPtr *int) []string {
 	words := splitWordsPreserveNBSP(p)
 	if len(words) == 0 {
@@ -145,9 +213,20 @@ func (t *Text) wrapParaByWordsScaled(p string, lineIdxPtr *int) []string {
 		f := t.fontForLine(*lineIdxPtr)
 		width := t.maxWidth
 
-		// If one word is too long, split it progressively by graphemes.
-		if measure(f, words[i]) > width {
-			chunks := t.splitLongTokenProgressive(words[i], lineIdxPtr, measure)
+		// If one word is too long, break it at a soft break mark when possible,
+		// otherwise split it progressively by graphemes.
+		if measure(f, stripSoftBreaks(words[i])) > width {
+			if head, tail, ok := t.softBreakPrefix(words[i], width, f, measure); ok {
+				lines = append(lines, head)
+				*lineIdxPtr++
+				if tail == "" {
+					i++
+				} else {
+					words[i] = tail
+				}
+				continue
+			}
+			chunks := t.splitLongTokenProgressive(stripSoftBreaks(words[i]), lineIdxPtr, measure)
 			lines = append(lines, chunks...)
 			i++
 			continue
@@ -159,7 +238,7 @@ func (t *Text) wrapParaByWordsScaled(p string, lineIdxPtr *int) []string {
 		rem := words[i:]
 		wW := make([]float64, len(rem))
 		for k := range rem {
-			wW[k] = measure(f, rem[k])
+			wW[k] = measure(f, stripSoftBreaks(rem[k]))
 		}
 		pref := make([]float64, len(rem)+1)
 		for k := 1; k <= len(rem); k++ {
@@ -179,7 +258,7 @@ func (t *Text) wrapParaByWordsScaled(p string, lineIdxPtr *int) []string {
 		lo, hi := 1, len(rem)
 		if wW[0] > width {
 			// Defensive: should be handled above.
-			lines = append(lines, rem[0])
+			lines = append(lines, stripSoftBreaks(rem[0]))
 			*lineIdxPtr++
 			i++
 			continue
@@ -193,10 +272,29 @@ func (t *Text) wrapParaByWordsScaled(p string, lineIdxPtr *int) []string {
 			}
 		}
 		count := hi
-		line := joinWithSpaces(rem[:count])
+		visible := make([]string, count)
+		for k := range visible {
+			visible[k] = stripSoftBreaks(rem[k])
+		}
+		line := joinWithSpaces(visible)
+		next := i + count
+
+		// Pull the head of the next word onto this line if it has a soft break that fits.
+		if count < len(rem) {
+			avail := width - widthOf(0, count) - spaceW
+			if head, tail, ok := t.softBreakPrefix(rem[count], avail, f, measure); ok {
+				line += " " + head
+				if tail == "" {
+					next++
+				} else {
+					words[next] = tail
+				}
+			}
+		}
+
 		lines = append(lines, line)
 		*lineIdxPtr++
-		i += count
+		i = next
 	}
 
 	return lines
@@ -285,6 +383,31 @@ func (t *Text) wrapParaBySymbolsScaled(p string, lineIdxPtr *int) []string {
 	return lines
 }
 
+// softBreakPrefix finds the longest prefix of token that ends at a soft break
+// mark and fits into avail under font f. The returned head is the visible text
+// (with wrapSymbol appended when breaking at a soft hyphen); tail is the rest
+// of the token after the mark. ok is false when no soft break fits.
+func (t *Text) softBreakPrefix(token string, avail float64, f *render.Font, measure func(*render.Font, string) float64) (head, tail string, ok bool) {
+	if avail <= 0 {
+		return "", "", false
+	}
+	for end := len(token); ; {
+		j := strings.LastIndexAny(token[:end], softBreakChars)
+		if j < 0 {
+			return "", "", false
+		}
+		r, size := utf8.DecodeRuneInString(token[j:])
+		h := stripSoftBreaks(token[:j])
+		if r == softHyphen {
+			h += t.wrapSymbol
+		}
+		if h != "" && measure(f, h) <= avail {
+			return h, token[j+size:], true
+		}
+		end = j
+	}
+}
+
 // splitLongTokenProgressive splits a single overlong token by grapheme clusters,
 // producing a sequence of lines, each fitting under the current line font.
 // It appends wrapSymbol at internal breaks when splitting inside a word.
@@ -458,6 +581,79 @@ func appendEllipsisGraphemes(lines []string, f *render.Font, maxWidth float64) [
 	return lines
 }
 
+// defaultTabWidth is the tab stop interval, in spaces, used for preserved
+// indentation when no tab width is set.
+const defaultTabWidth = 4
+
+// expandWhitespace applies tab stops and indentation preservation to one
+// paragraph laid out with font f. Affected whitespace becomes NBSP so word
+// wrapping neither splits nor collapses it.
+//
+// Each tab advances to the next multiple of tabWidth space widths, measured
+// from the start of the paragraph and rounded to whole spaces, so stops line
+// up exactly in monospaced fonts and to within half a space otherwise.
+func (t *Text) expandWhitespace(p string, f *render.Font) string {
+	if t.tabWidth <= 0 && !t.preserveIndent {
+		return p
+	}
+	tabs := t.tabWidth
+	if tabs <= 0 {
+		tabs = defaultTabWidth
+	}
+	spaceW, _ := f.MeasureString(nbsp)
+
+	var b strings.Builder
+	indent := t.preserveIndent
+	for _, r := range p {
+		switch {
+		case r == '\t' && (t.tabWidth > 0 || indent):
+			n := 1
+			if spaceW > 0 {
+				w, _ := f.MeasureString(stripSoftBreaks(b.String()))
+				stop := spaceW * float64(tabs)
+				next := (math.Floor(w/stop+1e-9) + 1) * stop
+				n = max(int(math.Round((next-w)/spaceW)), 1)
+			}
+			b.WriteString(strings.Repeat(nbsp, n))
+		case r == ' ' && indent:
+			b.WriteString(nbsp)
+		default:
+			indent = false
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// nbsp is the no-break space that expanded whitespace is made of.
+const nbsp = "\u00A0"
+
+// Soft break marks honored by word wrapping.
+const (
+	softHyphen     = '\u00AD'
+	zeroWidthSpace = '\u200B'
+
+	softBreakChars = "\u00AD\u200B"
+)
+
+// isSoftBreak reports whether r is an invisible break opportunity mark.
+func isSoftBreak(r rune) bool {
+	return r == softHyphen || r == zeroWidthSpace
+}
+
+// stripSoftBreaks removes soft hyphens and zero-width break marks from s.
+func stripSoftBreaks(s string) string {
+	if !strings.ContainsAny(s, softBreakChars) {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if isSoftBreak(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
 // normalizeNewlines converts CRLF and CR to LF.
 func normalizeNewlines(s string) string {
 	s = strings.ReplaceAll(s, "\r\n", "\n")
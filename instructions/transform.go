@@ -0,0 +1,161 @@
+package instructions
+
+import (
+	"image"
+	"math"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// Matrix is a 2D affine transform for SetTransform. Start from
+// IdentityMatrix or ShearMatrix and chain Translate, Scale, Rotate (in
+// radians) and Shear; each applies before the transform built so far.
+type Matrix = geom.Matrix
+
+// IdentityMatrix returns the transform that leaves points unchanged.
+func IdentityMatrix() Matrix { return geom.Identity() }
+
+// ShearMatrix returns a transform that moves x by sx per pixel of y and y by
+// sy per pixel of x. ShearMatrix(-0.2, 0) slants upright content to the
+// right like an italic.
+func ShearMatrix(sx, sy float64) Matrix { return geom.Shear(sx, sy) }
+
+// shapeTransform holds the rotation set by a shape's SetRotation and the
+// matrix set by SetTransform. Shapes embed it and pass transformMatrix to
+// the Line that builds their path, so edges are rasterized transformed
+// rather than resampled. Images and containers have no path of their own
+// and are resampled instead (see drawResampled).
+type shapeTransform struct {
+	// angle is in degrees, clockwise on screen like Image.Rotate.
+	angle float64
+	pivot Anchor
+
+	// matrix is applied after the rotation, about origin; zero when unset.
+	matrix geom.Matrix
+	origin Anchor
+}
+
+// setRotation stores the angle and pivot.
+func (s *shapeTransform) setRotation(deg float64, pivot Anchor) {
+	s.angle, s.pivot = deg, pivot
+}
+
+// setTransform stores the matrix and its origin.
+func (s *shapeTransform) setTransform(m Matrix, origin Anchor) {
+	s.matrix, s.origin = m, origin
+}
+
+// Rotation returns the angle in degrees and the pivot set by SetRotation.
+func (s *shapeTransform) Rotation() (float64, Anchor) { return s.angle, s.pivot }
+
+// Transform returns the matrix and origin set by SetTransform, or the
+// identity when none is set.
+func (s *shapeTransform) Transform() (Matrix, Anchor) {
+	if s.matrix == (geom.Matrix{}) {
+		return geom.Identity(), s.origin
+	}
+	return s.matrix, s.origin
+}
+
+// transformed reports whether the shape is drawn through a transform.
+func (s *shapeTransform) transformed() bool {
+	return math.Mod(s.angle, 360) != 0 || (s.matrix != geom.Matrix{} && s.matrix != geom.Identity())
+}
+
+// transformMatrix returns the rotation about the pivot, then the matrix
+// about its origin, of the w×h box at (x, y).
+func (s *shapeTransform) transformMatrix(x, y, w, h float64) geom.Matrix {
+	m := geom.Identity()
+	if math.Mod(s.angle, 360) != 0 {
+		px, py := s.pivot.point(x, y, w, h)
+		m = geom.Translate(-px, -py).
+			Multiply(geom.Rotate(geom.Deg2Rad(s.angle))).
+			Multiply(geom.Translate(px, py))
+	}
+	if s.matrix != (geom.Matrix{}) {
+		ox, oy := s.origin.point(x, y, w, h)
+		m = m.Multiply(geom.Translate(-ox, -oy)).
+			Multiply(s.matrix).
+			Multiply(geom.Translate(ox, oy))
+	}
+	return m
+}
+
+// transformBox returns the bounds of the w×h box at (x, y) after the
+// transform, which is what Position and Size report; the box itself when
+// the shape is not transformed.
+func (s *shapeTransform) transformBox(x, y, w, h float64) (bx, by, bw, bh float64) {
+	if !s.transformed() {
+		return x, y, w, h
+	}
+	return transformedBox(s.transformMatrix(x, y, w, h), x, y, w, h)
+}
+
+// boundsAfter returns r, a region drawn by the untransformed shape whose
+// box is the w×h box at (x, y), after the transform.
+func (s *shapeTransform) boundsAfter(r image.Rectangle, x, y, w, h float64) image.Rectangle {
+	if !s.transformed() || r.Empty() {
+		return r
+	}
+	return transformRect(s.transformMatrix(x, y, w, h), r)
+}
+
+// drawResampled draws a shape without a path of its own through the
+// transform of the w×h box at (x, y). draw, the shape's own Draw, renders
+// it untransformed over src onto a transparent buffer, which is resampled
+// onto overlay. The content is composited with normal blending, as it no
+// longer sees the base. It reports false, drawing nothing, when the shape
+// is not transformed, in which case Draw carries on as usual.
+func (s *shapeTransform) drawResampled(overlay *image.RGBA, src image.Rectangle, x, y, w, h float64, draw func(base, overlay *image.RGBA)) bool {
+	if !s.transformed() || overlay == nil {
+		return false
+	}
+	m := s.transformMatrix(x, y, w, h)
+	src = src.Inset(-dirtyPad)
+	if transformRect(m, src).Intersect(overlay.Bounds()).Empty() {
+		return true
+	}
+
+	saved := *s
+	*s = shapeTransform{}
+	content := image.NewRGBA(src)
+	draw(image.NewRGBA(src), content)
+	*s = saved
+
+	xdraw.BiLinear.Transform(overlay, f64.Aff3{m.XX, m.XY, m.X0, m.YX, m.YY, m.Y0},
+		content, src, xdraw.Over, nil)
+	return true
+}
+
+// scaledBy returns the transform for a device scale of f: the matrix
+// translation is in pixels and grows with it.
+func (s shapeTransform) scaledBy(f float64) shapeTransform {
+	s.matrix.X0 *= f
+	s.matrix.Y0 *= f
+	return s
+}
+
+// transformedBox returns the axis-aligned bounds of the w×h box at (x, y)
+// transformed by m.
+func transformedBox(m geom.Matrix, x, y, w, h float64) (bx, by, bw, bh float64) {
+	x0, y0 := math.Inf(1), math.Inf(1)
+	x1, y1 := math.Inf(-1), math.Inf(-1)
+	for _, c := range [4][2]float64{{x, y}, {x + w, y}, {x, y + h}, {x + w, y + h}} {
+		tx, ty := m.TransformPoint(c[0], c[1])
+		x0, y0 = math.Min(x0, tx), math.Min(y0, ty)
+		x1, y1 = math.Max(x1, tx), math.Max(y1, ty)
+	}
+	return x0, y0, x1 - x0, y1 - y0
+}
+
+// transformRect returns the pixel bounds of r transformed by m.
+func transformRect(m geom.Matrix, r image.Rectangle) image.Rectangle {
+	x, y, w, h := transformedBox(m,
+		float64(r.Min.X), float64(r.Min.Y), float64(r.Dx()), float64(r.Dy()))
+	return image.Rect(
+		int(math.Floor(x)), int(math.Floor(y)),
+		int(math.Ceil(x+w)), int(math.Ceil(y+h)),
+	)
+}
@@ -56,3 +56,33 @@ func (c *Effects) Clear() *Effects {
 
 // Count returns the number of stored effects.
 func (c *Effects) Count() int { return len(c.list) }
+
+// EstimateCost returns the largest temporary allocation of any stored effect
+// and the sum of their operations on a w×h buffer (see effects.EstimateCost).
+// Effects run one after another, so their temporaries never coexist.
+func (c *Effects) EstimateCost(w, h int) (tempBytes, ops int64) {
+	if c == nil {
+		return 0, 0
+	}
+	for _, e := range c.list {
+		t, o := effects.EstimateCost(e, w, h)
+		tempBytes = max(tempBytes, t)
+		ops += o
+	}
+	return tempBytes, ops
+}
+
+// MaxRadius returns the largest radius of the stored effects that report one
+// (see effects.Radiused), or 0.
+func (c *Effects) MaxRadius() float64 {
+	if c == nil {
+		return 0
+	}
+	var r float64
+	for _, e := range c.list {
+		if re, ok := e.(effects.Radiused); ok {
+			r = max(r, re.Radius())
+		}
+	}
+	return r
+}
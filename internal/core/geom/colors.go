@@ -8,16 +8,32 @@ import (
 
 // Color Space Conversion
 
+// srgbLUT holds the linear value of every 8-bit sRGB level.
+var srgbLUT = func() (t [256]float64) {
+	for i := range t {
+		t[i] = SrgbToLinear(float64(i) / 255)
+	}
+	return
+}()
+
+// linearSteps[v] is the linear value from which an sRGB channel rounds to
+// level v+1 instead of v.
+var linearSteps = func() (t [255]float64) {
+	for i := range t {
+		t[i] = SrgbToLinear((float64(i) + 0.5) / 255)
+	}
+	return
+}()
+
 // SrgbToLinear8 converts an 8-bit sRGB channel value (0–255) to a linear float64 value (0–1).
+// It reads a precomputed table.
 func SrgbToLinear8(u uint8) float64 {
-	c := float64(u) / 255.0
-	if c <= 0.04045 {
-		return c / 12.92
-	}
-	return math.Pow((c+0.055)/1.055, 2.4)
+	return srgbLUT[u]
 }
 
-// LinearToSRGB8 converts a linear float64 value (0–1) to an 8-bit sRGB channel value (0–255).
+// LinearToSRGB8 converts a linear float64 value (0–1) to an 8-bit sRGB channel value (0–255),
+// rounded to the nearest level. It binary-searches the level boundaries
+// instead of evaluating the transfer function.
 func LinearToSRGB8(f float64) uint8 {
 	if f <= 0 {
 		return 0
@@ -25,13 +41,17 @@ func LinearToSRGB8(f float64) uint8 {
 	if f >= 1 {
 		return 255
 	}
-	var c float64
-	if f <= 0.0031308 {
-		c = 12.92 * f
-	} else {
-		c = 1.055*math.Pow(f, 1.0/2.4) - 0.055
+	// Count the boundaries at or below f.
+	lo, hi := 0, len(linearSteps)
+	for lo < hi {
+		m := (lo + hi) / 2
+		if linearSteps[m] <= f {
+			lo = m + 1
+		} else {
+			hi = m
+		}
 	}
-	return uint8(math.Round(c * 255))
+	return uint8(lo)
 }
 
 // SrgbToLinear converts an sRGB channel value (0–1) to linear light space (0–1).
@@ -52,20 +72,44 @@ func LinearToSrgb(c float64) float64 {
 	return 1.055*math.Pow(c, 1.0/2.4) - 0.055
 }
 
+// Alpha Premultiplication
+
+// PremultiplyRGB scales straight color channels by alpha a, the form
+// image.RGBA stores.
+func PremultiplyRGB(r, g, b, a uint8) (uint8, uint8, uint8) {
+	return Mul255(r, a), Mul255(g, a), Mul255(b, a)
+}
+
+// UnpremultiplyRGB recovers straight color channels from channels
+// premultiplied by alpha a. Fully transparent pixels give black.
+func UnpremultiplyRGB(r, g, b, a uint8) (uint8, uint8, uint8) {
+	switch a {
+	case 0:
+		return 0, 0, 0
+	case 255:
+		return r, g, b
+	}
+	un := func(v uint8) uint8 { return uint8(min((int(v)*255+int(a)/2)/int(a), 255)) }
+	return un(r), un(g), un(b)
+}
+
 // Color Interpolation
 
 // LerpColor performs linear interpolation between two colors c1 and c2.
 // Parameter t defines the interpolation amount, where 0 = c1 and 1 = c2.
+// Channels are interpolated premultiplied, so fading toward a transparent
+// color does not darken the edge, and the result is a premultiplied
+// color.RGBA.
 func LerpColor(c1, c2 color.Color, t float64) color.Color {
 	r1, g1, b1, a1 := c1.RGBA()
 	r2, g2, b2, a2 := c2.RGBA()
 
-	r := uint8(Lerp(float64(r1>>8), float64(r2>>8), t))
-	g := uint8(Lerp(float64(g1>>8), float64(g2>>8), t))
-	b := uint8(Lerp(float64(b1>>8), float64(b2>>8), t))
-	a := uint8(Lerp(float64(a1>>8), float64(a2>>8), t))
+	r := uint8(math.Round(Lerp(float64(r1>>8), float64(r2>>8), t)))
+	g := uint8(math.Round(Lerp(float64(g1>>8), float64(g2>>8), t)))
+	b := uint8(math.Round(Lerp(float64(b1>>8), float64(b2>>8), t)))
+	a := uint8(math.Round(Lerp(float64(a1>>8), float64(a2>>8), t)))
 
-	return color.NRGBA{R: r, G: g, B: b, A: a}
+	return color.RGBA{R: r, G: g, B: b, A: a}
 }
 
 // GetColor returns a color interpolated from a set of color stops.
@@ -78,7 +122,13 @@ func GetColor(t float64, stops Stops) color.Color {
 	for i := 1; i < len(stops); i++ {
 		if t <= stops[i].Position() {
 			p0, p1 := stops[i-1], stops[i]
+			if p1.Position() <= p0.Position() {
+				return p1.Color()
+			}
 			f := Norm(t, p0.Position(), p1.Position())
+			if h, ok := p1.Hint(); ok {
+				f = hintCurve(f, Norm(h, p0.Position(), p1.Position()))
+			}
 			return LerpColor(p0.Color(), p1.Color(), f)
 		}
 	}
@@ -86,6 +136,21 @@ func GetColor(t float64, stops Stops) color.Color {
 	return stops[len(stops)-1].Color()
 }
 
+// hintCurve remaps the interpolation amount f so that f = h gives 0.5, using
+// the CSS Images 4 color hint formula f^(log 0.5 / log h). Hints at the ends
+// of the interval make a hard edge there.
+func hintCurve(f, h float64) float64 {
+	switch {
+	case h <= 0:
+		return 1
+	case h >= 1:
+		return 0
+	case h == 0.5:
+		return f
+	}
+	return math.Pow(f, math.Log(0.5)/math.Log(h))
+}
+
 // Processing
 
 // BilinearRGBAAt performs bilinear interpolation on an RGBA image at floating-point coordinates (fx, fy).
@@ -76,6 +76,23 @@ func (a Matrix) Multiply(b Matrix) Matrix {
 	}
 }
 
+// Invert returns the inverse transformation, or the identity when a is
+// singular.
+func (a Matrix) Invert() Matrix {
+	det := a.XX*a.YY - a.XY*a.YX
+	if det == 0 {
+		return Identity()
+	}
+	return Matrix{
+		XX: a.YY / det,
+		YX: -a.YX / det,
+		XY: -a.XY / det,
+		YY: a.XX / det,
+		X0: (a.XY*a.Y0 - a.YY*a.X0) / det,
+		Y0: (a.YX*a.X0 - a.XX*a.Y0) / det,
+	}
+}
+
 // Transformations
 
 // TransformVector applies the linear (rotation, scale, shear) part of the matrix
@@ -12,6 +12,11 @@ import "image/color"
 type Stop struct {
 	pos   float64
 	color color.Color
+
+	// hint is where the color halfway to this stop falls, as a position
+	// between the previous stop and this one; used when hinted is set.
+	hint   float64
+	hinted bool
 }
 
 // Color returns the color associated with this stop.
@@ -32,6 +37,17 @@ func NewStop(pos float64, color color.Color) *Stop {
 	}
 }
 
+// NewStopWithHint creates a stop whose transition from the previous stop
+// reaches the halfway color at position hint, like a CSS color hint.
+func NewStopWithHint(pos float64, color color.Color, hint float64) *Stop {
+	return &Stop{pos: pos, color: color, hint: hint, hinted: true}
+}
+
+// Hint returns the transition hint of the stop and whether it has one.
+func (s *Stop) Hint() (float64, bool) {
+	return s.hint, s.hinted
+}
+
 // Gradient Stop Collection
 
 // Stops represents a slice of gradient stops.
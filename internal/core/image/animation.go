@@ -0,0 +1,365 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// Animation is a decoded animated image with fully composited frames.
+// Every frame covers the whole logical canvas, so frames can be drawn
+// independently without replaying disposal or blend operations.
+type Animation struct {
+	Frames []*image.RGBA   // Composited frames, all with identical bounds
+	Delays []time.Duration // Display duration per frame
+	Loops  int             // Number of plays; 0 means loop forever
+}
+
+// pngSignature is the 8-byte header shared by PNG and APNG files.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// LoadAnimation reads and decodes an animated GIF or APNG file within
+// DefaultDecodeLimits. Static PNG files decode into a single-frame animation.
+func LoadAnimation(path string) (*Animation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %q: %w", path, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	a, err := DecodeAnimation(f, DefaultDecodeLimits())
+	if err != nil {
+		return nil, fmt.Errorf("decode %q: %w", path, err)
+	}
+	return a, nil
+}
+
+// DecodeAnimation decodes a GIF or APNG stream within lim, detecting the
+// format from its header. Like SafeDecode, it checks the canvas size declared
+// in the header before allocating it and returns decoder panics as errors.
+// The expansion limit applies to all composited frames together.
+func DecodeAnimation(r io.Reader, lim DecodeLimits) (*Animation, error) {
+	data, err := readLimited(r, lim)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(data, []byte("GIF8")):
+		return timed(lim.Timeout, func() (*Animation, error) { return decodeGIF(data, lim) })
+	case bytes.HasPrefix(data, pngSignature):
+		return timed(lim.Timeout, func() (*Animation, error) { return decodeAPNG(data, lim) })
+	default:
+		return nil, fmt.Errorf("%w: only GIF and APNG animations are allowed", ErrUnsupportedFormat)
+	}
+}
+
+// decodeGIF decodes all GIF frames and applies their disposal methods.
+func decodeGIF(data []byte, lim DecodeLimits) (*Animation, error) {
+	cfg, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	// The decoder keeps frames inside the logical screen unless it is empty,
+	// in which case the first frame sizes the canvas and is checked below.
+	if cfg.Width > 0 && cfg.Height > 0 {
+		if err := lim.check(cfg, len(data)); err != nil {
+			return nil, err
+		}
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(g.Image) == 0 {
+		return nil, errors.New("gif contains no frames")
+	}
+
+	w, h := g.Config.Width, g.Config.Height
+	if w == 0 || h == 0 {
+		b := g.Image[0].Bounds()
+		w, h = b.Max.X, b.Max.Y
+	}
+	if err := lim.check(image.Config{Width: w, Height: h}, len(data)); err != nil {
+		return nil, err
+	}
+	if err := lim.checkFrames(w, h, len(g.Image), len(data)); err != nil {
+		return nil, err
+	}
+	canvas := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	a := &Animation{Loops: gifPlays(g.LoopCount)}
+	for i, frame := range g.Image {
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		a.Frames = append(a.Frames, cloneRGBA(canvas))
+		a.Delays = append(a.Delays, time.Duration(g.Delay[i])*10*time.Millisecond)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return a, nil
+}
+
+// gifPlays converts a GIF LoopCount to a number of plays (0 = forever).
+func gifPlays(loopCount int) int {
+	switch {
+	case loopCount == 0:
+		return 0
+	case loopCount < 0:
+		return 1
+	default:
+		return loopCount + 1
+	}
+}
+
+// pngChunk is a raw PNG chunk without its length and CRC fields.
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+// apngFrame describes a single fcTL region and its compressed image data.
+type apngFrame struct {
+	w, h, x, y int
+	delay      time.Duration
+	dispose    byte
+	blend      byte
+	data       [][]byte
+}
+
+// APNG dispose and blend operations.
+const (
+	apngDisposeNone       = 0
+	apngDisposeBackground = 1
+	apngDisposePrevious   = 2
+	apngBlendSource       = 0
+)
+
+// decodeAPNG decodes an APNG stream. Files without an acTL chunk decode
+// as a static single-frame animation.
+func decodeAPNG(data []byte, lim DecodeLimits) (*Animation, error) {
+	chunks, err := readPNGChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ihdr     []byte
+		shared   []pngChunk
+		frames   []*apngFrame
+		cur      *apngFrame
+		animated bool
+		plays    int
+		seenIDAT bool
+	)
+	for _, c := range chunks {
+		switch c.typ {
+		case "IHDR":
+			ihdr = c.data
+		case "acTL":
+			if len(c.data) < 8 {
+				return nil, errors.New("apng: malformed acTL chunk")
+			}
+			animated = true
+			plays = int(binary.BigEndian.Uint32(c.data[4:8]))
+		case "fcTL":
+			if len(c.data) < 26 {
+				return nil, errors.New("apng: malformed fcTL chunk")
+			}
+			cur = parseFCTL(c.data)
+			frames = append(frames, cur)
+		case "IDAT":
+			seenIDAT = true
+			if cur != nil {
+				cur.data = append(cur.data, c.data)
+			}
+		case "fdAT":
+			if cur == nil || len(c.data) < 4 {
+				return nil, errors.New("apng: fdAT chunk without frame control")
+			}
+			cur.data = append(cur.data, c.data[4:])
+		case "IEND":
+		default:
+			if !seenIDAT {
+				shared = append(shared, c)
+			}
+		}
+	}
+	if ihdr == nil || len(ihdr) < 8 {
+		return nil, errors.New("png: missing IHDR chunk")
+	}
+
+	// PNG sizes are at most 2^31-1, which keeps w×h within an int64.
+	w := int(binary.BigEndian.Uint32(ihdr[0:4]))
+	h := int(binary.BigEndian.Uint32(ihdr[4:8]))
+	if w > math.MaxInt32 || h > math.MaxInt32 {
+		return nil, fmt.Errorf("invalid image size %dx%d", w, h)
+	}
+	if err := lim.check(image.Config{Width: w, Height: h}, len(data)); err != nil {
+		return nil, err
+	}
+
+	if !animated || len(frames) == 0 {
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return &Animation{Frames: []*image.RGBA{ToRGBA(img)}, Delays: []time.Duration{0}, Loops: 0}, nil
+	}
+
+	bounds := image.Rect(0, 0, w, h)
+	for i, f := range frames {
+		if f.w <= 0 || f.h <= 0 || !image.Rect(f.x, f.y, f.x+f.w, f.y+f.h).In(bounds) {
+			return nil, fmt.Errorf("apng frame %d: region %dx%d at (%d, %d) outside the %dx%d canvas", i, f.w, f.h, f.x, f.y, w, h)
+		}
+	}
+	if err := lim.checkFrames(w, h, len(frames), len(data)); err != nil {
+		return nil, err
+	}
+
+	canvas := image.NewRGBA(bounds)
+	a := &Animation{Loops: plays}
+	for i, f := range frames {
+		if len(f.data) == 0 {
+			continue
+		}
+		img, err := decodeAPNGFrame(ihdr, shared, f)
+		if err != nil {
+			return nil, fmt.Errorf("apng frame %d: %w", i, err)
+		}
+
+		dispose := f.dispose
+		if i == 0 && dispose == apngDisposePrevious {
+			dispose = apngDisposeBackground
+		}
+		var previous *image.RGBA
+		if dispose == apngDisposePrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		region := image.Rect(f.x, f.y, f.x+f.w, f.y+f.h)
+		op := draw.Over
+		if f.blend == apngBlendSource {
+			op = draw.Src
+		}
+		draw.Draw(canvas, region, img, img.Bounds().Min, op)
+		a.Frames = append(a.Frames, cloneRGBA(canvas))
+		a.Delays = append(a.Delays, f.delay)
+
+		switch dispose {
+		case apngDisposeBackground:
+			draw.Draw(canvas, region, image.Transparent, image.Point{}, draw.Src)
+		case apngDisposePrevious:
+			canvas = previous
+		}
+	}
+	if len(a.Frames) == 0 {
+		return nil, errors.New("apng contains no frames")
+	}
+	return a, nil
+}
+
+// parseFCTL decodes an fcTL chunk payload.
+func parseFCTL(d []byte) *apngFrame {
+	num := binary.BigEndian.Uint16(d[20:22])
+	den := binary.BigEndian.Uint16(d[22:24])
+	if den == 0 {
+		den = 100
+	}
+	return &apngFrame{
+		w:       int(binary.BigEndian.Uint32(d[4:8])),
+		h:       int(binary.BigEndian.Uint32(d[8:12])),
+		x:       int(binary.BigEndian.Uint32(d[12:16])),
+		y:       int(binary.BigEndian.Uint32(d[16:20])),
+		delay:   time.Duration(num) * time.Second / time.Duration(den),
+		dispose: d[24],
+		blend:   d[25],
+	}
+}
+
+// decodeAPNGFrame rebuilds a standalone PNG for one frame and decodes it.
+func decodeAPNGFrame(ihdr []byte, shared []pngChunk, f *apngFrame) (image.Image, error) {
+	hdr := make([]byte, len(ihdr))
+	copy(hdr, ihdr)
+	binary.BigEndian.PutUint32(hdr[0:4], uint32(f.w))
+	binary.BigEndian.PutUint32(hdr[4:8], uint32(f.h))
+
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+	writePNGChunk(&buf, "IHDR", hdr)
+	for _, c := range shared {
+		writePNGChunk(&buf, c.typ, c.data)
+	}
+	for _, d := range f.data {
+		writePNGChunk(&buf, "IDAT", d)
+	}
+	writePNGChunk(&buf, "IEND", nil)
+	return png.Decode(&buf)
+}
+
+// readPNGChunks splits a PNG stream into chunks. CRCs are not verified here;
+// the standard decoder validates reconstructed frames.
+func readPNGChunks(data []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errors.New("png: invalid signature")
+	}
+	var out []pngChunk
+	p := len(pngSignature)
+	for p+8 <= len(data) {
+		n := int(binary.BigEndian.Uint32(data[p : p+4]))
+		typ := string(data[p+4 : p+8])
+		if n < 0 || p+12+n > len(data) {
+			return nil, errors.New("png: truncated chunk")
+		}
+		out = append(out, pngChunk{typ: typ, data: data[p+8 : p+8+n]})
+		p += 12 + n
+		if typ == "IEND" {
+			break
+		}
+	}
+	return out, nil
+}
+
+// writePNGChunk appends a chunk with length and CRC fields to buf.
+func writePNGChunk(buf *bytes.Buffer, typ string, data []byte) {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(data)))
+	buf.Write(n[:])
+	crc := crc32.NewIEEE()
+	_, _ = crc.Write([]byte(typ))
+	_, _ = crc.Write(data)
+	buf.WriteString(typ)
+	buf.Write(data)
+	binary.BigEndian.PutUint32(n[:], crc.Sum32())
+	buf.Write(n[:])
+}
+
+// cloneRGBA returns a deep copy of src.
+func cloneRGBA(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(src.Bounds())
+	copy(dst.Pix, src.Pix)
+	return dst
+}
@@ -0,0 +1,165 @@
+package image
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // register JPEG for SafeDecode
+	_ "image/png"  // register PNG for SafeDecode
+	"io"
+	"time"
+
+	_ "golang.org/x/image/webp" // register WebP for SafeDecode
+)
+
+// DecodeLimits bound the work of decoding an untrusted image. Zero fields
+// are unlimited.
+type DecodeLimits struct {
+	MaxBytes  int64 // encoded size read from the source
+	MaxPixels int64 // declared width × height
+	// MaxExpansion caps decoded RGBA bytes per encoded byte. Real PNG, JPEG
+	// and WebP files stay far below 1<<16; headers declaring more pixels than
+	// the data could hold mark a decompression bomb or a forged size.
+	MaxExpansion float64
+	// Timeout abandons decodes that run longer. The decoder goroutine still
+	// runs to completion, so a tight MaxPixels remains the main defence.
+	Timeout time.Duration
+}
+
+// DefaultDecodeLimits returns the limits used when loading images from
+// paths and readers: 64 MiB of input, 50 megapixels, an expansion of 1<<16
+// and a 10 second timeout.
+func DefaultDecodeLimits() DecodeLimits {
+	return DecodeLimits{
+		MaxBytes:     64 << 20,
+		MaxPixels:    50_000_000,
+		MaxExpansion: 1 << 16,
+		Timeout:      10 * time.Second,
+	}
+}
+
+// SafeDecode decodes a PNG, JPEG or WebP image from r within lim and returns
+// it with its format name. The size declared in the header is checked before
+// any pixel buffer is allocated, and decoder panics on malformed input are
+// returned as errors, so it is safe to call on arbitrary bytes.
+func SafeDecode(r io.Reader, lim DecodeLimits) (image.Image, string, error) {
+	data, err := readLimited(r, lim)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cfg, format, err := decodeConfig(data)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := lim.check(cfg, len(data)); err != nil {
+		return nil, "", err
+	}
+	img, err := timed(lim.Timeout, func() (image.Image, error) {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		return img, err
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("decode %s: %w", format, err)
+	}
+	return img, format, nil
+}
+
+// decodeConfig reads the header of a supported image.
+func decodeConfig(data []byte) (cfg image.Config, format string, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("malformed image header: %v", p)
+		}
+	}()
+	cfg, format, err = image.DecodeConfig(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) {
+		return cfg, "", fmt.Errorf("%w: only PNG, JPEG and WebP are allowed", ErrUnsupportedFormat)
+	}
+	if err != nil {
+		return cfg, "", fmt.Errorf("decode image header: %w", err)
+	}
+	switch format {
+	case "png", "jpeg", "webp":
+		return cfg, format, nil
+	}
+	return cfg, "", fmt.Errorf("%w %q: only PNG, JPEG and WebP are allowed", ErrUnsupportedFormat, format)
+}
+
+// check rejects declared sizes over the limits.
+func (lim DecodeLimits) check(cfg image.Config, encoded int) error {
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return fmt.Errorf("invalid image size %dx%d", cfg.Width, cfg.Height)
+	}
+	px := int64(cfg.Width) * int64(cfg.Height)
+	if lim.MaxPixels > 0 && px > lim.MaxPixels {
+		return fmt.Errorf("%w: size %dx%d exceeds %d pixels", ErrImageTooLarge, cfg.Width, cfg.Height, lim.MaxPixels)
+	}
+	if lim.MaxExpansion > 0 && float64(px)*4 > lim.MaxExpansion*float64(max(encoded, 1)) {
+		return fmt.Errorf("%w: size %dx%d is implausible for %d bytes of data (possible decompression bomb)",
+			ErrImageTooLarge, cfg.Width, cfg.Height, encoded)
+	}
+	return nil
+}
+
+// checkFrames rejects n composited frames of a w×h canvas that together
+// expand beyond MaxExpansion, such as thousands of empty frames over a huge
+// canvas.
+func (lim DecodeLimits) checkFrames(w, h, n, encoded int) error {
+	if lim.MaxExpansion > 0 && float64(w)*float64(h)*4*float64(n) > lim.MaxExpansion*float64(max(encoded, 1)) {
+		return fmt.Errorf("%w: %d frames of %dx%d are implausible for %d bytes of data (possible decompression bomb)",
+			ErrImageTooLarge, n, w, h, encoded)
+	}
+	return nil
+}
+
+// readLimited reads r, failing once it yields more than lim.MaxBytes.
+func readLimited(r io.Reader, lim DecodeLimits) ([]byte, error) {
+	src := r
+	if lim.MaxBytes > 0 {
+		src = io.LimitReader(r, lim.MaxBytes+1)
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fmt.Errorf("read image: %w", err)
+	}
+	if lim.MaxBytes > 0 && int64(len(data)) > lim.MaxBytes {
+		return nil, fmt.Errorf("%w: data exceeds %d bytes", ErrImageTooLarge, lim.MaxBytes)
+	}
+	return data, nil
+}
+
+// timed runs decode, giving up after timeout when it is positive. Panics
+// in decode are returned as errors.
+func timed[T any](timeout time.Duration, decode func() (T, error)) (T, error) {
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var res result
+		defer func() {
+			if p := recover(); p != nil {
+				res = result{err: fmt.Errorf("malformed image: %v", p)}
+			}
+			done <- res
+		}()
+		res.v, res.err = decode()
+	}()
+
+	if timeout <= 0 {
+		res := <-done
+		return res.v, res.err
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case res := <-done:
+		return res.v, res.err
+	case <-timer.C:
+		var zero T
+		return zero, fmt.Errorf("timed out after %v", timeout)
+	}
+}
@@ -0,0 +1,16 @@
+package image
+
+import "errors"
+
+// Errors returned, possibly wrapped, by the loading and export helpers.
+// Use errors.Is to test for them.
+var (
+	// ErrUnsupportedFormat reports input or an output extension in a format
+	// the helper does not handle.
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	// ErrImageTooLarge reports input over the DecodeLimits, including
+	// headers declaring more pixels than the data could hold.
+	ErrImageTooLarge = errors.New("image too large")
+	// ErrImageNotRegistered reports a NamedImage lookup of an unknown name.
+	ErrImageNotRegistered = errors.New("image not registered")
+)
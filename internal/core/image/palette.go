@@ -0,0 +1,158 @@
+package image
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Dither selects how the error of mapping colors to a palette is spread
+// over neighbouring pixels.
+type Dither int
+
+const (
+	// DitherNone maps every pixel to its nearest palette color, giving flat
+	// areas with hard steps between them.
+	DitherNone Dither = iota
+	// DitherOrdered offsets pixels by a 4×4 Bayer matrix before mapping,
+	// giving a regular cross-hatch that suits pixel art and animates stably.
+	DitherOrdered
+	// DitherFloydSteinberg diffuses each pixel's error to the pixels right of
+	// and below it, giving the most faithful gradients.
+	DitherFloydSteinberg
+)
+
+// bayer4 is the 4×4 ordered dithering matrix, in sixteenths.
+var bayer4 = [4][4]float64{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// PaletteIndices maps every pixel of img inside r to the index of the
+// nearest color of pal by RGB distance, spreading the error as dither
+// selects. Colors are compared unpremultiplied and alpha is ignored; fully
+// transparent pixels get -1. Indices are returned row by row over r.
+func PaletteIndices(img *image.RGBA, r image.Rectangle, pal []color.NRGBA, dither Dither) []int {
+	r = r.Intersect(img.Bounds())
+	w, h := r.Dx(), r.Dy()
+	out := make([]int, w*h)
+	if w == 0 || h == 0 || len(pal) == 0 {
+		for i := range out {
+			out[i] = -1
+		}
+		return out
+	}
+
+	// Ordered dithering spreads offsets over about one palette step, assuming
+	// the colors are evenly spaced over the RGB cube.
+	spread := 255 / math.Max(math.Cbrt(float64(len(pal)))-1, 1)
+
+	// Floyd–Steinberg error carried into the current and next rows.
+	var cur, next [][3]float64
+	if dither == DitherFloydSteinberg {
+		cur, next = make([][3]float64, w+2), make([][3]float64, w+2)
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := img.PixOffset(r.Min.X+x, r.Min.Y+y)
+			a := float64(img.Pix[i+3])
+			if a == 0 {
+				out[y*w+x] = -1
+				continue
+			}
+			var c [3]float64
+			for ch := range c {
+				c[ch] = float64(img.Pix[i+ch]) * 255 / a
+			}
+			switch dither {
+			case DitherOrdered:
+				off := ((bayer4[y&3][x&3]+0.5)/16 - 0.5) * spread
+				for ch := range c {
+					c[ch] += off
+				}
+			case DitherFloydSteinberg:
+				for ch := range c {
+					c[ch] += cur[x+1][ch]
+				}
+			}
+
+			k := nearestColor(pal, c)
+			out[y*w+x] = k
+			if dither == DitherFloydSteinberg {
+				p := pal[k]
+				e := [3]float64{c[0] - float64(p.R), c[1] - float64(p.G), c[2] - float64(p.B)}
+				for ch := range e {
+					cur[x+2][ch] += e[ch] * 7 / 16
+					next[x][ch] += e[ch] * 3 / 16
+					next[x+1][ch] += e[ch] * 5 / 16
+					next[x+2][ch] += e[ch] * 1 / 16
+				}
+			}
+		}
+		if dither == DitherFloydSteinberg {
+			cur, next = next, cur
+			clear(next)
+		}
+	}
+	return out
+}
+
+// nearestColor returns the index of the color of pal closest to c. Fully
+// transparent entries are reserved for transparent pixels and never match.
+func nearestColor(pal []color.NRGBA, c [3]float64) int {
+	best, bestD := 0, math.Inf(1)
+	for i, p := range pal {
+		if p.A == 0 {
+			continue
+		}
+		dr, dg, db := c[0]-float64(p.R), c[1]-float64(p.G), c[2]-float64(p.B)
+		if d := dr*dr + dg*dg + db*db; d < bestD {
+			best, bestD = i, d
+		}
+	}
+	return best
+}
+
+// ToPaletted converts img to a paletted image of pal (at most 256 colors)
+// with the given dithering. Fully transparent pixels use the first
+// transparent color of pal, which is appended when pal has none.
+func ToPaletted(img image.Image, pal []color.NRGBA, dither Dither) (*image.Paletted, error) {
+	if len(pal) == 0 || len(pal) > 256 {
+		return nil, errors.New("palette must have 1 to 256 colors")
+	}
+	palette := make(color.Palette, len(pal))
+	transparent := -1
+	for i, c := range pal {
+		palette[i] = c
+		if c.A == 0 && transparent < 0 {
+			transparent = i
+		}
+	}
+
+	src := ToRGBA(img)
+	b := src.Bounds()
+	indices := PaletteIndices(src, b, pal, dither)
+	dst := image.NewPaletted(b, palette)
+	for y := 0; y < b.Dy(); y++ {
+		row := dst.Pix[y*dst.Stride : y*dst.Stride+b.Dx()]
+		for x := range row {
+			k := indices[y*b.Dx()+x]
+			if k < 0 {
+				if transparent < 0 {
+					if len(dst.Palette) == 256 {
+						return nil, errors.New("palette has no room for a transparent color")
+					}
+					transparent = len(dst.Palette)
+					dst.Palette = append(dst.Palette, color.NRGBA{})
+				}
+				k = transparent
+			}
+			row[x] = uint8(k)
+		}
+	}
+	return dst, nil
+}
@@ -0,0 +1,65 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// imageRegistry holds images registered by name, decoded once.
+var imageRegistry = struct {
+	sync.RWMutex
+	images map[string]*image.RGBA
+}{images: map[string]*image.RGBA{}}
+
+// RegisterImage decodes a PNG, JPEG or WebP image within DefaultDecodeLimits
+// and makes it available to NamedImage under name, replacing any image
+// registered before under the same name.
+func RegisterImage(name string, data []byte) error {
+	img, _, err := SafeDecode(bytes.NewReader(data), DefaultDecodeLimits())
+	if err != nil {
+		return fmt.Errorf("register image %q: %w", name, err)
+	}
+	rgba := ToRGBA(img)
+	imageRegistry.Lock()
+	imageRegistry.images[name] = rgba
+	imageRegistry.Unlock()
+	return nil
+}
+
+// RegisterImagesFS registers every file of fsys matching the fs.Glob
+// pattern, named by its base name without extension. It pairs with
+// //go:embed the same way as render.RegisterFontsFS.
+func RegisterImagesFS(fsys fs.FS, pattern string) error {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+	for _, p := range names {
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		base := path.Base(p)
+		if err := RegisterImage(strings.TrimSuffix(base, path.Ext(base)), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NamedImage returns the image registered under name. The image is shared
+// between callers and must not be modified.
+func NamedImage(name string) (*image.RGBA, error) {
+	imageRegistry.RLock()
+	img, ok := imageRegistry.images[name]
+	imageRegistry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("image %q: %w", name, ErrImageNotRegistered)
+	}
+	return img, nil
+}
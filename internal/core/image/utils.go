@@ -3,6 +3,7 @@ package image
 import (
 	"fmt"
 	"image"
+	"image/color"
 	"image/draw"
 	"image/jpeg"
 	"image/png"
@@ -30,7 +31,16 @@ func ResizeRGBA(src image.Image, W, H int) *image.RGBA {
 	return dst
 }
 
-// LoadImage opens and decodes a PNG or JPEG image efficiently.
+// ResizeRGBAWith scales an image to W×H using the given interpolator
+// and returns the result as an RGBA image.
+func ResizeRGBAWith(src image.Image, W, H int, interp xdraw.Interpolator) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, W, H))
+	interp.Scale(dst, dst.Bounds(), src, src.Bounds(), xdraw.Src, nil)
+	return dst
+}
+
+// LoadImage opens and decodes a PNG, JPEG or WebP image within
+// DefaultDecodeLimits (see SafeDecode).
 func LoadImage(path string) (image.Image, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -40,7 +50,7 @@ func LoadImage(path string) (image.Image, error) {
 		_ = f.Close()
 	}()
 
-	img, _, err := image.Decode(f)
+	img, _, err := SafeDecode(f, DefaultDecodeLimits())
 	if err != nil {
 		return nil, fmt.Errorf("decode %q: %w", path, err)
 	}
@@ -73,6 +83,16 @@ func ExportPNG(img image.Image, path string, level png.CompressionLevel) error {
 	return (&png.Encoder{CompressionLevel: level}).Encode(f, img)
 }
 
+// ExportPNGPaletted writes img as an indexed-color PNG restricted to pal,
+// mapping colors with the given dithering (see ToPaletted).
+func ExportPNGPaletted(img image.Image, path string, pal []color.NRGBA, dither Dither) error {
+	paletted, err := ToPaletted(img, pal, dither)
+	if err != nil {
+		return fmt.Errorf("export %q: %w", path, err)
+	}
+	return ExportPNG(paletted, path, png.BestCompression)
+}
+
 // ExportJPEG writes img as JPEG with given quality (1–100).
 func ExportJPEG(img image.Image, path string, quality int) error {
 	f, err := os.Create(path)
@@ -95,6 +115,6 @@ func ExportAuto(img image.Image, path string) error {
 	case ".jpg", ".jpeg":
 		return ExportJPEG(img, path, 75)
 	default:
-		return fmt.Errorf("unsupported extension %q", ext)
+		return fmt.Errorf("%w: extension %q", ErrUnsupportedFormat, ext)
 	}
 }
@@ -0,0 +1,34 @@
+package render
+
+import "github.com/golang/freetype/raster"
+
+// AliasedPainter wraps a raster.Painter and snaps span coverage to fully on or
+// fully off at the 50% threshold. It produces hard, non-antialiased edges for
+// pixel-art rendering and for masks that need exact coverage.
+type AliasedPainter struct {
+	painter raster.Painter
+	spans   []raster.Span // Reused buffer of thresholded spans
+}
+
+// Paint thresholds each span's alpha and forwards the surviving spans.
+func (a *AliasedPainter) Paint(ss []raster.Span, done bool) {
+	const half = 1 << 15 // Half of the maximum raster.Span alpha
+
+	out := a.spans[:0]
+	for _, s := range ss {
+		if s.Alpha < half {
+			continue
+		}
+		s.Alpha = 1<<16 - 1
+		out = append(out, s)
+	}
+	a.spans = out
+	if len(out) > 0 || done {
+		a.painter.Paint(out, done)
+	}
+}
+
+// NewAliasedPainter creates an AliasedPainter forwarding to p.
+func NewAliasedPainter(p raster.Painter) *AliasedPainter {
+	return &AliasedPainter{painter: p}
+}
@@ -0,0 +1,38 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+)
+
+// Errors returned, possibly wrapped, by font loading and lookup. Use
+// errors.Is to test for them.
+var (
+	// ErrFontGlyphMissing reports text with runes the font has no glyph for.
+	ErrFontGlyphMissing = errors.New("font glyph missing")
+	// ErrFontNotRegistered reports a lookup of an unknown font name or family.
+	ErrFontNotRegistered = errors.New("font not registered")
+)
+
+// CheckGlyphs returns an error wrapping ErrFontGlyphMissing that lists the
+// runes of s the font cannot draw, or nil when it has them all. Unlike
+// MissingRunes it neither depends on nor adds to earlier draws, so text can
+// be validated before rendering. Control and format characters are ignored.
+func (f *Font) CheckGlyphs(s string) error {
+	var missing []rune
+	seen := map[rune]bool{}
+	for _, r := range s {
+		if seen[r] || unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			continue
+		}
+		seen[r] = true
+		if _, ok := f.spacers[r]; !ok && !f.HasGlyph(r) {
+			missing = append(missing, r)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrFontGlyphMissing, string(missing))
+}
@@ -1,6 +1,7 @@
 package render
 
 import (
+	"bytes"
 	"fmt"
 	"image"
 	"image/color"
@@ -8,10 +9,15 @@ import (
 	"math"
 	"os"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/Krispeckt/glimo/internal/core/geom"
+	otfont "github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/font/opentype/tables"
 	"github.com/golang/freetype/truetype"
 	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -24,7 +30,34 @@ type Font struct {
 	sizePt        float64        // logical font size in points
 	dpi           float64        // dots per inch scaling
 	letterPercent float64        // tracking as percent of font size
+	wordPercent   float64        // extra space advance as percent of font size
 	capRatio      float64        // fallback cap height ratio
+
+	missingMode MissingGlyphMode // rendering policy for runes without glyphs
+	placeholder rune             // substitute rune for MissingGlyphPlaceholder
+	missing     *missingReport   // unresolved runes, shared between copies
+
+	sf       *sfnt.Font   // outlines for the shaping path; nil if unparsable
+	ot       *otfont.Font // tables for the shaper, shared between copies; nil if unparsable
+	unshaped bool         // use the legacy run-based path
+
+	axes       []FontAxis     // fvar design axes, shared between copies; nil for static fonts
+	axisValues []float64      // position on each axis in user units; nil at the default instance
+	coords     []tables.Coord // normalized axisValues; nil at the default instance
+
+	boldEm     float64 // synthetic stem thickening as a fraction of the size
+	slant      float64 // synthetic italic shear (tan of the angle)
+	faceWeight int     // weight class declared by the font file
+	faceItalic bool    // font file declares an italic style
+	family     string  // family name declared by the font file
+
+	underline decorationMetric // post table underline, in em
+	strikeout decorationMetric // OS/2 strikeout, in em
+
+	spacers map[rune]float64 // runes drawn as empty advances, in em; copied on write
+
+	hinting  HintingMode // grid fitting of outlines and advances
+	subpixel bool        // keep fractional line origins and glyph positions
 }
 
 // Loading
@@ -51,7 +84,20 @@ func LoadFontFromBytes(data []byte, sizePt float64) (*Font, error) {
 		dpi:           defaultDPI,
 		letterPercent: 0.0,
 		capRatio:      0.85,
+		placeholder:   '\uFFFD',
+		missing:       &missingReport{},
+	}
+	if sf, err := sfnt.Parse(data); err == nil {
+		f.sf = sf
+		f.family = familyName(sf)
+	}
+	if ld, err := ot.NewLoader(bytes.NewReader(data)); err == nil {
+		if ft, err := otfont.NewFont(ld); err == nil {
+			f.ot, f.axes = ft, parseAxes(ld)
+		}
 	}
+	f.parseFaceStyle(data)
+	f.parseDecorations(data)
 	return f.SetFontSizePt(sizePt), nil
 }
 
@@ -104,6 +150,20 @@ func (f *Font) SetLetterSpacingPercent(percent float64) *Font {
 	return f
 }
 
+// SetWordSpacingPercent adds extra advance after every space (U+0020 and
+// NBSP) as a percentage of font size, like CSS word-spacing. Negative
+// values tighten spacing.
+func (f *Font) SetWordSpacingPercent(percent float64) *Font {
+	f.wordPercent = percent
+	return f
+}
+
+// LetterSpacingPercent returns the tracking set by SetLetterSpacingPercent.
+func (f *Font) LetterSpacingPercent() float64 { return f.letterPercent }
+
+// WordSpacingPercent returns the word spacing set by SetWordSpacingPercent.
+func (f *Font) WordSpacingPercent() float64 { return f.wordPercent }
+
 // Accessors
 
 // HeightPt returns the font size in points.
@@ -120,27 +180,43 @@ func (f *Font) TrueTypeFont() *truetype.Font { return f.tt }
 
 // cacheKey builds a unique cache key for font face reuse.
 func (f *Font) cacheKey() string {
-	return fmt.Sprintf("%p_%.3f_%.1f", f.tt, f.sizePt, f.dpi)
+	return fmt.Sprintf("%p_%.3f_%.1f_%d_%t", f.tt, f.sizePt, f.dpi, f.hinting, f.subpixel)
 }
 
 // Face caching
 
-// Face returns a truetype.Face configured with the current size and DPI.
-// Faces are cached to prevent redundant allocations and ensure consistent rendering.
+// Face returns a truetype.Face configured with the current size, DPI and
+// hinting. Faces are cached to prevent redundant allocations and ensure
+// consistent rendering.
 func (f *Font) Face() font.Face {
 	key := f.cacheKey()
 	if face, ok := fontCache.get(key); ok {
 		return face
 	}
-	face := truetype.NewFace(f.tt, &truetype.Options{
+	opts := &truetype.Options{
 		Size:    f.sizePt,
 		DPI:     f.dpi,
-		Hinting: font.HintingNone,
-	})
+		Hinting: f.faceHinting(),
+	}
+	if f.subpixel {
+		opts.SubPixelsX = 64
+	}
+	face := truetype.NewFace(f.tt, opts)
 	fontCache.put(key, face)
 	return face
 }
 
+// FaceBytes returns the memory the glyph cache of Face allocates: a mask as
+// large as the font's bounding box for each of its 512 entries. It grows
+// with the square of the font size and is computed without building the
+// face.
+func (f *Font) FaceBytes() int64 {
+	b := f.tt.Bounds(fixed.Int26_6(0.5 + f.sizePt*f.dpi*64/72))
+	w := int64(b.Max.X+63)>>6 - int64(b.Min.X)>>6
+	h := int64(-(b.Min.Y-63))>>6 - int64(-b.Max.Y)>>6
+	return w * h * 512
+}
+
 // Metrics
 
 // TrackingPx returns the tracking offset (in pixels) applied between glyphs.
@@ -148,6 +224,14 @@ func (f *Font) TrackingPx() float64 {
 	return (f.letterPercent / 100.0) * f.HeightPx()
 }
 
+// WordSpacingPx returns the extra advance (in pixels) added after spaces.
+func (f *Font) WordSpacingPx() float64 {
+	return (f.wordPercent / 100.0) * f.HeightPx()
+}
+
+// isWordSpace reports whether word spacing applies after r.
+func isWordSpace(r rune) bool { return r == ' ' || r == '\u00A0' }
+
 // AscentPx returns the ascent (distance from baseline to top) in pixels.
 func (f *Font) AscentPx() float64 {
 	m := f.Face().Metrics()
@@ -209,48 +293,126 @@ func (f *Font) TopYForBaseline(baselineY float64) float64 {
 
 // DrawString draws a single line of text on the destination image.
 // Tracking and kerning are applied between glyphs, not after the final one.
-// The baseline is aligned to pixel grid to avoid blur.
+// The baseline is aligned to pixel grid to avoid blur, and so is x unless
+// subpixel positioning is enabled (see SetSubpixelPositioning).
+// Runes missing from the font are handled according to MissingGlyphMode.
+// With shaping enabled (see SetShaping) ligatures are formed as well.
 func (f *Font) DrawString(dst draw.Image, col color.Color, s string, x, baselineY float64) fixed.Point26_6 {
+	s, boxes := f.resolveMissing(s)
 	if s == "" {
 		return fixed.Point26_6{X: geom.Fix(x), Y: geom.Fix(baselineY)}
 	}
+	if !f.subpixel {
+		x = math.Round(x)
+	}
+	if f.shaped() {
+		glyphs, adv := f.shape(s, boxes)
+		return f.drawShaped(dst, col, glyphs, adv, x, math.Round(baselineY))
+	}
 	face := f.Face()
 	d := &font.Drawer{
 		Dst:  dst,
 		Src:  image.NewUniform(col),
 		Face: face,
 		Dot: fixed.Point26_6{
-			X: geom.Fix(math.Round(x)),
+			X: geom.Fix(x),
 			Y: geom.Fix(math.Round(baselineY)),
 		},
 	}
 	track := geom.Fix(f.TrackingPx())
-	runes := []rune(s)
-	for i, r := range runes {
-		d.DrawString(string(r))
-		if i < len(runes)-1 {
-			d.Dot.X += track
+	word := geom.Fix(f.WordSpacingPx())
+	start := 0 // byte offset of the pending run
+	for i, r := range s {
+		adv, spacer := f.spacerAdvancePx(r)
+		if !spacer && (boxes == 0 || !f.drawsAsBox(r)) {
+			continue
 		}
+		drawRun(d, s[start:i], track, word)
+		if spacer {
+			d.Dot.X += geom.Fix(adv)
+		} else {
+			f.drawMissingBox(dst, col, geom.Unfix(d.Dot.X), math.Round(baselineY))
+			d.Dot.X += geom.Fix(f.boxAdvancePx())
+		}
+		if isWordSpace(r) {
+			d.Dot.X += word
+		}
+		d.Dot.X += track
+		_, size := utf8.DecodeRuneInString(s[i:])
+		start = i + size
 	}
+	drawRun(d, s[start:], track, word)
+	d.Dot.X -= track // no tracking after the final glyph
 	return d.Dot
 }
 
+// drawRun draws a run of plain glyphs, kerned pairwise, and follows each
+// with tracking and, after spaces, word spacing. Without either the run is
+// handed to the drawer whole.
+func drawRun(d *font.Drawer, run string, track, word fixed.Int26_6) {
+	if track == 0 && word == 0 {
+		d.DrawString(run)
+		return
+	}
+	prev := rune(-1)
+	for _, r := range run {
+		if prev >= 0 {
+			d.Dot.X += d.Face.Kern(prev, r)
+		}
+		dr, mask, maskp, adv, _ := d.Face.Glyph(d.Dot, r)
+		if !dr.Empty() {
+			draw.DrawMask(d.Dst, dr, d.Src, image.Point{}, mask, maskp, draw.Over)
+		}
+		d.Dot.X += adv + track
+		if isWordSpace(r) {
+			d.Dot.X += word
+		}
+		prev = r
+	}
+}
+
 // Measurement
 
 // MeasureString measures the pixel width and height of a single-line string.
 // Width includes glyph advances and tracking between characters.
 // Height equals the line height in pixels.
 func (f *Font) MeasureString(s string) (w, h float64) {
+	s, boxes := f.resolveMissing(s)
 	if s == "" {
 		return 0, 0
 	}
-	face := f.Face()
-	adv := font.MeasureString(face, s)
-	w = float64(adv >> 6)
+	if f.shaped() {
+		_, adv := f.shape(s, boxes)
+		return geom.Unfix(adv), f.LineHeightPx()
+	}
 	runes := []rune(s)
+	glyphs := s
+	if boxes > 0 || len(f.spacers) > 0 {
+		glyphs = strings.Map(func(r rune) rune {
+			if adv, ok := f.spacerAdvancePx(r); ok {
+				w += adv
+				return -1
+			}
+			if boxes > 0 && f.drawsAsBox(r) {
+				return -1
+			}
+			return r
+		}, s)
+		w += float64(boxes) * f.boxAdvancePx()
+	}
+	face := f.Face()
+	adv := font.MeasureString(face, glyphs)
+	w += float64(adv >> 6)
 	if len(runes) > 1 {
 		w += float64(len(runes)-1) * f.TrackingPx()
 	}
+	if f.wordPercent != 0 {
+		for _, r := range runes {
+			if isWordSpace(r) {
+				w += f.WordSpacingPx()
+			}
+		}
+	}
 	h = f.LineHeightPx()
 	return
 }
@@ -0,0 +1,58 @@
+package render
+
+// decorationMetric is the placement of a text decoration stroke in em: the
+// offset of its center below the baseline (negative is above) and its
+// thickness.
+type decorationMetric struct {
+	offset, thickness float64
+}
+
+// Fallbacks for fonts without post or OS/2 decoration metrics.
+var (
+	defaultUnderline     = decorationMetric{offset: 0.1, thickness: 0.07}
+	defaultStrikethrough = decorationMetric{offset: -0.28, thickness: 0.07}
+)
+
+// UnderlinePx returns the font's suggested underline placement in pixels:
+// the offset of the stroke's center below the baseline, and its thickness.
+func (f *Font) UnderlinePx() (offset, thickness float64) {
+	return f.decorationPx(f.underline, defaultUnderline)
+}
+
+// StrikethroughPx returns the font's suggested strikethrough placement in
+// pixels: the offset of the stroke's center below the baseline (negative,
+// since it lies above), and its thickness.
+func (f *Font) StrikethroughPx() (offset, thickness float64) {
+	return f.decorationPx(f.strikeout, defaultStrikethrough)
+}
+
+// decorationPx scales m, or fallback when the font declares none, to pixels.
+func (f *Font) decorationPx(m, fallback decorationMetric) (offset, thickness float64) {
+	if m.thickness <= 0 {
+		m = fallback
+	}
+	em := f.HeightPx()
+	return m.offset * em, m.thickness * em
+}
+
+// parseDecorations reads the underline metrics from the post table and the
+// strikeout metrics from the OS/2 table. Both tables store the top edge of
+// the stroke; the center is derived from it.
+func (f *Font) parseDecorations(data []byte) {
+	upem := float64(u16(findTable(data, "head"), 18))
+	if upem <= 0 {
+		return
+	}
+	if post := findTable(data, "post"); len(post) >= 12 {
+		pos, th := float64(int16(u16(post, 8))), float64(int16(u16(post, 10)))
+		if th > 0 {
+			f.underline = decorationMetric{offset: (th/2 - pos) / upem, thickness: th / upem}
+		}
+	}
+	if os2 := findTable(data, "OS/2"); len(os2) >= 30 {
+		th, pos := float64(int16(u16(os2, 26))), float64(int16(u16(os2, 28)))
+		if th > 0 {
+			f.strikeout = decorationMetric{offset: (th/2 - pos) / upem, thickness: th / upem}
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package render
+
+import (
+	"sync"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// DefaultFontName is the registry name under which DefaultFont is always
+// available to NamedFont.
+const DefaultFontName = "default"
+
+var defaultFont = sync.OnceValue(func() *Font {
+	return MustLoadFontFromBytes(goregular.TTF, 12)
+})
+
+// DefaultFont returns the bundled Go Regular face at the given point size.
+// The font is embedded in the binary (BSD-licensed, see
+// golang.org/x/image/font/gofont) and parsed once; every call returns an
+// independent copy.
+func DefaultFont(sizePt float64) *Font {
+	c := *defaultFont()
+	return c.SetFontSizePt(sizePt)
+}
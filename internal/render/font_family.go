@@ -0,0 +1,259 @@
+package render
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// FontRegistry resolves fonts by family, weight and style, the way CSS
+// matches font-family, font-weight and font-style against the faces it has.
+// Faces are parsed once when registered; lookups return sized copies. It is
+// safe for concurrent use.
+type FontRegistry struct {
+	mu       sync.RWMutex
+	families map[string][]*Font // keyed by lower-cased family name
+}
+
+// NewFontRegistry returns an empty registry.
+func NewFontRegistry() *FontRegistry {
+	return &FontRegistry{families: map[string][]*Font{}}
+}
+
+var defaultFontRegistry = sync.OnceValue(func() *FontRegistry {
+	r := NewFontRegistry()
+	r.Add(defaultFont())
+	return r
+})
+
+// DefaultFontRegistry returns the process-wide registry. It starts out with
+// the bundled default font (family "Go").
+func DefaultFontRegistry() *FontRegistry { return defaultFontRegistry() }
+
+// Family returns the family name the font file declares (the typographic
+// family when present), or "" when it is unknown.
+func (f *Font) Family() string { return f.family }
+
+// Register parses a TrueType font and adds it under the family, weight and
+// style the file declares.
+func (r *FontRegistry) Register(data []byte) error {
+	f, err := LoadFontFromBytes(data, 12)
+	if err != nil {
+		return fmt.Errorf("register font: %w", err)
+	}
+	if f.family == "" {
+		return fmt.Errorf("register font: no family name")
+	}
+	r.Add(f)
+	return nil
+}
+
+// RegisterFS registers every font file of fsys matching the fs.Glob pattern.
+func (r *FontRegistry) RegisterFS(fsys fs.FS, pattern string) error {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+	for _, p := range names {
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		if err := r.Register(data); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// Add registers an already loaded font under its declared family, weight
+// and style. A face with the same family, weight and style replaces the
+// previous one.
+func (r *FontRegistry) Add(f *Font) *FontRegistry {
+	return r.AddAs(f.family, f.faceWeight, f.faceItalic, f)
+}
+
+// AddAs registers f under an explicit family, weight and style, overriding
+// what the font file declares.
+func (r *FontRegistry) AddAs(family string, weight int, italic bool, f *Font) *FontRegistry {
+	c := *f
+	c.family, c.faceWeight, c.faceItalic = family, weight, italic
+
+	key := strings.ToLower(family)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	faces := r.families[key]
+	for i, g := range faces {
+		if g.faceWeight == weight && g.faceItalic == italic {
+			faces[i] = &c
+			return r
+		}
+	}
+	r.families[key] = append(faces, &c)
+	return r
+}
+
+// Families returns the registered family names in sorted order.
+func (r *FontRegistry) Families() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.families))
+	for _, faces := range r.families {
+		out = append(out, faces[0].family)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Match returns the registered face of family nearest to weight and italic,
+// at sizePt. Family names are matched case-insensitively.
+//
+// Style is matched first, then weight by the CSS Fonts rules: for 400–500
+// heavier faces up to 500 are tried before lighter ones; below 400 lighter
+// faces are preferred, above 500 heavier ones; a variable face counts as
+// every weight of its "wght" axis and is moved to the requested one. When
+// the chosen face is upright but italic was requested it is slanted along
+// its axes or sheared synthetically, and bold (600 and up) is synthesized
+// on faces lighter than 600.
+func (r *FontRegistry) Match(family string, weight int, italic bool, sizePt float64) (*Font, error) {
+	r.mu.RLock()
+	faces := r.families[strings.ToLower(family)]
+	r.mu.RUnlock()
+	if len(faces) == 0 {
+		return nil, fmt.Errorf("font family %q: %w", family, ErrFontNotRegistered)
+	}
+
+	var styled []*Font
+	for _, f := range faces {
+		if f.faceItalic == italic {
+			styled = append(styled, f)
+		}
+	}
+	if len(styled) == 0 {
+		styled = faces
+	}
+
+	best := styled[0]
+	for _, f := range styled[1:] {
+		if weightRank(weight, f.nearestWeight(weight)) < weightRank(weight, best.nearestWeight(weight)) {
+			best = f
+		}
+	}
+
+	c := *best
+	c.SetFontSizePt(sizePt)
+	if italic && !c.faceItalic {
+		c.SetItalic(true)
+	}
+	if (weight >= 600 && c.faceWeight < 600) || c.axisIndex("wght") >= 0 {
+		c.SetWeight(weight)
+	}
+	return &c, nil
+}
+
+// weightRank orders candidate weights for a desired weight; lower is
+// better. It encodes the CSS Fonts font-weight matching order.
+func weightRank(desired, w int) float64 {
+	d := math.Abs(float64(w - desired))
+	switch {
+	case w == desired:
+		return 0
+	case desired >= 400 && desired <= 500:
+		if w > desired && w <= 500 {
+			return d
+		}
+		if w < desired {
+			return 1000 + d
+		}
+		return 2000 + d
+	case desired < 400:
+		if w < desired {
+			return d
+		}
+		return 1000 + d
+	default:
+		if w > desired {
+			return d
+		}
+		return 1000 + d
+	}
+}
+
+// Lookup resolves a declarative font description such as
+// "Montserrat 600 italic" or "Open Sans bold" at sizePt. The family comes
+// first, followed by an optional weight (a number or a keyword from "thin"
+// to "black") and style ("italic" or "oblique"); the defaults are 400 and
+// upright.
+func (r *FontRegistry) Lookup(spec string, sizePt float64) (*Font, error) {
+	family, weight, italic, err := ParseFontSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	return r.Match(family, weight, italic, sizePt)
+}
+
+// MustLookup is like Lookup but panics on error.
+func (r *FontRegistry) MustLookup(spec string, sizePt float64) *Font {
+	f, err := r.Lookup(spec, sizePt)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// fontWeights maps weight keywords to their numeric values.
+var fontWeights = map[string]int{
+	"thin": 100, "hairline": 100,
+	"extralight": 200, "ultralight": 200,
+	"light":   300,
+	"regular": 400, "normal": 400,
+	"medium":   500,
+	"semibold": 600, "demibold": 600,
+	"bold":      700,
+	"extrabold": 800, "ultrabold": 800,
+	"black": 900, "heavy": 900,
+}
+
+// ParseFontSpec splits a font description as accepted by
+// FontRegistry.Lookup into its family, weight and style.
+func ParseFontSpec(spec string) (family string, weight int, italic bool, err error) {
+	fields := strings.Fields(spec)
+	weight = 400
+	seenWeight, seenStyle := false, false
+	for len(fields) > 1 {
+		tok := strings.ToLower(fields[len(fields)-1])
+		if !seenStyle && (tok == "italic" || tok == "oblique") {
+			italic, seenStyle = true, true
+		} else if w, ok := fontWeights[tok]; ok && !seenWeight {
+			weight, seenWeight = w, true
+		} else if n, convErr := strconv.Atoi(tok); convErr == nil && !seenWeight {
+			if n < 1 || n > 1000 {
+				return "", 0, false, fmt.Errorf("font spec %q: weight %d out of range", spec, n)
+			}
+			weight, seenWeight = n, true
+		} else {
+			break
+		}
+		fields = fields[:len(fields)-1]
+	}
+	if len(fields) == 0 {
+		return "", 0, false, fmt.Errorf("font spec %q: missing family", spec)
+	}
+	return strings.Join(fields, " "), weight, italic, nil
+}
+
+// familyName returns the typographic family of sf, falling back to the
+// legacy family name.
+func familyName(sf *sfnt.Font) string {
+	if name, err := sf.Name(nil, sfnt.NameIDTypographicFamily); err == nil && name != "" {
+		return name
+	}
+	name, _ := sf.Name(nil, sfnt.NameIDFamily)
+	return name
+}
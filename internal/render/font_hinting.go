@@ -0,0 +1,188 @@
+package render
+
+import (
+	"math"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// HintingMode selects how glyphs are fitted to the pixel grid.
+type HintingMode int
+
+const (
+	// HintingNone draws outlines exactly as designed (default). Best for
+	// large text, where grid fitting distorts shapes more than it helps.
+	HintingNone HintingMode = iota
+	// HintingVertical runs the font's hinting instructions but keeps only
+	// their vertical moves: baselines, x-heights and horizontal stems land
+	// on whole pixels while advances and horizontal positions are unchanged.
+	HintingVertical
+	// HintingFull runs the font's hinting instructions in both directions and
+	// rounds advances and kerning to whole pixels, giving the crispest small
+	// text at the cost of less even spacing.
+	HintingFull
+)
+
+// SetHinting sets the hinting mode. Hinting sharpens small text (below about
+// 14px) in dense layouts such as tables.
+//
+// With SetShaping(false) the legacy path cannot hint vertically only, and
+// treats HintingVertical as HintingFull.
+func (f *Font) SetHinting(mode HintingMode) *Font {
+	f.hinting = mode
+	return f
+}
+
+// Hinting returns the hinting mode.
+func (f *Font) Hinting() HintingMode { return f.hinting }
+
+// SetSubpixelPositioning enables or disables subpixel positioning (disabled
+// by default). When enabled, DrawString keeps the fractional part of its x
+// coordinate instead of snapping the line to the pixel grid, and glyphs are
+// placed at their exact fractional advances, so text keeps its designed
+// rhythm wherever it starts. When disabled under HintingFull, every glyph
+// starts on a whole pixel.
+func (f *Font) SetSubpixelPositioning(enabled bool) *Font {
+	f.subpixel = enabled
+	return f
+}
+
+// SubpixelPositioning reports whether subpixel positioning is enabled.
+func (f *Font) SubpixelPositioning() bool { return f.subpixel }
+
+// faceHinting maps the hinting mode to its x/image/font equivalent.
+func (f *Font) faceHinting() font.Hinting {
+	switch f.hinting {
+	case HintingVertical:
+		return font.HintingVertical
+	case HintingFull:
+		return font.HintingFull
+	default:
+		return font.HintingNone
+	}
+}
+
+// advanceHinting returns the hinting applied to shaped advances and kerning;
+// only HintingFull rounds them.
+func (f *Font) advanceHinting() font.Hinting {
+	if f.hinting == HintingFull {
+		return font.HintingFull
+	}
+	return font.HintingNone
+}
+
+// loadOutline returns the outline of glyph index at ppem. Unhinted glyphs
+// come from the sfnt parser; hinted ones are run through the TrueType
+// interpreter and converted to the same segment form. Variable fonts away
+// from their default instance are never hinted; glyphs whose variation data
+// is malformed are drawn as designed.
+func (f *Font) loadOutline(buf *sfnt.Buffer, index sfnt.GlyphIndex, ppem fixed.Int26_6) (sfnt.Segments, error) {
+	if f.varied() {
+		if segs, ok := f.variedOutline(index, ppem); ok {
+			return segs, nil
+		}
+	}
+	if f.hinting == HintingNone {
+		return f.sf.LoadGlyph(buf, index, ppem, nil)
+	}
+	var g truetype.GlyphBuf
+	if err := g.Load(f.tt, ppem, truetype.Index(index), font.HintingFull); err != nil {
+		return nil, err
+	}
+	points := g.Points
+	if f.hinting == HintingVertical {
+		// Keep the hinted y of every point and its designed x.
+		points = make([]truetype.Point, len(g.Points))
+		for i, p := range g.Points {
+			points[i] = p
+			points[i].X = g.Unhinted[i].X
+		}
+	}
+
+	var segs sfnt.Segments
+	start := 0
+	for _, end := range g.Ends {
+		segs = appendContour(segs, points[start:end])
+		start = end
+	}
+	return segs, nil
+}
+
+// appendContour converts one closed TrueType contour, whose off-curve points
+// are quadratic controls with implied on-curve midpoints, to segments in
+// sfnt's y-down orientation.
+func appendContour(segs sfnt.Segments, ps []truetype.Point) sfnt.Segments {
+	if len(ps) == 0 {
+		return segs
+	}
+	pt := func(p truetype.Point) fixed.Point26_6 { return fixed.Point26_6{X: p.X, Y: -p.Y} }
+	on := func(p truetype.Point) bool { return p.Flags&0x01 != 0 }
+	mid := func(a, b truetype.Point) fixed.Point26_6 {
+		pa, pb := pt(a), pt(b)
+		return fixed.Point26_6{X: (pa.X + pb.X) / 2, Y: (pa.Y + pb.Y) / 2}
+	}
+
+	// Start on an on-curve point, or on the midpoint of two controls.
+	var first fixed.Point26_6
+	rot := 0
+	switch {
+	case on(ps[0]):
+		first = pt(ps[0])
+		rot = 1
+	case on(ps[len(ps)-1]):
+		first = pt(ps[len(ps)-1])
+	default:
+		first = mid(ps[len(ps)-1], ps[0])
+	}
+	segs = append(segs, sfnt.Segment{Op: sfnt.SegmentOpMoveTo, Args: [3]fixed.Point26_6{first}})
+
+	var ctrl truetype.Point
+	hasCtrl := false
+	for i := 0; i < len(ps); i++ {
+		p := ps[(rot+i)%len(ps)]
+		if rot == 0 && i == len(ps)-1 && on(p) {
+			break // the closing on-curve point is the start point
+		}
+		switch {
+		case on(p) && !hasCtrl:
+			segs = append(segs, sfnt.Segment{Op: sfnt.SegmentOpLineTo, Args: [3]fixed.Point26_6{pt(p)}})
+		case on(p):
+			segs = append(segs, sfnt.Segment{Op: sfnt.SegmentOpQuadTo, Args: [3]fixed.Point26_6{pt(ctrl), pt(p)}})
+			hasCtrl = false
+		case hasCtrl:
+			segs = append(segs, sfnt.Segment{Op: sfnt.SegmentOpQuadTo, Args: [3]fixed.Point26_6{pt(ctrl), mid(ctrl, p)}})
+			ctrl = p
+		default:
+			ctrl, hasCtrl = p, true
+		}
+	}
+	if hasCtrl {
+		segs = append(segs, sfnt.Segment{Op: sfnt.SegmentOpQuadTo, Args: [3]fixed.Point26_6{pt(ctrl), first}})
+	} else if last := segs[len(segs)-1]; last.Op == sfnt.SegmentOpMoveTo || lastPoint(last) != first {
+		segs = append(segs, sfnt.Segment{Op: sfnt.SegmentOpLineTo, Args: [3]fixed.Point26_6{first}})
+	}
+	return segs
+}
+
+// lastPoint returns the end point of a segment.
+func lastPoint(s sfnt.Segment) fixed.Point26_6 {
+	switch s.Op {
+	case sfnt.SegmentOpQuadTo:
+		return s.Args[1]
+	case sfnt.SegmentOpCubeTo:
+		return s.Args[2]
+	default:
+		return s.Args[0]
+	}
+}
+
+// placeX returns the x at which a glyph with pen position gx is drawn.
+func (f *Font) placeX(gx float64) float64 {
+	if f.hinting == HintingFull && !f.subpixel {
+		return math.Round(gx)
+	}
+	return gx
+}
@@ -0,0 +1,214 @@
+package render
+
+import (
+	"container/list"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+)
+
+// maskEntry is a cached string mask and its key.
+type maskEntry struct {
+	key  string
+	mask *image.RGBA
+}
+
+// maskLRU is a thread-safe Least Recently Used cache of rasterized string
+// masks. It mirrors fontLRU; evicted masks are left to the garbage collector.
+type maskLRU struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List // oldest → newest
+}
+
+// newMaskLRU creates a mask cache holding up to capacity masks. A capacity
+// below 1 disables caching.
+func newMaskLRU(capacity int) *maskLRU {
+	return &maskLRU{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get returns the mask cached under key and marks it recently used.
+func (c *maskLRU) get(key string) (*image.RGBA, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToBack(el)
+		return el.Value.(*maskEntry).mask, true
+	}
+	return nil, false
+}
+
+// put caches mask under key, evicting the least recently used mask when the
+// cache is full.
+func (c *maskLRU) put(key string, mask *image.RGBA) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.capacity < 1 {
+		return
+	}
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToBack(el)
+		el.Value.(*maskEntry).mask = mask
+		return
+	}
+	if c.order.Len() >= c.capacity {
+		oldest := c.order.Front()
+		delete(c.items, oldest.Value.(*maskEntry).key)
+		c.order.Remove(oldest)
+	}
+	c.items[key] = c.order.PushBack(&maskEntry{key: key, mask: mask})
+}
+
+// clear removes all cached masks.
+func (c *maskLRU) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+var maskCache = newMaskLRU(256)
+
+// wordCache holds masks of single words, used by StringMask to assemble
+// strings it has not seen from words it has. Off by default.
+var wordCache = newMaskLRU(0)
+
+// SetMaskCacheCapacity changes the max number of cached string masks.
+// Zero disables the cache.
+func SetMaskCacheCapacity(capacity int) {
+	maskCache = newMaskLRU(capacity)
+}
+
+// SetWordMaskCacheCapacity enables caching of up to capacity word masks.
+// With it, a string missing from the string mask cache is assembled from the
+// masks of its space-separated words, so templated renders where only a few
+// words change, such as numbers in a caption, rasterize only those words.
+// It applies to shaped fonts. Zero, the default, disables it.
+func SetWordMaskCacheCapacity(capacity int) {
+	wordCache = newMaskLRU(capacity)
+}
+
+// ClearMaskCache releases all cached string and word masks.
+func ClearMaskCache() {
+	maskCache.clear()
+	wordCache.clear()
+}
+
+// StringMask returns s drawn in opaque black on a transparent w×h mask with
+// its origin at (x, baselineY), exactly as DrawString would draw it there.
+//
+// Masks are cached by the font's face, size and rendering options together
+// with the string and its placement, so repeated renders of the same labels
+// skip rasterization. The returned mask is shared and must not be modified.
+func (f *Font) StringMask(s string, x, baselineY float64, w, h int) *image.RGBA {
+	key := fmt.Sprintf("%s|%q|%.4f|%.4f|%dx%d", f.renderKey(), s, x, baselineY, w, h)
+	if m, ok := maskCache.get(key); ok {
+		f.resolveMissing(s) // still report missing runes
+		return m
+	}
+	m := image.NewRGBA(image.Rect(0, 0, w, h))
+	if !f.drawWords(m, s, x, baselineY) {
+		_ = f.DrawString(m, color.Black, s, x, baselineY)
+	}
+	maskCache.put(key, m)
+	return m
+}
+
+// wordPlacement is a word of a shaped string and the pen position of its
+// first glyph.
+type wordPlacement struct {
+	word string
+	x    float64
+}
+
+// drawWords draws s onto m from the word mask cache, each word rasterized
+// on its own at the fractional pen position DrawString gives it, so the
+// result matches DrawString. It reports false, drawing nothing, when word
+// caching is off, the font is not shaped or s has a single word.
+func (f *Font) drawWords(m *image.RGBA, s string, x, baselineY float64) bool {
+	cache := wordCache
+	if cache.capacity < 1 || !f.shaped() || !strings.Contains(s, " ") {
+		return false
+	}
+	s, boxes := f.resolveMissing(s)
+	if !f.subpixel {
+		x = math.Round(x)
+	}
+	baselineY = math.Round(baselineY)
+
+	glyphs, _ := f.shape(s, boxes)
+	runes := []rune(s)
+	var words []wordPlacement
+	for start, gi := 0, 0; start < len(runes); {
+		if runes[start] == ' ' {
+			start++
+			continue
+		}
+		end := start
+		for end < len(runes) && runes[end] != ' ' {
+			end++
+		}
+		for gi < len(glyphs) && glyphs[gi].ri < start {
+			gi++
+		}
+		if gi == len(glyphs) || glyphs[gi].ri != start {
+			return false // a cluster spans the space
+		}
+		words = append(words, wordPlacement{string(runes[start:end]), x + geom.Unfix(glyphs[gi].x)})
+		start = end
+	}
+
+	left, right := f.OverhangPx()
+	pad := int(math.Ceil(left)) + 1
+	h := m.Bounds().Dy()
+	for _, wp := range words {
+		ix := math.Floor(wp.x)
+		frac := wp.x - ix
+		key := fmt.Sprintf("%s|%q|%.6f|%.4f|%d", f.renderKey(), wp.word, frac, baselineY, h)
+		wm, ok := cache.get(key)
+		if !ok {
+			wg, adv := f.shape(wp.word, boxes)
+			ww := int(math.Ceil(geom.Unfix(adv)+right)) + 2*pad
+			wm = image.NewRGBA(image.Rect(0, 0, ww, h))
+			f.drawShaped(wm, color.Black, wg, adv, float64(pad)+frac, baselineY)
+			cache.put(key, wm)
+		}
+		dx := int(ix) - pad
+		draw.Draw(m, wm.Bounds().Add(image.Pt(dx, 0)), wm, image.Point{}, draw.Over)
+	}
+	return true
+}
+
+// renderKey describes every setting of f that changes how a string is drawn.
+func (f *Font) renderKey() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s_%.4f_%.4f_%t_%d_%d_%.5f_%.5f",
+		f.cacheKey(), f.letterPercent, f.wordPercent, f.unshaped,
+		f.missingMode, f.placeholder, f.boldEm, f.slant)
+	for i, v := range f.axisValues {
+		fmt.Fprintf(&b, "_%s=%.4f", f.axes[i].Tag, v)
+	}
+	if len(f.spacers) > 0 {
+		runes := make([]rune, 0, len(f.spacers))
+		for r := range f.spacers {
+			runes = append(runes, r)
+		}
+		slices.Sort(runes)
+		for _, r := range runes {
+			fmt.Fprintf(&b, "_%d:%.4f", r, f.spacers[r])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,196 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// MissingGlyphMode defines how runes without a glyph in the font are rendered.
+type MissingGlyphMode int
+
+const (
+	// MissingGlyphNotdef draws the font's own .notdef glyph (default behavior).
+	MissingGlyphNotdef MissingGlyphMode = iota
+	// MissingGlyphSkip omits missing runes entirely; they take no horizontal space.
+	MissingGlyphSkip
+	// MissingGlyphBox draws a hollow replacement box in place of each missing rune.
+	MissingGlyphBox
+	// MissingGlyphPlaceholder substitutes a configurable placeholder rune.
+	MissingGlyphPlaceholder
+)
+
+// missingReport collects runes that could not be resolved by a font.
+// It is shared by pointer between copies of the same Font, so reports made
+// through per-line font copies are visible on the original instance.
+type missingReport struct {
+	mu    sync.Mutex
+	runes map[rune]struct{}
+}
+
+// add records r as unresolved.
+func (m *missingReport) add(r rune) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.runes == nil {
+		m.runes = make(map[rune]struct{})
+	}
+	m.runes[r] = struct{}{}
+}
+
+// list returns recorded runes in ascending order.
+func (m *missingReport) list() []rune {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]rune, 0, len(m.runes))
+	for r := range m.runes {
+		out = append(out, r)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// reset forgets all recorded runes.
+func (m *missingReport) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runes = nil
+}
+
+// SetMissingGlyphMode selects how runes absent from the font are rendered.
+func (f *Font) SetMissingGlyphMode(mode MissingGlyphMode) *Font {
+	f.missingMode = mode
+	return f
+}
+
+// SetMissingGlyphPlaceholder sets the rune used by MissingGlyphPlaceholder
+// and switches the font to that mode. If the placeholder itself is missing,
+// the font falls back to drawing a replacement box.
+func (f *Font) SetMissingGlyphPlaceholder(r rune) *Font {
+	f.placeholder = r
+	f.missingMode = MissingGlyphPlaceholder
+	return f
+}
+
+// MissingGlyphMode returns the current missing glyph behavior.
+func (f *Font) MissingGlyphMode() MissingGlyphMode { return f.missingMode }
+
+// MissingRunes returns the sorted set of runes that were measured or drawn
+// with this font but have no glyph in it. Copies of a Font share the report.
+func (f *Font) MissingRunes() []rune {
+	if f.missing == nil {
+		return nil
+	}
+	return f.missing.list()
+}
+
+// ResetMissingRunes clears the unresolved rune report.
+func (f *Font) ResetMissingRunes() {
+	if f.missing != nil {
+		f.missing.reset()
+	}
+}
+
+// HasGlyph reports whether the font defines a glyph for r.
+func (f *Font) HasGlyph(r rune) bool {
+	return f.tt.Index(r) != 0
+}
+
+// isMissing reports whether r has no glyph and should be treated as unresolved.
+// Control and format characters are never reported.
+func (f *Font) isMissing(r rune) bool {
+	if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+		return false
+	}
+	if _, ok := f.spacers[r]; ok || f.HasGlyph(r) {
+		return false
+	}
+	if f.missing != nil {
+		f.missing.add(r)
+	}
+	return true
+}
+
+// resolveMissing rewrites s according to the missing glyph mode.
+// Skipped runes are removed and placeholders substituted. Runes rendered as
+// boxes are left in place; boxes is the number of such runes.
+func (f *Font) resolveMissing(s string) (out string, boxes int) {
+	var b strings.Builder
+	changed := false
+	for _, r := range s {
+		if !f.isMissing(r) {
+			b.WriteRune(r)
+			continue
+		}
+		switch f.missingMode {
+		case MissingGlyphSkip:
+			changed = true
+		case MissingGlyphPlaceholder:
+			if f.placeholder != 0 && f.HasGlyph(f.placeholder) {
+				b.WriteRune(f.placeholder)
+				changed = true
+			} else {
+				b.WriteRune(r)
+				boxes++
+			}
+		case MissingGlyphBox:
+			b.WriteRune(r)
+			boxes++
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if !changed {
+		return s, boxes
+	}
+	return b.String(), boxes
+}
+
+// drawsAsBox reports whether a rune is rendered as a replacement box.
+func (f *Font) drawsAsBox(r rune) bool {
+	switch f.missingMode {
+	case MissingGlyphBox:
+		return !f.HasGlyph(r) && !unicode.IsControl(r) && !unicode.Is(unicode.Cf, r)
+	case MissingGlyphPlaceholder:
+		return !f.HasGlyph(r) && !unicode.IsControl(r) && !unicode.Is(unicode.Cf, r) &&
+			(f.placeholder == 0 || !f.HasGlyph(f.placeholder))
+	default:
+		return false
+	}
+}
+
+// boxAdvancePx returns the horizontal advance of a replacement box in pixels.
+func (f *Font) boxAdvancePx() float64 {
+	return math.Round(f.HeightPx() * 0.6)
+}
+
+// drawMissingBox draws a hollow replacement box whose left edge is x
+// and whose bottom sits on the baseline.
+func (f *Font) drawMissingBox(dst draw.Image, col color.Color, x, baselineY float64) {
+	adv := f.boxAdvancePx()
+	pad := math.Max(1, math.Round(adv*0.1))
+	th := int(math.Max(1, math.Round(f.HeightPx()/16)))
+
+	x0 := int(math.Round(x + pad))
+	x1 := int(math.Round(x + adv - pad))
+	y1 := int(math.Round(baselineY))
+	y0 := y1 - int(math.Round(f.CapHeightPx()))
+	if x1-x0 <= 2*th || y1-y0 <= 2*th {
+		return
+	}
+
+	src := image.NewUniform(col)
+	for _, r := range []image.Rectangle{
+		image.Rect(x0, y0, x1, y0+th),
+		image.Rect(x0, y1-th, x1, y1),
+		image.Rect(x0, y0+th, x0+th, y1-th),
+		image.Rect(x1-th, y0+th, x1, y1-th),
+	} {
+		draw.Draw(dst, r, src, image.Point{}, draw.Over)
+	}
+}
@@ -0,0 +1,74 @@
+package render
+
+import (
+	"math"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// StringOutline returns the glyph contours of s with its origin at
+// (x, baselineY), placed exactly as DrawString places the glyphs, so callers
+// can stroke text as vector paths. Synthetic styles are applied: synthetic
+// bold repeats each contour at the offsets DrawString smears the glyph
+// over, so filling with the non-zero rule gives the same emboldened shape.
+// Spacers and missing-glyph boxes have no contours.
+//
+// ok is false when the outlines cannot reproduce DrawString, which is the
+// case for fonts drawn without shaping (see Outlined).
+func (f *Font) StringOutline(s string, x, baselineY float64) (segs sfnt.Segments, ok bool) {
+	if !f.Outlined() {
+		return nil, false
+	}
+	s, boxes := f.resolveMissing(s)
+	if s == "" {
+		return nil, true
+	}
+	if !f.subpixel {
+		x = math.Round(x)
+	}
+	baselineY = math.Round(baselineY)
+
+	var buf sfnt.Buffer
+	ppem := geom.Fix(f.HeightPx())
+	glyphs, _ := f.shape(s, boxes)
+	offsets := boldOffsets(f.syntheticBoldPx())
+	for _, g := range glyphs {
+		if g.empty || g.box {
+			continue
+		}
+		gs, err := f.loadOutline(&buf, g.index, ppem)
+		if err != nil {
+			continue
+		}
+		gx, gy := f.placeX(x+geom.Unfix(g.x)), baselineY+geom.Unfix(g.y)
+		for _, off := range offsets {
+			for _, seg := range gs {
+				n := 1
+				switch seg.Op {
+				case sfnt.SegmentOpQuadTo:
+					n = 2
+				case sfnt.SegmentOpCubeTo:
+					n = 3
+				}
+				for i := 0; i < n; i++ {
+					seg.Args[i] = f.placePoint(seg.Args[i], gx+off.x, gy+off.y)
+				}
+				segs = append(segs, seg)
+			}
+		}
+	}
+	return segs, true
+}
+
+// Outlined reports whether StringOutline can reproduce the font's
+// rendering, which needs the shaping path (see SetShaping).
+func (f *Font) Outlined() bool { return f.shaped() }
+
+// placePoint moves an outline point to the glyph origin (gx, baselineY) and
+// applies the synthetic italic shear, as traceGlyph does.
+func (f *Font) placePoint(p fixed.Point26_6, gx, baselineY float64) fixed.Point26_6 {
+	px, py := geom.Unfix(p.X), geom.Unfix(p.Y)
+	return fixed.Point26_6{X: geom.Fix(gx + px - py*f.slant), Y: geom.Fix(baselineY + py)}
+}
@@ -0,0 +1,76 @@
+package render
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// fontRegistry holds fonts registered by name, parsed once.
+var fontRegistry = struct {
+	sync.RWMutex
+	fonts map[string]*Font
+}{fonts: map[string]*Font{}}
+
+// RegisterFont parses a TrueType font and makes it available to NamedFont
+// under name, replacing any font registered before under the same name.
+func RegisterFont(name string, data []byte) error {
+	f, err := LoadFontFromBytes(data, 12)
+	if err != nil {
+		return fmt.Errorf("register font %q: %w", name, err)
+	}
+	fontRegistry.Lock()
+	fontRegistry.fonts[name] = f
+	fontRegistry.Unlock()
+	return nil
+}
+
+// RegisterFontsFS registers every file of fsys matching the fs.Glob pattern,
+// named by its base name without extension ("fonts/Inter-Bold.ttf" becomes
+// "Inter-Bold"). It pairs with //go:embed:
+//
+//	//go:embed fonts/*.ttf
+//	var fonts embed.FS
+//
+//	render.RegisterFontsFS(fonts, "fonts/*.ttf")
+func RegisterFontsFS(fsys fs.FS, pattern string) error {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+	for _, p := range names {
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		if err := RegisterFont(assetName(p), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NamedFont returns a copy of the font registered under name at the given
+// point size. DefaultFontName resolves to DefaultFont unless a font was
+// registered under it.
+func NamedFont(name string, sizePt float64) (*Font, error) {
+	fontRegistry.RLock()
+	f, ok := fontRegistry.fonts[name]
+	fontRegistry.RUnlock()
+	if !ok {
+		if name == DefaultFontName {
+			return DefaultFont(sizePt), nil
+		}
+		return nil, fmt.Errorf("font %q: %w", name, ErrFontNotRegistered)
+	}
+	c := *f
+	return c.SetFontSizePt(sizePt), nil
+}
+
+// assetName returns the base name of p without its extension.
+func assetName(p string) string {
+	base := path.Base(p)
+	return strings.TrimSuffix(base, path.Ext(base))
+}
@@ -0,0 +1,437 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"slices"
+	"sync"
+	"unicode"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+	otfont "github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/harfbuzz"
+	"github.com/go-text/typesetting/language"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// shapedGlyph is one positioned glyph of a shaped run.
+type shapedGlyph struct {
+	index sfnt.GlyphIndex // glyph in the font; meaningless for boxes
+	r     rune            // first rune of the cluster the glyph stands for
+	ri    int             // index of that rune in the shaped string
+	box   bool            // drawn as a missing-glyph box
+	empty bool            // spacer: advances without drawing
+	x     fixed.Int26_6   // pen position relative to the start of the run
+	y     fixed.Int26_6   // offset below the baseline, for attached marks
+	adv   fixed.Int26_6   // advance from the shaper, before spacing
+	dx    fixed.Int26_6   // offset from the pen position, for attached marks
+
+	cluster int // shaper cluster; glyphs of one cluster share it
+}
+
+// SetShaping enables or disables glyph shaping (enabled by default).
+//
+// Shaped text goes through the HarfBuzz shaper of go-text/typesetting: the
+// font's GSUB substitutions and GPOS (or 'kern') positioning are applied, so
+// MeasureString and DrawString agree with each other and with the font's
+// design. Standard ligatures are formed when letter spacing is zero.
+// Disabling shaping restores the legacy path, which draws through the face's
+// own kerning and never forms ligatures.
+//
+// The shaping path also handles complex scripts: Arabic joining, Indic
+// reordering and conjuncts, and mark attachment follow the font's OpenType
+// features. Strings are expected in display order, as Text produces them;
+// right-to-left runs are shaped in logical order and laid out reversed. The
+// legacy path performs none of this.
+func (f *Font) SetShaping(enabled bool) *Font {
+	f.unshaped = !enabled
+	return f
+}
+
+// Shaping reports whether glyph shaping is enabled.
+func (f *Font) Shaping() bool { return !f.unshaped }
+
+// shaped reports whether the shaping path is used for this font.
+func (f *Font) shaped() bool { return !f.unshaped && f.sf != nil && f.ot != nil }
+
+// maxShaperFonts bounds the fonts one pooled shaper keeps ready; past it the
+// shaper starts over.
+const maxShaperFonts = 16
+
+// otShaper is the shaping state of one goroutine. HarfBuzz buffers and fonts
+// cache glyph data as they go and must not be shared, so they are pooled.
+type otShaper struct {
+	buf   *harfbuzz.Buffer
+	fonts map[*otfont.Font]*harfbuzz.Font
+}
+
+var shapers = sync.Pool{New: func() any {
+	return &otShaper{buf: harfbuzz.NewBuffer(), fonts: map[*otfont.Font]*harfbuzz.Font{}}
+}}
+
+// font returns the shaper's HarfBuzz font for f, at its variation
+// coordinates and scaled so positions come out in 26.6 pixels.
+func (sh *otShaper) font(f *Font) *harfbuzz.Font {
+	hf := sh.fonts[f.ot]
+	if hf == nil {
+		if len(sh.fonts) >= maxShaperFonts {
+			clear(sh.fonts)
+		}
+		hf = harfbuzz.NewFont(otfont.NewFace(f.ot))
+		sh.fonts[f.ot] = hf
+	}
+	if coords := f.coords; !slices.Equal(hf.Face().Coords(), coords) {
+		hf.Face().SetCoords(coords)
+	}
+	hf.XScale = int32(geom.Fix(f.HeightPx()))
+	hf.YScale = hf.XScale
+	return hf
+}
+
+// shape converts s, already resolved for missing glyphs, into a positioned
+// glyph run and returns it with its total advance.
+func (f *Font) shape(s string, boxes int) ([]shapedGlyph, fixed.Int26_6) {
+	glyphs, ok := f.shapeGlyphs(s, boxes)
+	if !ok && f.varied() {
+		// The shaper trusts per-glyph variation data; fonts where it is
+		// corrupt are laid out as their default instance.
+		def := *f
+		def.coords = nil
+		glyphs, _ = def.shapeGlyphs(s, boxes)
+	}
+
+	// Tracking, word spacing and synthetic bold widen clusters, not glyphs,
+	// so marks keep their place on their bases.
+	track := geom.Fix(f.TrackingPx())
+	word := geom.Fix(f.WordSpacingPx())
+	bold := geom.Fix(f.syntheticBoldPx())
+	var x, extra fixed.Int26_6
+	for i := range glyphs {
+		g := &glyphs[i]
+		g.x = x + g.dx
+		switch {
+		case g.empty:
+			spacer, _ := f.spacerAdvancePx(g.r)
+			x += geom.Fix(spacer)
+		case g.box:
+			x += geom.Fix(f.boxAdvancePx())
+		case g.adv != 0:
+			x += g.adv
+			extra += bold
+		}
+		if i+1 < len(glyphs) && glyphs[i+1].cluster == g.cluster {
+			continue // the cluster goes on
+		}
+		if isWordSpace(g.r) {
+			extra += word
+		}
+		if i+1 < len(glyphs) {
+			extra += track
+		}
+		x, extra = x+extra, 0
+	}
+	return glyphs, x
+}
+
+// shapeGlyphs shapes s into glyphs with their shaper advances and offsets.
+// It reports false, with no glyphs, when the shaper fails on malformed font
+// data; its pooled state is dropped then.
+func (f *Font) shapeGlyphs(s string, boxes int) (glyphs []shapedGlyph, ok bool) {
+	sh := shapers.Get().(*otShaper)
+	defer func() {
+		if recover() != nil {
+			glyphs, ok = nil, false
+			return
+		}
+		shapers.Put(sh)
+	}()
+	hf := sh.font(f)
+
+	// Spacers and boxes are laid out by the font itself; the text between
+	// them goes to the shaper.
+	runes := []rune(s)
+	glyphs = make([]shapedGlyph, 0, len(runes))
+	for start := 0; start < len(runes); {
+		if r := runes[start]; f.plain(r, boxes) {
+			_, spacer := f.spacers[r]
+			glyphs = append(glyphs, shapedGlyph{r: r, ri: start, cluster: start, empty: spacer, box: !spacer})
+			start++
+			continue
+		}
+		end := start + 1
+		for end < len(runes) && !f.plain(runes[end], boxes) {
+			end++
+		}
+		glyphs = f.shapeRuns(sh, hf, runes, start, end, glyphs)
+		start = end
+	}
+	return glyphs, true
+}
+
+// plain reports whether r is laid out without shaping: a spacer, or a
+// missing-glyph box when s has any.
+func (f *Font) plain(r rune, boxes int) bool {
+	_, ok := f.spacers[r]
+	return ok || (boxes > 0 && f.drawsAsBox(r))
+}
+
+// shapeRuns shapes runes[start:end], in display order, one direction and
+// script run at a time, and appends the glyphs in display order.
+func (f *Font) shapeRuns(sh *otShaper, hf *harfbuzz.Font, runes []rune, start, end int, out []shapedGlyph) []shapedGlyph {
+	for start < end {
+		next, rtl := directionRun(runes[:end], start)
+		if !rtl {
+			for from := start; from < next; {
+				script, to := scriptRun(runes, from, next)
+				out = f.shapeRun(sh, hf, runes, from, to, script, false, 0, out)
+				from = to
+			}
+			start = next
+			continue
+		}
+
+		// Script runs of a right-to-left run follow each other in logical
+		// order, so they are laid out last to first. Their glyphs report
+		// the start of the run as their rune, since their clusters index the
+		// reversed text.
+		text := logicalOrder(runes[start:next])
+		var parts [][]shapedGlyph
+		for from := 0; from < len(text); {
+			script, to := scriptRun(text, from, len(text))
+			parts = append(parts, f.shapeRun(sh, hf, text, from, to, script, true, start, nil))
+			from = to
+		}
+		for k := len(parts) - 1; k >= 0; k-- {
+			for _, g := range parts[k] {
+				g.ri = start
+				out = append(out, g)
+			}
+		}
+		start = next
+	}
+	return out
+}
+
+// ligaturesOff disables the common ligatures, as CSS does under letter
+// spacing.
+var ligaturesOff = []harfbuzz.Feature{
+	{Tag: ot.MustNewTag("liga"), Value: 0, Start: harfbuzz.FeatureGlobalStart, End: harfbuzz.FeatureGlobalEnd},
+	{Tag: ot.MustNewTag("clig"), Value: 0, Start: harfbuzz.FeatureGlobalStart, End: harfbuzz.FeatureGlobalEnd},
+}
+
+// shapeRun shapes text[from:to], one script in logical order, and appends
+// its glyphs in display order. Clusters are rune indices into text, offset
+// by base.
+func (f *Font) shapeRun(sh *otShaper, hf *harfbuzz.Font, text []rune, from, to int, script language.Script, rtl bool, base int, out []shapedGlyph) []shapedGlyph {
+	buf := sh.buf
+	buf.Clear()
+	buf.AddRunes(text, from, to-from)
+	buf.Props.Script = script
+	buf.Props.Direction = harfbuzz.LeftToRight
+	if rtl {
+		buf.Props.Direction = harfbuzz.RightToLeft
+	}
+	var features []harfbuzz.Feature
+	if f.letterPercent != 0 {
+		features = ligaturesOff
+	}
+	buf.Shape(hf, features)
+
+	for i, info := range buf.Info {
+		pos := buf.Pos[i]
+		g := shapedGlyph{
+			index: sfnt.GlyphIndex(info.Glyph),
+			r:     text[info.Cluster],
+			ri:    base + info.Cluster,
+			adv:   fixed.Int26_6(pos.XAdvance),
+			dx:    fixed.Int26_6(pos.XOffset),
+			y:     -fixed.Int26_6(pos.YOffset),
+
+			cluster: base + info.Cluster,
+		}
+		if f.advanceHinting() != 0 {
+			g.adv = fixed.Int26_6(g.adv.Round() << 6)
+		}
+		out = append(out, g)
+	}
+	return out
+}
+
+// scriptRun returns the script of the run starting at text[from] and its
+// end, at most to. Characters shared between scripts (spaces, punctuation,
+// digits, combining marks) join the surrounding run.
+func scriptRun(text []rune, from, to int) (language.Script, int) {
+	script := language.Common
+	for i := from; i < to; i++ {
+		switch s := language.LookupScript(text[i]); {
+		case s == language.Common || s == language.Inherited || s == language.Unknown || s == script:
+		case script == language.Common:
+			script = s
+		default:
+			return script, i
+		}
+	}
+	return script, to
+}
+
+// directionRun returns the end of the run starting at runes[start] and
+// whether it is right-to-left. A right-to-left run spans clusters of
+// right-to-left letters and the neutrals between them; numbers and
+// left-to-right letters end it.
+func directionRun(runes []rune, start int) (end int, rtl bool) {
+	rtl = runes[start] >= 0x0590 && isRTL(runes[start])
+	end = clusterEnd(runes, start)
+	for i := end; i < len(runes); {
+		next := clusterEnd(runes, i)
+		if !rtl && runes[i] < 0x0590 { // nothing right-to-left before Hebrew
+			end, i = next, next
+			continue
+		}
+		p, _ := bidi.LookupRune(runes[i])
+		switch c := p.Class(); {
+		case c == bidi.R || c == bidi.AL:
+			if !rtl {
+				return end, false
+			}
+			end = next
+		case !rtl:
+			end = next
+		case c == bidi.L || c == bidi.EN || c == bidi.AN:
+			return end, true
+		}
+		i = next
+	}
+	return end, rtl
+}
+
+// isRTL reports whether r is a right-to-left letter.
+func isRTL(r rune) bool {
+	p, _ := bidi.LookupRune(r)
+	return p.Class() == bidi.R || p.Class() == bidi.AL
+}
+
+// clusterEnd returns the end of the cluster starting at runes[i]: the rune
+// and the combining marks and joiners following it.
+func clusterEnd(runes []rune, i int) int {
+	for i++; i < len(runes) && (isMark(runes[i]) || runes[i] == '\u200C' || runes[i] == '\u200D'); i++ {
+	}
+	return i
+}
+
+// isMark reports whether r is a combining mark.
+func isMark(r rune) bool { return unicode.In(r, unicode.Mn, unicode.Me) }
+
+// logicalOrder reverses the clusters of a display-order right-to-left run,
+// keeping each cluster's marks after their base.
+func logicalOrder(run []rune) []rune {
+	var starts []int
+	for i := 0; i < len(run); i = clusterEnd(run, i) {
+		starts = append(starts, i)
+	}
+	out := make([]rune, 0, len(run))
+	for k, end := len(starts)-1, len(run); k >= 0; k-- {
+		out = append(out, run[starts[k]:end]...)
+		end = starts[k]
+	}
+	return out
+}
+
+// drawShaped renders a glyph run with its origin at (x, baselineY), the
+// baseline already snapped to the pixel grid, and returns the final pen
+// position.
+func (f *Font) drawShaped(dst draw.Image, col color.Color, glyphs []shapedGlyph, advance fixed.Int26_6, x, baselineY float64) fixed.Point26_6 {
+	var buf sfnt.Buffer
+	var z vector.Rasterizer
+	z.DrawOp = draw.Src
+	ppem := geom.Fix(f.HeightPx())
+	src := image.NewUniform(col)
+	bold := f.syntheticBoldPx()
+
+	for _, g := range glyphs {
+		gx := f.placeX(x + geom.Unfix(g.x))
+		gy := baselineY + geom.Unfix(g.y)
+		if g.empty {
+			continue
+		}
+		if g.box {
+			f.drawMissingBox(dst, col, gx, baselineY)
+			continue
+		}
+		segs, err := f.loadOutline(&buf, g.index, ppem)
+		if err != nil || len(segs) == 0 {
+			continue
+		}
+
+		// Shearing moves points by -y*slant; emboldening smears the outline
+		// right by bold and up by bold/2.
+		b := segs.Bounds()
+		x0, x1 := geom.Unfix(b.Min.X), geom.Unfix(b.Max.X)
+		y0, y1 := geom.Unfix(b.Min.Y), geom.Unfix(b.Max.Y)
+		x0 += math.Min(-y0*f.slant, -y1*f.slant)
+		x1 += math.Max(-y0*f.slant, -y1*f.slant) + bold
+		y0 -= bold / 2
+		r := image.Rect(
+			int(math.Floor(gx+x0)), int(math.Floor(gy+y0)),
+			int(math.Ceil(gx+x1)), int(math.Ceil(gy+y1)),
+		)
+		if r.Empty() || !r.Overlaps(dst.Bounds()) {
+			continue
+		}
+
+		mask := image.NewAlpha(image.Rect(0, 0, r.Dx(), r.Dy()))
+		var tmp *image.Alpha
+		for _, off := range boldOffsets(bold) {
+			target := mask
+			if off != (vec2{}) {
+				if tmp == nil {
+					tmp = image.NewAlpha(mask.Bounds())
+				}
+				target = tmp
+			}
+			ox := float32(gx - float64(r.Min.X) + off.x)
+			oy := float32(gy - float64(r.Min.Y) + off.y)
+			f.traceGlyph(&z, segs, ox, oy, r.Dx(), r.Dy())
+			z.Draw(target, target.Bounds(), image.Opaque, image.Point{})
+			if target == tmp {
+				maxAlpha(mask, tmp)
+			}
+		}
+		draw.DrawMask(dst, r, src, image.Point{}, mask, image.Point{}, draw.Over)
+	}
+	return fixed.Point26_6{X: geom.Fix(x) + advance, Y: geom.Fix(baselineY)}
+}
+
+// traceGlyph resets z to w×h and adds the outline segs with its origin at
+// (ox, oy), applying the synthetic italic shear.
+func (f *Font) traceGlyph(z *vector.Rasterizer, segs sfnt.Segments, ox, oy float32, w, h int) {
+	slant := float32(f.slant)
+	pt := func(p fixed.Point26_6) (float32, float32) {
+		px, py := float32(geom.Unfix(p.X)), float32(geom.Unfix(p.Y))
+		return ox + px - py*slant, oy + py
+	}
+
+	z.Reset(w, h)
+	for _, s := range segs {
+		switch s.Op {
+		case sfnt.SegmentOpMoveTo:
+			z.MoveTo(pt(s.Args[0]))
+		case sfnt.SegmentOpLineTo:
+			z.LineTo(pt(s.Args[0]))
+		case sfnt.SegmentOpQuadTo:
+			bx, by := pt(s.Args[0])
+			cx, cy := pt(s.Args[1])
+			z.QuadTo(bx, by, cx, cy)
+		case sfnt.SegmentOpCubeTo:
+			bx, by := pt(s.Args[0])
+			cx, cy := pt(s.Args[1])
+			dx, dy := pt(s.Args[2])
+			z.CubeTo(bx, by, cx, cy, dx, dy)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package render
+
+import "maps"
+
+// SetSpacer reserves r as an empty advance em font sizes wide, which draws
+// nothing and is never reported missing. Text uses spacers to leave room for
+// inline images and shapes in a line. A non-positive em removes the spacer.
+// Spacers are copied on write, so copies of a Font can differ.
+func (f *Font) SetSpacer(r rune, em float64) *Font {
+	spacers := maps.Clone(f.spacers)
+	if em > 0 {
+		if spacers == nil {
+			spacers = make(map[rune]float64)
+		}
+		spacers[r] = em
+	} else {
+		delete(spacers, r)
+	}
+	f.spacers = spacers
+	return f
+}
+
+// spacerAdvancePx returns the advance of r in pixels if it is a spacer.
+func (f *Font) spacerAdvancePx(r rune) (float64, bool) {
+	em, ok := f.spacers[r]
+	return em * f.HeightPx(), ok
+}
@@ -0,0 +1,163 @@
+package render
+
+import (
+	"image"
+	"math"
+)
+
+// syntheticItalicDeg is the shear SetItalic applies to upright faces.
+const syntheticItalicDeg = 12
+
+// syntheticBoldPerWeight is the stem thickening, as a fraction of the font
+// size, that SetWeight adds per 100 units of requested weight above the
+// face's own weight.
+const syntheticBoldPerWeight = 0.01
+
+// SetSyntheticBold thickens glyph outlines by em times the font size in
+// pixels, emulating a heavier weight (0.03 is close to bold on a regular
+// face). Advances grow by the same amount. Zero disables it.
+//
+// Synthetic styles are drawn by the shaping path; with SetShaping(false) they
+// are ignored.
+func (f *Font) SetSyntheticBold(em float64) *Font {
+	f.boldEm = math.Max(em, 0)
+	return f
+}
+
+// SetSyntheticItalic shears glyphs by deg degrees to the right, emulating an
+// oblique style. Advances are unchanged. Zero disables it.
+func (f *Font) SetSyntheticItalic(deg float64) *Font {
+	f.slant = math.Tan(deg * math.Pi / 180)
+	return f
+}
+
+// SetWeight requests a CSS-style weight (100–900). Variable fonts with a
+// "wght" axis move along it. When the weight is heavier than the face can
+// reach (see FaceWeight and Axes), synthetic bold makes up the difference;
+// lighter weights cannot be synthesized and leave the face as is.
+func (f *Font) SetWeight(weight int) *Font {
+	f.SetVariation("wght", float64(weight))
+	return f.SetSyntheticBold(float64(weight-f.nearestWeight(weight)) / 100 * syntheticBoldPerWeight)
+}
+
+// SetItalic requests an italic style. Variable fonts with an "ital" or
+// "slnt" axis move along it, upright faces without one are sheared
+// synthetically, and faces that are already italic are drawn unchanged.
+func (f *Font) SetItalic(italic bool) *Font {
+	if i := f.axisIndex("ital"); i >= 0 {
+		value := 0.0
+		if italic {
+			value = 1
+		}
+		f.SetVariation("ital", value)
+		return f.SetSyntheticItalic(0)
+	}
+	if i := f.axisIndex("slnt"); i >= 0 {
+		// slnt counts degrees counter-clockwise, so italics are negative.
+		value := f.axes[i].Default
+		if italic {
+			value = f.axes[i].Min
+		}
+		f.SetVariation("slnt", value)
+		return f.SetSyntheticItalic(0)
+	}
+	if italic && !f.faceItalic {
+		return f.SetSyntheticItalic(syntheticItalicDeg)
+	}
+	return f.SetSyntheticItalic(0)
+}
+
+// SetSlant leans glyphs by deg degrees to the right (negative values lean
+// left). Variable fonts with a "slnt" axis move along it, and a synthetic
+// shear makes up whatever lies beyond the axis range; other faces are
+// sheared synthetically.
+func (f *Font) SetSlant(deg float64) *Font {
+	i := f.axisIndex("slnt")
+	if i < 0 {
+		return f.SetSyntheticItalic(deg)
+	}
+	// slnt counts degrees counter-clockwise, so a rightward lean is negative.
+	f.SetVariation("slnt", -deg)
+	reached, _ := f.Variation("slnt")
+	return f.SetSyntheticItalic(deg + reached)
+}
+
+// nearestWeight returns the weight closest to weight that the face can draw
+// without synthetic bold: its own weight, or for variable fonts the
+// requested one clamped to the "wght" axis.
+func (f *Font) nearestWeight(weight int) int {
+	if i := f.axisIndex("wght"); i >= 0 {
+		a := f.axes[i]
+		return int(math.Round(math.Max(a.Min, math.Min(a.Max, float64(weight)))))
+	}
+	return f.faceWeight
+}
+
+// FaceWeight returns the weight class the font file declares (OS/2
+// usWeightClass), or 400 when it is absent.
+func (f *Font) FaceWeight() int { return f.faceWeight }
+
+// FaceItalic reports whether the font file declares an italic style.
+func (f *Font) FaceItalic() bool { return f.faceItalic }
+
+// OverhangPx returns how far, in pixels, synthetic styles can extend glyph
+// ink beyond the measured advance on the left and right of a line.
+func (f *Font) OverhangPx() (left, right float64) {
+	if f.unshaped {
+		return 0, 0
+	}
+	left = math.Abs(f.slant) * f.DescentPx()
+	right = math.Abs(f.slant)*f.AscentPx() + f.syntheticBoldPx()
+	if f.slant < 0 {
+		left, right = right-f.syntheticBoldPx(), left+f.syntheticBoldPx()
+	}
+	return left, right
+}
+
+// syntheticBoldPx returns the synthetic stem thickening in pixels.
+func (f *Font) syntheticBoldPx() float64 { return f.boldEm * f.HeightPx() }
+
+// parseFaceStyle reads the declared weight and italic flag from the OS/2 and
+// post tables.
+func (f *Font) parseFaceStyle(data []byte) {
+	f.faceWeight = 400
+	if os2 := findTable(data, "OS/2"); len(os2) >= 6 {
+		if w := int(u16(os2, 4)); w > 0 {
+			f.faceWeight = w
+		}
+		f.faceItalic = u16(os2, 62)&1 != 0
+	}
+	if post := findTable(data, "post"); len(post) >= 8 && u32(post, 4) != 0 {
+		f.faceItalic = true
+	}
+}
+
+// vec2 is a sub-pixel offset.
+type vec2 struct{ x, y float64 }
+
+// boldOffsets returns the outline offsets whose union emboldens a glyph by
+// px: right by up to px and up by up to px/2, sampled at most half a pixel
+// apart. Without emboldening it is the single zero offset.
+func boldOffsets(px float64) []vec2 {
+	if px <= 0 {
+		return []vec2{{}}
+	}
+	nx := int(math.Ceil(px * 2))
+	ny := int(math.Ceil(px))
+	out := make([]vec2, 0, (nx+1)*(ny+1))
+	for j := 0; j <= ny; j++ {
+		for i := 0; i <= nx; i++ {
+			out = append(out, vec2{x: px * float64(i) / float64(nx), y: -px / 2 * float64(j) / float64(ny)})
+		}
+	}
+	return out
+}
+
+// maxAlpha stores the per-pixel maximum of dst and src in dst.
+func maxAlpha(dst, src *image.Alpha) {
+	for i, a := range src.Pix {
+		if a > dst.Pix[i] {
+			dst.Pix[i] = a
+		}
+	}
+}
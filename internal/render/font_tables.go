@@ -0,0 +1,42 @@
+package render
+
+import "encoding/binary"
+
+// Raw table access for the few header fields (OS/2 style and strikeout,
+// post underline) that neither sfnt nor truetype expose.
+
+// findTable returns the bytes of the named table of a single sfnt font, or
+// nil if it is absent.
+func findTable(data []byte, tag string) []byte {
+	for i, n := 0, int(u16(data, 4)); i < n; i++ {
+		rec := 12 + i*16
+		if rec+16 > len(data) {
+			return nil
+		}
+		if string(data[rec:rec+4]) != tag {
+			continue
+		}
+		off, size := int(u32(data, rec+8)), int(u32(data, rec+12))
+		if off < 0 || size < 0 || off+size > len(data) {
+			return nil
+		}
+		return data[off : off+size]
+	}
+	return nil
+}
+
+// u16 reads a big-endian uint16 at off, returning 0 when out of range.
+func u16(b []byte, off int) uint16 {
+	if off < 0 || off+2 > len(b) {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b[off:])
+}
+
+// u32 reads a big-endian uint32 at off, returning 0 when out of range.
+func u32(b []byte, off int) uint32 {
+	if off < 0 || off+4 > len(b) {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b[off:])
+}
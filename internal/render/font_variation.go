@@ -0,0 +1,145 @@
+package render
+
+import (
+	"math"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+	otfont "github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/font/opentype/tables"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Variable fonts. sfnt and truetype only draw a font's default instance, so
+// for other positions on its design axes the shaping path takes outlines
+// and advances from go-text/typesetting, which applies the avar, gvar and
+// HVAR (or CFF2) variation data.
+
+// FontAxis is a design axis of a variable font, in the axis' user units
+// (for example 100–900 for "wght").
+type FontAxis struct {
+	Tag               string // four-letter axis tag: "wght", "wdth", "slnt", "ital", ...
+	Min, Default, Max float64
+}
+
+// parseAxes reads the design axes from the fvar table, returning nil for
+// static fonts and for tables it cannot use.
+func parseAxes(ld *ot.Loader) []FontAxis {
+	raw, err := ld.RawTable(ot.MustNewTag("fvar"))
+	if err != nil {
+		return nil
+	}
+	fvar, _, err := tables.ParseFvar(raw)
+	if err != nil {
+		return nil
+	}
+	var axes []FontAxis
+	for _, a := range fvar.Axis {
+		axes = append(axes, FontAxis{
+			Tag:     a.Tag.String(),
+			Min:     float64(a.Minimum),
+			Default: float64(a.Default),
+			Max:     float64(a.Maximum),
+		})
+	}
+	return axes
+}
+
+// Axes returns the design axes of a variable font, or nil for a static one.
+func (f *Font) Axes() []FontAxis {
+	return append([]FontAxis(nil), f.axes...)
+}
+
+// SetVariation moves a variable font along the axis with the given tag (for
+// example "wght", "wdth" or "slnt") to value, in the axis' user units and
+// clamped to its range. Tags the font has no axis for are ignored.
+//
+// Variations are drawn by the shaping path from unhinted outlines, with
+// advances and kerning of the instance; with SetShaping(false) the default
+// instance is drawn. Vertical metrics are those of the default instance.
+func (f *Font) SetVariation(tag string, value float64) *Font {
+	i := f.axisIndex(tag)
+	if i < 0 {
+		return f
+	}
+	axes := f.axes
+	values := make([]float64, len(axes))
+	for k, a := range axes {
+		values[k] = a.Default
+	}
+	copy(values, f.axisValues)
+	values[i] = math.Max(axes[i].Min, math.Min(axes[i].Max, value))
+
+	design := make([]float32, len(values))
+	for k, v := range values {
+		design[k] = float32(v)
+	}
+	coords := f.ot.NormalizeVariations(design)
+	varied := false
+	for _, c := range coords {
+		varied = varied || c != 0
+	}
+	f.axisValues, f.coords = values, coords
+	if !varied {
+		f.coords = nil
+	}
+	return f
+}
+
+// Variation returns the font's position on the axis with the given tag, and
+// false when the font has no such axis.
+func (f *Font) Variation(tag string) (float64, bool) {
+	i := f.axisIndex(tag)
+	switch {
+	case i < 0:
+		return 0, false
+	case f.axisValues == nil:
+		return f.axes[i].Default, true
+	}
+	return f.axisValues[i], true
+}
+
+// SetWidth selects the "wdth" axis of a variable font, as a percentage of
+// the normal width. Fonts without that axis are unchanged.
+func (f *Font) SetWidth(percent float64) *Font { return f.SetVariation("wdth", percent) }
+
+// axisIndex returns the index of the axis with the given tag, or -1.
+func (f *Font) axisIndex(tag string) int {
+	if f.ot == nil {
+		return -1
+	}
+	for i, a := range f.axes {
+		if a.Tag == tag {
+			return i
+		}
+	}
+	return -1
+}
+
+// varied reports whether glyphs are drawn away from the default instance.
+func (f *Font) varied() bool { return f.coords != nil && f.ot != nil }
+
+// variedOutline returns the outline of glyph index at ppem at the font's
+// axis positions, in the form loadOutline returns. It reports false when
+// the glyph's variation data is malformed.
+func (f *Font) variedOutline(index sfnt.GlyphIndex, ppem fixed.Int26_6) (segs sfnt.Segments, ok bool) {
+	sh := shapers.Get().(*otShaper)
+	defer func() {
+		if recover() != nil {
+			segs, ok = nil, false
+			return
+		}
+		shapers.Put(sh)
+	}()
+	outline, _ := sh.font(f).Face().GlyphData(otfont.GID(index)).(otfont.GlyphOutline)
+	scale := geom.Unfix(ppem) / float64(f.ot.Upem())
+	segs = make(sfnt.Segments, len(outline.Segments))
+	for i, s := range outline.Segments {
+		segs[i].Op = sfnt.SegmentOp(s.Op) // both list move, line, quad, cube
+		for k, p := range s.ArgsSlice() {
+			segs[i].Args[k] = fixed.Point26_6{X: geom.Fix(float64(p.X) * scale), Y: geom.Fix(-float64(p.Y) * scale)}
+		}
+	}
+	return segs, true
+}
@@ -0,0 +1,212 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Hyphenator finds legal hyphenation points in words using Frank Liang's
+// algorithm, the one TeX uses, driven by language-specific patterns such as
+// the hyph-*.pat.txt files of the hyph-utf8 project.
+type Hyphenator struct {
+	patterns   map[string][]uint8 // letters → inter-letter values
+	maxLen     int                // longest pattern, in runes
+	exceptions map[string][]int   // word → break positions, in runes
+	leftMin    int
+	rightMin   int
+}
+
+// NewHyphenator builds a Hyphenator from TeX patterns such as "hy3ph" or
+// ".ach4", and exceptions written with hyphens at their breaks, such as
+// "ta-ble". At least 2 letters are kept before a break and 3 after it, the
+// TeX defaults for English; see SetMinima.
+func NewHyphenator(patterns, exceptions []string) *Hyphenator {
+	h := &Hyphenator{
+		patterns:   make(map[string][]uint8, len(patterns)),
+		exceptions: make(map[string][]int, len(exceptions)),
+		leftMin:    2,
+		rightMin:   3,
+	}
+	for _, p := range patterns {
+		h.addPattern(p)
+	}
+	for _, e := range exceptions {
+		h.addException(e)
+	}
+	return h
+}
+
+// ParseHyphenator reads TeX patterns from r. Both bare pattern lists, one or
+// more per line, and TeX files with \patterns{...} and \hyphenation{...}
+// groups are understood; '%' starts a comment.
+func ParseHyphenator(r io.Reader) (*Hyphenator, error) {
+	var patterns, exceptions []string
+	target := &patterns
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '%'); i >= 0 {
+			line = line[:i]
+		}
+		for _, tok := range strings.Fields(line) {
+			switch {
+			case strings.HasPrefix(tok, `\patterns{`):
+				target, tok = &patterns, strings.TrimPrefix(tok, `\patterns{`)
+			case strings.HasPrefix(tok, `\hyphenation{`):
+				target, tok = &exceptions, strings.TrimPrefix(tok, `\hyphenation{`)
+			case strings.HasPrefix(tok, `\`):
+				continue
+			}
+			if tok = strings.TrimSuffix(tok, "}"); tok != "" {
+				*target = append(*target, tok)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("parse hyphenation patterns: %w", err)
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("parse hyphenation patterns: no patterns found")
+	}
+	return NewHyphenator(patterns, exceptions), nil
+}
+
+// SetMinima sets how many letters must remain before (left) and after
+// (right) a break. Values below 1 are raised to 1.
+func (h *Hyphenator) SetMinima(left, right int) *Hyphenator {
+	h.leftMin, h.rightMin = max(left, 1), max(right, 1)
+	return h
+}
+
+// addPattern splits a pattern like "hen5at" into its letters and the values
+// between them.
+func (h *Hyphenator) addPattern(p string) {
+	var letters []rune
+	values := []uint8{0}
+	for _, r := range p {
+		if r >= '0' && r <= '9' {
+			values[len(values)-1] = uint8(r - '0')
+			continue
+		}
+		letters = append(letters, unicode.ToLower(r))
+		values = append(values, 0)
+	}
+	if len(letters) == 0 {
+		return
+	}
+	h.patterns[string(letters)] = values
+	h.maxLen = max(h.maxLen, len(letters))
+}
+
+// addException records the breaks of an exception like "ta-ble".
+func (h *Hyphenator) addException(e string) {
+	var word []rune
+	var breaks []int
+	for _, r := range e {
+		if r == '-' {
+			breaks = append(breaks, len(word))
+			continue
+		}
+		word = append(word, unicode.ToLower(r))
+	}
+	h.exceptions[string(word)] = breaks
+}
+
+// Points returns the rune offsets inside word at which it may be broken,
+// in ascending order. The word should consist of letters only.
+func (h *Hyphenator) Points(word string) []int {
+	n := utf8.RuneCountInString(word)
+	if h == nil || n < h.leftMin+h.rightMin {
+		return nil
+	}
+	lower := strings.ToLower(word)
+	if breaks, ok := h.exceptions[lower]; ok {
+		return breaks
+	}
+
+	dotted := []rune("." + lower + ".")
+	values := make([]uint8, len(dotted)+1)
+	for i := range dotted {
+		for j := i + 1; j <= len(dotted) && j-i <= h.maxLen; j++ {
+			v, ok := h.patterns[string(dotted[i:j])]
+			if !ok {
+				continue
+			}
+			for k, x := range v {
+				values[i+k] = max(values[i+k], x)
+			}
+		}
+	}
+
+	// values[k] sits before dotted[k]; break before word rune p is values[p+1].
+	var points []int
+	for p := h.leftMin; p <= n-h.rightMin; p++ {
+		if values[p+1]%2 == 1 {
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+// Hyphenate returns s with sep inserted at every hyphenation point of its
+// words. Words are runs of letters; other characters are copied unchanged.
+func (h *Hyphenator) Hyphenate(s, sep string) string {
+	var b strings.Builder
+	var word []rune
+	flush := func() {
+		points := h.Points(string(word))
+		for i, r := range word {
+			if len(points) > 0 && points[0] == i {
+				b.WriteString(sep)
+				points = points[1:]
+			}
+			b.WriteRune(r)
+		}
+		word = word[:0]
+	}
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			word = append(word, r)
+			continue
+		}
+		flush()
+		b.WriteRune(r)
+	}
+	flush()
+	return b.String()
+}
+
+// hyphenators holds Hyphenators registered by language.
+var hyphenators = struct {
+	sync.RWMutex
+	byLang map[string]*Hyphenator
+}{byLang: map[string]*Hyphenator{}}
+
+// RegisterHyphenator makes h available to LookupHyphenator under the
+// language tag lang, such as "en-us". Tags are matched case-insensitively.
+func RegisterHyphenator(lang string, h *Hyphenator) {
+	hyphenators.Lock()
+	hyphenators.byLang[strings.ToLower(lang)] = h
+	hyphenators.Unlock()
+}
+
+// LookupHyphenator returns the Hyphenator registered for lang. When none is
+// registered for a tag like "en-GB", its primary language "en" is tried.
+func LookupHyphenator(lang string) (*Hyphenator, bool) {
+	lang = strings.ToLower(lang)
+	hyphenators.RLock()
+	defer hyphenators.RUnlock()
+	if h, ok := hyphenators.byLang[lang]; ok {
+		return h, true
+	}
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		h, ok := hyphenators.byLang[lang[:i]]
+		return h, ok
+	}
+	return nil, false
+}
@@ -0,0 +1,100 @@
+package render
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/golang/freetype/raster"
+)
+
+// MaskedSolidPainter implements the freetype/raster.Painter interface for a
+// single solid color under a clip mask. It mirrors raster.RGBAPainter's Over
+// compositing, but scales each pixel's span coverage by the mask alpha first,
+// so clipped solid fills and strokes avoid the generic PatternPainter path.
+type MaskedSolidPainter struct {
+	overlay        *image.RGBA  // Target overlay
+	mask           *image.Alpha // Clip mask in canvas coordinates
+	cr, cg, cb, ca uint32       // Premultiplied source color
+}
+
+// Paint renders a list of raster spans onto the overlay using the Over operator.
+// Pixels outside the mask or with zero mask alpha are left untouched.
+func (r *MaskedSolidPainter) Paint(ss []raster.Span, _ bool) {
+	b := r.overlay.Bounds()
+	mb := r.mask.Bounds()
+
+	const m = 1<<16 - 1 // Maximum alpha value used by raster.Span
+
+	for _, s := range ss {
+		// Skip spans outside vertical bounds
+		if s.Y < b.Min.Y {
+			continue
+		}
+		if s.Y >= b.Max.Y {
+			return
+		}
+
+		// Clamp horizontal span to image bounds
+		if s.X0 < b.Min.X {
+			s.X0 = b.Min.X
+		}
+		if s.X1 > b.Max.X {
+			s.X1 = b.Max.X
+		}
+		if s.X0 >= s.X1 {
+			continue
+		}
+
+		// The mask is addressed in canvas coordinates.
+		y := s.Y
+		if y < mb.Min.Y || y >= mb.Max.Y {
+			continue
+		}
+		x0, x1 := s.X0, s.X1
+		if x0 < mb.Min.X {
+			x0 = mb.Min.X
+		}
+		if x1 > mb.Max.X {
+			x1 = mb.Max.X
+		}
+		if x0 >= x1 {
+			continue
+		}
+
+		i := r.overlay.PixOffset(x0, y)
+		j := r.mask.PixOffset(x0, y)
+		for x := x0; x < x1; x, i, j = x+1, i+4, j+1 {
+			ma := s.Alpha * uint32(r.mask.Pix[j]) / 255
+			if ma == 0 {
+				continue
+			}
+
+			a := (m - (r.ca * ma / m)) * 0x101
+			dr := uint32(r.overlay.Pix[i+0])
+			dg := uint32(r.overlay.Pix[i+1])
+			db := uint32(r.overlay.Pix[i+2])
+			da := uint32(r.overlay.Pix[i+3])
+			r.overlay.Pix[i+0] = uint8((dr*a + r.cr*ma) / m >> 8)
+			r.overlay.Pix[i+1] = uint8((dg*a + r.cg*ma) / m >> 8)
+			r.overlay.Pix[i+2] = uint8((db*a + r.cb*ma) / m >> 8)
+			r.overlay.Pix[i+3] = uint8((da*a + r.ca*ma) / m >> 8)
+		}
+	}
+}
+
+// SetColor sets the color to paint the spans.
+func (r *MaskedSolidPainter) SetColor(c color.Color) {
+	r.cr, r.cg, r.cb, r.ca = c.RGBA()
+}
+
+// NewMaskedSolidPainter creates and returns a new MaskedSolidPainter.
+//
+// Parameters:
+//   - overlay: destination RGBA layer where spans are composited
+//   - mask: clip mask whose alpha scales span coverage
+//   - c: solid paint color
+func NewMaskedSolidPainter(overlay *image.RGBA, mask *image.Alpha, c color.Color) *MaskedSolidPainter {
+	p := &MaskedSolidPainter{overlay: overlay, mask: mask}
+	p.SetColor(c)
+	return p
+}
@@ -0,0 +1,368 @@
+package render
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// FormatMessage formats msg, a subset of ICU MessageFormat, with args for the
+// BCP 47 locale, so labels such as "1 member" and "3 members" can be written
+// once per language. Supported arguments are:
+//
+//   - {name} and {name, number}: the value of args[name].
+//   - {name, plural, =0 {...} one {...} other {...}}: the branch for the
+//     value's CLDR plural category in the locale, with exact =N matches
+//     taking precedence. An optional "offset:N" after the comma subtracts N
+//     from the value before choosing a category. Inside a branch, # is the
+//     value, minus the offset.
+//   - {name, selectordinal, one {#st} two {#nd} few {#rd} other {#th}}: as
+//     plural, with ordinal categories.
+//   - {name, select, female {...} male {...} other {...}}: the branch whose
+//     key equals the value as a string, for gender and other choices.
+//
+// Branches may nest arguments. An other branch is required in plural,
+// selectordinal and select. A single quote escapes a following brace, #, or
+// quote: "'{'" is a literal brace and a doubled quote is a literal quote.
+// Numbers are ints, unsigned ints, floats, or strings holding a number. An
+// unknown or empty locale uses English rules.
+func FormatMessage(locale, msg string, args map[string]any) (string, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		tag = language.English
+	}
+	p := &msgParser{s: msg, tag: tag, args: args}
+	var b strings.Builder
+	if err := p.message(&b, "", false); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// msgParser formats a message while parsing it. A nil builder parses a
+// branch that was not chosen, without looking up its arguments.
+type msgParser struct {
+	s    string
+	pos  int
+	tag  language.Tag
+	args map[string]any
+}
+
+// errorf returns a syntax error at the current position.
+func (p *msgParser) errorf(format string, a ...any) error {
+	return fmt.Errorf("message: %s at offset %d", fmt.Sprintf(format, a...), p.pos)
+}
+
+// emit writes s to b unless the branch is skipped.
+func emit(b *strings.Builder, s string) {
+	if b != nil {
+		b.WriteString(s)
+	}
+}
+
+// message formats text up to the end of input or, when nested, up to the
+// closing brace of the branch, which is left unread. hash replaces # inside
+// plural branches and is empty elsewhere.
+func (p *msgParser) message(b *strings.Builder, hash string, nested bool) error {
+	for p.pos < len(p.s) {
+		switch c := p.s[p.pos]; {
+		case c == '}':
+			if nested {
+				return nil
+			}
+			return p.errorf("unmatched '}'")
+		case c == '{':
+			if err := p.argument(b, hash); err != nil {
+				return err
+			}
+		case c == '#' && hash != "":
+			p.pos++
+			emit(b, hash)
+		case c == '\'':
+			p.quote(b, hash != "")
+		default:
+			_, n := utf8.DecodeRuneInString(p.s[p.pos:])
+			emit(b, p.s[p.pos:p.pos+n])
+			p.pos += n
+		}
+	}
+	if nested {
+		return p.errorf("unclosed '{'")
+	}
+	return nil
+}
+
+// quote handles an apostrophe: a doubled quote is a quote, and one before a
+// brace (or # in plural branches) starts literal text up to the next lone
+// quote. Other quotes are literal.
+func (p *msgParser) quote(b *strings.Builder, inPlural bool) {
+	p.pos++
+	if p.pos >= len(p.s) {
+		emit(b, "'")
+		return
+	}
+	switch c := p.s[p.pos]; {
+	case c == '\'':
+		p.pos++
+		emit(b, "'")
+		return
+	case c == '{' || c == '}' || c == '#' && inPlural:
+	default:
+		emit(b, "'")
+		return
+	}
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		if c != '\'' {
+			emit(b, p.s[p.pos-1:p.pos])
+			continue
+		}
+		if p.pos < len(p.s) && p.s[p.pos] == '\'' {
+			p.pos++
+			emit(b, "'")
+			continue
+		}
+		return
+	}
+}
+
+// skipSpace advances past ASCII white space.
+func (p *msgParser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// word reads an argument name, type or branch key.
+func (p *msgParser) word() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n{},", p.s[p.pos]) < 0 {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// expect consumes c, after optional white space.
+func (p *msgParser) expect(c byte) error {
+	p.skipSpace()
+	if p.pos >= len(p.s) || p.s[p.pos] != c {
+		return p.errorf("expected '%c'", c)
+	}
+	p.pos++
+	return nil
+}
+
+// argument formats the argument starting at the opening brace.
+func (p *msgParser) argument(b *strings.Builder, hash string) error {
+	p.pos++
+	name := p.word()
+	if name == "" {
+		return p.errorf("missing argument name")
+	}
+	val, ok := p.args[name]
+	if !ok && b != nil {
+		return fmt.Errorf("message: missing argument %q", name)
+	}
+
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == '}' {
+		p.pos++
+		emit(b, argString(val))
+		return nil
+	}
+	if err := p.expect(','); err != nil {
+		return err
+	}
+	switch kind := p.word(); kind {
+	case "number":
+		if err := p.expect('}'); err != nil {
+			return err
+		}
+		if b == nil {
+			return nil
+		}
+		num, ok := numberString(val)
+		if !ok {
+			return fmt.Errorf("message: argument %q is not a number", name)
+		}
+		emit(b, num)
+		return nil
+	case "plural", "selectordinal", "select":
+		if err := p.expect(','); err != nil {
+			return err
+		}
+		return p.choice(b, hash, name, kind, val)
+	default:
+		return p.errorf("unsupported argument type %q", kind)
+	}
+}
+
+// branch is one "key {message}" of a choice argument.
+type branch struct {
+	key   string
+	start int // offset of the message, after its opening brace
+}
+
+// choice formats a plural, selectordinal or select argument after its second
+// comma, through the closing brace.
+func (p *msgParser) choice(b *strings.Builder, hash, name, kind string, val any) error {
+	offset := 0.0
+	p.skipSpace()
+	if kind != "select" && strings.HasPrefix(p.s[p.pos:], "offset:") {
+		p.pos += len("offset:")
+		n, err := strconv.ParseFloat(p.word(), 64)
+		if err != nil {
+			return p.errorf("invalid offset")
+		}
+		offset = n
+	}
+
+	// Scan the branches, then format the chosen one.
+	var branches []branch
+	for {
+		p.skipSpace()
+		if p.pos < len(p.s) && p.s[p.pos] == '}' {
+			break
+		}
+		key := p.word()
+		if key == "" {
+			return p.errorf("missing %s key", kind)
+		}
+		if err := p.expect('{'); err != nil {
+			return err
+		}
+		branches = append(branches, branch{key: key, start: p.pos})
+		if err := p.message(nil, "#", true); err != nil {
+			return err
+		}
+		p.pos++
+	}
+	end := p.pos + 1
+
+	other := -1
+	for i, br := range branches {
+		if br.key == "other" {
+			other = i
+		}
+	}
+	if other < 0 {
+		return p.errorf("%s without an other branch", kind)
+	}
+	if b == nil {
+		p.pos = end
+		return nil
+	}
+
+	chosen := other
+	if kind == "select" {
+		key := argString(val)
+		for i, br := range branches {
+			if br.key == key {
+				chosen = i
+				break
+			}
+		}
+	} else {
+		num, ok := numberString(val)
+		if !ok {
+			return fmt.Errorf("message: argument %q is not a number", name)
+		}
+		f, _ := strconv.ParseFloat(num, 64)
+		if offset != 0 {
+			num = strconv.FormatFloat(f-offset, 'f', -1, 64)
+		}
+		hash = num
+		chosen = p.choose(branches, f, num, kind == "selectordinal", chosen)
+	}
+
+	p.pos = branches[chosen].start
+	if err := p.message(b, hash, true); err != nil {
+		return err
+	}
+	p.pos = end
+	return nil
+}
+
+// choose returns the branch for a plural value: an exact =N match on the
+// value before the offset, else the CLDR category of num, else fallback.
+func (p *msgParser) choose(branches []branch, value float64, num string, ordinal bool, fallback int) int {
+	for i, br := range branches {
+		if exact, ok := strings.CutPrefix(br.key, "="); ok {
+			if n, err := strconv.ParseFloat(exact, 64); err == nil && n == value {
+				return i
+			}
+		}
+	}
+	rules := plural.Cardinal
+	if ordinal {
+		rules = plural.Ordinal
+	}
+	i, v, w, f, t := pluralOperands(num)
+	cat := formNames[rules.MatchPlural(p.tag, i, v, w, f, t)]
+	for j, br := range branches {
+		if br.key == cat {
+			return j
+		}
+	}
+	return fallback
+}
+
+// formNames maps plural forms to their MessageFormat keywords.
+var formNames = map[plural.Form]string{
+	plural.Other: "other",
+	plural.Zero:  "zero",
+	plural.One:   "one",
+	plural.Two:   "two",
+	plural.Few:   "few",
+	plural.Many:  "many",
+}
+
+// pluralOperands returns the CLDR operands i, v, w, f and t of a decimal
+// number: the integer digits, the count of visible fraction digits with and
+// without trailing zeros, and those fraction digits as integers.
+func pluralOperands(num string) (i, v, w, f, t int) {
+	num = strings.TrimPrefix(num, "-")
+	ip, fp, _ := strings.Cut(num, ".")
+	i, _ = strconv.Atoi(ip)
+	v = len(fp)
+	f, _ = strconv.Atoi(fp)
+	tp := strings.TrimRight(fp, "0")
+	w = len(tp)
+	t, _ = strconv.Atoi(tp)
+	return i, v, w, f, t
+}
+
+// numberString formats a numeric argument, reporting false for other types.
+func numberString(val any) (string, bool) {
+	switch n := val.(type) {
+	case int:
+		return strconv.Itoa(n), true
+	case int8, int16, int32, int64:
+		return fmt.Sprint(n), true
+	case uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(n), true
+	case float32:
+		return strconv.FormatFloat(float64(n), 'f', -1, 32), true
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64), true
+	case string:
+		if _, err := strconv.ParseFloat(n, 64); err == nil {
+			return n, true
+		}
+	}
+	return "", false
+}
+
+// argString formats an argument for substitution or select.
+func argString(val any) string {
+	if num, ok := numberString(val); ok {
+		return num
+	}
+	return fmt.Sprint(val)
+}
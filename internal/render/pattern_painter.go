@@ -3,7 +3,8 @@ package render
 import (
 	"image"
 
-	"github.com/Krispeckt/glimo/internal/core/image/patterns"
+	"github.com/Krispeckt/glimo/internal/core/geom"
+	"github.com/Krispeckt/glimo/patterns"
 	"github.com/golang/freetype/raster"
 )
 
@@ -17,6 +18,8 @@ type PatternPainter struct {
 	overlay, base *image.RGBA  // Target overlay and base layers
 	mask          *image.Alpha // Optional alpha mask for coverage control
 	pattern       patterns.Pattern
+	colors        []patterns.Color // Reused per-span color buffer
+	accurate      bool             // blend with Color.BlendOverAccurate
 }
 
 // Paint renders a list of raster spans (`ss`) onto the overlay image.
@@ -24,6 +27,9 @@ type PatternPainter struct {
 // image according to the pattern's blend mode and opacity.
 //
 // If a mask is provided, it modulates the per-pixel alpha coverage.
+// Span colors are evaluated in one pass via patterns.FillSpan, so patterns
+// with span evaluators (gradients, surfaces) avoid per-pixel projection, and
+// runs of identical color, coverage and background are blended once.
 // This function is typically called by a rasterizer during vector path filling.
 func (r *PatternPainter) Paint(ss []raster.Span, _ bool) {
 	b := r.overlay.Bounds()
@@ -40,6 +46,19 @@ func (r *PatternPainter) Paint(ss []raster.Span, _ bool) {
 
 	const m = 1<<16 - 1 // Maximum alpha value used by raster.Span
 
+	blend := patterns.Color.BlendOver
+	if r.accurate {
+		blend = patterns.Color.BlendOverAccurate
+	}
+
+	// Last blend inputs and result, premultiplied.
+	var memo struct {
+		src, bg, out patterns.Color
+		ma           uint32
+		r, g, b      uint8
+		ok           bool
+	}
+
 	for _, s := range ss {
 		// Skip spans outside vertical bounds
 		if s.Y < b.Min.Y {
@@ -60,11 +79,21 @@ func (r *PatternPainter) Paint(ss []raster.Span, _ bool) {
 			continue
 		}
 
-		y := s.Y - r.overlay.Rect.Min.Y
-		x0 := s.X0 - r.overlay.Rect.Min.X
+		// Pattern and mask are sampled in canvas coordinates, so overlays
+		// covering only part of the canvas line up with full-size ones.
+		y := s.Y
+		x0 := s.X0
 		i0 := (s.Y-r.overlay.Rect.Min.Y)*r.overlay.Stride + (s.X0-r.overlay.Rect.Min.X)*4
 		i1 := i0 + (s.X1-s.X0)*4
 
+		// Sample pattern colors for the whole span
+		n := s.X1 - s.X0
+		if cap(r.colors) < n {
+			r.colors = make([]patterns.Color, n)
+		}
+		cols := r.colors[:n]
+		patterns.FillSpan(r.pattern, y, x0, x0+n, cols)
+
 		// Process each pixel in the span
 		for i, x := i0, x0; i < i1; i, x = i+4, x+1 {
 			ma := s.Alpha
@@ -77,41 +106,42 @@ func (r *PatternPainter) Paint(ss []raster.Span, _ bool) {
 				}
 			}
 
-			// Sample pattern color
-			col := r.pattern.ColorAt(x, y)
-			src, ok := col.(patterns.Color)
-			if !ok {
-				src = patterns.NewColorFromStd(col)
-			}
+			src := cols[x-x0]
 
 			// Apply inherited blend mode if "PassThrough"
 			if src.BlendMode().String() == "PassThrough" {
 				src = src.SetBlendMode(mode)
 			}
 
-			// Read background color
-			bg := patterns.Color{
-				R: r.base.Pix[i+0],
-				G: r.base.Pix[i+1],
-				B: r.base.Pix[i+2],
-				A: r.base.Pix[i+3],
+			// Read the premultiplied background; blending works on straight
+			// colors, so un-premultiply it first.
+			pb := r.base.Pix[i : i+4 : i+4]
+			bg := patterns.Color{A: pb[3]}
+			bg.R, bg.G, bg.B = geom.UnpremultiplyRGB(pb[0], pb[1], pb[2], pb[3])
+
+			// Solid fills and flat backgrounds repeat the same inputs along
+			// a span, so the last blend is reused when nothing changed.
+			if !memo.ok || memo.src != src || memo.bg != bg || memo.ma != ma {
+				blended := blend(src, bg, float64(ma)/float64(m))
+				memo.src, memo.bg, memo.ma, memo.ok = src, bg, ma, true
+				memo.out = blended
+				memo.r, memo.g, memo.b = geom.PremultiplyRGB(blended.R, blended.G, blended.B, blended.A)
 			}
-
-			coverage := float64(ma) / float64(m)
-			blended := src.BlendOver(bg, coverage)
+			blended := memo.out
+			br, bgr, bb := memo.r, memo.g, memo.b
 
 			// Apply opacity blending to the overlay
 			if opacity >= 1.0 {
-				r.overlay.Pix[i+0] = blended.R
-				r.overlay.Pix[i+1] = blended.G
-				r.overlay.Pix[i+2] = blended.B
+				r.overlay.Pix[i+0] = br
+				r.overlay.Pix[i+1] = bgr
+				r.overlay.Pix[i+2] = bb
 				r.overlay.Pix[i+3] = blended.A
 			} else {
 				inv := 1.0 - opacity
-				r.overlay.Pix[i+0] = uint8(inv*float64(bg.R) + opacity*float64(blended.R) + 0.5)
-				r.overlay.Pix[i+1] = uint8(inv*float64(bg.G) + opacity*float64(blended.G) + 0.5)
-				r.overlay.Pix[i+2] = uint8(inv*float64(bg.B) + opacity*float64(blended.B) + 0.5)
-				r.overlay.Pix[i+3] = uint8(inv*float64(bg.A) + opacity*float64(blended.A) + 0.5)
+				r.overlay.Pix[i+0] = uint8(inv*float64(pb[0]) + opacity*float64(br) + 0.5)
+				r.overlay.Pix[i+1] = uint8(inv*float64(pb[1]) + opacity*float64(bgr) + 0.5)
+				r.overlay.Pix[i+2] = uint8(inv*float64(pb[2]) + opacity*float64(bb) + 0.5)
+				r.overlay.Pix[i+3] = uint8(inv*float64(pb[3]) + opacity*float64(blended.A) + 0.5)
 			}
 		}
 	}
@@ -125,5 +155,13 @@ func (r *PatternPainter) Paint(ss []raster.Span, _ bool) {
 //   - mask: optional alpha mask (can be nil)
 //   - p: pattern implementing the patterns.Pattern interface
 func NewPatternPainter(overlay, base *image.RGBA, mask *image.Alpha, p patterns.Pattern) *PatternPainter {
-	return &PatternPainter{overlay, base, mask, p}
+	return &PatternPainter{overlay: overlay, base: base, mask: mask, pattern: p}
+}
+
+// SetAccurateBlending makes the painter composite with floating-point
+// Multiply and Screen (see patterns.Color.BlendOverAccurate). Returns the
+// receiver for chaining.
+func (r *PatternPainter) SetAccurateBlending(on bool) *PatternPainter {
+	r.accurate = on
+	return r
 }
@@ -0,0 +1,142 @@
+package render
+
+import (
+	"image"
+	"math/bits"
+	"sync"
+
+	"github.com/golang/freetype/raster"
+)
+
+// Pools for the short-lived buffers of drawing: rasterizers, paths and
+// scratch images. Shapes draw through a fresh Line and a canvas-sized
+// overlay each time, so reusing them keeps GC pressure flat when hundreds of
+// shapes are rendered. Everything handed out is reset; callers must not keep
+// a buffer after putting it back.
+
+// pooledRasterizer is a pooled rasterizer with the size it was set to.
+type pooledRasterizer struct {
+	r             *raster.Rasterizer
+	width, height int
+}
+
+// rasterizers holds *pooledRasterizer by power-of-two size class, like
+// pixels: class [i][j] holds rasterizers for widths up to 1<<i and heights
+// up to 1<<j. One reused at its own size keeps its grown cell buffer; other
+// sizes of the class are reset with SetBounds, which drops it.
+var rasterizers [32][32]sync.Pool
+
+// sizeClass returns the power-of-two class of a dimension n.
+func sizeClass(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	return bits.Len(uint(n - 1))
+}
+
+// GetRasterizer returns a cleared rasterizer for a width×height target.
+func GetRasterizer(width, height int) *raster.Rasterizer {
+	i, j := sizeClass(width), sizeClass(height)
+	if i < len(rasterizers) && j < len(rasterizers[i]) {
+		if p, ok := rasterizers[i][j].Get().(*pooledRasterizer); ok {
+			if p.width == width && p.height == height {
+				p.r.Clear()
+			} else {
+				p.r.SetBounds(width, height)
+			}
+			return p.r
+		}
+	}
+	return raster.NewRasterizer(width, height)
+}
+
+// PutRasterizer returns a rasterizer obtained for a width×height target.
+func PutRasterizer(r *raster.Rasterizer, width, height int) {
+	if r == nil {
+		return
+	}
+	i, j := sizeClass(width), sizeClass(height)
+	if i >= len(rasterizers) || j >= len(rasterizers[i]) {
+		return
+	}
+	rasterizers[i][j].Put(&pooledRasterizer{r: r, width: width, height: height})
+}
+
+// paths holds *raster.Path buffers.
+var paths sync.Pool
+
+// GetPath returns an empty path with spare capacity.
+func GetPath() raster.Path {
+	if p, ok := paths.Get().(*raster.Path); ok {
+		return (*p)[:0]
+	}
+	return nil
+}
+
+// PutPath returns a path buffer for reuse.
+func PutPath(p raster.Path) {
+	if cap(p) == 0 {
+		return
+	}
+	p = p[:0]
+	paths.Put(&p)
+}
+
+// pixels holds *[]uint8 buffers by size class: class k holds slices of
+// capacity 1<<k.
+var pixels [48]sync.Pool
+
+// getPixels returns a zeroed slice of length n.
+func getPixels(n int) []uint8 {
+	if n <= 0 {
+		return nil
+	}
+	k := bits.Len(uint(n - 1))
+	if k >= len(pixels) {
+		return make([]uint8, n)
+	}
+	if b, ok := pixels[k].Get().(*[]uint8); ok {
+		s := (*b)[:n]
+		clear(s)
+		return s
+	}
+	return make([]uint8, n, 1<<k)
+}
+
+// putPixels returns a slice obtained from getPixels.
+func putPixels(b []uint8) {
+	c := cap(b)
+	if c == 0 || c&(c-1) != 0 {
+		return // not from getPixels
+	}
+	k := bits.Len(uint(c - 1))
+	if k >= len(pixels) {
+		return
+	}
+	b = b[:c]
+	pixels[k].Put(&b)
+}
+
+// GetRGBA returns a transparent RGBA image with bounds r.
+func GetRGBA(r image.Rectangle) *image.RGBA {
+	return &image.RGBA{Pix: getPixels(4 * r.Dx() * r.Dy()), Stride: 4 * r.Dx(), Rect: r}
+}
+
+// PutRGBA returns an image obtained from GetRGBA.
+func PutRGBA(img *image.RGBA) {
+	if img != nil {
+		putPixels(img.Pix)
+	}
+}
+
+// GetAlpha returns a transparent alpha mask with bounds r.
+func GetAlpha(r image.Rectangle) *image.Alpha {
+	return &image.Alpha{Pix: getPixels(r.Dx() * r.Dy()), Stride: r.Dx(), Rect: r}
+}
+
+// PutAlpha returns a mask obtained from GetAlpha.
+func PutAlpha(img *image.Alpha) {
+	if img != nil {
+		putPixels(img.Pix)
+	}
+}
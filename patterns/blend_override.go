@@ -0,0 +1,47 @@
+package patterns
+
+import "image/color"
+
+// BlendOverride composites an inner pattern with a fixed blend mode instead
+// of its own or its colors', so one pattern can be shared by shapes that
+// composite differently. It can be used anywhere a Pattern is accepted.
+type BlendOverride struct {
+	inner Pattern
+	mode  BlendMode
+}
+
+// WithBlendMode returns p composited with mode. A nil p is returned as is.
+func WithBlendMode(p Pattern, mode BlendMode) Pattern {
+	if p == nil {
+		return p
+	}
+	if o, ok := p.(*BlendOverride); ok {
+		p = o.inner
+	}
+	return &BlendOverride{inner: p, mode: mode}
+}
+
+// ColorAt returns the inner color carrying the override mode.
+func (p *BlendOverride) ColorAt(x, y int) color.Color {
+	return toColor(p.inner.ColorAt(x, y)).SetBlendMode(p.mode)
+}
+
+// ColorsForSpan evaluates a row of the inner pattern with its span evaluator
+// where available.
+func (p *BlendOverride) ColorsForSpan(y, x0, x1 int, dst []Color) {
+	FillSpan(p.inner, y, x0, x1, dst)
+	for i := range dst[:x1-x0] {
+		dst[i].blendMode = p.mode
+	}
+}
+
+// BlendMode returns the override mode.
+func (p *BlendOverride) BlendMode() BlendMode { return p.mode }
+
+// Opacity forwards the inner pattern's opacity, or 1 when it does not define one.
+func (p *BlendOverride) Opacity() float64 {
+	if bp, ok := p.inner.(BlendedPattern); ok {
+		return bp.Opacity()
+	}
+	return 1
+}
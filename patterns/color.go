@@ -11,8 +11,9 @@ import (
 
 // Color Representation and Utilities
 
-// Color represents a simple 8-bit per channel RGBA color.
-// It includes an optional BlendMode field used for compositing.
+// Color represents a simple 8-bit per channel RGBA color with straight
+// (non-premultiplied) alpha; RGBA and ToColor premultiply it for image
+// buffers. It includes an optional BlendMode field used for compositing.
 type Color struct {
 	R, G, B, A uint8
 	blendMode  BlendMode
@@ -23,23 +24,20 @@ func (c Color) BlendMode() BlendMode {
 	return c.blendMode
 }
 
-// NewColorFromStd converts a standard color.Color into a Color type.
+// NewColorFromStd converts a standard color.Color into a Color type,
+// un-premultiplying its channels. The blend mode of a Color is reset.
 func NewColorFromStd(c color.Color) Color {
-	r, g, b, a := c.RGBA()
-	return Color{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: uint8(a >> 8)}
+	if v, ok := c.(Color); ok {
+		return Color{R: v.R, G: v.G, B: v.B, A: v.A}
+	}
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return Color{R: n.R, G: n.G, B: n.B, A: n.A}
 }
 
-// RGBA returns 16-bit per channel alpha-premultiplied color components.
+// RGBA returns 16-bit per channel alpha-premultiplied color components,
+// as color.NRGBA does: the R, G and B fields hold straight color.
 func (c Color) RGBA() (r, g, b, a uint32) {
-	r = uint32(c.R)
-	r |= r << 8
-	g = uint32(c.G)
-	g |= g << 8
-	b = uint32(c.B)
-	b |= b << 8
-	a = uint32(c.A)
-	a |= a << 8
-	return
+	return color.NRGBA{R: c.R, G: c.G, B: c.B, A: c.A}.RGBA()
 }
 
 // RGBA64 returns 64-bit (non-premultiplied) color channel values.
@@ -65,7 +63,7 @@ func (c Color) ToHex() string {
 	return fmt.Sprintf("#%02X%02X%02X%02X", c.R, c.G, c.B, c.A)
 }
 
-// ColorFromHex parses a hexadecimal color string (#RGB, #RRGGBB, or #RRGGBBAA)
+// ColorFromHex parses a hexadecimal color string (#RGB, #RGBA, #RRGGBB, or #RRGGBBAA)
 // and returns a corresponding Color value.
 func ColorFromHex(hex string) (Color, error) {
 	hex = strings.TrimPrefix(hex, "#")
@@ -78,6 +76,12 @@ func ColorFromHex(hex string) (Color, error) {
 			return Color{}, err
 		}
 		r, g, b = r*17, g*17, b*17
+	case 4:
+		_, err := fmt.Sscanf(hex, "%1x%1x%1x%1x", &r, &g, &b, &a)
+		if err != nil {
+			return Color{}, err
+		}
+		r, g, b, a = r*17, g*17, b*17, a*17
 	case 6:
 		_, err := fmt.Sscanf(hex, "%02x%02x%02x", &r, &g, &b)
 		if err != nil {
@@ -157,18 +161,20 @@ func ColorFromHSL(h, s, l float64, a uint8) Color {
 	}
 
 	return Color{
-		R: uint8((r + m) * 255),
-		G: uint8((g + m) * 255),
-		B: uint8((b + m) * 255),
+		R: uint8(math.Round((r + m) * 255)),
+		G: uint8(math.Round((g + m) * 255)),
+		B: uint8(math.Round((b + m) * 255)),
 		A: a,
 	}
 }
 
 // Conversion and Pattern Helpers
 
-// ToColor converts the custom Color type into a standard color.RGBA.
+// ToColor converts the custom Color type into a standard, premultiplied
+// color.RGBA.
 func (c Color) ToColor() color.RGBA {
-	return color.RGBA{R: c.R, G: c.G, B: c.B, A: c.A}
+	r, g, b := geom.PremultiplyRGB(c.R, c.G, c.B, c.A)
+	return color.RGBA{R: r, G: g, B: b, A: c.A}
 }
 
 // MakeSolidPattern creates a solid fill pattern from this color,
@@ -270,3 +276,96 @@ func (c Color) SetOpacity(opacity float64) Color {
 	c.A = uint8(math.Round(opacity * 255))
 	return c
 }
+
+// Luminance and Contrast
+
+// Luminance returns the WCAG relative luminance of the color in [0–1],
+// computed from linearized sRGB channels. Alpha is ignored.
+func (c Color) Luminance() float64 {
+	return 0.2126*geom.SrgbToLinear8(c.R) +
+		0.7152*geom.SrgbToLinear8(c.G) +
+		0.0722*geom.SrgbToLinear8(c.B)
+}
+
+// LuminanceContrast returns the WCAG contrast ratio between two relative
+// luminance values, in the range [1–21].
+func LuminanceContrast(l1, l2 float64) float64 {
+	if l1 < l2 {
+		l1, l2 = l2, l1
+	}
+	return (l1 + 0.05) / (l2 + 0.05)
+}
+
+// ContrastWith returns the WCAG contrast ratio between the color and other,
+// in the range [1–21]. Alpha is ignored.
+func (c Color) ContrastWith(other Color) float64 {
+	return LuminanceContrast(c.Luminance(), other.Luminance())
+}
+
+// BestTextColorOn returns the candidate with the highest contrast against
+// bg, the first one among equals. Without candidates it chooses between
+// white and black.
+func BestTextColorOn(bg Color, candidates ...Color) Color {
+	if len(candidates) == 0 {
+		candidates = []Color{{R: 255, G: 255, B: 255, A: 255}, {A: 255}}
+	}
+	best, ratio := candidates[0], candidates[0].ContrastWith(bg)
+	for _, c := range candidates[1:] {
+		if r := c.ContrastWith(bg); r > ratio {
+			best, ratio = c, r
+		}
+	}
+	return best
+}
+
+// Lighten raises the HSL lightness of the color by amount in [0–1], keeping
+// hue, saturation, alpha and blend mode.
+func (c Color) Lighten(amount float64) Color {
+	if amount == 0 {
+		return c
+	}
+	h, s, l := c.ToHSL()
+	out := ColorFromHSL(h, s, geom.ClampF64(l+amount, 0, 1), c.A)
+	out.blendMode = c.blendMode
+	return out
+}
+
+// Darken lowers the HSL lightness of the color by amount in [0–1]; see
+// Lighten.
+func (c Color) Darken(amount float64) Color { return c.Lighten(-amount) }
+
+// EnsureContrast returns the color mixed toward white or black just enough
+// to reach the contrast ratio against bg, taking the direction that needs
+// the smaller change. The color is returned unchanged when it already
+// contrasts enough, and white or black, whichever contrasts more, when the
+// ratio cannot be reached.
+func (c Color) EnsureContrast(bg Color, ratio float64) Color {
+	if c.ContrastWith(bg) >= ratio {
+		return c
+	}
+	white, black := Color{R: 255, G: 255, B: 255, A: c.A}, Color{A: c.A}
+	best, bestT := Color{}, 2.0
+	for _, to := range []Color{white, black} {
+		if to.ContrastWith(bg) < ratio {
+			continue
+		}
+		// Once the mix reaches the ratio it keeps it up to to itself, so
+		// bisect for the smallest amount that does.
+		lo, hi := 0.0, 1.0
+		for i := 0; i < 16; i++ {
+			if m := (lo + hi) / 2; c.Mix(to, m).ContrastWith(bg) >= ratio {
+				hi = m
+			} else {
+				lo = m
+			}
+		}
+		if hi < bestT {
+			best, bestT = c.Mix(to, hi), hi
+		}
+	}
+	if bestT > 1 {
+		best = BestTextColorOn(bg, white, black)
+	}
+	best.A, best.blendMode = c.A, c.blendMode
+	return best
+}
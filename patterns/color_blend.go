@@ -99,78 +99,95 @@ func (m BlendMode) String() string {
 //  3. Apply the CSS blend mode
 //  4. Combine using Porter–Duff SRC-OVER formula
 //  5. Convert back to sRGB for output
+//
+// The 8-bit conversions use lookup tables, and Multiply and Screen are
+// computed on the 8-bit channels rather than in floating point, which may
+// differ by one level; BlendOverAccurate avoids that.
 func (c Color) BlendOver(bg Color, opacity float64) Color {
+	return c.blendOver(bg, opacity, false)
+}
+
+// BlendOverAccurate is BlendOver with Multiply and Screen computed in
+// floating point. The results differ by at most one level per channel,
+// which matters only when comparing against other renderers bit for bit.
+func (c Color) BlendOverAccurate(bg Color, opacity float64) Color {
+	return c.blendOver(bg, opacity, true)
+}
+
+// blendOver implements BlendOver and BlendOverAccurate.
+func (c Color) blendOver(bg Color, opacity float64, accurate bool) Color {
 	mode := c.blendMode
 	if mode == BlendPassThrough {
 		mode = BlendNormal
 	}
 	opacity = geom.ClampF64(opacity, 0, 1)
 
-	// Convert from sRGB [0–255] → [0–1]
-	cbS := [3]float64{float64(bg.R) / 255, float64(bg.G) / 255, float64(bg.B) / 255}
-	csS := [3]float64{float64(c.R) / 255, float64(c.G) / 255, float64(c.B) / 255}
-
-	// Convert to linear space
-	cb := [3]float64{geom.SrgbToLinear(cbS[0]), geom.SrgbToLinear(cbS[1]), geom.SrgbToLinear(cbS[2])}
-	cs := [3]float64{geom.SrgbToLinear(csS[0]), geom.SrgbToLinear(csS[1]), geom.SrgbToLinear(csS[2])}
-
 	ab := float64(bg.A) / 255.0
 	as := float64(c.A) / 255.0 * opacity
 
+	// Linear channels come from tables; the blend formulas work on sRGB.
+	sb := [3]uint8{bg.R, bg.G, bg.B}
+	ss := [3]uint8{c.R, c.G, c.B}
+	var cb, cs [3]float64
+	for i := range cb {
+		cb[i] = geom.SrgbToLinear8(sb[i])
+		cs[i] = geom.SrgbToLinear8(ss[i])
+	}
+
 	// Apply blending mode in sRGB domain, result converted back to linear
 	var BcolL [3]float64
-	switch mode {
-	case BlendHue, BlendSaturation, BlendColor, BlendLuminosity:
-		BcolL = nonSeparableB(cb, cs, mode) // Non-separable modes use HSL operations
+	switch {
+	case mode == BlendNormal:
+		BcolL = cs
+	case mode >= BlendHue:
+		BcolL = nonSeparableB(sb, ss, mode) // Non-separable modes use HSL operations
+	case (mode == BlendMultiply || mode == BlendScreen) && !accurate:
+		for i := range BcolL {
+			BcolL[i] = geom.SrgbToLinear8(blendChannel8(sb[i], ss[i], mode))
+		}
 	default:
-		BcolL[0] = blendChannel(cb[0], cs[0], mode)
-		BcolL[1] = blendChannel(cb[1], cs[1], mode)
-		BcolL[2] = blendChannel(cb[2], cs[2], mode)
-	}
-	for i := 0; i < 3; i++ {
-		BcolL[i] = geom.ClampF64(BcolL[i], 0, 1)
+		for i := range BcolL {
+			BcolL[i] = geom.SrgbToLinear(geom.ClampF64(blendChannel(float64(sb[i])/255, float64(ss[i])/255, mode), 0, 1))
+		}
 	}
 
 	// Intermediate compositing:
 	// Cs' = (1 - Ab) * Cs + Ab * B(Cs, Cb)
-	csPrime := [3]float64{
-		(1-ab)*cs[0] + ab*BcolL[0],
-		(1-ab)*cs[1] + ab*BcolL[1],
-		(1-ab)*cs[2] + ab*BcolL[2],
-	}
-
 	// Porter–Duff "Source Over" (SRC_OVER):
 	// Co = As * Cs' + Ab * (1 - As) * Cb
 	// Ao = As + Ab * (1 - As)
-	co := [3]float64{
-		as*csPrime[0] + ab*(1-as)*cb[0],
-		as*csPrime[1] + ab*(1-as)*cb[1],
-		as*csPrime[2] + ab*(1-as)*cb[2],
-	}
 	ao := as + ab*(1-as)
 	if ao <= 0 {
 		return Color{}
 	}
-
-	// Un-premultiply, convert back to sRGB
-	Co := [3]float64{co[0] / ao, co[1] / ao, co[2] / ao}
-	outS := [3]float64{
-		geom.LinearToSrgb(Co[0]),
-		geom.LinearToSrgb(Co[1]),
-		geom.LinearToSrgb(Co[2]),
+	var out [3]uint8
+	for i := range out {
+		csPrime := (1-ab)*cs[i] + ab*BcolL[i]
+		co := as*csPrime + ab*(1-as)*cb[i]
+		// Un-premultiply, convert back to sRGB
+		out[i] = geom.LinearToSRGB8(co / ao)
 	}
 
 	return Color{
-		R: geom.U8(outS[0]),
-		G: geom.U8(outS[1]),
-		B: geom.U8(outS[2]),
+		R: out[0],
+		G: out[1],
+		B: out[2],
 		A: uint8(math.Round(ao * 255)),
 	}
 }
 
+// blendChannel8 applies Multiply or Screen to 8-bit sRGB channels.
+func blendChannel8(b, s uint8, mode BlendMode) uint8 {
+	if mode == BlendScreen {
+		return b + s - geom.Mul255(b, s)
+	}
+	return geom.Mul255(b, s)
+}
+
 // Separable blending modes
 
-// blendChannel applies a single-channel blend operation (Cb, Cs ∈ [0–1]).
+// blendChannel applies a single-channel blend operation to sRGB channels
+// (Cb, Cs ∈ [0–1]) and returns the blended sRGB value.
 //
 // Each formula matches the CSS Compositing and Blending specification.
 // See: https://www.w3.org/TR/compositing-1/
@@ -182,104 +199,75 @@ func blendChannel(Cb, Cs float64, mode BlendMode) float64 {
 
 	case BlendMultiply:
 		// Multiply: B(Cb, Cs) = Cb * Cs
-		sb := geom.LinearToSrgb(Cb)
-		ss := geom.LinearToSrgb(Cs)
-		return geom.SrgbToLinear(sb * ss)
+		return Cb * Cs
+
+	case BlendScreen:
+		// Screen: B(Cb, Cs) = Cb + Cs - Cb * Cs
+		return Cb + Cs - Cb*Cs
 
 	case BlendOverlay:
 		// Overlay: B(Cb, Cs) = 2*Cb*Cs if Cb < 0.5 else 1 - 2*(1-Cb)*(1-Cs)
-		sb := geom.LinearToSrgb(Cb)
-		ss := geom.LinearToSrgb(Cs)
-		var resS float64
-		if sb < 0.5 {
-			resS = 2 * sb * ss
-		} else {
-			resS = 1 - 2*(1-sb)*(1-ss)
+		if Cb < 0.5 {
+			return 2 * Cb * Cs
 		}
-		return geom.SrgbToLinear(resS)
+		return 1 - 2*(1-Cb)*(1-Cs)
 
 	case BlendDarken:
 		// Darken: B(Cb, Cs) = min(Cb, Cs)
-		sb := geom.LinearToSrgb(Cb)
-		ss := geom.LinearToSrgb(Cs)
-		return geom.SrgbToLinear(math.Min(sb, ss))
+		return math.Min(Cb, Cs)
 
 	case BlendLighten:
 		// Lighten: B(Cb, Cs) = max(Cb, Cs)
-		sb := geom.LinearToSrgb(Cb)
-		ss := geom.LinearToSrgb(Cs)
-		return geom.SrgbToLinear(math.Max(sb, ss))
+		return math.Max(Cb, Cs)
 
 	case BlendColorDodge:
 		// Color Dodge: B(Cb, Cs) = 1 if Cs >= 1 else min(1, Cb / (1 - Cs))
-		sb := geom.LinearToSrgb(Cb)
-		ss := geom.LinearToSrgb(Cs)
-		if ss >= 1 {
+		if Cs >= 1 {
 			return 1
 		}
-		if sb <= 0 {
+		if Cb <= 0 {
 			return 0
 		}
-		return geom.SrgbToLinear(geom.ClampF64(sb/(1-ss), 0, 1))
+		return geom.ClampF64(Cb/(1-Cs), 0, 1)
 
 	case BlendHardLight:
 		// Hard Light: B(Cb, Cs) = 2*Cb*Cs if Cs < 0.5 else 1 - 2*(1-Cb)*(1-Cs)
-		sb := geom.LinearToSrgb(Cb)
-		ss := geom.LinearToSrgb(Cs)
-		var resS float64
-		if ss <= 0.5 {
-			resS = 2 * sb * ss
-		} else {
-			resS = 1 - 2*(1-sb)*(1-ss)
+		if Cs <= 0.5 {
+			return 2 * Cb * Cs
 		}
-		return geom.SrgbToLinear(resS)
+		return 1 - 2*(1-Cb)*(1-Cs)
 
 	case BlendSoftLight:
 		// Soft Light (W3C formula):
 		// if Cb ≤ 0.25: g = ((16*Cb - 12)*Cb + 4)*Cb
 		// else: g = sqrt(Cb)
 		// then B(Cb, Cs) = Cb + (2*Cs - 1)*(g - Cb)
-		sb := geom.ClampF64(geom.LinearToSrgb(Cb), 0, 1)
-		ss := geom.ClampF64(geom.LinearToSrgb(Cs), 0, 1)
-
 		var g float64
-		if sb <= 0.25 {
-			g = ((16*sb-12)*sb + 4) * sb
+		if Cb <= 0.25 {
+			g = ((16*Cb-12)*Cb + 4) * Cb
 		} else {
-			g = math.Sqrt(sb)
+			g = math.Sqrt(Cb)
 		}
-
-		var resS float64
-		if ss <= 0.5 {
-			resS = sb - (1-2*ss)*sb*(1-sb)
-		} else {
-			resS = sb + (2*ss-1)*(g-sb)
+		if Cs <= 0.5 {
+			return Cb - (1-2*Cs)*Cb*(1-Cb)
 		}
-		return geom.SrgbToLinear(geom.ClampF64(resS, 0, 1))
+		return geom.ClampF64(Cb+(2*Cs-1)*(g-Cb), 0, 1)
 
 	case BlendDifference:
 		// Difference: B(Cb, Cs) = |Cb - Cs|
-		sb := geom.LinearToSrgb(Cb)
-		ss := geom.LinearToSrgb(Cs)
-		return geom.SrgbToLinear(math.Abs(sb - ss))
+		return math.Abs(Cb - Cs)
 
 	case BlendExclusion:
 		// Exclusion: B(Cb, Cs) = Cb + Cs - 2*Cb*Cs
-		sb := geom.LinearToSrgb(Cb)
-		ss := geom.LinearToSrgb(Cs)
-		return geom.SrgbToLinear(sb + ss - 2*sb*ss)
+		return Cb + Cs - 2*Cb*Cs
 
 	case BlendPlusLighter:
 		// Linear Dodge (Plus Lighter): B(Cb, Cs) = min(1, Cb + Cs)
-		sb := geom.LinearToSrgb(Cb)
-		ss := geom.LinearToSrgb(Cs)
-		return geom.SrgbToLinear(geom.ClampF64(sb+ss, 0, 1))
+		return geom.ClampF64(Cb+Cs, 0, 1)
 
 	case BlendPlusDarker:
 		// Linear Burn (Plus Darker): B(Cb, Cs) = max(0, Cb + Cs - 1)
-		sb := geom.LinearToSrgb(Cb)
-		ss := geom.LinearToSrgb(Cs)
-		return geom.SrgbToLinear(geom.ClampF64(sb+ss-1, 0, 1))
+		return geom.ClampF64(Cb+Cs-1, 0, 1)
 
 	default:
 		return Cs
@@ -290,10 +278,11 @@ func blendChannel(Cb, Cs float64, mode BlendMode) float64 {
 
 // nonSeparableB implements CSS non-separable blend modes:
 // Hue, Saturation, Color, and Luminosity.
-// These operate in HSL space rather than per-channel RGB.
-func nonSeparableB(Cb, Cs [3]float64, mode BlendMode) [3]float64 {
-	CbS := [3]float64{geom.LinearToSrgb(Cb[0]), geom.LinearToSrgb(Cb[1]), geom.LinearToSrgb(Cb[2])}
-	CsS := [3]float64{geom.LinearToSrgb(Cs[0]), geom.LinearToSrgb(Cs[1]), geom.LinearToSrgb(Cs[2])}
+// These operate in HSL space rather than per-channel RGB, on 8-bit sRGB
+// channels, and return linear channels.
+func nonSeparableB(Cb, Cs [3]uint8, mode BlendMode) [3]float64 {
+	CbS := [3]float64{float64(Cb[0]) / 255, float64(Cb[1]) / 255, float64(Cb[2]) / 255}
+	CsS := [3]float64{float64(Cs[0]) / 255, float64(Cs[1]) / 255, float64(Cs[2]) / 255}
 
 	var outS [3]float64
 	switch mode {
@@ -313,5 +302,9 @@ func nonSeparableB(Cb, Cs [3]float64, mode BlendMode) [3]float64 {
 		outS = CsS
 	}
 
-	return [3]float64{geom.SrgbToLinear(outS[0]), geom.SrgbToLinear(outS[1]), geom.SrgbToLinear(outS[2])}
+	var out [3]float64
+	for i := range out {
+		out[i] = geom.SrgbToLinear(geom.ClampF64(outS[i], 0, 1))
+	}
+	return out
 }
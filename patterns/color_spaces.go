@@ -0,0 +1,180 @@
+package patterns
+
+import (
+	"math"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+)
+
+// HSV Conversion
+
+// ToHSV converts the color to HSV representation.
+// Returns hue in [0–360], saturation in [0–1], and value in [0–1].
+func (c Color) ToHSV() (h, s, v float64) {
+	r := float64(c.R) / 255
+	g := float64(c.G) / 255
+	b := float64(c.B) / 255
+
+	v = math.Max(r, math.Max(g, b))
+	d := v - math.Min(r, math.Min(g, b))
+	if v > 0 {
+		s = d / v
+	}
+	return rgbHue(r, g, b, v, d), s, v
+}
+
+// ColorFromHSV creates a Color from HSV and alpha values.
+func ColorFromHSV(h, s, v float64, a uint8) Color {
+	s, v = geom.ClampF64(s, 0, 1), geom.ClampF64(v, 0, 1)
+	// HSV maps onto HSL with the same hue.
+	l := v * (1 - s/2)
+	var sl float64
+	if l > 0 && l < 1 {
+		sl = (v - l) / math.Min(l, 1-l)
+	}
+	return ColorFromHSL(normHue(h), sl, l, a)
+}
+
+// HWB Conversion
+
+// ToHWB converts the color to HWB (hue, whiteness, blackness) representation.
+// Returns hue in [0–360], whiteness in [0–1], and blackness in [0–1].
+func (c Color) ToHWB() (h, w, b float64) {
+	h, s, v := c.ToHSV()
+	return h, (1 - s) * v, 1 - v
+}
+
+// ColorFromHWB creates a Color from HWB and alpha values. When whiteness and
+// blackness add up to more than 1 they are scaled down to sum to 1, which
+// gives a gray, as in CSS.
+func ColorFromHWB(h, w, b float64, a uint8) Color {
+	w, b = geom.ClampF64(w, 0, 1), geom.ClampF64(b, 0, 1)
+	if sum := w + b; sum >= 1 {
+		g := uint8(math.Round(w / sum * 255))
+		return Color{R: g, G: g, B: g, A: a}
+	}
+	v := 1 - b
+	return ColorFromHSV(h, 1-w/v, v, a)
+}
+
+// OKLab and OKLCH Conversion
+
+// ToOKLab converts the color to OKLab, a perceptual color space in which
+// equal distances look about equally different. Returns lightness L in
+// [0–1] and the green–red and blue–yellow axes a and b, roughly in
+// [-0.4, 0.4].
+func (c Color) ToOKLab() (l, a, b float64) {
+	r := geom.SrgbToLinear8(c.R)
+	g := geom.SrgbToLinear8(c.G)
+	bl := geom.SrgbToLinear8(c.B)
+
+	lm := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*bl)
+	mm := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*bl)
+	sm := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*bl)
+
+	l = 0.2104542553*lm + 0.7936177850*mm - 0.0040720468*sm
+	a = 1.9779984951*lm - 2.4285922050*mm + 0.4505937099*sm
+	b = 0.0259040371*lm + 0.7827717662*mm - 0.8086757660*sm
+	return
+}
+
+// ColorFromOKLab creates a Color from OKLab and alpha values. Colors outside
+// the sRGB gamut are clipped per channel.
+func ColorFromOKLab(l, a, b float64, alpha uint8) Color {
+	r, g, bl := oklabToLinear(l, a, b)
+	return Color{
+		R: geom.LinearToSRGB8(r),
+		G: geom.LinearToSRGB8(g),
+		B: geom.LinearToSRGB8(bl),
+		A: alpha,
+	}
+}
+
+// ToOKLCH converts the color to OKLCH, the polar form of OKLab. Returns
+// lightness in [0–1], chroma from 0 (gray) to about 0.37, and hue in
+// [0–360]. Unlike HSL, changing the hue keeps the perceived lightness.
+func (c Color) ToOKLCH() (l, ch, h float64) {
+	l, a, b := c.ToOKLab()
+	ch = math.Hypot(a, b)
+	if ch < 1e-6 {
+		return l, 0, 0
+	}
+	return l, ch, normHue(math.Atan2(b, a) * 180 / math.Pi)
+}
+
+// ColorFromOKLCH creates a Color from OKLCH and alpha values. Colors outside
+// the sRGB gamut keep lightness and hue and lose chroma until they fit,
+// rather than being clipped per channel, which would shift the hue.
+func ColorFromOKLCH(l, ch, h float64, a uint8) Color {
+	l, ch = geom.ClampF64(l, 0, 1), math.Max(ch, 0)
+	rad := h * math.Pi / 180
+	cos, sin := math.Cos(rad), math.Sin(rad)
+	if !inGamut(oklabToLinear(l, ch*cos, ch*sin)) {
+		lo, hi := 0.0, ch
+		for i := 0; i < 24; i++ {
+			mid := (lo + hi) / 2
+			if inGamut(oklabToLinear(l, mid*cos, mid*sin)) {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		ch = lo
+	}
+	return ColorFromOKLab(l, ch*cos, ch*sin, a)
+}
+
+// RotateHue turns the OKLCH hue of the color by deg degrees, keeping its
+// perceived lightness, chroma where the gamut allows, alpha and blend mode.
+func (c Color) RotateHue(deg float64) Color {
+	l, ch, h := c.ToOKLCH()
+	if ch == 0 {
+		return c
+	}
+	return ColorFromOKLCH(l, ch, h+deg, c.A).SetBlendMode(c.blendMode)
+}
+
+// oklabToLinear converts OKLab to linear sRGB channels, unclamped.
+func oklabToLinear(l, a, b float64) (float64, float64, float64) {
+	lm := l + 0.3963377774*a + 0.2158037573*b
+	mm := l - 0.1055613458*a - 0.0638541728*b
+	sm := l - 0.0894841775*a - 1.2914855480*b
+	lm, mm, sm = lm*lm*lm, mm*mm*mm, sm*sm*sm
+
+	return 4.0767416621*lm - 3.3077115913*mm + 0.2309699292*sm,
+		-1.2684380046*lm + 2.6097574011*mm - 0.3413193965*sm,
+		-0.0041960863*lm - 0.7034186147*mm + 1.7076147010*sm
+}
+
+// inGamut reports whether linear sRGB channels lie within [0, 1], allowing
+// for rounding.
+func inGamut(r, g, b float64) bool {
+	const eps = 1e-4
+	return min(r, g, b) >= -eps && max(r, g, b) <= 1+eps
+}
+
+// rgbHue returns the hue in [0–360) of r, g, b with maximum v and range d.
+func rgbHue(r, g, b, v, d float64) float64 {
+	if d == 0 {
+		return 0
+	}
+	var h float64
+	switch v {
+	case r:
+		h = math.Mod((g-b)/d+6, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return h * 60
+}
+
+// normHue wraps a hue in degrees to [0, 360).
+func normHue(h float64) float64 {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
@@ -0,0 +1,316 @@
+package patterns
+
+import (
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+)
+
+// ConicGradient represents a conic (angular) gradient.
+// The gradient is centered at (cx, cy), and colors transition
+// around the center based on the angle of each pixel relative to it.
+type ConicGradient struct {
+	cx, cy   float64    // Center coordinates
+	rotation float64    // Rotation offset in turns (0–1)
+	gap      float64    // Empty sweep after the last stop, in turns (0–1)
+	ccw      bool       // Stops advance anticlockwise from the rotation
+	closed   bool       // The sweep ends on the color of the first stop
+	seam     float64    // Sweep blended across the seam, in turns (0–1)
+	stops    geom.Stops // Sorted list of color stops
+	grain    grain      // Optional per-pixel jitter of t
+
+	mode    BlendMode // Blending mode applied during rendering
+	opacity float64   // Opacity factor in [0, 1]
+}
+
+// BlendMode returns the blend mode of the gradient.
+func (g *ConicGradient) BlendMode() BlendMode { return g.mode }
+
+// Opacity returns the opacity factor of the gradient (0–1).
+func (g *ConicGradient) Opacity() float64 { return g.opacity }
+
+// Constructors
+
+// NewConicGradient creates a new conic gradient centered at (cx, cy),
+// rotating `deg` degrees clockwise.
+// Rotation is normalized to [0, 1) turns, where 1.0 = 360°.
+func NewConicGradient(cx, cy, deg float64) *ConicGradient {
+	return &ConicGradient{
+		cx:       cx,
+		cy:       cy,
+		rotation: geom.NormalizeAngle(deg) / 360,
+		mode:     BlendPassThrough,
+		opacity:  1,
+	}
+}
+
+// NewConicGradientWithBlend creates a conic gradient with a specified
+// blending mode and opacity.
+func NewConicGradientWithBlend(cx, cy, deg float64, mode BlendMode, opacity float64) *ConicGradient {
+	return &ConicGradient{
+		cx:       cx,
+		cy:       cy,
+		rotation: geom.NormalizeAngle(deg) / 360,
+		mode:     mode,
+		opacity:  geom.ClampF64(opacity, 0, 1),
+	}
+}
+
+// WithBlendMode sets the blending mode and returns the gradient.
+func (g *ConicGradient) WithBlendMode(m BlendMode) *ConicGradient {
+	g.mode = m
+	return g
+}
+
+// WithOpacity sets the opacity (0–1) and returns the gradient.
+func (g *ConicGradient) WithOpacity(a float64) *ConicGradient {
+	g.opacity = geom.ClampF64(a, 0, 1)
+	return g
+}
+
+// WithAlphaMask returns the gradient masked by the luminance of mask (see
+// MaskedPattern). The gradient itself is not modified.
+func (g *ConicGradient) WithAlphaMask(mask Pattern) Pattern { return WithAlphaMask(g, mask) }
+
+// WithGrain adds per-pixel noise to the gradient: the angular offset is
+// jittered by up to amount turns (in [0, 1]) using a deterministic pattern
+// derived from seed. An amount of 0 disables grain.
+func (g *ConicGradient) WithGrain(amount float64, seed uint64) *ConicGradient {
+	g.grain = newGrain(amount, seed)
+	return g
+}
+
+// WithGap leaves the last deg degrees of the sweep empty (transparent), so
+// the stops span 360-deg degrees starting at the rotation. Both ends of the
+// arc are anti-aliased, which suits circular progress indicators. Zero (the
+// default) closes the circle.
+func (g *ConicGradient) WithGap(deg float64) *ConicGradient {
+	g.gap = geom.ClampF64(deg, 0, 360) / 360
+	return g
+}
+
+// Gap returns the empty sweep set by WithGap, in degrees.
+func (g *ConicGradient) Gap() float64 { return g.gap * 360 }
+
+// WithClosedSeam makes the sweep end on the color of the first stop, as if
+// it were repeated at offset 1, so a full circle has no seam where the angle
+// wraps. The colors after the last stop fade into it; a last stop at offset
+// 1 keeps a hard edge there.
+func (g *ConicGradient) WithClosedSeam(on bool) *ConicGradient {
+	g.closed = on
+	return g
+}
+
+// WithSeamBlend cross-fades the colors on both sides of the seam over deg
+// degrees centered on it, which softens the seam without changing the stops.
+// It is ignored with a gap. Zero (the default) keeps the seam sharp.
+func (g *ConicGradient) WithSeamBlend(deg float64) *ConicGradient {
+	g.seam = geom.ClampF64(deg, 0, 360) / 360
+	return g
+}
+
+// Color Stops
+
+// AddColorStop adds a color stop to the gradient at the given offset [0–1].
+// Stops are automatically sorted by position. Stops sharing a position keep
+// the order they were added in and form a hard edge between their colors.
+func (g *ConicGradient) AddColorStop(offset float64, c Color) GradientPattern {
+	offset = geom.ClampF64(offset, 0, 1)
+	g.stops = append(g.stops, geom.NewStop(offset, c))
+	sort.Stable(g.stops)
+	return g
+}
+
+// AddColorStopWithHint adds a color stop at offset [0–1] whose transition
+// from the previous stop reaches the color halfway between them at position
+// hint [0–1] rather than in the middle, like a CSS color hint
+// (linear-gradient(red, 30%, blue)). A hint outside the interval between
+// the two stops makes a hard edge at its end.
+func (g *ConicGradient) AddColorStopWithHint(offset float64, c Color, hint float64) GradientPattern {
+	offset = geom.ClampF64(offset, 0, 1)
+	g.stops = append(g.stops, geom.NewStopWithHint(offset, c, geom.ClampF64(hint, 0, 1)))
+	sort.Stable(g.stops)
+	return g
+}
+
+// AddColorStopDeg adds a color stop angle degrees into the sweep, measured
+// from the rotation in the sweep direction; 360 is a full turn. With a gap,
+// the stops span the arc as with AddColorStop, so angle/360 is the fraction
+// of the arc.
+//
+// Two stops at the same angle make a hard edge, anti-aliased over one pixel,
+// which is how pie-chart slices are authored:
+//
+//	g.AddColorStopDeg(0, red).AddColorStopDeg(120, red).
+//		AddColorStopDeg(120, blue).AddColorStopDeg(360, blue)
+func (g *ConicGradient) AddColorStopDeg(angle float64, c Color) *ConicGradient {
+	g.AddColorStop(angle/360, c)
+	return g
+}
+
+// WithAnticlockwise makes the stops advance anticlockwise from the rotation
+// instead of clockwise. The gap, if any, stays at the end of the sweep.
+func (g *ConicGradient) WithAnticlockwise(ccw bool) *ConicGradient {
+	g.ccw = ccw
+	return g
+}
+
+// Anticlockwise reports whether the stops advance anticlockwise.
+func (g *ConicGradient) Anticlockwise() bool { return g.ccw }
+
+// StopsCount returns the total number of color stops.
+func (g *ConicGradient) StopsCount() int { return len(g.stops) }
+
+// Sampling
+
+// ColorAt computes the interpolated color for the given pixel (x, y).
+// The function calculates the angular position of the point relative to
+// the center, maps it to a normalized offset [0–1), and interpolates between
+// the nearest color stops.
+//
+// Pixels within half a pixel of the seam, where the last stop meets the
+// first, or of a hard stop blend the two colors by coverage; with a gap, the
+// arc fades out over the same distance at both ends.
+func (g *ConicGradient) ColorAt(x, y int) color.Color {
+	if len(g.stops) == 0 {
+		return color.Transparent
+	}
+	fx, fy := float64(x), float64(y)
+	t := g.angleToOffset(g.angleAt(fx, fy))
+	turn := 2 * math.Pi * math.Hypot(fx-g.cx, fy-g.cy) // pixels per turn here
+
+	if g.gap == 0 {
+		if h := g.seam / 2; h > 0 && (t < h || t > 1-h) {
+			s := t + h
+			if s >= 1 {
+				s--
+			}
+			return geom.LerpColor(g.colorFor(1-h, x, y), g.colorFor(h, x, y), s/g.seam)
+		}
+		c := g.edgeColor(t, turn, x, y)
+		// Distances in pixels to the seam, ahead of and behind it.
+		if d := t * turn; d < 0.5 {
+			return geom.LerpColor(g.colorFor(1, x, y), c, 0.5+d)
+		}
+		if d := (1 - t) * turn; d < 0.5 {
+			return geom.LerpColor(g.colorFor(0, x, y), c, 0.5+d)
+		}
+		return c
+	}
+
+	span := 1 - g.gap
+	if t >= span {
+		// Inside the gap: only the pixels touching an arc end get coverage.
+		if d := (1 - t) * turn; d < 0.5 {
+			return fade(g.colorFor(0, x, y), 0.5-d)
+		}
+		if d := (t - span) * turn; d < 0.5 {
+			return fade(g.colorFor(1, x, y), 0.5-d)
+		}
+		return color.Transparent
+	}
+	c := g.edgeColor(t/span, turn*span, x, y)
+	cov := math.Min(math.Min(0.5+t*turn, 0.5+(span-t)*turn), 1)
+	if cov < 1 {
+		return fade(c, cov)
+	}
+	return c
+}
+
+// ColorsForSpan evaluates a row of the gradient. Without grain, smooth
+// stretches are sampled sparsely and interpolated, while the seam, gap ends
+// and hard stops are evaluated per pixel (see sampleSpan).
+func (g *ConicGradient) ColorsForSpan(y, x0, x1 int, dst []Color) {
+	n := x1 - x0
+	if n <= 0 {
+		return
+	}
+	if g.grain.amount != 0 {
+		for i := 0; i < n; i++ {
+			dst[i] = toColor(g.ColorAt(x0+i, y))
+		}
+		return
+	}
+	sampleSpan(dst, n, func(i int) Color { return toColor(g.ColorAt(x0+i, y)) })
+}
+
+// colorFor returns the stop color at offset t, jittered by the grain.
+func (g *ConicGradient) colorFor(t float64, x, y int) color.Color {
+	if g.grain.amount != 0 {
+		t = g.grain.apply(t, x, y)
+		if g.gap == 0 {
+			t -= math.Floor(t)
+		} else {
+			t = geom.ClampF64(t, 0, 1)
+		}
+	}
+	if last := g.stops[len(g.stops)-1]; g.closed && t > last.Position() {
+		return geom.LerpColor(last.Color(), g.stops[0].Color(), geom.Norm(t, last.Position(), 1))
+	}
+	return geom.GetColor(t, g.stops)
+}
+
+// edgeColor returns the color at stop offset t like colorFor, blending the
+// colors on both sides of a hard stop within half a pixel of t. perUnit is
+// the number of pixels one unit of offset spans at the sampled point.
+func (g *ConicGradient) edgeColor(t, perUnit float64, x, y int) color.Color {
+	if g.grain.amount == 0 {
+		for i := 1; i < len(g.stops); i++ {
+			p := g.stops[i].Position()
+			if p != g.stops[i-1].Position() {
+				continue
+			}
+			if d := (t - p) * perUnit; math.Abs(d) < 0.5 {
+				return geom.LerpColor(g.stops[i-1].Color(), g.stops[i].Color(), 0.5+d)
+			}
+		}
+	}
+	return g.colorFor(t, x, y)
+}
+
+// fade scales the alpha of c by cov.
+func fade(c color.Color, cov float64) color.Color {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	n.A = uint8(float64(n.A)*geom.ClampF64(cov, 0, 1) + 0.5)
+	return n
+}
+
+// Geometry
+
+// Center returns the coordinates of the gradient’s center.
+func (g *ConicGradient) Center() (float64, float64) { return g.cx, g.cy }
+
+// Rotation returns the gradient rotation in degrees (0–360).
+func (g *ConicGradient) Rotation() float64 { return g.rotation * 360 }
+
+// SetCenter updates the gradient’s center coordinates.
+func (g *ConicGradient) SetCenter(cx, cy float64) { g.cx, g.cy = cx, cy }
+
+// SetRotation sets the rotation angle in degrees and normalizes it to [0, 360).
+func (g *ConicGradient) SetRotation(deg float64) {
+	g.rotation = geom.NormalizeAngle(deg) / 360
+}
+
+// Internal math
+
+// angleAt computes the polar angle (in radians) of a point relative to the gradient’s center.
+// Range: [-π, π].
+func (g *ConicGradient) angleAt(x, y float64) float64 {
+	return math.Atan2(y-g.cy, x-g.cx)
+}
+
+// angleToOffset converts an angle (radians) to a normalized offset [0, 1)
+// within the gradient’s angular range, applying rotation offset and the
+// sweep direction.
+func (g *ConicGradient) angleToOffset(a float64) float64 {
+	t := geom.Norm(a, -math.Pi, math.Pi) - g.rotation
+	if t < 0 {
+		t += 1
+	}
+	if g.ccw && t > 0 {
+		t = 1 - t
+	}
+	return t
+}
@@ -0,0 +1,83 @@
+package patterns
+
+import (
+	"image/color"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+)
+
+// FuncPattern is a procedural pattern whose colors come from a callback, for
+// fills such as checkerboards, plasma or distance fields that no built-in
+// pattern covers. It supports blend modes and opacity like the built-in
+// patterns.
+//
+// The callback is called once per covered pixel, possibly from several
+// goroutines when layers render concurrently, so it must be safe for
+// concurrent use and should be cheap.
+type FuncPattern struct {
+	fn      func(x, y int) Color
+	mode    BlendMode
+	opacity float64
+}
+
+// NewFuncPattern creates a pattern returning fn(x, y) for pixel (x, y), with
+// the default blend mode and full opacity. A nil fn is fully transparent.
+//
+// Example:
+//
+//	checker := colors.NewFuncPattern(func(x, y int) glimo.Color {
+//		if (x/8+y/8)%2 == 0 {
+//			return colors.White
+//		}
+//		return colors.Silver
+//	})
+func NewFuncPattern(fn func(x, y int) Color) *FuncPattern {
+	return &FuncPattern{fn: fn, mode: BlendPassThrough, opacity: 1}
+}
+
+// NewFuncPatternWithBlend creates a procedural pattern with a specific blend
+// mode and opacity. The opacity is clamped to [0, 1].
+func NewFuncPatternWithBlend(fn func(x, y int) Color, mode BlendMode, opacity float64) *FuncPattern {
+	return &FuncPattern{fn: fn, mode: mode, opacity: geom.ClampF64(opacity, 0, 1)}
+}
+
+// ColorAt returns fn(x, y).
+func (p *FuncPattern) ColorAt(x, y int) color.Color {
+	if p.fn == nil {
+		return Color{}
+	}
+	return p.fn(x, y)
+}
+
+// ColorsForSpan calls fn for each pixel of the run.
+func (p *FuncPattern) ColorsForSpan(y, x0, x1 int, dst []Color) {
+	for x := x0; x < x1; x++ {
+		if p.fn == nil {
+			dst[x-x0] = Color{}
+			continue
+		}
+		dst[x-x0] = p.fn(x, y)
+	}
+}
+
+// BlendMode returns the blend mode of the pattern.
+func (p *FuncPattern) BlendMode() BlendMode { return p.mode }
+
+// Opacity returns the opacity factor of the pattern (0–1).
+func (p *FuncPattern) Opacity() float64 { return p.opacity }
+
+// WithBlendMode sets the blending mode and returns the pattern.
+func (p *FuncPattern) WithBlendMode(m BlendMode) *FuncPattern {
+	p.mode = m
+	return p
+}
+
+// WithOpacity sets the opacity (0–1) and returns the pattern.
+func (p *FuncPattern) WithOpacity(a float64) *FuncPattern {
+	p.opacity = geom.ClampF64(a, 0, 1)
+	return p
+}
+
+// WithAlphaMask returns the pattern masked by the luminance of mask (see
+// MaskedPattern). The pattern itself is not modified.
+func (p *FuncPattern) WithAlphaMask(mask Pattern) Pattern { return WithAlphaMask(p, mask) }
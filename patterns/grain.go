@@ -0,0 +1,41 @@
+package patterns
+
+import "github.com/Krispeckt/glimo/internal/core/geom"
+
+// grain jitters a gradient's interpolation factor per pixel, producing the
+// "grainy gradient" look. The jitter is a deterministic hash of the pixel
+// position and seed, so the same gradient always renders the same grain and
+// ColorAt and ColorsForSpan agree.
+type grain struct {
+	amount float64 // maximum shift of t, in [0, 1]
+	seed   uint64
+}
+
+// newGrain returns a grain with amount clamped to [0, 1].
+func newGrain(amount float64, seed uint64) grain {
+	return grain{amount: geom.ClampF64(amount, 0, 1), seed: seed}
+}
+
+// apply returns t shifted by the grain at pixel (x, y). The shift has a
+// triangular distribution in (-amount, amount), which reads as finer grain
+// than uniform noise of the same strength.
+func (g grain) apply(t float64, x, y int) float64 {
+	if g.amount == 0 {
+		return t
+	}
+	h := hash2(x, y, g.seed)
+	u1 := float64(h>>40) / (1 << 24)
+	u2 := float64(h&(1<<24-1)) / (1 << 24)
+	return t + g.amount*(u1+u2-1)
+}
+
+// hash2 mixes a pixel position and seed into 64 well-distributed bits
+// (SplitMix64 finalizer).
+func hash2(x, y int, seed uint64) uint64 {
+	z := uint64(uint32(x)) | uint64(uint32(y))<<32
+	z ^= seed * 0xd1b54a32d192ed03
+	z += 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
@@ -0,0 +1,63 @@
+package patterns
+
+import "image/color"
+
+// MaskedPattern modulates the alpha of an inner pattern by the luminance of
+// a mask pattern, like an SVG luminance mask: white keeps the inner color,
+// black hides it, and the mask's own alpha scales the result further. It can
+// be used anywhere a Pattern is accepted.
+type MaskedPattern struct {
+	inner, mask Pattern
+}
+
+// WithAlphaMask returns p masked by the luminance of mask. A nil mask
+// returns p unchanged.
+func WithAlphaMask(p, mask Pattern) Pattern {
+	if p == nil || mask == nil {
+		return p
+	}
+	return &MaskedPattern{inner: p, mask: mask}
+}
+
+// ColorAt returns the inner color with its alpha scaled by the mask.
+func (p *MaskedPattern) ColorAt(x, y int) color.Color {
+	return applyMask(toColor(p.inner.ColorAt(x, y)), toColor(p.mask.ColorAt(x, y)))
+}
+
+// ColorsForSpan evaluates a row of both patterns with their span evaluators
+// where available.
+func (p *MaskedPattern) ColorsForSpan(y, x0, x1 int, dst []Color) {
+	FillSpan(p.inner, y, x0, x1, dst)
+	m := make([]Color, x1-x0)
+	FillSpan(p.mask, y, x0, x1, m)
+	for i := range m {
+		dst[i] = applyMask(dst[i], m[i])
+	}
+}
+
+// BlendMode forwards the inner pattern's blend mode, or BlendPassThrough
+// when the inner pattern does not define one.
+func (p *MaskedPattern) BlendMode() BlendMode {
+	if bp, ok := p.inner.(BlendedPattern); ok {
+		return bp.BlendMode()
+	}
+	return BlendPassThrough
+}
+
+// Opacity forwards the inner pattern's opacity, or 1 when it does not define one.
+func (p *MaskedPattern) Opacity() float64 {
+	if bp, ok := p.inner.(BlendedPattern); ok {
+		return bp.Opacity()
+	}
+	return 1
+}
+
+// WithAlphaMask masks the masked pattern again; the masks multiply.
+func (p *MaskedPattern) WithAlphaMask(mask Pattern) Pattern { return WithAlphaMask(p, mask) }
+
+// applyMask scales c's alpha by the Rec. 709 luma of m times m's alpha.
+func applyMask(c, m Color) Color {
+	luma := 2126*uint32(m.R) + 7152*uint32(m.G) + 722*uint32(m.B) // 0..255e4
+	c.A = uint8((uint64(c.A)*uint64(luma)*uint64(m.A) + 255e4*255/2) / (255e4 * 255))
+	return c
+}
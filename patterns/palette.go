@@ -0,0 +1,147 @@
+package patterns
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// paletteSamples caps the pixels ExtractPalette reads; larger images are
+// sampled on a regular grid.
+const paletteSamples = 1 << 16
+
+// ExtractPalette returns up to n representative colors of img, most common
+// first, using median cut: the pixels are split repeatedly at the median of
+// their widest channel and each group is averaged. Pixels under half
+// opacity are ignored and the colors are opaque. It returns fewer colors
+// when the image has fewer distinct ones, and none for an empty or fully
+// transparent image or n < 1.
+func ExtractPalette(img image.Image, n int) []Color {
+	if img == nil || n < 1 {
+		return nil
+	}
+	px := samplePixels(img)
+	if len(px) == 0 {
+		return nil
+	}
+
+	boxes := []colorBox{{px: px}}
+	for len(boxes) < n {
+		// Split the box with the widest channel, weighted by its size, so
+		// large spread-out groups are divided first.
+		best, score := -1, 0.0
+		for i, b := range boxes {
+			if s := float64(b.spread()) * math.Sqrt(float64(len(b.px))); len(b.px) > 1 && s > score {
+				best, score = i, s
+			}
+		}
+		if best < 0 {
+			break
+		}
+		lo, hi := boxes[best].split()
+		boxes[best] = lo
+		boxes = append(boxes, hi)
+	}
+
+	sort.SliceStable(boxes, func(i, j int) bool { return len(boxes[i].px) > len(boxes[j].px) })
+	out := make([]Color, len(boxes))
+	for i, b := range boxes {
+		out[i] = b.average()
+	}
+	return out
+}
+
+// DominantColor returns the most common color of img, as the largest group
+// of an eight-color ExtractPalette, which suits tinting a background to
+// match an avatar. It returns Transparent for an empty or fully transparent
+// image.
+func DominantColor(img image.Image) Color {
+	p := ExtractPalette(img, 8)
+	if len(p) == 0 {
+		return Color{}
+	}
+	return p[0]
+}
+
+// samplePixels returns the straight RGB values of the opaque enough pixels
+// of img, on a grid coarse enough to stay under paletteSamples.
+func samplePixels(img image.Image) [][3]uint8 {
+	b := img.Bounds()
+	step := 1
+	if total := b.Dx() * b.Dy(); total > paletteSamples {
+		step = int(math.Ceil(math.Sqrt(float64(total) / paletteSamples)))
+	}
+	var px [][3]uint8
+	for y := b.Min.Y; y < b.Max.Y; y += step {
+		for x := b.Min.X; x < b.Max.X; x += step {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A >= 128 {
+				px = append(px, [3]uint8{c.R, c.G, c.B})
+			}
+		}
+	}
+	return px
+}
+
+// colorBox is one group of pixels in median cut.
+type colorBox struct {
+	px [][3]uint8
+}
+
+// widest returns the channel with the largest range and that range.
+func (b colorBox) widest() (int, int) {
+	lo, hi := [3]uint8{255, 255, 255}, [3]uint8{}
+	for _, p := range b.px {
+		for c := 0; c < 3; c++ {
+			lo[c], hi[c] = min(lo[c], p[c]), max(hi[c], p[c])
+		}
+	}
+	ch, r := 0, -1
+	for c := 0; c < 3; c++ {
+		if d := int(hi[c]) - int(lo[c]); d > r {
+			ch, r = c, d
+		}
+	}
+	return ch, r
+}
+
+// spread returns the range of the widest channel.
+func (b colorBox) spread() int {
+	_, r := b.widest()
+	return r
+}
+
+// split divides the box at the median of its widest channel.
+func (b colorBox) split() (colorBox, colorBox) {
+	ch, _ := b.widest()
+	sort.Slice(b.px, func(i, j int) bool { return b.px[i][ch] < b.px[j][ch] })
+	// Move the cut to the nearest change of value, so equal values stay
+	// together; the channel has a nonzero range, so there is one.
+	m := len(b.px) / 2
+	for m < len(b.px) && b.px[m][ch] == b.px[m-1][ch] {
+		m++
+	}
+	if m == len(b.px) {
+		for m = len(b.px) / 2; b.px[m][ch] == b.px[m-1][ch]; m-- {
+		}
+	}
+	return colorBox{px: b.px[:m]}, colorBox{px: b.px[m:]}
+}
+
+// average returns the mean color of the box.
+func (b colorBox) average() Color {
+	var sum [3]int
+	for _, p := range b.px {
+		for c := 0; c < 3; c++ {
+			sum[c] += int(p[c])
+		}
+	}
+	n := len(b.px)
+	return Color{
+		R: uint8((sum[0] + n/2) / n),
+		G: uint8((sum[1] + n/2) / n),
+		B: uint8((sum[2] + n/2) / n),
+		A: 255,
+	}
+}
@@ -0,0 +1,68 @@
+// Package patterns defines the color sources shapes are filled and stroked
+// with: solid colors, gradients, surfaces and procedural fills, and the
+// Pattern interfaces painters accept. Any type implementing Pattern can be
+// passed to SetFillPattern and similar setters; implementing SpanPattern or
+// BlendedPattern as well makes it faster or controls how it composites.
+package patterns
+
+import (
+	"image/color"
+)
+
+// Pattern defines a minimal interface for any drawable color pattern.
+// It provides a method for sampling the color at a given pixel coordinate (x, y).
+type Pattern interface {
+	// ColorAt returns the color value at the specified pixel coordinate (x, y).
+	ColorAt(x, y int) color.Color
+}
+
+// BlendedPattern extends Pattern with blending and opacity properties.
+// This allows the pattern to define how it interacts visually with underlying content.
+type BlendedPattern interface {
+	Pattern
+	// BlendMode returns the blending mode applied when the pattern is composited.
+	BlendMode() BlendMode
+	// Opacity returns the opacity factor in the range [0, 1].
+	Opacity() float64
+}
+
+// GradientPattern defines a shared interface for all gradient-based patterns,
+// such as linear, radial, or conic gradients. It supports adding color stops
+// and includes blending and opacity control.
+type GradientPattern interface {
+	BlendedPattern
+	// AddColorStop adds a color stop to the gradient at the given normalized position [0, 1].
+	// Returns the same gradient instance for method chaining.
+	AddColorStop(offset float64, c Color) GradientPattern
+}
+
+// SpanPattern is an optional capability for patterns that can evaluate a
+// horizontal run of pixels faster than repeated ColorAt calls, typically by
+// stepping their parameters incrementally along the row.
+type SpanPattern interface {
+	Pattern
+	// ColorsForSpan writes the colors of pixels x0..x1-1 on row y into dst[:x1-x0].
+	// The results must match ColorAt converted to Color, within a level or
+	// two where smooth stretches are interpolated.
+	ColorsForSpan(y, x0, x1 int, dst []Color)
+}
+
+// FillSpan writes the colors of pixels x0..x1-1 on row y into dst[:x1-x0],
+// using the pattern's span evaluator when it has one and ColorAt otherwise.
+func FillSpan(p Pattern, y, x0, x1 int, dst []Color) {
+	if sp, ok := p.(SpanPattern); ok {
+		sp.ColorsForSpan(y, x0, x1, dst)
+		return
+	}
+	for x := x0; x < x1; x++ {
+		dst[x-x0] = toColor(p.ColorAt(x, y))
+	}
+}
+
+// toColor returns col as a Color, converting standard colors when needed.
+func toColor(col color.Color) Color {
+	if c, ok := col.(Color); ok {
+		return c
+	}
+	return NewColorFromStd(col)
+}
@@ -16,6 +16,7 @@ type RadialGradient struct {
 	a, inva    float64      // Precomputed coefficients for intersection solving
 	mindr      float64      // Minimum allowed distance between circles
 	stops      geom.Stops   // Sorted list of color stops
+	grain      grain        // Optional per-pixel jitter of t
 
 	mode    BlendMode // Blend mode applied during rendering
 	opacity float64   // Opacity in range [0, 1]
@@ -92,6 +93,18 @@ func (g *RadialGradient) WithOpacity(a float64) *RadialGradient {
 	return g
 }
 
+// WithAlphaMask returns the gradient masked by the luminance of mask (see
+// MaskedPattern). The gradient itself is not modified.
+func (g *RadialGradient) WithAlphaMask(mask Pattern) Pattern { return WithAlphaMask(g, mask) }
+
+// WithGrain adds per-pixel noise to the gradient: t is jittered by up to
+// amount (in [0, 1]) using a deterministic pattern derived from seed.
+// An amount of 0 disables grain.
+func (g *RadialGradient) WithGrain(amount float64, seed uint64) *RadialGradient {
+	g.grain = newGrain(amount, seed)
+	return g
+}
+
 // Color Stops
 
 // AddColorStop adds a color stop to the gradient at a specified offset [0, 1].
@@ -103,6 +116,18 @@ func (g *RadialGradient) AddColorStop(offset float64, c Color) GradientPattern {
 	return g
 }
 
+// AddColorStopWithHint adds a color stop at offset [0–1] whose transition
+// from the previous stop reaches the color halfway between them at position
+// hint [0–1] rather than in the middle, like a CSS color hint
+// (linear-gradient(red, 30%, blue)). A hint outside the interval between
+// the two stops makes a hard edge at its end.
+func (g *RadialGradient) AddColorStopWithHint(offset float64, c Color, hint float64) GradientPattern {
+	offset = geom.ClampF64(offset, 0, 1)
+	g.stops = append(g.stops, geom.NewStopWithHint(offset, c, geom.ClampF64(hint, 0, 1)))
+	sort.Sort(g.stops)
+	return g
+}
+
 // Sampling
 
 // ColorAt computes the interpolated color for a given pixel (x, y).
@@ -128,7 +153,47 @@ func (g *RadialGradient) ColorAt(x, y int) color.Color {
 	dx, dy := float64(x)+0.5-g.c0.X(), float64(y)+0.5-g.c0.Y()
 	b := geom.Dot3(dx, dy, g.c0.Radius(), g.cd.X(), g.cd.Y(), g.cd.Radius())
 	c := geom.Dot3(dx, dy, -g.c0.Radius(), dx, dy, g.c0.Radius())
+	return g.colorFor(b, c, x, y)
+}
+
+// ColorsForSpan evaluates a row of the gradient. Along a row the quadratic
+// coefficients change predictably: b grows by cd.X per pixel and c by 2*dx+1,
+// so both are stepped instead of recomputed from the pixel position. Without
+// grain, smooth stretches are sampled sparsely and interpolated (see
+// sampleSpan).
+func (g *RadialGradient) ColorsForSpan(y, x0, x1 int, dst []Color) {
+	n := x1 - x0
+	if n <= 0 {
+		return
+	}
+	if len(g.stops) == 0 {
+		for i := range dst[:n] {
+			dst[i] = Color{}
+		}
+		return
+	}
+
+	dx, dy := float64(x0)+0.5-g.c0.X(), float64(y)+0.5-g.c0.Y()
+	b := geom.Dot3(dx, dy, g.c0.Radius(), g.cd.X(), g.cd.Y(), g.cd.Radius())
+	c := geom.Dot3(dx, dy, -g.c0.Radius(), dx, dy, g.c0.Radius())
+	if g.grain.amount == 0 {
+		sampleSpan(dst, n, func(i int) Color {
+			fi := float64(i)
+			return toColor(g.colorFor(b+fi*g.cd.X(), c+fi*(2*dx+fi), x0+i, y))
+		})
+		return
+	}
+	for i := 0; i < n; i++ {
+		dst[i] = toColor(g.colorFor(b, c, x0+i, y))
+		b += g.cd.X()
+		c += 2*dx + 1
+		dx++
+	}
+}
 
+// colorFor solves the gradient equation for pixel (x, y) with quadratic
+// coefficients b and c and samples the color stops.
+func (g *RadialGradient) colorFor(b, c float64, x, y int) color.Color {
 	if g.a == 0 {
 		// Degenerate case: linear relationship between circles.
 		if b == 0 {
@@ -136,7 +201,7 @@ func (g *RadialGradient) ColorAt(x, y int) color.Color {
 		}
 		t := 0.5 * c / b
 		if t*g.cd.Radius() >= g.mindr {
-			return geom.GetColor(t, g.stops)
+			return geom.GetColor(g.grain.apply(t, x, y), g.stops)
 		}
 		return color.Transparent
 	}
@@ -153,9 +218,9 @@ func (g *RadialGradient) ColorAt(x, y int) color.Color {
 
 	switch {
 	case t0*g.cd.Radius() >= g.mindr:
-		return geom.GetColor(t0, g.stops)
+		return geom.GetColor(g.grain.apply(t0, x, y), g.stops)
 	case t1*g.cd.Radius() >= g.mindr:
-		return geom.GetColor(t1, g.stops)
+		return geom.GetColor(g.grain.apply(t1, x, y), g.stops)
 	default:
 		return color.Transparent
 	}
@@ -0,0 +1,91 @@
+package patterns
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+)
+
+// ScaledPattern samples an inner pattern in a coordinate space that is
+// magnified by a constant factor. Scaled uses it for patterns whose geometry
+// it cannot scale, such as FuncPattern and custom implementations.
+type ScaledPattern struct {
+	inner Pattern
+	scale float64
+}
+
+// Scaled returns p for a canvas magnified by s, so patterns authored in
+// logical pixels fill shapes rendered at a higher device scale (e.g. @2x
+// exports). Gradients and surfaces are returned as copies with their
+// geometry scaled, so they stay smooth at device resolution; masks and
+// blend or accuracy overrides scale what they wrap. Other patterns are
+// wrapped in a ScaledPattern. Solid patterns, nil patterns and a scale of 1
+// are returned unchanged.
+func Scaled(p Pattern, s float64) Pattern {
+	if p == nil || s <= 0 || s == 1 {
+		return p
+	}
+	switch q := p.(type) {
+	case *Solid:
+		return p
+	case *LinearGradient:
+		c := *q
+		c.x0, c.y0, c.x1, c.y1 = q.x0*s, q.y0*s, q.x1*s, q.y1*s
+		return &c
+	case *RadialGradient:
+		return q.scaledBy(s)
+	case *ConicGradient:
+		c := *q
+		c.cx, c.cy = q.cx*s, q.cy*s
+		return &c
+	case *Surface:
+		return q.scaledBy(s)
+	case *MaskedPattern:
+		return &MaskedPattern{inner: Scaled(q.inner, s), mask: Scaled(q.mask, s)}
+	case *BlendOverride:
+		return &BlendOverride{inner: Scaled(q.inner, s), mode: q.mode}
+	}
+	return &ScaledPattern{inner: p, scale: s}
+}
+
+// scaledBy returns a copy of the gradient with both circles scaled by f.
+func (g *RadialGradient) scaledBy(f float64) *RadialGradient {
+	c := *g
+	c.c0 = geom.NewCircle(g.c0.X()*f, g.c0.Y()*f, g.c0.Radius()*f)
+	c.c1 = geom.NewCircle(g.c1.X()*f, g.c1.Y()*f, g.c1.Radius()*f)
+	c.cd = geom.NewCircle(g.cd.X()*f, g.cd.Y()*f, g.cd.Radius()*f)
+	c.a = g.a * f * f
+	c.inva = 0
+	if c.a != 0 {
+		c.inva = 1 / c.a
+	}
+	c.mindr = -c.c0.Radius()
+	return &c
+}
+
+// ColorAt samples the inner pattern at the logical pixel holding the centre
+// of device pixel (x, y).
+func (p *ScaledPattern) ColorAt(x, y int) color.Color {
+	return p.inner.ColorAt(
+		int(math.Floor((float64(x)+0.5)/p.scale)),
+		int(math.Floor((float64(y)+0.5)/p.scale)),
+	)
+}
+
+// BlendMode forwards the inner pattern's blend mode, or BlendPassThrough
+// when the inner pattern does not define one.
+func (p *ScaledPattern) BlendMode() BlendMode {
+	if bp, ok := p.inner.(BlendedPattern); ok {
+		return bp.BlendMode()
+	}
+	return BlendPassThrough
+}
+
+// Opacity forwards the inner pattern's opacity, or 1 when it does not define one.
+func (p *ScaledPattern) Opacity() float64 {
+	if bp, ok := p.inner.(BlendedPattern); ok {
+		return bp.Opacity()
+	}
+	return 1
+}
@@ -56,3 +56,14 @@ func (p *Solid) WithOpacity(a float64) *Solid {
 	p.opacity = geom.ClampF64(a, 0, 1)
 	return p
 }
+
+// WithAlphaMask returns the solid masked by the luminance of mask (see
+// MaskedPattern). The solid itself is not modified.
+func (p *Solid) WithAlphaMask(mask Pattern) Pattern { return WithAlphaMask(p, mask) }
+
+// ColorsForSpan fills dst with the solid color.
+func (p *Solid) ColorsForSpan(_, x0, x1 int, dst []Color) {
+	for i := range dst[:x1-x0] {
+		dst[i] = p.color
+	}
+}
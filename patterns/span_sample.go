@@ -0,0 +1,80 @@
+package patterns
+
+import "math"
+
+// spanStep is the distance in pixels between exact samples of a sampled span.
+const spanStep = 8
+
+// sampleSpan fills dst[:n] for patterns that are costly per pixel but smooth
+// along a row, such as radial and conic gradients. at(i) is evaluated
+// exactly every spanStep pixels and at a third and two thirds of the way
+// between; when both probes lie within one level of the straight line
+// through their neighbours, and all four share an alpha, the remaining
+// pixels are interpolated instead of evaluated. Two uneven probes also catch
+// folds symmetric around the middle, such as a seam. Edges, hard stops and
+// alpha changes fail the check and are evaluated per pixel, so only
+// features narrower than a third of a step that fall between samples can be
+// smoothed away.
+func sampleSpan(dst []Color, n int, at func(i int) Color) {
+	if n <= spanStep {
+		for i := 0; i < n; i++ {
+			dst[i] = at(i)
+		}
+		return
+	}
+
+	dst[0] = at(0)
+	for i0 := 0; i0 < n-1; i0 += spanStep {
+		i1 := min(i0+spanStep, n-1)
+		dst[i1] = at(i1)
+		w := i1 - i0
+		if w < 3 {
+			for i := i0 + 1; i < i1; i++ {
+				dst[i] = at(i)
+			}
+			continue
+		}
+		p1, p2 := i0+w/3, i0+2*w/3
+		dst[p1], dst[p2] = at(p1), at(p2)
+
+		a, b := dst[i0], dst[i1]
+		frac := func(i int) float64 { return float64(i-i0) / float64(w) }
+		smooth := linearBetween(a, b, dst[p1], frac(p1)) && linearBetween(a, b, dst[p2], frac(p2))
+		for i := i0 + 1; i < i1; i++ {
+			switch {
+			case i == p1 || i == p2:
+			case smooth:
+				dst[i] = lerpStraight(a, b, frac(i))
+			default:
+				dst[i] = at(i)
+			}
+		}
+	}
+}
+
+// linearBetween reports whether c, sampled at fraction t between a and b,
+// matches their interpolation within one level and all three share an alpha.
+func linearBetween(a, b, c Color, t float64) bool {
+	if a.A != b.A || a.A != c.A || a.blendMode != b.blendMode {
+		return false
+	}
+	l := lerpStraight(a, b, t)
+	return absDiff(l.R, c.R) <= 1 && absDiff(l.G, c.G) <= 1 && absDiff(l.B, c.B) <= 1
+}
+
+// lerpStraight interpolates the color channels of a and b, keeping a's alpha
+// and blend mode.
+func lerpStraight(a, b Color, t float64) Color {
+	a.R = uint8(math.Round(float64(a.R) + (float64(b.R)-float64(a.R))*t))
+	a.G = uint8(math.Round(float64(a.G) + (float64(b.G)-float64(a.G))*t))
+	a.B = uint8(math.Round(float64(a.B) + (float64(b.B)-float64(a.B))*t))
+	return a
+}
+
+// absDiff returns |a - b|.
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
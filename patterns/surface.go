@@ -0,0 +1,233 @@
+package patterns
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+)
+
+// RepeatOp defines how an image-based pattern repeats along the X and Y axes.
+type RepeatOp int
+
+const (
+	// RepeatBoth repeats the image in both X and Y directions.
+	RepeatBoth RepeatOp = iota
+	// RepeatX repeats only along the X axis.
+	RepeatX
+	// RepeatY repeats only along the Y axis.
+	RepeatY
+	// RepeatNone disables repetition; pixels outside the image bounds are transparent.
+	RepeatNone
+)
+
+// Surface represents an image-based pattern (a texture) that can be repeated
+// or clamped along one or both axes. It implements the Pattern and BlendedPattern
+// interfaces and supports blending and opacity adjustments.
+//
+// By default the texture's top-left sits at the canvas origin at its natural
+// size. SetOffset, SetScale and SetAnchor place and size it, like CSS
+// background-position and background-size; SetCover and SetContain fit it to
+// a shape's box. SetFilter and SetMipmaps smooth textures drawn at other
+// than their natural size.
+type Surface struct {
+	im image.Image // Source image (texture)
+	op RepeatOp    // Repetition mode
+
+	offX, offY float64 // canvas point the anchor is placed at
+	sx, sy     float64 // texture scale
+	ax, ay     float64 // anchor as a fraction of the texture size
+
+	filter SurfaceFilter // Texel interpolation
+	levels []*image.RGBA // Prefiltered halvings of im; nil without mipmaps
+
+	mode    BlendMode // Blending mode
+	opacity float64   // Opacity factor [0, 1]
+}
+
+// BlendMode returns the blending mode associated with this surface pattern.
+func (s *Surface) BlendMode() BlendMode { return s.mode }
+
+// Opacity returns the pattern’s opacity factor in [0, 1].
+func (s *Surface) Opacity() float64 { return s.opacity }
+
+// Sampling
+
+// ColorAt returns the color of the surface at pixel coordinate (x, y).
+// Depending on the RepeatOp setting, the image can repeat along one or both axes.
+//
+// Behavior:
+//   - RepeatBoth — tiles in both X and Y directions.
+//   - RepeatX — repeats horizontally; outside vertical bounds is transparent.
+//   - RepeatY — repeats vertically; outside horizontal bounds is transparent.
+//   - RepeatNone — outside both bounds is transparent.
+//
+// Example use cases:
+//   - Seamless textures for fills or brush patterns.
+//   - Image-based masks with blending applied per pixel.
+func (s *Surface) ColorAt(x, y int) color.Color {
+	if s.filtered() {
+		return s.filteredAt(x, y)
+	}
+	b := s.im.Bounds()
+	tx, ok := s.texel(x, s.offX, s.sx, s.ax, b.Dx(), s.op == RepeatBoth || s.op == RepeatX)
+	if !ok {
+		return color.Transparent
+	}
+	ty, ok := s.texel(y, s.offY, s.sy, s.ay, b.Dy(), s.op == RepeatBoth || s.op == RepeatY)
+	if !ok {
+		return color.Transparent
+	}
+
+	// Convert to internal Color and apply blend mode
+	return NewColorFromStd(s.im.At(tx+b.Min.X, ty+b.Min.Y)).SetBlendMode(s.mode)
+}
+
+// texel maps canvas coordinate c to a texel index along an axis of n texels
+// placed by off, scale and anchor, wrapping it when repeat is set. It
+// reports false outside a non-repeating texture.
+func (s *Surface) texel(c int, off, scale, anchor float64, n int, repeat bool) (int, bool) {
+	t := int(math.Floor((float64(c)+0.5-off)/scale + anchor*float64(n)))
+	if repeat {
+		return (t%n + n) % n, true
+	}
+	return t, t >= 0 && t < n
+}
+
+// Placement
+
+// SetOffset places the texture's anchor point at canvas point (x, y). With
+// the default anchor this moves the texture's top-left, e.g. to a shape's
+// top-left corner. Returns the receiver for chaining.
+func (s *Surface) SetOffset(x, y float64) *Surface {
+	s.offX, s.offY = x, y
+	return s
+}
+
+// SetScale scales the texture by sx horizontally and sy vertically.
+// Non-positive factors are ignored. Returns the receiver for chaining.
+func (s *Surface) SetScale(sx, sy float64) *Surface {
+	if sx > 0 {
+		s.sx = sx
+	}
+	if sy > 0 {
+		s.sy = sy
+	}
+	return s
+}
+
+// SetAnchor selects the point of the texture placed at the offset, as a
+// fraction of its size: (0, 0) is its top-left, (0.5, 0.5) its center and
+// (1, 1) its bottom-right. Returns the receiver for chaining.
+func (s *Surface) SetAnchor(ax, ay float64) *Surface {
+	s.ax, s.ay = ax, ay
+	return s
+}
+
+// SetCover scales the texture uniformly to cover the box at (x, y) of size
+// w×h and centers it there, like CSS background-size: cover. Returns the
+// receiver for chaining.
+func (s *Surface) SetCover(x, y, w, h float64) *Surface {
+	return s.fitBox(x, y, w, h, math.Max)
+}
+
+// SetContain scales the texture uniformly to fit inside the box at (x, y) of
+// size w×h and centers it there, like CSS background-size: contain. Returns
+// the receiver for chaining.
+func (s *Surface) SetContain(x, y, w, h float64) *Surface {
+	return s.fitBox(x, y, w, h, math.Min)
+}
+
+// fitBox centers the texture in a box, scaled by pick of the two axis ratios.
+func (s *Surface) fitBox(x, y, w, h float64, pick func(a, b float64) float64) *Surface {
+	b := s.im.Bounds()
+	if b.Empty() || w <= 0 || h <= 0 {
+		return s
+	}
+	k := pick(w/float64(b.Dx()), h/float64(b.Dy()))
+	return s.SetScale(k, k).SetAnchor(0.5, 0.5).SetOffset(x+w/2, y+h/2)
+}
+
+// Constructors
+
+// NewSurface creates a new Surface pattern from an image with a repetition mode.
+// By default, it uses normal blending and full opacity.
+func NewSurface(im image.Image, op RepeatOp) *Surface {
+	return &Surface{im: im, op: op, sx: 1, sy: 1, opacity: 1}
+}
+
+// NewSurfaceWithBlend creates a new Surface pattern with custom blending and opacity.
+// The opacity value is clamped to [0, 1].
+func NewSurfaceWithBlend(im image.Image, op RepeatOp, mode BlendMode, opacity float64) *Surface {
+	return &Surface{
+		im:      im,
+		op:      op,
+		sx:      1,
+		sy:      1,
+		mode:    mode,
+		opacity: geom.ClampF64(opacity, 0, 1),
+	}
+}
+
+// Modifiers
+
+// WithBlendMode sets the blend mode and returns the same Surface instance for chaining.
+func (s *Surface) WithBlendMode(m BlendMode) *Surface {
+	s.mode = m
+	return s
+}
+
+// WithOpacity sets the opacity (clamped to [0, 1]) and returns the same Surface instance for chaining.
+func (s *Surface) WithOpacity(a float64) *Surface {
+	s.opacity = geom.ClampF64(a, 0, 1)
+	return s
+}
+
+// WithOffset places the texture like SetOffset, so its tiles line up with a
+// shape rather than the canvas origin, and returns the same Surface instance
+// for chaining.
+func (s *Surface) WithOffset(dx, dy float64) *Surface { return s.SetOffset(dx, dy) }
+
+// WithScale scales the texture like SetScale and returns the same Surface
+// instance for chaining.
+func (s *Surface) WithScale(sx, sy float64) *Surface { return s.SetScale(sx, sy) }
+
+// WithAlphaMask returns the surface masked by the luminance of mask (see
+// MaskedPattern). The surface itself is not modified.
+func (s *Surface) WithAlphaMask(mask Pattern) Pattern { return WithAlphaMask(s, mask) }
+
+// ColorsForSpan reads a row of the surface. The row is resolved once, and
+// *image.RGBA sources are read straight from their pixel buffer.
+func (s *Surface) ColorsForSpan(y, x0, x1 int, dst []Color) {
+	n := x1 - x0
+	if n <= 0 {
+		return
+	}
+	b := s.im.Bounds()
+	rgba, ok := s.im.(*image.RGBA)
+	if s.filtered() {
+		ok = false
+	}
+	ty, rowIn := s.texel(y, s.offY, s.sy, s.ay, b.Dy(), s.op == RepeatBoth || s.op == RepeatY)
+	if !ok || !rowIn {
+		for i := 0; i < n; i++ {
+			dst[i] = toColor(s.ColorAt(x0+i, y))
+		}
+		return
+	}
+
+	repX := s.op == RepeatBoth || s.op == RepeatX
+	row := rgba.PixOffset(b.Min.X, ty+b.Min.Y)
+	for i := 0; i < n; i++ {
+		tx, in := s.texel(x0+i, s.offX, s.sx, s.ax, b.Dx(), repX)
+		if !in {
+			dst[i] = Color{}
+			continue
+		}
+		o := row + tx*4
+		c := Color{A: rgba.Pix[o+3], blendMode: s.mode}
+		c.R, c.G, c.B = geom.UnpremultiplyRGB(rgba.Pix[o], rgba.Pix[o+1], rgba.Pix[o+2], c.A)
+		dst[i] = c
+	}
+}
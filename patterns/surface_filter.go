@@ -0,0 +1,157 @@
+package patterns
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/Krispeckt/glimo/internal/core/geom"
+)
+
+// SurfaceFilter selects how a Surface interpolates between texels.
+type SurfaceFilter int
+
+const (
+	// FilterNearest picks the nearest texel. It is the fastest and keeps
+	// pixel art crisp, but scaled textures show jagged edges.
+	FilterNearest SurfaceFilter = iota
+	// FilterBilinear blends the four nearest texels, so magnified and
+	// slightly minified textures look smooth.
+	FilterBilinear
+)
+
+// SetFilter selects texel interpolation, FilterNearest by default. Returns
+// the receiver for chaining.
+func (s *Surface) SetFilter(f SurfaceFilter) *Surface {
+	s.filter = f
+	return s
+}
+
+// SetMipmaps enables prefiltering for textures drawn well below their
+// natural size, where even bilinear sampling skips texels and shows moiré:
+// the texture is halved repeatedly with a box filter when enabled, and
+// samples read the level closest to the drawn scale. Combine it with
+// FilterBilinear for the smoothest result. The levels are built from the
+// current image. Returns the receiver for chaining.
+func (s *Surface) SetMipmaps(on bool) *Surface {
+	s.levels = nil
+	if on && !s.im.Bounds().Empty() {
+		s.levels = buildMipmaps(s.im)
+	}
+	return s
+}
+
+// filtered reports whether sampling differs from the plain nearest lookup.
+func (s *Surface) filtered() bool {
+	return s.filter != FilterNearest || s.levels != nil
+}
+
+// level returns the texture to sample for the current scale with its
+// horizontal and vertical scale relative to that level.
+func (s *Surface) level() (image.Image, float64, float64) {
+	if len(s.levels) == 0 {
+		return s.im, s.sx, s.sy
+	}
+	k := 0
+	for k+1 < len(s.levels) && s.sx*float64(int(1)<<(k+1)) <= 1 && s.sy*float64(int(1)<<(k+1)) <= 1 {
+		k++
+	}
+	b0, bk := s.levels[0].Bounds(), s.levels[k].Bounds()
+	return s.levels[k],
+		s.sx * float64(b0.Dx()) / float64(bk.Dx()),
+		s.sy * float64(b0.Dy()) / float64(bk.Dy())
+}
+
+// filteredAt samples the surface with its filter and mipmaps.
+func (s *Surface) filteredAt(x, y int) color.Color {
+	im, sx, sy := s.level()
+	b := im.Bounds()
+	w, h := b.Dx(), b.Dy()
+	repX := s.op == RepeatBoth || s.op == RepeatX
+	repY := s.op == RepeatBoth || s.op == RepeatY
+
+	tx, okX := s.texel(x, s.offX, sx, s.ax, w, repX)
+	ty, okY := s.texel(y, s.offY, sy, s.ay, h, repY)
+	if !okX || !okY {
+		return color.Transparent
+	}
+	if s.filter == FilterNearest {
+		return NewColorFromStd(im.At(tx+b.Min.X, ty+b.Min.Y)).SetBlendMode(s.mode)
+	}
+
+	// Texel centers sit at half-integers, so shift by 0.5 to interpolate
+	// between the two nearest ones on each axis.
+	u := (float64(x)+0.5-s.offX)/sx + s.ax*float64(w) - 0.5
+	v := (float64(y)+0.5-s.offY)/sy + s.ay*float64(h) - 0.5
+	x0, y0 := math.Floor(u), math.Floor(v)
+	fx, fy := u-x0, v-y0
+
+	// fetch returns a premultiplied texel, wrapping repeated axes and
+	// clamping the others to the edge.
+	fetch := func(tx, ty int) [4]float64 {
+		if repX {
+			tx = (tx%w + w) % w
+		} else {
+			tx = min(max(tx, 0), w-1)
+		}
+		if repY {
+			ty = (ty%h + h) % h
+		} else {
+			ty = min(max(ty, 0), h-1)
+		}
+		r, g, bl, a := im.At(tx+b.Min.X, ty+b.Min.Y).RGBA()
+		return [4]float64{float64(r >> 8), float64(g >> 8), float64(bl >> 8), float64(a >> 8)}
+	}
+	ix, iy := int(x0), int(y0)
+	c00, c10 := fetch(ix, iy), fetch(ix+1, iy)
+	c01, c11 := fetch(ix, iy+1), fetch(ix+1, iy+1)
+
+	var out [4]uint8
+	for i := range out {
+		top := c00[i]*(1-fx) + c10[i]*fx
+		bottom := c01[i]*(1-fx) + c11[i]*fx
+		out[i] = uint8(math.Round(top*(1-fy) + bottom*fy))
+	}
+	r, g, bl := geom.UnpremultiplyRGB(out[0], out[1], out[2], out[3])
+	return Color{R: r, G: g, B: bl, A: out[3], blendMode: s.mode}
+}
+
+// scaledBy returns a copy of the surface placed for a device scale of f, so
+// it is sampled at device resolution and keeps its filter there.
+func (s *Surface) scaledBy(f float64) *Surface {
+	c := *s
+	c.offX, c.offY = s.offX*f, s.offY*f
+	c.sx, c.sy = s.sx*f, s.sy*f
+	return &c
+}
+
+// buildMipmaps returns im as RGBA followed by successive halvings down to
+// 1×1, each texel the average of a 2×2 block of the previous level.
+func buildMipmaps(im image.Image) []*image.RGBA {
+	b := im.Bounds()
+	base := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(base, base.Bounds(), im, b.Min, draw.Src)
+	levels := []*image.RGBA{base}
+
+	for prev := base; prev.Rect.Dx() > 1 || prev.Rect.Dy() > 1; {
+		pw, ph := prev.Rect.Dx(), prev.Rect.Dy()
+		w, h := max(pw/2, 1), max(ph/2, 1)
+		next := image.NewRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			y0, y1 := min(2*y, ph-1), min(2*y+1, ph-1)
+			for x := 0; x < w; x++ {
+				x0, x1 := min(2*x, pw-1), min(2*x+1, pw-1)
+				o := next.PixOffset(x, y)
+				for i := 0; i < 4; i++ {
+					sum := int(prev.Pix[prev.PixOffset(x0, y0)+i]) + int(prev.Pix[prev.PixOffset(x1, y0)+i]) +
+						int(prev.Pix[prev.PixOffset(x0, y1)+i]) + int(prev.Pix[prev.PixOffset(x1, y1)+i])
+					next.Pix[o+i] = uint8((sum + 2) / 4)
+				}
+			}
+		}
+		levels = append(levels, next)
+		prev = next
+	}
+	return levels
+}
@@ -0,0 +1,169 @@
+// Package preview renders standardized preview tiles of patterns and effects,
+// for generating visual catalogs of blend modes, gradients and effects in
+// documentation.
+//
+// Every tile is TileSize pixels square: a swatch on top and a centered label
+// underneath. Patterns are sampled in tile coordinates, so a gradient built
+// to span Swatch fills it exactly. Effects are applied to a sample shape
+// inside the swatch. Catalog arranges tiles in a grid:
+//
+//	tiles := preview.BlendModes(colors.NewSolid(colors.Red))
+//	img := preview.Catalog(tiles, 6)
+package preview
+
+import (
+	"image"
+	"math"
+
+	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/effects"
+	"github.com/Krispeckt/glimo/instructions"
+	"github.com/Krispeckt/glimo/internal/render"
+	"github.com/Krispeckt/glimo/patterns"
+)
+
+// Tile geometry, in pixels.
+const (
+	TileSize    = 160 // width and height of a tile
+	Padding     = 8   // margin around the swatch and gap between catalog tiles
+	LabelHeight = 28  // band under the swatch holding the label
+)
+
+// Swatch is the area of a tile filled by its pattern or sample shape.
+var Swatch = image.Rect(Padding, Padding, TileSize-Padding, TileSize-LabelHeight)
+
+// Tile describes one preview. Set Pattern for a pattern swatch, Effect for
+// an effect sample, or both to preview an effect on a shape filled with
+// Pattern.
+type Tile struct {
+	Label    string
+	Pattern  patterns.Pattern // swatch fill
+	Backdrop patterns.Pattern // drawn under the swatch; a checkerboard when nil
+	Effect   effects.Effect   // applied to a sample shape inside the swatch
+}
+
+// Pattern returns the preview tile of p. An empty label names the blend
+// mode of blended patterns.
+func Pattern(p patterns.Pattern, label string) *image.RGBA {
+	return Tile{Label: label, Pattern: p}.Render()
+}
+
+// Effect returns the preview tile of e applied to the sample shape. An empty
+// label uses e.Name().
+func Effect(e effects.Effect, label string) *image.RGBA {
+	return Tile{Label: label, Effect: e}.Render()
+}
+
+// BlendModes returns one tile per blend mode, each compositing src over a
+// colorful backdrop in that mode and labeled with the mode's name.
+func BlendModes(src patterns.Pattern) []Tile {
+	modes := []patterns.BlendMode{
+		patterns.BlendNormal, patterns.BlendDarken, patterns.BlendMultiply,
+		patterns.BlendLinearBurn, patterns.BlendColorBurn, patterns.BlendLighten,
+		patterns.BlendScreen, patterns.BlendLinearDodge, patterns.BlendColorDodge,
+		patterns.BlendOverlay, patterns.BlendSoftLight, patterns.BlendHardLight,
+		patterns.BlendDifference, patterns.BlendExclusion, patterns.BlendHue,
+		patterns.BlendSaturation, patterns.BlendColor, patterns.BlendLuminosity,
+	}
+	backdrop := patterns.NewLinearGradient(float64(Swatch.Min.X), 0, float64(Swatch.Max.X), 0).
+		AddColorStop(0, colors.RGB(32, 64, 224)).
+		AddColorStop(0.5, colors.RGB(240, 220, 40)).
+		AddColorStop(1, colors.RGB(220, 40, 120))
+
+	tiles := make([]Tile, len(modes))
+	for i, m := range modes {
+		tiles[i] = Tile{
+			Label: m.String(),
+			Pattern: patterns.NewFuncPatternWithBlend(func(x, y int) patterns.Color {
+				return patterns.NewColorFromStd(src.ColorAt(x, y))
+			}, m, 1),
+			Backdrop: backdrop,
+		}
+	}
+	return tiles
+}
+
+// Render draws the tile.
+func (t Tile) Render() *image.RGBA {
+	l := instructions.NewLayer(TileSize, TileSize)
+	l.LoadInstruction(instructions.NewRectangle(0, 0, TileSize, TileSize).
+		SetRadius(6).
+		SetFillColor(colors.RGB(250, 250, 250)).
+		SetStrokeColor(colors.RGB(220, 220, 224)))
+
+	sw := float64(Swatch.Dx())
+	sh := float64(Swatch.Dy())
+	sx, sy := float64(Swatch.Min.X), float64(Swatch.Min.Y)
+	backdrop := t.Backdrop
+	if backdrop == nil {
+		backdrop = checkerboard()
+	}
+	l.LoadInstruction(instructions.NewRectangle(sx, sy, sw, sh).SetLineWidth(0).SetFillPattern(backdrop))
+
+	label := t.Label
+	switch {
+	case t.Effect != nil:
+		fill := t.Pattern
+		if fill == nil {
+			fill = sampleFill()
+		}
+		inset := math.Round(sh / 5)
+		l.LoadInstruction(instructions.NewRectangle(sx+inset, sy+inset, sw-2*inset, sh-2*inset).
+			SetRadius(10).
+			SetLineWidth(0).
+			SetFillPattern(fill).
+			AddEffect(t.Effect))
+		if label == "" {
+			label = t.Effect.Name()
+		}
+	case t.Pattern != nil:
+		l.LoadInstruction(instructions.NewRectangle(sx, sy, sw, sh).SetLineWidth(0).SetFillPattern(t.Pattern))
+		if bp, ok := t.Pattern.(patterns.BlendedPattern); ok && label == "" {
+			label = bp.BlendMode().String()
+		}
+	}
+
+	f := render.DefaultFont(10)
+	l.LoadInstruction(instructions.NewText(label, Padding, float64(Swatch.Max.Y)+(LabelHeight-f.HeightPx())/2, f).
+		SetAlign(instructions.AlignTextCenter).
+		SetMaxWidth(TileSize - 2*Padding).
+		SetMaxLines(1).
+		SetSolidColor(colors.RGB(48, 48, 56)))
+	return l.Image()
+}
+
+// Catalog arranges tiles in a grid of the given number of columns, Padding
+// apart, on a white background.
+func Catalog(tiles []Tile, columns int) *image.RGBA {
+	columns = max(min(columns, len(tiles)), 1)
+	rows := (len(tiles) + columns - 1) / columns
+	step := TileSize + Padding
+	l := instructions.NewLayer(columns*step+Padding, rows*step+Padding)
+	l.LoadInstruction(instructions.NewRectangle(0, 0, float64(columns*step+Padding), float64(rows*step+Padding)).
+		SetLineWidth(0).
+		SetFillColor(colors.White))
+	for i, t := range tiles {
+		x := Padding + i%columns*step
+		y := Padding + i/columns*step
+		l.LoadInstruction(instructions.NewImage(t.Render(), x, y))
+	}
+	return l.Image()
+}
+
+// checkerboard is the default backdrop, showing transparency.
+func checkerboard() patterns.Pattern {
+	light, dark := colors.RGB(255, 255, 255), colors.RGB(214, 214, 220)
+	return patterns.NewFuncPattern(func(x, y int) patterns.Color {
+		if (x/8+y/8)%2 == 0 {
+			return light
+		}
+		return dark
+	})
+}
+
+// sampleFill fills the sample shape of effect tiles.
+func sampleFill() patterns.Pattern {
+	return patterns.NewLinearGradient(float64(Swatch.Min.X), float64(Swatch.Min.Y), float64(Swatch.Max.X), float64(Swatch.Max.Y)).
+		AddColorStop(0, colors.RGB(255, 138, 76)).
+		AddColorStop(1, colors.RGB(124, 58, 237))
+}
@@ -0,0 +1,54 @@
+package preview_test
+
+import (
+	"image/png"
+	"os"
+	"testing"
+
+	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/effects"
+	"github.com/Krispeckt/glimo/patterns"
+	"github.com/Krispeckt/glimo/preview"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewTiles(t *testing.T) {
+	grad := colors.NewLinearGradient(float64(preview.Swatch.Min.X), 0, float64(preview.Swatch.Max.X), 0).
+		AddColorStop(0, colors.Red).
+		AddColorStop(1, colors.Blue)
+	tile := preview.Pattern(grad, "linear")
+	require.Equal(t, preview.TileSize, tile.Bounds().Dx())
+	require.Equal(t, preview.TileSize, tile.Bounds().Dy())
+	left := tile.RGBAAt(preview.Swatch.Min.X, preview.Swatch.Min.Y+10)
+	right := tile.RGBAAt(preview.Swatch.Max.X-1, preview.Swatch.Min.Y+10)
+	require.Greater(t, left.R, right.R)
+	require.Greater(t, right.B, left.B)
+
+	// The label band holds dark text pixels.
+	dark := 0
+	for y := preview.Swatch.Max.Y; y < preview.TileSize; y++ {
+		for x := 0; x < preview.TileSize; x++ {
+			if tile.RGBAAt(x, y).R < 128 {
+				dark++
+			}
+		}
+	}
+	require.Positive(t, dark)
+
+	blends := preview.BlendModes(colors.NewSolid(colors.RGB(230, 90, 30)))
+	require.Equal(t, patterns.BlendMultiply.String(), blends[2].Label)
+	tiles := append(blends,
+		preview.Tile{Pattern: grad},
+		preview.Tile{Effect: effects.NewDropShadow(4, 6, 8, 0, colors.Black, 0.5)},
+		preview.Tile{Effect: effects.NewLayerBlurEffect(6)},
+	)
+	catalog := preview.Catalog(tiles, 6)
+	step := preview.TileSize + preview.Padding
+	require.Equal(t, 6*step+preview.Padding, catalog.Bounds().Dx())
+	require.Equal(t, 4*step+preview.Padding, catalog.Bounds().Dy())
+
+	f, err := os.Create("./output/catalog.png")
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, png.Encode(f, catalog))
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Krispeckt/glimo/instructions"
+)
+
+// ExportSpec describes one output of Scene.ExportSet.
+//
+// The output size is the scene canvas times a device scale: Scale when set,
+// otherwise the one that makes the canvas Width pixels wide or Height pixels
+// tall, the smaller of the two when both are set. With none of them the
+// scene's own Scale applies. Format and Quality are as in Scene, and empty
+// fields fall back to the scene's.
+type ExportSpec struct {
+	Scale   float64 `json:"scale,omitempty"`
+	Width   int     `json:"width,omitempty"`
+	Height  int     `json:"height,omitempty"`
+	Format  string  `json:"format,omitempty"`
+	Quality int     `json:"quality,omitempty"`
+
+	// Background replaces the scene background for this output.
+	Background string `json:"background,omitempty"`
+
+	// Overrides restyles instructions by their ID for this output only, for
+	// example a larger font or a shorter text on a small thumbnail.
+	Overrides map[string]StyleOverride `json:"overrides,omitempty"`
+}
+
+// StyleOverride replaces fields of an Instruction. Empty and zero fields keep
+// the instruction's value; Hidden leaves the instruction out.
+type StyleOverride struct {
+	Fill      string   `json:"fill,omitempty"`
+	Stroke    string   `json:"stroke,omitempty"`
+	LineWidth *float64 `json:"lineWidth,omitempty"`
+	Text      string   `json:"text,omitempty"`
+	Size      float64  `json:"size,omitempty"`
+	Color     string   `json:"color,omitempty"`
+	MaxLines  int      `json:"maxLines,omitempty"`
+	Opacity   *float64 `json:"opacity,omitempty"`
+	Hidden    bool     `json:"hidden,omitempty"`
+}
+
+// apply returns a copy of in with the override's fields set.
+func (o StyleOverride) apply(in Instruction) Instruction {
+	if o.Fill != "" {
+		in.Fill = o.Fill
+	}
+	if o.Stroke != "" {
+		in.Stroke = o.Stroke
+	}
+	if o.LineWidth != nil {
+		in.LineWidth = *o.LineWidth
+	}
+	if o.Text != "" {
+		in.Text = o.Text
+	}
+	if o.Size > 0 {
+		in.Size = o.Size
+	}
+	if o.Color != "" {
+		in.Color = o.Color
+	}
+	if o.MaxLines != 0 {
+		in.MaxLines = o.MaxLines
+	}
+	if o.Opacity != nil {
+		in.Opacity = o.Opacity
+	}
+	return in
+}
+
+// Export is one encoded output of Scene.ExportSet.
+type Export struct {
+	Data          []byte
+	ContentType   string
+	Width, Height int // pixel size of the encoded image
+}
+
+// ExportSet renders the scene once per named spec, such as "og", "thumb" and
+// "retina", and returns the encoded outputs under the same names. Assets are
+// decoded once for all outputs, and specs at the same scale without
+// background or overrides share one render, differing only in encoding.
+func (s *Scene) ExportSet(specs map[string]ExportSpec) (map[string]Export, error) {
+	return s.ExportSetWithLimits(specs, instructions.Limits{})
+}
+
+// ExportSetWithLimits is ExportSet under lim, which every output must fit
+// (see BuildWithLimits).
+func (s *Scene) ExportSetWithLimits(specs map[string]ExportSpec, lim instructions.Limits) (map[string]Export, error) {
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	a := s.newAssets()
+	shared := map[float64]*instructions.Layer{}
+	out := make(map[string]Export, len(specs))
+	for _, name := range names {
+		spec := specs[name]
+		scale := s.exportScale(spec)
+		if err := lim.CheckCanvas(s.Width, s.Height, scale); err != nil {
+			return nil, fmt.Errorf("server: export %q: %w", name, err)
+		}
+
+		plain := spec.Background == "" && len(spec.Overrides) == 0
+		l := shared[scale]
+		if !plain || l == nil {
+			background := s.Background
+			if spec.Background != "" {
+				background = spec.Background
+			}
+			shapes, err := s.shapesWith(a, background, spec.Overrides)
+			if err != nil {
+				return nil, fmt.Errorf("server: export %q: %w", name, err)
+			}
+			if err := lim.CheckShapes(0, shapes...); err != nil {
+				return nil, fmt.Errorf("server: export %q: %w", name, err)
+			}
+			l = instructions.NewLayerWithScale(s.Width, s.Height, scale).SetLimits(lim)
+			l.LoadInstructions(shapes...)
+			if plain {
+				shared[scale] = l
+			}
+		}
+
+		format, quality := s.Format, s.Quality
+		if spec.Format != "" {
+			format = spec.Format
+		}
+		if spec.Quality != 0 {
+			quality = spec.Quality
+		}
+		data, contentType, err := encode(l.Image(), format, quality)
+		if err != nil {
+			return nil, fmt.Errorf("server: export %q: %w", name, err)
+		}
+		b := l.Image().Bounds()
+		out[name] = Export{Data: data, ContentType: contentType, Width: b.Dx(), Height: b.Dy()}
+	}
+	return out, nil
+}
+
+// exportScale returns the device scale of an output.
+func (s *Scene) exportScale(spec ExportSpec) float64 {
+	switch {
+	case spec.Scale > 0:
+		return spec.Scale
+	case s.Width <= 0 || s.Height <= 0:
+		return s.scale()
+	case spec.Width > 0 && spec.Height > 0:
+		return math.Min(float64(spec.Width)/float64(s.Width), float64(spec.Height)/float64(s.Height))
+	case spec.Width > 0:
+		return float64(spec.Width) / float64(s.Width)
+	case spec.Height > 0:
+		return float64(spec.Height) / float64(s.Height)
+	}
+	return s.scale()
+}
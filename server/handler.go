@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/Krispeckt/glimo/instructions"
+)
+
+// Handler is an http.Handler that renders POSTed Scene JSON and responds with
+// the encoded image. Renders are limited to a fixed number running at once,
+// and each request is bounded by a timeout covering both the wait for a slot
+// and the render itself.
+//
+// Assets are decoded and the scene is checked while holding a slot, and a
+// render that outlives its request keeps its slot until it finishes, so the
+// concurrency limit holds even under timeouts.
+type Handler struct {
+	slots    chan struct{}
+	timeout  time.Duration
+	maxBody  int64
+	maxBytes int64
+	limits   instructions.Limits
+}
+
+// NewHandler creates a Handler allowing runtime.NumCPU() concurrent renders,
+// a 10 second timeout, request bodies up to 32 MiB, renders estimated at up
+// to 1 GiB and instructions.DefaultLimits.
+func NewHandler() *Handler {
+	return &Handler{
+		slots:    make(chan struct{}, runtime.NumCPU()),
+		timeout:  10 * time.Second,
+		maxBody:  32 << 20,
+		maxBytes: 1 << 30,
+		limits:   instructions.DefaultLimits(),
+	}
+}
+
+// SetMaxConcurrent sets how many renders may run at once. Values below 1 are
+// treated as 1. Call it before the handler starts serving.
+func (h *Handler) SetMaxConcurrent(n int) *Handler {
+	if n < 1 {
+		n = 1
+	}
+	h.slots = make(chan struct{}, n)
+	return h
+}
+
+// SetTimeout sets the per-request deadline. Zero or negative disables it.
+func (h *Handler) SetTimeout(d time.Duration) *Handler {
+	h.timeout = d
+	return h
+}
+
+// SetMaxBodyBytes limits the size of the request body, assets included.
+func (h *Handler) SetMaxBodyBytes(n int64) *Handler {
+	h.maxBody = n
+	return h
+}
+
+// SetMaxRenderBytes rejects scenes whose estimated peak memory (see
+// EstimateCost) exceeds n bytes before any canvas is allocated. The default
+// is 1 GiB; zero or negative disables the check.
+func (h *Handler) SetMaxRenderBytes(n int64) *Handler {
+	h.maxBytes = n
+	return h
+}
+
+// SetLimits sets hard caps on canvas size, effect radii and instruction count
+// (see instructions.Limits). Scenes over them are rejected before their
+// canvas is allocated. The default is instructions.DefaultLimits; the zero
+// Limits caps nothing.
+func (h *Handler) SetLimits(lim instructions.Limits) *Handler {
+	h.limits = lim
+	return h
+}
+
+// result carries a finished render back to the waiting request.
+type result struct {
+	data        []byte
+	contentType string
+	err         error
+}
+
+// ServeHTTP decodes the scene, renders it and writes the image.
+//
+// Status codes: 405 for non-POST requests, 413 for oversized bodies, 400 for
+// malformed scenes, 422 for scenes over the render memory limit or the
+// Limits, 503 when no render slot frees up in time and 504 when the render
+// does not finish in time.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	var scene Scene
+	body := http.MaxBytesReader(w, r.Body, h.maxBody)
+	if err := json.NewDecoder(body).Decode(&scene); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid scene: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		http.Error(w, "render capacity exhausted", http.StatusServiceUnavailable)
+		return
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		defer func() { <-h.slots }()
+		data, ct, err := h.render(&scene)
+		done <- result{data: data, contentType: ct, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		var limit *instructions.LimitError
+		if errors.As(res.err, &limit) {
+			http.Error(w, res.err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", res.contentType)
+		_, _ = w.Write(res.data)
+	case <-ctx.Done():
+		http.Error(w, "render timed out", http.StatusGatewayTimeout)
+	}
+}
+
+// render builds the scene's shapes once, checks them against the limits and
+// the render memory budget, then draws and encodes them. It runs while
+// holding a render slot, so decoding assets counts towards the concurrency
+// limit too. A scene over the budget fails with an *instructions.LimitError.
+func (h *Handler) render(s *Scene) ([]byte, string, error) {
+	shapes, err := s.prepare(h.limits)
+	if err != nil {
+		return nil, "", err
+	}
+	if h.maxBytes > 0 {
+		cost := instructions.EstimateCost(s.Width, s.Height, s.scale(), shapes...)
+		if cost.PeakBytes > h.maxBytes {
+			return nil, "", &instructions.LimitError{Limit: "render bytes", Value: float64(cost.PeakBytes), Max: float64(h.maxBytes)}
+		}
+	}
+	return s.encodeLayer(s.draw(shapes, h.limits))
+}
@@ -0,0 +1,433 @@
+// Package server exposes glimo as an HTTP rendering endpoint. A client posts
+// a declarative scene as JSON, with fonts and images embedded as base64
+// assets, and receives the encoded image.
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"github.com/Krispeckt/glimo/colors"
+	"github.com/Krispeckt/glimo/instructions"
+	imageUtil "github.com/Krispeckt/glimo/internal/core/image"
+	"github.com/Krispeckt/glimo/internal/render"
+	"github.com/Krispeckt/glimo/patterns"
+)
+
+// Scene is the JSON request body: canvas settings, named assets and the
+// instructions to draw in order.
+type Scene struct {
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	Scale      float64 `json:"scale,omitempty"`      // device scale, 1 by default
+	Background string  `json:"background,omitempty"` // CSS color, transparent by default
+	Format     string  `json:"format,omitempty"`     // "png" (default) or "jpeg"
+	Quality    int     `json:"quality,omitempty"`    // JPEG quality, 90 by default
+
+	// Assets maps names to base64-encoded font (TTF) or image (PNG/JPEG) data.
+	Assets map[string]string `json:"assets,omitempty"`
+
+	// Data, when set, makes every text an ICU MessageFormat message formatted
+	// with these arguments in Locale (a BCP 47 tag, English by default), so
+	// plurals and gendered wording are resolved here instead of by the
+	// caller. See render.FormatMessage for the supported syntax.
+	Data   map[string]any `json:"data,omitempty"`
+	Locale string         `json:"locale,omitempty"`
+
+	Instructions []Instruction `json:"instructions"`
+}
+
+// Instruction describes one shape. Type selects which fields apply:
+//
+//   - "rect":   X, Y, Width, Height, Radius, Fill, Stroke, LineWidth
+//   - "circle": X, Y (top-left of the bounding box), Radius, Fill, Stroke, LineWidth
+//   - "text":   X, Y, Text, Font (asset), Size (pt), Color, MaxWidth, MaxLines, Align
+//   - "image":  X, Y, Src (asset), Width, Height, Fit, Opacity
+//   - "line":   Points, Close, Fill, Stroke, LineWidth
+//
+// Asset names missing from Scene.Assets resolve to fonts and images
+// registered with the server process (render.RegisterFont,
+// RegisterImage); text without a Font uses the bundled default font.
+//
+// Colors use CSS syntax (see colors.Parse), such as "#ff8800", "#ff880080",
+// "rebeccapurple" or "rgb(255 136 0 / 50%)".
+type Instruction struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"` // shape ID (see instructions.FindByID), also keys ExportSpec.Overrides
+
+	X      float64 `json:"x,omitempty"`
+	Y      float64 `json:"y,omitempty"`
+	Width  float64 `json:"width,omitempty"`
+	Height float64 `json:"height,omitempty"`
+	Radius float64 `json:"radius,omitempty"`
+
+	Fill      string  `json:"fill,omitempty"`
+	Stroke    string  `json:"stroke,omitempty"`
+	LineWidth float64 `json:"lineWidth,omitempty"`
+
+	Text     string  `json:"text,omitempty"`
+	Font     string  `json:"font,omitempty"`
+	Size     float64 `json:"size,omitempty"`
+	Color    string  `json:"color,omitempty"`
+	MaxWidth float64 `json:"maxWidth,omitempty"`
+	MaxLines int     `json:"maxLines,omitempty"`
+	Align    string  `json:"align,omitempty"` // "left", "center" or "right"
+
+	Src     string   `json:"src,omitempty"`
+	Fit     string   `json:"fit,omitempty"` // "contain" (default), "cover" or "stretch"
+	Opacity *float64 `json:"opacity,omitempty"`
+
+	Points [][2]float64 `json:"points,omitempty"`
+	Close  bool         `json:"close,omitempty"`
+}
+
+// Render builds the scene and returns the encoded image and its content type.
+func (s *Scene) Render() ([]byte, string, error) {
+	return s.RenderWithLimits(instructions.Limits{})
+}
+
+// RenderWithLimits is Render under lim (see BuildWithLimits).
+func (s *Scene) RenderWithLimits(lim instructions.Limits) ([]byte, string, error) {
+	l, err := s.BuildWithLimits(lim)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.encodeLayer(l)
+}
+
+// encodeLayer encodes the drawn scene in its Format and Quality.
+func (s *Scene) encodeLayer(l *instructions.Layer) ([]byte, string, error) {
+	data, contentType, err := encode(l.Image(), s.Format, s.Quality)
+	if err != nil {
+		return nil, "", fmt.Errorf("server: %w", err)
+	}
+	return data, contentType, nil
+}
+
+// encode encodes img in format ("png" by default, or "jpeg" at quality, 90
+// by default) and returns the data and its content type.
+func encode(img image.Image, format string, quality int) ([]byte, string, error) {
+	var buf bytes.Buffer
+	switch strings.ToLower(format) {
+	case "", "png":
+		if err := (&png.Encoder{CompressionLevel: png.BestSpeed}).Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	case "jpeg", "jpg":
+		q := quality
+		if q <= 0 || q > 100 {
+			q = 90
+		}
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: q}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// Build validates the scene and draws it onto a new Layer.
+func (s *Scene) Build() (*instructions.Layer, error) {
+	return s.BuildWithLimits(instructions.Limits{})
+}
+
+// BuildWithLimits is Build under lim. Scenes over a limit fail with an
+// *instructions.LimitError before the canvas is allocated; the background
+// counts as an instruction.
+func (s *Scene) BuildWithLimits(lim instructions.Limits) (*instructions.Layer, error) {
+	shapes, err := s.prepare(lim)
+	if err != nil {
+		return nil, err
+	}
+	return s.draw(shapes, lim), nil
+}
+
+// prepare validates the scene under lim and returns the shapes to draw,
+// decoding assets but without allocating the canvas.
+func (s *Scene) prepare(lim instructions.Limits) ([]instructions.Shape, error) {
+	if err := lim.CheckCanvas(s.Width, s.Height, s.scale()); err != nil {
+		return nil, fmt.Errorf("server: %w", err)
+	}
+	shapes, err := s.shapes()
+	if err != nil {
+		return nil, err
+	}
+	if err := lim.CheckShapes(0, shapes...); err != nil {
+		return nil, fmt.Errorf("server: %w", err)
+	}
+	return shapes, nil
+}
+
+// draw loads shapes returned by prepare onto a new Layer for the scene.
+func (s *Scene) draw(shapes []instructions.Shape, lim instructions.Limits) *instructions.Layer {
+	l := instructions.NewLayerWithScale(s.Width, s.Height, s.scale()).SetLimits(lim)
+	l.LoadInstructions(shapes...)
+	return l
+}
+
+// EstimateCost validates the scene and predicts the memory and work of
+// rendering it (see instructions.EstimateCost) without allocating the
+// canvas. Assets are decoded to build the shapes.
+func EstimateCost(s *Scene) (instructions.Cost, error) {
+	shapes, err := s.shapes()
+	if err != nil {
+		return instructions.Cost{}, err
+	}
+	return instructions.EstimateCost(s.Width, s.Height, s.scale(), shapes...), nil
+}
+
+// scale returns the device scale, 1 when unset.
+func (s *Scene) scale() float64 {
+	if s.Scale <= 0 {
+		return 1
+	}
+	return s.Scale
+}
+
+// shapes validates the scene and converts it into the shapes to draw, the
+// background first.
+func (s *Scene) shapes() ([]instructions.Shape, error) {
+	return s.shapesWith(s.newAssets(), s.Background, nil)
+}
+
+// newAssets returns an empty asset cache for the scene.
+func (s *Scene) newAssets() *assets {
+	return &assets{raw: s.Assets, fonts: map[string]*render.Font{}, images: map[string]image.Image{}}
+}
+
+// shapesWith is shapes with decoded assets shared through a, the given
+// background and the style overrides applied to instructions by ID.
+func (s *Scene) shapesWith(a *assets, background string, overrides map[string]StyleOverride) ([]instructions.Shape, error) {
+	if s.Width <= 0 || s.Height <= 0 {
+		return nil, fmt.Errorf("server: invalid canvas size %dx%d", s.Width, s.Height)
+	}
+	var shapes []instructions.Shape
+	if background != "" {
+		bg, err := colors.Parse(background)
+		if err != nil {
+			return nil, fmt.Errorf("server: background: %w", err)
+		}
+		shapes = append(shapes, instructions.NewRectangle(0, 0, float64(s.Width), float64(s.Height)).
+			SetFillColor(bg).SetLineWidth(0))
+	}
+
+	for i := range s.Instructions {
+		in := s.Instructions[i]
+		if o, ok := overrides[in.ID]; ok && in.ID != "" {
+			if o.Hidden {
+				continue
+			}
+			in = o.apply(in)
+		}
+		shape, err := in.shape(s, a)
+		if err != nil {
+			return nil, fmt.Errorf("server: instruction %d (%s): %w", i, s.Instructions[i].Type, err)
+		}
+		shapes = append(shapes, shape)
+	}
+	return shapes, nil
+}
+
+// shape converts the instruction into a drawable instructions.Shape.
+func (in *Instruction) shape(s *Scene, a *assets) (instructions.Shape, error) {
+	switch in.Type {
+	case "rect":
+		r := instructions.NewRectangle(in.X, in.Y, in.Width, in.Height).
+			SetRadius(in.Radius).SetLineWidth(in.LineWidth).SetID(in.ID)
+		if err := applyColor(in.Fill, func(c patterns.Color) { r.SetFillColor(c) }); err != nil {
+			return nil, err
+		}
+		if err := applyColor(in.Stroke, func(c patterns.Color) { r.SetStrokeColor(c) }); err != nil {
+			return nil, err
+		}
+		return r, nil
+
+	case "circle":
+		c := instructions.NewCircle(in.X, in.Y, in.Radius).SetLineWidth(in.LineWidth).SetID(in.ID)
+		if err := applyColor(in.Fill, func(col patterns.Color) { c.SetFillColor(col) }); err != nil {
+			return nil, err
+		}
+		if err := applyColor(in.Stroke, func(col patterns.Color) { c.SetStrokeColor(col) }); err != nil {
+			return nil, err
+		}
+		return c, nil
+
+	case "text":
+		size := in.Size
+		if size <= 0 {
+			size = 16
+		}
+		f, err := a.font(in.Font, size)
+		if err != nil {
+			return nil, err
+		}
+		text := in.Text
+		if s.Data != nil {
+			if text, err = render.FormatMessage(s.Locale, text, s.Data); err != nil {
+				return nil, err
+			}
+		}
+		t := instructions.NewText(text, in.X, in.Y, f).
+			SetMaxWidth(in.MaxWidth).
+			SetMaxLines(in.MaxLines).
+			SetSolidColor(colors.Black).
+			SetID(in.ID)
+		switch in.Align {
+		case "", "left":
+		case "center":
+			t.SetAlign(instructions.AlignTextCenter)
+		case "right":
+			t.SetAlign(instructions.AlignTextRight)
+		default:
+			return nil, fmt.Errorf("unknown align %q", in.Align)
+		}
+		if err := applyColor(in.Color, func(c patterns.Color) { t.SetSolidColor(c) }); err != nil {
+			return nil, err
+		}
+		return t, nil
+
+	case "image":
+		src, err := a.image(in.Src)
+		if err != nil {
+			return nil, err
+		}
+		im := instructions.NewImage(src, int(in.X), int(in.Y)).
+			SetSize(int(in.Width), int(in.Height)).
+			SetID(in.ID)
+		switch in.Fit {
+		case "", "contain":
+		case "cover":
+			im.SetFit(instructions.FitCover)
+		case "stretch":
+			im.SetFit(instructions.FitStretch)
+		default:
+			return nil, fmt.Errorf("unknown fit %q", in.Fit)
+		}
+		if in.Opacity != nil {
+			im.SetOpacity(*in.Opacity)
+		}
+		return im, nil
+
+	case "line":
+		if len(in.Points) < 2 {
+			return nil, fmt.Errorf("line needs at least 2 points")
+		}
+		ln := instructions.NewLine().SetLineWidth(in.LineWidth).SetID(in.ID)
+		ln.MoveTo(in.Points[0][0], in.Points[0][1])
+		for _, p := range in.Points[1:] {
+			ln.LineTo(p[0], p[1])
+		}
+		if in.Close {
+			ln.ClosePath()
+		}
+		if in.Fill != "" {
+			c, err := colors.Parse(in.Fill)
+			if err != nil {
+				return nil, err
+			}
+			ln.SetFillPattern(c.MakeSolidPattern()).FillPreserve()
+		}
+		if in.Stroke != "" {
+			c, err := colors.Parse(in.Stroke)
+			if err != nil {
+				return nil, err
+			}
+			ln.SetStrokePattern(c.MakeSolidPattern()).StrokePreserve()
+		}
+		return ln, nil
+
+	default:
+		return nil, fmt.Errorf("unknown instruction type")
+	}
+}
+
+// applyColor parses a CSS color and passes it to set; empty strings are
+// skipped.
+func applyColor(css string, set func(patterns.Color)) error {
+	if css == "" {
+		return nil
+	}
+	c, err := colors.Parse(css)
+	if err != nil {
+		return err
+	}
+	set(c)
+	return nil
+}
+
+// assets decodes named base64 assets on first use and caches the results
+// for the duration of one render, or of all the outputs of ExportSet.
+type assets struct {
+	raw    map[string]string
+	fonts  map[string]*render.Font
+	images map[string]image.Image
+}
+
+// bytes returns the decoded data of the named asset.
+func (a *assets) bytes(name string) ([]byte, error) {
+	enc, ok := a.raw[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown asset %q", name)
+	}
+	data, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return nil, fmt.Errorf("asset %q: %w", name, err)
+	}
+	return data, nil
+}
+
+// font returns the named font at the given point size. Each asset is parsed
+// once; sizes are applied to copies.
+func (a *assets) font(name string, sizePt float64) (*render.Font, error) {
+	if _, ok := a.raw[name]; !ok {
+		if name == "" {
+			name = render.DefaultFontName
+		}
+		if f, err := render.NamedFont(name, sizePt); err == nil {
+			return f, nil
+		}
+	}
+	f, ok := a.fonts[name]
+	if !ok {
+		data, err := a.bytes(name)
+		if err != nil {
+			return nil, err
+		}
+		if f, err = render.LoadFontFromBytes(data, sizePt); err != nil {
+			return nil, fmt.Errorf("asset %q: %w", name, err)
+		}
+		a.fonts[name] = f
+	}
+	c := *f
+	return c.SetFontSizePt(sizePt), nil
+}
+
+// image returns the named image asset decoded as RGBA.
+func (a *assets) image(name string) (image.Image, error) {
+	if im, ok := a.images[name]; ok {
+		return im, nil
+	}
+	if _, ok := a.raw[name]; !ok {
+		if im, err := imageUtil.NamedImage(name); err == nil {
+			return im, nil
+		}
+	}
+	data, err := a.bytes(name)
+	if err != nil {
+		return nil, err
+	}
+	im, _, err := imageUtil.SafeDecode(bytes.NewReader(data), imageUtil.DefaultDecodeLimits())
+	if err != nil {
+		return nil, fmt.Errorf("asset %q: %w", name, err)
+	}
+	rgba := imageUtil.ToRGBA(im)
+	a.images[name] = rgba
+	return rgba, nil
+}
@@ -0,0 +1,245 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Krispeckt/glimo"
+	"github.com/Krispeckt/glimo/server"
+	"github.com/stretchr/testify/require"
+)
+
+func post(t *testing.T, h http.Handler, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	b, err := json.Marshal(body)
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/render", bytes.NewReader(b)))
+	return rec
+}
+
+func TestHandlerRendersScene(t *testing.T) {
+	ttf, err := os.ReadFile("../../instructions/tests/testdata/montserrat.ttf")
+	require.NoError(t, err)
+
+	scene := server.Scene{
+		Width:      200,
+		Height:     100,
+		Background: "#ffffff",
+		Assets:     map[string]string{"montserrat": base64.StdEncoding.EncodeToString(ttf)},
+		Instructions: []server.Instruction{
+			{Type: "rect", X: 10, Y: 10, Width: 60, Height: 40, Radius: 6, Fill: "red"},
+			{Type: "circle", X: 120, Y: 10, Radius: 20, Fill: "rgb(0 0 255)"},
+			{Type: "text", X: 10, Y: 60, Text: "served", Font: "montserrat", Size: 20, Color: "#000000"},
+			{Type: "line", Points: [][2]float64{{0, 95}, {200, 95}}, Stroke: "#00ff00", LineWidth: 2},
+		},
+	}
+
+	rec := post(t, server.NewHandler().SetMaxConcurrent(2), scene)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+
+	img, err := png.Decode(rec.Body)
+	require.NoError(t, err)
+	require.Equal(t, 200, img.Bounds().Dx())
+	require.Equal(t, 100, img.Bounds().Dy())
+
+	r, g, b, _ := img.At(40, 30).RGBA()
+	require.Equal(t, uint32(0xffff), r)
+	require.Zero(t, g)
+	require.Zero(t, b)
+}
+
+func TestHandlerRejectsBadRequests(t *testing.T) {
+	h := server.NewHandler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/render", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/render", bytes.NewBufferString("{")))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = post(t, h, server.Scene{Width: 10, Height: 10, Instructions: []server.Instruction{{Type: "hexagon"}}})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "unknown instruction type")
+
+	rec = post(t, h, server.Scene{Width: 10, Height: 10, Instructions: []server.Instruction{{Type: "text", Font: "missing"}}})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = post(t, server.NewHandler().SetMaxBodyBytes(16), server.Scene{Width: 10, Height: 10})
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestHandlerUsesRegisteredAssets(t *testing.T) {
+	require.NoError(t, glimo.RegisterImagesFS(os.DirFS("../../instructions/tests/testdata"), "*.png"))
+
+	scene := server.Scene{
+		Width:  200,
+		Height: 100,
+		Instructions: []server.Instruction{
+			{Type: "image", X: 0, Y: 0, Width: 100, Height: 100, Src: "image"},
+			{Type: "text", X: 110, Y: 40, Text: "default", Size: 20, Color: "#000000"},
+		},
+	}
+	rec := post(t, server.NewHandler(), scene)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	scene.Instructions = []server.Instruction{{Type: "text", Text: "x", Font: "unregistered"}}
+	rec = post(t, server.NewHandler(), scene)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandlerRejectsCostlyScenes(t *testing.T) {
+	small := server.Scene{Width: 200, Height: 100, Instructions: []server.Instruction{
+		{Type: "rect", X: 10, Y: 10, Width: 60, Height: 40, Fill: "#ff0000"},
+	}}
+	cost, err := server.EstimateCost(&small)
+	require.NoError(t, err)
+	require.Equal(t, int64(200*100), cost.Pixels)
+	require.GreaterOrEqual(t, cost.PeakBytes, int64(200*100*4))
+
+	huge := server.Scene{Width: 20000, Height: 20000, Background: "#ffffff"}
+	hugeCost, err := server.EstimateCost(&huge)
+	require.NoError(t, err)
+	require.Greater(t, hugeCost.PeakBytes, int64(1<<30))
+
+	_, err = server.EstimateCost(&server.Scene{Width: 0, Height: 10})
+	require.Error(t, err)
+
+	h := server.NewHandler().SetMaxRenderBytes(64 << 20)
+	rec := post(t, h, huge)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Equal(t, http.StatusOK, post(t, h, small).Code)
+}
+
+func TestHandlerEnforcesLimits(t *testing.T) {
+	h := server.NewHandler().SetLimits(glimo.Limits{MaxWidth: 500, MaxHeight: 500, MaxInstructions: 2})
+	scene := server.Scene{Width: 200, Height: 100, Background: "#ffffff", Instructions: []server.Instruction{
+		{Type: "rect", X: 10, Y: 10, Width: 60, Height: 40, Fill: "#ff0000"},
+	}}
+	require.Equal(t, http.StatusOK, post(t, h, scene).Code)
+
+	// The background counts as an instruction.
+	crowded := scene
+	crowded.Instructions = append(crowded.Instructions, crowded.Instructions[0])
+	rec := post(t, h, crowded)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Contains(t, rec.Body.String(), "instruction count 3 exceeds the limit of 2")
+
+	wide := scene
+	wide.Width = 400
+	wide.Scale = 2
+	rec = post(t, h, wide)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Contains(t, rec.Body.String(), "canvas width")
+
+	_, err := wide.BuildWithLimits(glimo.Limits{MaxWidth: 500})
+	var limitErr *glimo.LimitError
+	require.ErrorAs(t, err, &limitErr)
+
+	// The defaults refuse huge canvases without any configuration.
+	rec = post(t, server.NewHandler(), server.Scene{Width: 100000, Height: 100000})
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	rec = post(t, server.NewHandler().SetMaxRenderBytes(0), server.Scene{Width: 100000, Height: 100000})
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Contains(t, rec.Body.String(), "canvas pixels")
+
+	// Huge text fits the canvas limits but not the font size limit.
+	big := server.Scene{Width: 200, Height: 100, Instructions: []server.Instruction{
+		{Type: "text", Text: "x", Size: 100000},
+	}}
+	rec = post(t, server.NewHandler(), big)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Contains(t, rec.Body.String(), "font size")
+	_, err = big.BuildWithLimits(glimo.Limits{MaxFontSize: 500})
+	require.ErrorAs(t, err, &limitErr)
+
+	// Under it, the render budget charges text by its glyph caches and masks.
+	big.Instructions[0].Size = 200
+	rec = post(t, server.NewHandler().SetMaxRenderBytes(16<<20), big)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Contains(t, rec.Body.String(), "render bytes")
+}
+
+func TestSceneFormatsMessages(t *testing.T) {
+	text := func(s string) server.Instruction {
+		return server.Instruction{Type: "text", X: 5, Y: 5, Text: s, Size: 14, Color: "#000000"}
+	}
+	scene := server.Scene{
+		Width: 160, Height: 30, Background: "#ffffff", Locale: "en",
+		Data:         map[string]any{"n": 1.0},
+		Instructions: []server.Instruction{text("{n, plural, one {# member} other {# members}}")},
+	}
+	got, err := scene.Build()
+	require.NoError(t, err)
+
+	want, err := (&server.Scene{
+		Width: 160, Height: 30, Background: "#ffffff",
+		Instructions: []server.Instruction{text("1 member")},
+	}).Build()
+	require.NoError(t, err)
+	require.Equal(t, want.Image().Pix, got.Image().Pix)
+
+	scene.Instructions = []server.Instruction{text("{count}")}
+	rec := post(t, server.NewHandler(), scene)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), `missing argument "count"`)
+}
+
+func TestSceneExportSet(t *testing.T) {
+	scene := server.Scene{
+		Width: 200, Height: 100, Background: "#ffffff",
+		Instructions: []server.Instruction{
+			{Type: "rect", ID: "card", X: 10, Y: 10, Width: 80, Height: 80, Fill: "#ff0000"},
+			{Type: "circle", ID: "badge", X: 120, Y: 20, Radius: 30, Fill: "#0000ff"},
+		},
+	}
+	out, err := scene.ExportSet(map[string]server.ExportSpec{
+		"full":  {},
+		"jpeg":  {Format: "jpeg", Quality: 70},
+		"thumb": {Width: 100},
+		"retina": {
+			Scale: 2,
+			Overrides: map[string]server.StyleOverride{
+				"card":  {Fill: "#00ff00"},
+				"badge": {Hidden: true},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, out, 4)
+
+	require.Equal(t, "image/jpeg", out["jpeg"].ContentType)
+	require.Equal(t, 100, out["thumb"].Width)
+	require.Equal(t, 50, out["thumb"].Height)
+	require.Equal(t, 400, out["retina"].Width)
+
+	full, err := png.Decode(bytes.NewReader(out["full"].Data))
+	require.NoError(t, err)
+	plain, _, err := scene.Render()
+	require.NoError(t, err)
+	want, err := png.Decode(bytes.NewReader(plain))
+	require.NoError(t, err)
+	require.Equal(t, want, full)
+
+	retina, err := png.Decode(bytes.NewReader(out["retina"].Data))
+	require.NoError(t, err)
+	r, g, _, _ := retina.At(100, 100).RGBA()
+	require.Zero(t, r)
+	require.Equal(t, uint32(0xffff), g)
+	_, _, b, _ := retina.At(300, 100).RGBA()
+	require.Equal(t, uint32(0xffff), b, "hidden badge leaves the white background")
+	r, _, _, _ = retina.At(300, 100).RGBA()
+	require.Equal(t, uint32(0xffff), r)
+
+	_, err = scene.ExportSet(map[string]server.ExportSpec{"bad": {Format: "gif"}})
+	require.ErrorContains(t, err, `export "bad": unsupported format "gif"`)
+}